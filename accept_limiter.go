@@ -0,0 +1,66 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import "time"
+
+// acceptRateLimiter limits the rate at which inbound connections are accepted,
+// allowing bursts of up to burst connections. It's only used by the accept
+// loop, so it's not safe for concurrent use.
+type acceptRateLimiter struct {
+	interval time.Duration
+	burst    int
+	timeNow  func() time.Time
+
+	// next is the time at which the next connection would be accepted if
+	// there were no burst allowance.
+	next time.Time
+}
+
+func newAcceptRateLimiter(timeNow func() time.Time, rate, burst int) *acceptRateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = rate
+	}
+	return &acceptRateLimiter{
+		interval: time.Second / time.Duration(rate),
+		burst:    burst,
+		timeNow:  timeNow,
+	}
+}
+
+// reserve reserves the next accept, and returns how long the caller must wait
+// before handling the connection.
+func (l *acceptRateLimiter) reserve() time.Duration {
+	now := l.timeNow()
+	if l.next.Before(now) {
+		l.next = now
+	}
+
+	delay := l.next.Sub(now) - time.Duration(l.burst-1)*l.interval
+	l.next = l.next.Add(l.interval)
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptRateLimiterDisabled(t *testing.T) {
+	assert.Nil(t, newAcceptRateLimiter(time.Now, 0, 10), "Expected no limiter without a rate")
+}
+
+func TestAcceptRateLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := newAcceptRateLimiter(func() time.Time { return now }, 10 /* rate */, 3 /* burst */)
+
+	// The first burst of connections is accepted immediately.
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, time.Duration(0), l.reserve(), "Connection %v in the burst should not be delayed", i)
+	}
+
+	// Further connections are spaced out at the rate.
+	assert.Equal(t, 100*time.Millisecond, l.reserve(), "Unexpected delay after the burst")
+	assert.Equal(t, 200*time.Millisecond, l.reserve(), "Unexpected delay after the burst")
+
+	// After enough time, the burst is available again.
+	now = now.Add(time.Second)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, time.Duration(0), l.reserve(), "Connection %v in the burst should not be delayed", i)
+	}
+	assert.Equal(t, 100*time.Millisecond, l.reserve(), "Unexpected delay after the burst")
+}
+
+func TestAcceptRateLimiterDefaultBurst(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := newAcceptRateLimiter(func() time.Time { return now }, 5 /* rate */, 0 /* burst */)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, time.Duration(0), l.reserve(), "Connection %v in the burst should not be delayed", i)
+	}
+	assert.Equal(t, 200*time.Millisecond, l.reserve(), "Unexpected delay after the burst")
+}
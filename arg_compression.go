@@ -0,0 +1,315 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// ArgCompressionGzip compresses call arguments using gzip. It's the only
+	// built-in codec: tchannel-go doesn't depend on a snappy implementation,
+	// so applications that want snappy must register their own ArgCompressor.
+	ArgCompressionGzip = "gzip"
+
+	// defaultArgCompressionThreshold is the default minimum size of an
+	// argument before it's compressed.
+	defaultArgCompressionThreshold = 1024
+
+	// argUncompressed and argCompressed are the values of the byte that
+	// prefixes arg2 and arg3 on connections that negotiated argument compression.
+	argUncompressed byte = 0
+	argCompressed   byte = 1
+)
+
+var (
+	argCompressors = map[string]ArgCompressor{}
+
+	errUnknownArgCompression = errors.New("unknown argument compression flag")
+)
+
+// ArgCompressor compresses and decompresses arg2 and arg3 of calls made on
+// connections that negotiated its codec.
+type ArgCompressor interface {
+	// NewWriter returns an ArgWriter that compresses bytes written to it
+	// into w. Close must finish the compressed stream without closing w.
+	NewWriter(w io.Writer) ArgWriter
+
+	// NewReader returns a reader that decompresses the stream read from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+func init() {
+	RegisterArgCompressor(ArgCompressionGzip, gzipArgCompressor{})
+}
+
+// RegisterArgCompressor registers the ArgCompressor for the codec with the given
+// name, so it can be used in ConnectionOptions.ArgCompression. It is not
+// thread-safe, and should be called from an init function. It panics if name
+// is already registered.
+func RegisterArgCompressor(name string, c ArgCompressor) {
+	if name == "" || strings.Contains(name, ",") {
+		panic(fmt.Sprintf("tchannel: invalid argument compressor name %q", name))
+	}
+	if _, ok := argCompressors[name]; ok {
+		panic(fmt.Sprintf("tchannel: argument compressor %v is already registered", name))
+	}
+	argCompressors[name] = c
+}
+
+type gzipArgCompressor struct{}
+
+func (gzipArgCompressor) NewWriter(w io.Writer) ArgWriter {
+	return gzip.NewWriter(w)
+}
+
+func (gzipArgCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// argCompression is the argument compression negotiated for a connection.
+type argCompression struct {
+	name       string
+	compressor ArgCompressor
+	threshold  int
+
+	statsReporter StatsReporter
+	statsTags     map[string]string
+}
+
+// advertisedArgCompression returns the value of InitParamArgCompression,
+// which lists the registered codecs in ConnectionOptions.ArgCompression.
+// Relays forward argument bytes as-is, so they never advertise any codecs.
+func (ch *Channel) advertisedArgCompression() string {
+	if ch.RelayHost() != nil {
+		return ""
+	}
+
+	var names []string
+	for _, name := range ch.connectionOptions.ArgCompression {
+		if _, ok := argCompressors[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// negotiateArgCompression returns the first codec advertised locally that the
+// remote peer also advertised, or nil if there's no codec in common.
+func (ch *Channel) negotiateArgCompression(remote initParams) *argCompression {
+	remoteNames := strings.Split(remote[InitParamArgCompression], ",")
+	for _, name := range strings.Split(ch.advertisedArgCompression(), ",") {
+		if name == "" {
+			continue
+		}
+		for _, remoteName := range remoteNames {
+			if name != remoteName {
+				continue
+			}
+
+			threshold := ch.connectionOptions.ArgCompressionThreshold
+			if threshold <= 0 {
+				threshold = defaultArgCompressionThreshold
+			}
+			return &argCompression{
+				name:          name,
+				compressor:    argCompressors[name],
+				threshold:     threshold,
+				statsReporter: ch.statsReporter,
+				statsTags:     ch.commonStatsTags,
+			}
+		}
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.n += n
+	return n, err
+}
+
+// compressingArgWriter prefixes an argument with whether it's compressed.
+// Bytes are buffered until the threshold is reached, so small arguments are
+// sent uncompressed. Flushing before the threshold is reached also sends the
+// argument uncompressed, so streamed arguments are not delayed.
+type compressingArgWriter struct {
+	ArgWriter
+
+	compression  *argCompression
+	buf          bytes.Buffer
+	decided      bool
+	compressor   ArgWriter
+	compressed   *countingWriter
+	uncompressed int
+}
+
+func newCompressingArgWriter(w ArgWriter, compression *argCompression) *compressingArgWriter {
+	return &compressingArgWriter{ArgWriter: w, compression: compression}
+}
+
+// decide writes the compression flag and any buffered bytes.
+func (w *compressingArgWriter) decide(compress bool) error {
+	w.decided = true
+	flag := argUncompressed
+	if compress {
+		flag = argCompressed
+	}
+	if _, err := w.ArgWriter.Write([]byte{flag}); err != nil {
+		return err
+	}
+
+	var dst io.Writer = w.ArgWriter
+	if compress {
+		w.compressed = &countingWriter{w: w.ArgWriter}
+		w.compressor = w.compression.compressor.NewWriter(w.compressed)
+		dst = w.compressor
+	}
+	_, err := dst.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+func (w *compressingArgWriter) Write(b []byte) (int, error) {
+	if w.compressor != nil {
+		w.uncompressed += len(b)
+		return w.compressor.Write(b)
+	}
+	if w.decided {
+		return w.ArgWriter.Write(b)
+	}
+
+	w.buf.Write(b)
+	if w.buf.Len() < w.compression.threshold {
+		return len(b), nil
+	}
+	w.uncompressed = w.buf.Len()
+	if err := w.decide(true /* compress */); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *compressingArgWriter) Flush() error {
+	if !w.decided {
+		if err := w.decide(false /* compress */); err != nil {
+			return err
+		}
+	}
+	if w.compressor != nil {
+		if err := w.compressor.Flush(); err != nil {
+			return err
+		}
+	}
+	return w.ArgWriter.Flush()
+}
+
+func (w *compressingArgWriter) Close() error {
+	if !w.decided {
+		if err := w.decide(false /* compress */); err != nil {
+			return err
+		}
+	}
+	if w.compressor != nil {
+		if err := w.compressor.Close(); err != nil {
+			return err
+		}
+
+		c := w.compression
+		c.statsReporter.IncCounter("args.compression.uncompressed-bytes", c.statsTags, int64(w.uncompressed))
+		c.statsReporter.IncCounter("args.compression.compressed-bytes", c.statsTags, int64(w.compressed.n))
+	}
+	return w.ArgWriter.Close()
+}
+
+// decompressingArgReader reads the compression flag that prefixes an argument,
+// and decompresses the argument if needed.
+type decompressingArgReader struct {
+	ArgReader
+
+	compression  *argCompression
+	readFlag     bool
+	decompressor io.ReadCloser
+}
+
+func newDecompressingArgReader(r ArgReader, compression *argCompression) *decompressingArgReader {
+	return &decompressingArgReader{ArgReader: r, compression: compression}
+}
+
+func (r *decompressingArgReader) readCompressionFlag() error {
+	r.readFlag = true
+
+	var flag [1]byte
+	if _, err := io.ReadFull(r.ArgReader, flag[:]); err != nil {
+		return err
+	}
+
+	switch flag[0] {
+	case argUncompressed:
+		return nil
+	case argCompressed:
+		decompressor, err := r.compression.compressor.NewReader(r.ArgReader)
+		if err != nil {
+			return err
+		}
+		r.decompressor = decompressor
+		return nil
+	default:
+		return errUnknownArgCompression
+	}
+}
+
+func (r *decompressingArgReader) Read(b []byte) (int, error) {
+	if !r.readFlag {
+		if err := r.readCompressionFlag(); err != nil {
+			return 0, err
+		}
+	}
+	if r.decompressor != nil {
+		return r.decompressor.Read(b)
+	}
+	return r.ArgReader.Read(b)
+}
+
+func (r *decompressingArgReader) Close() error {
+	if !r.readFlag {
+		if err := r.readCompressionFlag(); err != nil {
+			return err
+		}
+	}
+	if r.decompressor != nil {
+		if err := r.decompressor.Close(); err != nil {
+			return err
+		}
+	}
+	return r.ArgReader.Close()
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getConnArgCompression(t *testing.T, ch *Channel) []string {
+	var codecs []string
+	for _, peer := range ch.IntrospectState(nil).RootPeers {
+		for _, conn := range peer.InboundConnections {
+			codecs = append(codecs, conn.ArgCompression)
+		}
+		for _, conn := range peer.OutboundConnections {
+			codecs = append(codecs, conn.ArgCompression)
+		}
+	}
+	require.NotEmpty(t, codecs, "Expected connections on channel")
+	return codecs
+}
+
+func TestArgCompression(t *testing.T) {
+	largeArg := bytes.Repeat([]byte("compress me please "), 1000)
+	smallArg := []byte("small")
+
+	tests := []struct {
+		msg            string
+		serverCodecs   []string
+		clientCodecs   []string
+		arg2           []byte
+		arg3           []byte
+		disable        bool
+		wantCodec      string
+		wantCompressed bool
+	}{
+		{
+			msg:            "both peers support gzip",
+			serverCodecs:   []string{ArgCompressionGzip},
+			clientCodecs:   []string{ArgCompressionGzip},
+			arg2:           smallArg,
+			arg3:           largeArg,
+			wantCodec:      ArgCompressionGzip,
+			wantCompressed: true,
+		},
+		{
+			msg:          "call disables compression",
+			serverCodecs: []string{ArgCompressionGzip},
+			clientCodecs: []string{ArgCompressionGzip},
+			arg2:         smallArg,
+			arg3:         largeArg,
+			disable:      true,
+			wantCodec:    ArgCompressionGzip,
+		},
+		{
+			msg:          "arguments below threshold",
+			serverCodecs: []string{ArgCompressionGzip},
+			clientCodecs: []string{ArgCompressionGzip},
+			arg2:         smallArg,
+			arg3:         smallArg,
+			wantCodec:    ArgCompressionGzip,
+		},
+		{
+			msg:          "empty arguments",
+			serverCodecs: []string{ArgCompressionGzip},
+			clientCodecs: []string{ArgCompressionGzip},
+			wantCodec:    ArgCompressionGzip,
+		},
+		{
+			msg:          "server does not advertise codecs",
+			clientCodecs: []string{ArgCompressionGzip},
+			arg3:         largeArg,
+		},
+		{
+			msg:          "client does not advertise codecs",
+			serverCodecs: []string{ArgCompressionGzip},
+			arg3:         largeArg,
+		},
+		{
+			msg:          "unknown codecs are not advertised",
+			serverCodecs: []string{"unknown"},
+			clientCodecs: []string{"unknown", ArgCompressionGzip},
+			arg3:         largeArg,
+		},
+	}
+
+	for _, tt := range tests {
+		opts := testutils.NewOpts().NoRelay().SetArgCompression(0, tt.serverCodecs...)
+		testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+			ts.Register(raw.Wrap(newTestHandler(t)), "echo")
+
+			statsReporter := newRecordingStatsReporter()
+			clientOpts := testutils.NewOpts().
+				SetStatsReporter(statsReporter).
+				SetArgCompression(0, tt.clientCodecs...)
+			client := ts.NewClient(clientOpts)
+
+			cb := NewContextBuilder(time.Second)
+			if tt.disable {
+				cb.DisableCompression()
+			}
+			ctx, cancel := cb.Build()
+			defer cancel()
+
+			resArg2, resArg3, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", tt.arg2, tt.arg3)
+			require.NoError(t, err, "%v: call failed", tt.msg)
+			assert.Equal(t, len(tt.arg2), len(resArg2), "%v: unexpected arg2", tt.msg)
+			assert.Equal(t, len(tt.arg3), len(resArg3), "%v: unexpected arg3", tt.msg)
+			assert.True(t, bytes.Equal(tt.arg3, resArg3), "%v: arg3 mismatch", tt.msg)
+
+			assert.Equal(t, []string{tt.wantCodec}, getConnArgCompression(t, client),
+				"%v: unexpected client codec", tt.msg)
+			assert.Equal(t, []string{tt.wantCodec}, getConnArgCompression(t, ts.Server()),
+				"%v: unexpected server codec", tt.msg)
+
+			uncompressed := statsReporter.getCount("args.compression.uncompressed-bytes", client.StatsTags())
+			compressed := statsReporter.getCount("args.compression.compressed-bytes", client.StatsTags())
+			if !tt.wantCompressed {
+				assert.Zero(t, uncompressed, "%v: unexpected uncompressed bytes", tt.msg)
+				assert.Zero(t, compressed, "%v: unexpected compressed bytes", tt.msg)
+				return
+			}
+
+			assert.EqualValues(t, len(tt.arg3), uncompressed, "%v: unexpected uncompressed bytes", tt.msg)
+			assert.True(t, compressed > 0 && compressed < uncompressed/10,
+				"%v: expected compressed bytes, got %v", tt.msg, compressed)
+		})
+	}
+}
+
+func TestArgCompressionStreamedArgFlush(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay().SetArgCompression(0, ArgCompressionGzip)
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		ts.Register(raw.Wrap(newTestHandler(t)), "echo")
+
+		client := ts.NewClient(testutils.NewOpts().SetArgCompression(0, ArgCompressionGzip))
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		call, err := client.BeginCall(ctx, ts.HostPort(), ts.ServiceName(), "echo", nil)
+		require.NoError(t, err, "BeginCall failed")
+		require.NoError(t, NewArgWriter(call.Arg2Writer()).Write(nil), "Write arg2 failed")
+
+		// Flushing before the threshold is reached sends the argument uncompressed,
+		// while the rest of the argument can exceed the threshold.
+		w, err := call.Arg3Writer()
+		require.NoError(t, err, "Arg3Writer failed")
+		_, err = w.Write([]byte("first"))
+		require.NoError(t, err, "Write failed")
+		require.NoError(t, w.Flush(), "Flush failed")
+		large := bytes.Repeat([]byte("a"), 10000)
+		_, err = w.Write(large)
+		require.NoError(t, err, "Write failed")
+		require.NoError(t, w.Close(), "Close failed")
+
+		var resArg3 []byte
+		require.NoError(t, NewArgReader(call.Response().Arg2Reader()).Read(new([]byte)), "Read arg2 failed")
+		require.NoError(t, NewArgReader(call.Response().Arg3Reader()).Read(&resArg3), "Read arg3 failed")
+		assert.True(t, bytes.Equal(append([]byte("first"), large...), resArg3), "arg3 mismatch")
+	})
+}
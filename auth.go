@@ -0,0 +1,71 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import "golang.org/x/net/context"
+
+// AuthProvider provides the credentials sent to remote peers when creating
+// outbound connections. Credentials are sent in the init handshake, so the
+// remote peer can authenticate the connection before any calls are made on it.
+type AuthProvider interface {
+	// Credentials returns the credentials for a new connection to hostPort.
+	// If it returns an error, the connection is not created.
+	Credentials(ctx context.Context, hostPort string) (string, error)
+}
+
+// AuthValidator authenticates inbound connections using the credentials sent
+// by the remote peer during the init handshake.
+type AuthValidator interface {
+	// Validate is called with the credentials sent by the remote peer, which
+	// are empty if the peer did not send any. If it returns an error, the
+	// connection is rejected and no calls can be made on it.
+	Validate(ctx context.Context, remotePeer PeerInfo, credentials string) error
+}
+
+// An AuthProviderFunc is an adapter to allow the use of ordinary functions as
+// an AuthProvider.
+type AuthProviderFunc func(ctx context.Context, hostPort string) (string, error)
+
+// Credentials calls f(ctx, hostPort).
+func (f AuthProviderFunc) Credentials(ctx context.Context, hostPort string) (string, error) {
+	return f(ctx, hostPort)
+}
+
+// An AuthValidatorFunc is an adapter to allow the use of ordinary functions as
+// an AuthValidator.
+type AuthValidatorFunc func(ctx context.Context, remotePeer PeerInfo, credentials string) error
+
+// Validate calls f(ctx, remotePeer, credentials).
+func (f AuthValidatorFunc) Validate(ctx context.Context, remotePeer PeerInfo, credentials string) error {
+	return f(ctx, remotePeer, credentials)
+}
+
+// validateAuth authenticates an inbound connection using the init params
+// sent by the remote peer. It returns nil if no validator is configured.
+func validateAuth(ctx context.Context, validator AuthValidator, remotePeer PeerInfo, remote initParams) error {
+	if validator == nil {
+		return nil
+	}
+	if err := validator.Validate(ctx, remotePeer, remote[InitParamAuth]); err != nil {
+		return NewSystemError(ErrCodeDeclined, "connection authentication failed: %v", err)
+	}
+	return nil
+}
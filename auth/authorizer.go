@@ -0,0 +1,155 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package auth provides a tchannel.CallAuthorizer that authenticates callers
+// using JWTs or SPIFFE certificates, and allows or denies their calls using
+// rules for each caller, service and method.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/uber/tchannel-go"
+
+	"golang.org/x/net/context"
+)
+
+// ErrNoCredentials is returned by a Verifier when the call does not carry the
+// identity material that it verifies, e.g. there is no auth token.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Verifier authenticates the caller of an inbound call using its identity
+// material, and returns the caller's verified identity.
+type Verifier interface {
+	Verify(ctx context.Context, id tchannel.CallIdentity) (string, error)
+}
+
+// A VerifierFunc is an adapter to allow the use of ordinary functions as a
+// Verifier.
+type VerifierFunc func(ctx context.Context, id tchannel.CallIdentity) (string, error)
+
+// Verify calls f(ctx, id).
+func (f VerifierFunc) Verify(ctx context.Context, id tchannel.CallIdentity) (string, error) {
+	return f(ctx, id)
+}
+
+// Rule allows or denies calls from a caller to a service's method. Each field
+// matches any value if it's empty or "*", and matches any value with the
+// given prefix if it ends with "*". Otherwise, the value must match exactly.
+type Rule struct {
+	Caller  string
+	Service string
+	Method  string
+
+	// Deny denies matching calls, rather than allowing them.
+	Deny bool
+}
+
+func (r Rule) matches(caller, service, method string) bool {
+	return matchPattern(r.Caller, caller) &&
+		matchPattern(r.Service, service) &&
+		matchPattern(r.Method, method)
+}
+
+func matchPattern(pattern, value string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(value, pattern[:len(pattern)-1])
+	}
+	return pattern == value
+}
+
+// Options configures an Authorizer.
+type Options struct {
+	// Verifiers authenticate the caller of each call. They're tried in order,
+	// and the identity returned by the first one that succeeds is used to
+	// match Rules. If no verifiers are set, the caller name sent by the
+	// caller is used, without being authenticated.
+	Verifiers []Verifier
+
+	// Rules allow or deny calls. The first rule that matches a call is used.
+	Rules []Rule
+
+	// DefaultAllow allows calls that don't match any rule. By default, they
+	// are denied.
+	DefaultAllow bool
+}
+
+// Authorizer is a tchannel.CallAuthorizer that authenticates callers using
+// Verifiers, and authorizes their calls using Rules.
+type Authorizer struct {
+	opts Options
+}
+
+var _ tchannel.CallAuthorizer = (*Authorizer)(nil)
+
+// New returns an Authorizer using the given options.
+func New(opts Options) *Authorizer {
+	return &Authorizer{opts: opts}
+}
+
+// Authorize authenticates the caller of a call, and returns an error if the
+// caller is not authenticated or is not allowed to make the call.
+func (a *Authorizer) Authorize(ctx context.Context, id tchannel.CallIdentity) error {
+	caller, err := a.verify(ctx, id)
+	if err != nil {
+		return fmt.Errorf("caller not authenticated: %v", err)
+	}
+
+	if !a.allowed(caller, id.Service, id.Method) {
+		return fmt.Errorf("%v is not allowed to call %v::%v", caller, id.Service, id.Method)
+	}
+	return nil
+}
+
+// allowed returns whether the first rule that matches the call allows it.
+func (a *Authorizer) allowed(caller, service, method string) bool {
+	for _, r := range a.opts.Rules {
+		if r.matches(caller, service, method) {
+			return !r.Deny
+		}
+	}
+	return a.opts.DefaultAllow
+}
+
+// verify returns the identity of the caller from the first verifier that
+// succeeds. If every verifier fails, the first error other than
+// ErrNoCredentials is returned.
+func (a *Authorizer) verify(ctx context.Context, id tchannel.CallIdentity) (string, error) {
+	if len(a.opts.Verifiers) == 0 {
+		return id.Caller, nil
+	}
+
+	err := ErrNoCredentials
+	for _, v := range a.opts.Verifiers {
+		caller, verr := v.Verify(ctx, id)
+		if verr == nil {
+			return caller, nil
+		}
+		if err == ErrNoCredentials {
+			err = verr
+		}
+	}
+	return "", err
+}
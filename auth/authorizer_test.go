@@ -0,0 +1,180 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestAuthorizerRules(t *testing.T) {
+	a := New(Options{
+		Rules: []Rule{
+			{Caller: "admin"},
+			{Method: "Admin::*", Deny: true},
+			{Caller: "web", Service: "users", Method: "Users::get"},
+			{Caller: "batch-*", Service: "users"},
+		},
+	})
+
+	tests := []struct {
+		caller  string
+		service string
+		method  string
+		allowed bool
+	}{
+		{"admin", "users", "Admin::delete", true},
+		{"web", "users", "Admin::delete", false},
+		{"web", "users", "Users::get", true},
+		{"web", "users", "Users::put", false},
+		{"web", "orders", "Users::get", false},
+		{"batch-nightly", "users", "Users::put", true},
+		{"batch-nightly", "users", "Admin::reset", false},
+		{"unknown", "users", "Users::get", false},
+	}
+
+	for _, tt := range tests {
+		err := a.Authorize(context.Background(), tchannel.CallIdentity{
+			Caller:  tt.caller,
+			Service: tt.service,
+			Method:  tt.method,
+		})
+		if tt.allowed {
+			assert.NoError(t, err, "%v should be allowed to call %v::%v", tt.caller, tt.service, tt.method)
+		} else {
+			assert.Error(t, err, "%v should not be allowed to call %v::%v", tt.caller, tt.service, tt.method)
+		}
+	}
+}
+
+func TestAuthorizerDefaultAllow(t *testing.T) {
+	a := New(Options{
+		Rules:        []Rule{{Caller: "blocked", Deny: true}},
+		DefaultAllow: true,
+	})
+
+	assert.NoError(t, a.Authorize(context.Background(), tchannel.CallIdentity{Caller: "web"}),
+		"Calls that match no rule should be allowed")
+	assert.Error(t, a.Authorize(context.Background(), tchannel.CallIdentity{Caller: "blocked"}),
+		"Calls that match a deny rule should be denied")
+}
+
+func TestAuthorizerVerifiers(t *testing.T) {
+	noCredentials := VerifierFunc(func(ctx context.Context, id tchannel.CallIdentity) (string, error) {
+		return "", ErrNoCredentials
+	})
+	invalid := VerifierFunc(func(ctx context.Context, id tchannel.CallIdentity) (string, error) {
+		return "", errors.New("invalid token")
+	})
+	valid := VerifierFunc(func(ctx context.Context, id tchannel.CallIdentity) (string, error) {
+		return "verified", nil
+	})
+
+	tests := []struct {
+		msg       string
+		verifiers []Verifier
+		wantErr   string
+	}{
+		{
+			msg:       "no credentials",
+			verifiers: []Verifier{noCredentials},
+			wantErr:   "caller not authenticated: no credentials",
+		},
+		{
+			msg:       "invalid credentials",
+			verifiers: []Verifier{noCredentials, invalid},
+			wantErr:   "caller not authenticated: invalid token",
+		},
+		{
+			msg:       "verified after failure",
+			verifiers: []Verifier{invalid, valid},
+		},
+		{
+			msg: "verified identity replaces caller name",
+			verifiers: []Verifier{VerifierFunc(func(ctx context.Context, id tchannel.CallIdentity) (string, error) {
+				return "other", nil
+			})},
+			wantErr: "other is not allowed to call svc::method",
+		},
+	}
+
+	for _, tt := range tests {
+		a := New(Options{
+			Verifiers: tt.verifiers,
+			Rules:     []Rule{{Caller: "verified"}},
+		})
+		err := a.Authorize(context.Background(), tchannel.CallIdentity{
+			Caller:  "verified",
+			Service: "svc",
+			Method:  "method",
+		})
+		if tt.wantErr == "" {
+			assert.NoError(t, err, "%v: unexpected error", tt.msg)
+		} else {
+			assert.EqualError(t, err, tt.wantErr, "%v: unexpected error", tt.msg)
+		}
+	}
+}
+
+func TestAuthorizerChannel(t *testing.T) {
+	key := []byte("secret")
+	now := time.Unix(1500000000, 0)
+
+	opts := testutils.NewOpts().NoRelay().SetServiceName("users")
+	opts.ChannelOptions.CallAuthorizer = New(Options{
+		Verifiers: []Verifier{NewJWTVerifier(JWTOptions{
+			Key:     key,
+			TimeNow: func() time.Time { return now },
+		})},
+		Rules: []Rule{{Caller: "web", Service: "users", Method: "echo"}},
+	})
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+		client := ts.NewClient(nil)
+
+		call := func(token string) error {
+			ctx, cancel := tchannel.NewContextBuilder(testutils.Timeout(time.Second)).
+				SetAuthToken(token).
+				Build()
+			defer cancel()
+
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), "users", "echo", nil, nil)
+			return err
+		}
+
+		require.NoError(t, call(signHS256(t, key, map[string]interface{}{"sub": "web"})), "Call from web failed")
+
+		err := call(signHS256(t, key, map[string]interface{}{"sub": "batch"}))
+		assert.Equal(t, tchannel.ErrCodeDeclined, tchannel.GetSystemErrorCode(err), "Call from batch should be declined")
+
+		err = call("")
+		assert.Equal(t, tchannel.ErrCodeDeclined, tchannel.GetSystemErrorCode(err), "Call without a token should be declined")
+	})
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/uber/tchannel-go"
+
+	"golang.org/x/net/context"
+)
+
+// JWTOptions configures the Verifier returned by NewJWTVerifier.
+type JWTOptions struct {
+	// Key is the key used to verify tokens: a []byte secret for HS256, an
+	// *rsa.PublicKey for RS256, or an *ecdsa.PublicKey (P-256) for ES256.
+	// Tokens must be signed with the algorithm that matches the key.
+	Key interface{}
+
+	// KeyFunc returns the key for the key ID ("kid") in a token's header,
+	// which allows keys to be rotated. It's used instead of Key if it's set.
+	KeyFunc func(kid string) (interface{}, error)
+
+	// Issuer, if set, must match the token's "iss" claim.
+	Issuer string
+
+	// Audience, if set, must be one of the token's "aud" claims.
+	Audience string
+
+	// Leeway is the clock skew allowed when checking the "exp" and "nbf"
+	// claims.
+	Leeway time.Duration
+
+	// TimeNow is a variable for overriding time.Now in unit tests.
+	// Note: This is not a stable part of the API and may change.
+	TimeNow func() time.Time
+}
+
+type jwtVerifier struct {
+	opts JWTOptions
+}
+
+// NewJWTVerifier returns a Verifier that authenticates callers using the JWT
+// in the call's AuthToken transport header. The caller's identity is the
+// token's "sub" claim.
+func NewJWTVerifier(opts JWTOptions) Verifier {
+	if opts.TimeNow == nil {
+		opts.TimeNow = time.Now
+	}
+	return &jwtVerifier{opts: opts}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string      `json:"sub"`
+	Issuer    string      `json:"iss"`
+	Audience  jwtAudience `json:"aud"`
+	ExpiresAt *int64      `json:"exp"`
+	NotBefore *int64      `json:"nbf"`
+}
+
+// jwtAudience is the "aud" claim, which may be a string or a list of strings.
+type jwtAudience []string
+
+func (a *jwtAudience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = jwtAudience{single}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(a))
+}
+
+func (v *jwtVerifier) Verify(ctx context.Context, id tchannel.CallIdentity) (string, error) {
+	if id.AuthToken == "" {
+		return "", ErrNoCredentials
+	}
+
+	parts := strings.Split(id.AuthToken, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed JWT")
+	}
+
+	var header jwtHeader
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return "", fmt.Errorf("invalid JWT header: %v", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("invalid JWT signature: %v", err)
+	}
+
+	key := v.opts.Key
+	if v.opts.KeyFunc != nil {
+		if key, err = v.opts.KeyFunc(header.Kid); err != nil {
+			return "", fmt.Errorf("no key for JWT: %v", err)
+		}
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return "", err
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return "", fmt.Errorf("invalid JWT claims: %v", err)
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return "", err
+	}
+	return claims.Subject, nil
+}
+
+func (v *jwtVerifier) validateClaims(claims jwtClaims) error {
+	now := v.opts.TimeNow()
+	if claims.ExpiresAt != nil && !now.Before(time.Unix(*claims.ExpiresAt, 0).Add(v.opts.Leeway)) {
+		return errors.New("JWT has expired")
+	}
+	if claims.NotBefore != nil && now.Before(time.Unix(*claims.NotBefore, 0).Add(-v.opts.Leeway)) {
+		return errors.New("JWT is not valid yet")
+	}
+	if v.opts.Issuer != "" && claims.Issuer != v.opts.Issuer {
+		return fmt.Errorf("unexpected JWT issuer %q", claims.Issuer)
+	}
+	if v.opts.Audience != "" && !claims.Audience.contains(v.opts.Audience) {
+		return errors.New("JWT is not for this audience")
+	}
+	if claims.Subject == "" {
+		return errors.New("JWT has no subject")
+	}
+	return nil
+}
+
+func (a jwtAudience) contains(audience string) bool {
+	for _, aud := range a {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeJWTSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// verifyJWTSignature verifies the signature of a JWT. The algorithm in the
+// token must match the type of the key, so a token can't choose a weaker
+// algorithm than the key is meant for.
+func verifyJWTSignature(alg string, key interface{}, signed string, sig []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch key := key.(type) {
+	case []byte:
+		if alg != "HS256" {
+			break
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return errors.New("invalid JWT signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		if alg != "RS256" {
+			break
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return errors.New("invalid JWT signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if alg != "ES256" {
+			break
+		}
+		if len(sig) != 64 {
+			return errors.New("invalid JWT signature")
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return errors.New("invalid JWT signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported JWT key type %T", key)
+	}
+	return fmt.Errorf("unexpected JWT algorithm %q", alg)
+}
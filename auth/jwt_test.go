@@ -0,0 +1,240 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func encodeJWT(t *testing.T, header, claims map[string]interface{}) string {
+	h, err := json.Marshal(header)
+	require.NoError(t, err, "Failed to marshal header")
+	c, err := json.Marshal(claims)
+	require.NoError(t, err, "Failed to marshal claims")
+	return base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+}
+
+func withSignature(signed string, sig []byte) string {
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func signHS256(t *testing.T, key []byte, claims map[string]interface{}) string {
+	signed := encodeJWT(t, map[string]interface{}{"alg": "HS256"}, claims)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signed))
+	return withSignature(signed, mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	signed := encodeJWT(t, map[string]interface{}{"alg": "RS256"}, claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err, "Failed to sign token")
+	return withSignature(signed, sig)
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signed := encodeJWT(t, map[string]interface{}{"alg": "ES256", "kid": kid}, claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err, "Failed to sign token")
+
+	sig := make([]byte, 64)
+	rb, sb := r.Bytes(), s.Bytes()
+	copy(sig[32-len(rb):32], rb)
+	copy(sig[64-len(sb):], sb)
+	return withSignature(signed, sig)
+}
+
+func verifyToken(v Verifier, token string) (string, error) {
+	return v.Verify(context.Background(), tchannel.CallIdentity{AuthToken: token})
+}
+
+func TestJWTVerifierAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Failed to generate RSA key")
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Failed to generate ECDSA key")
+	claims := map[string]interface{}{"sub": "caller"}
+
+	tests := []struct {
+		msg   string
+		key   interface{}
+		token string
+	}{
+		{msg: "HS256", key: []byte("secret"), token: signHS256(t, []byte("secret"), claims)},
+		{msg: "RS256", key: &rsaKey.PublicKey, token: signRS256(t, rsaKey, claims)},
+		{msg: "ES256", key: &ecKey.PublicKey, token: signES256(t, ecKey, "", claims)},
+	}
+
+	for _, tt := range tests {
+		for _, other := range tests {
+			caller, err := verifyToken(NewJWTVerifier(JWTOptions{Key: other.key}), tt.token)
+			if tt.msg == other.msg {
+				assert.NoError(t, err, "%v: verify failed", tt.msg)
+				assert.Equal(t, "caller", caller, "%v: unexpected caller", tt.msg)
+			} else {
+				assert.Error(t, err, "%v token should not be verified by %v key", tt.msg, other.msg)
+			}
+		}
+	}
+}
+
+func TestJWTVerifierSignature(t *testing.T) {
+	v := NewJWTVerifier(JWTOptions{Key: []byte("secret")})
+	token := signHS256(t, []byte("secret"), map[string]interface{}{"sub": "caller"})
+
+	tests := []struct {
+		msg   string
+		token string
+	}{
+		{msg: "wrong key", token: signHS256(t, []byte("other"), map[string]interface{}{"sub": "caller"})},
+		{msg: "malformed", token: "not-a-jwt"},
+		{msg: "tampered claims", token: encodeJWT(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "admin"}) + token[len(token)-43:]},
+		{msg: "unsigned", token: encodeJWT(t, map[string]interface{}{"alg": "none"}, map[string]interface{}{"sub": "caller"}) + "."},
+	}
+
+	for _, tt := range tests {
+		_, err := verifyToken(v, tt.token)
+		assert.Error(t, err, "%v: expected verify to fail", tt.msg)
+	}
+
+	_, err := verifyToken(v, "")
+	assert.Equal(t, ErrNoCredentials, err, "Missing token should return ErrNoCredentials")
+}
+
+func TestJWTVerifierClaims(t *testing.T) {
+	key := []byte("secret")
+	now := time.Unix(1500000000, 0)
+	v := NewJWTVerifier(JWTOptions{
+		Key:      key,
+		Issuer:   "issuer",
+		Audience: "users",
+		Leeway:   time.Minute,
+		TimeNow:  func() time.Time { return now },
+	})
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"sub": "caller",
+			"iss": "issuer",
+			"aud": []string{"orders", "users"},
+			"exp": now.Add(time.Hour).Unix(),
+			"nbf": now.Add(-time.Hour).Unix(),
+		}
+	}
+
+	tests := []struct {
+		msg     string
+		update  func(map[string]interface{})
+		wantErr error
+	}{
+		{msg: "valid", update: func(map[string]interface{}) {}},
+		{msg: "single audience", update: func(c map[string]interface{}) { c["aud"] = "users" }},
+		{msg: "expired within leeway", update: func(c map[string]interface{}) { c["exp"] = now.Add(-time.Second).Unix() }},
+		{
+			msg:     "expired",
+			update:  func(c map[string]interface{}) { c["exp"] = now.Add(-time.Hour).Unix() },
+			wantErr: errors.New("JWT has expired"),
+		},
+		{
+			msg:     "not valid yet",
+			update:  func(c map[string]interface{}) { c["nbf"] = now.Add(time.Hour).Unix() },
+			wantErr: errors.New("JWT is not valid yet"),
+		},
+		{
+			msg:     "wrong issuer",
+			update:  func(c map[string]interface{}) { c["iss"] = "other" },
+			wantErr: errors.New(`unexpected JWT issuer "other"`),
+		},
+		{
+			msg:     "wrong audience",
+			update:  func(c map[string]interface{}) { c["aud"] = "orders" },
+			wantErr: errors.New("JWT is not for this audience"),
+		},
+		{
+			msg:     "no subject",
+			update:  func(c map[string]interface{}) { delete(c, "sub") },
+			wantErr: errors.New("JWT has no subject"),
+		},
+	}
+
+	for _, tt := range tests {
+		claims := valid()
+		tt.update(claims)
+
+		caller, err := verifyToken(v, signHS256(t, key, claims))
+		if tt.wantErr != nil {
+			assert.Equal(t, tt.wantErr, err, "%v: unexpected error", tt.msg)
+			continue
+		}
+		if assert.NoError(t, err, "%v: verify failed", tt.msg) {
+			assert.Equal(t, "caller", caller, "%v: unexpected caller", tt.msg)
+		}
+	}
+}
+
+func TestJWTVerifierKeyFunc(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Failed to generate key")
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Failed to generate key")
+
+	keys := map[string]*ecdsa.PrivateKey{"old": oldKey, "new": newKey}
+	v := NewJWTVerifier(JWTOptions{
+		KeyFunc: func(kid string) (interface{}, error) {
+			key, ok := keys[kid]
+			if !ok {
+				return nil, errors.New("unknown key")
+			}
+			return &key.PublicKey, nil
+		},
+	})
+
+	for kid, key := range keys {
+		caller, err := verifyToken(v, signES256(t, key, kid, map[string]interface{}{"sub": kid}))
+		assert.NoError(t, err, "Verify with key %v failed", kid)
+		assert.Equal(t, kid, caller, "Unexpected caller")
+	}
+
+	_, err = verifyToken(v, signES256(t, oldKey, "new", map[string]interface{}{"sub": "caller"}))
+	assert.Error(t, err, "Token signed with a different key should fail")
+
+	_, err = verifyToken(v, signES256(t, oldKey, "unknown", map[string]interface{}{"sub": "caller"}))
+	assert.EqualError(t, err, "no key for JWT: unknown key", "Unexpected error for unknown key")
+}
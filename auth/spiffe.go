@@ -0,0 +1,105 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package auth
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/uber/tchannel-go"
+
+	"golang.org/x/net/context"
+)
+
+// SPIFFEOptions configures the Verifier returned by NewSPIFFEVerifier.
+type SPIFFEOptions struct {
+	// TrustDomains are the SPIFFE trust domains that callers may belong to.
+	// If none are set, callers from any trust domain are accepted.
+	TrustDomains []string
+
+	// Roots, if set, is used to verify the caller's certificate chain. If it's
+	// not set, the chain must be verified by the server's tls.Config (e.g.
+	// using tls.RequireAndVerifyClientCert), since the certificates would
+	// otherwise be accepted without verification.
+	Roots *x509.CertPool
+}
+
+type spiffeVerifier struct {
+	opts         SPIFFEOptions
+	trustDomains map[string]struct{}
+}
+
+// NewSPIFFEVerifier returns a Verifier that authenticates callers using the
+// SPIFFE ID (e.g. "spiffe://example.org/service") in the URI SAN of the
+// certificate that the caller presented over TLS. The caller's identity is
+// the SPIFFE ID.
+func NewSPIFFEVerifier(opts SPIFFEOptions) Verifier {
+	v := &spiffeVerifier{opts: opts}
+	if len(opts.TrustDomains) > 0 {
+		v.trustDomains = make(map[string]struct{}, len(opts.TrustDomains))
+		for _, td := range opts.TrustDomains {
+			v.trustDomains[td] = struct{}{}
+		}
+	}
+	return v
+}
+
+func (v *spiffeVerifier) Verify(ctx context.Context, id tchannel.CallIdentity) (string, error) {
+	if len(id.PeerCertificates) == 0 {
+		return "", ErrNoCredentials
+	}
+
+	leaf := id.PeerCertificates[0]
+	if v.opts.Roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, cert := range id.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		if _, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         v.opts.Roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}); err != nil {
+			return "", fmt.Errorf("invalid certificate: %v", err)
+		}
+	}
+
+	var spiffeID string
+	for _, uri := range leaf.URIs {
+		if uri.Scheme != "spiffe" {
+			continue
+		}
+		if spiffeID != "" {
+			return "", errors.New("certificate has more than one SPIFFE ID")
+		}
+		if v.trustDomains != nil {
+			if _, ok := v.trustDomains[uri.Host]; !ok {
+				return "", fmt.Errorf("SPIFFE ID %v is not in a trusted domain", uri)
+			}
+		}
+		spiffeID = uri.String()
+	}
+	if spiffeID == "" {
+		return "", errors.New("certificate has no SPIFFE ID")
+	}
+	return spiffeID, nil
+}
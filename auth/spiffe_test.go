@@ -0,0 +1,162 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newCertificate(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Failed to generate key")
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err, "Failed to create certificate")
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err, "Failed to parse certificate")
+	return cert, key
+}
+
+func newTestCA(t *testing.T) *testCA {
+	cert, key := newCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+func (ca *testCA) issue(t *testing.T, uris ...string) *x509.Certificate {
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "client"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, s := range uris {
+		u, err := url.Parse(s)
+		require.NoError(t, err, "Failed to parse URI")
+		template.URIs = append(template.URIs, u)
+	}
+
+	cert, _ := newCertificate(t, template, ca.cert, ca.key)
+	return cert
+}
+
+func TestSPIFFEVerifier(t *testing.T) {
+	ca := newTestCA(t)
+	otherCA := newTestCA(t)
+
+	tests := []struct {
+		msg     string
+		opts    SPIFFEOptions
+		certs   []*x509.Certificate
+		want    string
+		wantErr string
+	}{
+		{
+			msg:   "valid SPIFFE ID",
+			certs: []*x509.Certificate{ca.issue(t, "spiffe://example.org/web")},
+			want:  "spiffe://example.org/web",
+		},
+		{
+			msg:   "trusted domain",
+			opts:  SPIFFEOptions{TrustDomains: []string{"example.org"}},
+			certs: []*x509.Certificate{ca.issue(t, "https://example.org/web", "spiffe://example.org/web")},
+			want:  "spiffe://example.org/web",
+		},
+		{
+			msg:     "untrusted domain",
+			opts:    SPIFFEOptions{TrustDomains: []string{"example.org"}},
+			certs:   []*x509.Certificate{ca.issue(t, "spiffe://other.org/web")},
+			wantErr: "SPIFFE ID spiffe://other.org/web is not in a trusted domain",
+		},
+		{
+			msg:     "no SPIFFE ID",
+			certs:   []*x509.Certificate{ca.issue(t, "https://example.org/web")},
+			wantErr: "certificate has no SPIFFE ID",
+		},
+		{
+			msg:     "multiple SPIFFE IDs",
+			certs:   []*x509.Certificate{ca.issue(t, "spiffe://example.org/web", "spiffe://example.org/admin")},
+			wantErr: "certificate has more than one SPIFFE ID",
+		},
+		{
+			msg:   "verified by roots",
+			opts:  SPIFFEOptions{Roots: ca.pool},
+			certs: []*x509.Certificate{ca.issue(t, "spiffe://example.org/web")},
+			want:  "spiffe://example.org/web",
+		},
+		{
+			msg:     "not verified by roots",
+			opts:    SPIFFEOptions{Roots: ca.pool},
+			certs:   []*x509.Certificate{otherCA.issue(t, "spiffe://example.org/web")},
+			wantErr: "invalid certificate",
+		},
+		{
+			msg:     "no certificates",
+			wantErr: ErrNoCredentials.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		v := NewSPIFFEVerifier(tt.opts)
+		got, err := v.Verify(context.Background(), tchannel.CallIdentity{PeerCertificates: tt.certs})
+		if tt.wantErr != "" {
+			if assert.Error(t, err, "%v: expected error", tt.msg) {
+				assert.Contains(t, err.Error(), tt.wantErr, "%v: unexpected error", tt.msg)
+			}
+			continue
+		}
+		if assert.NoError(t, err, "%v: verify failed", tt.msg) {
+			assert.Equal(t, tt.want, got, "%v: unexpected identity", tt.msg)
+		}
+	}
+}
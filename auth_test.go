@@ -0,0 +1,118 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestConnectionAuth(t *testing.T) {
+	const token = "secret-token"
+
+	validator := AuthValidatorFunc(func(ctx context.Context, remotePeer PeerInfo, credentials string) error {
+		if credentials != token {
+			return errors.New("invalid token")
+		}
+		return nil
+	})
+
+	tests := []struct {
+		msg         string
+		credentials string
+		wantErr     bool
+	}{
+		{msg: "no credentials", wantErr: true},
+		{msg: "invalid credentials", credentials: "wrong", wantErr: true},
+		{msg: "valid credentials", credentials: token},
+	}
+
+	for _, tt := range tests {
+		opts := testutils.NewOpts().NoRelay()
+		opts.DefaultConnectionOptions.AuthValidator = validator
+		if tt.wantErr {
+			opts.AddLogFilter("Failed during connection handshake.", 1)
+		}
+		testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+			testutils.RegisterEcho(ts.Server(), nil)
+
+			clientOpts := testutils.NewOpts()
+			if tt.credentials != "" {
+				clientOpts.DefaultConnectionOptions.AuthProvider = AuthProviderFunc(func(ctx context.Context, hostPort string) (string, error) {
+					assert.Equal(t, ts.HostPort(), hostPort, "Unexpected hostPort for credentials")
+					return tt.credentials, nil
+				})
+			}
+			if tt.wantErr {
+				clientOpts.AddLogFilter("Failed during connection handshake.", 1)
+			}
+			client := ts.NewClient(clientOpts)
+
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+
+			err := client.Ping(ctx, ts.HostPort())
+			if !tt.wantErr {
+				require.NoError(t, err, "%v: expected authenticated connection to succeed", tt.msg)
+				testutils.AssertEcho(t, client, ts.HostPort(), ts.ServiceName())
+				return
+			}
+
+			require.Error(t, err, "%v: expected unauthenticated connection to be rejected", tt.msg)
+			assert.Equal(t, ErrCodeDeclined, GetSystemErrorCode(err), "%v: unexpected error code", tt.msg)
+			assert.Contains(t, err.Error(), "invalid token", "%v: unexpected error", tt.msg)
+			assert.False(t, client.Peers().GetOrAdd(ts.HostPort()).HasActiveConnection(),
+				"%v: rejected connection should not be active", tt.msg)
+		})
+	}
+}
+
+func TestConnectionAuthProviderError(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	opts.DefaultConnectionOptions.AuthValidator = AuthValidatorFunc(func(ctx context.Context, remotePeer PeerInfo, credentials string) error {
+		t.Errorf("Validator should not be called when the provider fails")
+		return nil
+	})
+	opts.AddLogFilter("Failed during connection handshake.", 1)
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		providerErr := errors.New("no credentials available")
+		clientOpts := testutils.NewOpts().AddLogFilter("Failed during connection handshake.", 1)
+		clientOpts.DefaultConnectionOptions.AuthProvider = AuthProviderFunc(func(ctx context.Context, hostPort string) (string, error) {
+			return "", providerErr
+		})
+		client := ts.NewClient(clientOpts)
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		_, err := client.Connect(ctx, ts.HostPort())
+		assert.Equal(t, providerErr, err, "Connect should fail with the provider error")
+	})
+}
@@ -0,0 +1,290 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber-go/atomic"
+)
+
+// BandwidthLimits limits the rate at which a channel's connections send and
+// receive call frames, in bytes per second. A zero limit means unlimited.
+// Connections wait before writing or reading a frame once a channel or peer
+// limit is exceeded, so a peer making bulk transfers can't starve
+// latency-sensitive calls sharing the same host. Only call frames count
+// against the limits, so pings and errors are never delayed.
+type BandwidthLimits struct {
+	// SendBytesPerSecond limits the bytes sent across all connections.
+	SendBytesPerSecond int
+
+	// ReceiveBytesPerSecond limits the bytes received across all connections.
+	ReceiveBytesPerSecond int
+
+	// PeerSendBytesPerSecond limits the bytes sent to each peer, across all
+	// connections to that peer.
+	PeerSendBytesPerSecond int
+
+	// PeerReceiveBytesPerSecond limits the bytes received from each peer,
+	// across all connections from that peer.
+	PeerReceiveBytesPerSecond int
+
+	// CallSendBytesPerSecond limits the bytes sent by each call. Calls over
+	// the limit wait before sending each fragment, without delaying other
+	// calls on the same connection.
+	CallSendBytesPerSecond int
+
+	// CallReceiveBytesPerSecond limits the bytes received by each call. Calls
+	// over the limit wait before reading each fragment, without delaying
+	// other calls on the same connection.
+	CallReceiveBytesPerSecond int
+}
+
+// bandwidthLimiter is a token bucket that allows bursts of up to a second of
+// bytes. Frames may be larger than the bucket, in which case the bucket goes
+// into debt and the frame waits for it to be repaid.
+type bandwidthLimiter struct {
+	timeNow func() time.Time
+	rate    atomic.Int64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newBandwidthLimiter(timeNow func() time.Time, rate int) *bandwidthLimiter {
+	l := &bandwidthLimiter{timeNow: timeNow}
+	l.setRate(rate)
+	return l
+}
+
+func (l *bandwidthLimiter) setRate(rate int) {
+	l.mu.Lock()
+	l.rate.Store(int64(rate))
+	l.tokens = float64(rate)
+	l.last = l.timeNow()
+	l.mu.Unlock()
+}
+
+// reserve takes n bytes from the bucket, and returns how long the caller must
+// wait before using them.
+func (l *bandwidthLimiter) reserve(n int) time.Duration {
+	rate := l.rate.Load()
+	if rate <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.timeNow()
+	l.tokens += now.Sub(l.last).Seconds() * float64(rate)
+	if l.tokens > float64(rate) {
+		l.tokens = float64(rate)
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / float64(rate) * float64(time.Second))
+}
+
+// peerBandwidth limits the bandwidth used by all connections to a peer.
+type peerBandwidth struct {
+	send    *bandwidthLimiter
+	receive *bandwidthLimiter
+}
+
+// callBandwidth limits the bandwidth used by a single call.
+type callBandwidth struct {
+	send    *bandwidthLimiter
+	receive *bandwidthLimiter
+}
+
+// channelBandwidth limits the bandwidth used by a channel's connections, and
+// holds the limits used for each peer and call.
+type channelBandwidth struct {
+	timeNow func() time.Time
+	send    *bandwidthLimiter
+	receive *bandwidthLimiter
+
+	sync.RWMutex
+	limits BandwidthLimits
+}
+
+func newChannelBandwidth(timeNow func() time.Time, limits BandwidthLimits) *channelBandwidth {
+	return &channelBandwidth{
+		timeNow: timeNow,
+		send:    newBandwidthLimiter(timeNow, limits.SendBytesPerSecond),
+		receive: newBandwidthLimiter(timeNow, limits.ReceiveBytesPerSecond),
+		limits:  limits,
+	}
+}
+
+func (b *channelBandwidth) newPeer() *peerBandwidth {
+	b.RLock()
+	defer b.RUnlock()
+
+	return &peerBandwidth{
+		send:    newBandwidthLimiter(b.timeNow, b.limits.PeerSendBytesPerSecond),
+		receive: newBandwidthLimiter(b.timeNow, b.limits.PeerReceiveBytesPerSecond),
+	}
+}
+
+// newCall returns the limiters for a new call, or nil if calls are not limited.
+func (b *channelBandwidth) newCall() *callBandwidth {
+	b.RLock()
+	defer b.RUnlock()
+
+	if b.limits.CallSendBytesPerSecond <= 0 && b.limits.CallReceiveBytesPerSecond <= 0 {
+		return nil
+	}
+	return &callBandwidth{
+		send:    newBandwidthLimiter(b.timeNow, b.limits.CallSendBytesPerSecond),
+		receive: newBandwidthLimiter(b.timeNow, b.limits.CallReceiveBytesPerSecond),
+	}
+}
+
+// BandwidthLimits returns the channel's current bandwidth limits.
+func (ch *Channel) BandwidthLimits() BandwidthLimits {
+	ch.bandwidth.RLock()
+	defer ch.bandwidth.RUnlock()
+
+	return ch.bandwidth.limits
+}
+
+// SetBandwidthLimits replaces the channel's bandwidth limits, including the
+// per-peer limits of existing peers. The per-call limits apply to new calls.
+func (ch *Channel) SetBandwidthLimits(limits BandwidthLimits) {
+	b := ch.bandwidth
+	b.Lock()
+	b.limits = limits
+	b.send.setRate(limits.SendBytesPerSecond)
+	b.receive.setRate(limits.ReceiveBytesPerSecond)
+	b.Unlock()
+
+	root := ch.RootPeers()
+	root.RLock()
+	defer root.RUnlock()
+	for _, p := range root.peersByHostPort {
+		p.bandwidth.send.setRate(limits.PeerSendBytesPerSecond)
+		p.bandwidth.receive.setRate(limits.PeerReceiveBytesPerSecond)
+	}
+}
+
+// isCallFrame returns whether the frame is part of a call, and so counts
+// against the bandwidth limits.
+func isCallFrame(f *Frame) bool {
+	switch f.Header.messageType {
+	case messageTypeCallReq, messageTypeCallReqContinue, messageTypeCallRes, messageTypeCallResContinue:
+		return true
+	}
+	return false
+}
+
+// throttleSend waits until the frame can be sent within the channel and peer
+// bandwidth limits, or the connection is stopped.
+func (c *Connection) throttleSend(f *Frame) {
+	if !isCallFrame(f) {
+		return
+	}
+
+	n := f.Header.frameSize()
+	delay := c.bandwidth.send.reserve(n)
+	if peer, ok := c.peerBandwidth.Load().(*peerBandwidth); ok {
+		delay = maxDuration(delay, peer.send.reserve(n))
+	}
+	c.throttle(delay)
+}
+
+// throttleReceive waits until the frame can be received within the channel
+// and peer bandwidth limits, or the connection is stopped.
+func (c *Connection) throttleReceive(f *Frame) {
+	if !isCallFrame(f) {
+		return
+	}
+
+	n := f.Header.frameSize()
+	delay := c.bandwidth.receive.reserve(n)
+	if peer, ok := c.peerBandwidth.Load().(*peerBandwidth); ok {
+		delay = maxDuration(delay, peer.receive.reserve(n))
+	}
+	c.throttle(delay)
+}
+
+func (c *Connection) throttle(delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-c.stopCh:
+	}
+}
+
+// throttleSend waits until the frame can be sent within the call's bandwidth
+// limit. It's called on the call's goroutine, so only this call is delayed.
+func (mex *messageExchange) throttleSend(f *Frame) error {
+	if mex.bandwidth == nil {
+		return nil
+	}
+	return mex.throttle(mex.bandwidth.send.reserve(f.Header.frameSize()))
+}
+
+// throttleReceive waits until the frame can be received within the call's
+// bandwidth limit. It's called on the call's goroutine, so only this call is
+// delayed.
+func (mex *messageExchange) throttleReceive(f *Frame) error {
+	if mex.bandwidth == nil {
+		return nil
+	}
+	return mex.throttle(mex.bandwidth.receive.reserve(f.Header.frameSize()))
+}
+
+func (mex *messageExchange) throttle(delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-mex.ctx.Done():
+		return GetContextError(mex.ctx.Err())
+	case <-mex.errCh.c:
+		return mex.errCh.err
+	}
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
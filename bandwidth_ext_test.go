@@ -0,0 +1,117 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidthLimits(t *testing.T) {
+	const rate = 50000
+	arg3 := testutils.RandBytes(20000)
+
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		client := ts.NewClient(&testutils.ChannelOpts{
+			ChannelOptions: ChannelOptions{
+				BandwidthLimits: BandwidthLimits{PeerSendBytesPerSecond: rate},
+			},
+		})
+		assert.Equal(t, BandwidthLimits{PeerSendBytesPerSecond: rate}, client.BandwidthLimits())
+
+		sendAll := func() time.Duration {
+			start := time.Now()
+			for i := 0; i < 5; i++ {
+				ctx, cancel := NewContext(testutils.Timeout(5 * time.Second))
+				_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, arg3)
+				cancel()
+				require.NoError(t, err, "Call failed")
+			}
+			return time.Since(start)
+		}
+
+		// 100KB are sent, of which the first 50KB are within the burst, so
+		// sending should take around a second.
+		elapsed := sendAll()
+		assert.True(t, elapsed > 800*time.Millisecond, "Sending should be throttled, took %v", elapsed)
+
+		client.SetBandwidthLimits(BandwidthLimits{})
+		elapsed = sendAll()
+		assert.True(t, elapsed < 500*time.Millisecond, "Sending should not be throttled, took %v", elapsed)
+	})
+}
+
+func TestBandwidthLimitsPerCall(t *testing.T) {
+	const rate = 50000
+	bulk := testutils.RandBytes(100000)
+
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		client := ts.NewClient(&testutils.ChannelOpts{
+			ChannelOptions: ChannelOptions{
+				BandwidthLimits: BandwidthLimits{CallSendBytesPerSecond: rate},
+			},
+		})
+
+		call := func(arg3 []byte) (time.Duration, error) {
+			ctx, cancel := NewContext(testutils.Timeout(5 * time.Second))
+			defer cancel()
+
+			start := time.Now()
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, arg3)
+			return time.Since(start), err
+		}
+
+		bulkDone := make(chan time.Duration)
+		go func() {
+			elapsed, err := call(bulk)
+			assert.NoError(t, err, "Bulk call failed")
+			bulkDone <- elapsed
+		}()
+
+		// Calls sharing the connection with the throttled bulk call have their
+		// own limit, so they aren't delayed by it.
+		time.Sleep(testutils.Timeout(100 * time.Millisecond))
+		for i := 0; i < 3; i++ {
+			elapsed, err := call([]byte("small"))
+			require.NoError(t, err, "Call failed")
+			assert.True(t, elapsed < 300*time.Millisecond, "Small call should not be throttled, took %v", elapsed)
+		}
+
+		// 100KB are sent, of which the first 50KB are within the burst, so
+		// sending should take around a second.
+		elapsed := <-bulkDone
+		assert.True(t, elapsed > 800*time.Millisecond, "Bulk call should be throttled, took %v", elapsed)
+	})
+}
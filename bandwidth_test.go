@@ -0,0 +1,99 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBandwidthLimiterUnlimited(t *testing.T) {
+	l := newBandwidthLimiter(time.Now, 0)
+	assert.Equal(t, time.Duration(0), l.reserve(MaxFrameSize), "Unlimited limiter should not delay")
+}
+
+func TestBandwidthLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := newBandwidthLimiter(func() time.Time { return now }, 1000 /* rate */)
+
+	// A second of bytes is available immediately.
+	assert.Equal(t, time.Duration(0), l.reserve(600), "Unexpected delay within the burst")
+	assert.Equal(t, time.Duration(0), l.reserve(400), "Unexpected delay within the burst")
+
+	// Frames beyond the burst wait for the bytes to be repaid at the rate.
+	assert.Equal(t, 100*time.Millisecond, l.reserve(100), "Unexpected delay after the burst")
+	assert.Equal(t, 2100*time.Millisecond, l.reserve(2000), "Frames larger than the burst should be delayed")
+
+	// Bytes accrue over time, up to the burst.
+	now = now.Add(2100*time.Millisecond + time.Minute)
+	assert.Equal(t, time.Duration(0), l.reserve(1000), "Unexpected delay within the burst")
+	assert.Equal(t, 500*time.Millisecond, l.reserve(500), "Unexpected delay after the burst")
+
+	// Changing the rate resets the bucket.
+	l.setRate(2000)
+	assert.Equal(t, time.Duration(0), l.reserve(2000), "Unexpected delay within the new burst")
+	assert.Equal(t, 500*time.Millisecond, l.reserve(1000), "Unexpected delay at the new rate")
+
+	l.setRate(0)
+	assert.Equal(t, time.Duration(0), l.reserve(MaxFrameSize), "Unlimited limiter should not delay")
+}
+
+func TestBandwidthOnlyLimitsCallFrames(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := &Connection{stopCh: make(chan struct{})}
+	c.bandwidth = newChannelBandwidth(func() time.Time { return now }, BandwidthLimits{
+		SendBytesPerSecond:    1000,
+		ReceiveBytesPerSecond: 1000,
+	})
+
+	tests := []struct {
+		msgType messageType
+		limited bool
+	}{
+		{messageTypeCallReq, true},
+		{messageTypeCallReqContinue, true},
+		{messageTypeCallRes, true},
+		{messageTypeCallResContinue, true},
+		{messageTypePingReq, false},
+		{messageTypePingRes, false},
+		{messageTypeError, false},
+	}
+
+	for _, tt := range tests {
+		f := NewFrame(100)
+		f.Header.messageType = tt.msgType
+		f.Header.SetPayloadSize(100)
+
+		c.bandwidth.send.setRate(1000)
+		c.bandwidth.receive.setRate(1000)
+		c.throttleSend(f)
+		c.throttleReceive(f)
+
+		want := 1000.0
+		if tt.limited {
+			want -= float64(f.Header.frameSize())
+		}
+		assert.Equal(t, want, c.bandwidth.send.tokens, "Unexpected send bytes counted for %v", tt.msgType)
+		assert.Equal(t, want, c.bandwidth.receive.tokens, "Unexpected receive bytes counted for %v", tt.msgType)
+	}
+}
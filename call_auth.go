@@ -0,0 +1,93 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"crypto/x509"
+
+	"golang.org/x/net/context"
+)
+
+// CallIdentity is the identity material of the caller of an inbound call,
+// which is passed to a CallAuthorizer.
+type CallIdentity struct {
+	// Caller is the calling service from the CallerName transport header.
+	// It's set by the caller, so it should not be trusted on its own.
+	Caller string
+
+	// Service and Method are the service and method being called.
+	Service string
+	Method  string
+
+	// RemotePeer is the peer that sent the call.
+	RemotePeer PeerInfo
+
+	// PeerCertificates is the certificate chain presented by the remote peer,
+	// leaf first, if the call was received over a TLS connection.
+	PeerCertificates []*x509.Certificate
+
+	// AuthToken is the token from the AuthToken transport header, if any.
+	AuthToken string
+}
+
+// CallAuthorizer authenticates and authorizes inbound calls before they're
+// dispatched to their handler, so handlers don't need to check the caller
+// themselves. The auth package provides a CallAuthorizer that verifies JWTs
+// and SPIFFE certificates and applies per-caller rules.
+type CallAuthorizer interface {
+	// Authorize is called with the identity of the caller of each inbound
+	// call. If it returns an error, the call is failed without running the
+	// handler. SystemErrors are returned to the caller as-is, while other
+	// errors are returned as declined errors.
+	Authorize(ctx context.Context, id CallIdentity) error
+}
+
+// A CallAuthorizerFunc is an adapter to allow the use of ordinary functions as
+// a CallAuthorizer.
+type CallAuthorizerFunc func(ctx context.Context, id CallIdentity) error
+
+// Authorize calls f(ctx, id).
+func (f CallAuthorizerFunc) Authorize(ctx context.Context, id CallIdentity) error {
+	return f(ctx, id)
+}
+
+// authorizeCall runs the channel's CallAuthorizer for the given inbound call.
+func (c *Connection) authorizeCall(call *InboundCall) error {
+	id := CallIdentity{
+		Caller:     call.CallerName(),
+		Service:    call.ServiceName(),
+		Method:     call.MethodString(),
+		RemotePeer: call.RemotePeer(),
+		AuthToken:  call.AuthToken(),
+	}
+	if state, ok := c.tlsConnectionState(); ok {
+		id.PeerCertificates = state.PeerCertificates
+	}
+
+	err := c.callAuthorizer.Authorize(call.mex.ctx, id)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(SystemError); ok {
+		return err
+	}
+	return NewSystemError(ErrCodeDeclined, "call not authorized: %v", err)
+}
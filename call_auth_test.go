@@ -0,0 +1,139 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"crypto/tls"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"golang.org/x/net/context"
+)
+
+func TestCallAuthorizer(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		ids []CallIdentity
+	)
+	authorizer := CallAuthorizerFunc(func(ctx context.Context, id CallIdentity) error {
+		mu.Lock()
+		ids = append(ids, id)
+		mu.Unlock()
+
+		switch id.AuthToken {
+		case "valid":
+			return nil
+		case "busy":
+			return ErrServerBusy
+		default:
+			return errors.New("invalid token")
+		}
+	})
+
+	tests := []struct {
+		msg     string
+		token   string
+		wantErr error
+	}{
+		{msg: "no token", wantErr: NewSystemError(ErrCodeDeclined, "call not authorized: invalid token")},
+		{msg: "valid token", token: "valid"},
+		{msg: "system error", token: "busy", wantErr: ErrServerBusy},
+	}
+
+	opts := testutils.NewOpts().NoRelay().SetServiceName("svc")
+	opts.ChannelOptions.CallAuthorizer = authorizer
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		var handled atomic.Int32
+		testutils.RegisterEcho(ts.Server(), func() { handled.Inc() })
+
+		client := ts.NewClient(testutils.NewOpts().SetServiceName("caller"))
+		for _, tt := range tests {
+			ctx, cancel := NewContextBuilder(testutils.Timeout(time.Second)).
+				SetAuthToken(tt.token).
+				Build()
+
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), "svc", "echo", nil, nil)
+			cancel()
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err, "%v: unexpected error", tt.msg)
+			} else {
+				assert.NoError(t, err, "%v: call failed", tt.msg)
+			}
+		}
+		assert.Equal(t, int32(1), handled.Load(), "Only authorized calls should be handled")
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, ids, len(tests), "Authorizer should be called for every call")
+		for i, id := range ids {
+			assert.Equal(t, "caller", id.Caller, "Unexpected caller")
+			assert.Equal(t, "svc", id.Service, "Unexpected service")
+			assert.Equal(t, "echo", id.Method, "Unexpected method")
+			assert.Equal(t, tests[i].token, id.AuthToken, "Unexpected auth token")
+			assert.Equal(t, client.PeerInfo().ProcessName, id.RemotePeer.ProcessName, "Unexpected remote peer")
+			assert.Nil(t, id.PeerCertificates, "Expected no certificates without TLS")
+		}
+	})
+}
+
+func TestCallAuthorizerPeerCertificates(t *testing.T) {
+	serverCA := newTestCA(t, "server-ca")
+	clientCA := newTestCA(t, "client-ca")
+
+	var callerCN atomic.String
+	server, err := NewChannel("tls-server", &ChannelOptions{
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{*serverCA.issue(t, "server")},
+			ClientCAs:    clientCA.pool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+		},
+		CallAuthorizer: CallAuthorizerFunc(func(ctx context.Context, id CallIdentity) error {
+			if len(id.PeerCertificates) == 0 {
+				return errors.New("no peer certificates")
+			}
+			callerCN.Store(id.PeerCertificates[0].Subject.CommonName)
+			return nil
+		}),
+	})
+	require.NoError(t, err, "NewChannel failed")
+	defer server.Close()
+	require.NoError(t, server.ListenAndServe("127.0.0.1:0"), "ListenAndServe failed")
+	testutils.RegisterEcho(server, nil)
+
+	client := testutils.NewClient(t, testutils.NewOpts().SetTLSConfig(&tls.Config{
+		RootCAs:      serverCA.pool,
+		Certificates: []tls.Certificate{*clientCA.issue(t, "authorized-client")},
+	}))
+	defer client.Close()
+
+	require.NoError(t, testutils.CallEcho(client, server.PeerInfo().HostPort, "tls-server", nil), "Call failed")
+	assert.Equal(t, "authorized-client", callerCN.Load(), "Authorizer should get the client certificate")
+}
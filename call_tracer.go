@@ -0,0 +1,141 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// CallTracer creates spans for calls using a tracing API other than
+// OpenTracing, such as OpenTelemetry, so that the channel doesn't depend on
+// it. It's set using ChannelOptions.CallTracer, and the trace/otel package
+// provides a CallTracer for OpenTelemetry.
+// This is an unstable API - breaking changes are likely.
+type CallTracer interface {
+	// StartOutbound starts a span for an outbound call as a child of any span
+	// in ctx. The span context may be propagated in the call's transport
+	// headers, which only accept the TraceParent and TraceState keys.
+	StartOutbound(ctx context.Context, call CallTraceInfo, transportHeaders TraceCarrier) CallTraceSpan
+
+	// StartInbound starts a span for an inbound call, as a child of the span
+	// context propagated in its transport or application headers. It returns
+	// the context passed to the handler, which contains the span and any
+	// baggage propagated in the application headers.
+	StartInbound(ctx context.Context, call CallTraceInfo, transportHeaders, appHeaders TraceCarrier) (context.Context, CallTraceSpan)
+
+	// RecordRetry is called with the context passed to RunWithRetry when an
+	// attempt fails with err, and is retried.
+	RecordRetry(ctx context.Context, attempt int, err error)
+}
+
+// CallTraceSpan is a span started by a CallTracer.
+type CallTraceSpan interface {
+	// Inject propagates the span context, and any baggage, of an outbound
+	// call in its application headers.
+	Inject(appHeaders TraceCarrier)
+
+	// End ends the span at now. err is the system error the call failed
+	// with, if any, and failed is whether the call failed, which includes
+	// application errors.
+	End(now time.Time, err error, failed bool)
+}
+
+// TraceCarrier reads and writes the tracing keys of a call's headers. It has
+// the same methods as OpenTelemetry's propagation.TextMapCarrier.
+type TraceCarrier interface {
+	Get(key string) string
+	Set(key, value string)
+	Keys() []string
+}
+
+// CallTraceInfo describes the call a CallTracer starts a span for.
+type CallTraceInfo struct {
+	Service string
+	Method  string
+	Format  Format
+
+	// Caller is the service that made an inbound call. It's empty for
+	// outbound calls.
+	Caller string
+
+	// RemoteHostPort is the host:port of the connection's remote peer.
+	RemoteHostPort string
+
+	// StartTime is the time the call started.
+	StartTime time.Time
+}
+
+// transportHeadersCarrier is a TraceCarrier for the W3C trace context
+// transport headers.
+type transportHeadersCarrier transportHeaders
+
+func (c transportHeadersCarrier) Get(key string) string {
+	return c[TransportHeaderName(key)]
+}
+
+func (c transportHeadersCarrier) Set(key, value string) {
+	switch name := TransportHeaderName(key); name {
+	case TraceParent, TraceState:
+		c[name] = value
+	}
+}
+
+func (c transportHeadersCarrier) Keys() []string {
+	return []string{string(TraceParent), string(TraceState)}
+}
+
+// startOutboundCallTrace starts the CallTracer's span for an outbound call,
+// and propagates its span context in the transport headers. It returns nil if
+// there's no CallTracer.
+func (c *Connection) startOutboundCallTrace(ctx context.Context, serviceName, method string, call *OutboundCall, startTime time.Time) CallTraceSpan {
+	if c.callTracer == nil {
+		return nil
+	}
+	return c.callTracer.StartOutbound(ctx, CallTraceInfo{
+		Service:        serviceName,
+		Method:         method,
+		Format:         Format(call.callReq.Headers[ArgScheme]),
+		RemoteHostPort: c.remotePeerInfo.HostPort,
+		StartTime:      startTime,
+	}, transportHeadersCarrier(call.callReq.Headers))
+}
+
+// startInboundCallTrace starts the CallTracer's span for an inbound call,
+// using the span context and baggage in the transport and application headers,
+// and returns the context containing the span.
+func (call *InboundCall) startInboundCallTrace(ctx context.Context, headers map[string]string) context.Context {
+	if call.conn == nil || call.conn.callTracer == nil {
+		return ctx
+	}
+
+	ctx, span := call.conn.callTracer.StartInbound(ctx, CallTraceInfo{
+		Service:        call.ServiceName(),
+		Method:         call.MethodString(),
+		Format:         call.Format(),
+		Caller:         call.CallerName(),
+		RemoteHostPort: call.conn.remotePeerInfo.HostPort,
+		StartTime:      call.response.calledAt,
+	}, transportHeadersCarrier(call.headers), tracingHeadersCarrier(headers))
+	call.response.traceSpan = span
+	return ctx
+}
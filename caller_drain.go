@@ -0,0 +1,95 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sort"
+	"sync"
+)
+
+// errCallerDrained is returned to callers whose inbound calls are drained.
+// It uses ErrCodeDeclined so that callers retry the call on another peer.
+var errCallerDrained = NewSystemError(ErrCodeDeclined, "caller is drained")
+
+// callerDrain is the set of callers whose inbound calls are rejected.
+type callerDrain struct {
+	sync.RWMutex
+
+	callers map[string]struct{}
+}
+
+func (d *callerDrain) drain(callers []string) {
+	d.Lock()
+	if d.callers == nil {
+		d.callers = make(map[string]struct{}, len(callers))
+	}
+	for _, caller := range callers {
+		d.callers[caller] = struct{}{}
+	}
+	d.Unlock()
+}
+
+func (d *callerDrain) undrain(callers []string) {
+	d.Lock()
+	for _, caller := range callers {
+		delete(d.callers, caller)
+	}
+	d.Unlock()
+}
+
+func (d *callerDrain) isDrained(caller string) bool {
+	d.RLock()
+	_, drained := d.callers[caller]
+	d.RUnlock()
+	return drained
+}
+
+// list returns the drained callers in sorted order.
+func (d *callerDrain) list() []string {
+	d.RLock()
+	callers := make([]string, 0, len(d.callers))
+	for caller := range d.callers {
+		callers = append(callers, caller)
+	}
+	d.RUnlock()
+
+	sort.Strings(callers)
+	return callers
+}
+
+// DrainCallers rejects new inbound calls from the given caller names with a
+// retryable declined error, so that they are routed to other peers, while
+// calls from other callers are still served. Calls that have already started
+// are not affected. Calls forwarded by a relay are not drained.
+func (ch *Channel) DrainCallers(callers ...string) {
+	ch.callerDrain.drain(callers)
+}
+
+// UndrainCallers resumes serving inbound calls from callers drained using
+// DrainCallers.
+func (ch *Channel) UndrainCallers(callers ...string) {
+	ch.callerDrain.undrain(callers)
+}
+
+// DrainedCallers returns the caller names whose inbound calls are drained.
+func (ch *Channel) DrainedCallers() []string {
+	return ch.callerDrain.list()
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/tchannel-go/internal/ratelimit"
+)
+
+var errCallerRateLimit = NewSystemError(ErrCodeBusy, "caller exceeded rate limit")
+
+// RateLimit is a rate limit of RPS calls per second, allowing bursts of up to
+// Burst calls. A zero RPS means no limit.
+type RateLimit struct {
+	// RPS is the number of calls allowed per second.
+	RPS float64
+
+	// Burst is the number of calls that can be made at once. Defaults to RPS,
+	// and is at least 1.
+	Burst int
+}
+
+// CallerRateLimits limits the rate of inbound calls from each calling
+// service, which is identified by the caller name ("cn" transport header).
+// Calls over the limit are failed with a Busy error without being handled.
+type CallerRateLimits struct {
+	// Default is the limit for each caller that's not in Callers. Each caller
+	// is limited separately.
+	Default RateLimit
+
+	// Callers overrides Default for specific calling services.
+	Callers map[string]RateLimit
+}
+
+func (l *CallerRateLimits) limit(caller string) RateLimit {
+	if limit, ok := l.Callers[caller]; ok {
+		return limit
+	}
+	return l.Default
+}
+
+// maxCallerBuckets bounds the number of callers that are tracked separately
+// under the Default limit, so that rotating caller names can't grow the
+// limiter without bound.
+const maxCallerBuckets = 10000
+
+// callerRateLimiter tracks the rate of inbound calls from each caller, and is
+// shared by all connections of a channel.
+type callerRateLimiter struct {
+	limits     CallerRateLimits
+	timeNow    func() time.Time
+	maxBuckets int
+
+	sync.RWMutex
+	// buckets only has buckets for callers that are limited.
+	buckets   map[string]*ratelimit.Bucket
+	lastSweep time.Time
+	// overflow is shared by callers under the Default limit once buckets is
+	// full, so callers that aren't tracked are still limited.
+	overflow *ratelimit.Bucket
+}
+
+// newCallerRateLimiter returns a limiter for the limits, or nil if no limits
+// are set.
+func newCallerRateLimiter(timeNow func() time.Time, l *CallerRateLimits) *callerRateLimiter {
+	if l == nil || (l.Default.RPS <= 0 && len(l.Callers) == 0) {
+		return nil
+	}
+	return &callerRateLimiter{
+		limits:     *l,
+		timeNow:    timeNow,
+		maxBuckets: maxCallerBuckets,
+		buckets:    make(map[string]*ratelimit.Bucket),
+	}
+}
+
+// allow returns whether a call from caller is within the caller's limit.
+func (rl *callerRateLimiter) allow(caller string) bool {
+	limit := rl.limits.limit(caller)
+	if limit.RPS <= 0 {
+		return true
+	}
+
+	now := rl.timeNow()
+	return rl.getBucket(caller, limit, now).Take(now)
+}
+
+func (rl *callerRateLimiter) getBucket(caller string, limit RateLimit, now time.Time) *ratelimit.Bucket {
+	rl.RLock()
+	bucket, ok := rl.buckets[caller]
+	rl.RUnlock()
+	if ok {
+		return bucket
+	}
+
+	rl.Lock()
+	defer rl.Unlock()
+
+	if bucket, ok := rl.buckets[caller]; ok {
+		return bucket
+	}
+
+	// Callers with their own limit are bounded by the configuration, so only
+	// callers under the Default limit count against maxBuckets.
+	if _, ok := rl.limits.Callers[caller]; !ok && len(rl.buckets) >= rl.maxBuckets {
+		rl.sweep(now)
+		if len(rl.buckets) >= rl.maxBuckets {
+			if rl.overflow == nil {
+				rl.overflow = newRateBucket(limit, now)
+			}
+			return rl.overflow
+		}
+	}
+
+	bucket = newRateBucket(limit, now)
+	rl.buckets[caller] = bucket
+	return bucket
+}
+
+// sweep removes buckets that have refilled, since they are the same as a new
+// bucket. To avoid scanning every bucket for each call while the limiter is
+// full, sweep runs at most once a second. It must be called with the lock held.
+func (rl *callerRateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < time.Second {
+		return
+	}
+	rl.lastSweep = now
+
+	for caller, bucket := range rl.buckets {
+		if bucket.Full(now) {
+			delete(rl.buckets, caller)
+		}
+	}
+}
+
+// newRateBucket returns a full bucket for the limit, which must have a
+// positive RPS.
+func newRateBucket(limit RateLimit, now time.Time) *ratelimit.Bucket {
+	burst := float64(limit.Burst)
+	if burst <= 0 {
+		burst = limit.RPS
+	}
+	return ratelimit.NewBucket(limit.RPS, burst, now)
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallerRateLimiterBoundsCallers(t *testing.T) {
+	now := time.Unix(1000, 0)
+	rl := newCallerRateLimiter(func() time.Time { return now }, &CallerRateLimits{
+		Default: RateLimit{RPS: 1},
+		Callers: map[string]RateLimit{
+			"trusted": {},
+			"special": {RPS: 1},
+		},
+	})
+	rl.maxBuckets = 3
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, rl.allow(fmt.Sprint("caller-", i)), "First call from caller %v should be allowed", i)
+	}
+	assert.True(t, rl.allow("trusted"), "Callers without a limit should be allowed")
+	assert.Len(t, rl.buckets, 3, "Callers without a limit should not be tracked")
+
+	// Once the limiter is full, new callers share a single bucket under the
+	// Default limit, so rotating caller names can't escape the limit.
+	assert.True(t, rl.allow("rotated-1"), "First untracked caller should use the overflow bucket")
+	assert.False(t, rl.allow("rotated-2"), "Untracked callers should share the overflow bucket")
+	assert.Len(t, rl.buckets, 3, "Buckets should be bounded")
+
+	assert.True(t, rl.allow("special"), "Callers with their own limit should always be tracked")
+	assert.Len(t, rl.buckets, 4, "Callers with their own limit should be tracked past the bound")
+
+	// Buckets that have refilled are dropped to make room for new callers.
+	now = now.Add(2 * time.Second)
+	assert.True(t, rl.allow("rotated-3"), "New caller should be allowed after idle buckets are dropped")
+	assert.False(t, rl.allow("rotated-3"), "New caller should have its own bucket")
+	assert.Len(t, rl.buckets, 1, "Refilled buckets should be dropped")
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallerRateLimits(t *testing.T) {
+	clock := testutils.NewStubClock(time.Unix(1000, 0))
+	stats := newRecordingStatsReporter()
+	opts := testutils.NewOpts().SetStatsReporter(stats).SetTimeNow(clock.Now)
+	opts.CallerRateLimits = &CallerRateLimits{
+		Callers: map[string]RateLimit{"noisy": {RPS: 1, Burst: 2}},
+	}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+		noisy := ts.NewClient(testutils.NewOpts().SetServiceName("noisy"))
+		quiet := ts.NewClient(testutils.NewOpts().SetServiceName("quiet"))
+
+		call := func(client *Channel) error {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+			return err
+		}
+		rateLimited := func() int64 {
+			stats.Lock()
+			defer stats.Unlock()
+
+			var n int64
+			for _, v := range stats.Values["inbound.calls.rate-limited"] {
+				n += v.count
+			}
+			return n
+		}
+
+		// The stats reporter is shared by the runs with and without a relay.
+		base := rateLimited()
+		for i := 0; i < 2; i++ {
+			require.NoError(t, call(noisy), "Calls within the burst should succeed")
+		}
+		err := call(noisy)
+		assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Expected Busy error over the limit, got %v", err)
+		assert.EqualValues(t, 1, rateLimited()-base, "Rejected calls should be counted")
+
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, call(quiet), "Callers without a limit should not be limited")
+		}
+
+		clock.Elapse(time.Second)
+		assert.NoError(t, call(noisy), "Calls should be allowed once the bucket refills")
+		err = call(noisy)
+		assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Expected Busy error over the limit, got %v", err)
+	})
+}
+
+func TestCallerRateLimitsDefault(t *testing.T) {
+	opts := testutils.NewOpts().SetTimeNow(testutils.NewStubClock(time.Unix(1000, 0)).Now)
+	opts.CallerRateLimits = &CallerRateLimits{
+		Default: RateLimit{RPS: 1},
+		Callers: map[string]RateLimit{"trusted": {}},
+	}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		for _, caller := range []string{"c1", "c2"} {
+			client := ts.NewClient(testutils.NewOpts().SetServiceName(caller))
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+			assert.NoError(t, err, "First call from %v should succeed", caller)
+			_, _, _, err = raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+			assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Second call from %v should be limited", caller)
+			cancel()
+		}
+
+		trusted := ts.NewClient(testutils.NewOpts().SetServiceName("trusted"))
+		for i := 0; i < 5; i++ {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			_, _, _, err := raw.Call(ctx, trusted, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+			cancel()
+			assert.NoError(t, err, "Calls from trusted should not be limited")
+		}
+	})
+}
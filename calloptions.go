@@ -20,6 +20,8 @@
 
 package tchannel
 
+import "time"
+
 // Format is the arg scheme used for a specific call.
 type Format string
 
@@ -27,6 +29,7 @@ type Format string
 const (
 	HTTP   Format = "http"
 	JSON   Format = "json"
+	Proto  Format = "proto"
 	Raw    Format = "raw"
 	Thrift Format = "thrift"
 )
@@ -56,6 +59,62 @@ type CallOptions struct {
 	// to an instance of the intended service.
 	RoutingDelegate string
 
+	// BestEffort marks the call as best-effort (droppable) rather than
+	// critical. It's sent in the "be" transport header so servers can shed
+	// best-effort calls first under load. Best-effort calls are also not
+	// started on connections whose send buffer is backed up, leaving room
+	// for critical calls.
+	BestEffort bool
+
+	// Priority determines how the call's frames are scheduled on the
+	// connection. Frames for high priority calls are written before frames
+	// for normal priority calls. It's sent in the "pri" transport header, so
+	// servers use the same priority for the response, and relays honor it.
+	// It's only used on connections where both peers support FeaturePriority.
+	Priority CallPriority
+
+	// IdempotencyKey identifies retries of the same logical call. It's sent in
+	// the "idempotency-key" transport header, and servers that deduplicate
+	// calls return the cached result of an earlier call with the same key.
+	IdempotencyKey string
+
+	// MaxTotalDuration bounds the total time spent on a call across all
+	// attempts made by RunWithRetry, independent of the per-attempt timeout.
+	// Once it's exceeded, no further attempts are made and ErrTimeout is
+	// returned. It's only used from the CallOptions set on the context passed
+	// to RunWithRetry (see ContextBuilder.SetMaxTotalDuration).
+	MaxTotalDuration time.Duration
+
+	// DetachDeadline makes a context built with a ParentContext use only its
+	// own timeout, rather than the earlier of its timeout and the parent's
+	// deadline. The parent's values (such as headers and tracing spans) are
+	// still inherited, but its deadline and cancellation are not. This is
+	// useful for background calls started from a handler that should not be
+	// bound by the inbound call's deadline, so the builder's timeout must be
+	// set. It's only used by ContextBuilder.Build (see
+	// ContextBuilder.SetDetachDeadline).
+	DetachDeadline bool
+
+	// PreferredPeer is the host:port of a peer that calls through a SubChannel
+	// should use if possible, such as the owner of a cached entry. If the peer
+	// is not in the subchannel's peer list, is unhealthy, or the call cannot be
+	// started on it due to a connection failure, the call falls back to normal
+	// peer selection. It only applies to the first attempt of a call; retries
+	// use normal peer selection.
+	PreferredPeer string
+
+	// AuthToken is a signed token, such as a JWT, that identifies the caller.
+	// It's sent in the "auth" transport header, and is verified by servers
+	// that authorize inbound calls (see ChannelOptions.CallAuthorizer).
+	AuthToken string
+
+	// DisableCompression sends the call's args without compression, such as
+	// args that are already compressed. The call's frames bypass any
+	// connection-level compression, and its args are not compressed even if
+	// the connection negotiated argument compression. The response may still
+	// be compressed.
+	DisableCompression bool
+
 	// callerName can only be used when forwarding a request. It can only be set internally,
 	// e.g. by calling (*InboundCall).CallOptions() when forwarding a request
 	callerName string
@@ -85,6 +144,18 @@ func (c *CallOptions) overrideHeaders(headers transportHeaders) {
 	if c.callerName != "" {
 		headers[CallerName] = c.callerName
 	}
+	if c.BestEffort {
+		headers[BestEffort] = "1"
+	}
+	if c.IdempotencyKey != "" {
+		headers[IdempotencyKey] = c.IdempotencyKey
+	}
+	if c.Priority != PriorityNormal {
+		headers[Priority] = c.Priority.String()
+	}
+	if c.AuthToken != "" {
+		headers[AuthToken] = c.AuthToken
+	}
 }
 
 // setResponseHeaders copies some headers from the incoming call request to the response.
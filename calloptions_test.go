@@ -31,6 +31,9 @@ func TestSetHeaders(t *testing.T) {
 		format          Format
 		routingDelegate string
 		routingKey      string
+		bestEffort      bool
+		idempotencyKey  string
+		priority        CallPriority
 		expectedHeaders transportHeaders
 	}{
 		{
@@ -58,6 +61,35 @@ func TestSetHeaders(t *testing.T) {
 				RoutingKey: "canary",
 			},
 		},
+		{
+			format:     Thrift,
+			bestEffort: true,
+			expectedHeaders: transportHeaders{
+				ArgScheme:  Thrift.String(),
+				BestEffort: "1",
+			},
+		},
+		{
+			format:         Raw,
+			idempotencyKey: "req-1",
+			expectedHeaders: transportHeaders{
+				ArgScheme:      Raw.String(),
+				IdempotencyKey: "req-1",
+			},
+		},
+		{
+			format:   JSON,
+			priority: PriorityHigh,
+			expectedHeaders: transportHeaders{
+				ArgScheme: JSON.String(),
+				Priority:  "high",
+			},
+		},
+		{
+			format:          JSON,
+			priority:        PriorityNormal,
+			expectedHeaders: transportHeaders{ArgScheme: JSON.String()},
+		},
 	}
 
 	for _, tt := range tests {
@@ -65,6 +97,9 @@ func TestSetHeaders(t *testing.T) {
 			Format:          tt.format,
 			RoutingDelegate: tt.routingDelegate,
 			RoutingKey:      tt.routingKey,
+			BestEffort:      tt.bestEffort,
+			IdempotencyKey:  tt.idempotencyKey,
+			Priority:        tt.priority,
 		}
 		headers := make(transportHeaders)
 		callOpts.setHeaders(headers)
@@ -21,6 +21,7 @@
 package tchannel
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
@@ -31,6 +32,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/uber/tchannel-go/relay"
 	"github.com/uber/tchannel-go/tnet"
 
 	"github.com/opentracing/opentracing-go"
@@ -63,6 +65,12 @@ type ChannelOptions struct {
 	// a connection to a peer.
 	OnPeerStatusChanged func(*Peer)
 
+	// OnDeadPeer is an optional callback that's called when keepalive pings
+	// detect that a peer is dead, before its connection is closed. It can be
+	// used to re-resolve the peer's addresses (see Peer.SetAddresses), or
+	// replace the peer, before the next connection to it is dialed.
+	OnDeadPeer func(*Peer)
+
 	// The logger to use for this channel
 	Logger Logger
 
@@ -79,6 +87,95 @@ type ChannelOptions struct {
 	// This is an unstable API - breaking changes are likely.
 	RelayMaxTimeout time.Duration
 
+	// RelayAdjustTTL is an optional function that is passed the TTL of an
+	// inbound relayed call, and returns the TTL to forward to the destination.
+	// It's applied before the RelayMaxTimeout clamp. Calls with a non-positive
+	// adjusted TTL are failed with a timeout error.
+	// This is an unstable API - breaking changes are likely.
+	RelayAdjustTTL func(f relay.CallFrame, ttl time.Duration) time.Duration
+
+	// RelayTimeoutPolicy, if set, reduces the TTL of relayed calls by a
+	// relay overhead budget, and clamps it per destination service. It's
+	// applied after RelayAdjustTTL, and before the RelayMaxTimeout clamp.
+	// This is an unstable API - breaking changes are likely.
+	RelayTimeoutPolicy *RelayTimeoutPolicy
+
+	// RelayCallObserver is optionally notified as relayed calls begin, have a
+	// peer selected, receive a response, and end. See RelayCallObserver.
+	// This is an unstable API - breaking changes are likely.
+	RelayCallObserver RelayCallObserver
+
+	// RelayStats, if set, is notified of the outcome of each relayed call.
+	// See relay.Stats.
+	RelayStats relay.Stats
+
+	// RelayInterceptors are run, in order, for each relayed call before it's
+	// forwarded. They may reject the call, be notified of its outcome, or
+	// tag it for frame logging. See RelayInterceptor.
+	RelayInterceptors []RelayInterceptor
+
+	// RelayRateLimiter is optionally consulted for each relayed call before
+	// it's forwarded. Calls that are not allowed are failed with a busy error.
+	// See relay.NewEdgeRateLimiter for a per-edge rate limiter.
+	// This is an unstable API - breaking changes are likely.
+	RelayRateLimiter relay.RateLimiter
+
+	// RelayMaxPeerRetries is the number of times the relay retries a call on
+	// a different peer when it fails to connect to the selected peer. Retries
+	// require the RelayCall to implement RelayRetryableCall, and are bounded
+	// by the call's TTL. Defaults to 0 (no retries).
+	// This is an unstable API - breaking changes are likely.
+	RelayMaxPeerRetries int
+
+	// RelayFailoverCooldown enables failover away from destinations whose
+	// connection failed, either while calls were in progress or when
+	// connecting. For this long after a failure, calls that the RelayHost
+	// sends to the destination are immediately retried on another peer,
+	// unless the destination has another active connection. Failover uses
+	// the retries allowed by RelayMaxPeerRetries, and if there's no other
+	// peer, the destination is still tried. Defaults to 0 (disabled).
+	// This is an unstable API - breaking changes are likely.
+	RelayFailoverCooldown time.Duration
+
+	// RelayConnectionsPerPeer is the number of connections the relay uses for
+	// each destination. Connections are created as calls are relayed to the
+	// destination, and calls are spread across its healthy connections.
+	// Defaults to 1.
+	// This is an unstable API - breaking changes are likely.
+	RelayConnectionsPerPeer int
+
+	// RelayMaxRequestSize limits the total size of the args of a relayed
+	// call request, across all of its fragments. Calls that exceed it are
+	// failed with a BadRequest error. Defaults to 0 (no limit).
+	// This is an unstable API - breaking changes are likely.
+	RelayMaxRequestSize int
+
+	// RelayMaxResponseSize limits the total size of the args of a relayed
+	// call response, across all of its fragments. Calls whose response
+	// exceeds it are failed with an Unexpected error. Defaults to 0 (no limit).
+	// This is an unstable API - breaking changes are likely.
+	RelayMaxResponseSize int
+
+	// RelayCircuitBreaker enables a circuit breaker per service and method
+	// that rejects relayed calls with a declined error while the destination
+	// is failing consistently. By default, there is no circuit breaker.
+	// This is an unstable API - breaking changes are likely.
+	RelayCircuitBreaker RelayCircuitBreakerOptions
+
+	// CircuitBreaker enables a circuit breaker per peer, service and method
+	// that fails outbound calls with ErrCircuitOpen while calls to the peer
+	// are failing consistently. State changes are reported to the
+	// StatsReporter. By default, there is no circuit breaker.
+	// This is an unstable API - breaking changes are likely.
+	CircuitBreaker CircuitBreakerOptions
+
+	// RelayShadow mirrors a percentage of relayed calls to a secondary
+	// destination for dark-launch testing. Responses from the shadow
+	// destination are discarded. By default, no calls are mirrored.
+	// See RelayShadowOptions.
+	// This is an unstable API - breaking changes are likely.
+	RelayShadow RelayShadowOptions
+
 	// RelayTimerVerification will disable pooling of relay timers, and instead
 	// verify that timers are not used once they are released.
 	// This is an unstable API - breaking changes are likely.
@@ -100,19 +197,225 @@ type ChannelOptions struct {
 	// is set.
 	MaxIdleTime time.Duration
 
+	// UnhealthyPeerCooldown is how long a peer is considered unhealthy after
+	// failing to connect, unless it has an active connection. Calls made
+	// through a SubChannel avoid unhealthy peers, and fail immediately with
+	// ErrNoHealthyPeers if all peers are unhealthy. If this is zero (the
+	// default), peers are never considered unhealthy.
+	UnhealthyPeerCooldown time.Duration
+
+	// PeerSelection is the algorithm used to select peers from the channel's
+	// peer lists. The default, PeerSelectionScore, selects the peer with the
+	// best score. See PeerSelection for the available algorithms.
+	PeerSelection PeerSelection
+
+	// PeerSelectionStrategy is an optional custom algorithm used to select
+	// peers from the channel's peer lists, which takes precedence over
+	// PeerSelection. It's told the outcome of each outbound call, so it can
+	// implement feedback-driven load balancing. See PeerSelectionStrategy.
+	PeerSelectionStrategy PeerSelectionStrategy
+
+	// PeerLatencyHalfLife is the half-life of the moving averages of call
+	// latency and error rate kept for each peer, which are used by
+	// PeerSelectionLatency and PeerSelectionAdaptive. Shorter half-lives react
+	// faster to changes, and probe peers that haven't been called sooner.
+	// Passing zero uses the default of 10s.
+	PeerLatencyHalfLife time.Duration
+
+	// MaxCallsPerConnection is the number of concurrent outbound calls on a
+	// connection at which it's considered saturated. Calls to a peer are
+	// spread across its connections that are not saturated, and once all of
+	// them are, a new connection is created, up to MaxConnectionsPerPeer.
+	// If this is zero (the default), calls to a peer share a single connection.
+	MaxCallsPerConnection int
+
+	// MaxConnectionsPerPeer limits the number of outbound connections created
+	// to each peer when MaxCallsPerConnection is set. If this is zero, the
+	// number of connections is not limited.
+	MaxConnectionsPerPeer int
+
+	// ConnectionQueueTimeout is how long a call waits for a connection to
+	// have capacity when all connections to the peer are saturated and
+	// MaxConnectionsPerPeer has been reached. If this is zero (the default),
+	// calls fail immediately with ErrPeerConnectionsSaturated.
+	ConnectionQueueTimeout time.Duration
+
 	// IdleCheckInterval controls how often the channel runs a sweep over
 	// all active connections to see if they can be dropped. Connections that
-	// are idle for longer than MaxIdleTime are disconnected. If this is set to
-	// zero (the default), idle checking is disabled.
+	// are idle for longer than MaxIdleTime are disconnected, and counted in
+	// the connections.idle-closed stat. If this is set to zero (the default),
+	// idle checking is disabled.
 	IdleCheckInterval time.Duration
 
 	// Tracer is an OpenTracing Tracer used to manage distributed tracing spans.
 	// If not set, opentracing.GlobalTracer() is used.
 	Tracer opentracing.Tracer
 
+	// CallTracer, if set, is used to create spans for inbound and outbound
+	// calls in addition to the OpenTracing spans, such as the OpenTelemetry
+	// spans created by the trace/otel package. If not set, no such spans are
+	// created.
+	CallTracer CallTracer
+
 	// Handler is an alternate handler for all inbound requests, overriding the
 	// default handler that delegates to a subchannel.
 	Handler Handler
+
+	// ErrorSanitizer, if set, is passed the errors sent by inbound call
+	// handlers using InboundCallResponse.SendSystemError, and returns the
+	// error to send to the caller instead. This can be used to map internal
+	// errors to client-safe messages and codes. The code and message of the
+	// returned error are sent, and non-SystemErrors are sent as ErrCodeUnexpected.
+	// Errors that are replaced are logged with the original error.
+	ErrorSanitizer func(err error) error
+
+	// MaxHandlerDuration, if set, bounds the time inbound call handlers are
+	// given: the handler's context is cancelled once it elapses, even if the
+	// caller's deadline is later.
+	MaxHandlerDuration time.Duration
+
+	// UnknownServiceHandler handles inbound calls for services other than the
+	// channel's own service that have no registered handlers. By default, these
+	// calls are rejected with ErrCodeDeclined, so callers can distinguish an
+	// unknown service from an unknown method, which fails with ErrCodeBadRequest.
+	// This is not used if Handler is set.
+	UnknownServiceHandler Handler
+
+	// DefaultRetryOptions are the RetryOptions used by RunWithRetry when the
+	// context does not set any. Subchannels can override these using the
+	// DefaultRetryOptions SubChannelOption. If this is nil, calls are retried
+	// on connection errors up to 5 attempts.
+	DefaultRetryOptions *RetryOptions
+
+	// DefaultTimeout is the timeout used for calls made with BeginCall or
+	// through a SubChannel when the context has no deadline. Subchannels can
+	// override it using the DefaultTimeout SubChannelOption. If this is zero,
+	// calls without a deadline fail with ErrTimeoutRequired.
+	DefaultTimeout time.Duration
+
+	// StallTimeout enables a watchdog that fails calls that have not sent or
+	// received any frames within the timeout with ErrCallStalled. This frees
+	// connections from calls that can never make progress, such as a handler
+	// that stops reading a large request. Handlers that take longer than this
+	// to respond are also failed. If this is zero (the default), calls are only
+	// failed when they time out.
+	StallTimeout time.Duration
+
+	// StallCheckInterval controls how often the watchdog checks for stalled
+	// calls when StallTimeout is set. Passing zero uses half of StallTimeout.
+	StallCheckInterval time.Duration
+
+	// MaxHeapSize is the heap size (in bytes) above which inbound calls are
+	// rejected with a busy error, to protect the process from running out of
+	// memory when overloaded. If this is zero (the default), inbound calls are
+	// never rejected due to memory pressure.
+	MaxHeapSize uint64
+
+	// HeapCheckInterval controls how often the heap size is sampled when
+	// MaxHeapSize is set. Passing zero uses the default of 1s.
+	HeapCheckInterval time.Duration
+
+	// HeapSize is a variable for overriding how the heap size is read in unit tests.
+	// Note: This is not a stable part of the API and may change.
+	HeapSize func() uint64
+
+	// DeduplicationWindow enables deduplication of inbound calls that carry the
+	// same idempotency key (see CallOptions.IdempotencyKey) from the same caller
+	// for the same service and method. The result of a completed call is cached for this duration,
+	// and duplicate calls are sent the cached result without running the
+	// handler. Only successful responses and application errors are cached.
+	// If this is zero (the default), calls are not deduplicated.
+	DeduplicationWindow time.Duration
+
+	// DeduplicationMaxEntries is the maximum number of call results cached for
+	// deduplication. When the cache is full, the least recently used entry is
+	// evicted. Passing zero uses the default of 10000.
+	DeduplicationMaxEntries int
+
+	// MaxConnectionAcceptRate limits the rate at which new inbound connections
+	// are accepted, in connections per second. Once a burst of
+	// ConnectionAcceptBurst connections has been accepted, further connections
+	// are delayed to the configured rate, which protects the process from
+	// connection floods. If this is zero (the default), accepts are not limited.
+	MaxConnectionAcceptRate int
+
+	// ConnectionAcceptBurst is the number of connections that can be accepted
+	// at once before MaxConnectionAcceptRate applies. Passing zero uses
+	// MaxConnectionAcceptRate.
+	ConnectionAcceptBurst int
+
+	// OutboundLatencyWindow enables tracking the latency of outbound calls
+	// per service and method over a sliding window of this duration, which
+	// can be read using OutboundLatency. If this is zero (the default),
+	// outbound latencies are not tracked.
+	OutboundLatencyWindow time.Duration
+
+	// ConnectionPoolStatsInterval enables periodic reporting of the
+	// connection.pool.utilization gauge for each peer, which is the peer's
+	// active outbound connections as a percentage of MaxConnectionsPerPeer.
+	// If this or MaxConnectionsPerPeer is zero (the default), pool
+	// utilization is not reported.
+	ConnectionPoolStatsInterval time.Duration
+
+	// FramePoolSize, if set, uses a frame pool that retains at most this many
+	// released frames (see NewBoundedFramePool), and reports the pool's hits,
+	// misses and discards to the StatsReporter. It is ignored if
+	// DefaultConnectionOptions.FramePool is set.
+	FramePoolSize int
+
+	// KeepAlive enables keepalive pings on idle connections, closing
+	// connections whose pings go unanswered. It is a shorthand for setting
+	// DefaultConnectionOptions.KeepAlive, which it overrides if set.
+	KeepAlive KeepAliveOptions
+
+	// RetryBudget limits the retries made by RunWithRetry to a fraction of
+	// the original requests over a sliding window. Once the budget is
+	// exhausted, calls fail with the last error instead of being retried.
+	// By default, retries are not limited.
+	RetryBudget RetryBudgetOptions
+
+	// BandwidthLimits limits the rate at which frames are sent and received,
+	// across the channel and per peer. The limits can be changed using
+	// SetBandwidthLimits. By default, bandwidth is not limited.
+	BandwidthLimits BandwidthLimits
+
+	// HeaderLimits limits the number and size of the transport and
+	// application headers of inbound and relayed calls. See HeaderLimits.
+	// By default, headers are only limited by the protocol.
+	HeaderLimits *HeaderLimits
+
+	// ConcurrencyLimits limits the number of inbound calls handled
+	// concurrently per endpoint and per calling service. See
+	// ConcurrencyLimits. By default, concurrency is not limited.
+	ConcurrencyLimits *ConcurrencyLimits
+
+	// CallerRateLimits limits the rate of inbound calls from each calling
+	// service. See CallerRateLimits. By default, calls are not rate limited.
+	CallerRateLimits *CallerRateLimits
+
+	// CallAuthorizer, if set, is called with the identity of the caller of
+	// each inbound call before it's dispatched to the handler. Calls that it
+	// rejects are failed with a declined error. See CallAuthorizer.
+	CallAuthorizer CallAuthorizer
+
+	// DispatchPool runs the handlers for inbound calls on a fixed pool of
+	// goroutines. See DispatchPoolOptions. By default, each inbound call is
+	// handled on a new goroutine.
+	DispatchPool DispatchPoolOptions
+
+	// Dialer is optional factory method which can be used for overriding
+	// outbound connections for things like SOCKS proxy or TLS.
+	Dialer func(ctx context.Context, network, hostPort string) (net.Conn, error)
+
+	// DialFallbackDelay is how long to wait for a dial to a peer's address to
+	// succeed before also dialing its next address, for peers with alternate
+	// addresses set using Peer.SetAddresses. If zero, it defaults to 300ms.
+	DialFallbackDelay time.Duration
+
+	// TLSConfig, if set, is used to accept connections over TLS in
+	// ListenAndServe, and to dial peers over TLS if Dialer is not set.
+	// The TChannel handshake happens once the TLS handshake completes.
+	TLSConfig *tls.Config
 }
 
 // ChannelState is the state of a channel.
@@ -147,17 +450,44 @@ const (
 type Channel struct {
 	channelConnectionCommon
 
-	chID                uint32
-	createdStack        string
-	commonStatsTags     map[string]string
-	connectionOptions   ConnectionOptions
-	peers               *PeerList
-	relayHost           RelayHost
-	relayMaxTimeout     time.Duration
-	relayTimerVerify    bool
-	handler             Handler
-	onPeerStatusChanged func(*Peer)
-	closed              chan struct{}
+	chID                  uint32
+	createdStack          string
+	commonStatsTags       map[string]string
+	connectionOptions     ConnectionOptions
+	jumboFrames           *jumboFrames
+	peers                 *PeerList
+	relayHost             RelayHost
+	relayMaxTimeout       time.Duration
+	relayAdjustTTL        func(relay.CallFrame, time.Duration) time.Duration
+	relayCallObserver     RelayCallObserver
+	relayStats            relay.Stats
+	relayInterceptors     []RelayInterceptor
+	relayRateLimiter      relay.RateLimiter
+	relayCircuitBreaker   *relayCircuitBreaker
+	relayMaxPeerRetries   int
+	relayFailover         time.Duration
+	relayConnsPerPeer     int
+	relayMaxRequestSize   int
+	relayMaxResponseSize  int
+	relayShadow           *relayShadow
+	relayTimerVerify      bool
+	handler               Handler
+	middleware            *middlewareChain
+	unknownServiceHandler Handler
+	onPeerStatusChanged   func(*Peer)
+	onDeadPeer            func(*Peer)
+	dialer                func(ctx context.Context, network, hostPort string) (net.Conn, error)
+	dialFallbackDelay     time.Duration
+	tlsConfig             *tls.Config
+	outboundPause         *outboundPause
+	acceptLimiter         *acceptRateLimiter
+	stallWatchdog         *stallWatchdog
+	connPoolStats         *connPoolStats
+	defaultRetryOptions   *RetryOptions
+	retryBudget           *retryBudget
+	defaultTimeout        time.Duration
+	healthChecks          healthChecks
+	closed                chan struct{}
 
 	// mutable contains all the members of Channel which are mutable.
 	mutable struct {
@@ -177,9 +507,46 @@ type channelConnectionCommon struct {
 	relayLocal    map[string]struct{}
 	statsReporter StatsReporter
 	tracer        opentracing.Tracer
+	callTracer    CallTracer
 	subChannels   *subChannelMap
 	timeNow       func() time.Time
 	timeTicker    func(time.Duration) *time.Ticker
+	memPressure   *memoryPressure
+
+	errorSanitizer     func(error) error
+	maxHandlerDuration time.Duration
+	dedup              *dedupCache
+	callerDrain        *callerDrain
+	latencies          *latencyAggregator
+
+	// draining is set once Shutdown or Drain is called.
+	draining *atomic.Bool
+
+	// bandwidth limits the bytes sent and received by connections.
+	bandwidth *channelBandwidth
+
+	// headerLimits limits the headers of inbound calls, and is nil if no
+	// limits are set.
+	headerLimits *HeaderLimits
+
+	// concurrencyLimiter limits the inbound calls handled concurrently, and
+	// is nil if no limits are set.
+	concurrencyLimiter *concurrencyLimiter
+
+	// callerRateLimiter limits the rate of inbound calls from each caller,
+	// and is nil if no limits are set.
+	callerRateLimiter *callerRateLimiter
+
+	// callAuthorizer authorizes inbound calls, and is nil if calls are not
+	// authorized.
+	callAuthorizer CallAuthorizer
+
+	// dispatchPool runs the handlers for inbound calls, and is nil if each
+	// call is handled on a new goroutine.
+	dispatchPool *dispatchPool
+
+	// eventBus publishes the channel's events to subscribers.
+	eventBus *eventBus
 }
 
 // _nextChID is used to allocate unique IDs to every channel for debugging purposes.
@@ -227,6 +594,16 @@ func NewChannel(serviceName string, opts *ChannelOptions) (*Channel, error) {
 		timeNow = time.Now
 	}
 
+	dialer := opts.Dialer
+	if dialer == nil && opts.TLSConfig != nil {
+		dialer = NewTLSDialer(TLSDialerOptions{Config: opts.TLSConfig})
+	}
+
+	dialFallbackDelay := opts.DialFallbackDelay
+	if dialFallbackDelay == 0 {
+		dialFallbackDelay = _defaultDialFallbackDelay
+	}
+
 	timeTicker := opts.TimeTicker
 	if timeTicker == nil {
 		timeTicker = time.NewTicker
@@ -245,28 +622,66 @@ func NewChannel(serviceName string, opts *ChannelOptions) (*Channel, error) {
 
 	ch := &Channel{
 		channelConnectionCommon: channelConnectionCommon{
-			log:           logger,
-			relayLocal:    toStringSet(opts.RelayLocalHandlers),
-			statsReporter: statsReporter,
-			subChannels:   &subChannelMap{},
-			timeNow:       timeNow,
-			timeTicker:    timeTicker,
-			tracer:        opts.Tracer,
+			log:                logger,
+			relayLocal:         toStringSet(opts.RelayLocalHandlers),
+			statsReporter:      statsReporter,
+			subChannels:        &subChannelMap{},
+			timeNow:            timeNow,
+			timeTicker:         timeTicker,
+			tracer:             opts.Tracer,
+			callTracer:         opts.CallTracer,
+			memPressure:        startMemoryPressure(logger, timeTicker, opts),
+			errorSanitizer:     opts.ErrorSanitizer,
+			maxHandlerDuration: opts.MaxHandlerDuration,
+			dedup:              newDedupCache(timeNow, opts),
+			callerDrain:        &callerDrain{},
+			latencies:          newLatencyAggregator(timeNow, opts.OutboundLatencyWindow),
+			draining:           atomic.NewBool(false),
+			bandwidth:          newChannelBandwidth(timeNow, opts.BandwidthLimits),
+			headerLimits:       enabledHeaderLimits(opts.HeaderLimits),
+			concurrencyLimiter: newConcurrencyLimiter(opts.ConcurrencyLimits, opts.DispatchPool.Workers > 0),
+			callerRateLimiter:  newCallerRateLimiter(timeNow, opts.CallerRateLimits),
+			callAuthorizer:     opts.CallAuthorizer,
+			eventBus:           newEventBus(timeNow),
 		},
-		chID:              chID,
-		connectionOptions: opts.DefaultConnectionOptions.withDefaults(),
-		relayHost:         opts.RelayHost,
-		relayMaxTimeout:   validateRelayMaxTimeout(opts.RelayMaxTimeout, logger),
-		relayTimerVerify:  opts.RelayTimerVerification,
-		closed:            make(chan struct{}),
+		chID:                 chID,
+		connectionOptions:    opts.DefaultConnectionOptions.withDefaults(),
+		relayHost:            opts.RelayHost,
+		relayMaxTimeout:      validateRelayMaxTimeout(opts.RelayMaxTimeout, logger),
+		relayAdjustTTL:       relayAdjustTTL(opts.RelayAdjustTTL, opts.RelayTimeoutPolicy),
+		relayCallObserver:    opts.RelayCallObserver,
+		relayStats:           opts.RelayStats,
+		relayInterceptors:    opts.RelayInterceptors,
+		relayRateLimiter:     opts.RelayRateLimiter,
+		relayCircuitBreaker:  newRelayCircuitBreaker(timeNow, opts.RelayCircuitBreaker),
+		relayMaxPeerRetries:  opts.RelayMaxPeerRetries,
+		relayFailover:        opts.RelayFailoverCooldown,
+		relayConnsPerPeer:    opts.RelayConnectionsPerPeer,
+		relayMaxRequestSize:  opts.RelayMaxRequestSize,
+		relayMaxResponseSize: opts.RelayMaxResponseSize,
+		relayShadow:          newRelayShadow(opts.RelayShadow),
+		relayTimerVerify:     opts.RelayTimerVerification,
+		dialer:               dialer,
+		dialFallbackDelay:    dialFallbackDelay,
+		onDeadPeer:           opts.OnDeadPeer,
+		tlsConfig:            opts.TLSConfig,
+		outboundPause:        &outboundPause{},
+		acceptLimiter:        newAcceptRateLimiter(timeNow, opts.MaxConnectionAcceptRate, opts.ConnectionAcceptBurst),
+		defaultRetryOptions:  copyRetryOptions(opts.DefaultRetryOptions),
+		retryBudget:          newRetryBudget(timeNow, opts.RetryBudget),
+		defaultTimeout:       opts.DefaultTimeout,
+		closed:               make(chan struct{}),
 	}
-	ch.peers = newRootPeerList(ch, opts.OnPeerStatusChanged).newChild()
-
 	if opts.Handler != nil {
 		ch.handler = opts.Handler
 	} else {
 		ch.handler = channelHandler{ch}
 	}
+	ch.middleware = newMiddlewareChain(ch.handler)
+	ch.unknownServiceHandler = opts.UnknownServiceHandler
+	if ch.unknownServiceHandler == nil {
+		ch.unknownServiceHandler = HandlerFunc(rejectUnknownService)
+	}
 
 	ch.mutable.peerInfo = LocalPeerInfo{
 		PeerInfo: PeerInfo{
@@ -284,6 +699,28 @@ func NewChannel(serviceName string, opts *ChannelOptions) (*Channel, error) {
 	ch.mutable.state = ChannelClient
 	ch.mutable.conns = make(map[uint32]*Connection)
 	ch.createCommonStats()
+	ch.peers = newRootPeerList(ch, rootPeerListOptions{
+		onPeerStatusChanged: opts.OnPeerStatusChanged,
+		timeNow:             timeNow,
+		outboundPause:       ch.outboundPause,
+		unhealthyCooldown:   opts.UnhealthyPeerCooldown,
+		peerSelection:       opts.PeerSelection,
+		selectionStrategy:   opts.PeerSelectionStrategy,
+		circuitBreaker:      newCircuitBreaker(timeNow, statsReporter, ch.commonStatsTags, opts.CircuitBreaker),
+		latencyHalfLife:     opts.PeerLatencyHalfLife,
+		connLimits:          newPeerConnLimits(opts),
+		bandwidth:           ch.bandwidth,
+		events:              ch.eventBus,
+	}).newChild()
+	if opts.FramePoolSize > 0 && opts.DefaultConnectionOptions.FramePool == nil {
+		ch.connectionOptions.FramePool = NewBoundedFramePool(opts.FramePoolSize, statsReporter, ch.commonStatsTags)
+	}
+	if opts.KeepAlive.enabled() {
+		ch.connectionOptions.KeepAlive = opts.KeepAlive.withDefaults()
+	}
+	if opts.RelayHost == nil {
+		ch.jumboFrames = newJumboFrames(ch.connectionOptions)
+	}
 
 	// Register internal unless the root handler has been overridden, since
 	// Register will panic.
@@ -299,6 +736,9 @@ func NewChannel(serviceName string, opts *ChannelOptions) (*Channel, error) {
 
 	// Start the idle connection timer.
 	ch.mutable.idleSweep = startIdleSweep(ch, opts)
+	ch.stallWatchdog = startStallWatchdog(ch, opts)
+	ch.connPoolStats = startConnPoolStats(ch, opts)
+	ch.dispatchPool = startDispatchPool(ch, opts)
 
 	return ch, nil
 }
@@ -351,6 +791,9 @@ func (ch *Channel) ListenAndServe(hostPort string) error {
 		mutable.RUnlock()
 		return err
 	}
+	if ch.tlsConfig != nil {
+		l = tls.NewListener(l, ch.tlsConfig)
+	}
 
 	mutable.RUnlock()
 	return ch.Serve(l)
@@ -431,6 +874,24 @@ func (ch *Channel) GetSubChannel(serviceName string, opts ...SubChannelOption) *
 	return sub
 }
 
+// PauseOutbound pauses all new outbound calls made using this channel until
+// ResumeOutbound is called. Paused calls block until outbound traffic is
+// resumed, or fail with a timeout if their context deadline passes first.
+// Calls that were started before the pause are not affected.
+func (ch *Channel) PauseOutbound() {
+	ch.outboundPause.pause()
+}
+
+// ResumeOutbound resumes outbound calls paused using PauseOutbound.
+func (ch *Channel) ResumeOutbound() {
+	ch.outboundPause.resume()
+}
+
+// OutboundPaused returns whether outbound calls are currently paused.
+func (ch *Channel) OutboundPaused() bool {
+	return ch.outboundPause.paused()
+}
+
 // Peers returns the PeerList for the channel.
 func (ch *Channel) Peers() *PeerList {
 	return ch.peers
@@ -448,7 +909,9 @@ func (ch *Channel) RootPeers() *RootPeerList {
 // be used to write the arguments of the call.
 func (ch *Channel) BeginCall(ctx context.Context, hostPort, serviceName, methodName string, callOptions *CallOptions) (*OutboundCall, error) {
 	p := ch.RootPeers().GetOrAdd(hostPort)
-	return p.BeginCall(ctx, serviceName, methodName, callOptions)
+	ctx, cancel := withDefaultTimeout(ctx, ch.defaultTimeout)
+	call, err := p.BeginCall(ctx, serviceName, methodName, callOptions)
+	return cancelWithCall(call, err, cancel)
 }
 
 // serve runs the listener to accept and manage new incoming connections, blocking
@@ -469,6 +932,7 @@ func (ch *Channel) serve() {
 				if max := 1 * time.Second; acceptBackoff > max {
 					acceptBackoff = max
 				}
+				ch.statsReporter.IncCounter("inbound.connections.accept-errors", ch.commonStatsTags, 1)
 				ch.log.WithFields(
 					ErrField(err),
 					LogField{"backoff", acceptBackoff},
@@ -486,6 +950,12 @@ func (ch *Channel) serve() {
 		}
 
 		acceptBackoff = 0
+		ch.statsReporter.IncCounter("inbound.connections.accepted", ch.commonStatsTags, 1)
+
+		if !ch.waitForAcceptLimit() {
+			netConn.Close()
+			return
+		}
 
 		// Perform the connection handshake in a background goroutine.
 		go func() {
@@ -494,6 +964,8 @@ func (ch *Channel) serve() {
 				OnActive:           ch.inboundConnectionActive,
 				OnCloseStateChange: ch.connectionCloseStateChange,
 				OnExchangeUpdated:  ch.exchangeUpdated,
+				OnPeerEjected:      ch.peerEjected,
+				OnPeerDead:         ch.peerDead,
 			}
 			if _, err := ch.inboundHandshake(context.Background(), netConn, events); err != nil {
 				netConn.Close()
@@ -502,6 +974,32 @@ func (ch *Channel) serve() {
 	}
 }
 
+// waitForAcceptLimit delays handling an accepted connection if connections are
+// accepted faster than MaxConnectionAcceptRate. It returns false if the channel
+// is closed while waiting.
+func (ch *Channel) waitForAcceptLimit() bool {
+	if ch.acceptLimiter == nil {
+		return true
+	}
+
+	delay := ch.acceptLimiter.reserve()
+	if delay <= 0 {
+		return true
+	}
+
+	ch.statsReporter.IncCounter("inbound.connections.rate-limited", ch.commonStatsTags, 1)
+	ch.statsReporter.RecordTimer("inbound.connections.accept-delay", ch.commonStatsTags, delay)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ch.closed:
+		return false
+	}
+}
+
 // Ping sends a ping message to the given hostPort and waits for a response.
 func (ch *Channel) Ping(ctx context.Context, hostPort string) error {
 	peer := ch.RootPeers().GetOrAdd(hostPort)
@@ -547,6 +1045,9 @@ func (ch *Channel) Connect(ctx context.Context, hostPort string) (*Connection, e
 		ch.log.Debugf("Connect rejecting new connection as state is %v", state)
 		return nil, errInvalidStateForOp
 	}
+	if ch.Draining() {
+		return nil, errChannelDraining
+	}
 
 	// The context timeout applies to the whole call, but users may want a lower
 	// connect timeout (e.g. for streams).
@@ -560,6 +1061,8 @@ func (ch *Channel) Connect(ctx context.Context, hostPort string) (*Connection, e
 		OnActive:           ch.outboundConnectionActive,
 		OnCloseStateChange: ch.connectionCloseStateChange,
 		OnExchangeUpdated:  ch.exchangeUpdated,
+		OnPeerEjected:      ch.peerEjected,
+		OnPeerDead:         ch.peerDead,
 	}
 
 	if err := ctx.Err(); err != nil {
@@ -567,7 +1070,7 @@ func (ch *Channel) Connect(ctx context.Context, hostPort string) (*Connection, e
 	}
 
 	timeout := getTimeout(ctx)
-	tcpConn, err := dialContext(ctx, hostPort)
+	tcpConn, err := ch.dialPeer(ctx, hostPort)
 	if err != nil {
 		if ne, ok := err.(net.Error); ok && ne.Timeout() {
 			ch.log.WithFields(
@@ -607,6 +1110,15 @@ func (ch *Channel) Connect(ctx context.Context, hostPort string) (*Connection, e
 	return conn, err
 }
 
+// dial creates an outbound network connection to hostPort, using the
+// configured Dialer if one was provided.
+func (ch *Channel) dial(ctx context.Context, hostPort string) (net.Conn, error) {
+	if ch.dialer != nil {
+		return ch.dialer(ctx, "tcp", hostPort)
+	}
+	return dialContext(ctx, hostPort)
+}
+
 // exchangeUpdated updates the peer heap.
 func (ch *Channel) exchangeUpdated(c *Connection) {
 	if c.remotePeerInfo.HostPort == "" {
@@ -626,6 +1138,7 @@ func (ch *Channel) exchangeUpdated(c *Connection) {
 func (ch *Channel) updatePeer(p *Peer) {
 	ch.peers.onPeerChange(p)
 	ch.subChannels.updatePeer(p)
+	p.notifyCapacity()
 	p.callOnUpdateComplete()
 }
 
@@ -661,6 +1174,11 @@ func (ch *Channel) connectionActive(c *Connection, direction connectionDirection
 	}
 
 	ch.addConnectionToPeer(c.remotePeerInfo.HostPort, c, direction)
+	ch.eventBus.publish(Event{
+		Type:       EventConnectionOpened,
+		HostPort:   c.remotePeerInfo.HostPort,
+		Connection: c,
+	})
 }
 
 func (ch *Channel) addConnectionToPeer(hostPort string, c *Connection, direction connectionDirection) {
@@ -692,8 +1210,17 @@ func (ch *Channel) removeClosedConn(c *Connection) {
 	}
 
 	ch.mutable.Lock()
+	_, ok := ch.mutable.conns[c.connID]
 	delete(ch.mutable.conns, c.connID)
 	ch.mutable.Unlock()
+
+	if ok {
+		ch.eventBus.publish(Event{
+			Type:       EventConnectionClosed,
+			HostPort:   c.remotePeerInfo.HostPort,
+			Connection: c,
+		})
+	}
 }
 
 func (ch *Channel) getMinConnectionState() connectionState {
@@ -809,6 +1336,10 @@ func (ch *Channel) Close() {
 
 		// Stop the idle connections timer.
 		ch.mutable.idleSweep.Stop()
+		ch.memPressure.Stop()
+		ch.stallWatchdog.Stop()
+		ch.connPoolStats.Stop()
+		ch.dispatchPool.Stop()
 
 		ch.mutable.state = ChannelStartClose
 		if len(ch.mutable.conns) == 0 {
@@ -21,12 +21,22 @@
 package tchannel
 
 import (
+	"fmt"
 	"hash"
 	"hash/crc32"
+	"strconv"
+	"strings"
 	"sync"
 )
 
-var checksumPools [checksumCount]sync.Pool
+var (
+	checksumPools [checksumCount]sync.Pool
+
+	// checksumRegistered and checksumSizes track the checksum types that have
+	// an implementation, and the size of their checksums.
+	checksumRegistered [checksumCount]bool
+	checksumSizes      [checksumCount]int
+)
 
 // A ChecksumType is a checksum algorithm supported by TChannel for checksumming call bodies
 type ChecksumType byte
@@ -41,10 +51,21 @@ const (
 	// ChecksumTypeFarmhash indicates the message checksum is calculated using Farmhash
 	ChecksumTypeFarmhash ChecksumType = 2
 
-	// ChecksumTypeCrc32C indicates the message checksum is calculated using crc32c
+	// ChecksumTypeCrc32C indicates the message checksum is calculated using crc32c.
+	// hash/crc32 calculates it using CPU instructions where they're available
+	// (SSE4.2 on amd64), as it does for crc32. Use BenchmarkChecksum to compare
+	// the checksum types on a given machine. Not all TChannel implementations
+	// support it, so it's only used on connections where the peer advertises
+	// support for it.
 	ChecksumTypeCrc32C ChecksumType = 3
 
-	checksumCount = 4
+	// ChecksumTypeXXHash64 indicates the message checksum is calculated using
+	// 64-bit xxHash. It's not part of the TChannel protocol, so it's only used
+	// on connections where the peer advertises support for it.
+	ChecksumTypeXXHash64 ChecksumType = 4
+
+	// checksumCount is the number of checksum types that fit in a frame.
+	checksumCount = 256
 )
 
 func init() {
@@ -64,6 +85,77 @@ func init() {
 	ChecksumTypeFarmhash.pool().New = func() interface{} {
 		return nullChecksum{}
 	}
+
+	for t := ChecksumTypeNone; t <= ChecksumTypeCrc32C; t++ {
+		checksumRegistered[t] = true
+	}
+
+	RegisterChecksum(byte(ChecksumTypeXXHash64), func() Checksum {
+		return newHashChecksum(ChecksumTypeXXHash64, newXXHash64())
+	})
+}
+
+// RegisterChecksum registers the Checksum implementation for the checksum type
+// with the given code, so it can be used as ConnectionOptions.ChecksumType and
+// verified on incoming calls. Registered types are advertised during the init
+// handshake, and connections to peers that don't support the configured type
+// fall back to ChecksumTypeCrc32.
+//
+// Checksums created by factory must return code from TypeCode, and should call
+// TypeCode().Release(checksum) on Release. RegisterChecksum is not thread-safe,
+// and should be called from an init function. It panics if code is already
+// registered.
+func RegisterChecksum(code byte, factory func() Checksum) {
+	t := ChecksumType(code)
+	if checksumRegistered[t] {
+		panic(fmt.Sprintf("tchannel: checksum type %v is already registered", code))
+	}
+
+	checksumRegistered[t] = true
+	checksumSizes[t] = factory().Size()
+	t.pool().New = func() interface{} {
+		return factory()
+	}
+}
+
+// isBuiltin returns whether the checksum type is supported by all peers.
+func (t ChecksumType) isBuiltin() bool {
+	return t < ChecksumTypeCrc32C
+}
+
+// advertisedChecksumTypes returns the value of InitParamChecksumTypes, which
+// lists crc32c and the registered checksum types, since not all peers support
+// them.
+// Relays forward frames without recomputing their checksums, so they don't
+// advertise any, since the peers they forward to may not support them.
+func (ch *Channel) advertisedChecksumTypes() string {
+	if ch.RelayHost() != nil {
+		return ""
+	}
+
+	var codes []string
+	for t := int(ChecksumTypeCrc32C); t < checksumCount; t++ {
+		if checksumRegistered[t] {
+			codes = append(codes, strconv.Itoa(t))
+		}
+	}
+	return strings.Join(codes, ",")
+}
+
+// negotiateChecksumType returns the checksum type to use for calls sent on a
+// connection, falling back to crc32 if the peer doesn't support local.
+func negotiateChecksumType(local ChecksumType, remote initParams) ChecksumType {
+	if local.isBuiltin() {
+		return local
+	}
+
+	localCode := strconv.Itoa(int(local))
+	for _, code := range strings.Split(remote[InitParamChecksumTypes], ",") {
+		if code == localCode {
+			return local
+		}
+	}
+	return ChecksumTypeCrc32
 }
 
 // ChecksumSize returns the size in bytes of the checksum calculation
@@ -76,7 +168,7 @@ func (t ChecksumType) ChecksumSize() int {
 	case ChecksumTypeFarmhash:
 		return 4
 	default:
-		return 0
+		return checksumSizes[t]
 	}
 }
 
@@ -152,7 +244,7 @@ func newHashChecksum(t ChecksumType, hash hash.Hash) *hashChecksum {
 	return &hashChecksum{
 		checksumType: t,
 		hash:         hash,
-		sumCache:     make([]byte, 0, 4),
+		sumCache:     make([]byte, 0, hash.Size()),
 	}
 }
 
@@ -0,0 +1,189 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/uber/tchannel-go/typed"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXXHash64(t *testing.T) {
+	tests := []struct {
+		input string
+		want  uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"abc", 0x44bc2cf5ad770999},
+		{"Nobody inspects the spammish repetition", 0xfbcea83c8a378bf1},
+	}
+
+	for _, tt := range tests {
+		h := newXXHash64()
+		h.Write([]byte(tt.input))
+		assert.Equal(t, tt.want, h.Sum64(), "Unexpected hash for %q", tt.input)
+
+		var want [8]byte
+		binary.BigEndian.PutUint64(want[:], tt.want)
+		assert.Equal(t, want[:], h.Sum(nil), "Unexpected sum for %q", tt.input)
+	}
+}
+
+func TestXXHash64Streaming(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdefghijklmnopqrstuvwxyz"), 20)
+
+	whole := newXXHash64()
+	whole.Write(data)
+
+	for _, chunkSize := range []int{1, 3, 7, 31, 32, 33, 100} {
+		h := newXXHash64()
+		for b := data; len(b) > 0; {
+			n := chunkSize
+			if n > len(b) {
+				n = len(b)
+			}
+			h.Write(b[:n])
+			b = b[n:]
+		}
+		assert.Equal(t, whole.Sum64(), h.Sum64(), "Unexpected hash writing %v bytes at a time", chunkSize)
+	}
+}
+
+func TestXXHash64Checksum(t *testing.T) {
+	c := ChecksumTypeXXHash64.New()
+	defer c.Release()
+
+	assert.Equal(t, ChecksumTypeXXHash64, c.TypeCode(), "Unexpected type code")
+	assert.Equal(t, 8, c.Size(), "Unexpected checksum size")
+	assert.Equal(t, 8, ChecksumTypeXXHash64.ChecksumSize(), "Unexpected checksum type size")
+
+	c.Add([]byte("a"))
+	assert.Equal(t, []byte{0xd2, 0x4e, 0xc4, 0xf1, 0xa9, 0x8c, 0x6e, 0x5b}, c.Sum(), "Unexpected checksum")
+}
+
+func TestRegisterChecksumDuplicate(t *testing.T) {
+	for _, code := range []ChecksumType{ChecksumTypeCrc32, ChecksumTypeXXHash64} {
+		assert.Panics(t, func() {
+			RegisterChecksum(byte(code), func() Checksum { return nullChecksum{} })
+		}, "Registering checksum type %v again should panic", code)
+	}
+}
+
+func TestNegotiateChecksumType(t *testing.T) {
+	tests := []struct {
+		msg    string
+		local  ChecksumType
+		remote initParams
+		want   ChecksumType
+	}{
+		{
+			msg:   "crc32 is always supported",
+			local: ChecksumTypeCrc32,
+			want:  ChecksumTypeCrc32,
+		},
+		{
+			msg:    "peer supports crc32c",
+			local:  ChecksumTypeCrc32C,
+			remote: initParams{InitParamChecksumTypes: "3,4"},
+			want:   ChecksumTypeCrc32C,
+		},
+		{
+			msg:   "peer does not advertise crc32c",
+			local: ChecksumTypeCrc32C,
+			want:  ChecksumTypeCrc32,
+		},
+		{
+			msg:    "peer supports checksum type",
+			local:  ChecksumTypeXXHash64,
+			remote: initParams{InitParamChecksumTypes: "4"},
+			want:   ChecksumTypeXXHash64,
+		},
+		{
+			msg:    "peer supports multiple checksum types",
+			local:  ChecksumTypeXXHash64,
+			remote: initParams{InitParamChecksumTypes: "10,4,11"},
+			want:   ChecksumTypeXXHash64,
+		},
+		{
+			msg:    "peer supports other checksum types",
+			local:  ChecksumTypeXXHash64,
+			remote: initParams{InitParamChecksumTypes: "10,44"},
+			want:   ChecksumTypeCrc32,
+		},
+		{
+			msg:   "peer does not advertise checksum types",
+			local: ChecksumTypeXXHash64,
+			want:  ChecksumTypeCrc32,
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, negotiateChecksumType(tt.local, tt.remote), tt.msg)
+	}
+}
+
+func TestAdvertisedChecksumTypes(t *testing.T) {
+	ch := &Channel{}
+	assert.Equal(t, "3,4", ch.advertisedChecksumTypes(), "Unexpected advertised checksum types")
+
+	ch.relayHost = struct{ RelayHost }{}
+	assert.Empty(t, ch.advertisedChecksumTypes(), "Relays should not advertise checksum types")
+}
+
+func TestParseInboundFragmentUnknownChecksum(t *testing.T) {
+	frame := NewFrame(MaxFramePayloadSize)
+	frame.Header.messageType = messageTypeCallRes
+	// flags, call res fields, then an unregistered checksum type.
+	msg := &callRes{ResponseCode: responseOK}
+	wbuf := typed.NewWriteBuffer(frame.Payload)
+	wbuf.WriteSingleByte(0)
+	msg.write(wbuf)
+	wbuf.WriteSingleByte(200)
+	frame.Header.SetPayloadSize(uint16(wbuf.BytesWritten()))
+
+	_, err := parseInboundFragment(DefaultFramePool, frame, &callRes{})
+	assert.Equal(t, ErrCodeProtocol, GetSystemErrorCode(err), "Unexpected error: %v", err)
+}
+
+func BenchmarkChecksum(b *testing.B) {
+	data := bytes.Repeat([]byte("checksum me "), 5000)
+	checksumTypes := map[string]ChecksumType{
+		"crc32":    ChecksumTypeCrc32,
+		"crc32c":   ChecksumTypeCrc32C,
+		"xxhash64": ChecksumTypeXXHash64,
+	}
+	for name, t := range checksumTypes {
+		t := t
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				checksum := t.New()
+				checksum.Add(data)
+				checksum.Release()
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getConnChecksumTypes(t *testing.T, ch *Channel) []ChecksumType {
+	var checksumTypes []ChecksumType
+	for _, peer := range ch.IntrospectState(nil).RootPeers {
+		for _, conn := range peer.InboundConnections {
+			checksumTypes = append(checksumTypes, conn.ChecksumType)
+		}
+		for _, conn := range peer.OutboundConnections {
+			checksumTypes = append(checksumTypes, conn.ChecksumType)
+		}
+	}
+	require.NotEmpty(t, checksumTypes, "Expected connections on channel")
+	return checksumTypes
+}
+
+func TestConnectionChecksumXXHash64(t *testing.T) {
+	// Use a large payload so the call is split across multiple fragments.
+	arg3 := bytes.Repeat([]byte("checksum me "), 20000)
+
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		ts.Register(raw.Wrap(newTestHandler(t)), "echo")
+
+		client := ts.NewClient(testutils.NewOpts().SetChecksumType(ChecksumTypeXXHash64))
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		_, resArg3, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, arg3)
+		require.NoError(t, err, "Call failed")
+		assert.Equal(t, arg3, resArg3, "Unexpected response")
+
+		assert.Equal(t, []ChecksumType{ChecksumTypeXXHash64}, getConnChecksumTypes(t, client),
+			"Unexpected client connection checksum type")
+		assert.Equal(t, []ChecksumType{ChecksumTypeCrc32}, getConnChecksumTypes(t, ts.Server()),
+			"Unexpected server connection checksum type")
+	})
+}
+
+func TestConnectionChecksumXXHash64Relay(t *testing.T) {
+	arg3 := bytes.Repeat([]byte("checksum me "), 20000)
+
+	opts := testutils.NewOpts().SetRelayOnly()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		ts.Register(raw.Wrap(newTestHandler(t)), "echo")
+
+		client := ts.NewClient(testutils.NewOpts().SetChecksumType(ChecksumTypeXXHash64))
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		_, resArg3, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, arg3)
+		require.NoError(t, err, "Call failed")
+		assert.Equal(t, arg3, resArg3, "Unexpected response")
+
+		// The relay doesn't advertise xxhash64, since it forwards frames to
+		// servers that may not support it.
+		assert.Equal(t, []ChecksumType{ChecksumTypeCrc32}, getConnChecksumTypes(t, client),
+			"Unexpected client connection checksum type")
+	})
+}
+
+func TestConnectionChecksumCrc32C(t *testing.T) {
+	arg3 := bytes.Repeat([]byte("checksum me "), 20000)
+
+	opts := testutils.NewOpts().SetChecksumType(ChecksumTypeCrc32C)
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		ts.Register(raw.Wrap(newTestHandler(t)), "echo")
+
+		client := ts.NewClient(testutils.NewOpts().SetChecksumType(ChecksumTypeCrc32C))
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		_, resArg3, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, arg3)
+		require.NoError(t, err, "Call failed")
+		assert.Equal(t, arg3, resArg3, "Unexpected response")
+
+		// Relays don't advertise crc32c, so the client falls back to crc32.
+		want := ChecksumTypeCrc32C
+		if ts.HasRelay() {
+			want = ChecksumTypeCrc32
+		}
+		assert.Equal(t, []ChecksumType{want}, getConnChecksumTypes(t, client),
+			"Unexpected client connection checksum type")
+	})
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreakerOptions configure a circuit breaker for outbound calls, which
+// tracks calls to each peer, service and method, and fails calls with
+// ErrCircuitOpen while they are failing consistently.
+type CircuitBreakerOptions struct {
+	// FailureRatio is the ratio of failed calls to calls within the Window at
+	// which the circuit opens. If this is zero (the default), the circuit
+	// breaker is disabled.
+	//
+	// System errors and timeouts are counted as failures, except for calls
+	// cancelled by the caller and bad requests. Application errors are not
+	// counted.
+	FailureRatio float64
+
+	// Window is the rolling window over which calls and failures are counted.
+	// Passing zero uses the default of 10s.
+	Window time.Duration
+
+	// MinRequests is the number of calls required within the Window before
+	// the circuit can open. Passing zero uses the default of 10.
+	MinRequests int
+
+	// Cooldown is how long the circuit stays open before it half-opens, and
+	// allows a single call to probe whether the peer has recovered. If the
+	// probe succeeds, the circuit closes, otherwise it opens again.
+	// Passing zero uses the default of 5s.
+	Cooldown time.Duration
+}
+
+func (o CircuitBreakerOptions) enabled() bool {
+	return o.FailureRatio > 0
+}
+
+func (o CircuitBreakerOptions) withDefaults() CircuitBreakerOptions {
+	return CircuitBreakerOptions(RelayCircuitBreakerOptions(o).withDefaults())
+}
+
+// circuitStateTags are the values of the "state" tag used when reporting
+// circuit state changes.
+var circuitStateTags = map[CircuitState]string{
+	CircuitClosed:   "closed",
+	CircuitOpen:     "open",
+	CircuitHalfOpen: "half-open",
+}
+
+type circuitKey struct {
+	hostPort string
+	service  string
+	method   string
+}
+
+// circuitBreaker tracks the outcome of outbound calls per peer, service and
+// method, and rejects calls for a circuit that is open. It's shared by all of
+// a channel's peers.
+type circuitBreaker struct {
+	opts          CircuitBreakerOptions
+	timeNow       func() time.Time
+	statsReporter StatsReporter
+	statsTags     map[string]string
+	maxCircuits   int
+
+	sync.RWMutex
+	circuits  map[circuitKey]*circuit
+	lastSweep time.Time
+}
+
+func newCircuitBreaker(timeNow func() time.Time, statsReporter StatsReporter, statsTags map[string]string, opts CircuitBreakerOptions) *circuitBreaker {
+	if !opts.enabled() {
+		return nil
+	}
+	return &circuitBreaker{
+		opts:          opts.withDefaults(),
+		timeNow:       timeNow,
+		statsReporter: statsReporter,
+		statsTags:     statsTags,
+		maxCircuits:   _maxCircuits,
+		circuits:      make(map[circuitKey]*circuit),
+	}
+}
+
+func (b *circuitBreaker) getCircuit(key circuitKey) *circuit {
+	b.RLock()
+	c, ok := b.circuits[key]
+	b.RUnlock()
+	if ok {
+		return c
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if c, ok := b.circuits[key]; ok {
+		// Another call for this edge added the circuit first.
+		return c
+	}
+
+	c = newCircuit(b.opts.Window)
+	if len(b.circuits) >= b.maxCircuits {
+		b.sweep(b.timeNow())
+		if len(b.circuits) >= b.maxCircuits {
+			// The call is allowed by an untracked circuit, so calls beyond
+			// the limit aren't broken until circuits expire.
+			return c
+		}
+	}
+	b.circuits[key] = c
+	return c
+}
+
+// sweep removes idle circuits. To avoid scanning every circuit for each call
+// while the circuit breaker is full, sweep runs at most once a second. It must
+// be called with the lock held.
+func (b *circuitBreaker) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < time.Second {
+		return
+	}
+	b.lastSweep = now
+
+	for key, c := range b.circuits {
+		if c.idle(now) {
+			delete(b.circuits, key)
+		}
+	}
+}
+
+// allow returns whether a call to the given peer, service and method should
+// be made. Allowed calls must report their outcome using the returned circuit.
+func (b *circuitBreaker) allow(key circuitKey) (*circuit, bool) {
+	c := b.getCircuit(key)
+
+	ok, halfOpened := c.allow(b.timeNow(), b.opts.Cooldown)
+	if halfOpened {
+		b.reportStateChange(key, CircuitHalfOpen)
+	}
+	if !ok {
+		b.statsReporter.IncCounter("outbound.calls.circuit-open", b.tags(key), 1)
+		return nil, false
+	}
+	return c, true
+}
+
+// record records the outcome of a call that was allowed by the circuit.
+func (b *circuitBreaker) record(key circuitKey, c *circuit, outcome circuitOutcome) {
+	if state, changed := c.record(b.timeNow(), outcome, RelayCircuitBreakerOptions(b.opts)); changed {
+		b.reportStateChange(key, state)
+	}
+}
+
+func (b *circuitBreaker) reportStateChange(key circuitKey, state CircuitState) {
+	tags := b.tags(key)
+	tags["state"] = circuitStateTags[state]
+	b.statsReporter.IncCounter("outbound.circuit-breaker.state-changes", tags, 1)
+}
+
+func (b *circuitBreaker) tags(key circuitKey) map[string]string {
+	tags := cloneTags(b.statsTags)
+	tags["target-service"] = key.service
+	tags["target-endpoint"] = key.method
+	tags["target-host"] = key.hostPort
+	return tags
+}
+
+// circuitOutcomeOf returns how the outcome of a call that ended with err is
+// counted by the circuit breaker.
+func circuitOutcomeOf(err error) circuitOutcome {
+	if err == nil {
+		return circuitSucceeded
+	}
+	switch GetSystemErrorCode(GetContextError(err)) {
+	case ErrCodeCancelled, ErrCodeBadRequest:
+		return circuitIgnored
+	}
+	return circuitFailed
+}
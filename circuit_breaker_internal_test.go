@@ -0,0 +1,67 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreakerExpiresIdleCircuits(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newCircuitBreaker(func() time.Time { return now }, NullStatsReporter, nil, CircuitBreakerOptions{
+		FailureRatio: 0.5,
+		Window:       time.Second,
+		MinRequests:  1,
+	})
+	b.maxCircuits = 2
+	key := func(method string) circuitKey {
+		return circuitKey{hostPort: "1.1.1.1:1", service: "svc", method: method}
+	}
+
+	// Open the circuit for m0, and make a call for m1.
+	c, ok := b.allow(key("m0"))
+	require.True(t, ok, "First call should be allowed")
+	b.record(key("m0"), c, circuitFailed)
+	c, ok = b.allow(key("m1"))
+	require.True(t, ok, "First call should be allowed")
+	b.record(key("m1"), c, circuitSucceeded)
+
+	for i := 2; i < 5; i++ {
+		_, ok := b.allow(key(fmt.Sprint("m", i)))
+		assert.True(t, ok, "Calls for methods beyond the limit should be allowed")
+	}
+	assert.Len(t, b.circuits, 2, "Circuits should be bounded")
+
+	// Once the window has passed, the idle circuit for m1 is dropped to make
+	// room, but the open circuit for m0 is kept.
+	now = now.Add(2 * time.Second)
+	_, ok = b.allow(key("m5"))
+	assert.True(t, ok, "New method should be allowed")
+	assert.Len(t, b.circuits, 2, "Circuits should be bounded")
+	assert.Contains(t, b.circuits, key("m0"), "Open circuit should not be dropped")
+	assert.Contains(t, b.circuits, key("m5"), "New circuit should be tracked")
+	assert.NotContains(t, b.circuits, key("m1"), "Idle circuit should be dropped")
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		clock := testutils.NewStubClock(time.Now())
+		stats := newRecordingStatsReporter()
+		clientOpts := testutils.NewOpts().SetTimeNow(clock.Now).SetStatsReporter(stats)
+		clientOpts.CircuitBreaker = CircuitBreakerOptions{
+			FailureRatio: 0.5,
+			MinRequests:  2,
+			Cooldown:     time.Minute,
+		}
+
+		testutils.RegisterFunc(ts.Server(), "method", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			switch string(args.Arg3) {
+			case "busy":
+				return nil, ErrServerBusy
+			case "app-error":
+				return &raw.Res{IsErr: true}, nil
+			}
+			return &raw.Res{}, nil
+		})
+		testutils.RegisterEcho(ts.Server(), nil)
+		client := ts.NewClient(clientOpts)
+
+		call := func(method, result string) error {
+			// The client's TTL is relative to the stub clock.
+			ctx, cancel := context.WithDeadline(context.Background(), clock.Now().Add(testutils.Timeout(time.Second)))
+			defer cancel()
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), method, nil, []byte(result))
+			return err
+		}
+		stateChanges := func(state string) int64 {
+			stats.Lock()
+			defer stats.Unlock()
+
+			var count int64
+			for tags, v := range stats.Values["outbound.circuit-breaker.state-changes"] {
+				if strings.Contains(tags, "state = "+state) && strings.Contains(tags, "target-endpoint = method") {
+					count += v.count
+				}
+			}
+			return count
+		}
+
+		// Application errors should not trip the breaker.
+		for i := 0; i < 3; i++ {
+			require.NoError(t, call("method", "app-error"), "Application errors should not fail the call")
+		}
+		require.NoError(t, call("method", "ok"), "Call should succeed")
+		require.NoError(t, call("method", "ok"), "Call should succeed")
+		require.Error(t, call("method", "busy"), "Expected busy error")
+		assert.Zero(t, stateChanges("open"), "Circuit should not open with 1 failure in 3 calls")
+		require.Error(t, call("method", "busy"), "Expected busy error")
+		assert.EqualValues(t, 1, stateChanges("open"), "Circuit should open once the failure ratio is exceeded")
+
+		assert.Equal(t, ErrCircuitOpen, call("method", "ok"), "Calls should fail while open")
+		require.NoError(t, call("echo", "ok"), "Calls to other methods should not be affected")
+
+		// After the cooldown, a failed probe reopens the circuit.
+		clock.Elapse(time.Minute)
+		require.Error(t, call("method", "busy"), "Expected busy error")
+		assert.EqualValues(t, 1, stateChanges("half-open"), "Circuit should half-open after the cooldown")
+		assert.EqualValues(t, 2, stateChanges("open"), "Failed probe should reopen the circuit")
+		assert.Equal(t, ErrCircuitOpen, call("method", "ok"), "Calls should fail while open")
+
+		// A successful probe closes the circuit.
+		clock.Elapse(time.Minute)
+		require.NoError(t, call("method", "ok"), "Probe should succeed")
+		assert.EqualValues(t, 1, stateChanges("closed"), "Successful probe should close the circuit")
+		require.NoError(t, call("method", "ok"), "Call should succeed once closed")
+	})
+}
+
+func TestCircuitBreakerProbeTimeout(t *testing.T) {
+	opts := testutils.NewOpts().DisableLogVerification()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		clientOpts := testutils.NewOpts()
+		clientOpts.CircuitBreaker = CircuitBreakerOptions{
+			FailureRatio: 0.5,
+			MinRequests:  2,
+			Cooldown:     testutils.Timeout(50 * time.Millisecond),
+		}
+
+		release := make(chan struct{})
+		testutils.RegisterFunc(ts.Server(), "method", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			switch string(args.Arg3) {
+			case "busy":
+				return nil, ErrServerBusy
+			case "timeout":
+				<-release
+			}
+			return &raw.Res{}, nil
+		})
+		client := ts.NewClient(clientOpts)
+
+		call := func(result string, timeout time.Duration) error {
+			ctx, cancel := NewContext(timeout)
+			defer cancel()
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "method", nil, []byte(result))
+			return err
+		}
+
+		require.Error(t, call("busy", testutils.Timeout(time.Second)), "Expected busy error")
+		require.Error(t, call("busy", testutils.Timeout(time.Second)), "Expected busy error")
+		assert.Equal(t, ErrCircuitOpen, call("ok", testutils.Timeout(time.Second)), "Calls should fail while open")
+
+		// A probe that times out should reopen the circuit, rather than
+		// leaving it half-open with a probe that never completes.
+		time.Sleep(clientOpts.CircuitBreaker.Cooldown)
+		assert.Equal(t, ErrTimeout, call("timeout", 20*time.Millisecond), "Expected probe to time out")
+		close(release)
+		assert.Equal(t, ErrCircuitOpen, call("ok", testutils.Timeout(time.Second)), "Calls should fail while open")
+
+		time.Sleep(clientOpts.CircuitBreaker.Cooldown)
+		require.NoError(t, call("ok", testutils.Timeout(time.Second)), "Probe after the cooldown should succeed")
+		require.NoError(t, call("ok", testutils.Timeout(time.Second)), "Call should succeed once closed")
+	})
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/uber/tchannel-go"
+
+	"golang.org/x/net/context"
+)
+
+// request encodes the arguments of a call for an arg scheme, and decodes the
+// response.
+type request interface {
+	method() string
+	args() (arg2, arg3 []byte, err error)
+	decode(res *response, arg2, arg3 []byte) error
+}
+
+// response is the result of a call that's printed as JSON.
+type response struct {
+	OK      bool        `json:"ok"`
+	Peer    string      `json:"peer"`
+	Latency string      `json:"latency"`
+	Headers interface{} `json:"headers,omitempty"`
+	Body    interface{} `json:"body"`
+
+	// Exception is the name of the Thrift exception returned, if any.
+	Exception string `json:"exception,omitempty"`
+}
+
+func call(ctx context.Context, sc *tchannel.SubChannel, opts *options, req request) (*response, error) {
+	arg2, arg3, err := req.args()
+	if err != nil {
+		return nil, err
+	}
+
+	callOptions := opts.callOptions
+	callOptions.Format = opts.format
+
+	start := time.Now()
+	call, err := sc.BeginCall(ctx, req.method(), &callOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tchannel.NewArgWriter(call.Arg2Writer()).Write(arg2); err != nil {
+		return nil, fmt.Errorf("failed to write arg2: %v", err)
+	}
+	if err := tchannel.NewArgWriter(call.Arg3Writer()).Write(arg3); err != nil {
+		return nil, fmt.Errorf("failed to write arg3: %v", err)
+	}
+
+	var respArg2, respArg3 []byte
+	if err := tchannel.NewArgReader(call.Response().Arg2Reader()).Read(&respArg2); err != nil {
+		return nil, err
+	}
+	if err := tchannel.NewArgReader(call.Response().Arg3Reader()).Read(&respArg3); err != nil {
+		return nil, err
+	}
+
+	res := &response{
+		OK:      !call.Response().ApplicationError(),
+		Peer:    call.RemotePeer().HostPort,
+		Latency: time.Since(start).String(),
+	}
+	if err := req.decode(res, respArg2, respArg3); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// rawRequest passes arg2 and arg3 through as-is.
+type rawRequest struct {
+	opts *options
+}
+
+func (r rawRequest) method() string {
+	return r.opts.method
+}
+
+func (r rawRequest) args() ([]byte, []byte, error) {
+	return []byte(r.opts.arg2), []byte(r.opts.arg3), nil
+}
+
+func (r rawRequest) decode(res *response, arg2, arg3 []byte) error {
+	if len(arg2) > 0 {
+		res.Headers = string(arg2)
+	}
+	res.Body = string(arg3)
+	return nil
+}
+
+// jsonRequest sends the application headers as arg2 and the JSON arguments
+// as arg3.
+type jsonRequest struct {
+	opts *options
+	body json.RawMessage
+}
+
+func newJSONRequest(opts *options) (request, error) {
+	body := opts.arg3
+	if body == "" {
+		body = "null"
+	}
+	if !json.Valid([]byte(body)) {
+		return nil, fmt.Errorf("arg3 is not valid JSON: %v", body)
+	}
+	return jsonRequest{opts, json.RawMessage(body)}, nil
+}
+
+func (r jsonRequest) method() string {
+	return r.opts.method
+}
+
+func (r jsonRequest) args() ([]byte, []byte, error) {
+	arg2, err := json.Marshal(r.opts.headers)
+	if err != nil {
+		return nil, nil, err
+	}
+	return arg2, r.body, nil
+}
+
+func (r jsonRequest) decode(res *response, arg2, arg3 []byte) error {
+	if len(arg2) > 0 {
+		var headers map[string]string
+		if err := json.Unmarshal(arg2, &headers); err != nil {
+			return fmt.Errorf("failed to decode response headers: %v", err)
+		}
+		if len(headers) > 0 {
+			res.Headers = headers
+		}
+	}
+	if len(arg3) > 0 {
+		if err := json.Unmarshal(arg3, &res.Body); err != nil {
+			return fmt.Errorf("failed to decode response body: %v", err)
+		}
+	}
+	return nil
+}
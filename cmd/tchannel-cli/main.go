@@ -0,0 +1,216 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// tchannel-cli makes calls to TChannel services from the command line, and
+// prints the response, for debugging services.
+//
+// Calls can use the raw, JSON or Thrift arg schemes. Thrift calls load the
+// service definitions from a .thrift file at runtime, and take and print
+// arguments as JSON:
+//
+//	tchannel-cli -p 127.0.0.1:4040 -s keyvalue -thrift keyvalue.thrift \
+//	  -m KeyValue::Get -arg3 '{"key": "foo"}'
+//
+// The -introspect and -runtime flags call the introspection endpoints of a
+// Go TChannel process.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/uber/tchannel-go"
+)
+
+// headerFlags is a flag that can be set multiple times with key:value pairs.
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	var pairs []string
+	for k, v := range h {
+		pairs = append(pairs, k+":"+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (h headerFlags) Set(s string) error {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("header %q must be in the form key:value", s)
+	}
+	h[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	return nil
+}
+
+type options struct {
+	peers      []string
+	service    string
+	method     string
+	format     tchannel.Format
+	thriftFile string
+	timeout    time.Duration
+
+	arg2    string
+	arg3    string
+	headers map[string]string
+
+	callOptions tchannel.CallOptions
+}
+
+func parseFlags(args []string, errOut io.Writer) (*options, error) {
+	var (
+		opts    options
+		peers   string
+		asJSON  bool
+		intro   bool
+		runtime bool
+		headers = make(headerFlags)
+	)
+
+	flags := flag.NewFlagSet("tchannel-cli", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	flags.StringVar(&peers, "p", "", "Comma-separated host:ports of the peers to call")
+	flags.StringVar(&opts.service, "s", "", "Service name to call")
+	flags.StringVar(&opts.method, "m", "", "Method to call, which is Service::method for Thrift calls")
+	flags.StringVar(&opts.thriftFile, "thrift", "", "Make a Thrift call using the services defined in this .thrift file")
+	flags.BoolVar(&asJSON, "json", false, "Make a JSON call")
+	flags.BoolVar(&intro, "introspect", false, "Call the introspection endpoint, with -arg3 as the options")
+	flags.BoolVar(&runtime, "runtime", false, "Call the Go runtime state endpoint, with -arg3 as the options")
+	flags.DurationVar(&opts.timeout, "timeout", time.Second, "Timeout for the call")
+	flags.StringVar(&opts.arg2, "arg2", "", "Arg2 for raw calls")
+	flags.StringVar(&opts.arg3, "arg3", "", "Arg3 for raw calls, or the JSON arguments for JSON and Thrift calls")
+	flags.Var(headers, "H", "Application header as key:value for JSON and Thrift calls (can be repeated)")
+	flags.StringVar(&opts.callOptions.ShardKey, "shard-key", "", "Shard key transport header")
+	flags.StringVar(&opts.callOptions.RoutingKey, "routing-key", "", "Routing key transport header")
+	flags.StringVar(&opts.callOptions.RoutingDelegate, "routing-delegate", "", "Routing delegate transport header")
+	flags.StringVar(&opts.callOptions.IdempotencyKey, "idempotency-key", "", "Idempotency key transport header")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	for _, p := range strings.Split(peers, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			opts.peers = append(opts.peers, p)
+		}
+	}
+	opts.headers = headers
+
+	opts.format = tchannel.Raw
+	switch {
+	case intro || runtime:
+		opts.format = tchannel.JSON
+		opts.method = "_gometa_introspect"
+		if runtime {
+			opts.method = "_gometa_runtime"
+		}
+		if opts.service == "" {
+			opts.service = "tchannel"
+		}
+		if opts.arg3 == "" {
+			opts.arg3 = "{}"
+		}
+	case opts.thriftFile != "":
+		opts.format = tchannel.Thrift
+	case asJSON:
+		opts.format = tchannel.JSON
+	}
+
+	if len(opts.peers) == 0 {
+		return nil, errors.New("no peers specified, use -p")
+	}
+	if opts.service == "" {
+		return nil, errors.New("no service specified, use -s")
+	}
+	if opts.method == "" {
+		return nil, errors.New("no method specified, use -m")
+	}
+	return &opts, nil
+}
+
+func main() {
+	opts, err := parseFlags(os.Args[1:], os.Stderr)
+	if err == flag.ErrHelp {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	res, err := run(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := printResponse(os.Stdout, res); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if !res.OK {
+		os.Exit(1)
+	}
+}
+
+func run(opts *options) (*response, error) {
+	ch, err := tchannel.NewChannel("tchannel-cli", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer ch.Close()
+
+	sc := ch.GetSubChannel(opts.service)
+	for _, p := range opts.peers {
+		sc.Peers().Add(p)
+	}
+
+	var req request
+	switch opts.format {
+	case tchannel.JSON:
+		req, err = newJSONRequest(opts)
+	case tchannel.Thrift:
+		req, err = newThriftRequest(opts)
+	default:
+		req = rawRequest{opts}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := tchannel.NewContext(opts.timeout)
+	defer cancel()
+
+	return call(ctx, sc, opts, req)
+}
+
+func printResponse(w io.Writer, res *response) error {
+	bs, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(bs))
+	return err
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/json"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		msg     string
+		args    []string
+		want    func(o *options)
+		wantErr string
+	}{
+		{
+			msg:     "no peers",
+			args:    []string{"-s", "svc", "-m", "echo"},
+			wantErr: "no peers specified",
+		},
+		{
+			msg:     "no service",
+			args:    []string{"-p", "127.0.0.1:1", "-m", "echo"},
+			wantErr: "no service specified",
+		},
+		{
+			msg:     "no method",
+			args:    []string{"-p", "127.0.0.1:1", "-s", "svc"},
+			wantErr: "no method specified",
+		},
+		{
+			msg:     "invalid header",
+			args:    []string{"-p", "127.0.0.1:1", "-s", "svc", "-m", "echo", "-H", "novalue"},
+			wantErr: "must be in the form key:value",
+		},
+		{
+			msg:  "raw call",
+			args: []string{"-p", "127.0.0.1:1, 127.0.0.1:2", "-s", "svc", "-m", "echo", "-arg2", "a2", "-arg3", "a3"},
+			want: func(o *options) {
+				o.peers = []string{"127.0.0.1:1", "127.0.0.1:2"}
+				o.arg2 = "a2"
+				o.arg3 = "a3"
+			},
+		},
+		{
+			msg: "json call with headers",
+			args: []string{"-p", "127.0.0.1:1", "-s", "svc", "-m", "echo", "-json",
+				"-H", "k1:v1", "-H", "k2: v2", "-routing-key", "rk", "-idempotency-key", "ik"},
+			want: func(o *options) {
+				o.format = tchannel.JSON
+				o.headers = map[string]string{"k1": "v1", "k2": "v2"}
+				o.callOptions.RoutingKey = "rk"
+				o.callOptions.IdempotencyKey = "ik"
+			},
+		},
+		{
+			msg:  "thrift call",
+			args: []string{"-p", "127.0.0.1:1", "-s", "svc", "-m", "Svc::echo", "-thrift", "svc.thrift", "-timeout", "5s"},
+			want: func(o *options) {
+				o.format = tchannel.Thrift
+				o.method = "Svc::echo"
+				o.thriftFile = "svc.thrift"
+				o.timeout = 5 * time.Second
+			},
+		},
+		{
+			msg:  "introspection",
+			args: []string{"-p", "127.0.0.1:1", "-introspect"},
+			want: func(o *options) {
+				o.format = tchannel.JSON
+				o.service = "tchannel"
+				o.method = "_gometa_introspect"
+				o.arg3 = "{}"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			got, err := parseFlags(tt.args, &bytes.Buffer{})
+			if tt.wantErr != "" {
+				require.Error(t, err, "Expected parse to fail")
+				assert.Contains(t, err.Error(), tt.wantErr, "Unexpected error")
+				return
+			}
+			require.NoError(t, err, "Failed to parse flags")
+
+			want := &options{
+				peers:   []string{"127.0.0.1:1"},
+				service: "svc",
+				method:  "echo",
+				format:  tchannel.Raw,
+				timeout: time.Second,
+				headers: map[string]string{},
+			}
+			tt.want(want)
+			assert.Equal(t, want, got, "Unexpected options")
+		})
+	}
+}
+
+func TestRawCall(t *testing.T) {
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+	testutils.RegisterEcho(server, nil)
+
+	res, err := run(&options{
+		peers:   []string{server.PeerInfo().HostPort},
+		service: server.ServiceName(),
+		method:  "echo",
+		format:  tchannel.Raw,
+		timeout: testutils.Timeout(time.Second),
+		arg2:    "arg2",
+		arg3:    "arg3",
+	})
+	require.NoError(t, err, "Raw call failed")
+	assert.True(t, res.OK, "Expected call to succeed")
+	assert.Equal(t, server.PeerInfo().HostPort, res.Peer, "Unexpected peer")
+	assert.Equal(t, "arg2", res.Headers, "Unexpected response arg2")
+	assert.Equal(t, "arg3", res.Body, "Unexpected response arg3")
+	assert.NotEmpty(t, res.Latency, "Expected latency")
+
+	var out bytes.Buffer
+	require.NoError(t, printResponse(&out, res), "Failed to print response")
+	assert.Contains(t, out.String(), `"body": "arg3"`, "Unexpected output")
+}
+
+func TestJSONCall(t *testing.T) {
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+
+	handler := func(ctx json.Context, args map[string]interface{}) (map[string]interface{}, error) {
+		ctx.SetResponseHeaders(ctx.Headers())
+		if args["fail"] == true {
+			return nil, errors.New("failed")
+		}
+		return map[string]interface{}{"echo": args["value"]}, nil
+	}
+	require.NoError(t, json.Register(server, json.Handlers{"echo": handler}, nil), "Register failed")
+
+	tests := []struct {
+		msg         string
+		arg3        string
+		wantOK      bool
+		wantBody    interface{}
+		wantErr     bool
+		wantHeaders interface{}
+	}{
+		{
+			msg:         "success",
+			arg3:        `{"value": "foo"}`,
+			wantOK:      true,
+			wantBody:    map[string]interface{}{"echo": "foo"},
+			wantHeaders: map[string]string{"k": "v"},
+		},
+		{
+			msg:         "application error",
+			arg3:        `{"fail": true}`,
+			wantBody:    map[string]interface{}{"type": "error", "message": "failed"},
+			wantHeaders: map[string]string{"k": "v"},
+		},
+		{
+			msg:     "invalid JSON",
+			arg3:    `{`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			res, err := run(&options{
+				peers:   []string{server.PeerInfo().HostPort},
+				service: server.ServiceName(),
+				method:  "echo",
+				format:  tchannel.JSON,
+				timeout: testutils.Timeout(time.Second),
+				arg3:    tt.arg3,
+				headers: map[string]string{"k": "v"},
+			})
+			if tt.wantErr {
+				assert.Error(t, err, "Expected call to fail")
+				return
+			}
+			require.NoError(t, err, "JSON call failed")
+			assert.Equal(t, tt.wantOK, res.OK, "Unexpected ok")
+			assert.Equal(t, tt.wantBody, res.Body, "Unexpected body")
+			assert.Equal(t, tt.wantHeaders, res.Headers, "Unexpected headers")
+		})
+	}
+}
+
+func TestIntrospectCall(t *testing.T) {
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+
+	opts, err := parseFlags([]string{"-p", server.PeerInfo().HostPort, "-introspect"}, &bytes.Buffer{})
+	require.NoError(t, err, "Failed to parse flags")
+
+	res, err := run(opts)
+	require.NoError(t, err, "Introspection call failed")
+	require.True(t, res.OK, "Expected call to succeed")
+	body, ok := res.Body.(map[string]interface{})
+	require.True(t, ok, "Expected introspection state, got %v", res.Body)
+	localPeer, ok := body["localPeer"].(map[string]interface{})
+	require.True(t, ok, "Expected local peer in introspection state, got %v", body)
+	assert.Equal(t, server.ServiceName(), localPeer["serviceName"], "Unexpected introspected service name")
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/uber/tchannel-go/thrift"
+	"github.com/uber/tchannel-go/thrift/dynamic"
+)
+
+// thriftRequest encodes the JSON arguments of a Thrift method using the
+// method's definition.
+type thriftRequest struct {
+	opts *options
+	m    *dynamic.Method
+	body interface{}
+}
+
+func newThriftRequest(opts *options) (request, error) {
+	idl, err := dynamic.Parse(opts.thriftFile)
+	if err != nil {
+		return nil, err
+	}
+	return newIDLRequest(idl, opts)
+}
+
+func newIDLRequest(idl *dynamic.IDL, opts *options) (request, error) {
+	parts := strings.SplitN(opts.method, "::", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("thrift method %q must be in the form Service::method", opts.method)
+	}
+
+	m, err := idl.Method(parts[0], parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	req := &thriftRequest{opts: opts, m: m}
+	if req.body, err = req.decodeArgs(opts.arg3); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// decodeArgs decodes the JSON arguments, keeping numbers as json.Number so
+// that i64 values don't lose precision.
+func (r *thriftRequest) decodeArgs(arg3 string) (interface{}, error) {
+	if arg3 == "" {
+		return nil, nil
+	}
+
+	var args interface{}
+	decoder := json.NewDecoder(strings.NewReader(arg3))
+	decoder.UseNumber()
+	if err := decoder.Decode(&args); err != nil {
+		return nil, fmt.Errorf("arg3 is not valid JSON: %v", err)
+	}
+	return args, nil
+}
+
+func (r *thriftRequest) method() string {
+	return r.opts.method
+}
+
+func (r *thriftRequest) args() ([]byte, []byte, error) {
+	var arg2, arg3 bytes.Buffer
+	if err := thrift.WriteHeaders(&arg2, r.opts.headers); err != nil {
+		return nil, nil, err
+	}
+
+	args, err := r.m.Args(r.body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode arguments: %v", err)
+	}
+	if err := thrift.WriteStruct(&arg3, args); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode arguments: %v", err)
+	}
+	return arg2.Bytes(), arg3.Bytes(), nil
+}
+
+func (r *thriftRequest) decode(res *response, arg2, arg3 []byte) error {
+	if len(arg2) > 0 {
+		headers, err := thrift.ReadHeaders(bytes.NewReader(arg2))
+		if err != nil {
+			return fmt.Errorf("failed to decode response headers: %v", err)
+		}
+		if len(headers) > 0 {
+			res.Headers = headers
+		}
+	}
+
+	result := r.m.NewResult()
+	if err := thrift.ReadStruct(bytes.NewReader(arg3), result); err != nil {
+		return fmt.Errorf("failed to decode result: %v", err)
+	}
+
+	if res.OK {
+		res.Body = result.Success()
+		return nil
+	}
+	if ex := result.Exception(); ex != nil {
+		res.Exception = ex.Name
+		res.Body = ex.Value
+	}
+	return nil
+}
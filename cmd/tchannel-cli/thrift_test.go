@@ -0,0 +1,189 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/testutils"
+	"github.com/uber/tchannel-go/thrift"
+	"github.com/uber/tchannel-go/thrift/dynamic"
+	gen "github.com/uber/tchannel-go/thrift/gen-go/test"
+
+	"github.com/samuel/go-thrift/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func thriftType(name string) *parser.Type {
+	return &parser.Type{Name: name}
+}
+
+// testIDL returns the parsed form of SimpleService in thrift/test.thrift.
+func testIDL() *dynamic.IDL {
+	data := &parser.Struct{Name: "Data", Fields: []*parser.Field{
+		{ID: 1, Name: "b1", Type: thriftType("bool")},
+		{ID: 2, Name: "s2", Type: thriftType("string")},
+		{ID: 3, Name: "i3", Type: thriftType("i32")},
+	}}
+	simpleErr := &parser.Struct{Name: "SimpleErr", Fields: []*parser.Field{
+		{ID: 1, Name: "message", Type: thriftType("string")},
+	}}
+	service := &parser.Service{Name: "SimpleService", Methods: map[string]*parser.Method{
+		"Call": {
+			Name:       "Call",
+			ReturnType: thriftType("Data"),
+			Arguments:  []*parser.Field{{ID: 1, Name: "arg", Type: thriftType("Data")}},
+		},
+		"Simple": {
+			Name:       "Simple",
+			Exceptions: []*parser.Field{{ID: 1, Name: "simpleErr", Type: thriftType("SimpleErr")}},
+		},
+	}}
+	extended := &parser.Service{Name: "ExtendedService", Extends: "SimpleService"}
+
+	return dynamic.NewIDL(map[string]*parser.Thrift{
+		"test.thrift": {
+			Structs:    map[string]*parser.Struct{"Data": data},
+			Exceptions: map[string]*parser.Struct{"SimpleErr": simpleErr},
+			Services:   map[string]*parser.Service{"SimpleService": service, "ExtendedService": extended},
+		},
+	}, "test.thrift")
+}
+
+type simpleHandler struct{}
+
+func (simpleHandler) Call(ctx thrift.Context, arg *gen.Data) (*gen.Data, error) {
+	ctx.SetResponseHeaders(ctx.Headers())
+	return &gen.Data{B1: !arg.B1, S2: arg.S2 + "!", I3: arg.I3 * 2}, nil
+}
+
+func (simpleHandler) Simple(ctx thrift.Context) error {
+	return &gen.SimpleErr{Message: "simple failed"}
+}
+
+func (simpleHandler) SimpleFuture(ctx thrift.Context) error {
+	return errors.New("unimplemented")
+}
+
+func TestThriftCall(t *testing.T) {
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+	thrift.NewServer(server).Register(gen.NewTChanSimpleServiceServer(simpleHandler{}))
+
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+	sc := client.GetSubChannel(server.ServiceName())
+	sc.Peers().Add(server.PeerInfo().HostPort)
+
+	tests := []struct {
+		msg           string
+		method        string
+		arg3          string
+		wantOK        bool
+		wantBody      interface{}
+		wantHeaders   interface{}
+		wantException string
+		wantErr       string
+	}{
+		{
+			msg:         "success",
+			method:      "SimpleService::Call",
+			arg3:        `{"arg": {"b1": true, "s2": "foo", "i3": 21}}`,
+			wantOK:      true,
+			wantBody:    map[string]interface{}{"b1": false, "s2": "foo!", "i3": int32(42)},
+			wantHeaders: map[string]string{"k": "v"},
+		},
+		{
+			msg:           "exception",
+			method:        "SimpleService::Simple",
+			wantBody:      map[string]interface{}{"message": "simple failed"},
+			wantException: "simpleErr",
+		},
+		{
+			msg:     "unknown field",
+			method:  "SimpleService::Call",
+			arg3:    `{"arg": {"b2": true}}`,
+			wantErr: "unknown field b2",
+		},
+		{
+			msg:     "wrong field type",
+			method:  "SimpleService::Call",
+			arg3:    `{"arg": {"i3": "foo"}}`,
+			wantErr: "field i3: expected a number",
+		},
+		{
+			msg:     "unknown method",
+			method:  "SimpleService::Unknown",
+			wantErr: "method Unknown not found in service SimpleService",
+		},
+		{
+			msg:     "method without service",
+			method:  "Call",
+			wantErr: "must be in the form Service::method",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			opts := &options{
+				service: server.ServiceName(),
+				method:  tt.method,
+				format:  tchannel.Thrift,
+				arg3:    tt.arg3,
+				headers: map[string]string{"k": "v"},
+			}
+
+			ctx, cancel := tchannel.NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+
+			req, err := newIDLRequest(testIDL(), opts)
+			if err == nil {
+				var res *response
+				res, err = call(ctx, sc, opts, req)
+				if err == nil {
+					assert.Equal(t, tt.wantOK, res.OK, "Unexpected ok")
+					assert.Equal(t, tt.wantBody, res.Body, "Unexpected body")
+					assert.Equal(t, tt.wantException, res.Exception, "Unexpected exception")
+					if tt.wantHeaders != nil {
+						assert.Equal(t, tt.wantHeaders, res.Headers, "Unexpected headers")
+					}
+				}
+			}
+
+			if tt.wantErr != "" {
+				require.Error(t, err, "Expected call to fail")
+				assert.Contains(t, err.Error(), tt.wantErr, "Unexpected error")
+			} else {
+				assert.NoError(t, err, "Thrift call failed")
+			}
+		})
+	}
+}
+
+func TestThriftInheritedMethod(t *testing.T) {
+	req, err := newIDLRequest(testIDL(), &options{method: "ExtendedService::Call"})
+	require.NoError(t, err, "Failed to resolve inherited method")
+	assert.Equal(t, "ExtendedService::Call", req.method(), "Unexpected method name")
+}
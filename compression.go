@@ -0,0 +1,190 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+)
+
+// CompressionType is the compression applied to all frames on a connection.
+type CompressionType string
+
+const (
+	// CompressionNone disables connection-level compression.
+	CompressionNone CompressionType = ""
+
+	// CompressionFlate compresses the connection stream using DEFLATE.
+	CompressionFlate CompressionType = "flate"
+)
+
+// negotiateCompression returns the compression to use for a connection given
+// the local compression and the init params received from the remote peer.
+func negotiateCompression(local CompressionType, remote initParams) CompressionType {
+	if local == CompressionNone {
+		return CompressionNone
+	}
+	if CompressionType(remote[InitParamCompression]) != local {
+		return CompressionNone
+	}
+	return local
+}
+
+// compressedConn is a net.Conn that compresses all bytes written to it,
+// and decompresses all bytes read from it. Writes are buffered until Flush
+// is called.
+type compressedConn struct {
+	net.Conn
+
+	r io.ReadCloser
+	w *flate.Writer
+
+	// uncompressed is set once bytes are written using writeUncompressed,
+	// until w is reset to compress bytes again.
+	uncompressed bool
+	storedHeader [5]byte
+}
+
+func newCompressedConn(conn net.Conn, compression CompressionType) net.Conn {
+	// The only supported compression is flate, so there's no error for
+	// an invalid compression level.
+	w, _ := flate.NewWriter(conn, flate.DefaultCompression)
+	return &compressedConn{
+		Conn: conn,
+		r:    flate.NewReader(conn),
+		w:    w,
+	}
+}
+
+func (c *compressedConn) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	if err == io.ErrUnexpectedEOF {
+		// The flate stream is never terminated, so a closed connection
+		// results in an unexpected EOF from the decompressor.
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (c *compressedConn) Write(b []byte) (int, error) {
+	if c.uncompressed {
+		// The peer's history includes the uncompressed bytes, which w
+		// doesn't know about, so w must not refer to any earlier bytes.
+		c.w.Reset(c.Conn)
+		c.uncompressed = false
+	}
+	return c.w.Write(b)
+}
+
+// writeUncompressed writes b to the stream in DEFLATE stored blocks, so it's
+// decompressed by the peer as usual, without spending time compressing it.
+func (c *compressedConn) writeUncompressed(b []byte) (int, error) {
+	if !c.uncompressed {
+		// Flushing writes any pending compressed bytes, and ends on a byte
+		// boundary, where a stored block can start.
+		if err := c.w.Flush(); err != nil {
+			return 0, err
+		}
+		c.uncompressed = true
+	}
+
+	var written int
+	for len(b) > 0 {
+		n := len(b)
+		if n > math.MaxUint16 {
+			n = math.MaxUint16
+		}
+		if err := c.writeStoredBlock(b[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// writeStoredBlock writes b, which must fit in a single block, as a DEFLATE
+// stored block.
+func (c *compressedConn) writeStoredBlock(b []byte) error {
+	// The block header is BFINAL=0 and BTYPE=00 padded to a byte, followed by
+	// the length and its one's complement.
+	c.storedHeader[0] = 0
+	binary.LittleEndian.PutUint16(c.storedHeader[1:], uint16(len(b)))
+	binary.LittleEndian.PutUint16(c.storedHeader[3:], ^uint16(len(b)))
+	if _, err := c.Conn.Write(c.storedHeader[:]); err != nil {
+		return err
+	}
+	_, err := c.Conn.Write(b)
+	return err
+}
+
+// Flush writes any buffered data to the underlying connection, and flushes
+// the underlying connection if it's also buffered.
+func (c *compressedConn) Flush() error {
+	var err error
+	if c.uncompressed {
+		// Like the sync marker written by w.Flush, an empty stored block
+		// makes the peer's decompressor return the bytes it has read.
+		err = c.writeStoredBlock(nil)
+	} else {
+		err = c.w.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	return flushConn(c.Conn)
+}
+
+// uncompressedWriter writes to a compressedConn without compressing.
+type uncompressedWriter struct {
+	c *compressedConn
+}
+
+func (w uncompressedWriter) Write(b []byte) (int, error) {
+	return w.c.writeUncompressed(b)
+}
+
+// frameWriter returns the writer to write the frame to, which bypasses
+// connection-level compression for frames of calls that disabled it.
+func frameWriter(conn net.Conn, f *Frame) io.Writer {
+	if c, ok := conn.(*compressedConn); ok && f.uncompressed {
+		return uncompressedWriter{c}
+	}
+	return conn
+}
+
+func (c *compressedConn) Close() error {
+	c.r.Close()
+	return c.Conn.Close()
+}
+
+// flushConn flushes any buffered writes if the connection supports it.
+func flushConn(conn net.Conn) error {
+	if f, ok := conn.(interface {
+		Flush() error
+	}); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/atomic"
+)
+
+// countingProxy forwards TCP connections to dest, counting the bytes
+// sent from the client to dest.
+func countingProxy(t *testing.T, dest string) (hostPort string, sent *atomic.Int64, cancel func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Listen failed")
+
+	sent = atomic.NewInt64(0)
+	var wg sync.WaitGroup
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			outC, err := net.Dial("tcp", dest)
+			if !assert.NoError(t, err, "Dial to destination failed") {
+				c.Close()
+				return
+			}
+
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				n, _ := io.Copy(outC, c)
+				sent.Add(n)
+				outC.Close()
+			}()
+			go func() {
+				defer wg.Done()
+				io.Copy(c, outC)
+				c.Close()
+			}()
+		}
+	}()
+
+	return ln.Addr().String(), sent, func() {
+		ln.Close()
+		wg.Wait()
+	}
+}
+
+func getConnCompression(t *testing.T, ch *Channel) []CompressionType {
+	// Outbound connections may be listed under multiple peers, so dedupe by ID.
+	byID := make(map[uint32]CompressionType)
+	for _, peer := range ch.IntrospectState(nil).RootPeers {
+		for _, conn := range peer.InboundConnections {
+			byID[conn.ID] = conn.Compression
+		}
+		for _, conn := range peer.OutboundConnections {
+			byID[conn.ID] = conn.Compression
+		}
+	}
+	require.NotEmpty(t, byID, "Expected connections on channel")
+
+	var compression []CompressionType
+	for _, c := range byID {
+		compression = append(compression, c)
+	}
+	return compression
+}
+
+func TestConnectionCompression(t *testing.T) {
+	arg3 := bytes.Repeat([]byte("compress me please "), 10000)
+
+	tests := []struct {
+		msg               string
+		serverCompression CompressionType
+		clientCompression CompressionType
+		wantCompression   CompressionType
+	}{
+		{
+			msg:               "both peers support compression",
+			serverCompression: CompressionFlate,
+			clientCompression: CompressionFlate,
+			wantCompression:   CompressionFlate,
+		},
+		{
+			msg:               "server does not support compression",
+			clientCompression: CompressionFlate,
+			wantCompression:   CompressionNone,
+		},
+		{
+			msg:               "client does not support compression",
+			serverCompression: CompressionFlate,
+			wantCompression:   CompressionNone,
+		},
+	}
+
+	for _, tt := range tests {
+		opts := testutils.NewOpts().NoRelay().SetCompression(tt.serverCompression)
+		testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+			ts.Register(raw.Wrap(newTestHandler(t)), "echo")
+
+			proxyHostPort, sent, cancel := countingProxy(t, ts.HostPort())
+			defer cancel()
+
+			client := ts.NewClient(testutils.NewOpts().SetCompression(tt.clientCompression))
+			ctx, cancelCtx := NewContext(time.Second)
+			defer cancelCtx()
+
+			_, resArg3, _, err := raw.Call(ctx, client, proxyHostPort, ts.ServiceName(), "echo", nil, arg3)
+			require.NoError(t, err, "%v: call failed", tt.msg)
+			assert.Equal(t, arg3, resArg3, "%v: unexpected response", tt.msg)
+
+			assert.Equal(t, []CompressionType{tt.wantCompression}, getConnCompression(t, client),
+				"%v: unexpected client connection compression", tt.msg)
+			assert.Equal(t, []CompressionType{tt.wantCompression}, getConnCompression(t, ts.Server()),
+				"%v: unexpected server connection compression", tt.msg)
+
+			// Close the client so the proxy has counted all bytes sent.
+			client.Close()
+			cancel()
+			if tt.wantCompression == CompressionNone {
+				assert.True(t, sent.Load() > int64(len(arg3)), "%v: expected uncompressed bytes", tt.msg)
+			} else {
+				assert.True(t, sent.Load() < int64(len(arg3))/10, "%v: expected compressed bytes, sent %v", tt.msg, sent.Load())
+			}
+		})
+	}
+}
+
+func TestConnectionCompressionDisabledPerCall(t *testing.T) {
+	arg3 := bytes.Repeat([]byte("compress me please "), 10000)
+
+	opts := testutils.NewOpts().NoRelay().SetCompression(CompressionFlate)
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		ts.Register(raw.Wrap(newTestHandler(t)), "echo")
+
+		proxyHostPort, sent, cancel := countingProxy(t, ts.HostPort())
+		defer cancel()
+
+		client := ts.NewClient(testutils.NewOpts().SetCompression(CompressionFlate))
+
+		// Calls that disable compression are interleaved with compressed
+		// calls on the same connection.
+		for _, disable := range []bool{false, true, false, true, false} {
+			cb := NewContextBuilder(time.Second)
+			if disable {
+				cb.DisableCompression()
+			}
+			ctx, cancelCtx := cb.Build()
+			_, resArg3, _, err := raw.Call(ctx, client, proxyHostPort, ts.ServiceName(), "echo", nil, arg3)
+			cancelCtx()
+			require.NoError(t, err, "Call failed (disable compression: %v)", disable)
+			assert.Equal(t, arg3, resArg3, "Unexpected response (disable compression: %v)", disable)
+		}
+
+		assert.Equal(t, []CompressionType{CompressionFlate}, getConnCompression(t, client),
+			"Unexpected client connection compression")
+
+		// Close the client so the proxy has counted all bytes sent.
+		client.Close()
+		cancel()
+		assert.True(t, sent.Load() > 2*int64(len(arg3)), "Expected uncompressed bytes, sent %v", sent.Load())
+		assert.True(t, sent.Load() < 3*int64(len(arg3)), "Expected compressed bytes, sent %v", sent.Load())
+	})
+}
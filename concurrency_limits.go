@@ -0,0 +1,238 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sync"
+
+	"github.com/uber-go/atomic"
+	"golang.org/x/net/context"
+)
+
+var (
+	errEndpointConcurrencyLimit = NewSystemError(ErrCodeBusy, "too many concurrent calls to endpoint")
+	errCallerConcurrencyLimit   = NewSystemError(ErrCodeBusy, "too many concurrent calls from caller")
+)
+
+// ConcurrencyLimits limits the number of inbound calls that are handled
+// concurrently, per endpoint and per calling service. Calls over a limit wait
+// in a queue for a handler to complete, and once the queue is full, calls
+// are failed with a Busy error. Zero values mean no limit.
+type ConcurrencyLimits struct {
+	// MaxPerEndpoint limits the calls handled concurrently by each endpoint,
+	// which is the service and method being called.
+	MaxPerEndpoint int
+
+	// Endpoints overrides MaxPerEndpoint for specific endpoints, keyed by
+	// service and then method.
+	Endpoints map[string]map[string]int
+
+	// MaxPerCaller limits the calls handled concurrently from each calling
+	// service, across all endpoints.
+	MaxPerCaller int
+
+	// Callers overrides MaxPerCaller for specific calling services.
+	Callers map[string]int
+
+	// MaxQueued is the number of calls that can wait for each limit. Queued
+	// calls are failed if they time out or are cancelled before they're
+	// handled. If this is zero, calls over a limit are failed immediately.
+	// MaxQueued is ignored when inbound calls are handled by a DispatchPool,
+	// since a queued call would hold one of the pool's workers.
+	MaxQueued int
+}
+
+func (l *ConcurrencyLimits) endpointLimit(service, method string) int {
+	if max, ok := l.Endpoints[service][method]; ok {
+		return max
+	}
+	return l.MaxPerEndpoint
+}
+
+func (l *ConcurrencyLimits) callerLimit(caller string) int {
+	if max, ok := l.Callers[caller]; ok {
+		return max
+	}
+	return l.MaxPerCaller
+}
+
+type endpointKey struct {
+	service, method string
+}
+
+// concurrencyLimiter tracks the calls being handled for each endpoint and
+// caller, and is shared by all connections of a channel. Semaphores are only
+// kept while calls are using them, so the number of entries is bounded by the
+// calls in progress, rather than growing with every endpoint and caller seen.
+type concurrencyLimiter struct {
+	limits ConcurrencyLimits
+
+	sync.Mutex
+	endpoints map[endpointKey]*semaphore
+	callers   map[string]*semaphore
+}
+
+// newConcurrencyLimiter returns a limiter for the limits, or nil if no
+// limits are set. If calls are handled by a dispatch pool, calls over a limit
+// are failed rather than queued, so that workers aren't blocked waiting for
+// one endpoint or caller while calls to others wait for a worker.
+func newConcurrencyLimiter(l *ConcurrencyLimits, dispatchPool bool) *concurrencyLimiter {
+	if l == nil || (l.MaxPerEndpoint <= 0 && l.MaxPerCaller <= 0 && len(l.Endpoints) == 0 && len(l.Callers) == 0) {
+		return nil
+	}
+
+	limits := *l
+	if dispatchPool {
+		limits.MaxQueued = 0
+	}
+	return &concurrencyLimiter{
+		limits:    limits,
+		endpoints: make(map[endpointKey]*semaphore),
+		callers:   make(map[string]*semaphore),
+	}
+}
+
+// acquire waits until the call can be handled under the limits, and returns
+// a function to call once the call has been handled. If the call can't be
+// handled, it returns an error to fail the call with.
+func (cl *concurrencyLimiter) acquire(ctx context.Context, service, method, caller string) (release func(), _ error) {
+	key := endpointKey{service, method}
+	callerSem, endpointSem := cl.semaphores(key, caller)
+
+	if callerSem != nil {
+		if err := callerSem.acquire(ctx, errCallerConcurrencyLimit); err != nil {
+			cl.unref(key, caller)
+			return nil, err
+		}
+	}
+	if endpointSem != nil {
+		if err := endpointSem.acquire(ctx, errEndpointConcurrencyLimit); err != nil {
+			callerSem.release()
+			cl.unref(key, caller)
+			return nil, err
+		}
+	}
+
+	return func() {
+		endpointSem.release()
+		callerSem.release()
+		cl.unref(key, caller)
+	}, nil
+}
+
+// semaphores returns the semaphores that limit the call, which are nil for
+// any limits that aren't set. The caller must call unref once it's done with
+// them.
+func (cl *concurrencyLimiter) semaphores(key endpointKey, caller string) (callerSem, endpointSem *semaphore) {
+	cl.Lock()
+	defer cl.Unlock()
+
+	endpointSem, ok := cl.endpoints[key]
+	if !ok {
+		endpointSem = newSemaphore(cl.limits.endpointLimit(key.service, key.method), cl.limits.MaxQueued)
+		if endpointSem != nil {
+			cl.endpoints[key] = endpointSem
+		}
+	}
+
+	callerSem, ok = cl.callers[caller]
+	if !ok {
+		callerSem = newSemaphore(cl.limits.callerLimit(caller), cl.limits.MaxQueued)
+		if callerSem != nil {
+			cl.callers[caller] = callerSem
+		}
+	}
+
+	if endpointSem != nil {
+		endpointSem.refs++
+	}
+	if callerSem != nil {
+		callerSem.refs++
+	}
+	return callerSem, endpointSem
+}
+
+// unref releases the references taken by semaphores, and removes semaphores
+// that are no longer used by any calls.
+func (cl *concurrencyLimiter) unref(key endpointKey, caller string) {
+	cl.Lock()
+	defer cl.Unlock()
+
+	if s, ok := cl.endpoints[key]; ok {
+		if s.refs--; s.refs == 0 {
+			delete(cl.endpoints, key)
+		}
+	}
+	if s, ok := cl.callers[caller]; ok {
+		if s.refs--; s.refs == 0 {
+			delete(cl.callers, caller)
+		}
+	}
+}
+
+// semaphore limits the number of holders, with a bounded number of waiters.
+type semaphore struct {
+	slots     chan struct{}
+	maxQueued int32
+	queued    atomic.Int32
+
+	// refs is the number of calls using the semaphore, and is protected by
+	// the concurrencyLimiter's mutex.
+	refs int
+}
+
+// newSemaphore returns a semaphore with max slots, or nil if max is not positive.
+func newSemaphore(max, maxQueued int) *semaphore {
+	if max <= 0 {
+		return nil
+	}
+	return &semaphore{
+		slots:     make(chan struct{}, max),
+		maxQueued: int32(maxQueued),
+	}
+}
+
+func (s *semaphore) acquire(ctx context.Context, busyErr error) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if s.queued.Inc() > s.maxQueued {
+		s.queued.Dec()
+		return busyErr
+	}
+	defer s.queued.Dec()
+
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return GetContextError(ctx.Err())
+	}
+}
+
+func (s *semaphore) release() {
+	if s != nil {
+		<-s.slots
+	}
+}
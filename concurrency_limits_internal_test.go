@@ -0,0 +1,64 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestConcurrencyLimiterRemovesIdleSemaphores(t *testing.T) {
+	cl := newConcurrencyLimiter(&ConcurrencyLimits{
+		MaxPerCaller: 1,
+		Endpoints:    map[string]map[string]int{"svc": {"limited": 1}},
+	}, false /* dispatchPool */)
+
+	var releases []func()
+	for i := 0; i < 10; i++ {
+		release, err := cl.acquire(context.Background(), "svc", fmt.Sprintf("method-%v", i), fmt.Sprintf("caller-%v", i))
+		require.NoError(t, err, "acquire failed")
+		releases = append(releases, release)
+	}
+	release, err := cl.acquire(context.Background(), "svc", "limited", "caller-0")
+	assert.Equal(t, errCallerConcurrencyLimit, err, "Expected the caller limit to be enforced")
+	assert.Nil(t, release, "Unexpected release for failed acquire")
+
+	cl.Lock()
+	assert.Empty(t, cl.endpoints, "Endpoints without limits should not be tracked")
+	assert.Len(t, cl.callers, 10, "Expected a semaphore per caller with calls in progress")
+	cl.Unlock()
+
+	for _, release := range releases {
+		release()
+	}
+	release, err = cl.acquire(context.Background(), "svc", "limited", "caller-0")
+	require.NoError(t, err, "acquire failed")
+	release()
+
+	cl.Lock()
+	defer cl.Unlock()
+	assert.Empty(t, cl.endpoints, "Idle endpoint semaphores should be removed")
+	assert.Empty(t, cl.callers, "Idle caller semaphores should be removed")
+}
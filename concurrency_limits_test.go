@@ -0,0 +1,221 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// blockingServer registers methods that block until unblock is closed, and
+// returns a channel that receives a value when each call starts.
+func blockingServer(ts *testutils.TestServer, unblock <-chan struct{}, methods ...string) <-chan struct{} {
+	started := make(chan struct{}, 10)
+	for _, method := range methods {
+		ts.RegisterFunc(method, func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			started <- struct{}{}
+			<-unblock
+			return &raw.Res{}, nil
+		})
+	}
+	return started
+}
+
+func callAsync(ch *Channel, ts *testutils.TestServer, method string) <-chan error {
+	errC := make(chan error, 1)
+	go func() {
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		_, _, _, err := raw.Call(ctx, ch, ts.HostPort(), ts.ServiceName(), method, nil, nil)
+		errC <- err
+	}()
+	return errC
+}
+
+func waitStarted(t testing.TB, started <-chan struct{}) {
+	select {
+	case <-started:
+	case <-time.After(testutils.Timeout(time.Second)):
+		t.Fatal("Call was not handled")
+	}
+}
+
+func TestConcurrencyLimitPerEndpoint(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.ConcurrencyLimits = &ConcurrencyLimits{
+		MaxPerEndpoint: 1,
+		Endpoints:      map[string]map[string]int{"testService": {"unlimited": 0}},
+	}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		unblock := make(chan struct{})
+		started := blockingServer(ts, unblock, "limited", "other", "unlimited")
+		client := ts.NewClient(nil)
+
+		first := callAsync(client, ts, "limited")
+		waitStarted(t, started)
+
+		err := <-callAsync(client, ts, "limited")
+		assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Expected Busy error over the limit, got %v", err)
+
+		// Other endpoints have their own limits.
+		other := callAsync(client, ts, "other")
+		waitStarted(t, started)
+		unlimited := []<-chan error{callAsync(client, ts, "unlimited"), callAsync(client, ts, "unlimited")}
+		waitStarted(t, started)
+		waitStarted(t, started)
+
+		close(unblock)
+		for _, errC := range append(unlimited, first, other) {
+			assert.NoError(t, <-errC, "Call failed")
+		}
+		assert.NoError(t, <-callAsync(client, ts, "limited"), "Call should succeed once handlers complete")
+	})
+}
+
+func TestConcurrencyLimitQueue(t *testing.T) {
+	stats := newRecordingStatsReporter()
+	opts := testutils.NewOpts().SetStatsReporter(stats)
+	opts.ConcurrencyLimits = &ConcurrencyLimits{MaxPerEndpoint: 1, MaxQueued: 1}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		unblock := make(chan struct{})
+		started := blockingServer(ts, unblock, "limited")
+		client := ts.NewClient(nil)
+
+		recvd := func() int64 {
+			stats.Lock()
+			defer stats.Unlock()
+
+			var n int64
+			for _, v := range stats.Values["inbound.calls.recvd"] {
+				n += v.count
+			}
+			return n
+		}
+
+		// The stats reporter is shared by the runs with and without a relay.
+		base := recvd()
+		first := callAsync(client, ts, "limited")
+		waitStarted(t, started)
+		queued := callAsync(client, ts, "limited")
+		require.True(t, testutils.WaitFor(time.Second, func() bool { return recvd()-base == 2 }),
+			"Queued call was not received")
+
+		err := <-callAsync(client, ts, "limited")
+		assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Expected Busy error once the queue is full, got %v", err)
+
+		close(unblock)
+		assert.NoError(t, <-first, "Call failed")
+		assert.NoError(t, <-queued, "Queued call should be handled")
+	})
+}
+
+func TestConcurrencyLimitPerCaller(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.ConcurrencyLimits = &ConcurrencyLimits{
+		MaxPerCaller: 1,
+		Callers:      map[string]int{"privileged": 2},
+	}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		unblock := make(chan struct{})
+		started := blockingServer(ts, unblock, "m1", "m2")
+		client := ts.NewClient(testutils.NewOpts().SetServiceName("client"))
+		privileged := ts.NewClient(testutils.NewOpts().SetServiceName("privileged"))
+
+		first := callAsync(client, ts, "m1")
+		waitStarted(t, started)
+
+		err := <-callAsync(client, ts, "m2")
+		assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Expected Busy error over the caller limit, got %v", err)
+
+		privilegedCalls := []<-chan error{callAsync(privileged, ts, "m1"), callAsync(privileged, ts, "m2")}
+		waitStarted(t, started)
+		waitStarted(t, started)
+
+		close(unblock)
+		for _, errC := range append(privilegedCalls, first) {
+			assert.NoError(t, <-errC, "Call failed")
+		}
+	})
+}
+
+func TestConcurrencyLimitQueueTimeout(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.ConcurrencyLimits = &ConcurrencyLimits{MaxPerEndpoint: 1, MaxQueued: 1}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		unblock := make(chan struct{})
+		defer close(unblock)
+		started := blockingServer(ts, unblock, "limited")
+		client := ts.NewClient(nil)
+
+		callAsync(client, ts, "limited")
+		waitStarted(t, started)
+
+		ctx, cancel := NewContext(testutils.Timeout(50 * time.Millisecond))
+		defer cancel()
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "limited", nil, nil)
+		require.Error(t, err, "Queued call should time out")
+		assert.Equal(t, ErrCodeTimeout, GetSystemErrorCode(err), "Expected timeout, got %v", err)
+	})
+}
+
+func TestConcurrencyLimitDispatchPool(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay().SetDispatchPool(DispatchPoolOptions{
+		Workers:   2,
+		QueueSize: 5,
+	})
+	opts.ConcurrencyLimits = &ConcurrencyLimits{
+		Endpoints: map[string]map[string]int{"testService": {"saturated": 1}},
+		MaxQueued: 5,
+	}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		unblock := make(chan struct{})
+		started := blockingServer(ts, unblock, "saturated")
+		ts.RegisterFunc("other", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return &raw.Res{}, nil
+		})
+		client := ts.NewClient(nil)
+
+		first := callAsync(client, ts, "saturated")
+		waitStarted(t, started)
+
+		// Calls over the limit are rejected rather than queued, since a queued
+		// call would hold the only idle worker.
+		for i := 0; i < 3; i++ {
+			err := <-callAsync(client, ts, "saturated")
+			assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Expected Busy error over the limit, got %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, <-callAsync(client, ts, "other"), "Calls to other endpoints should not wait for the saturated endpoint")
+		}
+
+		close(unblock)
+		assert.NoError(t, <-first, "Call failed")
+	})
+}
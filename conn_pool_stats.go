@@ -0,0 +1,96 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"time"
+
+	"github.com/uber-go/atomic"
+)
+
+// connPoolStats periodically reports how much of each peer's connection pool
+// is in use, which can be used to right-size connection limits.
+type connPoolStats struct {
+	ch       *Channel
+	interval time.Duration
+	maxConns int
+
+	stopped atomic.Bool
+	stopCh  chan struct{}
+}
+
+// startConnPoolStats starts reporting connection pool utilization if
+// ConnectionPoolStatsInterval and MaxConnectionsPerPeer are set. It returns
+// nil if reporting is disabled.
+func startConnPoolStats(ch *Channel, opts *ChannelOptions) *connPoolStats {
+	if opts.ConnectionPoolStatsInterval <= 0 || opts.MaxConnectionsPerPeer <= 0 {
+		return nil
+	}
+
+	ps := &connPoolStats{
+		ch:       ch,
+		interval: opts.ConnectionPoolStatsInterval,
+		maxConns: opts.MaxConnectionsPerPeer,
+		stopCh:   make(chan struct{}),
+	}
+
+	go ps.pollerLoop()
+	return ps
+}
+
+// Stop stops reporting connection pool utilization.
+func (ps *connPoolStats) Stop() {
+	if ps == nil || !ps.stopped.CAS(false, true) {
+		return
+	}
+	close(ps.stopCh)
+}
+
+func (ps *connPoolStats) pollerLoop() {
+	ticker := ps.ch.timeTicker(ps.interval)
+
+	for {
+		select {
+		case <-ticker.C:
+			ps.report()
+		case <-ps.stopCh:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// report emits the connection.pool.utilization gauge for every peer with
+// active outbound connections. The value is the peer's active outbound
+// connections as a percentage of MaxConnectionsPerPeer.
+func (ps *connPoolStats) report() {
+	for hostPort, peer := range ps.ch.RootPeers().Copy() {
+		active := peer.numActiveOutbound()
+		if active == 0 {
+			continue
+		}
+
+		tags := ps.ch.StatsTags()
+		tags["peer"] = hostPort
+		utilization := int64(active * 100 / ps.maxConns)
+		ps.ch.statsReporter.UpdateGauge("connection.pool.utilization", tags, utilization)
+	}
+}
@@ -131,7 +131,9 @@ type ConnectionOptions struct {
 	// The size of send channel buffers. Defaults to 512.
 	SendBufferSize int
 
-	// The type of checksum to use when sending messages.
+	// The type of checksum to use when sending messages. crc32c and types
+	// that aren't defined by the protocol (see RegisterChecksum) fall back to
+	// crc32 on connections to peers that don't support them.
 	ChecksumType ChecksumType
 
 	// ToS class name marked on outbound packets.
@@ -141,9 +143,112 @@ type ConnectionOptions struct {
 	// By default, health checks are not enabled.
 	HealthChecks HealthCheckOptions
 
+	// KeepAlive configures keepalive pings on idle connections.
+	// By default, keepalives are not enabled.
+	KeepAlive KeepAliveOptions
+
 	// MaxCloseTime controls how long we allow a connection to complete pending
 	// calls before shutting down. Only used if it is non-zero.
 	MaxCloseTime time.Duration
+
+	// Compression is the connection-level compression advertised during the
+	// init handshake. It is only used if the remote peer advertises the same
+	// compression, otherwise the connection falls back to no compression.
+	// This is an unstable API - breaking changes are likely.
+	Compression CompressionType
+
+	// DisabledFeatures are optional protocol features that are not advertised
+	// in the init handshake, so they're not used on any connection. Disabling
+	// FeatureCompression also disables Compression. This allows rolling back
+	// a feature without changing the protocol version.
+	DisabledFeatures []ProtocolFeature
+
+	// JumboFrameSize is the maximum frame size, including the header, that's
+	// used on connections where the remote peer also supports jumbo frames
+	// (FeatureLargeFrames). Calls are sent in fewer, larger frames, which
+	// reduces the overhead of fragmenting large args. The frame size is the
+	// smaller of the two peers' sizes. If this is no larger than MaxFrameSize
+	// (the default), jumbo frames are not used. It's capped at
+	// MaxJumboFrameSize. Relays never use jumbo frames, so calls through a
+	// relay use standard frames.
+	JumboFrameSize int
+
+	// ReportProtocolStats reports per-connection frame and byte counters to
+	// the channel's StatsReporter, tagged by the remote peer and frame type.
+	// Protocol stats are always available via introspection.
+	ReportProtocolStats bool
+
+	// MaxFragmentsPerCall is the maximum number of fragments that a single
+	// inbound call request or outbound call response can span. A peer that
+	// exceeds it is sent a protocol error and the connection is closed, which
+	// protects against peers that split calls into an excessive number of tiny
+	// fragments. This is not applied to relayed calls. If this is zero (the
+	// default), the number of fragments is not limited.
+	MaxFragmentsPerCall int
+
+	// AuthProvider provides the credentials sent in the init handshake of
+	// outbound connections. If it's nil (the default), no credentials are sent.
+	AuthProvider AuthProvider
+
+	// AuthValidator authenticates inbound connections using the credentials
+	// sent in the init handshake. Connections that fail validation are
+	// rejected before any calls are made on them. If it's nil (the default),
+	// inbound connections are not authenticated.
+	AuthValidator AuthValidator
+
+	// ArgCompression lists, in order of preference, the codecs used to
+	// compress arg2 and arg3 of calls (see RegisterArgCompressor). They're
+	// advertised during the init handshake, and arguments are only compressed
+	// on connections where the remote peer advertises a codec in common.
+	// This is an unstable API - breaking changes are likely.
+	ArgCompression []string
+
+	// ArgCompressionThreshold is the minimum size in bytes of an argument
+	// before it's compressed. Defaults to 1KB.
+	ArgCompressionThreshold int
+
+	// MinTimeToLive is the smallest remaining TTL that outbound and relayed
+	// calls are sent with. Calls with less time remaining are failed with
+	// ErrTimeout instead of being sent. Defaults to a millisecond, since
+	// smaller TTLs are sent as 0.
+	MinTimeToLive time.Duration
+
+	// MinInboundTimeToLive is the smallest remaining TTL that inbound calls
+	// are accepted with. Calls that arrive with less time remaining, or
+	// whose deadline has already passed, are failed immediately with
+	// ErrTimeout rather than running the handler, or being forwarded by a
+	// relay. This avoids wasted work when callers are already timing out.
+	MinInboundTimeToLive time.Duration
+
+	// MaxConnectionAge is how long an outbound connection is used before
+	// it's closed, so calls are rebalanced across the instances behind a
+	// load balancer or VIP. Calls in progress complete on the old connection,
+	// while new calls use a new connection. Connections are closed up to 10%
+	// early so they don't all reconnect at once. If this is zero (the
+	// default), connections are not closed based on their age.
+	MaxConnectionAge time.Duration
+
+	// SocketReadBufferSize and SocketWriteBufferSize set the size of the
+	// operating system's receive and send buffers for TCP connections. If
+	// they're zero (the default), the operating system's defaults are used.
+	SocketReadBufferSize  int
+	SocketWriteBufferSize int
+
+	// EnableNagle enables Nagle's algorithm on TCP connections, which
+	// delays small writes to combine them into fewer packets. By default,
+	// Nagle's algorithm is disabled so frames are sent immediately.
+	EnableNagle bool
+
+	// WriteCoalescing configures buffering of frames written to connections
+	// so that many small frames are written using a single syscall. By
+	// default, frames are written as soon as there are no more frames
+	// waiting to be written.
+	WriteCoalescing WriteCoalescingOptions
+
+	// FrameSniffer is notified of every frame sent and received on
+	// connections, which can be used to capture frames for debugging with a
+	// FrameCaptureWriter. If it's nil (the default), frames are not sniffed.
+	FrameSniffer FrameSniffer
 }
 
 // connectionEvents are the events that can be triggered by a connection.
@@ -156,6 +261,14 @@ type connectionEvents struct {
 
 	// OnExchangeUpdated is called when a message exchange added or removed.
 	OnExchangeUpdated func(c *Connection)
+
+	// OnPeerEjected is called when failed health checks eject the connection's
+	// peer until the given time, or with a zero time once they recover.
+	OnPeerEjected func(c *Connection, until time.Time)
+
+	// OnPeerDead is called when keepalive pings detect that the connection's
+	// peer is dead.
+	OnPeerDead func(c *Connection)
 }
 
 // Connection represents a connection to a remote peer.
@@ -169,16 +282,23 @@ type Connection struct {
 	localPeerInfo   LocalPeerInfo
 	remotePeerInfo  PeerInfo
 	sendCh          chan *Frame
+	prioritySendCh  chan *Frame
 	stopCh          chan struct{}
 	state           connectionState
 	stateMut        sync.RWMutex
 	inbound         *messageExchangeSet
 	outbound        *messageExchangeSet
 	handler         Handler
+	peerBandwidth   atomic.Value // *peerBandwidth
 	nextMessageID   atomic.Uint32
 	events          connectionEvents
 	commonStatsTags map[string]string
 	relay           *Relayer
+	compression     CompressionType
+	argCompression  *argCompression
+	protocolStats   *connectionStats
+	features        connectionFeatures
+	maxFrameSize    int
 
 	// outboundHP is the host:port we used to create this outbound connection.
 	// It may not match remotePeerInfo.HostPort, in which case the connection is
@@ -190,6 +310,14 @@ type Connection struct {
 	closeNetworkCalled atomic.Bool
 	// stoppedExchanges is atomically set when exchanges are stopped due to error.
 	stoppedExchanges atomic.Bool
+	// failed is set when the connection is closed due to an error while it
+	// was active, rather than being closed gracefully.
+	failed atomic.Bool
+	// lastError is the last connLastError seen on the connection.
+	lastError atomic.Value
+	// remoteDraining is set once the remote peer signals that it is draining,
+	// after which no new outbound calls are started on this connection.
+	remoteDraining atomic.Bool
 	// pendingMethods is the number of methods running that may block closing of sendCh.
 	pendingMethods atomic.Int64
 	// remotePeerAddress is used as a cache for remote peer address parsed into individual
@@ -202,6 +330,18 @@ type Connection struct {
 	healthCheckDone    chan struct{}
 	healthCheckHistory *healthHistory
 
+	// keepAliveCtx/Quit are used to stop keepalives.
+	keepAliveCtx  context.Context
+	keepAliveQuit context.CancelFunc
+	keepAliveDone chan struct{}
+
+	// maxAgeTimer closes the connection once it reaches MaxConnectionAge.
+	maxAgeTimer *time.Timer
+
+	// lastReceived is when the last call frame was received, used to decide
+	// whether a keepalive ping is needed. (unix time, nano)
+	lastReceived atomic.Int64
+
 	// lastActivity is used to track how long the connection has been idle.
 	// (unix time, nano)
 	lastActivity atomic.Int64
@@ -257,9 +397,19 @@ func (co ConnectionOptions) withDefaults() ConnectionOptions {
 		co.SendBufferSize = defaultConnectionBufferSize
 	}
 	co.HealthChecks = co.HealthChecks.withDefaults()
+	co.KeepAlive = co.KeepAlive.withDefaults()
+	if co.MinTimeToLive < time.Millisecond {
+		co.MinTimeToLive = time.Millisecond
+	}
 	return co
 }
 
+// inboundExpired returns whether an inbound call with the given TTL should
+// be failed without being handled or forwarded.
+func (co ConnectionOptions) inboundExpired(ttl time.Duration) bool {
+	return ttl <= 0 || ttl < co.MinInboundTimeToLive
+}
+
 func (ch *Channel) setConnectionTosPriority(tosPriority tos.ToS, c net.Conn) error {
 	tcpAddr, isTCP := c.RemoteAddr().(*net.TCPAddr)
 	if !isTCP {
@@ -277,8 +427,41 @@ func (ch *Channel) setConnectionTosPriority(tosPriority tos.ToS, c net.Conn) err
 	return err
 }
 
-func (ch *Channel) newConnection(conn net.Conn, initialID uint32, outboundHP string, remotePeer PeerInfo, remotePeerAddress peerAddressComponents, events connectionEvents) *Connection {
+// setSocketOptions sets the socket buffer sizes and Nagle's algorithm on TCP
+// connections, including TCP connections that use TLS.
+func setSocketOptions(opts ConnectionOptions, c net.Conn) error {
+	if nc, ok := c.(interface{ NetConn() net.Conn }); ok {
+		c = nc.NetConn()
+	}
+	tcpConn, ok := c.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+
+	if opts.SocketReadBufferSize > 0 {
+		if err := tcpConn.SetReadBuffer(opts.SocketReadBufferSize); err != nil {
+			return err
+		}
+	}
+	if opts.SocketWriteBufferSize > 0 {
+		if err := tcpConn.SetWriteBuffer(opts.SocketWriteBufferSize); err != nil {
+			return err
+		}
+	}
+	if opts.EnableNagle {
+		return tcpConn.SetNoDelay(false)
+	}
+	return nil
+}
+
+func (ch *Channel) newConnection(conn net.Conn, initialID uint32, outboundHP string, remotePeer PeerInfo, remotePeerAddress peerAddressComponents, compression CompressionType, argCompression *argCompression, checksumType ChecksumType, features connectionFeatures, events connectionEvents) *Connection {
 	opts := ch.connectionOptions.withDefaults()
+	opts.ChecksumType = checksumType
+	maxFrameSize := MaxFrameSize
+	if features.jumboFrameSize > 0 {
+		maxFrameSize = features.jumboFrameSize
+		opts.FramePool = jumboFramePool{opts.FramePool, ch.jumboFrames}
+	}
 
 	connID := _nextConnID.Inc()
 	connDirection := inbound
@@ -307,6 +490,7 @@ func (ch *Channel) newConnection(conn net.Conn, initialID uint32, outboundHP str
 		opts:               opts,
 		state:              connectionActive,
 		sendCh:             make(chan *Frame, opts.SendBufferSize),
+		prioritySendCh:     make(chan *Frame, opts.SendBufferSize),
 		stopCh:             make(chan struct{}),
 		localPeerInfo:      peerInfo,
 		remotePeerInfo:     remotePeer,
@@ -314,11 +498,15 @@ func (ch *Channel) newConnection(conn net.Conn, initialID uint32, outboundHP str
 		outboundHP:         outboundHP,
 		inbound:            newMessageExchangeSet(log, messageExchangeSetInbound),
 		outbound:           newMessageExchangeSet(log, messageExchangeSetOutbound),
-		handler:            ch.handler,
+		handler:            ch.middleware,
 		events:             events,
 		commonStatsTags:    ch.commonStatsTags,
+		argCompression:     argCompression,
+		features:           features,
+		maxFrameSize:       maxFrameSize,
 		healthCheckHistory: newHealthHistory(),
 		lastActivity:       *atomic.NewInt64(ch.timeNow().UnixNano()),
+		lastReceived:       *atomic.NewInt64(ch.timeNow().UnixNano()),
 	}
 
 	if tosPriority := opts.TosPriority; tosPriority > 0 {
@@ -326,6 +514,23 @@ func (ch *Channel) newConnection(conn net.Conn, initialID uint32, outboundHP str
 			log.WithFields(ErrField(err)).Error("Failed to set ToS priority.")
 		}
 	}
+	if err := setSocketOptions(opts, conn); err != nil {
+		log.WithFields(ErrField(err)).Error("Failed to set socket options.")
+	}
+
+	var protocolStatsReporter StatsReporter
+	if opts.ReportProtocolStats {
+		protocolStatsReporter = ch.statsReporter
+	}
+	c.protocolStats = newConnectionStats(protocolStatsReporter, ch.commonStatsTags, remotePeer)
+
+	if opts.WriteCoalescing.enabled() {
+		c.conn = newCoalescingConn(c.conn, opts.WriteCoalescing)
+	}
+	if compression != CompressionNone {
+		c.compression = compression
+		c.conn = newCompressedConn(c.conn, compression)
+	}
 
 	c.nextMessageID.Store(initialID)
 	c.log = log
@@ -333,6 +538,10 @@ func (ch *Channel) newConnection(conn net.Conn, initialID uint32, outboundHP str
 	c.outbound.onRemoved = c.checkExchanges
 	c.inbound.onAdded = c.onExchangeAdded
 	c.outbound.onAdded = c.onExchangeAdded
+	c.inbound.maxFragments = opts.MaxFragmentsPerCall
+	c.outbound.maxFragments = opts.MaxFragmentsPerCall
+	c.inbound.timeNow = c.timeNow
+	c.outbound.timeNow = c.timeNow
 
 	if ch.RelayHost() != nil {
 		c.relay = NewRelayer(ch, c)
@@ -355,6 +564,12 @@ func (c *Connection) IsActive() bool {
 	return c.readState() == connectionActive
 }
 
+// canStartCalls returns whether new outbound calls can be started on this
+// connection: it must be active, and the remote peer must not be draining.
+func (c *Connection) canStartCalls() bool {
+	return c.IsActive() && !c.remoteDraining.Load()
+}
+
 func (c *Connection) callOnActive() {
 	log := c.log
 	if remoteVersion := c.remotePeerInfo.Version; remoteVersion != (PeerVersion{}) {
@@ -375,6 +590,14 @@ func (c *Connection) callOnActive() {
 		c.healthCheckDone = make(chan struct{})
 		go c.healthCheck(c.connID)
 	}
+
+	if c.opts.KeepAlive.enabled() {
+		c.keepAliveCtx, c.keepAliveQuit = context.WithCancel(context.Background())
+		c.keepAliveDone = make(chan struct{})
+		go c.keepAlive(c.connID)
+	}
+
+	c.startMaxAge()
 }
 
 func (c *Connection) callOnCloseStateChange() {
@@ -440,7 +663,8 @@ func (c *Connection) handlePingReq(frame *Frame) {
 	}
 }
 
-// sendMessage sends a standalone message (typically a control message)
+// sendMessage sends a standalone message (typically a control message).
+// Control messages are sent with a high priority.
 func (c *Connection) sendMessage(msg message) error {
 	frame := c.opts.FramePool.Get()
 	if err := frame.write(msg); err != nil {
@@ -449,7 +673,7 @@ func (c *Connection) sendMessage(msg message) error {
 	}
 
 	select {
-	case c.sendCh <- frame:
+	case c.prioritySendCh <- frame:
 		return nil
 	default:
 		return ErrSendBufferFull
@@ -477,6 +701,18 @@ func (c *Connection) RemotePeerInfo() PeerInfo {
 	return c.remotePeerInfo
 }
 
+// sendBufferBackedUp returns whether the send buffer is more than half full.
+// Best-effort calls are not started on backed up connections, so the rest of
+// the buffer is available to critical calls.
+func (c *Connection) sendBufferBackedUp() bool {
+	return len(c.sendCh) > cap(c.sendCh)/2
+}
+
+// ProtocolStats returns the low-level protocol counters for this connection.
+func (c *Connection) ProtocolStats() ConnectionProtocolStats {
+	return c.protocolStats.snapshot()
+}
+
 // NextMessageID reserves the next available message id for this connection
 func (c *Connection) NextMessageID() uint32 {
 	return c.nextMessageID.Inc()
@@ -486,12 +722,16 @@ func (c *Connection) NextMessageID() uint32 {
 func (c *Connection) SendSystemError(id uint32, span Span, err error) error {
 	frame := c.opts.FramePool.Get()
 
-	if err := frame.write(&errorMessage{
+	errMsg := &errorMessage{
 		id:      id,
 		errCode: GetSystemErrorCode(err),
 		tracing: span,
 		message: GetSystemErrorMessage(err),
-	}); err != nil {
+	}
+	if se, ok := err.(SystemError); ok && se.NoRetry() {
+		errMsg.headers = transportHeaders{NoRetry: "1"}
+	}
+	if err := frame.write(errMsg); err != nil {
 
 		// This shouldn't happen - it means writing the errorMessage is broken.
 		c.log.WithFields(
@@ -533,6 +773,7 @@ func (c *Connection) logConnectionError(site string, err error) error {
 	if err == io.EOF {
 		c.log.Debugf("Connection got EOF")
 	} else {
+		c.recordError(err)
 		logger := c.log.WithFields(
 			LogField{"site", site},
 			ErrField(err),
@@ -563,7 +804,12 @@ func (c *Connection) connectionError(site string, err error) error {
 	}
 
 	c.stopHealthCheck()
+	c.stopKeepAlive()
+	c.stopMaxAge()
 	err = c.logConnectionError(site, err)
+	if c.readState() == connectionActive {
+		c.failed.Store(true)
+	}
 	c.close(closeLogFields...)
 
 	// On any connection error, notify the exchanges of this error.
@@ -579,6 +825,7 @@ func (c *Connection) connectionError(site string, err error) error {
 
 func (c *Connection) protocolError(id uint32, err error) error {
 	c.log.WithFields(ErrField(err)).Warn("Protocol error.")
+	c.recordError(err)
 	sysErr := NewWrappedSystemError(ErrCodeProtocol, err)
 	c.SendSystemError(id, Span{}, sysErr)
 	// Don't close the connection until the error has been sent.
@@ -595,6 +842,21 @@ func (c *Connection) protocolError(id uint32, err error) error {
 	return sysErr
 }
 
+// tooManyFragments is called when a peer sends more fragments for a call than
+// MaxFragmentsPerCall allows, and treats it as a protocol error.
+func (c *Connection) tooManyFragments(frame *Frame) {
+	// Fragments that are received while the connection is closing are dropped.
+	if c.readState() != connectionActive {
+		return
+	}
+
+	c.log.WithFields(
+		LogField{"header", frame.Header},
+		LogField{"maxFragments", c.opts.MaxFragmentsPerCall},
+	).Warn("Call exceeded the maximum number of fragments.")
+	c.protocolError(frame.Header.ID, errTooManyFragments)
+}
+
 // withStateLock performs an action with the connection state mutex locked
 func (c *Connection) withStateLock(f func() error) error {
 	c.stateMut.Lock()
@@ -644,28 +906,45 @@ func (c *Connection) readFrames(_ uint32) {
 			return
 		}
 
-		frame := c.opts.FramePool.Get()
-		if err := frame.ReadBody(headerBuf, c.conn); err != nil {
+		frame := c.getFrame(headerBuf)
+		if err := frame.readBody(headerBuf, c.conn, c.maxFrameSize); err != nil {
 			handleErr(err)
 			c.opts.FramePool.Release(frame)
 			return
 		}
 
+		c.throttleReceive(frame)
 		c.updateLastActivity(frame)
-
-		var releaseFrame bool
-		if c.relay == nil {
-			releaseFrame = c.handleFrameNoRelay(frame)
-		} else {
-			releaseFrame = c.handleFrameRelay(frame)
-		}
-		if releaseFrame {
-			c.opts.FramePool.Release(frame)
+		if c.opts.KeepAlive.enabled() {
+			c.updateLastReceived(frame)
 		}
+		c.protocolStats.frameReceived(frame)
+		c.sniffFrame(FrameReceived, frame)
+		c.handleFrame(frame)
+	}
+}
+
+// handleFrame handles a single frame read from the connection, releasing it
+// if it's no longer needed.
+func (c *Connection) handleFrame(frame *Frame) {
+	var releaseFrame bool
+	if c.relay == nil {
+		releaseFrame = c.handleFrameNoRelay(frame)
+	} else {
+		releaseFrame = c.handleFrameRelay(frame)
+	}
+	if releaseFrame {
+		c.opts.FramePool.Release(frame)
 	}
 }
 
 func (c *Connection) handleFrameRelay(frame *Frame) bool {
+	// Connection-level error frames (such as the drain signal) are not part of
+	// any relayed call, so they're handled locally.
+	if frame.Header.messageType == messageTypeError && frame.Header.ID == invalidMessageID {
+		return c.handleFrameNoRelay(frame)
+	}
+
 	switch frame.Header.messageType {
 	case messageTypeCallReq, messageTypeCallReqContinue, messageTypeCallRes, messageTypeCallResContinue, messageTypeError:
 		if err := c.relay.Relay(frame); err != nil {
@@ -711,35 +990,70 @@ func (c *Connection) handleFrameNoRelay(frame *Frame) bool {
 	return releaseFrame
 }
 
-// writeFrames is the main loop that pulls frames from the send channel and
+// writeFrames is the main loop that pulls frames from the send channels and
 // writes them to the connection.
 func (c *Connection) writeFrames(_ uint32) {
+	for {
+		f := c.nextFrame()
+		if f == nil {
+			// Close the network once we're no longer writing frames.
+			c.closeNetwork()
+			return
+		}
+
+		if err := c.writeFrame(f); err != nil {
+			c.connectionError("write frames", err)
+			return
+		}
+	}
+}
+
+// nextFrame returns the next frame to write. Frames in prioritySendCh are
+// always returned before frames in sendCh. It returns nil once the connection
+// is stopped and there are no more frames to write.
+func (c *Connection) nextFrame() *Frame {
 	for {
 		select {
-		case f := <-c.sendCh:
-			if c.log.Enabled(LogLevelDebug) {
-				c.log.Debugf("Writing frame %s", f.Header)
-			}
+		case f := <-c.prioritySendCh:
+			return f
+		default:
+		}
 
-			c.updateLastActivity(f)
-			err := f.WriteOut(c.conn)
-			c.opts.FramePool.Release(f)
-			if err != nil {
-				c.connectionError("write frames", err)
-				return
-			}
+		select {
+		case f := <-c.prioritySendCh:
+			return f
+		case f := <-c.sendCh:
+			return f
 		case <-c.stopCh:
-			// If there are frames in sendCh, we want to drain them.
-			if len(c.sendCh) > 0 {
-				continue
+			// If there are frames in the send channels, we want to drain them.
+			if c.pendingFrames() == 0 {
+				return nil
 			}
-			// Close the network once we're no longer writing frames.
-			c.closeNetwork()
-			return
 		}
 	}
 }
 
+// writeFrame writes a single frame to the connection, and releases it.
+func (c *Connection) writeFrame(f *Frame) error {
+	if c.log.Enabled(LogLevelDebug) {
+		c.log.Debugf("Writing frame %s", f.Header)
+	}
+
+	c.throttleSend(f)
+	c.updateLastActivity(f)
+	c.protocolStats.frameSent(f)
+	c.sniffFrame(FrameSent, f)
+	err := f.WriteOut(frameWriter(c.conn, f))
+	f.uncompressed = false
+	c.opts.FramePool.Release(f)
+	if err == nil && c.pendingFrames() == 0 {
+		// Buffered writers (e.g. compression) are flushed once there
+		// are no more frames waiting to be written.
+		err = flushConn(c.conn)
+	}
+	return err
+}
+
 // updateLastActivity marks when the last message was received/sent on the channel.
 // This is used for monitoring idle connections and timing them out.
 func (c *Connection) updateLastActivity(frame *Frame) {
@@ -896,6 +1210,8 @@ func (c *Connection) closeNetwork() {
 	// channel would be dangerous since other goroutine might be sending)
 	c.log.Debugf("Closing underlying network connection")
 	c.stopHealthCheck()
+	c.stopKeepAlive()
+	c.stopMaxAge()
 	c.closeNetworkCalled.Store(true)
 	if err := c.conn.Close(); err != nil {
 		c.log.WithFields(
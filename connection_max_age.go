@@ -0,0 +1,59 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import "time"
+
+// maxAgeJitter is the fraction of MaxConnectionAge that connections may close
+// early, so connections created together don't all reconnect at once.
+const maxAgeJitter = 0.1
+
+// startMaxAge starts the timer that closes an outbound connection once it
+// reaches the MaxConnectionAge.
+func (c *Connection) startMaxAge() {
+	maxAge := c.opts.MaxConnectionAge
+	if maxAge <= 0 || c.connDirection != outbound {
+		return
+	}
+
+	jitter := time.Duration(peerRng.Float64() * maxAgeJitter * float64(maxAge))
+	c.maxAgeTimer = time.AfterFunc(maxAge-jitter, c.closeAged)
+}
+
+func (c *Connection) stopMaxAge() {
+	if c.maxAgeTimer != nil {
+		c.maxAgeTimer.Stop()
+	}
+}
+
+// closeAged closes a connection that reached its max age. Calls in progress
+// are allowed to complete, while new calls to the peer use a new connection.
+func (c *Connection) closeAged() {
+	if !c.IsActive() {
+		return
+	}
+
+	c.statsReporter.IncCounter("connections.age-closed", c.commonStatsTags, 1)
+	c.close(LogFields{
+		{"reason", "max connection age reached"},
+		{"maxConnectionAge", c.opts.MaxConnectionAge},
+	}...)
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestMaxConnectionAge(t *testing.T) {
+	const maxAge = 100 * time.Millisecond
+
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+		testutils.RegisterFunc(ts.Server(), "slow", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			time.Sleep(2 * maxAge)
+			return &raw.Res{}, nil
+		})
+
+		stats := newRecordingStatsReporter()
+		clientOpts := testutils.NewOpts().SetStatsReporter(stats)
+		clientOpts.DefaultConnectionOptions.MaxConnectionAge = maxAge
+		client := ts.NewClient(clientOpts)
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		peer := client.RootPeers().GetOrAdd(ts.HostPort())
+		conn, err := peer.GetConnection(ctx)
+		require.NoError(t, err, "Failed to get connection")
+
+		// The slow call outlives the connection, but completes on it.
+		started := time.Now()
+		_, _, _, err = raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "slow", nil, nil)
+		require.NoError(t, err, "Call in progress should complete on an aged connection")
+		assert.True(t, time.Since(started) > maxAge, "Call should outlive the max connection age")
+
+		require.True(t, testutils.WaitFor(time.Second, func() bool {
+			return !conn.IsActive()
+		}), "Connection should be closed once it reaches the max age")
+		assert.Equal(t, int64(1), stats.getCount("connections.age-closed", client.StatsTags()),
+			"Expected max age close to be reported")
+
+		// New calls use a new connection.
+		require.NoError(t, testutils.CallEcho(client, ts.HostPort(), ts.ServiceName(), nil), "Call failed")
+		newConn, err := peer.GetConnection(ctx)
+		require.NoError(t, err, "Failed to get connection")
+		assert.NotEqual(t, conn, newConn, "Expected a new connection after the max age")
+	})
+}
+
+func TestMaxConnectionAgeInbound(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	opts.DefaultConnectionOptions.MaxConnectionAge = 20 * time.Millisecond
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+		client := ts.NewClient(nil)
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		conn, err := client.RootPeers().GetOrAdd(ts.HostPort()).GetConnection(ctx)
+		require.NoError(t, err, "Failed to get connection")
+		require.NoError(t, testutils.CallEcho(client, ts.HostPort(), ts.ServiceName(), nil), "Call failed")
+
+		// Only outbound connections are closed based on their age.
+		time.Sleep(testutils.Timeout(50 * time.Millisecond))
+		assert.True(t, conn.IsActive(), "Inbound connections should not be closed based on age")
+	})
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import "github.com/uber-go/atomic"
+
+// protocolFrameTypes are the frame types tracked by connection protocol stats,
+// with the names used in introspection and as the "frame-type" stats tag.
+var protocolFrameTypes = [...]struct {
+	messageType messageType
+	name        string
+}{
+	{messageTypeInitReq, "init-req"},
+	{messageTypeInitRes, "init-res"},
+	{messageTypeCallReq, "call-req"},
+	{messageTypeCallRes, "call-res"},
+	{messageTypeCallReqContinue, "call-req-continue"},
+	{messageTypeCallResContinue, "call-res-continue"},
+	{messageTypePingReq, "ping-req"},
+	{messageTypePingRes, "ping-res"},
+	{messageTypeError, "error"},
+}
+
+const (
+	// unknownFrameTypeIndex is the index used for frames with an unknown type.
+	unknownFrameTypeIndex = len(protocolFrameTypes)
+
+	// numFrameTypeCounters includes an additional counter for unknown frame types.
+	numFrameTypeCounters = len(protocolFrameTypes) + 1
+)
+
+func frameTypeIndex(t messageType) int {
+	for i, ft := range protocolFrameTypes {
+		if ft.messageType == t {
+			return i
+		}
+	}
+	return unknownFrameTypeIndex
+}
+
+func frameTypeName(i int) string {
+	if i == unknownFrameTypeIndex {
+		return "unknown"
+	}
+	return protocolFrameTypes[i].name
+}
+
+// ConnectionProtocolStats are low-level protocol counters for a single connection.
+// Only frames sent or received after the init handshake are counted.
+type ConnectionProtocolStats struct {
+	// FramesSent is the number of frames sent, keyed by frame type.
+	FramesSent map[string]uint64 `json:"framesSent"`
+	// FramesReceived is the number of frames received, keyed by frame type.
+	FramesReceived map[string]uint64 `json:"framesReceived"`
+	// BytesSent is the total size of all frames sent.
+	BytesSent uint64 `json:"bytesSent"`
+	// BytesReceived is the total size of all frames received.
+	BytesReceived uint64 `json:"bytesReceived"`
+	// ErrorsSent is the number of error frames sent.
+	ErrorsSent uint64 `json:"errorsSent"`
+	// ErrorsReceived is the number of error frames received.
+	ErrorsReceived uint64 `json:"errorsReceived"`
+}
+
+// frameCounters counts frames in a single direction.
+type frameCounters struct {
+	frames [numFrameTypeCounters]atomic.Uint64
+	bytes  atomic.Uint64
+}
+
+func (fc *frameCounters) byType() map[string]uint64 {
+	m := make(map[string]uint64)
+	for i := range fc.frames {
+		if n := fc.frames[i].Load(); n > 0 {
+			m[frameTypeName(i)] = n
+		}
+	}
+	return m
+}
+
+// connectionStats tracks protocol stats for a connection, and optionally
+// reports them to a StatsReporter.
+type connectionStats struct {
+	sent     frameCounters
+	received frameCounters
+
+	// reporter is nil unless protocol stats are reported.
+	reporter StatsReporter
+	tags     [numFrameTypeCounters]map[string]string
+}
+
+func newConnectionStats(reporter StatsReporter, commonTags map[string]string, remotePeer PeerInfo) *connectionStats {
+	cs := &connectionStats{reporter: reporter}
+	if reporter == nil {
+		return cs
+	}
+
+	for i := range cs.tags {
+		tags := make(map[string]string, len(commonTags)+2)
+		for k, v := range commonTags {
+			tags[k] = v
+		}
+		tags["peer"] = remotePeer.HostPort
+		tags["frame-type"] = frameTypeName(i)
+		cs.tags[i] = tags
+	}
+	return cs
+}
+
+func (cs *connectionStats) frameSent(f *Frame) {
+	cs.record(&cs.sent, "connection.frames-sent", "connection.bytes-sent", f)
+}
+
+func (cs *connectionStats) frameReceived(f *Frame) {
+	cs.record(&cs.received, "connection.frames-received", "connection.bytes-received", f)
+}
+
+func (cs *connectionStats) record(fc *frameCounters, framesKey, bytesKey string, f *Frame) {
+	i := frameTypeIndex(f.Header.messageType)
+	size := uint64(f.Header.frameSize())
+	fc.frames[i].Inc()
+	fc.bytes.Add(size)
+
+	if cs.reporter != nil {
+		cs.reporter.IncCounter(framesKey, cs.tags[i], 1)
+		cs.reporter.IncCounter(bytesKey, cs.tags[i], int64(size))
+	}
+}
+
+func (cs *connectionStats) snapshot() ConnectionProtocolStats {
+	errIdx := frameTypeIndex(messageTypeError)
+	return ConnectionProtocolStats{
+		FramesSent:     cs.sent.byType(),
+		FramesReceived: cs.received.byType(),
+		BytesSent:      cs.sent.bytes.Load(),
+		BytesReceived:  cs.received.bytes.Load(),
+		ErrorsSent:     cs.sent.frames[errIdx].Load(),
+		ErrorsReceived: cs.received.frames[errIdx].Load(),
+	}
+}
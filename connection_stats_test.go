@@ -0,0 +1,147 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func getSingleConnState(t *testing.T, ch *Channel, hostPort string, outbound bool) ConnectionRuntimeState {
+	state := ch.IntrospectState(nil)
+	peer, ok := state.RootPeers[hostPort]
+	require.True(t, ok, "Missing peer %v", hostPort)
+
+	conns := peer.InboundConnections
+	if outbound {
+		conns = peer.OutboundConnections
+	}
+	require.Len(t, conns, 1, "Expected a single connection to %v", hostPort)
+	return conns[0]
+}
+
+func TestConnectionProtocolStats(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		ts.Register(raw.Wrap(newTestHandler(t)), "echo")
+		ts.Register(ErrorHandlerFunc(func(ctx context.Context, call *InboundCall) error {
+			if _, err := raw.ReadArgs(call); err != nil {
+				return err
+			}
+			return ErrServerBusy
+		}), "busy")
+
+		client := ts.NewClient(nil)
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", []byte("arg2"), []byte("arg3"))
+		require.NoError(t, err, "Call failed")
+
+		clientStats := getSingleConnState(t, client, ts.HostPort(), true /* outbound */).ProtocolStats
+		assert.Equal(t, map[string]uint64{"call-req": 1}, clientStats.FramesSent, "Unexpected client frames sent")
+		assert.Equal(t, map[string]uint64{"call-res": 1}, clientStats.FramesReceived, "Unexpected client frames received")
+		assert.True(t, clientStats.BytesSent > 0, "Expected client to send bytes")
+		assert.True(t, clientStats.BytesReceived > 0, "Expected client to receive bytes")
+		assert.Zero(t, clientStats.ErrorsReceived, "Unexpected errors received")
+
+		_, _, _, err = raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "busy", nil, nil)
+		require.Error(t, err, "Call should fail")
+
+		clientConn := getSingleConnState(t, client, ts.HostPort(), true /* outbound */)
+		clientStats2 := clientConn.ProtocolStats
+		assert.Equal(t, map[string]uint64{"call-req": 2}, clientStats2.FramesSent, "Unexpected client frames sent")
+		assert.Equal(t, map[string]uint64{"call-res": 1, "error": 1}, clientStats2.FramesReceived, "Unexpected client frames received")
+		assert.True(t, clientStats2.BytesSent > clientStats.BytesSent, "Expected bytes sent to increase")
+		assert.Equal(t, uint64(1), clientStats2.ErrorsReceived, "Unexpected errors received")
+
+		// The server's view of the connection mirrors the client's.
+		serverStats := getSingleConnState(t, ts.Server(), clientConn.LocalHostPort, false /* outbound */).ProtocolStats
+		assert.Equal(t, clientStats2.FramesSent, serverStats.FramesReceived, "Server received frame mismatch")
+		assert.Equal(t, clientStats2.FramesReceived, serverStats.FramesSent, "Server sent frame mismatch")
+		assert.Equal(t, clientStats2.BytesSent, serverStats.BytesReceived, "Server received bytes mismatch")
+		assert.Equal(t, clientStats2.BytesReceived, serverStats.BytesSent, "Server sent bytes mismatch")
+		assert.Equal(t, uint64(1), serverStats.ErrorsSent, "Unexpected errors sent")
+	})
+}
+
+func TestConnectionProtocolStatsReported(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		ts.Register(raw.Wrap(newTestHandler(t)), "echo")
+
+		statsReporter := newRecordingStatsReporter()
+		clientOpts := testutils.NewOpts().SetStatsReporter(statsReporter)
+		clientOpts.DefaultConnectionOptions.ReportProtocolStats = true
+		client := ts.NewClient(clientOpts)
+
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", []byte("arg2"), []byte("arg3"))
+		require.NoError(t, err, "Call failed")
+
+		connStats := getSingleConnState(t, client, ts.HostPort(), true /* outbound */).ProtocolStats
+		counterFor := func(name, frameType string) int64 {
+			statsReporter.Lock()
+			defer statsReporter.Unlock()
+
+			for tags, v := range statsReporter.Values[name] {
+				if strings.Contains(tags, "frame-type = "+frameType) && strings.Contains(tags, "peer = "+ts.HostPort()) {
+					return v.count
+				}
+			}
+			return 0
+		}
+
+		assert.EqualValues(t, 1, counterFor("connection.frames-sent", "call-req"), "Unexpected frames-sent")
+		assert.EqualValues(t, 1, counterFor("connection.frames-received", "call-res"), "Unexpected frames-received")
+		assert.EqualValues(t, connStats.BytesSent, counterFor("connection.bytes-sent", "call-req"), "Unexpected bytes-sent")
+		assert.EqualValues(t, connStats.BytesReceived, counterFor("connection.bytes-received", "call-res"), "Unexpected bytes-received")
+	})
+}
+
+func TestPeerReconnections(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		client := ts.NewClient(nil)
+
+		for i := 0; i < 3; i++ {
+			ctx, cancel := NewContext(time.Second)
+			_, err := client.Connect(ctx, ts.HostPort())
+			cancel()
+			require.NoError(t, err, "Connect failed")
+		}
+
+		peer := client.IntrospectState(nil).RootPeers[ts.HostPort()]
+		assert.EqualValues(t, 2, peer.Reconnections, "Unexpected reconnections")
+	})
+}
@@ -21,6 +21,7 @@
 package tchannel_test
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -362,6 +363,70 @@ func TestBadRequest(t *testing.T) {
 	})
 }
 
+func TestUnknownServiceVsUnknownMethod(t *testing.T) {
+	busyHandler := HandlerFunc(func(ctx context.Context, call *InboundCall) {
+		call.Response().SendSystemError(ErrServerBusy)
+	})
+
+	tests := []struct {
+		msg            string
+		unknownHandler Handler
+		service        string
+		method         string
+		wantCode       SystemErrCode
+	}{
+		{
+			msg:      "unknown method on channel service",
+			method:   "unknown",
+			wantCode: ErrCodeBadRequest,
+		},
+		{
+			msg:      "unknown method on registered service",
+			service:  "known",
+			method:   "unknown",
+			wantCode: ErrCodeBadRequest,
+		},
+		{
+			msg:      "unknown service",
+			service:  "unknown",
+			method:   "echo",
+			wantCode: ErrCodeDeclined,
+		},
+		{
+			msg:            "unknown service with custom handler",
+			unknownHandler: busyHandler,
+			service:        "unknown",
+			method:         "echo",
+			wantCode:       ErrCodeBusy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			opts := testutils.NewOpts().
+				NoRelay().
+				AddLogFilter("Couldn't find handler.", 1)
+			opts.UnknownServiceHandler = tt.unknownHandler
+
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				testutils.RegisterEcho(ts.Server().GetSubChannel("known"), nil)
+
+				service := tt.service
+				if service == "" {
+					service = ts.ServiceName()
+				}
+
+				ctx, cancel := NewContext(time.Second)
+				defer cancel()
+
+				_, _, _, err := raw.Call(ctx, ts.NewClient(nil), ts.HostPort(), service, tt.method, nil, nil)
+				require.Error(t, err, "Call should fail")
+				assert.Equal(t, tt.wantCode, GetSystemErrorCode(err), "Unexpected error code: %v", err)
+			})
+		})
+	}
+}
+
 func TestNoTimeout(t *testing.T) {
 	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
 		ts.Register(raw.Wrap(newTestHandler(t)), "Echo")
@@ -450,6 +515,165 @@ func TestUnexpectedHandlerError(t *testing.T) {
 	})
 }
 
+func TestMaxFragmentsPerCall(t *testing.T) {
+	const maxFragments = 5
+
+	opts := testutils.NewOpts().
+		AddLogFilter("Call exceeded the maximum number of fragments.", 1).
+		AddLogFilter("Protocol error.", 1).
+		NoRelay()
+	opts.DefaultConnectionOptions.MaxFragmentsPerCall = maxFragments
+
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		ts.Register(HandlerFunc(func(ctx context.Context, call *InboundCall) {
+			args, err := raw.ReadArgs(call)
+			if err != nil {
+				return
+			}
+			raw.WriteResponse(call.Response(), &raw.Res{Arg3: args.Arg3})
+		}), "echo")
+
+		client := ts.NewClient(testutils.NewOpts().
+			AddLogFilter("Peer reported protocol error.", 1).
+			AddLogFilter("Connection error.", 1))
+
+		// callWithFlushes sends arg3 one byte at a time, flushing each byte
+		// as a separate fragment.
+		callWithFlushes := func(flushes int) ([]byte, error) {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+
+			call, err := client.BeginCall(ctx, ts.HostPort(), ts.ServiceName(), "echo", nil)
+			require.NoError(t, err, "BeginCall failed")
+			require.NoError(t, NewArgWriter(call.Arg2Writer()).Write(nil), "Write arg2 failed")
+
+			w, err := call.Arg3Writer()
+			require.NoError(t, err, "Arg3Writer failed")
+			for i := 0; i < flushes; i++ {
+				if _, err := w.Write([]byte{'a'}); err != nil {
+					break
+				}
+				if err := w.Flush(); err != nil {
+					break
+				}
+			}
+			w.Close()
+
+			var arg2, arg3 []byte
+			if err := NewArgReader(call.Response().Arg2Reader()).Read(&arg2); err != nil {
+				return nil, err
+			}
+			err = NewArgReader(call.Response().Arg3Reader()).Read(&arg3)
+			return arg3, err
+		}
+
+		// The first fragment contains arg1, arg2 and the first byte of arg3.
+		arg3, err := callWithFlushes(maxFragments - 1)
+		require.NoError(t, err, "Call under the fragment limit failed")
+		assert.Equal(t, strings.Repeat("a", maxFragments-1), string(arg3), "Unexpected response")
+
+		_, err = callWithFlushes(maxFragments * 2)
+		require.Error(t, err, "Call over the fragment limit should fail")
+		assert.Equal(t, ErrCodeProtocol, GetSystemErrorCode(err), "Unexpected error: %v", err)
+
+		// The connection is closed, but new calls use a new connection.
+		arg3, err = callWithFlushes(1)
+		require.NoError(t, err, "Call after the fragment limit was hit failed")
+		assert.Equal(t, "a", string(arg3), "Unexpected response")
+	})
+}
+
+// lockedBuffer is a bytes.Buffer that can be written to concurrently.
+type lockedBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.String()
+}
+
+func TestErrorSanitizer(t *testing.T) {
+	internalErr := errors.New("query failed: user=admin password=hunter2")
+	safeErr := NewSystemError(ErrCodeDeclined, "request could not be processed")
+
+	tests := []struct {
+		msg       string
+		sanitizer func(error) error
+		wantCode  SystemErrCode
+		wantMsg   string
+		wantLog   bool
+	}{
+		{
+			msg:      "no sanitizer",
+			wantCode: ErrCodeUnexpected,
+			wantMsg:  internalErr.Error(),
+		},
+		{
+			msg: "sanitized",
+			sanitizer: func(err error) error {
+				if err == internalErr {
+					return safeErr
+				}
+				return err
+			},
+			wantCode: ErrCodeDeclined,
+			wantMsg:  "request could not be processed",
+			wantLog:  true,
+		},
+		{
+			msg:       "sanitizer returns the original error",
+			sanitizer: func(err error) error { return err },
+			wantCode:  ErrCodeUnexpected,
+			wantMsg:   internalErr.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			logOut := &lockedBuffer{}
+			opts := testutils.NewOpts().
+				AddLogFilter("Unexpected handler error", 1).
+				NoRelay()
+			opts.Logger = NewLogger(logOut)
+			opts.ErrorSanitizer = tt.sanitizer
+
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				ts.Register(ErrorHandlerFunc(func(ctx context.Context, call *InboundCall) error {
+					if _, err := raw.ReadArgs(call); err != nil {
+						return err
+					}
+					return internalErr
+				}), "internal")
+
+				ctx, cancel := NewContext(time.Second)
+				defer cancel()
+
+				_, _, _, err := raw.Call(ctx, ts.Server(), ts.HostPort(), ts.ServiceName(), "internal", nil, nil)
+				require.Error(t, err, "Call should fail")
+				assert.Equal(t, tt.wantCode, GetSystemErrorCode(err), "Unexpected error code")
+				assert.Equal(t, tt.wantMsg, GetSystemErrorMessage(err), "Unexpected error message")
+			})
+
+			logs := logOut.String()
+			if tt.wantLog {
+				assert.Contains(t, logs, "Sending sanitized error to caller.", "Sanitized error should be logged")
+				assert.Contains(t, logs, internalErr.Error(), "Original error should be logged in full")
+			} else {
+				assert.NotContains(t, logs, "Sending sanitized error to caller.", "No sanitized error should be logged")
+			}
+		})
+	}
+}
+
 type onErrorTestHandler struct {
 	*testHandler
 	onError func(ctx context.Context, err error)
@@ -489,6 +713,43 @@ func TestTimeout(t *testing.T) {
 	})
 }
 
+func TestMinTimeToLive(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	opts.DefaultConnectionOptions.MinTimeToLive = 100 * time.Millisecond
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), func() {
+			t.Errorf("Call with less than MinTimeToLive remaining should not be sent")
+		})
+
+		ctx, cancel := NewContext(50 * time.Millisecond)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, ts.Server(), ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		assert.Equal(t, ErrTimeout, err, "Unexpected error")
+	})
+}
+
+func TestMinInboundTimeToLive(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.DefaultConnectionOptions.MinInboundTimeToLive = 100 * time.Millisecond
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), func() {
+			t.Errorf("Call with less than MinInboundTimeToLive remaining should not be handled")
+		})
+
+		client := ts.NewClient(nil)
+		ctx, cancel := NewContext(50 * time.Millisecond)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		assert.Equal(t, ErrTimeout, err, "Unexpected error")
+
+		calls := relaytest.NewMockStats()
+		calls.Add(client.ServiceName(), ts.ServiceName(), "echo").Failed("relay-timeout").End()
+		ts.AssertRelayStats(calls)
+	})
+}
+
 func TestLargeMethod(t *testing.T) {
 	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
 		ctx, cancel := NewContext(time.Second)
@@ -33,6 +33,7 @@ type contextKey int
 const (
 	contextKeyTChannel contextKey = iota
 	contextKeyHeaders
+	contextKeyBaggage
 )
 
 type tchannelCtxParams struct {
@@ -60,6 +61,18 @@ type IncomingCall interface {
 	// transport header.
 	RoutingDelegate() string
 
+	// BestEffort returns whether the call is best-effort, from the BestEffort
+	// transport header. Best-effort calls may be shed before other calls.
+	BestEffort() bool
+
+	// Priority returns the priority of the call from the Priority transport
+	// header. The response is sent with the same priority.
+	Priority() CallPriority
+
+	// ArgScheme returns the format of the call's arguments from the ArgScheme
+	// transport header, e.g. "raw", "json" or "thrift".
+	ArgScheme() string
+
 	// LocalPeer returns the local peer information.
 	LocalPeer() LocalPeerInfo
 
@@ -0,0 +1,118 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// BaggageHeaderPrefix is the prefix of the transport headers that carry
+// baggage. The baggage key "user" is sent in the "bg-user" transport header.
+const BaggageHeaderPrefix = "bg-"
+
+const (
+	// Transport header names are limited to 16 bytes by the protocol, and
+	// values are length-prefixed with a single byte.
+	maxBaggageKeyLen   = 16 - len(BaggageHeaderPrefix)
+	maxBaggageValueLen = 255
+
+	// maxTransportHeaders is the number of transport headers that can be
+	// sent in a call req, since the count is a single byte.
+	maxTransportHeaders = 255
+)
+
+var errBaggageTooLarge = errors.New("baggage exceeds the transport header limits")
+
+// WithBaggage returns a context with the given baggage added to any baggage
+// already in ctx. Baggage is sent in transport headers, so unlike application
+// headers it's available for every arg scheme and is visible to relays.
+//
+// Baggage from an inbound call is added to the context passed to the
+// handler, and is forwarded on any calls made using that context (or a
+// context derived from it), so it propagates across services without
+// handlers copying it. An empty value removes the key.
+//
+// Keys are limited to 13 bytes and values to 255 bytes; calls with baggage
+// that exceeds these limits fail.
+func WithBaggage(ctx context.Context, baggage map[string]string) context.Context {
+	parent := Baggage(ctx)
+	merged := make(map[string]string, len(parent)+len(baggage))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range baggage {
+		if v == "" {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+	return context.WithValue(ctx, contextKeyBaggage, merged)
+}
+
+// Baggage returns the baggage in the context. The returned map must not be
+// modified, use WithBaggage to change the baggage instead.
+func Baggage(ctx context.Context) map[string]string {
+	baggage, _ := ctx.Value(contextKeyBaggage).(map[string]string)
+	return baggage
+}
+
+// withInboundBaggage adds the baggage in an inbound call's transport headers
+// to the context.
+func withInboundBaggage(ctx context.Context, headers transportHeaders) context.Context {
+	var baggage map[string]string
+	for k, v := range headers {
+		if !strings.HasPrefix(string(k), BaggageHeaderPrefix) {
+			continue
+		}
+		if baggage == nil {
+			baggage = make(map[string]string)
+		}
+		baggage[strings.TrimPrefix(string(k), BaggageHeaderPrefix)] = v
+	}
+	if baggage == nil {
+		return ctx
+	}
+	// Keep the context's request headers visible via ContextWithHeaders.
+	return Wrap(context.WithValue(ctx, contextKeyBaggage, baggage))
+}
+
+// addBaggageHeaders adds the baggage in the context to the transport headers
+// of an outbound call.
+func addBaggageHeaders(ctx context.Context, headers transportHeaders) error {
+	baggage := Baggage(ctx)
+	if len(baggage) == 0 {
+		return nil
+	}
+	if len(headers)+len(baggage) > maxTransportHeaders {
+		return errBaggageTooLarge
+	}
+	for k, v := range baggage {
+		if len(k) > maxBaggageKeyLen || len(v) > maxBaggageValueLen {
+			return errBaggageTooLarge
+		}
+		headers[TransportHeaderName(BaggageHeaderPrefix+k)] = v
+	}
+	return nil
+}
@@ -60,6 +60,7 @@ type ContextBuilder struct {
 	// The new (child) context inherits a number of properties from the parent context:
 	//   - context fields, accessible via `ctx.Value(key)`
 	//   - headers if parent is a ContextWithHeaders, unless replaced via SetHeaders()
+	//   - the deadline and cancellation, unless the DetachDeadline call option is set
 	ParentContext context.Context
 
 	// Hidden fields: we do not want users outside of tchannel to set these.
@@ -124,6 +125,15 @@ func (cb *ContextBuilder) SetRoutingKey(rk string) *ContextBuilder {
 	return cb
 }
 
+// SetAuthToken sets the AuthToken call option ("auth" transport header).
+func (cb *ContextBuilder) SetAuthToken(token string) *ContextBuilder {
+	if cb.CallOptions == nil {
+		cb.CallOptions = new(CallOptions)
+	}
+	cb.CallOptions.AuthToken = token
+	return cb
+}
+
 // SetRoutingDelegate sets the RoutingDelegate call options ("rd" transport header).
 func (cb *ContextBuilder) SetRoutingDelegate(rd string) *ContextBuilder {
 	if cb.CallOptions == nil {
@@ -133,6 +143,36 @@ func (cb *ContextBuilder) SetRoutingDelegate(rd string) *ContextBuilder {
 	return cb
 }
 
+// SetMaxTotalDuration sets the MaxTotalDuration call option, which bounds the
+// time spent on a call across all retries made by RunWithRetry.
+func (cb *ContextBuilder) SetMaxTotalDuration(d time.Duration) *ContextBuilder {
+	if cb.CallOptions == nil {
+		cb.CallOptions = new(CallOptions)
+	}
+	cb.CallOptions.MaxTotalDuration = d
+	return cb
+}
+
+// SetDetachDeadline sets the DetachDeadline call option, so the built context
+// uses its own timeout instead of being bound by the ParentContext's deadline.
+func (cb *ContextBuilder) SetDetachDeadline() *ContextBuilder {
+	if cb.CallOptions == nil {
+		cb.CallOptions = new(CallOptions)
+	}
+	cb.CallOptions.DetachDeadline = true
+	return cb
+}
+
+// DisableCompression sets the DisableCompression call option, so the call's
+// args are sent without compression.
+func (cb *ContextBuilder) DisableCompression() *ContextBuilder {
+	if cb.CallOptions == nil {
+		cb.CallOptions = new(CallOptions)
+	}
+	cb.CallOptions.DisableCompression = true
+	return cb
+}
+
 // SetConnectTimeout sets the ConnectionTimeout for this context.
 // The context timeout applies to the whole call, while the connect
 // timeout only applies to creating a new connection.
@@ -224,6 +264,9 @@ func (cb *ContextBuilder) Build() (ContextWithHeaders, context.CancelFunc) {
 		// Unwrap any headerCtx, since we'll be rewrapping anyway.
 		parent = headerCtx.Context
 	}
+	if cb.CallOptions != nil && cb.CallOptions.DetachDeadline {
+		parent = detachedContext{parent}
+	}
 
 	var (
 		ctx    context.Context
@@ -242,3 +285,17 @@ func (cb *ContextBuilder) Build() (ContextWithHeaders, context.CancelFunc) {
 	ctx = context.WithValue(ctx, contextKeyTChannel, params)
 	return WrapWithHeaders(ctx, cb.getHeaders()), cancel
 }
+
+// detachedContext exposes the values of its parent context, but not the
+// parent's deadline or cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
@@ -115,7 +115,27 @@ func WrapWithHeaders(ctx context.Context, headers map[string]string) ContextWith
 	return headerCtx{Context: newCtx}
 }
 
-// WithoutHeaders hides any TChannel headers from the given context.
+// WithHeaders returns a Context that can be used to make a call with request
+// headers. Unlike WrapWithHeaders, the given headers are added to any request
+// headers already in ctx, such as the headers of an inbound call, which are
+// kept unless they're overridden. The headers in ctx are not modified.
+//
+// Application headers are sent by the Thrift, JSON and Proto arg schemes. Use
+// WithBaggage for values that must be sent with every arg scheme.
+func WithHeaders(ctx context.Context, headers map[string]string) ContextWithHeaders {
+	parent := Wrap(ctx).Headers()
+	merged := make(map[string]string, len(parent)+len(headers))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range headers {
+		merged[k] = v
+	}
+	return WrapWithHeaders(ctx, merged)
+}
+
+// WithoutHeaders hides any TChannel headers and baggage from the given context.
 func WithoutHeaders(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, contextKeyBaggage, nil)
 	return context.WithValue(context.WithValue(ctx, contextKeyTChannel, nil), contextKeyHeaders, nil)
 }
@@ -20,6 +20,7 @@
 package tchannel_test
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -123,6 +124,41 @@ func TestShardKeyPropagates(t *testing.T) {
 	})
 }
 
+func TestArgSchemePropagates(t *testing.T) {
+	WithVerifiedServer(t, nil, func(ch *Channel, hostPort string) {
+		peerInfo := ch.PeerInfo()
+		testutils.RegisterFunc(ch, "test", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return &raw.Res{
+				Arg3: []byte(CurrentCall(ctx).ArgScheme()),
+			}, nil
+		})
+
+		tests := []struct {
+			format Format
+			want   string
+		}{
+			{format: "", want: "raw"},
+			{format: Raw, want: "raw"},
+			{format: JSON, want: "json"},
+			{format: Thrift, want: "thrift"},
+		}
+
+		sc := ch.GetSubChannel(peerInfo.ServiceName)
+		ch.Peers().Add(hostPort)
+		for _, tt := range tests {
+			ctx, cancel := NewContext(time.Second)
+			res, err := raw.CallV2(ctx, sc, raw.CArgs{
+				Method:      "test",
+				CallOptions: &CallOptions{Format: tt.format},
+			})
+			cancel()
+
+			require.NoError(t, err, "Call with format %q failed", tt.format)
+			assert.Equal(t, tt.want, string(res.Arg3), "Unexpected arg scheme for format %q", tt.format)
+		}
+	})
+}
+
 func TestCurrentCallWithNilResult(t *testing.T) {
 	ctx, cancel := NewContext(time.Second)
 	defer cancel()
@@ -177,6 +213,73 @@ func TestContextBuilderParentContextMergeHeaders(t *testing.T) {
 	goroutines.VerifyNoLeaks(t, nil)
 }
 
+func TestContextBuilderDetachDeadline(t *testing.T) {
+	parent, parentCancel := NewContextBuilder(testutils.Timeout(50*time.Millisecond)).
+		AddHeader("header key", "header value").
+		Build()
+	parentDeadline, _ := parent.Deadline()
+
+	derived, cancel := NewContextBuilder(time.Minute).SetParentContext(parent).Build()
+	defer cancel()
+	deadline, ok := derived.Deadline()
+	require.True(t, ok, "Derived context should have a deadline")
+	assert.Equal(t, parentDeadline, deadline, "Derived context should use the parent's earlier deadline")
+
+	detached, cancel := NewContextBuilder(time.Minute).
+		SetParentContext(parent).
+		SetDetachDeadline().
+		Build()
+	defer cancel()
+	deadline, ok = detached.Deadline()
+	require.True(t, ok, "Detached context should have a deadline")
+	assert.True(t, deadline.After(parentDeadline.Add(30*time.Second)),
+		"Detached context should use its own timeout, got deadline %v with parent deadline %v", deadline, parentDeadline)
+	assert.Equal(t, map[string]string{"header key": "header value"}, detached.Headers(),
+		"Detached context should inherit the parent's headers")
+
+	// Cancelling the parent only cancels the derived context.
+	parentCancel()
+	assert.Error(t, derived.Err(), "Derived context should be cancelled with the parent")
+	assert.NoError(t, detached.Err(), "Detached context should not be cancelled with the parent")
+}
+
+func TestDetachDeadlineCall(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		// ttl returns the remaining time before the inbound call's deadline.
+		ts.RegisterFunc("ttl", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			deadline, _ := ctx.Deadline()
+			return &raw.Res{Arg3: []byte(deadline.Sub(time.Now()).String())}, nil
+		})
+		// fanout makes a call to ttl using a context built from the inbound context.
+		ts.RegisterFunc("fanout", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			cb := NewContextBuilder(testutils.Timeout(5 * time.Second)).SetParentContext(ctx)
+			if string(args.Arg3) == "detach" {
+				cb.SetDetachDeadline()
+			}
+			callCtx, cancel := cb.Build()
+			defer cancel()
+
+			_, arg3, _, err := raw.Call(callCtx, ts.Server(), ts.HostPort(), ts.ServiceName(), "ttl", nil, nil)
+			return &raw.Res{Arg3: arg3}, err
+		})
+
+		inboundTimeout := testutils.Timeout(200 * time.Millisecond)
+		callTTL := func(mode string) time.Duration {
+			ctx, cancel := NewContext(inboundTimeout)
+			defer cancel()
+
+			_, arg3, _, err := raw.Call(ctx, ts.NewClient(nil), ts.HostPort(), ts.ServiceName(), "fanout", nil, []byte(mode))
+			require.NoError(t, err, "Call failed")
+			ttl, err := time.ParseDuration(string(arg3))
+			require.NoError(t, err, "Failed to parse TTL")
+			return ttl
+		}
+
+		assert.True(t, callTTL("derive") <= inboundTimeout, "Derived call should be bound by the inbound deadline")
+		assert.True(t, callTTL("detach") > inboundTimeout, "Detached call should use its own deadline")
+	})
+}
+
 func TestContextBuilderParentContextReplaceHeaders(t *testing.T) {
 	ctx := getParentContext(t)
 	ctx.Headers()["fixed header"] = "fixed value"
@@ -211,6 +314,75 @@ func TestContextWrapWithHeaders(t *testing.T) {
 	assert.Equal(t, headers2, ctx2.Headers(), "Headers mismatch after WrapWithHeaders")
 }
 
+func TestContextWithHeaders(t *testing.T) {
+	ctx, cancel := NewContextBuilder(time.Second).
+		SetHeaders(map[string]string{"k1": "v1", "k2": "v2"}).
+		Build()
+	defer cancel()
+
+	ctx2 := WithHeaders(ctx, map[string]string{"k2": "v2-new", "k3": "v3"})
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2-new", "k3": "v3"}, ctx2.Headers(), "Headers should be merged")
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, ctx.Headers(), "Parent headers should not change")
+
+	ctx3 := WithHeaders(context.Background(), map[string]string{"k1": "v1"})
+	assert.Equal(t, map[string]string{"k1": "v1"}, ctx3.Headers(), "Headers mismatch without parent headers")
+}
+
+func TestContextWithBaggage(t *testing.T) {
+	ctx := WithBaggage(context.Background(), map[string]string{"k1": "v1", "k2": "v2"})
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, Baggage(ctx), "Baggage mismatch")
+
+	ctx2 := WithBaggage(ctx, map[string]string{"k1": "", "k3": "v3"})
+	assert.Equal(t, map[string]string{"k2": "v2", "k3": "v3"}, Baggage(ctx2), "Baggage should be merged")
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, Baggage(ctx), "Parent baggage should not change")
+
+	assert.Nil(t, Baggage(WithoutHeaders(ctx)), "WithoutHeaders should hide baggage")
+}
+
+func TestBaggagePropagates(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		testutils.RegisterFunc(ts.Server(), "baggage", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return &raw.Res{Arg3: []byte(Baggage(ctx)["user"] + "," + Baggage(ctx)["tenant"])}, nil
+		})
+		testutils.RegisterFunc(ts.Server(), "forward", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			// Baggage from the inbound call is forwarded along with new baggage.
+			ctx = WithBaggage(ctx, map[string]string{"tenant": "t1"})
+			_, arg3, _, err := raw.Call(ctx, ts.Server(), ts.HostPort(), ts.ServiceName(), "baggage", nil, nil)
+			return &raw.Res{Arg3: arg3}, err
+		})
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		_, arg3, _, err := raw.Call(ctx, ts.Server(), ts.HostPort(), ts.ServiceName(), "baggage", nil, nil)
+		require.NoError(t, err, "Call failed")
+		assert.Equal(t, ",", string(arg3), "Unexpected baggage without baggage")
+
+		bctx := WithBaggage(ctx, map[string]string{"user": "alice"})
+		_, arg3, _, err = raw.Call(bctx, ts.Server(), ts.HostPort(), ts.ServiceName(), "forward", nil, nil)
+		require.NoError(t, err, "Call failed")
+		assert.Equal(t, "alice,t1", string(arg3), "Baggage was not forwarded")
+	})
+}
+
+func TestBaggageTooLarge(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		tests := []map[string]string{
+			{"this-key-is-too-long": "v"},
+			{"k": strings.Repeat("v", 256)},
+		}
+		for _, baggage := range tests {
+			_, _, _, err := raw.Call(WithBaggage(ctx, baggage), ts.Server(), ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+			assert.Error(t, err, "Expected call with baggage %v to fail", baggage)
+		}
+	})
+}
+
 func TestContextWithHeadersAsContext(t *testing.T) {
 	var ctx context.Context = getParentContext(t)
 	assert.EqualValues(t, "some value", ctx.Value("some key"), "inherited from parent ctx")
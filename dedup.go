@@ -0,0 +1,276 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"bytes"
+	"container/list"
+	"sync"
+	"time"
+)
+
+const defaultDeduplicationMaxEntries = 10000
+
+var errDuplicateCallInProgress = NewSystemError(ErrCodeBusy, "a call with the same idempotency key is in progress")
+
+// dedupKey identifies a call for deduplication. Keys are scoped to the
+// caller, service and method, so the same idempotency key can be reused across
+// methods, and callers never get the results of another caller's calls.
+type dedupKey struct {
+	caller  string
+	service string
+	method  string
+	key     string
+}
+
+// dedupResult is the response of a completed call, which is replayed to
+// duplicate calls.
+type dedupResult struct {
+	applicationError bool
+	arg2             []byte
+	arg3             []byte
+}
+
+type dedupEntry struct {
+	key dedupKey
+
+	// result is nil while the original call is still in progress.
+	result  *dedupResult
+	expires time.Time
+}
+
+// dedupCache is a bounded cache of recent call results keyed by idempotency key.
+// Entries expire after the deduplication window, and when the cache is full,
+// the least recently used entry is evicted.
+type dedupCache struct {
+	sync.Mutex
+
+	window     time.Duration
+	maxEntries int
+	timeNow    func() time.Time
+
+	entries map[dedupKey]*list.Element
+	// lru has the most recently used entries at the front.
+	lru *list.List
+}
+
+// newDedupCache returns a cache for deduplicating calls if DeduplicationWindow
+// is set. It returns nil if deduplication is disabled.
+func newDedupCache(timeNow func() time.Time, opts *ChannelOptions) *dedupCache {
+	if opts.DeduplicationWindow <= 0 {
+		return nil
+	}
+
+	maxEntries := opts.DeduplicationMaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultDeduplicationMaxEntries
+	}
+
+	return &dedupCache{
+		window:     opts.DeduplicationWindow,
+		maxEntries: maxEntries,
+		timeNow:    timeNow,
+		entries:    make(map[dedupKey]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// begin is called when a call with an idempotency key is received. It returns
+// the cached result if the call is a duplicate of a completed call, and whether
+// a call with the same key is still in progress. Otherwise, the call is
+// recorded as in progress, and either complete or abandon must be called. Since
+// the call can't complete once it times out, it's only in progress for timeout.
+func (c *dedupCache) begin(k dedupKey, timeout time.Duration) (result *dedupResult, inProgress bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	now := c.timeNow()
+	if elem, ok := c.entries[k]; ok {
+		entry := elem.Value.(*dedupEntry)
+		if now.Before(entry.expires) {
+			c.lru.MoveToFront(elem)
+			return entry.result, entry.result == nil
+		}
+		c.removeLocked(elem)
+	}
+
+	c.addLocked(&dedupEntry{key: k, expires: now.Add(timeout)})
+	return nil, false
+}
+
+// complete records the result of a call, which is cached for the
+// deduplication window.
+func (c *dedupCache) complete(k dedupKey, result *dedupResult) {
+	c.Lock()
+	defer c.Unlock()
+
+	expires := c.timeNow().Add(c.window)
+	if elem, ok := c.entries[k]; ok {
+		entry := elem.Value.(*dedupEntry)
+		entry.result = result
+		entry.expires = expires
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.addLocked(&dedupEntry{key: k, result: result, expires: expires})
+}
+
+// abandon removes an in-progress call that completed without a result that
+// can be cached, so that a retry with the same key runs the handler again.
+func (c *dedupCache) abandon(k dedupKey) {
+	c.Lock()
+	defer c.Unlock()
+
+	if elem, ok := c.entries[k]; ok && elem.Value.(*dedupEntry).result == nil {
+		c.removeLocked(elem)
+	}
+}
+
+// len returns the number of entries in the cache, including expired entries
+// that have not been evicted yet.
+func (c *dedupCache) len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.lru.Len()
+}
+
+func (c *dedupCache) addLocked(entry *dedupEntry) {
+	c.entries[entry.key] = c.lru.PushFront(entry)
+
+	// Evict expired entries from the back first, and then the least recently
+	// used entries if the cache is still over capacity.
+	now := c.timeNow()
+	for back := c.lru.Back(); back != nil && c.lru.Len() > 1; back = c.lru.Back() {
+		if c.lru.Len() <= c.maxEntries && now.Before(back.Value.(*dedupEntry).expires) {
+			break
+		}
+		c.removeLocked(back)
+	}
+}
+
+func (c *dedupCache) removeLocked(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*dedupEntry).key)
+}
+
+// dedupRecorder records the response of a call with an idempotency key, so
+// that it can be cached once the call completes.
+type dedupRecorder struct {
+	cache *dedupCache
+	key   dedupKey
+	arg2  bytes.Buffer
+	arg3  bytes.Buffer
+}
+
+// recordingArgWriter is an ArgWriter that records all bytes written.
+type recordingArgWriter struct {
+	ArgWriter
+	buf *bytes.Buffer
+}
+
+func (w recordingArgWriter) Write(b []byte) (int, error) {
+	n, err := w.ArgWriter.Write(b)
+	w.buf.Write(b[:n])
+	return n, err
+}
+
+// done is called when the response has been sent. Only successful responses
+// and application errors are cached, other errors may be retried.
+func (r *dedupRecorder) done(response *InboundCallResponse) {
+	if response.systemError || response.err != nil {
+		r.failed(response.err)
+		return
+	}
+
+	r.cache.complete(r.key, &dedupResult{
+		applicationError: response.applicationError,
+		arg2:             r.arg2.Bytes(),
+		arg3:             r.arg3.Bytes(),
+	})
+}
+
+// failed is called when sending the response fails, so that a retry with the
+// same key runs the handler again.
+func (r *dedupRecorder) failed(err error) {
+	r.cache.abandon(r.key)
+}
+
+// handleDeduplicated handles an inbound call with an idempotency key. Duplicates
+// of a completed call are sent the cached result without running the handler,
+// and duplicates of a call that is still in progress are rejected as busy.
+func (c *Connection) handleDeduplicated(call *InboundCall) {
+	k := dedupKey{
+		caller:  call.CallerName(),
+		service: call.ServiceName(),
+		method:  call.MethodString(),
+		key:     call.IdempotencyKey(),
+	}
+
+	timeout := c.dedup.window
+	if deadline, ok := call.mex.ctx.Deadline(); ok {
+		timeout = deadline.Sub(time.Now())
+	}
+	result, inProgress := c.dedup.begin(k, timeout)
+	if inProgress || result != nil {
+		call.statsReporter.IncCounter("inbound.calls.deduplicated", call.commonStatsTags, 1)
+	}
+
+	switch {
+	case inProgress:
+		call.Response().SendSystemError(errDuplicateCallInProgress)
+	case result != nil:
+		if err := replayResult(call, result); err != nil {
+			call.log.WithFields(ErrField(err)).Info("Couldn't send cached result for duplicate call.")
+		}
+	default:
+		// Handlers may respond after Handle returns, so the call stays in
+		// progress until the response is sent, or sending it fails.
+		recorder := &dedupRecorder{cache: c.dedup, key: k}
+		call.response.dedup = recorder
+		call.response.onFailed = recorder.failed
+		c.handler.Handle(call.mex.ctx, call)
+	}
+}
+
+// replayResult reads the arguments of a duplicate call, and responds with the
+// cached result.
+func replayResult(call *InboundCall, result *dedupResult) error {
+	var arg2, arg3 []byte
+	if err := NewArgReader(call.Arg2Reader()).Read(&arg2); err != nil {
+		return err
+	}
+	if err := NewArgReader(call.Arg3Reader()).Read(&arg3); err != nil {
+		return err
+	}
+
+	response := call.Response()
+	if result.applicationError {
+		if err := response.SetApplicationError(); err != nil {
+			return err
+		}
+	}
+	if err := NewArgWriter(response.Arg2Writer()).Write(result.arg2); err != nil {
+		return err
+	}
+	return NewArgWriter(response.Arg3Writer()).Write(result.arg3)
+}
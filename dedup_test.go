@@ -0,0 +1,134 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDedupCache(window time.Duration, maxEntries int) (*dedupCache, *time.Time) {
+	now := time.Unix(1000, 0)
+	cache := newDedupCache(func() time.Time { return now }, &ChannelOptions{
+		DeduplicationWindow:     window,
+		DeduplicationMaxEntries: maxEntries,
+	})
+	return cache, &now
+}
+
+func TestDedupCacheDisabled(t *testing.T) {
+	assert.Nil(t, newDedupCache(time.Now, &ChannelOptions{}), "Expected no cache without a window")
+}
+
+func TestDedupCacheLifecycle(t *testing.T) {
+	cache, now := newTestDedupCache(time.Minute, 0)
+	k := dedupKey{"caller", "svc", "method", "key"}
+	result := &dedupResult{arg2: []byte("arg2"), arg3: []byte("arg3")}
+
+	got, inProgress := cache.begin(k, time.Minute)
+	assert.Nil(t, got, "Unexpected result for new call")
+	assert.False(t, inProgress, "New call should not be in progress")
+
+	got, inProgress = cache.begin(k, time.Minute)
+	assert.Nil(t, got, "Unexpected result for in progress call")
+	assert.True(t, inProgress, "Duplicate should see the call in progress")
+
+	cache.complete(k, result)
+	got, inProgress = cache.begin(k, time.Minute)
+	assert.Equal(t, result, got, "Duplicate should get the cached result")
+	assert.False(t, inProgress, "Completed call should not be in progress")
+
+	got, _ = cache.begin(dedupKey{"caller", "svc", "other-method", "key"}, time.Minute)
+	assert.Nil(t, got, "Keys should be scoped to the method")
+	got, _ = cache.begin(dedupKey{"other-caller", "svc", "method", "key"}, time.Minute)
+	assert.Nil(t, got, "Keys should be scoped to the caller")
+
+	// The window starts when the call completes.
+	*now = now.Add(time.Minute - time.Second)
+	got, _ = cache.begin(k, time.Minute)
+	assert.Equal(t, result, got, "Result should be cached within the window")
+
+	*now = now.Add(time.Second)
+	got, inProgress = cache.begin(k, time.Minute)
+	assert.Nil(t, got, "Result should expire after the window")
+	assert.False(t, inProgress, "Expired call should not be in progress")
+}
+
+func TestDedupCacheAbandon(t *testing.T) {
+	cache, _ := newTestDedupCache(time.Minute, 0)
+	k := dedupKey{"caller", "svc", "method", "key"}
+
+	cache.begin(k, time.Minute)
+	cache.abandon(k)
+	got, inProgress := cache.begin(k, time.Minute)
+	assert.Nil(t, got, "Unexpected result after abandon")
+	assert.False(t, inProgress, "Abandoned call should not be in progress")
+
+	// Abandon does not remove completed results.
+	result := &dedupResult{arg3: []byte("result")}
+	cache.complete(k, result)
+	cache.abandon(k)
+	got, _ = cache.begin(k, time.Minute)
+	assert.Equal(t, result, got, "Completed result should not be abandoned")
+}
+
+func TestDedupCacheInProgressTimeout(t *testing.T) {
+	cache, now := newTestDedupCache(time.Minute, 0)
+	k := dedupKey{"caller", "svc", "method", "key"}
+
+	cache.begin(k, time.Second)
+	_, inProgress := cache.begin(k, time.Second)
+	assert.True(t, inProgress, "Duplicate should see the call in progress")
+
+	// Once the call has timed out, it can't complete, so retries run again.
+	*now = now.Add(time.Second)
+	_, inProgress = cache.begin(k, time.Second)
+	assert.False(t, inProgress, "Timed out call should not be in progress")
+}
+
+func TestDedupCacheEviction(t *testing.T) {
+	cache, now := newTestDedupCache(time.Minute, 3)
+	key := func(k string) dedupKey { return dedupKey{"caller", "svc", "method", k} }
+
+	for _, k := range []string{"k1", "k2", "k3"} {
+		cache.begin(key(k), time.Minute)
+		cache.complete(key(k), &dedupResult{arg3: []byte(k)})
+	}
+	require.Equal(t, 3, cache.len(), "Unexpected cache size")
+
+	// Use k1 so k2 is the least recently used entry.
+	got, _ := cache.begin(key("k1"), time.Minute)
+	require.NotNil(t, got, "Expected cached result for k1")
+
+	cache.begin(key("k4"), time.Minute)
+	assert.Equal(t, 3, cache.len(), "Cache should not exceed max entries")
+	got, inProgress := cache.begin(key("k2"), time.Minute)
+	assert.Nil(t, got, "Least recently used entry should be evicted")
+	assert.False(t, inProgress, "Evicted entry should not be in progress")
+
+	// Expired entries are evicted when new entries are added.
+	*now = now.Add(2 * time.Minute)
+	cache.begin(key("k5"), time.Minute)
+	assert.Equal(t, 1, cache.len(), "Expired entries should be evicted")
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sync"
+	"time"
+)
+
+// _dispatchPoolStatsInterval is how often the dispatch pool's queue depth is
+// reported.
+const _dispatchPoolStatsInterval = time.Second
+
+// DispatchPoolOptions configures a fixed pool of goroutines that run the
+// handlers for inbound calls, instead of starting a goroutine for each call.
+// This bounds the number of goroutines under bursts of inbound calls, at the
+// cost of rejecting calls once the pool's queue is full. Calls over a
+// ConcurrencyLimits limit are also rejected rather than queued, since waiting
+// for the limit would block a worker that could handle other calls.
+type DispatchPoolOptions struct {
+	// Workers is the number of goroutines that run handlers. If this is zero
+	// (the default), each inbound call is handled on a new goroutine.
+	Workers int
+
+	// QueueSize is the number of inbound calls that can wait for a worker.
+	// Calls received while the queue is full are rejected with a busy error.
+	// If this is zero, calls are rejected unless a worker is idle.
+	QueueSize int
+}
+
+// dispatchPool runs the handlers for inbound calls on a fixed set of workers,
+// and periodically reports the inbound.dispatch.queue-depth gauge.
+type dispatchPool struct {
+	ch *Channel
+
+	mu      sync.RWMutex
+	stopped bool
+	queue   chan func()
+	stopCh  chan struct{}
+}
+
+// startDispatchPool starts the workers if DispatchPool.Workers is set. It
+// returns nil if inbound calls are handled on a goroutine per call.
+func startDispatchPool(ch *Channel, opts *ChannelOptions) *dispatchPool {
+	if opts.DispatchPool.Workers <= 0 {
+		return nil
+	}
+
+	p := &dispatchPool{
+		ch:     ch,
+		queue:  make(chan func(), opts.DispatchPool.QueueSize),
+		stopCh: make(chan struct{}),
+	}
+	for i := 0; i < opts.DispatchPool.Workers; i++ {
+		go p.worker()
+	}
+	go p.statsLoop()
+	return p
+}
+
+// submit queues f to be run by a worker, and returns false if the queue is
+// full or the pool has been stopped.
+func (p *dispatchPool) submit(f func()) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		return false
+	}
+	select {
+	case p.queue <- f:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop stops the workers once they have run all queued calls.
+func (p *dispatchPool) Stop() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return
+	}
+	p.stopped = true
+	close(p.queue)
+	close(p.stopCh)
+}
+
+func (p *dispatchPool) worker() {
+	for f := range p.queue {
+		f()
+	}
+}
+
+func (p *dispatchPool) statsLoop() {
+	ticker := p.ch.timeTicker(_dispatchPoolStatsInterval)
+
+	for {
+		select {
+		case <-ticker.C:
+			p.ch.statsReporter.UpdateGauge("inbound.dispatch.queue-depth", p.ch.StatsTags(), int64(len(p.queue)))
+		case <-p.stopCh:
+			ticker.Stop()
+			return
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/atomic"
+	"golang.org/x/net/context"
+)
+
+func TestDispatchPoolLimitsConcurrency(t *testing.T) {
+	const (
+		numCalls = 10
+		workers  = 2
+	)
+
+	opts := testutils.NewOpts().NoRelay().SetDispatchPool(DispatchPoolOptions{
+		Workers:   workers,
+		QueueSize: numCalls,
+	})
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		var active, maxActive atomic.Int32
+		ts.RegisterFunc("call", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			n := active.Inc()
+			defer active.Dec()
+			for {
+				max := maxActive.Load()
+				if n <= max || maxActive.CAS(max, n) {
+					break
+				}
+			}
+			time.Sleep(testutils.Timeout(5 * time.Millisecond))
+			return &raw.Res{}, nil
+		})
+		client := ts.NewClient(nil)
+
+		var wg sync.WaitGroup
+		for i := 0; i < numCalls; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				ctx, cancel := NewContext(testutils.Timeout(time.Second))
+				defer cancel()
+
+				_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "call", nil, nil)
+				assert.NoError(t, err, "Call failed")
+			}()
+		}
+		wg.Wait()
+
+		assert.True(t, maxActive.Load() <= workers, "Ran %v handlers concurrently, limit is %v", maxActive.Load(), workers)
+	})
+}
+
+func TestDispatchPoolRejectsWhenFull(t *testing.T) {
+	statsReporter := newRecordingStatsReporter()
+	ft := testutils.NewFakeTicker()
+	opts := testutils.NewOpts().
+		NoRelay().
+		SetStatsReporter(statsReporter).
+		SetTimeTicker(ft.New).
+		SetDispatchPool(DispatchPoolOptions{
+			Workers:   1,
+			QueueSize: 1,
+		})
+
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		started := make(chan struct{}, 2)
+		release := make(chan struct{})
+		ts.RegisterFunc("block", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			started <- struct{}{}
+			<-release
+			return &raw.Res{}, nil
+		})
+		client := ts.NewClient(nil)
+
+		call := func() error {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "block", nil, nil)
+			return err
+		}
+
+		var wg sync.WaitGroup
+		blockedCall := func() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, call(), "Queued call should succeed")
+			}()
+		}
+
+		// The first call occupies the only worker, and the second call waits
+		// in the queue.
+		blockedCall()
+		<-started
+		blockedCall()
+
+		tags := ts.Server().StatsTags()
+		require.True(t, testutils.WaitFor(time.Second, func() bool {
+			ft.TryTick()
+			depth, _ := statsReporter.getGauge("inbound.dispatch.queue-depth", tags)
+			return depth == 1
+		}), "Expected the second call to be queued")
+
+		err := call()
+		assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Expected call to be rejected with busy, got %v", err)
+		assert.EqualValues(t, 1, statsReporter.getCount("inbound.calls.dispatch-rejected", tags), "Expected rejected call to be counted")
+
+		close(release)
+		wg.Wait()
+	})
+}
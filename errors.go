@@ -95,6 +95,33 @@ var (
 
 	// ErrMethodTooLarge is a SystemError indicating that the method is too large.
 	ErrMethodTooLarge = NewSystemError(ErrCodeProtocol, "method too large")
+
+	// ErrCircuitOpen is a SystemError indicating that the call was not made
+	// because the circuit breaker for the peer, service and method is open.
+	ErrCircuitOpen = NewSystemError(ErrCodeDeclined, "circuit breaker is open")
+)
+
+// Sentinel errors for each SystemErrCode. SystemErrors match any error with
+// the same code when using errors.Is, so callers can check the code of an
+// error, even if it's wrapped, with errors.Is(err, tchannel.ErrBusy).
+var (
+	// ErrBusy matches SystemErrors with ErrCodeBusy.
+	ErrBusy = ErrServerBusy
+
+	// ErrDeclined matches SystemErrors with ErrCodeDeclined.
+	ErrDeclined = NewSystemError(ErrCodeDeclined, "declined")
+
+	// ErrUnexpected matches SystemErrors with ErrCodeUnexpected.
+	ErrUnexpected = NewSystemError(ErrCodeUnexpected, "unexpected error")
+
+	// ErrBadRequest matches SystemErrors with ErrCodeBadRequest.
+	ErrBadRequest = NewSystemError(ErrCodeBadRequest, "bad request")
+
+	// ErrNetwork matches SystemErrors with ErrCodeNetwork.
+	ErrNetwork = NewSystemError(ErrCodeNetwork, "network error")
+
+	// ErrProtocol matches SystemErrors with ErrCodeProtocol.
+	ErrProtocol = NewSystemError(ErrCodeProtocol, "protocol error")
 )
 
 // MetricsKey is a string representation of the error code that's suitable for
@@ -157,6 +184,7 @@ type SystemError struct {
 	code    SystemErrCode
 	msg     string
 	wrapped error
+	noRetry bool
 }
 
 // NewSystemError defines a new SystemError with a code and message
@@ -173,6 +201,19 @@ func NewWrappedSystemError(code SystemErrCode, wrapped error) error {
 	return SystemError{code: code, msg: fmt.Sprint(wrapped), wrapped: wrapped}
 }
 
+// NewNoRetryError marks err so that the caller is told not to retry the call,
+// regardless of the error code or the caller's RetryOptions. Errors that are
+// not SystemErrors are wrapped with ErrCodeUnexpected, the code they would be
+// sent with otherwise. Callers that don't support the hint ignore it.
+func NewNoRetryError(err error) error {
+	se, ok := err.(SystemError)
+	if !ok {
+		se = SystemError{code: ErrCodeUnexpected, msg: fmt.Sprint(err), wrapped: err}
+	}
+	se.noRetry = true
+	return se
+}
+
 // Error returns the code and message, conforming to the error interface
 func (se SystemError) Error() string {
 	return fmt.Sprintf("tchannel error %v: %s", se.Code(), se.msg)
@@ -181,6 +222,17 @@ func (se SystemError) Error() string {
 // Wrapped returns the wrapped error
 func (se SystemError) Wrapped() error { return se.wrapped }
 
+// Unwrap returns the wrapped error, for use with errors.Is and errors.As.
+func (se SystemError) Unwrap() error { return se.wrapped }
+
+// Is returns whether target is a SystemError with the same code, so that
+// errors.Is(err, ErrTimeout) matches any timeout, including timeouts that
+// were sent by the peer with a different message.
+func (se SystemError) Is(target error) bool {
+	t, ok := target.(SystemError)
+	return ok && t.code == se.code
+}
+
 // Code returns the SystemError code, for sending to a peer
 func (se SystemError) Code() SystemErrCode {
 	return se.code
@@ -191,6 +243,11 @@ func (se SystemError) Message() string {
 	return se.msg
 }
 
+// NoRetry returns whether the call that returned this error must not be retried.
+func (se SystemError) NoRetry() bool {
+	return se.noRetry
+}
+
 // GetContextError converts the context error to a tchannel error.
 func GetContextError(err error) error {
 	if err == context.DeadlineExceeded {
@@ -202,20 +259,73 @@ func GetContextError(err error) error {
 	return err
 }
 
+// IsContextError returns whether err is the result of ctx being cancelled or
+// its deadline passing, as opposed to an error sent by the peer. The context
+// must be the one used to make the call.
+func IsContextError(ctx context.Context, err error) bool {
+	ctxErr := ctx.Err()
+	if err == nil || ctxErr == nil {
+		return false
+	}
+	if unwrapErr(err, ctxErr) {
+		return true
+	}
+
+	code, ok := SystemErrorCode(err)
+	return ok && code == GetSystemErrorCode(GetContextError(ctxErr))
+}
+
 // GetSystemErrorCode returns the code to report for the given error.  If the error is a
-// SystemError, we can get the code directly.  Otherwise treat it as an unexpected error
+// SystemError, or wraps one, we can get the code directly.  Otherwise treat it as an
+// unexpected error
 func GetSystemErrorCode(err error) SystemErrCode {
 	if err == nil {
 		return ErrCodeInvalid
 	}
 
-	if se, ok := err.(SystemError); ok {
-		return se.Code()
+	if code, ok := SystemErrorCode(err); ok {
+		return code
 	}
 
 	return ErrCodeUnexpected
 }
 
+// SystemErrorCode returns the code of the SystemError in err's chain of
+// wrapped errors, and whether there is one.
+func SystemErrorCode(err error) (SystemErrCode, bool) {
+	se, ok := findSystemError(err)
+	return se.Code(), ok
+}
+
+// findSystemError returns the first SystemError in err's chain of wrapped
+// errors, following Unwrap like errors.As.
+func findSystemError(err error) (SystemError, bool) {
+	for err != nil {
+		if se, ok := err.(SystemError); ok {
+			return se, true
+		}
+		err = unwrap(err)
+	}
+	return SystemError{}, false
+}
+
+// unwrapErr returns whether target is in err's chain of wrapped errors.
+func unwrapErr(err, target error) bool {
+	for ; err != nil; err = unwrap(err) {
+		if err == target {
+			return true
+		}
+	}
+	return false
+}
+
+func unwrap(err error) error {
+	if u, ok := err.(interface{ Unwrap() error }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
+
 // GetSystemErrorMessage returns the message to report for the given error.  If the error is a
 // SystemError, we can get the underlying message. Otherwise, use the Error() method.
 func GetSystemErrorMessage(err error) string {
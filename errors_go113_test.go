@@ -0,0 +1,78 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// +build go1.13
+
+package tchannel_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+	"golang.org/x/net/context"
+)
+
+func TestErrorsIsSystemErrorCodes(t *testing.T) {
+	tests := []struct {
+		err      error
+		sentinel error
+	}{
+		{ErrServerBusy, ErrBusy},
+		{ErrChannelClosed, ErrDeclined},
+		{ErrTimeoutRequired, ErrBadRequest},
+		{NewSystemError(ErrCodeTimeout, "custom timeout"), ErrTimeout},
+		{NewWrappedSystemError(ErrCodeNetwork, errors.New("reset")), ErrNetwork},
+	}
+
+	for _, tt := range tests {
+		wrapped := fmt.Errorf("retry failed: %w", fmt.Errorf("attempt failed: %w", tt.err))
+		assert.True(t, errors.Is(wrapped, tt.sentinel), "Expected %v to match %v", tt.err, tt.sentinel)
+		assert.False(t, errors.Is(wrapped, ErrProtocol), "%v should not match other codes", tt.err)
+	}
+}
+
+func TestErrorsIsRunWithRetry(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		testutils.RegisterFunc(ts.Server(), "busy", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return nil, NewSystemError(ErrCodeBusy, "overloaded")
+		})
+
+		ctx, cancel := NewContextBuilder(testutils.Timeout(time.Second)).
+			SetRetryOptions(&RetryOptions{MaxAttempts: 2}).
+			Build()
+		defer cancel()
+
+		client := ts.NewClient(nil)
+		err := client.RunWithRetry(ctx, func(ctx context.Context, rs *RequestState) error {
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "busy", nil, nil)
+			return fmt.Errorf("attempt %v: %w", rs.Attempt, err)
+		})
+		assert.True(t, errors.Is(err, ErrBusy), "Expected busy error from the peer, got %v", err)
+		assert.False(t, IsContextError(ctx, err), "Error from the peer is not a context error")
+		assert.Equal(t, "attempt 2: tchannel error ErrCodeBusy: overloaded", err.Error(), "Call should be retried")
+	})
+}
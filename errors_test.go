@@ -24,10 +24,21 @@ import (
 	"io"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
 )
 
+// wrappedError wraps an error the same way as fmt.Errorf with %w.
+type wrappedError struct {
+	msg string
+	err error
+}
+
+func (e wrappedError) Error() string { return e.msg + ": " + e.err.Error() }
+func (e wrappedError) Unwrap() error { return e.err }
+
 func TestErrorMetricKeys(t *testing.T) {
 	codes := []SystemErrCode{
 		ErrCodeInvalid,
@@ -67,6 +78,61 @@ func TestSystemError(t *testing.T) {
 	assert.Equal(t, ErrCodeTimeout, code, "tchannel timeout error produces ErrCodeTimeout")
 }
 
+func TestSystemErrorCodeWrapped(t *testing.T) {
+	code, ok := SystemErrorCode(io.EOF)
+	assert.False(t, ok, "io.EOF is not a SystemError")
+	assert.Equal(t, ErrCodeInvalid, code, "Unexpected code for non-SystemError")
+
+	wrapped := wrappedError{"attempt failed", wrappedError{"call failed", ErrServerBusy}}
+	code, ok = SystemErrorCode(wrapped)
+	assert.True(t, ok, "Expected wrapped SystemError to be found")
+	assert.Equal(t, ErrCodeBusy, code, "Unexpected code for wrapped SystemError")
+	assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(wrapped), "GetSystemErrorCode should unwrap errors")
+
+	assert.True(t, RetryConnectionError.CanRetry(wrapped), "Wrapped busy errors should be retried")
+	assert.False(t, RetryIdempotent.CanRetry(wrappedError{"call failed", NewNoRetryError(ErrServerBusy)}),
+		"Wrapped no-retry errors should not be retried")
+}
+
+func TestSystemErrorIs(t *testing.T) {
+	received := errorMessage{errCode: ErrCodeBusy, message: "overloaded"}.AsSystemError()
+	assert.True(t, received.(SystemError).Is(ErrBusy), "Errors from the peer should match by code")
+	assert.False(t, received.(SystemError).Is(ErrDeclined), "Errors with different codes should not match")
+	assert.False(t, received.(SystemError).Is(io.EOF), "SystemErrors should not match other errors")
+}
+
+func TestIsContextError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.False(t, IsContextError(ctx, ErrRequestCancelled), "Context is not cancelled")
+
+	cancel()
+	assert.True(t, IsContextError(ctx, GetContextError(ctx.Err())), "Expected cancelled context error")
+	assert.True(t, IsContextError(ctx, wrappedError{"call failed", context.Canceled}), "Expected wrapped context error")
+	assert.False(t, IsContextError(ctx, ErrServerBusy), "Busy error is not caused by the context")
+	assert.False(t, IsContextError(ctx, ErrTimeout), "Timeout does not match a cancelled context")
+	assert.False(t, IsContextError(ctx, nil), "nil is not a context error")
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	assert.True(t, IsContextError(ctx, ErrTimeout), "Expected deadline context error")
+	assert.False(t, IsContextError(ctx, ErrRequestCancelled), "Cancellation does not match a deadline")
+}
+
+func TestNoRetryError(t *testing.T) {
+	assert.False(t, ErrServerBusy.(SystemError).NoRetry(), "Errors should be retryable by default")
+
+	err := NewNoRetryError(ErrServerBusy).(SystemError)
+	assert.True(t, err.NoRetry(), "Expected no-retry hint")
+	assert.Equal(t, ErrCodeBusy, err.Code(), "No-retry hint should keep the error code")
+	assert.Equal(t, "server busy", err.Message(), "No-retry hint should keep the error message")
+
+	err = NewNoRetryError(io.EOF).(SystemError)
+	assert.True(t, err.NoRetry(), "Expected no-retry hint")
+	assert.Equal(t, ErrCodeUnexpected, err.Code(), "Non-SystemErrors should use ErrCodeUnexpected")
+	assert.Equal(t, io.EOF, err.Wrapped(), "Expected original error to be wrapped")
+}
+
 func TestRelayMetricsKey(t *testing.T) {
 	for i := 0; i <= 256; i++ {
 		code := SystemErrCode(i)
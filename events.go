@@ -0,0 +1,247 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/uber-go/atomic"
+)
+
+// EventType is a type of event published by a channel. See Channel.Subscribe.
+type EventType int
+
+// Event types that can be subscribed to.
+const (
+	// EventConnectionOpened is published when a connection becomes active.
+	EventConnectionOpened EventType = iota + 1
+
+	// EventConnectionClosed is published when an active connection is closed.
+	EventConnectionClosed
+
+	// EventPeerAdded is published when a peer is added to the channel's root
+	// peer list, either explicitly or by a connection to or from the peer.
+	EventPeerAdded
+
+	// EventPeerEjected is published when failed active health checks eject a
+	// peer until EjectedUntil.
+	EventPeerEjected
+
+	// EventCallStarted is published when an inbound or outbound call starts.
+	EventCallStarted
+
+	// EventCallCompleted is published when an inbound or outbound call
+	// completes, successfully or not.
+	EventCallCompleted
+
+	// EventRetry is published when RunWithRetry retries a call after the
+	// attempt in Attempt failed with Err.
+	EventRetry
+
+	numEventTypes = iota
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventConnectionOpened:
+		return "connectionOpened"
+	case EventConnectionClosed:
+		return "connectionClosed"
+	case EventPeerAdded:
+		return "peerAdded"
+	case EventPeerEjected:
+		return "peerEjected"
+	case EventCallStarted:
+		return "callStarted"
+	case EventCallCompleted:
+		return "callCompleted"
+	case EventRetry:
+		return "retry"
+	default:
+		return fmt.Sprintf("EventType(%d)", int(t))
+	}
+}
+
+// Event is an event published by a channel. Fields that don't apply to the
+// event's type are left unset.
+type Event struct {
+	// Type is the type of the event.
+	Type EventType
+
+	// Time is when the event occurred.
+	Time time.Time
+
+	// HostPort is the host:port of the remote peer, if any.
+	HostPort string
+
+	// Connection is the connection for connection and call events, and the
+	// connection whose health checks failed for EventPeerEjected.
+	Connection *Connection
+
+	// Peer is the peer for peer events.
+	Peer *Peer
+
+	// EjectedUntil is when the peer is no longer ejected for EventPeerEjected.
+	EjectedUntil time.Time
+
+	// Inbound is whether a call event is for an inbound call.
+	Inbound bool
+
+	// Service and Method are the service and method of call events.
+	Service string
+	Method  string
+
+	// Latency is the latency of the call for EventCallCompleted.
+	Latency time.Duration
+
+	// ApplicationError is whether the call failed with an application error
+	// for EventCallCompleted.
+	ApplicationError bool
+
+	// Err is the error that failed the call for EventCallCompleted and
+	// EventRetry, if any.
+	Err error
+
+	// Attempt is the attempt that failed for EventRetry, starting from 1.
+	Attempt int
+}
+
+// eventBus publishes a channel's events to its subscribers. Publishing is
+// cheap when there are no subscribers for an event type, so events can be
+// published on hot paths.
+type eventBus struct {
+	sync.Mutex
+
+	timeNow     func() time.Time
+	subscribers atomic.Value // eventSubscribers
+}
+
+// eventSubscribers are the subscribers for each event type. It's replaced,
+// rather than modified, when subscribers change.
+type eventSubscribers [numEventTypes + 1][]*eventSubscriber
+
+type eventSubscriber struct {
+	f func(Event)
+}
+
+func newEventBus(timeNow func() time.Time) *eventBus {
+	b := &eventBus{timeNow: timeNow}
+	b.subscribers.Store(eventSubscribers{})
+	return b
+}
+
+func (b *eventBus) subscribe(t EventType, f func(Event)) (unsubscribe func()) {
+	if t <= 0 || t > numEventTypes {
+		panic(fmt.Sprintf("tchannel: cannot subscribe to unknown event type %v", t))
+	}
+
+	sub := &eventSubscriber{f}
+	b.update(func(subs *eventSubscribers) {
+		subs[t] = append(subs[t][:len(subs[t]):len(subs[t])], sub)
+	})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.update(func(subs *eventSubscribers) {
+				var remaining []*eventSubscriber
+				for _, s := range subs[t] {
+					if s != sub {
+						remaining = append(remaining, s)
+					}
+				}
+				subs[t] = remaining
+			})
+		})
+	}
+}
+
+func (b *eventBus) update(f func(*eventSubscribers)) {
+	b.Lock()
+	defer b.Unlock()
+
+	subs := b.subscribers.Load().(eventSubscribers)
+	f(&subs)
+	b.subscribers.Store(subs)
+}
+
+// subscribed returns whether there are subscribers for the event type, so
+// callers can avoid building events that aren't published.
+func (b *eventBus) subscribed(t EventType) bool {
+	subs := b.subscribers.Load().(eventSubscribers)
+	return len(subs[t]) > 0
+}
+
+// publish calls the event type's subscribers with the event, setting its
+// time if it isn't set.
+func (b *eventBus) publish(e Event) {
+	subs := b.subscribers.Load().(eventSubscribers)[e.Type]
+	if len(subs) == 0 {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = b.timeNow()
+	}
+	for _, s := range subs {
+		s.f(e)
+	}
+}
+
+// publishCallStarted publishes EventCallStarted for a call on the connection.
+// It returns the EventCallCompleted to publish once the call completes, or
+// nil if there are no subscribers for it.
+func (c *Connection) publishCallStarted(inbound bool, service, method string, now time.Time) *Event {
+	started := c.eventBus.subscribed(EventCallStarted)
+	completed := c.eventBus.subscribed(EventCallCompleted)
+	if !started && !completed {
+		return nil
+	}
+
+	e := Event{
+		Type:       EventCallStarted,
+		Time:       now,
+		HostPort:   c.remotePeerInfo.HostPort,
+		Connection: c,
+		Inbound:    inbound,
+		Service:    service,
+		Method:     method,
+	}
+	if started {
+		c.eventBus.publish(e)
+	}
+	if !completed {
+		return nil
+	}
+	e.Type = EventCallCompleted
+	return &e
+}
+
+// Subscribe calls f with every event of type t published by the channel, and
+// returns a function that unsubscribes f. Events are published synchronously
+// by the goroutine that triggered them, often while a call or connection is
+// being processed, so f must be safe for concurrent use, and should return
+// quickly and not block. Subscribe panics if t is not a known EventType.
+func (ch *Channel) Subscribe(t EventType, f func(Event)) (unsubscribe func()) {
+	return ch.eventBus.subscribe(t, f)
+}
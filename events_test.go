@@ -0,0 +1,260 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// eventRecorder records events published to its subscriptions.
+type eventRecorder struct {
+	sync.Mutex
+
+	events []Event
+}
+
+func recordEvents(ch *Channel, types ...EventType) *eventRecorder {
+	r := &eventRecorder{}
+	for _, t := range types {
+		ch.Subscribe(t, r.record)
+	}
+	return r
+}
+
+func (r *eventRecorder) record(e Event) {
+	r.Lock()
+	defer r.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) get() []Event {
+	r.Lock()
+	defer r.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+func (r *eventRecorder) waitFor(t *testing.T, n int) []Event {
+	require.True(t, testutils.WaitFor(time.Second, func() bool {
+		return len(r.get()) >= n
+	}), "Failed while waiting for %v events, got %v", n, len(r.get()))
+	return r.get()
+}
+
+func eventTypes(events []Event) []EventType {
+	types := make([]EventType, len(events))
+	for i, e := range events {
+		types[i] = e.Type
+	}
+	return types
+}
+
+func TestEventsConnectionLifecycle(t *testing.T) {
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+
+	client := testutils.NewClient(t, nil)
+	clientEvents := recordEvents(client, EventConnectionOpened, EventConnectionClosed, EventPeerAdded)
+	serverEvents := recordEvents(server, EventConnectionOpened, EventConnectionClosed)
+
+	ctx, cancel := NewContext(time.Second)
+	defer cancel()
+	conn, err := client.Connect(ctx, server.PeerInfo().HostPort)
+	require.NoError(t, err, "Connect failed")
+
+	events := clientEvents.waitFor(t, 2)
+	assert.Equal(t, []EventType{EventPeerAdded, EventConnectionOpened}, eventTypes(events), "Unexpected client events")
+	assert.Equal(t, server.PeerInfo().HostPort, events[0].HostPort, "Unexpected peer added")
+	assert.Equal(t, server.PeerInfo().HostPort, events[0].Peer.HostPort(), "Unexpected peer")
+	assert.Equal(t, conn, events[1].Connection, "Unexpected opened connection")
+	assert.False(t, events[1].Time.IsZero(), "Expected event time")
+
+	serverOpened := serverEvents.waitFor(t, 1)
+	assert.Equal(t, EventConnectionOpened, serverOpened[0].Type, "Unexpected server event")
+	assert.NotNil(t, serverOpened[0].Connection, "Expected server connection")
+
+	client.Close()
+	events = clientEvents.waitFor(t, 3)
+	assert.Equal(t, EventConnectionClosed, events[2].Type, "Unexpected client event")
+	assert.Equal(t, conn, events[2].Connection, "Unexpected closed connection")
+
+	serverClosed := serverEvents.waitFor(t, 2)
+	assert.Equal(t, EventConnectionClosed, serverClosed[1].Type, "Unexpected server event")
+}
+
+func TestEventsCalls(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+		testutils.RegisterFunc(ts.Server(), "app-error", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return &raw.Res{IsErr: true}, nil
+		})
+		testutils.RegisterFunc(ts.Server(), "busy", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return nil, ErrServerBusy
+		})
+
+		serverEvents := recordEvents(ts.Server(), EventCallStarted, EventCallCompleted)
+		client := ts.NewClient(nil)
+		clientEvents := recordEvents(client, EventCallStarted, EventCallCompleted)
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		tests := []struct {
+			method      string
+			wantAppErr  bool
+			wantErr     error
+			wantCallErr bool
+		}{
+			{method: "echo"},
+			{method: "app-error", wantAppErr: true},
+			{method: "busy", wantErr: ErrServerBusy, wantCallErr: true},
+		}
+
+		for i, tt := range tests {
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), tt.method, nil, nil)
+			if tt.wantCallErr {
+				require.Error(t, err, "%v: expected call to fail", tt.method)
+			} else {
+				require.NoError(t, err, "%v: call failed", tt.method)
+			}
+
+			for _, r := range []*eventRecorder{clientEvents, serverEvents} {
+				inbound := r == serverEvents
+				events := r.waitFor(t, 2*(i+1))[2*i:]
+				assert.Equal(t, []EventType{EventCallStarted, EventCallCompleted}, eventTypes(events),
+					"%v: unexpected events (inbound %v)", tt.method, inbound)
+
+				completed := events[1]
+				assert.Equal(t, inbound, completed.Inbound, "%v: unexpected direction", tt.method)
+				assert.Equal(t, ts.ServiceName(), completed.Service, "%v: unexpected service", tt.method)
+				assert.Equal(t, tt.method, completed.Method, "%v: unexpected method", tt.method)
+				assert.Equal(t, tt.wantAppErr, completed.ApplicationError, "%v: unexpected application error", tt.method)
+				assert.NotNil(t, completed.Connection, "%v: expected connection", tt.method)
+				if tt.wantErr != nil {
+					assert.Equal(t, tt.wantErr, completed.Err, "%v: unexpected error (inbound %v)", tt.method, inbound)
+				} else {
+					assert.NoError(t, completed.Err, "%v: unexpected error", tt.method)
+				}
+			}
+		}
+	})
+}
+
+func TestEventsCallTimeout(t *testing.T) {
+	opts := testutils.NewOpts().DisableLogVerification()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		release := make(chan struct{})
+		testutils.RegisterFunc(ts.Server(), "block", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			<-release
+			return &raw.Res{}, nil
+		})
+
+		client := ts.NewClient(nil)
+		clientEvents := recordEvents(client, EventCallStarted, EventCallCompleted)
+
+		ctx, cancel := NewContext(20 * time.Millisecond)
+		defer cancel()
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "block", nil, nil)
+		close(release)
+		require.Equal(t, ErrTimeout, err, "Expected call to time out")
+
+		events := clientEvents.waitFor(t, 2)
+		assert.Equal(t, []EventType{EventCallStarted, EventCallCompleted}, eventTypes(events), "Unexpected events")
+		assert.Equal(t, ErrTimeout, events[1].Err, "Unexpected error")
+		assert.NotZero(t, events[1].Latency, "Expected latency")
+	})
+}
+
+func TestEventsRetry(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	events := recordEvents(ch, EventRetry)
+
+	ctx, cancel := NewContextBuilder(time.Second).
+		SetRetryOptions(&RetryOptions{MaxAttempts: 3}).
+		Build()
+	defer cancel()
+
+	var attempts int
+	err := ch.RunWithRetry(ctx, func(ctx context.Context, rs *RequestState) error {
+		attempts++
+		return ErrServerBusy
+	})
+	assert.Equal(t, ErrServerBusy, err, "Unexpected error")
+	assert.Equal(t, 3, attempts, "Unexpected number of attempts")
+
+	// The final attempt isn't retried.
+	got := events.get()
+	require.Len(t, got, 2, "Unexpected number of retry events")
+	for i, e := range got {
+		assert.Equal(t, EventRetry, e.Type, "Unexpected event type")
+		assert.Equal(t, i+1, e.Attempt, "Unexpected attempt")
+		assert.Equal(t, ErrServerBusy, e.Err, "Unexpected error")
+	}
+}
+
+func TestEventsUnsubscribe(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	var first, second int
+	unsubscribe := ch.Subscribe(EventPeerAdded, func(Event) { first++ })
+	ch.Subscribe(EventPeerAdded, func(Event) { second++ })
+
+	ch.Peers().Add("1.1.1.1:1")
+	unsubscribe()
+	unsubscribe()
+	ch.Peers().Add("1.1.1.1:2")
+
+	// Adding an existing peer doesn't publish an event.
+	ch.Peers().Add("1.1.1.1:2")
+
+	assert.Equal(t, 1, first, "Unsubscribed subscriber should only see the first event")
+	assert.Equal(t, 2, second, "Subscriber should see both events")
+}
+
+func TestEventsSubscribeUnknownType(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	assert.Panics(t, func() {
+		ch.Subscribe(EventType(0), func(Event) {})
+	}, "Expected unknown event type to panic")
+	assert.Panics(t, func() {
+		ch.Subscribe(EventType(100), func(Event) {})
+	}, "Expected unknown event type to panic")
+}
+
+func TestEventTypeString(t *testing.T) {
+	assert.Equal(t, "callCompleted", EventCallCompleted.String(), "Unexpected String")
+	assert.Equal(t, "EventType(100)", EventType(100).String(), "Unexpected String for unknown type")
+}
@@ -46,6 +46,10 @@ type readableFragment struct {
 	checksum     []byte
 	contents     *typed.ReadBuffer
 	onDone       func()
+
+	// jumbo is set for fragments in jumbo frames, which use 4 byte chunk
+	// sizes rather than 2 bytes.
+	jumbo bool
 }
 
 func (f *readableFragment) done() {
@@ -283,11 +287,16 @@ func (r *fragmentingReader) recvAndParseNextFragment(initial bool) error {
 	r.hasMoreFragments = (r.curFragment.flags & hasMoreFragmentsFlag) == hasMoreFragmentsFlag
 	r.remainingChunks = nil
 	for r.curFragment.contents.BytesRemaining() > 0 && r.curFragment.contents.Err() == nil {
-		chunkSize := r.curFragment.contents.ReadUint16()
-		if chunkSize > uint16(r.curFragment.contents.BytesRemaining()) {
+		var chunkSize int
+		if r.curFragment.jumbo {
+			chunkSize = int(r.curFragment.contents.ReadUint32())
+		} else {
+			chunkSize = int(r.curFragment.contents.ReadUint16())
+		}
+		if chunkSize > r.curFragment.contents.BytesRemaining() {
 			return errChunkExceedsFragmentSize
 		}
-		chunkData := r.curFragment.contents.ReadBytes(int(chunkSize))
+		chunkData := r.curFragment.contents.ReadBytes(chunkSize)
 		r.remainingChunks = append(r.remainingChunks, chunkData)
 		r.checksum.Add(chunkData)
 	}
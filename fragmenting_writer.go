@@ -34,7 +34,6 @@ var (
 )
 
 const (
-	chunkHeaderSize      = 2    // each chunk is a uint16
 	hasMoreFragmentsFlag = 0x01 // flags indicating there are more fragments coming
 )
 
@@ -47,6 +46,10 @@ type writableFragment struct {
 	checksum    Checksum
 	contents    *typed.WriteBuffer
 	frame       interface{}
+
+	// jumbo is set for fragments in jumbo frames, which use 4 byte chunk
+	// sizes rather than 2 bytes.
+	jumbo bool
 }
 
 // finish finishes the fragment, updating the final checksum and fragment flags
@@ -59,23 +62,43 @@ func (f *writableFragment) finish(hasMoreFragments bool) {
 	}
 }
 
+// chunkHeaderSize returns the size of the chunk sizes in the fragment.
+func (f *writableFragment) chunkHeaderSize() int {
+	return chunkSizeLen(f.jumbo)
+}
+
+// writeEmptyChunk writes a chunk with no data.
+func (f *writableFragment) writeEmptyChunk() {
+	if f.jumbo {
+		f.contents.WriteUint32(0)
+	} else {
+		f.contents.WriteUint16(0)
+	}
+}
+
 // A writableChunk is a chunk of data within a fragment, representing the
 // contents of an argument within that fragment
 type writableChunk struct {
-	size     uint16
-	sizeRef  typed.Uint16Ref
-	checksum Checksum
-	contents *typed.WriteBuffer
+	size      uint32
+	sizeRef   typed.Uint16Ref
+	sizeRef32 typed.Uint32Ref // used instead of sizeRef in jumbo frames
+	checksum  Checksum
+	contents  *typed.WriteBuffer
 }
 
-// newWritableChunk creates a new writable chunk around a checksum and a buffer to hold data
-func newWritableChunk(checksum Checksum, contents *typed.WriteBuffer) *writableChunk {
-	return &writableChunk{
-		size:     0,
-		sizeRef:  contents.DeferUint16(),
+// newWritableChunk creates a new writable chunk around a checksum and a
+// fragment to hold data
+func newWritableChunk(checksum Checksum, fragment *writableFragment) *writableChunk {
+	c := &writableChunk{
 		checksum: checksum,
-		contents: contents,
+		contents: fragment.contents,
+	}
+	if fragment.jumbo {
+		c.sizeRef32 = fragment.contents.DeferUint32()
+	} else {
+		c.sizeRef = fragment.contents.DeferUint16()
 	}
+	return c
 }
 
 // writeAsFits writes as many bytes from the given slice as fits into the chunk
@@ -88,13 +111,17 @@ func (c *writableChunk) writeAsFits(b []byte) int {
 	c.contents.WriteBytes(b)
 
 	written := len(b)
-	c.size += uint16(written)
+	c.size += uint32(written)
 	return written
 }
 
 // finish finishes the chunk, updating its chunk size
 func (c *writableChunk) finish() {
-	c.sizeRef.Update(c.size)
+	if c.sizeRef32 != nil {
+		c.sizeRef32.Update(c.size)
+	} else {
+		c.sizeRef.Update(uint16(c.size))
+	}
 }
 
 // A fragmentSender allocates and sends outbound fragments to a target
@@ -183,12 +210,12 @@ func (w *fragmentingWriter) BeginArgument(last bool) error {
 	// If there's no room in the current fragment, freak out.  This will
 	// only happen due to an implementation error in the TChannel stack
 	// itself
-	if w.curFragment.contents.BytesRemaining() <= chunkHeaderSize {
+	if w.curFragment.contents.BytesRemaining() <= w.curFragment.chunkHeaderSize() {
 		panic(fmt.Errorf("attempting to begin an argument in a fragment with only %d bytes available",
 			w.curFragment.contents.BytesRemaining()))
 	}
 
-	w.curChunk = newWritableChunk(w.checksum, w.curFragment.contents)
+	w.curChunk = newWritableChunk(w.checksum, w.curFragment)
 	w.state = fragmentingWriteInArgument
 	if last {
 		w.state = fragmentingWriteInLastArgument
@@ -238,7 +265,7 @@ func (w *fragmentingWriter) Flush() error {
 		return w.err
 	}
 
-	w.curChunk = newWritableChunk(w.checksum, w.curFragment.contents)
+	w.curChunk = newWritableChunk(w.checksum, w.curFragment)
 	return nil
 }
 
@@ -274,7 +301,7 @@ func (w *fragmentingWriter) Close() error {
 	}
 
 	w.state = fragmentingWriteWaitingForArgument
-	if w.curFragment.contents.BytesRemaining() > chunkHeaderSize {
+	if w.curFragment.contents.BytesRemaining() > w.curFragment.chunkHeaderSize() {
 		// There's enough room in this fragment for the next argument's
 		// initial chunk, so we're done here
 		return nil
@@ -291,6 +318,6 @@ func (w *fragmentingWriter) Close() error {
 	}
 
 	// Write an empty chunk to indicate this argument has ended
-	w.curFragment.contents.WriteUint16(0)
+	w.curFragment.writeEmptyChunk()
 	return nil
 }
@@ -38,6 +38,17 @@ const (
 
 	// MaxFramePayloadSize is the maximum size of the payload for a single frame
 	MaxFramePayloadSize = MaxFrameSize - FrameHeaderSize
+
+	// MaxJumboFrameSize is the total maximum size for a jumbo frame. Jumbo
+	// frames are only sent on connections where both peers support
+	// FeatureLargeFrames (see ConnectionOptions.JumboFrameSize).
+	MaxJumboFrameSize = 1<<23 - 1
+
+	// jumboFrameFlag is set in the reserved byte of the header of jumbo
+	// frames. The rest of the reserved byte holds the high bits of the
+	// frame size, and the arg chunk sizes in jumbo frames are 4 bytes
+	// rather than 2.
+	jumboFrameFlag = 0x80
 )
 
 // FrameHeader is the header for a frame, containing the MessageType and size
@@ -63,16 +74,46 @@ func (fh *FrameHeader) SetPayloadSize(size uint16) {
 	fh.size = size + FrameHeaderSize
 }
 
-// PayloadSize returns the size of the frame payload
+// PayloadSize returns the size of the frame payload. It's only valid for
+// frames that aren't jumbo frames.
 func (fh FrameHeader) PayloadSize() uint16 {
 	return fh.size - FrameHeaderSize
 }
 
-// FrameSize returns the total size of the frame
+// FrameSize returns the total size of the frame. It's only valid for frames
+// that aren't jumbo frames.
 func (fh FrameHeader) FrameSize() uint16 {
 	return fh.size
 }
 
+// isJumbo returns whether the frame is a jumbo frame.
+func (fh FrameHeader) isJumbo() bool {
+	return fh.reserved1&jumboFrameFlag != 0
+}
+
+// frameSize returns the total size of the frame, including jumbo frames.
+func (fh FrameHeader) frameSize() int {
+	if fh.isJumbo() {
+		return int(fh.reserved1&^jumboFrameFlag)<<16 | int(fh.size)
+	}
+	return int(fh.size)
+}
+
+// payloadSize returns the size of the frame payload, including jumbo frames.
+func (fh FrameHeader) payloadSize() int {
+	return fh.frameSize() - FrameHeaderSize
+}
+
+// setPayloadSize sets the size of the frame payload, keeping whether the
+// frame is a jumbo frame. The size must fit in the frame.
+func (fh *FrameHeader) setPayloadSize(size int) {
+	size += FrameHeaderSize
+	fh.size = uint16(size)
+	if fh.isJumbo() {
+		fh.reserved1 = jumboFrameFlag | byte(size>>16)
+	}
+}
+
 func (fh FrameHeader) String() string { return fmt.Sprintf("%v[%d]", fh.messageType, fh.ID) }
 
 // MarshalJSON returns a `{"id":NNN, "msgType":MMM, "size":SSS}` representation
@@ -113,6 +154,10 @@ type Frame struct {
 
 	// The payload for the frame
 	Payload []byte
+
+	// uncompressed is set for frames of calls that disabled compression, so
+	// they're written without connection-level compression.
+	uncompressed bool
 }
 
 // NewFrame allocates a new frame with the given payload capacity
@@ -128,18 +173,33 @@ func NewFrame(payloadCapacity int) *Frame {
 // based on the size specified in the header. This allows callers to defer
 // the frame allocation till the body needs to be read.
 func (f *Frame) ReadBody(header []byte, r io.Reader) error {
-	// Copy the header into the underlying buffer so we have an assembled frame
-	// that can be directly forwarded.
-	copy(f.buffer, header)
+	return f.readBody(header, r, MaxFrameSize)
+}
 
+// readBody reads the body of a frame whose header has been read, allowing
+// jumbo frames of up to maxFrameSize. The frame is grown if it's too small
+// for the payload.
+func (f *Frame) readBody(header []byte, r io.Reader, maxFrameSize int) error {
 	// Parse the header into our typed struct.
 	if err := f.Header.read(typed.NewReadBuffer(header)); err != nil {
 		return err
 	}
 
-	switch payloadSize := f.Header.PayloadSize(); {
-	case payloadSize > MaxFramePayloadSize:
-		return fmt.Errorf("invalid frame size %v", f.Header.size)
+	if f.Header.isJumbo() && maxFrameSize <= MaxFrameSize {
+		return fmt.Errorf("unexpected jumbo frame of size %v", f.Header.frameSize())
+	}
+	if frameSize := f.Header.frameSize(); frameSize > maxFrameSize || frameSize < FrameHeaderSize {
+		return fmt.Errorf("invalid frame size %v", frameSize)
+	}
+	if f.Header.frameSize() > len(f.buffer) {
+		f.grow(f.Header.payloadSize())
+	}
+
+	// Copy the header into the underlying buffer so we have an assembled frame
+	// that can be directly forwarded.
+	copy(f.buffer, header)
+
+	switch payloadSize := f.Header.payloadSize(); {
 	case payloadSize > 0:
 		_, err := io.ReadFull(r, f.SizedPayload())
 		return err
@@ -170,7 +230,7 @@ func (f *Frame) WriteOut(w io.Writer) error {
 		return err
 	}
 
-	fullFrame := f.buffer[:f.Header.FrameSize()]
+	fullFrame := f.buffer[:f.Header.frameSize()]
 	if _, err := w.Write(fullFrame); err != nil {
 		return err
 	}
@@ -180,7 +240,15 @@ func (f *Frame) WriteOut(w io.Writer) error {
 
 // SizedPayload returns the slice of the payload actually used, as defined by the header
 func (f *Frame) SizedPayload() []byte {
-	return f.Payload[:f.Header.PayloadSize()]
+	return f.Payload[:f.Header.payloadSize()]
+}
+
+// grow replaces the frame's buffer with one that has the given payload
+// capacity. The contents of the frame are not kept.
+func (f *Frame) grow(payloadCapacity int) {
+	f.buffer = make([]byte, payloadCapacity+FrameHeaderSize)
+	f.Payload = f.buffer[FrameHeaderSize:]
+	f.headerBuffer = f.buffer[:FrameHeaderSize]
 }
 
 // messageType returns the message type.
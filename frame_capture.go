@@ -0,0 +1,210 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/uber/tchannel-go/typed"
+)
+
+// FrameDirection is the direction of a frame on a connection.
+type FrameDirection byte
+
+const (
+	// FrameSent is a frame that was written to the connection.
+	FrameSent FrameDirection = iota + 1
+	// FrameReceived is a frame that was read from the connection.
+	FrameReceived
+)
+
+func (d FrameDirection) String() string {
+	switch d {
+	case FrameSent:
+		return "sent"
+	case FrameReceived:
+		return "received"
+	default:
+		return fmt.Sprintf("FrameDirection(%d)", byte(d))
+	}
+}
+
+// FrameSniffer is notified of every frame sent or received on a connection
+// after the connection handshake. SniffFrame is called synchronously from
+// the connection's read and write loops, so it should be fast, and the frame
+// may be reused once it returns, so it must not be retained.
+type FrameSniffer interface {
+	SniffFrame(conn *Connection, dir FrameDirection, f *Frame)
+}
+
+// sniffFrame notifies the connection's FrameSniffer, if any, of a frame.
+func (c *Connection) sniffFrame(dir FrameDirection, f *Frame) {
+	if s := c.opts.FrameSniffer; s != nil {
+		s.SniffFrame(c, dir, f)
+	}
+}
+
+// frameCaptureMagic is written at the start of each capture to identify the
+// format and version of the capture.
+var frameCaptureMagic = []byte("tchcap\x00\x01")
+
+// frameCaptureRecordSize is the size of the fields written before each frame:
+// the timestamp in nanoseconds, the direction and the connection ID.
+const frameCaptureRecordSize = 8 + 1 + 4
+
+var errFrameCaptureMagic = errors.New("tchannel: not a frame capture")
+
+// CapturedFrame is a frame read from a capture.
+type CapturedFrame struct {
+	// Time is when the frame was sent or received.
+	Time time.Time
+	// Direction is whether the frame was sent or received.
+	Direction FrameDirection
+	// ConnID identifies the connection the frame was captured on. It's
+	// unique within the process that captured the frame.
+	ConnID uint32
+	// Frame is the captured frame.
+	Frame *Frame
+}
+
+// FrameCaptureWriter is a FrameSniffer that writes each frame to an
+// io.Writer, with the time, direction and connection of the frame, so the
+// frames can be inspected or replayed using FrameCaptureReader. It's safe
+// for concurrent use by multiple connections.
+type FrameCaptureWriter struct {
+	sync.Mutex
+
+	w           io.Writer
+	timeNow     func() time.Time
+	wroteHeader bool
+	err         error
+}
+
+// NewFrameCaptureWriter returns a FrameCaptureWriter that writes to w.
+func NewFrameCaptureWriter(w io.Writer) *FrameCaptureWriter {
+	return &FrameCaptureWriter{w: w, timeNow: time.Now}
+}
+
+// SniffFrame writes the frame to the capture.
+func (cw *FrameCaptureWriter) SniffFrame(conn *Connection, dir FrameDirection, f *Frame) {
+	buf := make([]byte, frameCaptureRecordSize+f.Header.frameSize())
+	wbuf := typed.NewWriteBuffer(buf)
+	wbuf.WriteUint64(uint64(cw.timeNow().UnixNano()))
+	wbuf.WriteSingleByte(byte(dir))
+	wbuf.WriteUint32(conn.connID)
+	f.Header.write(wbuf)
+	wbuf.WriteBytes(f.SizedPayload())
+
+	cw.Lock()
+	defer cw.Unlock()
+
+	if cw.err != nil {
+		return
+	}
+	if !cw.wroteHeader {
+		if _, cw.err = cw.w.Write(frameCaptureMagic); cw.err != nil {
+			return
+		}
+		cw.wroteHeader = true
+	}
+	_, cw.err = cw.w.Write(buf)
+}
+
+// Err returns the first error writing to the capture. Once a write fails,
+// no more frames are written.
+func (cw *FrameCaptureWriter) Err() error {
+	cw.Lock()
+	defer cw.Unlock()
+	return cw.err
+}
+
+// FrameCaptureReader reads frames written by a FrameCaptureWriter.
+type FrameCaptureReader struct {
+	r          io.Reader
+	readHeader bool
+}
+
+// NewFrameCaptureReader returns a FrameCaptureReader that reads from r.
+func NewFrameCaptureReader(r io.Reader) *FrameCaptureReader {
+	return &FrameCaptureReader{r: r}
+}
+
+// Next returns the next frame in the capture, or io.EOF once there are no
+// more frames.
+func (cr *FrameCaptureReader) Next() (*CapturedFrame, error) {
+	if !cr.readHeader {
+		magic := make([]byte, len(frameCaptureMagic))
+		if _, err := io.ReadFull(cr.r, magic); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return nil, errFrameCaptureMagic
+			}
+			return nil, err
+		}
+		if !bytes.Equal(magic, frameCaptureMagic) {
+			return nil, errFrameCaptureMagic
+		}
+		cr.readHeader = true
+	}
+
+	record := make([]byte, frameCaptureRecordSize)
+	if _, err := io.ReadFull(cr.r, record); err != nil {
+		return nil, err
+	}
+	rbuf := typed.NewReadBuffer(record)
+	captured := &CapturedFrame{
+		Time:      time.Unix(0, int64(rbuf.ReadUint64())),
+		Direction: FrameDirection(rbuf.ReadSingleByte()),
+		ConnID:    rbuf.ReadUint32(),
+		Frame:     NewFrame(MaxFramePayloadSize),
+	}
+	header := make([]byte, FrameHeaderSize)
+	_, err := io.ReadFull(cr.r, header)
+	if err == nil {
+		err = captured.Frame.readBody(header, cr.r, MaxJumboFrameSize)
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return captured, nil
+}
+
+// ReadAll returns all remaining frames in the capture.
+func (cr *FrameCaptureReader) ReadAll() ([]*CapturedFrame, error) {
+	var frames []*CapturedFrame
+	for {
+		f, err := cr.Next()
+		if err == io.EOF {
+			return frames, nil
+		}
+		if err != nil {
+			return frames, err
+		}
+		frames = append(frames, f)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/atomic"
+	"golang.org/x/net/context"
+)
+
+func TestFrameCaptureReplay(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		var calls atomic.Int32
+		ts.RegisterFunc("echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			calls.Inc()
+			return &raw.Res{Arg2: args.Arg2, Arg3: args.Arg3}, nil
+		})
+
+		var buf bytes.Buffer
+		capture := NewFrameCaptureWriter(&buf)
+		opts := testutils.NewOpts()
+		opts.DefaultConnectionOptions.FrameSniffer = capture
+		client := ts.NewClient(opts)
+
+		// The large argument is fragmented across multiple frames.
+		largeArg := testutils.RandBytes(100000)
+		for _, arg3 := range [][]byte{[]byte("small"), largeArg} {
+			ctx, cancel := NewContext(time.Second)
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, arg3)
+			cancel()
+			require.NoError(t, err, "Call failed")
+		}
+
+		capture.Lock()
+		captured, err := NewFrameCaptureReader(bytes.NewReader(buf.Bytes())).ReadAll()
+		capture.Unlock()
+		require.NoError(t, err, "Failed to read capture")
+		require.NoError(t, capture.Err(), "Unexpected capture error")
+
+		var sent, received int
+		for _, f := range captured {
+			assert.False(t, f.Time.IsZero(), "Missing frame time")
+			switch f.Direction {
+			case FrameSent:
+				sent++
+			case FrameReceived:
+				received++
+			}
+		}
+		assert.True(t, sent > 2, "Expected the fragmented call to send multiple frames, got %v", sent)
+		assert.True(t, received > 2, "Expected the fragmented response to be multiple frames, got %v", received)
+
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+		result, err := client.ReplayCalls(ctx, ts.HostPort(), captured, nil)
+		require.NoError(t, err, "ReplayCalls failed")
+		assert.Equal(t, ReplayResult{Calls: 2, Responses: 2}, result, "Unexpected replay result")
+		assert.EqualValues(t, 4, calls.Load(), "Replayed calls should reach the handler")
+
+		_, err = client.ReplayCalls(ctx, ts.HostPort(), captured, &ReplayOptions{ConnID: 1 << 31})
+		assert.Error(t, err, "ReplayCalls should fail with no calls to replay")
+	})
+}
+
+func TestFrameCaptureReaderInvalid(t *testing.T) {
+	_, err := NewFrameCaptureReader(strings.NewReader("not a capture")).Next()
+	assert.Error(t, err, "Expected error for invalid capture")
+
+	_, err = NewFrameCaptureReader(strings.NewReader("")).Next()
+	assert.Equal(t, io.EOF, err, "Expected EOF for empty capture")
+}
@@ -84,3 +84,60 @@ func (c channelFramePool) Release(f *Frame) {
 		// Too many frames in the channel, discard it.
 	}
 }
+
+type boundedFramePool struct {
+	frames        chan *Frame
+	statsReporter StatsReporter
+	statsTags     map[string]string
+}
+
+// NewBoundedFramePool returns a frame pool that retains at most capacity
+// released frames, and allocates a new frame when it's empty. Released frames
+// are cleared before they're reused. Each Get is counted as a frame-pool.hits
+// or frame-pool.misses stat, and each frame released while the pool is full
+// is counted as a frame-pool.discards stat.
+func NewBoundedFramePool(capacity int, statsReporter StatsReporter, tags map[string]string) FramePool {
+	if statsReporter == nil {
+		statsReporter = NullStatsReporter
+	}
+	return &boundedFramePool{
+		frames:        make(chan *Frame, capacity),
+		statsReporter: statsReporter,
+		statsTags:     tags,
+	}
+}
+
+func (p *boundedFramePool) Get() *Frame {
+	select {
+	case frame := <-p.frames:
+		p.statsReporter.IncCounter("frame-pool.hits", p.statsTags, 1)
+		return frame
+	default:
+		p.statsReporter.IncCounter("frame-pool.misses", p.statsTags, 1)
+		return NewFrame(MaxFramePayloadSize)
+	}
+}
+
+func (p *boundedFramePool) Release(f *Frame) {
+	clearFrame(f)
+	select {
+	case p.frames <- f:
+	default:
+		p.statsReporter.IncCounter("frame-pool.discards", p.statsTags, 1)
+	}
+}
+
+// clearFrame zeroes the header and the bytes used by a frame, so a reused
+// frame doesn't contain the previous payload. If the frame's size was never
+// set, the whole buffer is cleared.
+func clearFrame(f *Frame) {
+	n := f.Header.frameSize()
+	if n == 0 || n > len(f.buffer) {
+		n = len(f.buffer)
+	}
+	buf := f.buffer[:n]
+	for i := range buf {
+		buf[i] = 0
+	}
+	f.Header = FrameHeader{}
+}
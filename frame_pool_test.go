@@ -179,3 +179,40 @@ func TestDirtyFrameRequests(t *testing.T) {
 		}
 	})
 }
+
+func TestBoundedFramePool(t *testing.T) {
+	statsReporter := newRecordingStatsReporter()
+	tags := map[string]string{"service": "pool"}
+	pool := NewBoundedFramePool(1, statsReporter, tags)
+
+	f1 := pool.Get()
+	f2 := pool.Get()
+	assert.EqualValues(t, 2, statsReporter.getCount("frame-pool.misses", tags), "Empty pool should miss")
+
+	copy(f1.Payload, "secret payload")
+	f1.Header.ID = 5
+	f1.Header.SetPayloadSize(14)
+	pool.Release(f1)
+	pool.Release(f2)
+	assert.EqualValues(t, 1, statsReporter.getCount("frame-pool.discards", tags), "Full pool should discard")
+
+	reused := pool.Get()
+	assert.EqualValues(t, 1, statsReporter.getCount("frame-pool.hits", tags), "Retained frame should hit")
+	assert.Equal(t, FrameHeader{}, reused.Header, "Reused frame header should be cleared")
+	assert.Equal(t, make([]byte, 14), reused.Payload[:14], "Reused frame payload should be cleared")
+}
+
+func TestChannelFramePoolSize(t *testing.T) {
+	statsReporter := newRecordingStatsReporter()
+	opts := testutils.NewOpts().SetServiceName("swap-server").SetStatsReporter(statsReporter)
+	opts.FramePoolSize = 10
+
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		ts.Register(raw.Wrap(&swapper{t}), "swap")
+		doPingAndCall(t, ts.Server(), ts.HostPort())
+
+		tags := ts.Server().StatsTags()
+		assert.True(t, statsReporter.getCount("frame-pool.misses", tags) > 0, "Expected frame pool misses")
+		assert.True(t, statsReporter.getCount("frame-pool.hits", tags) > 0, "Expected frame pool hits")
+	})
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+var errReplayNoCalls = errors.New("tchannel: no calls to replay")
+
+// ReplayOptions are options for replaying captured calls using ReplayCalls.
+type ReplayOptions struct {
+	// Direction selects which captured call frames are replayed. Defaults to
+	// FrameSent, which replays the calls made by the captured process. Use
+	// FrameReceived to replay the calls that a captured server received.
+	Direction FrameDirection
+
+	// ConnID, if non-zero, only replays calls captured on that connection.
+	ConnID uint32
+
+	// PreserveTiming waits between frames for the time that passed between
+	// them when they were captured, rather than sending them immediately.
+	PreserveTiming bool
+}
+
+// ReplayResult summarizes the responses to replayed calls.
+type ReplayResult struct {
+	// Calls is the number of calls that were sent.
+	Calls int
+	// Responses is the number of calls that completed with a call response,
+	// including application errors.
+	Responses int
+	// Errors is the number of calls that completed with an error frame.
+	Errors int
+}
+
+type replayKey struct {
+	connID uint32
+	id     uint32
+}
+
+// ReplayCalls re-sends the call frames in a capture to hostPort over a new
+// connection, and waits for each call to complete. The frames are sent as
+// they were captured, including any checksums, TTLs and tracing, so calls
+// should be captured on connections without compression. Message IDs are
+// changed so calls from different captured connections don't conflict.
+// It returns once all calls complete, or when ctx is done.
+func (ch *Channel) ReplayCalls(ctx context.Context, hostPort string, frames []*CapturedFrame, opts *ReplayOptions) (ReplayResult, error) {
+	var result ReplayResult
+	replayOpts := ReplayOptions{Direction: FrameSent}
+	if opts != nil {
+		replayOpts = *opts
+		if replayOpts.Direction == 0 {
+			replayOpts.Direction = FrameSent
+		}
+	}
+
+	var toSend []*CapturedFrame
+	for _, f := range frames {
+		if f.Direction != replayOpts.Direction {
+			continue
+		}
+		if replayOpts.ConnID != 0 && f.ConnID != replayOpts.ConnID {
+			continue
+		}
+		switch f.Frame.Header.messageType {
+		case messageTypeCallReq, messageTypeCallReqContinue:
+			toSend = append(toSend, f)
+		}
+	}
+	if len(toSend) == 0 {
+		return result, errReplayNoCalls
+	}
+
+	conn, err := dialContext(ctx, hostPort)
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+
+	if err := ch.replayHandshake(ctx, conn); err != nil {
+		return result, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	completed := make(chan messageType)
+	go replayReadResponses(conn, completed, done)
+
+	// The init handshake uses ID 1, so calls start at 2.
+	nextID := uint32(2)
+	ids := make(map[replayKey]uint32)
+	var lastSent time.Time
+	for _, captured := range toSend {
+		if replayOpts.PreserveTiming && !lastSent.IsZero() {
+			if err := replaySleep(ctx, captured.Time.Sub(lastSent)); err != nil {
+				return result, err
+			}
+		}
+		lastSent = captured.Time
+
+		key := replayKey{captured.ConnID, captured.Frame.Header.ID}
+		id, ok := ids[key]
+		if !ok {
+			if captured.Frame.Header.messageType != messageTypeCallReq {
+				// The start of the call wasn't captured, so it can't be replayed.
+				continue
+			}
+			id = nextID
+			nextID++
+			ids[key] = id
+			result.Calls++
+		}
+
+		f := NewFrame(captured.Frame.Header.payloadSize())
+		f.Header = captured.Frame.Header
+		f.Header.ID = id
+		copy(f.Payload, captured.Frame.SizedPayload())
+		if err := f.WriteOut(conn); err != nil {
+			return result, err
+		}
+	}
+
+	for result.Responses+result.Errors < result.Calls {
+		select {
+		case msgType, ok := <-completed:
+			if !ok {
+				return result, ErrConnectionClosed
+			}
+			if msgType == messageTypeError {
+				result.Errors++
+			} else {
+				result.Responses++
+			}
+		case <-ctx.Done():
+			return result, ctx.Err()
+		}
+	}
+	return result, nil
+}
+
+// replayHandshake completes the init handshake on a replay connection.
+func (ch *Channel) replayHandshake(ctx context.Context, conn net.Conn) error {
+	defer setInitDeadline(ctx, conn)()
+
+	if err := ch.writeMessage(conn, &initReq{initMessage: ch.getInitMessage(ctx, 1)}); err != nil {
+		return err
+	}
+
+	res := &initRes{}
+	if _, err := ch.readMessage(conn, res); err != nil {
+		return err
+	}
+	if res.Version != CurrentProtocolVersion {
+		return unsupportedProtocolVersion(res.Version)
+	}
+	return nil
+}
+
+// replayReadResponses reads frames from a replay connection, and sends the
+// message type of the last frame of each call to completed. It closes
+// completed when the connection fails.
+func replayReadResponses(conn net.Conn, completed chan<- messageType, done <-chan struct{}) {
+	defer close(completed)
+
+	f := NewFrame(MaxFramePayloadSize)
+	for {
+		if err := f.ReadIn(conn); err != nil {
+			return
+		}
+
+		switch f.Header.messageType {
+		case messageTypeCallRes, messageTypeCallResContinue:
+			if payload := f.SizedPayload(); len(payload) > 0 && payload[0]&hasMoreFragmentsFlag != 0 {
+				continue
+			}
+		case messageTypeError:
+		default:
+			continue
+		}
+
+		select {
+		case completed <- f.Header.messageType:
+		case <-done:
+			return
+		}
+	}
+}
+
+func replaySleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
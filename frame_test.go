@@ -157,6 +157,37 @@ func TestReservedBytes(t *testing.T) {
 		buf.Bytes(), "Unexpected bytes")
 }
 
+func TestJumboFrameReadBody(t *testing.T) {
+	const payloadSize = 200 * 1024
+
+	f := NewFrame(payloadSize)
+	f.Header.reserved1 = jumboFrameFlag
+	f.Header.messageType = messageTypeCallReq
+	f.Header.ID = 1
+	f.Header.setPayloadSize(payloadSize)
+	io.ReadFull(testreader.Looper([]byte{1, 2, 3}), f.Payload)
+	assert.True(t, f.Header.isJumbo(), "Expected jumbo frame")
+	assert.Equal(t, payloadSize, f.Header.payloadSize(), "Unexpected payload size")
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, f.WriteOut(buf), "WriteOut failed")
+	assert.Equal(t, FrameHeaderSize+payloadSize, buf.Len(), "Unexpected frame size")
+	header := buf.Bytes()[:FrameHeaderSize]
+
+	read := NewFrame(MaxFramePayloadSize)
+	require.NoError(t, read.readBody(header, bytes.NewReader(buf.Bytes()[FrameHeaderSize:]), MaxJumboFrameSize), "readBody failed")
+	assert.Equal(t, f.Header, read.Header, "Unexpected header")
+	assert.Equal(t, f.SizedPayload(), read.SizedPayload(), "Unexpected payload")
+
+	// Jumbo frames are rejected unless they're expected.
+	err := NewFrame(MaxFramePayloadSize).ReadBody(header, bytes.NewReader(buf.Bytes()[FrameHeaderSize:]))
+	assert.Error(t, err, "Expected unexpected jumbo frame to fail")
+
+	// As are jumbo frames larger than the max frame size.
+	err = NewFrame(MaxFramePayloadSize).readBody(header, bytes.NewReader(buf.Bytes()[FrameHeaderSize:]), 100*1024)
+	assert.Error(t, err, "Expected jumbo frame larger than the max size to fail")
+}
+
 func TestMessageType(t *testing.T) {
 	frame := NewFrame(MaxFramePayloadSize)
 	err := frame.write(&callReq{Service: "foo"})
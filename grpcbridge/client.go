@@ -0,0 +1,198 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package grpcbridge
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/raw"
+
+	"golang.org/x/net/context"
+)
+
+// ClientOptions are options used when creating a Client.
+type ClientOptions struct {
+	// Transport is used to make requests, and must support HTTP/2. Defaults
+	// to http.DefaultTransport, which uses HTTP/2 for backends that support
+	// it over TLS.
+	Transport http.RoundTripper
+
+	// Plaintext makes requests over cleartext HTTP rather than HTTPS. The
+	// Transport must then use HTTP/2 with prior knowledge, such as an
+	// golang.org/x/net/http2 Transport with AllowHTTP set.
+	Plaintext bool
+}
+
+// Client makes unary gRPC calls to a gRPC backend.
+type Client struct {
+	baseURL   string
+	transport http.RoundTripper
+}
+
+// NewClient returns a Client that makes gRPC calls to hostPort.
+func NewClient(hostPort string, opts *ClientOptions) *Client {
+	if opts == nil {
+		opts = &ClientOptions{}
+	}
+
+	c := &Client{
+		baseURL:   "https://" + hostPort + "/",
+		transport: opts.Transport,
+	}
+	if opts.Plaintext {
+		c.baseURL = "http://" + hostPort + "/"
+	}
+	if c.transport == nil {
+		c.transport = http.DefaultTransport
+	}
+	return c
+}
+
+// Call makes a unary gRPC call to method, which is the full gRPC method name,
+// e.g. "pkg.Service/Method". The headers are sent as gRPC metadata, and the
+// response metadata is returned. If the call fails, the error is a Status,
+// and the response message is returned if there is one.
+func (c *Client) Call(ctx context.Context, method string, headers map[string]string, req []byte) (map[string]string, []byte, error) {
+	var body bytes.Buffer
+	if err := writeMessage(&body, req); err != nil {
+		return nil, nil, err
+	}
+
+	httpReq, err := http.NewRequest("POST", c.baseURL+strings.TrimPrefix(method, "/"), &body)
+	if err != nil {
+		return nil, nil, err
+	}
+	httpReq = httpReq.WithContext(ctx)
+
+	h := httpReq.Header
+	setMetadata(h, headers)
+	h.Set("Content-Type", _contentType)
+	h.Set("Te", "trailers")
+	if deadline, ok := ctx.Deadline(); ok {
+		h.Set(_timeoutHeader, formatTimeout(deadline.Sub(time.Now())))
+	}
+
+	httpRes, err := c.transport.RoundTrip(httpReq)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, nil, tchannel.GetContextError(ctxErr)
+		}
+		return nil, nil, err
+	}
+	defer httpRes.Body.Close()
+
+	if httpRes.StatusCode != http.StatusOK {
+		return nil, nil, Status{CodeUnknown, fmt.Sprintf("unexpected HTTP status %v", httpRes.Status)}
+	}
+
+	res, err := readMessage(httpRes.Body)
+	if err != nil && err != io.EOF {
+		return nil, nil, Status{CodeInternal, "failed to read response: " + err.Error()}
+	}
+	// Trailers are only available once the body has been read.
+	if _, err := io.Copy(ioutil.Discard, httpRes.Body); err != nil {
+		return nil, nil, Status{CodeInternal, "failed to read response: " + err.Error()}
+	}
+
+	resHeaders := headersFromMetadata(httpRes.Header)
+	status, err := readStatus(httpRes)
+	if err != nil {
+		return nil, nil, err
+	}
+	if status.Code != CodeOK {
+		return resHeaders, res, status
+	}
+	return resHeaders, res, nil
+}
+
+// readStatus reads the status from the response trailers, or from the headers
+// for a response without a message.
+func readStatus(res *http.Response) (Status, error) {
+	h := res.Trailer
+	if h.Get(_statusHeader) == "" {
+		h = res.Header
+	}
+
+	v := h.Get(_statusHeader)
+	code, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		return Status{}, Status{CodeInternal, fmt.Sprintf("invalid grpc-status %q", v)}
+	}
+	return Status{Code(code), decodeMessage(h.Get(_messageHeader))}, nil
+}
+
+// Handler returns a tchannel.Handler that forwards TChannel calls to the gRPC
+// backend. The TChannel method "service::method" is called as the gRPC method
+// "service/method", and application headers are sent as gRPC metadata using
+// the call's arg scheme.
+//
+// Failed gRPC calls are returned as system errors, except for CodeUnknown,
+// which is returned as an application error with the response message as arg3.
+func (c *Client) Handler() tchannel.Handler {
+	return tchannel.HandlerFunc(func(ctx context.Context, call *tchannel.InboundCall) {
+		response := call.Response()
+
+		arg2, arg3, err := raw.ReadArgsV2(call)
+		if err != nil {
+			response.SendSystemError(err)
+			return
+		}
+		headers, err := decodeHeaders(call.Format(), arg2)
+		if err != nil {
+			response.SendSystemError(tchannel.NewSystemError(tchannel.ErrCodeBadRequest, "failed to decode headers: %v", err))
+			return
+		}
+
+		method := strings.Replace(call.MethodString(), "::", "/", 1)
+		resHeaders, res, err := c.Call(ctx, method, headers, arg3)
+		if err != nil {
+			status, ok := err.(Status)
+			if !ok {
+				response.SendSystemError(err)
+				return
+			}
+			if status.Code != CodeUnknown {
+				response.SendSystemError(errorForStatus(status))
+				return
+			}
+			if err := response.SetApplicationError(); err != nil {
+				return
+			}
+		}
+
+		resArg2, err := encodeHeaders(call.Format(), resHeaders)
+		if err != nil {
+			response.SendSystemError(err)
+			return
+		}
+		if err := tchannel.NewArgWriter(response.Arg2Writer()).Write(resArg2); err != nil {
+			return
+		}
+		tchannel.NewArgWriter(response.Arg3Writer()).Write(res)
+	})
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package grpcbridge bridges TChannel and gRPC, so a service can serve the
+// same handlers over both protocols while it migrates, and TChannel callers
+// can reach gRPC backends.
+//
+// Server is an http.Handler that accepts unary gRPC calls and makes them as
+// TChannel calls, typically to the same service instance. It must be served
+// over HTTP/2, e.g. by an http.Server using TLS.
+//
+// Client makes unary gRPC calls, and Client.Handler returns a tchannel.Handler
+// that forwards TChannel calls to the gRPC backend.
+//
+// Messages are passed through as bytes without being decoded: arg3 is the
+// gRPC message, and the application headers in arg2 are mapped to gRPC
+// metadata using the call's arg scheme. Streaming calls and compressed
+// messages are not supported.
+package grpcbridge
@@ -0,0 +1,154 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+//go:build go1.14
+// +build go1.14
+
+package grpcbridge_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	. "github.com/uber/tchannel-go/grpcbridge"
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"golang.org/x/net/context"
+)
+
+// echoServer returns a TChannel server that echoes arg2 and arg3, and fails
+// calls with arg3 "busy" or "app-error".
+func echoServer(t *testing.T) *tchannel.Channel {
+	server := testutils.NewServer(t, testutils.NewOpts().SetServiceName("echo-svc").DisableLogVerification())
+	testutils.RegisterFunc(server, "Echo::echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		switch string(args.Arg3) {
+		case "busy":
+			return nil, tchannel.ErrServerBusy
+		case "app-error":
+			return &raw.Res{IsErr: true, Arg2: args.Arg2, Arg3: args.Arg3}, nil
+		}
+		return &raw.Res{Arg2: args.Arg2, Arg3: args.Arg3}, nil
+	})
+	return server
+}
+
+// grpcBridge serves a Server over HTTP/2 that calls the given TChannel
+// server, and returns a Client for it.
+func grpcBridge(t *testing.T, server *tchannel.Channel) (*Client, func()) {
+	ch := testutils.NewClient(t, nil)
+	bridge := NewServer(ch, server.ServiceName(), &ServerOptions{
+		HostPort: server.PeerInfo().HostPort,
+		Format:   tchannel.JSON,
+	})
+
+	var nonHTTP2 atomic.Int32
+	httpServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			nonHTTP2.Inc()
+		}
+		bridge.ServeHTTP(w, r)
+	}))
+	httpServer.EnableHTTP2 = true
+	httpServer.StartTLS()
+
+	client := NewClient(httpServer.Listener.Addr().String(), &ClientOptions{
+		Transport: httpServer.Client().Transport,
+	})
+	return client, func() {
+		httpServer.Close()
+		ch.Close()
+		assert.Zero(t, nonHTTP2.Load(), "Calls should use HTTP/2")
+	}
+}
+
+func TestServerAndClient(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+	client, closeBridge := grpcBridge(t, server)
+	defer closeBridge()
+
+	ctx, cancel := tchannel.NewContext(time.Second)
+	defer cancel()
+
+	headers := map[string]string{"key": "value"}
+	resHeaders, res, err := client.Call(ctx, "Echo/echo", headers, []byte("hello"))
+	require.NoError(t, err, "Call failed")
+	assert.Equal(t, headers, resHeaders, "Response metadata mismatch")
+	assert.Equal(t, "hello", string(res), "Response mismatch")
+
+	_, res, err = client.Call(ctx, "Echo/echo", nil, []byte("app-error"))
+	require.Error(t, err, "Expected application error")
+	assert.Equal(t, CodeUnknown, err.(Status).Code, "Unexpected code for application error")
+	assert.Equal(t, "app-error", string(res), "Application errors should return arg3")
+
+	_, _, err = client.Call(ctx, "Echo/echo", nil, []byte("busy"))
+	require.Error(t, err, "Expected busy error")
+	assert.Equal(t, CodeResourceExhausted, err.(Status).Code, "Unexpected code for busy error")
+
+	_, _, err = client.Call(ctx, "Echo/unknown", nil, nil)
+	require.Error(t, err, "Expected unknown method to fail")
+	assert.Equal(t, CodeInvalidArgument, err.(Status).Code, "Unexpected code for unknown method")
+}
+
+func TestClientHandler(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+	client, closeBridge := grpcBridge(t, server)
+	defer closeBridge()
+
+	// TChannel calls to the proxy are forwarded over gRPC to the bridge,
+	// which calls the TChannel server.
+	proxy := testutils.NewServer(t, testutils.NewOpts().SetServiceName("proxy"))
+	defer proxy.Close()
+	proxy.Register(client.Handler(), "Echo::echo")
+
+	caller := testutils.NewClient(t, nil)
+	defer caller.Close()
+
+	call := func(arg3 string) ([]byte, []byte, *tchannel.OutboundCallResponse, error) {
+		ctx, cancel := tchannel.NewContext(time.Second)
+		defer cancel()
+
+		call, err := caller.BeginCall(ctx, proxy.PeerInfo().HostPort, "proxy", "Echo::echo", &tchannel.CallOptions{Format: tchannel.JSON})
+		require.NoError(t, err, "BeginCall failed")
+		return raw.WriteArgs(call, []byte(`{"key":"value"}`), []byte(arg3))
+	}
+
+	arg2, arg3, res, err := call("hello")
+	require.NoError(t, err, "Call failed")
+	assert.JSONEq(t, `{"key":"value"}`, string(arg2), "Response headers mismatch")
+	assert.Equal(t, "hello", string(arg3), "Response mismatch")
+	assert.False(t, res.ApplicationError(), "Unexpected application error")
+
+	_, arg3, res, err = call("app-error")
+	require.NoError(t, err, "Call failed")
+	assert.True(t, res.ApplicationError(), "Expected application error")
+	assert.Equal(t, "app-error", string(arg3), "Response mismatch")
+
+	_, _, _, err = call("busy")
+	assert.Equal(t, tchannel.ErrCodeBusy, tchannel.GetSystemErrorCode(err), "Unexpected error code")
+}
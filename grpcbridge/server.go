@@ -0,0 +1,181 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package grpcbridge
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/raw"
+
+	"golang.org/x/net/context"
+)
+
+// ServerOptions are options used when creating a Server.
+type ServerOptions struct {
+	// HostPort is the TChannel peer that calls are made to. If it's not set,
+	// calls are made to the channel's peers for the service.
+	HostPort string
+
+	// Format is the arg scheme of the TChannel handlers, which determines
+	// how gRPC metadata is encoded in arg2. Defaults to Raw, which drops the
+	// metadata.
+	Format tchannel.Format
+
+	// Timeout is the timeout for gRPC calls that don't set a deadline.
+	// Defaults to 1s.
+	Timeout time.Duration
+
+	// MethodName returns the TChannel method for a gRPC service and method.
+	// Defaults to "service::method".
+	MethodName func(service, method string) string
+}
+
+// Server is an http.Handler that makes TChannel calls for unary gRPC calls.
+type Server struct {
+	ch          *tchannel.Channel
+	serviceName string
+	opts        ServerOptions
+}
+
+// NewServer returns a Server that makes TChannel calls to serviceName using
+// the given channel.
+func NewServer(ch *tchannel.Channel, serviceName string, opts *ServerOptions) *Server {
+	s := &Server{
+		ch:          ch,
+		serviceName: serviceName,
+	}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Format == "" {
+		s.opts.Format = tchannel.Raw
+	}
+	if s.opts.Timeout <= 0 {
+		s.opts.Timeout = time.Second
+	}
+	if s.opts.MethodName == nil {
+		s.opts.MethodName = func(service, method string) string {
+			return service + "::" + method
+		}
+	}
+	return s
+}
+
+// ServeHTTP handles a unary gRPC call.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || !strings.HasPrefix(r.Header.Get("Content-Type"), _contentType) {
+		http.Error(w, "expected a gRPC request", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		writeStatus(w, Status{CodeUnimplemented, "invalid method " + r.URL.Path})
+		return
+	}
+	method := s.opts.MethodName(parts[0], parts[1])
+
+	timeout := s.opts.Timeout
+	if v := r.Header.Get(_timeoutHeader); v != "" {
+		var ok bool
+		if timeout, ok = parseTimeout(v); !ok {
+			writeStatus(w, Status{CodeInvalidArgument, "invalid grpc-timeout " + v})
+			return
+		}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	arg3, err := readMessage(r.Body)
+	if err != nil {
+		writeStatus(w, Status{CodeInvalidArgument, "failed to read request: " + err.Error()})
+		return
+	}
+	arg2, err := encodeHeaders(s.opts.Format, headersFromMetadata(r.Header))
+	if err != nil {
+		writeStatus(w, Status{CodeInternal, "failed to encode headers: " + err.Error()})
+		return
+	}
+
+	resArg2, resArg3, res, err := s.call(ctx, method, arg2, arg3)
+	if err != nil {
+		writeStatus(w, Status{codeForError(err), err.Error()})
+		return
+	}
+	resHeaders, err := decodeHeaders(s.opts.Format, resArg2)
+	if err != nil {
+		writeStatus(w, Status{CodeInternal, "failed to decode headers: " + err.Error()})
+		return
+	}
+
+	// Application errors are returned with CodeUnknown, and arg3 as the
+	// message so that it can be decoded by callers that understand it.
+	status := Status{Code: CodeOK}
+	if res.ApplicationError() {
+		status = Status{CodeUnknown, "application error"}
+	}
+
+	h := w.Header()
+	setMetadata(h, resHeaders)
+	h.Set("Content-Type", _contentType)
+	h.Set("Trailer", _statusHeader+", "+_messageHeader)
+	w.WriteHeader(http.StatusOK)
+	if err := writeMessage(w, resArg3); err != nil {
+		return
+	}
+	setStatus(h, status)
+}
+
+func (s *Server) call(ctx context.Context, method string, arg2, arg3 []byte) ([]byte, []byte, *tchannel.OutboundCallResponse, error) {
+	callOptions := &tchannel.CallOptions{Format: s.opts.Format}
+
+	var (
+		call *tchannel.OutboundCall
+		err  error
+	)
+	if s.opts.HostPort != "" {
+		call, err = s.ch.BeginCall(ctx, s.opts.HostPort, s.serviceName, method, callOptions)
+	} else {
+		call, err = s.ch.GetSubChannel(s.serviceName).BeginCall(ctx, method, callOptions)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return raw.WriteArgs(call, arg2, arg3)
+}
+
+// writeStatus writes a response without a message for a call that failed.
+func writeStatus(w http.ResponseWriter, status Status) {
+	h := w.Header()
+	h.Set("Content-Type", _contentType)
+	setStatus(h, status)
+	w.WriteHeader(http.StatusOK)
+}
+
+func setStatus(h http.Header, status Status) {
+	h.Set(_statusHeader, strconv.Itoa(int(status.Code)))
+	if status.Message != "" {
+		h.Set(_messageHeader, encodeMessage(status.Message))
+	}
+}
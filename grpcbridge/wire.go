@@ -0,0 +1,298 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package grpcbridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/thrift"
+)
+
+const (
+	_contentType = "application/grpc"
+
+	// _maxMessageSize is the largest message that will be read.
+	_maxMessageSize = 64 * 1024 * 1024
+
+	_statusHeader  = "Grpc-Status"
+	_messageHeader = "Grpc-Message"
+	_timeoutHeader = "Grpc-Timeout"
+)
+
+var (
+	errCompressed      = errors.New("compressed gRPC messages are not supported")
+	errMessageTooLarge = errors.New("gRPC message is too large")
+)
+
+// Code is a gRPC status code.
+type Code uint32
+
+// The gRPC status codes that TChannel errors are mapped to.
+const (
+	CodeOK                Code = 0
+	CodeCanceled          Code = 1
+	CodeUnknown           Code = 2
+	CodeInvalidArgument   Code = 3
+	CodeDeadlineExceeded  Code = 4
+	CodeResourceExhausted Code = 8
+	CodeUnimplemented     Code = 12
+	CodeInternal          Code = 13
+	CodeUnavailable       Code = 14
+)
+
+// Status is the error returned for a gRPC call that failed.
+type Status struct {
+	Code    Code
+	Message string
+}
+
+func (s Status) Error() string {
+	return fmt.Sprintf("grpc error code %d: %v", s.Code, s.Message)
+}
+
+// codeForError returns the gRPC status code for a TChannel error.
+func codeForError(err error) Code {
+	switch tchannel.GetSystemErrorCode(tchannel.GetContextError(err)) {
+	case tchannel.ErrCodeTimeout:
+		return CodeDeadlineExceeded
+	case tchannel.ErrCodeCancelled:
+		return CodeCanceled
+	case tchannel.ErrCodeBusy:
+		return CodeResourceExhausted
+	case tchannel.ErrCodeDeclined, tchannel.ErrCodeNetwork:
+		return CodeUnavailable
+	case tchannel.ErrCodeBadRequest:
+		return CodeInvalidArgument
+	case tchannel.ErrCodeProtocol:
+		return CodeInternal
+	}
+	return CodeUnknown
+}
+
+// errorForStatus returns the TChannel system error for a failed gRPC call.
+func errorForStatus(s Status) error {
+	code := tchannel.ErrCodeUnexpected
+	switch s.Code {
+	case CodeDeadlineExceeded:
+		code = tchannel.ErrCodeTimeout
+	case CodeCanceled:
+		code = tchannel.ErrCodeCancelled
+	case CodeResourceExhausted:
+		code = tchannel.ErrCodeBusy
+	case CodeUnavailable:
+		code = tchannel.ErrCodeDeclined
+	case CodeInvalidArgument, CodeUnimplemented:
+		code = tchannel.ErrCodeBadRequest
+	}
+	return tchannel.NewSystemError(code, s.Message)
+}
+
+// writeMessage writes a length-prefixed, uncompressed gRPC message.
+func writeMessage(w io.Writer, msg []byte) error {
+	var prefix [5]byte
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(msg)))
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(msg)
+	return err
+}
+
+// readMessage reads a single length-prefixed gRPC message. It returns io.EOF
+// if there is no message.
+func readMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	if prefix[0] != 0 {
+		return nil, errCompressed
+	}
+
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > _maxMessageSize {
+		return nil, errMessageTooLarge
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+var _timeoutUnits = []struct {
+	unit byte
+	d    time.Duration
+}{
+	{'n', time.Nanosecond},
+	{'u', time.Microsecond},
+	{'m', time.Millisecond},
+	{'S', time.Second},
+	{'M', time.Minute},
+	{'H', time.Hour},
+}
+
+// formatTimeout formats a grpc-timeout header value, which is at most 8
+// digits, using the most precise unit that fits.
+func formatTimeout(d time.Duration) string {
+	if d <= 0 {
+		d = time.Nanosecond
+	}
+	for _, u := range _timeoutUnits {
+		// Round up so the callee's deadline isn't earlier than the caller's.
+		if n := (d + u.d - 1) / u.d; n < 1e8 {
+			return strconv.FormatInt(int64(n), 10) + string(u.unit)
+		}
+	}
+	return "99999999H"
+}
+
+// parseTimeout parses a grpc-timeout header value.
+func parseTimeout(s string) (time.Duration, bool) {
+	if len(s) < 2 || len(s) > 9 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	for _, u := range _timeoutUnits {
+		if u.unit == s[len(s)-1] {
+			return time.Duration(n) * u.d, true
+		}
+	}
+	return 0, false
+}
+
+// encodeMessage percent-encodes a grpc-message header value.
+func encodeMessage(msg string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(msg); i++ {
+		if c := msg[i]; c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&buf, "%%%02X", c)
+			continue
+		}
+		buf.WriteByte(msg[i])
+	}
+	return buf.String()
+}
+
+// decodeMessage decodes a percent-encoded grpc-message header value.
+func decodeMessage(msg string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(msg); i++ {
+		if msg[i] == '%' && i+2 < len(msg) {
+			if c, err := strconv.ParseUint(msg[i+1:i+3], 16, 8); err == nil {
+				buf.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		buf.WriteByte(msg[i])
+	}
+	return buf.String()
+}
+
+// isReservedHeader returns whether an HTTP/2 header is used by the gRPC
+// protocol rather than being call metadata.
+func isReservedHeader(key string) bool {
+	switch key {
+	case "content-type", "content-length", "date", "te", "trailer", "user-agent", "accept-encoding":
+		return true
+	}
+	return strings.HasPrefix(key, "grpc-")
+}
+
+// headersFromMetadata returns the application headers for the gRPC metadata
+// in h. Keys are lower-cased, and multiple values are joined with commas.
+func headersFromMetadata(h http.Header) map[string]string {
+	var headers map[string]string
+	for k, vs := range h {
+		key := strings.ToLower(k)
+		if isReservedHeader(key) {
+			continue
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[key] = strings.Join(vs, ",")
+	}
+	return headers
+}
+
+// setMetadata sets the application headers as gRPC metadata in h.
+func setMetadata(h http.Header, headers map[string]string) {
+	for k, v := range headers {
+		if !isReservedHeader(strings.ToLower(k)) {
+			h.Set(k, v)
+		}
+	}
+}
+
+// encodeHeaders encodes application headers as arg2 for the arg scheme.
+// Raw calls have no headers, so arg2 is empty.
+func encodeHeaders(format tchannel.Format, headers map[string]string) ([]byte, error) {
+	switch format {
+	case tchannel.Thrift:
+		var buf bytes.Buffer
+		if err := thrift.WriteHeaders(&buf, headers); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case tchannel.JSON:
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		return json.Marshal(headers)
+	}
+	return nil, nil
+}
+
+// decodeHeaders decodes the application headers in arg2 for the arg scheme.
+func decodeHeaders(format tchannel.Format, arg2 []byte) (map[string]string, error) {
+	if len(arg2) == 0 {
+		return nil, nil
+	}
+
+	switch format {
+	case tchannel.Thrift:
+		return thrift.ReadHeaders(bytes.NewReader(arg2))
+	case tchannel.JSON:
+		var headers map[string]string
+		if err := json.Unmarshal(arg2, &headers); err != nil {
+			return nil, err
+		}
+		return headers, nil
+	}
+	return nil, nil
+}
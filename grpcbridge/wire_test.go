@@ -0,0 +1,82 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package grpcbridge
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{time.Millisecond, "1000000n"},
+		{time.Second, "1000000u"},
+		{1500 * time.Microsecond, "1500000n"},
+		{time.Minute, "60000000u"},
+		{48 * time.Hour, "172800S"},
+	}
+
+	for _, tt := range tests {
+		got := formatTimeout(tt.d)
+		assert.Equal(t, tt.want, got, "formatTimeout(%v)", tt.d)
+
+		parsed, ok := parseTimeout(got)
+		require.True(t, ok, "parseTimeout(%q) failed", got)
+		assert.Equal(t, tt.d, parsed, "parseTimeout(%q)", got)
+	}
+
+	for _, invalid := range []string{"", "1", "1x", "-1m", "123456789m"} {
+		_, ok := parseTimeout(invalid)
+		assert.False(t, ok, "parseTimeout(%q) should fail", invalid)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeMessage(&buf, []byte("hello")), "writeMessage failed")
+
+	msg, err := readMessage(&buf)
+	require.NoError(t, err, "readMessage failed")
+	assert.Equal(t, "hello", string(msg), "Message mismatch")
+
+	_, err = readMessage(&buf)
+	assert.Equal(t, io.EOF, err, "Expected EOF without a message")
+
+	_, err = readMessage(bytes.NewReader([]byte{1, 0, 0, 0, 0}))
+	assert.Equal(t, errCompressed, err, "Expected compressed messages to fail")
+}
+
+func TestStatusMessage(t *testing.T) {
+	for _, msg := range []string{"", "simple", "100% done", "línea\nnueva"} {
+		encoded := encodeMessage(msg)
+		for _, c := range []byte(encoded) {
+			assert.True(t, c >= ' ' && c <= '~', "Encoded message %q has invalid byte %q", encoded, c)
+		}
+		assert.Equal(t, msg, decodeMessage(encoded), "Message round trip failed")
+	}
+}
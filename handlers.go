@@ -118,10 +118,42 @@ func (hmap *handlerMap) Handle(ctx context.Context, call *InboundCall) {
 	h.Handle(ctx, call)
 }
 
+func (hmap *handlerMap) isEmpty() bool {
+	hmap.RLock()
+	empty := len(hmap.handlers) == 0
+	hmap.RUnlock()
+
+	return empty
+}
+
 // channelHandler is a Handler that wraps a Channel and delegates requests
 // to SubChannels based on the inbound call's service name.
 type channelHandler struct{ ch *Channel }
 
 func (c channelHandler) Handle(ctx context.Context, call *InboundCall) {
-	c.ch.GetSubChannel(call.ServiceName()).handler.Handle(ctx, call)
+	// The channel's own service and services that a relay handles locally are
+	// always served by this channel, so unknown methods return bad requests.
+	serviceName := call.ServiceName()
+	if _, local := c.ch.relayLocal[serviceName]; local || serviceName == c.ch.ServiceName() {
+		c.ch.GetSubChannel(serviceName).handler.Handle(ctx, call)
+		return
+	}
+
+	sc, ok := c.ch.subChannels.get(serviceName)
+	if !ok || !sc.hasHandlers() {
+		c.ch.unknownServiceHandler.Handle(ctx, call)
+		return
+	}
+	sc.handler.Handle(ctx, call)
+}
+
+// rejectUnknownService is the default handler for calls to a service
+// that has no registered handlers.
+func rejectUnknownService(ctx context.Context, call *InboundCall) {
+	call.log.WithFields(
+		LogField{"serviceName", call.ServiceName()},
+		LogField{"method", call.MethodString()},
+	).Info("Couldn't find handlers for service.")
+	call.Response().SendSystemError(
+		NewSystemError(ErrCodeDeclined, "no handlers for service %q", call.ServiceName()))
 }
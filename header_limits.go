@@ -0,0 +1,211 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sort"
+)
+
+// HeaderLimitPolicy is how inbound calls with headers that exceed the
+// HeaderLimits are handled.
+type HeaderLimitPolicy int
+
+const (
+	// HeaderLimitReject fails calls whose headers exceed the limits with a
+	// BadRequest error. This is the default.
+	HeaderLimitReject HeaderLimitPolicy = iota
+
+	// HeaderLimitTruncate drops the largest transport headers until the
+	// remaining headers are within the limits. Calls whose application
+	// headers exceed the limit are rejected, since arg2 can't be truncated
+	// without knowing its format.
+	HeaderLimitTruncate
+
+	// HeaderLimitLog logs calls whose headers exceed the limits, and handles
+	// them as usual.
+	HeaderLimitLog
+)
+
+// HeaderLimits limits the size of the headers of inbound calls, including
+// relayed calls. Limits are checked using the call's first frame, before the
+// call is handled or forwarded. Zero values mean no limit.
+type HeaderLimits struct {
+	// MaxTransportHeaders limits the number of transport headers.
+	MaxTransportHeaders int
+
+	// MaxTransportHeaderBytes limits the total size of the keys and values
+	// of the transport headers.
+	MaxTransportHeaderBytes int
+
+	// MaxApplicationHeaderBytes limits the size of arg2, which contains the
+	// application headers for Thrift and JSON calls. An arg2 that doesn't end
+	// in the call's first frame counts as exceeding the limit.
+	MaxApplicationHeaderBytes int
+
+	// Policy is how calls that exceed the limits are handled.
+	Policy HeaderLimitPolicy
+
+	// Validate is an optional function that's called with the transport
+	// headers of each inbound call, after any have been dropped by
+	// HeaderLimitTruncate. If it returns an error, the call is failed with
+	// that error if it's a SystemError, and with a BadRequest error otherwise.
+	Validate func(headers map[string]string) error
+}
+
+// enabledHeaderLimits returns a copy of the limits, or nil if none of the
+// limits or the validation hook are set.
+func enabledHeaderLimits(l *HeaderLimits) *HeaderLimits {
+	if l == nil || (l.MaxTransportHeaders <= 0 && l.MaxTransportHeaderBytes <= 0 &&
+		l.MaxApplicationHeaderBytes <= 0 && l.Validate == nil) {
+		return nil
+	}
+	limits := *l
+	return &limits
+}
+
+// transportHeadersSize returns the total size of the keys and values.
+func transportHeadersSize(headers [][2]string) int {
+	size := 0
+	for _, h := range headers {
+		size += len(h[0]) + len(h[1])
+	}
+	return size
+}
+
+// withinTransportLimits returns whether the transport headers are within the
+// limits.
+func (l *HeaderLimits) withinTransportLimits(headers [][2]string) bool {
+	if l.MaxTransportHeaders > 0 && len(headers) > l.MaxTransportHeaders {
+		return false
+	}
+	if l.MaxTransportHeaderBytes > 0 && transportHeadersSize(headers) > l.MaxTransportHeaderBytes {
+		return false
+	}
+	return true
+}
+
+// truncate returns the keys of the largest transport headers that need to be
+// dropped for the rest to be within the limits, and the remaining headers.
+func (l *HeaderLimits) truncate(headers [][2]string) (drop []string, kept [][2]string) {
+	kept = append([][2]string(nil), headers...)
+	sort.SliceStable(kept, func(i, j int) bool {
+		return len(kept[i][0])+len(kept[i][1]) > len(kept[j][0])+len(kept[j][1])
+	})
+	for len(kept) > 0 && !l.withinTransportLimits(kept) {
+		drop = append(drop, kept[0][0])
+		kept = kept[1:]
+	}
+	return drop, kept
+}
+
+// callReqArg2Size returns the size of arg2 in the first frame of a call, and
+// whether arg2 ends in the frame. cur is the offset after the transport
+// headers.
+func callReqArg2Size(f *Frame, cur int) (size int, complete bool, err error) {
+	payload := f.SizedPayload()
+	if cur >= len(payload) {
+		return 0, false, errMalformedCallFrame
+	}
+	hasMore := payload[_flagsIndex]&hasMoreFragmentsFlag != 0
+
+	// csumtype:1 (csum:4){0,1} arg1~2 arg2~2, or arg1~4 arg2~4 in jumbo frames
+	jumbo := f.Header.isJumbo()
+	sizeLen := chunkSizeLen(jumbo)
+	cur += 1 + ChecksumType(payload[cur]).ChecksumSize()
+	if cur+sizeLen > len(payload) {
+		return 0, !hasMore, nil
+	}
+	cur += sizeLen + readChunkSize(payload[cur:], jumbo)
+	if cur+sizeLen > len(payload) {
+		return 0, !hasMore, nil
+	}
+	size = readChunkSize(payload[cur:], jumbo)
+	cur += sizeLen + size
+	return size, cur < len(payload) || !hasMore, nil
+}
+
+// checkHeaderLimits checks the headers of an inbound call req frame against
+// the channel's HeaderLimits. It returns the keys of transport headers that
+// should be dropped, and an error if the call should be failed.
+func (c *Connection) checkHeaderLimits(f *Frame) (drop []string, err error) {
+	limits := c.headerLimits
+	payload := f.SizedPayload()
+	headerStart, err := callReqHeaderStart(payload)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := readTransportHeaders(payload, headerStart)
+	if err != nil {
+		return nil, err
+	}
+
+	exceeded := func(reason string, fields ...LogField) error {
+		c.statsReporter.IncCounter("inbound.calls.header-limit-exceeded", c.commonStatsTags, 1)
+		fields = append(fields, LogField{"id", f.Header.ID}, LogField{"reason", reason})
+		if limits.Policy == HeaderLimitLog {
+			c.log.WithFields(fields...).Warn("Inbound call headers exceed the limits.")
+			return nil
+		}
+		return NewSystemError(ErrCodeBadRequest, "call headers exceed the limits: %v", reason)
+	}
+
+	if !limits.withinTransportLimits(headers) {
+		if limits.Policy == HeaderLimitTruncate {
+			drop, headers = limits.truncate(headers)
+		} else if err := exceeded("transport headers",
+			LogField{"numHeaders", len(headers)},
+			LogField{"headersSize", transportHeadersSize(headers)},
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	if max := limits.MaxApplicationHeaderBytes; max > 0 {
+		headerEnd, err := skipTransportHeaders(payload, headerStart)
+		if err != nil {
+			return nil, err
+		}
+		size, complete, err := callReqArg2Size(f, headerEnd)
+		if err != nil {
+			return nil, err
+		}
+		if size > max || !complete {
+			if err := exceeded("application headers", LogField{"arg2Size", size}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if limits.Validate != nil {
+		m := make(map[string]string, len(headers))
+		for _, h := range headers {
+			m[h[0]] = h[1]
+		}
+		if err := limits.Validate(m); err != nil {
+			if _, ok := err.(SystemError); !ok {
+				err = NewWrappedSystemError(ErrCodeBadRequest, err)
+			}
+			return nil, err
+		}
+	}
+
+	return drop, nil
+}
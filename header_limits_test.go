@@ -0,0 +1,184 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// callWithShardKey makes a raw call to the test server's echo method with
+// the given shard key, returning the shard key seen by the handler.
+func callWithShardKey(ts *testutils.TestServer, shardKey string, arg2 []byte) (string, error) {
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	client := ts.NewClient(nil)
+	call, err := client.BeginCall(ctx, ts.HostPort(), ts.ServiceName(), "echo", &CallOptions{
+		Format:   Raw,
+		ShardKey: shardKey,
+	})
+	if err != nil {
+		return "", err
+	}
+	_, resArg3, _, err := raw.WriteArgs(call, arg2, nil)
+	return string(resArg3), err
+}
+
+func registerShardKeyEcho(ts *testutils.TestServer) {
+	testutils.RegisterFunc(ts.Server(), "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		return &raw.Res{Arg3: []byte(CurrentCall(ctx).ShardKey())}, nil
+	})
+}
+
+func TestHeaderLimitsReject(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.HeaderLimits = &HeaderLimits{
+		MaxTransportHeaderBytes:   100,
+		MaxApplicationHeaderBytes: 50,
+	}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		registerShardKeyEcho(ts)
+
+		got, err := callWithShardKey(ts, "key", []byte("small"))
+		require.NoError(t, err, "Call within limits failed")
+		assert.Equal(t, "key", got, "Unexpected shard key")
+
+		_, err = callWithShardKey(ts, strings.Repeat("k", 150), nil)
+		assert.Equal(t, ErrCodeBadRequest, GetSystemErrorCode(err), "Expected large transport headers to be rejected")
+
+		_, err = callWithShardKey(ts, "key", make([]byte, 100))
+		assert.Equal(t, ErrCodeBadRequest, GetSystemErrorCode(err), "Expected large arg2 to be rejected")
+
+		// arg2 that spans multiple frames is rejected.
+		_, err = callWithShardKey(ts, "key", make([]byte, 100000))
+		assert.Equal(t, ErrCodeBadRequest, GetSystemErrorCode(err), "Expected fragmented arg2 to be rejected")
+	})
+}
+
+func TestHeaderLimitsTruncatedFrame(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.HeaderLimits = &HeaderLimits{MaxTransportHeaders: 10}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		registerShardKeyEcho(ts)
+
+		// Rewrite the call req to declare 5 transport headers, but end the
+		// frame before any of them.
+		relayFunc := func(outgoing bool, f *Frame) *Frame {
+			if outgoing && strings.Contains(f.Header.String(), "CallReq[") {
+				// flags:1 ttl:4 tracing:25 service~1
+				headerStart := 31 + int(f.Payload[30])
+				f.Payload[headerStart] = 5
+				f.Header.SetPayloadSize(uint16(headerStart + 1))
+			}
+			return f
+		}
+		relay, shutdown := testutils.FrameRelay(t, ts.HostPort(), relayFunc)
+		defer shutdown()
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, ts.NewClient(nil), relay, ts.ServiceName(), "echo", nil, nil)
+		assert.Equal(t, ErrCodeBadRequest, GetSystemErrorCode(err), "Expected truncated frame to be rejected, got %v", err)
+
+		// The server should still handle calls.
+		got, err := callWithShardKey(ts, "key", nil)
+		require.NoError(t, err, "Call after truncated frame failed")
+		assert.Equal(t, "key", got, "Unexpected shard key")
+	})
+}
+
+func TestHeaderLimitsTruncate(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.HeaderLimits = &HeaderLimits{
+		MaxTransportHeaderBytes:   100,
+		MaxApplicationHeaderBytes: 50,
+		Policy:                    HeaderLimitTruncate,
+	}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		registerShardKeyEcho(ts)
+
+		got, err := callWithShardKey(ts, strings.Repeat("k", 150), nil)
+		require.NoError(t, err, "Call with truncated headers failed")
+		assert.Empty(t, got, "Expected the largest transport header to be dropped")
+
+		_, err = callWithShardKey(ts, "key", make([]byte, 100))
+		assert.Equal(t, ErrCodeBadRequest, GetSystemErrorCode(err), "Expected large arg2 to be rejected")
+	})
+}
+
+func TestHeaderLimitsLog(t *testing.T) {
+	opts := testutils.NewOpts().
+		AddLogFilter("Inbound call headers exceed the limits.", 2)
+	opts.HeaderLimits = &HeaderLimits{
+		MaxTransportHeaders: 1,
+		Policy:              HeaderLimitLog,
+	}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		registerShardKeyEcho(ts)
+
+		got, err := callWithShardKey(ts, "key", nil)
+		require.NoError(t, err, "Call exceeding logged limits failed")
+		assert.Equal(t, "key", got, "Unexpected shard key")
+	})
+}
+
+func TestHeaderLimitsValidate(t *testing.T) {
+	errDeclined := NewSystemError(ErrCodeDeclined, "declined")
+	opts := testutils.NewOpts()
+	opts.HeaderLimits = &HeaderLimits{
+		Validate: func(headers map[string]string) error {
+			switch headers[string(ShardKey)] {
+			case "bad":
+				return errors.New("bad shard key")
+			case "declined":
+				return errDeclined
+			}
+			return nil
+		},
+	}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		registerShardKeyEcho(ts)
+
+		got, err := callWithShardKey(ts, "good", nil)
+		require.NoError(t, err, "Call with valid headers failed")
+		assert.Equal(t, "good", got, "Unexpected shard key")
+
+		_, err = callWithShardKey(ts, "bad", nil)
+		assert.Equal(t, ErrCodeBadRequest, GetSystemErrorCode(err), "Unexpected error code")
+		assert.Contains(t, err.Error(), "bad shard key", "Unexpected error")
+
+		_, err = callWithShardKey(ts, "declined", nil)
+		assert.Equal(t, ErrCodeDeclined, GetSystemErrorCode(err), "Expected SystemErrors to be returned as-is")
+	})
+}
@@ -30,6 +30,7 @@ import (
 const (
 	_defaultHealthCheckTimeout         = time.Second
 	_defaultHealthCheckFailuresToClose = 5
+	_defaultHealthCheckEjectDuration   = 30 * time.Second
 
 	_healthHistorySize = 256
 )
@@ -51,6 +52,16 @@ type HealthCheckOptions struct {
 	// will cause this connection to be closed.
 	// If no value is specified, it defaults to 5.
 	FailuresToClose int
+
+	// FailuresToEject is the number of consecutive health check failures that
+	// will cause the connection's peer to be ejected, so peer selection avoids
+	// it until EjectDuration passes or a health check succeeds. If this is
+	// zero, peers are not ejected.
+	FailuresToEject int
+
+	// EjectDuration is how long a peer is ejected for.
+	// If no value is specified, it defaults to 30 seconds.
+	EjectDuration time.Duration
 }
 
 type healthHistory struct {
@@ -103,6 +114,9 @@ func (hco HealthCheckOptions) withDefaults() HealthCheckOptions {
 	if hco.FailuresToClose == 0 {
 		hco.FailuresToClose = _defaultHealthCheckFailuresToClose
 	}
+	if hco.FailuresToEject > 0 && hco.EjectDuration == 0 {
+		hco.EjectDuration = _defaultHealthCheckEjectDuration
+	}
 	return hco
 }
 
@@ -132,6 +146,9 @@ func (c *Connection) healthCheck(connID uint32) {
 			if c.log.Enabled(LogLevelDebug) {
 				c.log.Debug("Performed successful active health check.")
 			}
+			if opts.FailuresToEject > 0 && consecutiveFailures >= opts.FailuresToEject {
+				c.ejectPeer(time.Time{})
+			}
 			consecutiveFailures = 0
 			continue
 		}
@@ -150,6 +167,10 @@ func (c *Connection) healthCheck(connID uint32) {
 			{"failuresToClose", opts.FailuresToClose},
 		}...).Warn("Failed active health check.")
 
+		if consecutiveFailures == opts.FailuresToEject {
+			c.ejectPeer(c.timeNow().Add(opts.EjectDuration))
+		}
+
 		if consecutiveFailures >= opts.FailuresToClose {
 			c.close(LogFields{
 				{"reason", "health check failure"},
@@ -160,6 +181,43 @@ func (c *Connection) healthCheck(connID uint32) {
 	}
 }
 
+// ejectPeer ejects the connection's peer until the given time, or clears the
+// ejection if until is zero.
+func (c *Connection) ejectPeer(until time.Time) {
+	if c.events.OnPeerEjected != nil {
+		c.events.OnPeerEjected(c, until)
+	}
+}
+
+// peerEjected updates the ejection of the peer for a connection whose health
+// checks failed or recovered. Outbound connections update the peer for the
+// host:port they were created with, as well as the remote's host:port.
+func (ch *Channel) peerEjected(c *Connection, until time.Time) {
+	var ejectedUntil int64
+	if !until.IsZero() {
+		ejectedUntil = until.UnixNano()
+		c.log.WithFields(LogField{"ejectedUntil", until}).Warn("Ejecting peer after failed active health checks.")
+	}
+
+	for i, hostPort := range []string{c.outboundHP, c.remotePeerInfo.HostPort} {
+		if i > 0 && hostPort == c.outboundHP {
+			break
+		}
+		if peer, ok := ch.RootPeers().Get(hostPort); ok {
+			peer.ejectedUntil.Store(ejectedUntil)
+			if !until.IsZero() {
+				ch.eventBus.publish(Event{
+					Type:         EventPeerEjected,
+					HostPort:     hostPort,
+					Connection:   c,
+					Peer:         peer,
+					EjectedUntil: until,
+				})
+			}
+		}
+	}
+}
+
 func (c *Connection) stopHealthCheck() {
 	// Health checks are not enabled.
 	if c.healthCheckDone == nil {
@@ -0,0 +1,196 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// HealthCheckFunc is an application health check registered using
+// RegisterHealthCheck. It returns a non-nil error if the check fails.
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthCheckRegisterOption configures a health check registered using
+// RegisterHealthCheck.
+type HealthCheckRegisterOption func(*registeredHealthCheck)
+
+// NonFatalHealthCheck marks a health check as informational: its result is
+// reported, but a failure does not fail the overall health status.
+func NonFatalHealthCheck(c *registeredHealthCheck) {
+	c.nonFatal = true
+}
+
+// ReadinessHealthCheck marks a health check as a readiness probe: it's only
+// run when checking whether the process should receive traffic, and not when
+// checking whether the process is alive.
+func ReadinessHealthCheck(c *registeredHealthCheck) {
+	c.readiness = true
+}
+
+// HealthCheckType is the type of health being checked.
+type HealthCheckType int
+
+const (
+	// LivenessHealth checks whether the process is up. Health checks marked
+	// as ReadinessHealthCheck are not run.
+	LivenessHealth HealthCheckType = iota
+
+	// ReadinessHealth checks whether the process should receive traffic, and
+	// runs all registered health checks.
+	ReadinessHealth
+)
+
+// HealthCheckResult is the result of running a registered health check.
+type HealthCheckResult struct {
+	// Name is the name the check was registered with.
+	Name string
+
+	// NonFatal is whether the check is informational only.
+	NonFatal bool
+
+	// Readiness is whether the check is a readiness probe.
+	Readiness bool
+
+	// Err is the error returned by the check, or nil if it passed.
+	Err error
+}
+
+// HealthStatus is the aggregated health of a channel.
+type HealthStatus struct {
+	// Ok is false if the channel is draining or closed, or if any check that
+	// is not marked as NonFatalHealthCheck failed.
+	Ok bool
+
+	// Message describes why the channel is unhealthy, and is empty if Ok.
+	Message string
+
+	// Checks are the results of the health checks that were run.
+	Checks []HealthCheckResult
+}
+
+type registeredHealthCheck struct {
+	name      string
+	fn        HealthCheckFunc
+	nonFatal  bool
+	readiness bool
+}
+
+type healthChecks struct {
+	sync.RWMutex
+
+	checks []registeredHealthCheck
+}
+
+// RegisterHealthCheck registers an application health check which is run
+// by the Meta::health endpoint. The overall health status fails if any check
+// that is not marked as NonFatalHealthCheck fails. Registering a check with
+// the same name as an existing check replaces it.
+func (ch *Channel) RegisterHealthCheck(name string, fn HealthCheckFunc, opts ...HealthCheckRegisterOption) {
+	check := registeredHealthCheck{name: name, fn: fn}
+	for _, opt := range opts {
+		opt(&check)
+	}
+
+	hc := &ch.healthChecks
+	hc.Lock()
+	defer hc.Unlock()
+
+	for i := range hc.checks {
+		if hc.checks[i].name == name {
+			hc.checks[i] = check
+			return
+		}
+	}
+	hc.checks = append(hc.checks, check)
+}
+
+// RunHealthChecks runs all registered health checks concurrently, and returns
+// their results in the order they were registered.
+func (ch *Channel) RunHealthChecks(ctx context.Context) []HealthCheckResult {
+	return ch.runHealthChecks(ctx, ReadinessHealth)
+}
+
+// Health runs the health checks for the given type of health, and returns
+// the channel's aggregated health status.
+func (ch *Channel) Health(ctx context.Context, healthType HealthCheckType) HealthStatus {
+	if ch.Draining() {
+		return HealthStatus{Message: "draining"}
+	}
+	if state := ch.State(); state >= ChannelStartClose {
+		return HealthStatus{Message: state.String()}
+	}
+
+	status := HealthStatus{Ok: true, Checks: ch.runHealthChecks(ctx, healthType)}
+	for _, r := range status.Checks {
+		if r.Err == nil || r.NonFatal {
+			continue
+		}
+		if status.Ok {
+			status.Message = fmt.Sprintf("health check %v failed: %v", r.Name, r.Err)
+		}
+		status.Ok = false
+	}
+	return status
+}
+
+func (ch *Channel) runHealthChecks(ctx context.Context, healthType HealthCheckType) []HealthCheckResult {
+	hc := &ch.healthChecks
+	hc.RLock()
+	checks := make([]registeredHealthCheck, 0, len(hc.checks))
+	for _, check := range hc.checks {
+		if check.readiness && healthType != ReadinessHealth {
+			continue
+		}
+		checks = append(checks, check)
+	}
+	hc.RUnlock()
+
+	results := make([]HealthCheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check registeredHealthCheck) {
+			defer wg.Done()
+			results[i] = HealthCheckResult{
+				Name:      check.name,
+				NonFatal:  check.nonFatal,
+				Readiness: check.readiness,
+				Err:       check.fn(ctx),
+			}
+		}(i, check)
+	}
+	wg.Wait()
+	return results
+}
+
+// RunHealthChecks runs the health checks registered on the subchannel's
+// channel.
+func (c *SubChannel) RunHealthChecks(ctx context.Context) []HealthCheckResult {
+	return c.topChannel.RunHealthChecks(ctx)
+}
+
+// Health returns the aggregated health of the subchannel's channel.
+func (c *SubChannel) Health(ctx context.Context, healthType HealthCheckType) HealthStatus {
+	return c.topChannel.Health(ctx, healthType)
+}
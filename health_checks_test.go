@@ -0,0 +1,98 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestRunHealthChecks(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	assert.Empty(t, ch.RunHealthChecks(ctx), "Expected no results without registered checks")
+
+	errDB := errors.New("db unavailable")
+	ch.RegisterHealthCheck("db", func(context.Context) error { return errDB })
+	ch.RegisterHealthCheck("cache", func(context.Context) error { return errDB }, NonFatalHealthCheck)
+	ch.RegisterHealthCheck("queue", func(context.Context) error { return nil })
+
+	want := []HealthCheckResult{
+		{Name: "db", Err: errDB},
+		{Name: "cache", NonFatal: true, Err: errDB},
+		{Name: "queue"},
+	}
+	assert.Equal(t, want, ch.RunHealthChecks(ctx), "Unexpected health check results")
+
+	// Registering an existing name replaces the check, keeping its position.
+	ch.RegisterHealthCheck("db", func(context.Context) error { return nil })
+	want[0].Err = nil
+	assert.Equal(t, want, ch.RunHealthChecks(ctx), "Unexpected health check results after replacing check")
+
+	assert.Equal(t, want, ch.GetSubChannel("svc").RunHealthChecks(ctx), "SubChannel should run the channel's checks")
+}
+
+func TestChannelHealth(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	assert.Equal(t, HealthStatus{Ok: true, Checks: []HealthCheckResult{}}, ch.Health(ctx, ReadinessHealth), "Expected healthy channel without checks")
+
+	errWarmup := errors.New("warming up")
+	ch.RegisterHealthCheck("db", func(context.Context) error { return nil })
+	ch.RegisterHealthCheck("warmup", func(context.Context) error { return errWarmup }, ReadinessHealthCheck)
+
+	assert.Equal(t, HealthStatus{
+		Ok:     true,
+		Checks: []HealthCheckResult{{Name: "db"}},
+	}, ch.Health(ctx, LivenessHealth), "Liveness should not run readiness checks")
+
+	assert.Equal(t, HealthStatus{
+		Message: "health check warmup failed: warming up",
+		Checks: []HealthCheckResult{
+			{Name: "db"},
+			{Name: "warmup", Readiness: true, Err: errWarmup},
+		},
+	}, ch.Health(ctx, ReadinessHealth), "Readiness should fail on failed readiness check")
+
+	assert.Len(t, ch.RunHealthChecks(ctx), 2, "RunHealthChecks should run all checks")
+	assert.Equal(t, ch.Health(ctx, LivenessHealth), ch.GetSubChannel("svc").Health(ctx, LivenessHealth),
+		"SubChannel should return the channel's health")
+
+	ch.Close()
+	status := ch.Health(ctx, LivenessHealth)
+	assert.False(t, status.Ok, "Closed channel should be unhealthy")
+	assert.Empty(t, status.Checks, "Closed channel should not run checks")
+}
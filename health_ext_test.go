@@ -206,6 +206,74 @@ func TestHealthCheckIntegration(t *testing.T) {
 	}
 }
 
+func TestHealthCheckEjectsPeer(t *testing.T) {
+	pingResponses := []bool{true, false, false, true}
+	wantHealthy := []bool{true, true, false, true}
+
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		var pingCount int
+		errFrame := getErrorFrame(t)
+		frameRelay, cancel := testutils.FrameRelay(t, ts.HostPort(), func(outgoing bool, f *Frame) *Frame {
+			if strings.Contains(f.Header.String(), "PingRes") {
+				success := pingResponses[pingCount]
+				pingCount++
+				if !success {
+					errFrame.Header.ID = f.Header.ID
+					f = errFrame
+				}
+			}
+			return f
+		})
+		defer cancel()
+
+		ft := testutils.NewFakeTicker()
+		opts := testutils.NewOpts().
+			SetTimeTicker(ft.New).
+			SetHealthChecks(HealthCheckOptions{Interval: time.Second, FailuresToEject: 2}).
+			AddLogFilter("Failed active health check.", 2).
+			AddLogFilter("Ejecting peer after failed active health checks.", 1).
+			AddLogFilter("Unexpected ping response.", 2)
+		client := ts.NewClient(opts)
+
+		ejected := make(chan Event, len(pingResponses))
+		client.Subscribe(EventPeerEjected, func(e Event) { ejected <- e })
+
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		peers := client.GetSubChannel(ts.ServiceName()).Peers()
+		peers.Add(frameRelay)
+		conn, err := client.RootPeers().GetOrAdd(frameRelay).GetConnection(ctx)
+		require.NoError(t, err, "Failed to get connection")
+
+		for i := range pingResponses {
+			ft.TryTick()
+			waitForNHealthChecks(t, conn, i+1)
+
+			_, err := peers.GetN(1)
+			if wantHealthy[i] {
+				assert.NoError(t, err, "Peer should be selectable after health check %v", i+1)
+			} else {
+				assert.Equal(t, ErrNoHealthyPeers, err, "Peer should be ejected after health check %v", i+1)
+			}
+		}
+		assert.True(t, conn.IsActive(), "Ejection should not close the connection")
+
+		// Both the peer the connection was made to, and the remote's peer
+		// are ejected.
+		require.Len(t, ejected, 2, "Unexpected number of peer ejected events")
+		var ejectedHostPorts []string
+		for i := 0; i < 2; i++ {
+			e := <-ejected
+			ejectedHostPorts = append(ejectedHostPorts, e.HostPort)
+			assert.Equal(t, conn, e.Connection, "Unexpected connection")
+			assert.False(t, e.EjectedUntil.IsZero(), "Expected ejection time")
+		}
+		assert.Equal(t, []string{frameRelay, ts.HostPort()}, ejectedHostPorts, "Unexpected ejected peers")
+	})
+}
+
 func waitForNHealthChecks(t *testing.T, conn *Connection, n int) {
 	require.True(t, testutils.WaitFor(time.Second, func() bool {
 		return len(introspectConn(conn).HealthChecks) >= n
@@ -57,6 +57,14 @@ func TestHealthCheckOptionsDefaults(t *testing.T) {
 			opts: HealthCheckOptions{Timeout: 2 * time.Second, FailuresToClose: 3},
 			want: HealthCheckOptions{Timeout: 2 * time.Second, FailuresToClose: 3},
 		},
+		{
+			opts: HealthCheckOptions{FailuresToEject: 2},
+			want: HealthCheckOptions{Timeout: _defaultHealthCheckTimeout, FailuresToClose: _defaultHealthCheckFailuresToClose, FailuresToEject: 2, EjectDuration: _defaultHealthCheckEjectDuration},
+		},
+		{
+			opts: HealthCheckOptions{FailuresToEject: 2, EjectDuration: time.Minute},
+			want: HealthCheckOptions{Timeout: _defaultHealthCheckTimeout, FailuresToClose: _defaultHealthCheckFailuresToClose, FailuresToEject: 2, EjectDuration: time.Minute},
+		},
 	}
 
 	for _, tt := range tests {
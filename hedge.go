@@ -0,0 +1,132 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// hedgedPeers is the set of peers selected by the attempts of a hedged
+// request, shared by the attempts so that the hedged attempt is made to a
+// different peer.
+type hedgedPeers struct {
+	sync.Mutex
+	hostPorts []string
+}
+
+func (p *hedgedPeers) add(hostPort string) {
+	p.Lock()
+	p.hostPorts = append(p.hostPorts, hostPort)
+	p.Unlock()
+}
+
+// addTo adds the selected peers to the given request state.
+func (p *hedgedPeers) addTo(rs *RequestState) {
+	p.Lock()
+	hostPorts := append([]string(nil), p.hostPorts...)
+	p.Unlock()
+
+	for _, hostPort := range hostPorts {
+		rs.AddSelectedPeer(hostPort)
+	}
+}
+
+// runAttempt runs a single attempt of f, hedging it if hedgeDelay is set.
+func (ch *Channel) runAttempt(ctx context.Context, rs *RequestState, hedgeDelay time.Duration, budget *retryBudget, f RetriableFunc) error {
+	if hedgeDelay <= 0 {
+		return f(ctx, rs)
+	}
+	return ch.runHedged(ctx, rs, hedgeDelay, budget, f)
+}
+
+// runHedged runs a single attempt of f. If it hasn't completed after delay,
+// and the retry budget permits it, a second attempt is started using a
+// different peer. The first attempt to succeed is used, and the other attempt
+// is cancelled. runHedged waits for both attempts to return, so f does not
+// run after RunWithRetry returns.
+func (ch *Channel) runHedged(ctx context.Context, rs *RequestState, delay time.Duration, budget *retryBudget, f RetriableFunc) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	peers := &hedgedPeers{}
+	rs.hedgedPeers = peers
+	defer func() { rs.hedgedPeers = nil }()
+
+	results := make(chan error, 2)
+	go func() {
+		results <- f(ctx, rs)
+	}()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case err := <-results:
+		return err
+	case <-timer.C:
+	}
+
+	if !budget.tryRetry() {
+		return <-results
+	}
+
+	ch.statsReporter.IncCounter("outbound.calls.hedged", ch.commonStatsTags, 1)
+	hedgeRS := &RequestState{
+		Start:     rs.Start,
+		Attempt:   rs.Attempt,
+		retryOpts: rs.retryOpts,
+	}
+	peers.addTo(hedgeRS)
+	hedgeRS.hedgedPeers = peers
+	go func() {
+		results <- f(ctx, hedgeRS)
+	}()
+
+	// Use the first successful attempt, or the last error if both fail.
+	err := <-results
+	if err == nil {
+		cancel()
+	}
+	if otherErr := <-results; err != nil {
+		err = otherErr
+	}
+
+	// Later attempts should avoid the peers used by both attempts.
+	rs.hedgedPeers = nil
+	peers.addTo(rs)
+	return err
+}
+
+// HedgeDelay returns a SubChannelOption that hedges calls made using the
+// subchannel's RunWithRetry: if an attempt hasn't completed after delay, a
+// second attempt is started on a different peer, and whichever succeeds first
+// is used while the other is cancelled. Hedged attempts count as retries
+// against the retry budget.
+func HedgeDelay(delay time.Duration) SubChannelOption {
+	return func(s *SubChannel) {
+		s.Lock()
+		s.hedgeDelay = delay
+		s.Unlock()
+	}
+}
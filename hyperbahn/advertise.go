@@ -39,6 +39,11 @@ const (
 	// advertiseRetryInterval is the unfuzzed base duration to wait before retry on the first
 	// advertise failure. Successive retries will use 2 * previous base duration.
 	advertiseRetryInterval = 1 * time.Second
+	// maxAdvertiseRetryInterval is the default cap on the unfuzzed retry duration.
+	maxAdvertiseRetryInterval = advertiseRetryInterval * (1 << (maxAdvertiseFailures - 1))
+	// healthPollInterval is the default time between health checks while
+	// advertisements are paused.
+	healthPollInterval = 5 * time.Second
 )
 
 // ErrAdvertiseFailed is triggered when advertise fails.
@@ -47,9 +52,15 @@ type ErrAdvertiseFailed struct {
 	WillRetry bool
 	// Cause is the underlying error returned from the advertise call.
 	Cause error
+	// Cluster is the name of the cluster that the advertisement failed for.
+	// It's empty for the cluster configured using Configuration.InitialNodes.
+	Cluster string
 }
 
 func (e ErrAdvertiseFailed) Error() string {
+	if e.Cluster != "" {
+		return fmt.Sprintf("advertise to cluster %v failed, retry: %v, cause: %v", e.Cluster, e.WillRetry, e.Cause)
+	}
 	return fmt.Sprintf("advertise failed, retry: %v, cause: %v", e.WillRetry, e.Cause)
 }
 
@@ -64,6 +75,51 @@ func (c *Client) fuzzedAdvertiseInterval() time.Duration {
 	return advertiseInterval + fuzzInterval(advertiseFuzzInterval)
 }
 
+// fuzzedRetryInterval returns the time to sleep before retrying after the
+// given number of consecutive failures, using exponential backoff with jitter.
+func (c *Client) fuzzedRetryInterval(failures uint) time.Duration {
+	interval := c.opts.RetryInterval
+	for i := uint(0); i < failures && interval < c.opts.MaxRetryInterval; i++ {
+		interval *= 2
+	}
+	if interval > c.opts.MaxRetryInterval {
+		interval = c.opts.MaxRetryInterval
+	}
+	return fuzzInterval(interval)
+}
+
+// healthy returns whether the channel's readiness health checks pass.
+func (c *Client) healthy() bool {
+	ctx, cancel := tchannel.NewContext(c.opts.Timeout)
+	defer cancel()
+	return c.tchan.Health(ctx, tchannel.ReadinessHealth).Ok
+}
+
+// waitHealthy pauses advertisements while the channel is unhealthy, such as
+// when readiness health checks fail, or the channel is draining. It returns
+// false if the client is closed while advertisements are paused.
+func (c *Client) waitHealthy() bool {
+	if c.healthy() {
+		return true
+	}
+
+	c.tchan.Logger().Info("Channel is unhealthy, pausing Hyperbahn advertisements.")
+	c.opts.Handler.On(AdvertisePaused)
+	for {
+		c.sleep(c.opts.HealthPollInterval)
+		if c.IsClosed() {
+			return false
+		}
+		if c.healthy() {
+			break
+		}
+	}
+
+	c.tchan.Logger().Info("Channel is healthy, resuming Hyperbahn advertisements.")
+	c.opts.Handler.On(AdvertiseResumed)
+	return true
+}
+
 // logFailedRegistrationRetry logs either a warning or info depending on the number of
 // consecutiveFailures. If consecutiveFailures > maxAdvertiseFailures, then we log a warning.
 func (c *Client) logFailedRegistrationRetry(errLogger tchannel.Logger, consecutiveFailures uint) {
@@ -75,8 +131,10 @@ func (c *Client) logFailedRegistrationRetry(errLogger tchannel.Logger, consecuti
 	logFn("Hyperbahn client registration failed, will retry.")
 }
 
-// advertiseLoop readvertises the service approximately every minute (with some fuzzing).
-func (c *Client) advertiseLoop() {
+// advertiseLoop readvertises the service to the cluster approximately every
+// minute (with some fuzzing). Advertisements are paused while the channel is
+// unhealthy, and sent as soon as it's healthy again.
+func (c *Client) advertiseLoop(cl *cluster) {
 	sleepFor := c.fuzzedAdvertiseInterval()
 	consecutiveFailures := uint(0)
 
@@ -86,22 +144,25 @@ func (c *Client) advertiseLoop() {
 			c.tchan.Logger().Infof("Hyperbahn client closed")
 			return
 		}
+		if !c.waitHealthy() {
+			c.tchan.Logger().Infof("Hyperbahn client closed")
+			return
+		}
 
-		if err := c.sendAdvertise(); err != nil {
+		if err := c.sendAdvertise(cl); err != nil {
 			consecutiveFailures++
 			errLogger := c.tchan.Logger().WithFields(tchannel.ErrField(err))
+			if cl.name != "" {
+				errLogger = errLogger.WithFields(tchannel.LogField{Key: "cluster", Value: cl.name})
+			}
 			if consecutiveFailures >= maxAdvertiseFailures && c.opts.FailStrategy == FailStrategyFatal {
-				c.opts.Handler.OnError(ErrAdvertiseFailed{Cause: err, WillRetry: false})
+				c.opts.Handler.OnError(ErrAdvertiseFailed{Cause: err, WillRetry: false, Cluster: cl.name})
 				errLogger.Fatal("Hyperbahn client registration failed.")
 			}
 
 			c.logFailedRegistrationRetry(errLogger, consecutiveFailures)
-			c.opts.Handler.OnError(ErrAdvertiseFailed{Cause: err, WillRetry: true})
-
-			// Even after many failures, cap backoff.
-			if consecutiveFailures < maxAdvertiseFailures {
-				sleepFor = fuzzInterval(advertiseRetryInterval * time.Duration(1<<consecutiveFailures))
-			}
+			c.opts.Handler.OnError(ErrAdvertiseFailed{Cause: err, WillRetry: true, Cluster: cl.name})
+			sleepFor = c.fuzzedRetryInterval(consecutiveFailures)
 		} else {
 			c.opts.Handler.On(Readvertised)
 			sleepFor = c.fuzzedAdvertiseInterval()
@@ -110,13 +171,13 @@ func (c *Client) advertiseLoop() {
 	}
 }
 
-// initialAdvertise will do the initial Advertise call to Hyperbahn with additional
+// initialAdvertise will do the initial Advertise call to the cluster with additional
 // retries on top of the built-in TChannel retries. It will use exponential backoff
 // between each of the call attempts.
-func (c *Client) initialAdvertise() error {
+func (c *Client) initialAdvertise(cl *cluster) error {
 	var err error
 	for attempt := uint(0); attempt < maxAdvertiseFailures; attempt++ {
-		err = c.sendAdvertise()
+		err = c.sendAdvertise(cl)
 		if err == nil || err == errEphemeralPeer {
 			break
 		}
@@ -125,8 +186,7 @@ func (c *Client) initialAdvertise() error {
 			"Hyperbahn client initial registration failure, will retry")
 
 		// Back off for a while.
-		sleepFor := fuzzInterval(advertiseRetryInterval * time.Duration(1<<attempt))
-		c.sleep(sleepFor)
+		c.sleep(c.fuzzedRetryInterval(attempt))
 	}
 	return err
 }
@@ -26,6 +26,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/uber-go/atomic"
+	"golang.org/x/net/context"
+
 	"github.com/uber/tchannel-go"
 	"github.com/uber/tchannel-go/json"
 	"github.com/uber/tchannel-go/testutils"
@@ -261,7 +264,7 @@ func TestRetryTemporaryFailure(t *testing.T) {
 		checkAdvertiseInterval(t, s1)
 
 		// When registrations fail, it retries after a short connection and triggers OnError.
-		r.mock.On("OnError", ErrAdvertiseFailed{true, advertiseErr}).Return(nil).Times(3)
+		r.mock.On("OnError", ErrAdvertiseFailed{WillRetry: true, Cause: advertiseErr}).Return(nil).Times(3)
 		for i := 0; i < 3; i++ {
 			r.sleepBlock <- struct{}{}
 			r.setAdvertiseFailure()
@@ -298,7 +301,7 @@ func TestRetryFailure(t *testing.T) {
 		// to signal that we're done testing and close the Hyperbahn client (so
 		// that the Advertise call returns).
 		expectationCalled := 0
-		r.mock.On("OnError", ErrAdvertiseFailed{true, advertiseErr}).Return(nil).Times(maxAdvertiseFailures * 2).Run(func(_ mock.Arguments) {
+		r.mock.On("OnError", ErrAdvertiseFailed{WillRetry: true, Cause: advertiseErr}).Return(nil).Times(maxAdvertiseFailures * 2).Run(func(_ mock.Arguments) {
 			expectationCalled++
 			if expectationCalled >= maxAdvertiseFailures*2 {
 				close(doneTesting)
@@ -340,6 +343,131 @@ func TestRetryFailure(t *testing.T) {
 	})
 }
 
+func TestAdvertisePausedWhileUnhealthy(t *testing.T) {
+	runRetryTest(t, func(r *retryTest) {
+		healthy := atomic.NewBool(true)
+		r.ch.RegisterHealthCheck("ready", func(ctx context.Context) error {
+			if !healthy.Load() {
+				return errors.New("not ready")
+			}
+			return nil
+		}, tchannel.ReadinessHealthCheck)
+
+		r.mock.On("On", SendAdvertise).Return().Times(2)
+		r.mock.On("On", Advertised).Return().Once()
+		r.setAdvertiseSuccess()
+		require.NoError(t, r.client.Advertise())
+		<-r.reqCh
+
+		healthy.Store(false)
+		r.mock.On("On", AdvertisePaused).Return().Once()
+		checkAdvertiseInterval(t, <-r.sleepArgs)
+		r.sleepBlock <- struct{}{}
+
+		// While unhealthy, the client polls health instead of advertising.
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, healthPollInterval, <-r.sleepArgs, "Unexpected health poll interval")
+			r.sleepBlock <- struct{}{}
+		}
+
+		// Once healthy, the client re-advertises immediately.
+		assert.Equal(t, healthPollInterval, <-r.sleepArgs, "Unexpected health poll interval")
+		healthy.Store(true)
+		r.mock.On("On", AdvertiseResumed).Return().Once()
+		r.mock.On("On", Readvertised).Return().Once()
+		r.setAdvertiseSuccess()
+		r.sleepBlock <- struct{}{}
+		<-r.reqCh
+
+		checkAdvertiseInterval(t, <-r.sleepArgs)
+	})
+}
+
+func TestAdvertiseMultipleClusters(t *testing.T) {
+	withSetup(t, func(hypCh *tchannel.Channel, hostPort string) {
+		withSetup(t, func(westCh *tchannel.Channel, westHostPort string) {
+			var ads, westAds atomic.Int32
+			json.Register(hypCh, json.Handlers{"ad": func(ctx json.Context, req *AdRequest) (*AdResponse, error) {
+				ads.Inc()
+				return &AdResponse{1}, nil
+			}}, nil)
+			json.Register(westCh, json.Handlers{"ad": func(ctx json.Context, req *AdRequest) (*AdResponse, error) {
+				westAds.Inc()
+				return &AdResponse{1}, nil
+			}}, nil)
+
+			// Block re-advertisements until the test ends.
+			done := make(chan struct{})
+			defer close(done)
+			opts := &ClientOptions{TimeSleep: func(time.Duration) { <-done }}
+
+			serverCh := testutils.NewServer(t, nil)
+			defer serverCh.Close()
+
+			// The first node of the west cluster isn't listening, so the client
+			// may need to advertise to the other node.
+			config := Configuration{
+				InitialNodes: []string{hostPort},
+				Clusters: []ClusterConfiguration{{
+					Name:         "west",
+					InitialNodes: []string{testutils.GetClosedHostPort(t), westHostPort},
+				}},
+			}
+			client, err := NewClient(serverCh, config, opts)
+			require.NoError(t, err, "NewClient")
+			defer client.Close()
+
+			require.NoError(t, client.Advertise(), "Advertise failed")
+			assert.Equal(t, int32(1), ads.Load(), "Expected advertisement to the default cluster")
+			assert.Equal(t, int32(1), westAds.Load(), "Expected advertisement to the west cluster")
+			assert.Equal(t, []string{hostPort}, getPeers(serverCh), "Cluster nodes should not be added as peers")
+		})
+	})
+}
+
+func TestAdvertiseClusterFailure(t *testing.T) {
+	withSetup(t, func(hypCh *tchannel.Channel, hostPort string) {
+		json.Register(hypCh, json.Handlers{"ad": func(ctx json.Context, req *AdRequest) (*AdResponse, error) {
+			return &AdResponse{1}, nil
+		}}, nil)
+
+		serverCh := testutils.NewServer(t, nil)
+		defer serverCh.Close()
+
+		config := Configuration{
+			InitialNodes: []string{hostPort},
+			Clusters: []ClusterConfiguration{{
+				Name:         "west",
+				InitialNodes: []string{testutils.GetClosedHostPort(t)},
+			}},
+		}
+		client, err := NewClient(serverCh, config, stubbedSleep())
+		require.NoError(t, err, "NewClient")
+		defer client.Close()
+
+		err = client.Advertise()
+		require.Error(t, err, "Advertise should fail if a cluster fails")
+		advertiseErr, ok := err.(ErrAdvertiseFailed)
+		require.True(t, ok, "Expected ErrAdvertiseFailed, got %T", err)
+		assert.Equal(t, "west", advertiseErr.Cluster, "Unexpected cluster")
+	})
+}
+
+func TestFuzzedRetryInterval(t *testing.T) {
+	c := &Client{opts: ClientOptions{
+		RetryInterval:    100 * time.Millisecond,
+		MaxRetryInterval: 300 * time.Millisecond,
+	}}
+	maxIntervals := []time.Duration{100, 200, 300, 300, 300}
+	for failures, max := range maxIntervals {
+		for i := 0; i < 10; i++ {
+			got := c.fuzzedRetryInterval(uint(failures))
+			assert.True(t, got < max*time.Millisecond,
+				"%v failures: retry interval %v should be less than %vms", failures, got, max)
+		}
+	}
+}
+
 func checkAdvertiseInterval(t *testing.T, sleptFor time.Duration) {
 	assert.True(t, sleptFor >= advertiseInterval,
 		"advertise interval should be > advertiseInterval")
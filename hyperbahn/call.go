@@ -22,8 +22,10 @@ package hyperbahn
 
 import (
 	"errors"
+	"math/rand"
 
 	"github.com/uber/tchannel-go"
+	tjson "github.com/uber/tchannel-go/json"
 )
 
 var errEphemeralPeer = errors.New("cannot advertise on channel that has not called ListenAndServe")
@@ -57,7 +59,7 @@ func (c *Client) createRequest() *AdRequest {
 	return req
 }
 
-func (c *Client) sendAdvertise() error {
+func (c *Client) sendAdvertise(cl *cluster) error {
 	// Cannot advertise from an ephemeral peer.
 	if c.tchan.PeerInfo().IsEphemeralHostPort() {
 		return errEphemeralPeer
@@ -67,6 +69,11 @@ func (c *Client) sendAdvertise() error {
 		RetryOn:           tchannel.RetryIdempotent,
 		TimeoutPerAttempt: c.opts.TimeoutPerAttempt,
 	}
+	if cl.nodes != nil {
+		// Calls to additional clusters are retried on the cluster's other
+		// nodes by advertiseNodes instead.
+		retryOpts.RetryOn = tchannel.RetryNever
+	}
 
 	ctx, cancel := tchannel.NewContextBuilder(c.opts.Timeout).
 		SetRetryOptions(retryOpts).
@@ -77,5 +84,22 @@ func (c *Client) sendAdvertise() error {
 
 	var resp AdResponse
 	c.opts.Handler.On(SendAdvertise)
+	if cl.nodes != nil {
+		return advertiseNodes(ctx, cl.nodes, c.createRequest(), &resp)
+	}
 	return c.jsonClient.Call(ctx, "ad", c.createRequest(), &resp)
 }
+
+// advertiseNodes sends the advertisement to each of the nodes, starting at a
+// random node, until one succeeds or the context ends.
+func advertiseNodes(ctx tjson.Context, nodes []*tjson.Client, req *AdRequest, resp *AdResponse) error {
+	var err error
+	start := rand.Intn(len(nodes))
+	for i := range nodes {
+		node := nodes[(start+i)%len(nodes)]
+		if err = node.Call(ctx, "ad", req, resp); err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
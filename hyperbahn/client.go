@@ -42,6 +42,16 @@ type Client struct {
 
 	jsonClient      *tjson.Client
 	hyperbahnClient htypes.TChanHyperbahn
+	clusters        []*cluster
+}
+
+// cluster is a Hyperbahn routing cluster that services are advertised to.
+type cluster struct {
+	// name is empty for the cluster configured using Configuration.InitialNodes.
+	name string
+	// nodes are the clients for each node of an additional cluster. The
+	// cluster configured using InitialNodes uses the hyperbahn subchannel.
+	nodes []*tjson.Client
 }
 
 // FailStrategy is the strategy to use when registration fails maxRegistrationFailures
@@ -83,6 +93,19 @@ type ClientOptions struct {
 	Handler           Handler
 	FailStrategy      FailStrategy
 
+	// RetryInterval is the maximum time to wait before retrying the first
+	// failed advertisement, which doubles after each consecutive failure up
+	// to MaxRetryInterval. The actual wait is chosen randomly up to the
+	// maximum. Defaults to 1 second.
+	RetryInterval time.Duration
+	// MaxRetryInterval caps the maximum time to wait between retries of
+	// failed advertisements. Defaults to 16 seconds.
+	MaxRetryInterval time.Duration
+	// HealthPollInterval is how often the channel's readiness health is
+	// checked while advertisements are paused because it's unhealthy.
+	// Defaults to 5 seconds.
+	HealthPollInterval time.Duration
+
 	// The following are variables for stubbing in unit tests.
 	// They are not part of the stable API and may change.
 	TimeSleep func(d time.Duration)
@@ -108,14 +131,34 @@ func NewClient(ch *tchannel.Channel, config Configuration, opts *ClientOptions)
 	if client.opts.TimeSleep == nil {
 		client.opts.TimeSleep = time.Sleep
 	}
+	if client.opts.RetryInterval == 0 {
+		client.opts.RetryInterval = advertiseRetryInterval
+	}
+	if client.opts.MaxRetryInterval == 0 {
+		client.opts.MaxRetryInterval = maxAdvertiseRetryInterval
+	}
+	if client.opts.HealthPollInterval == 0 {
+		client.opts.HealthPollInterval = healthPollInterval
+	}
 
 	if err := parseConfig(&config); err != nil {
 		return nil, err
 	}
 
 	// Add the given initial nodes as peers.
-	for _, node := range config.InitialNodes {
-		addPeer(ch, node)
+	if len(config.InitialNodes) > 0 {
+		for _, node := range config.InitialNodes {
+			addPeer(ch, node)
+		}
+		client.clusters = append(client.clusters, &cluster{})
+	}
+
+	for _, clusterConfig := range config.Clusters {
+		cl := &cluster{name: clusterConfig.Name}
+		for _, node := range clusterConfig.InitialNodes {
+			cl.nodes = append(cl.nodes, tjson.NewClient(ch, hyperbahnServiceName, &tjson.ClientOptions{HostPort: node}))
+		}
+		client.clusters = append(client.clusters, cl)
 	}
 
 	client.jsonClient = tjson.NewClient(ch, hyperbahnServiceName, nil)
@@ -127,30 +170,65 @@ func NewClient(ch *tchannel.Channel, config Configuration, opts *ClientOptions)
 
 // parseConfig parses the configuration options (e.g. InitialNodesFile)
 func parseConfig(config *Configuration) error {
-	if config.InitialNodesFile != "" {
-		f, err := os.Open(config.InitialNodesFile)
+	nodes, err := parseNodes(config.InitialNodes, config.InitialNodesFile)
+	if err != nil {
+		return err
+	}
+	config.InitialNodes = nodes
+
+	if len(config.InitialNodes) == 0 && len(config.Clusters) == 0 {
+		return fmt.Errorf("hyperbahn Client requires at least one initial node")
+	}
+
+	// Copy the clusters so parsing doesn't modify the caller's configuration.
+	config.Clusters = append([]ClusterConfiguration(nil), config.Clusters...)
+	names := make(map[string]struct{}, len(config.Clusters))
+	for i := range config.Clusters {
+		cluster := &config.Clusters[i]
+		if cluster.Name == "" {
+			return fmt.Errorf("hyperbahn Client requires a name for each cluster")
+		}
+		if _, ok := names[cluster.Name]; ok {
+			return fmt.Errorf("hyperbahn Client got duplicate cluster %v", cluster.Name)
+		}
+		names[cluster.Name] = struct{}{}
+
+		nodes, err := parseNodes(cluster.InitialNodes, cluster.InitialNodesFile)
 		if err != nil {
-			return err
+			return fmt.Errorf("hyperbahn Client cluster %v: %v", cluster.Name, err)
+		}
+		if len(nodes) == 0 {
+			return fmt.Errorf("hyperbahn Client requires at least one initial node for cluster %v", cluster.Name)
+		}
+		cluster.InitialNodes = nodes
+	}
+
+	return nil
+}
+
+// parseNodes returns the nodes from nodesFile if it's set, or nodes otherwise,
+// and validates that each node is a host:port.
+func parseNodes(nodes []string, nodesFile string) ([]string, error) {
+	if nodesFile != "" {
+		f, err := os.Open(nodesFile)
+		if err != nil {
+			return nil, err
 		}
 		defer f.Close()
 
 		decoder := json.NewDecoder(f)
-		if err := decoder.Decode(&config.InitialNodes); err != nil {
-			return err
+		if err := decoder.Decode(&nodes); err != nil {
+			return nil, err
 		}
 	}
 
-	if len(config.InitialNodes) == 0 {
-		return fmt.Errorf("hyperbahn Client requires at least one initial node")
-	}
-
-	for _, node := range config.InitialNodes {
+	for _, node := range nodes {
 		if _, _, err := net.SplitHostPort(node); err != nil {
-			return fmt.Errorf("hyperbahn Client got invalid node %v: %v", node, err)
+			return nil, fmt.Errorf("hyperbahn Client got invalid node %v: %v", node, err)
 		}
 	}
 
-	return nil
+	return nodes, nil
 }
 
 // addPeer adds a peer to the Hyperbahn subchannel.
@@ -171,16 +249,25 @@ func (c *Client) getServiceNames(otherServices []tchannel.Registrar) {
 
 // Advertise advertises the service with Hyperbahn, and returns any errors on initial advertisement.
 // Advertise can register multiple services hosted on the same endpoint.
-// If the advertisement succeeds, a goroutine is started to re-advertise periodically.
+// If there are multiple clusters, the service is advertised to each of them, and
+// fails if the initial advertisement to any of them fails.
+// If the advertisement succeeds, a goroutine is started per cluster to re-advertise periodically.
 func (c *Client) Advertise(otherServices ...tchannel.Registrar) error {
 	c.getServiceNames(otherServices)
 
-	if err := c.initialAdvertise(); err != nil {
-		return err
+	for _, cl := range c.clusters {
+		if err := c.initialAdvertise(cl); err != nil {
+			if cl.name != "" {
+				err = ErrAdvertiseFailed{Cluster: cl.name, Cause: err}
+			}
+			return err
+		}
 	}
 
 	c.opts.Handler.On(Advertised)
-	go c.advertiseLoop()
+	for _, cl := range c.clusters {
+		go c.advertiseLoop(cl)
+	}
 	return nil
 }
 
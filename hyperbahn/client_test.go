@@ -123,6 +123,59 @@ func TestParseConfiguration(t *testing.T) {
 	}
 }
 
+func TestParseClusterConfiguration(t *testing.T) {
+	peers := []string{"1.1.1.1:1"}
+	tests := []struct {
+		name     string
+		clusters []ClusterConfiguration
+		wantErr  string
+	}{
+		{
+			name:     "valid cluster",
+			clusters: []ClusterConfiguration{{Name: "west", InitialNodes: peers}},
+		},
+		{
+			name:     "missing name",
+			clusters: []ClusterConfiguration{{InitialNodes: peers}},
+			wantErr:  "requires a name",
+		},
+		{
+			name: "duplicate name",
+			clusters: []ClusterConfiguration{
+				{Name: "west", InitialNodes: peers},
+				{Name: "west", InitialNodes: peers},
+			},
+			wantErr: "duplicate cluster west",
+		},
+		{
+			name:     "no nodes",
+			clusters: []ClusterConfiguration{{Name: "west"}},
+			wantErr:  "at least one initial node for cluster west",
+		},
+		{
+			name:     "invalid node",
+			clusters: []ClusterConfiguration{{Name: "west", InitialNodes: []string{"2.2.2.2"}}},
+			wantErr:  "cluster west",
+		},
+	}
+
+	for _, tt := range tests {
+		ch := testutils.NewClient(t, nil)
+		defer ch.Close()
+
+		config := Configuration{InitialNodes: peers, Clusters: tt.clusters}
+		_, err := NewClient(ch, config, nil)
+		if tt.wantErr != "" {
+			if assert.Error(t, err, "%v: NewClient expected to fail", tt.name) {
+				assert.Contains(t, err.Error(), tt.wantErr, "%v: unexpected error", tt.name)
+			}
+			continue
+		}
+		assert.NoError(t, err, "%v: hyperbahn.NewClient failed", tt.name)
+		assert.Equal(t, peers, getPeers(ch), "%v: cluster nodes should not be added as peers", tt.name)
+	}
+}
+
 func TestUnmarshalFailStrategyFormats(t *testing.T) {
 	type appConfig struct {
 		Name string       `json:"name" yaml:"name"`
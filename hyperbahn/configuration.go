@@ -27,4 +27,20 @@ type Configuration struct {
 	// InitialNodesFile is a JSON file that contains the list of known Hyperbahn nodes.
 	// If this option is set, it overrides InitialNodes.
 	InitialNodesFile string
+	// Clusters are additional Hyperbahn routing clusters, such as the clusters
+	// in other regions, that services are advertised to at the same time.
+	// InitialNodes may be empty if Clusters is set.
+	Clusters []ClusterConfiguration
+}
+
+// ClusterConfiguration is the configuration for an additional Hyperbahn
+// routing cluster.
+type ClusterConfiguration struct {
+	// Name identifies the cluster in errors.
+	Name string
+	// InitialNodes is the list of known Hyperbahn nodes in the cluster.
+	InitialNodes []string
+	// InitialNodesFile is a JSON file that contains the list of known Hyperbahn
+	// nodes in the cluster. If this option is set, it overrides InitialNodes.
+	InitialNodesFile string
 }
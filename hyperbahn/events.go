@@ -32,6 +32,12 @@ const (
 	Advertised
 	// Readvertised is triggered on periodic advertisements.
 	Readvertised
+	// AdvertisePaused is triggered when periodic advertisements are paused
+	// because the channel is unhealthy.
+	AdvertisePaused
+	// AdvertiseResumed is triggered when periodic advertisements resume after
+	// the channel is healthy again.
+	AdvertiseResumed
 )
 
 //go:generate stringer -type=Event
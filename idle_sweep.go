@@ -118,6 +118,7 @@ func (is *idleSweep) checkIdleConnections() {
 			LogField{"remotePeer", conn.remotePeerInfo},
 			LogField{"lastActivityTime", conn.getLastActivityTime()},
 		).Info("Closing idle inbound connection.")
+		is.ch.statsReporter.IncCounter("connections.idle-closed", is.ch.commonStatsTags, 1)
 		conn.close(LogField{"reason", "Idle connection closed"})
 	}
 }
@@ -106,12 +106,14 @@ func TestServerBasedSweep(t *testing.T) {
 	serverTicker := testutils.NewFakeTicker()
 	clock := testutils.NewStubClock(time.Now())
 
+	serverStats := newRecordingStatsReporter()
 	serverOpts := testutils.NewOpts().
 		SetTimeTicker(serverTicker.New).
 		SetIdleCheckInterval(30 * time.Second).
 		SetMaxIdleTime(3 * time.Minute).
 		SetOnPeerStatusChanged(listener.onStatusChange).
 		SetTimeNow(clock.Now).
+		SetStatsReporter(serverStats).
 		NoRelay()
 
 	clientOpts := testutils.NewOpts().
@@ -132,11 +134,15 @@ func TestServerBasedSweep(t *testing.T) {
 			assert.Equal(t, 1, numConnections(ts.Server()))
 			assert.Equal(t, 1, numConnections(client))
 		}
+		assert.Zero(t, serverStats.getCount("connections.idle-closed", ts.Server().StatsTags()),
+			"No connections should be closed before they're idle")
 
 		// Move the clock forward and trigger the idle poller.
 		clock.Elapse(90 * time.Second)
 		serverTicker.Tick()
 		listener.waitForZeroConnections(t, ts.Server(), client)
+		assert.EqualValues(t, 1, serverStats.getCount("connections.idle-closed", ts.Server().StatsTags()),
+			"Unexpected number of idle connections closed")
 	})
 }
 
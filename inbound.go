@@ -47,6 +47,21 @@ func (c *Connection) handleCallReq(frame *Frame) bool {
 		panic(fmt.Errorf("unknown connection state for call req: %v", state))
 	}
 
+	if c.memPressure.shouldShed() {
+		c.statsReporter.IncCounter("inbound.calls.shed", c.commonStatsTags, 1)
+		c.SendSystemError(frame.Header.ID, callReqSpan(frame), ErrServerBusy)
+		return true
+	}
+
+	var dropHeaders []string
+	if c.headerLimits != nil {
+		var err error
+		if dropHeaders, err = c.checkHeaderLimits(frame); err != nil {
+			c.SendSystemError(frame.Header.ID, callReqSpan(frame), err)
+			return true
+		}
+	}
+
 	callReq := new(callReq)
 	callReq.id = frame.Header.ID
 	initialFragment, err := parseInboundFragment(c.opts.FramePool, frame, callReq)
@@ -58,10 +73,30 @@ func (c *Connection) handleCallReq(frame *Frame) bool {
 		).Error("Couldn't decode initial fragment.")
 		return true
 	}
+	for _, k := range dropHeaders {
+		delete(callReq.Headers, TransportHeaderName(k))
+	}
+
+	if c.callerDrain.isDrained(callReq.Headers[CallerName]) {
+		c.statsReporter.IncCounter("inbound.calls.drained", c.commonStatsTags, 1)
+		c.SendSystemError(frame.Header.ID, callReqSpan(frame), errCallerDrained)
+		return true
+	}
+
+	if c.opts.inboundExpired(callReq.TimeToLive) {
+		c.statsReporter.IncCounter("inbound.calls.expired", c.commonStatsTags, 1)
+		c.SendSystemError(frame.Header.ID, callReqSpan(frame), ErrTimeout)
+		return true
+	}
 
 	call := new(InboundCall)
 	call.conn = c
-	ctx, cancel := newIncomingContext(call, callReq.TimeToLive)
+	timeout := callReq.TimeToLive
+	if max := c.maxHandlerDuration; max > 0 && timeout > max {
+		timeout = max
+	}
+	ctx, cancel := newIncomingContext(call, timeout)
+	ctx = withInboundBaggage(ctx, callReq.Headers)
 
 	if !c.pendingExchangeMethodAdd() {
 		// Connection is closed, no need to do anything.
@@ -85,6 +120,12 @@ func (c *Connection) handleCallReq(frame *Frame) bool {
 		return true
 	}
 
+	// The read loop can't wait for a single call, so the initial frame is
+	// counted without waiting, and the call's next frame waits for it instead.
+	if mex.bandwidth = c.bandwidth.newCall(); mex.bandwidth != nil {
+		mex.bandwidth.receive.reserve(frame.Header.frameSize())
+	}
+
 	response := new(InboundCallResponse)
 	response.call = call
 	response.calledAt = now
@@ -98,6 +139,8 @@ func (c *Connection) handleCallReq(frame *Frame) bool {
 	response.cancel = cancel
 	response.log = c.log.WithFields(LogField{"In-Response", callReq.ID()})
 	response.contents = newFragmentingWriter(response.log, response, initialFragment.checksumType.New())
+	response.compression = c.argCompression
+	response.priority = c.callPriority(callReq.Headers)
 	response.headers = transportHeaders{}
 	response.messageForFragment = func(initial bool) message {
 		if initial {
@@ -121,6 +164,7 @@ func (c *Connection) handleCallReq(frame *Frame) bool {
 	call.log = c.log.WithFields(LogField{"In-Call", callReq.ID()})
 	call.messageForFragment = func(initial bool) message { return new(callReqContinue) }
 	call.contents = newFragmentingReader(call.log, call)
+	call.compression = c.argCompression
 	call.statsReporter = c.statsReporter
 	call.createStatsTags(c.commonStatsTags)
 
@@ -128,7 +172,18 @@ func (c *Connection) handleCallReq(frame *Frame) bool {
 	response.commonStatsTags = call.commonStatsTags
 
 	setResponseHeaders(call.headers, response.headers)
-	go c.dispatchInbound(c.connID, callReq.ID(), call, frame)
+	if c.dispatchPool == nil {
+		go c.dispatchInbound(c.connID, callReq.ID(), call, frame)
+		return false
+	}
+
+	if !c.dispatchPool.submit(func() { c.dispatchInbound(c.connID, callReq.ID(), call, frame) }) {
+		c.statsReporter.IncCounter("inbound.calls.dispatch-rejected", c.commonStatsTags, 1)
+		mex.shutdown()
+		cancel()
+		c.SendSystemError(frame.Header.ID, callReqSpan(frame), ErrServerBusy)
+		return true
+	}
 	return false
 }
 
@@ -137,7 +192,11 @@ func (c *Connection) handleCallReq(frame *Frame) bool {
 // defragmentation
 func (c *Connection) handleCallReqContinue(frame *Frame) bool {
 	if err := c.inbound.forwardPeerFrame(frame); err != nil {
-		// If forward fails, it's due to a timeout. We can free this frame.
+		if err == errTooManyFragments {
+			c.tooManyFragments(frame)
+		}
+		// If forward fails, it's due to a timeout or too many fragments.
+		// We can free this frame.
 		return true
 	}
 	return false
@@ -168,10 +227,17 @@ func (c *Connection) dispatchInbound(_ uint32, _ uint32, call *InboundCall, fram
 		return
 	}
 
-	call.commonStatsTags["endpoint"] = call.methodString
+	if c.draining.Load() && call.methodString != healthMethod {
+		call.Response().SendSystemError(errChannelDraining)
+		return
+	}
+
+	endpoint := c.subChannels.endpointName(call.ServiceName(), call.methodString)
+	call.commonStatsTags["endpoint"] = endpoint
 	call.statsReporter.IncCounter("inbound.calls.recvd", call.commonStatsTags, 1)
+	call.response.completedEvent = c.publishCallStarted(true /* inbound */, call.ServiceName(), call.methodString, call.response.calledAt)
 	if span := call.response.span; span != nil {
-		span.SetOperationName(call.methodString)
+		span.SetOperationName(endpoint)
 	}
 
 	// TODO(prashant): This is an expensive way to check for cancellation. Use a heap for timeouts.
@@ -197,6 +263,35 @@ func (c *Connection) dispatchInbound(_ uint32, _ uint32, call *InboundCall, fram
 		}
 	}()
 
+	if c.callAuthorizer != nil {
+		if err := c.authorizeCall(call); err != nil {
+			call.statsReporter.IncCounter("inbound.calls.unauthorized", call.commonStatsTags, 1)
+			call.Response().SendSystemError(err)
+			return
+		}
+	}
+
+	if c.callerRateLimiter != nil && !c.callerRateLimiter.allow(call.CallerName()) {
+		call.statsReporter.IncCounter("inbound.calls.rate-limited", call.commonStatsTags, 1)
+		call.Response().SendSystemError(errCallerRateLimit)
+		return
+	}
+
+	if c.concurrencyLimiter != nil {
+		release, err := c.concurrencyLimiter.acquire(call.mex.ctx, call.ServiceName(), call.methodString, call.CallerName())
+		if err != nil {
+			call.statsReporter.IncCounter("inbound.calls.limited", call.commonStatsTags, 1)
+			call.Response().SendSystemError(err)
+			return
+		}
+		defer release()
+	}
+
+	if c.dedup != nil && call.IdempotencyKey() != "" {
+		c.handleDeduplicated(call)
+		return
+	}
+
 	c.handler.Handle(call.mex.ctx, call)
 }
 
@@ -234,11 +329,21 @@ func (call *InboundCall) Format() Format {
 	return Format(call.headers[ArgScheme])
 }
 
+// ArgScheme returns the format of the arguments from the ArgScheme transport header.
+func (call *InboundCall) ArgScheme() string {
+	return call.headers[ArgScheme]
+}
+
 // CallerName returns the caller name from the CallerName transport header.
 func (call *InboundCall) CallerName() string {
 	return call.headers[CallerName]
 }
 
+// AuthToken returns the token from the AuthToken transport header.
+func (call *InboundCall) AuthToken() string {
+	return call.headers[AuthToken]
+}
+
 // ShardKey returns the shard key from the ShardKey transport header.
 func (call *InboundCall) ShardKey() string {
 	return call.headers[ShardKey]
@@ -254,6 +359,28 @@ func (call *InboundCall) RoutingDelegate() string {
 	return call.headers[RoutingDelegate]
 }
 
+// BestEffort returns whether the call was marked as best-effort using the
+// BestEffort transport header.
+func (call *InboundCall) BestEffort() bool {
+	return call.headers[BestEffort] == "1"
+}
+
+// Priority returns the priority of the call from the Priority transport header,
+// or the normal priority if the connection doesn't support priorities.
+func (call *InboundCall) Priority() CallPriority {
+	return call.conn.callPriority(call.headers)
+}
+
+// TraceParent returns the W3C trace context from the TraceParent transport header.
+func (call *InboundCall) TraceParent() string {
+	return call.headers[TraceParent]
+}
+
+// IdempotencyKey returns the idempotency key from the IdempotencyKey transport header.
+func (call *InboundCall) IdempotencyKey() string {
+	return call.headers[IdempotencyKey]
+}
+
 // LocalPeer returns the local peer information for this call.
 func (call *InboundCall) LocalPeer() LocalPeerInfo {
 	return call.conn.localPeerInfo
@@ -272,6 +399,8 @@ func (call *InboundCall) CallOptions() *CallOptions {
 		ShardKey:        call.ShardKey(),
 		RoutingDelegate: call.RoutingDelegate(),
 		RoutingKey:      call.RoutingKey(),
+		BestEffort:      call.BestEffort(),
+		Priority:        call.Priority(),
 	}
 }
 
@@ -327,8 +456,16 @@ type InboundCallResponse struct {
 	systemError      bool
 	headers          transportHeaders
 	span             opentracing.Span
+	traceSpan        CallTraceSpan
 	statsReporter    StatsReporter
 	commonStatsTags  map[string]string
+
+	// dedup records the response if the call is being deduplicated.
+	dedup *dedupRecorder
+
+	// completedEvent, if set, is published once the call completes. It's
+	// only set if there were subscribers when the call started.
+	completedEvent *Event
 }
 
 // SendSystemError returns a system error response to the peer.  The call is considered
@@ -340,11 +477,24 @@ func (response *InboundCallResponse) SendSystemError(err error) error {
 	// Fail all future attempts to read fragments
 	response.state = reqResWriterComplete
 	response.systemError = true
+	if response.completedEvent != nil {
+		response.completedEvent.Err = err
+	}
 	response.doneSending()
 	response.call.releasePreviousFragment()
 
 	span := CurrentSpan(response.mex.ctx)
 
+	if sanitize := response.conn.errorSanitizer; sanitize != nil {
+		if sanitized := sanitize(err); sanitized != err {
+			response.log.WithFields(
+				ErrField(err),
+				LogField{"sanitizedError", sanitized},
+			).Info("Sending sanitized error to caller.")
+			err = sanitized
+		}
+	}
+
 	return response.conn.SendSystemError(response.mex.msgID, *span, err)
 }
 
@@ -374,13 +524,21 @@ func (response *InboundCallResponse) Arg2Writer() (ArgWriter, error) {
 	if err := NewArgWriter(response.arg1Writer()).Write(nil); err != nil {
 		return nil, err
 	}
-	return response.arg2Writer()
+	w, err := response.arg2Writer()
+	if err == nil && response.dedup != nil {
+		w = recordingArgWriter{w, &response.dedup.arg2}
+	}
+	return w, err
 }
 
 // Arg3Writer returns a WriteCloser that can be used to write the last argument.
 // The returned writer must be closed once the write is complete.
 func (response *InboundCallResponse) Arg3Writer() (ArgWriter, error) {
-	return response.arg3Writer()
+	w, err := response.arg3Writer()
+	if err == nil && response.dedup != nil {
+		w = recordingArgWriter{w, &response.dedup.arg3}
+	}
+	return w, err
 }
 
 // doneSending shuts down the message exchange for this call.
@@ -395,9 +553,12 @@ func (response *InboundCallResponse) doneSending() {
 		}
 		span.FinishWithOptions(opentracing.FinishOptions{FinishTime: now})
 	}
+	if span := response.traceSpan; span != nil {
+		span.End(now, nil, response.applicationError || response.systemError)
+	}
 
 	latency := now.Sub(response.calledAt)
-	response.statsReporter.RecordTimer("inbound.calls.latency", response.commonStatsTags, latency)
+	recordCallTimer(response.statsReporter, "inbound.calls.latency", response.commonStatsTags, latency, response.span)
 
 	if response.systemError {
 		// TODO(prashant): Report the error code type as per metrics doc and enable.
@@ -408,6 +569,17 @@ func (response *InboundCallResponse) doneSending() {
 		response.statsReporter.IncCounter("inbound.calls.success", response.commonStatsTags, 1)
 	}
 
+	if e := response.completedEvent; e != nil {
+		e.Time = now
+		e.Latency = latency
+		e.ApplicationError = response.applicationError
+		response.conn.eventBus.publish(*e)
+	}
+
+	if response.dedup != nil {
+		response.dedup.done(response)
+	}
+
 	// Cancel the context since the response is complete.
 	response.cancel()
 
@@ -21,6 +21,7 @@
 package tchannel_test
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -32,6 +33,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/uber-go/atomic"
 	"golang.org/x/net/context"
 )
 
@@ -143,6 +145,351 @@ func TestInboundConnection_CallOptions(t *testing.T) {
 	})
 }
 
+func TestBestEffortCallsShedFirst(t *testing.T) {
+	// The load-shedding policy sheds best-effort calls once any call is in
+	// progress, but only sheds critical calls once maxInProgress is reached.
+	const maxInProgress = 2
+
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		var inProgress atomic.Int32
+		started := make(chan struct{})
+		unblock := make(chan struct{})
+		ts.RegisterFunc("work", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			limit := int32(maxInProgress)
+			if CurrentCall(ctx).BestEffort() {
+				limit = 1
+			}
+			if inProgress.Inc() > limit {
+				inProgress.Dec()
+				return nil, ErrServerBusy
+			}
+			defer inProgress.Dec()
+
+			if string(args.Arg2) == "block" {
+				close(started)
+				<-unblock
+			}
+			return &raw.Res{}, nil
+		})
+
+		client := ts.NewClient(nil)
+		sc := client.GetSubChannel(ts.ServiceName())
+		sc.Peers().Add(ts.HostPort())
+
+		call := func(bestEffort bool, arg2 string) error {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+
+			_, err := raw.CallV2(ctx, sc, raw.CArgs{
+				Method:      "work",
+				Arg2:        []byte(arg2),
+				CallOptions: &CallOptions{BestEffort: bestEffort},
+			})
+			return err
+		}
+
+		// With no load, best-effort calls succeed.
+		require.NoError(t, call(true /* bestEffort */, ""), "Best-effort call without load failed")
+
+		blockedErr := make(chan error, 1)
+		go func() { blockedErr <- call(false /* bestEffort */, "block") }()
+		<-started
+
+		err := call(true /* bestEffort */, "")
+		assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Best-effort call should be shed under load")
+		assert.NoError(t, call(false /* bestEffort */, ""), "Critical call should not be shed under load")
+
+		close(unblock)
+		assert.NoError(t, <-blockedErr, "Blocked call failed")
+	})
+}
+
+func TestCallPriority(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		ts.RegisterFunc("priority", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			call := CurrentCall(ctx)
+			assert.Equal(t, call.Priority(), call.CallOptions().Priority, "Forwarded priority mismatch")
+			return &raw.Res{Arg3: []byte(call.Priority().String())}, nil
+		})
+
+		client := ts.NewClient(nil)
+		sc := client.GetSubChannel(ts.ServiceName())
+		sc.Peers().Add(ts.HostPort())
+
+		// Relays should propagate the priority to the server, and the server
+		// should see the priority set by the client.
+		for _, priority := range []CallPriority{PriorityNormal, PriorityHigh} {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			res, err := raw.CallV2(ctx, sc, raw.CArgs{
+				Method:      "priority",
+				CallOptions: &CallOptions{Priority: priority},
+			})
+			cancel()
+			require.NoError(t, err, "Call with priority %v failed", priority)
+			assert.Equal(t, priority.String(), string(res.Arg3), "Unexpected priority")
+		}
+	})
+}
+
+func TestCallPriorityRequiresFeature(t *testing.T) {
+	tests := []struct {
+		msg            string
+		clientDisabled []ProtocolFeature
+		serverDisabled []ProtocolFeature
+	}{
+		{msg: "disabled on client", clientDisabled: []ProtocolFeature{FeaturePriority}},
+		{msg: "disabled on server", serverDisabled: []ProtocolFeature{FeaturePriority}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			opts := testutils.NewOpts().NoRelay()
+			opts.DefaultConnectionOptions.DisabledFeatures = tt.serverDisabled
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				ts.RegisterFunc("priority", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+					return &raw.Res{Arg3: []byte(CurrentCall(ctx).Priority().String())}, nil
+				})
+
+				clientOpts := testutils.NewOpts()
+				clientOpts.DefaultConnectionOptions.DisabledFeatures = tt.clientDisabled
+				client := ts.NewClient(clientOpts)
+				sc := client.GetSubChannel(ts.ServiceName())
+				sc.Peers().Add(ts.HostPort())
+
+				ctx, cancel := NewContext(testutils.Timeout(time.Second))
+				defer cancel()
+				res, err := raw.CallV2(ctx, sc, raw.CArgs{
+					Method:      "priority",
+					CallOptions: &CallOptions{Priority: PriorityHigh},
+				})
+				require.NoError(t, err, "Call failed")
+				assert.Equal(t, PriorityNormal.String(), string(res.Arg3), "Priority should not be used unless both peers support it")
+			})
+		})
+	}
+}
+
+func TestIdempotencyKeyDeduplication(t *testing.T) {
+	const window = time.Minute
+
+	clock := testutils.NewStubClock(time.Now())
+	opts := testutils.NewOpts().SetTimeNow(clock.Now)
+	opts.DeduplicationWindow = window
+
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		var handled atomic.Int32
+		started := make(chan struct{})
+		unblock := make(chan struct{})
+		ts.RegisterFunc("mutate", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			n := handled.Inc()
+			switch string(args.Arg2) {
+			case "block":
+				close(started)
+				<-unblock
+			case "busy":
+				return nil, ErrServerBusy
+			}
+			return &raw.Res{
+				IsErr: string(args.Arg2) == "app-error",
+				Arg2:  args.Arg2,
+				Arg3:  []byte(fmt.Sprintf("call %v", n)),
+			}, nil
+		})
+
+		client := ts.NewClient(nil)
+		sc := client.GetSubChannel(ts.ServiceName())
+		sc.Peers().Add(ts.HostPort())
+
+		call := func(key, arg2 string) (*raw.CRes, error) {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+
+			return raw.CallV2(ctx, sc, raw.CArgs{
+				Method:      "mutate",
+				Arg2:        []byte(arg2),
+				CallOptions: &CallOptions{IdempotencyKey: key},
+			})
+		}
+
+		res, err := call("k1", "")
+		require.NoError(t, err, "Call failed")
+		assert.Equal(t, "call 1", string(res.Arg3), "Unexpected result")
+
+		res, err = call("k1", "")
+		require.NoError(t, err, "Retried call failed")
+		assert.Equal(t, "call 1", string(res.Arg3), "Retry should get the cached result")
+		assert.EqualValues(t, 1, handled.Load(), "Handler should not run for the retry")
+
+		res, err = call("k2", "")
+		require.NoError(t, err, "Call with a new key failed")
+		assert.Equal(t, "call 2", string(res.Arg3), "New key should run the handler")
+
+		res, err = call("", "")
+		require.NoError(t, err, "Call without a key failed")
+		assert.Equal(t, "call 3", string(res.Arg3), "Calls without a key are not deduplicated")
+
+		// Application errors are cached.
+		res, err = call("k3", "app-error")
+		require.NoError(t, err, "Call failed")
+		assert.True(t, res.AppError, "Expected application error")
+		res, err = call("k3", "app-error")
+		require.NoError(t, err, "Retried call failed")
+		assert.True(t, res.AppError, "Cached result should be an application error")
+		assert.Equal(t, "app-error", string(res.Arg2), "Unexpected cached arg2")
+		assert.Equal(t, "call 4", string(res.Arg3), "Retry should get the cached result")
+
+		// System errors are not cached, so retries run the handler again.
+		_, err = call("k4", "busy")
+		assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Expected busy error")
+		_, err = call("k4", "busy")
+		assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Expected busy error")
+		assert.EqualValues(t, 6, handled.Load(), "Retries of system errors should run the handler")
+
+		// Duplicates of a call that is still in progress are rejected as busy.
+		blockedRes := make(chan *raw.CRes, 1)
+		go func() {
+			res, err := call("k5", "block")
+			assert.NoError(t, err, "Blocked call failed")
+			blockedRes <- res
+		}()
+		<-started
+		_, err = call("k5", "block")
+		assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Duplicate of in-progress call should be busy")
+		close(unblock)
+		assert.Equal(t, "call 7", string((<-blockedRes).Arg3), "Unexpected result for blocked call")
+
+		// Once the window has elapsed, the handler runs again.
+		clock.Elapse(window)
+		res, err = call("k1", "")
+		require.NoError(t, err, "Call after window failed")
+		assert.Equal(t, "call 8", string(res.Arg3), "Handler should run after the window")
+	})
+}
+
+func TestIdempotencyKeyScopedToCaller(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.DeduplicationWindow = time.Minute
+
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		var handled atomic.Int32
+		ts.RegisterFunc("mutate", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return &raw.Res{Arg3: []byte(fmt.Sprintf("call %v", handled.Inc()))}, nil
+		})
+
+		call := func(client *Channel) string {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+
+			res, err := raw.CallV2(ctx, client.GetSubChannel(ts.ServiceName()), raw.CArgs{
+				Method:      "mutate",
+				CallOptions: &CallOptions{IdempotencyKey: "shared-key"},
+			})
+			require.NoError(t, err, "%v: call failed", client.ServiceName())
+			return string(res.Arg3)
+		}
+
+		var clients []*Channel
+		for _, caller := range []string{"caller-a", "caller-b"} {
+			client := ts.NewClient(testutils.NewOpts().SetServiceName(caller))
+			client.GetSubChannel(ts.ServiceName()).Peers().Add(ts.HostPort())
+			clients = append(clients, client)
+		}
+
+		assert.Equal(t, "call 1", call(clients[0]), "Unexpected result for first caller")
+		assert.Equal(t, "call 2", call(clients[1]), "Another caller with the same key should run the handler")
+		assert.Equal(t, "call 1", call(clients[0]), "Retry should get the caller's cached result")
+		assert.Equal(t, "call 2", call(clients[1]), "Retry should get the caller's cached result")
+		assert.EqualValues(t, 2, handled.Load(), "Handler should run once per caller")
+	})
+}
+
+func TestIdempotencyKeyAsyncResponse(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.DeduplicationWindow = time.Minute
+
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		var handled atomic.Int32
+		started := make(chan struct{}, 1)
+		unblock := make(chan struct{})
+		ts.Register(HandlerFunc(func(ctx context.Context, call *InboundCall) {
+			n := handled.Inc()
+			started <- struct{}{}
+
+			// Respond after Handle has returned.
+			go func() {
+				<-unblock
+				_, err := raw.ReadArgs(call)
+				assert.NoError(t, err, "Failed to read args")
+				err = raw.WriteResponse(call.Response(), &raw.Res{Arg3: []byte(fmt.Sprintf("call %v", n))})
+				assert.NoError(t, err, "Failed to write response")
+			}()
+		}), "mutate")
+
+		client := ts.NewClient(nil)
+		sc := client.GetSubChannel(ts.ServiceName())
+		sc.Peers().Add(ts.HostPort())
+
+		call := func() (*raw.CRes, error) {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+
+			return raw.CallV2(ctx, sc, raw.CArgs{
+				Method:      "mutate",
+				CallOptions: &CallOptions{IdempotencyKey: "key"},
+			})
+		}
+
+		firstRes := make(chan *raw.CRes, 1)
+		go func() {
+			res, err := call()
+			assert.NoError(t, err, "Call failed")
+			firstRes <- res
+		}()
+		<-started
+
+		_, err := call()
+		assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Duplicate should be busy until the response is sent")
+
+		close(unblock)
+		assert.Equal(t, "call 1", string((<-firstRes).Arg3), "Unexpected result")
+
+		res, err := call()
+		require.NoError(t, err, "Retried call failed")
+		assert.Equal(t, "call 1", string(res.Arg3), "Retry should get the cached result")
+		assert.EqualValues(t, 1, handled.Load(), "Handler should only run once")
+	})
+}
+
+func TestDrainCallers(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		drained := ts.NewClient(testutils.NewOpts().SetServiceName("drained-caller"))
+		served := ts.NewClient(testutils.NewOpts().SetServiceName("served-caller"))
+
+		call := func(client *Channel) error {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+			return err
+		}
+
+		ts.Server().DrainCallers("drained-caller", "other-caller")
+		assert.Equal(t, []string{"drained-caller", "other-caller"}, ts.Server().DrainedCallers(), "Unexpected drained callers")
+
+		err := call(drained)
+		require.Error(t, err, "Call from drained caller should fail")
+		assert.Equal(t, ErrCodeDeclined, GetSystemErrorCode(err), "Drained calls should fail with a retryable error")
+		assert.True(t, RetryConnectionError.CanRetry(err), "Drained calls should be retryable")
+		assert.NoError(t, call(served), "Call from other callers should succeed")
+
+		ts.Server().UndrainCallers("drained-caller")
+		assert.Equal(t, []string{"other-caller"}, ts.Server().DrainedCallers(), "Unexpected drained callers")
+		assert.NoError(t, call(drained), "Call from undrained caller should succeed")
+	})
+}
+
 func TestBlackhole(t *testing.T) {
 	ctx, cancel := NewContext(testutils.Timeout(time.Hour))
 
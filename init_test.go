@@ -242,6 +242,8 @@ func TestHandleInitReqNewVersion(t *testing.T) {
 					InitParamTChannelLanguage:        "go",
 					InitParamTChannelLanguageVersion: strings.TrimPrefix(runtime.Version(), "go"),
 					InitParamTChannelVersion:         VersionInfo,
+					InitParamChecksumTypes:           "3,4",
+					InitParamFeatures:                "compression,drain_signal,priority",
 				},
 			},
 		}, msg, "unexpected init res")
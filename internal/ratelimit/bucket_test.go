@@ -0,0 +1,58 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucket(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewBucket(2, 3, now)
+	assert.True(t, b.Full(now), "New bucket should be full")
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, b.Take(now), "Take %v within the burst should succeed", i)
+	}
+	assert.False(t, b.Take(now), "Take over the burst should fail")
+	assert.False(t, b.Full(now), "Drained bucket should not be full")
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, b.Take(now), "Bucket should refill at the rate")
+	assert.False(t, b.Take(now), "Bucket should only refill one token in half a second")
+
+	now = now.Add(time.Hour)
+	assert.True(t, b.Full(now), "Bucket should refill up to the burst")
+	for i := 0; i < 3; i++ {
+		assert.True(t, b.Take(now), "Take %v within the burst should succeed", i)
+	}
+	assert.False(t, b.Take(now), "Refill should not exceed the burst")
+}
+
+func TestBucketMinBurst(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewBucket(0.5, 0, now)
+	assert.True(t, b.Take(now), "Burst should be at least 1")
+	assert.False(t, b.Take(now), "Take over the burst should fail")
+}
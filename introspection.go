@@ -88,6 +88,47 @@ type RuntimeState struct {
 
 	// RuntimeVersion is the version information about the runtime and the library.
 	RuntimeVersion RuntimeVersion `json:"runtimeVersion"`
+
+	// Retry is the channel's default retry and backoff configuration.
+	Retry RetryRuntimeState `json:"retry"`
+
+	// Timeout is the channel's default timeout for calls without a deadline.
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// ConnectionOptions are the default options used for new connections.
+	ConnectionOptions ConnectionOptionsRuntimeState `json:"connectionOptions"`
+
+	// RelayCircuitBreakers is the state of the relay's circuit breakers,
+	// keyed by "service::method".
+	RelayCircuitBreakers map[string]CircuitBreakerRuntimeState `json:"relayCircuitBreakers,omitempty"`
+}
+
+// ConnectionOptionsRuntimeState is the serializable subset of the channel's
+// default ConnectionOptions.
+type ConnectionOptionsRuntimeState struct {
+	SendBufferSize      int                `json:"sendBufferSize"`
+	ChecksumType        ChecksumType       `json:"checksumType"`
+	HealthChecks        HealthCheckOptions `json:"healthChecks"`
+	KeepAlive           KeepAliveOptions   `json:"keepAlive"`
+	MaxCloseTime        time.Duration      `json:"maxCloseTime"`
+	Compression         CompressionType    `json:"compression,omitempty"`
+	ArgCompression      []string           `json:"argCompression,omitempty"`
+	MaxFragmentsPerCall int                `json:"maxFragmentsPerCall,omitempty"`
+	ReportProtocolStats bool               `json:"reportProtocolStats"`
+	MaxConnectionAge    time.Duration      `json:"maxConnectionAge,omitempty"`
+}
+
+// RetryRuntimeState is the retry and backoff configuration in effect for
+// calls made with RunWithRetry when the context does not set RetryOptions.
+type RetryRuntimeState struct {
+	MaxAttempts       int           `json:"maxAttempts"`
+	RetryOn           string        `json:"retryOn"`
+	TimeoutPerAttempt time.Duration `json:"timeoutPerAttempt"`
+	HasPeerSelector   bool          `json:"hasPeerSelector"`
+
+	// UnhealthyPeerCooldown is how long peers that fail to connect are
+	// avoided by retries.
+	UnhealthyPeerCooldown time.Duration `json:"unhealthyPeerCooldown"`
 }
 
 // GoRuntimeStateOptions are the options used when getting Go runtime state.
@@ -119,6 +160,10 @@ type SubChannelRuntimeState struct {
 	// IsolatedPeers is the list of all isolated peers for this channel.
 	IsolatedPeers []SubPeerScore      `json:"isolatedPeers,omitempty"`
 	Handler       HandlerRuntimeState `json:"handler"`
+	Retry         RetryRuntimeState   `json:"retry"`
+	Timeout       time.Duration       `json:"timeout,omitempty"`
+	// MethodTimeouts are the per-method timeouts set using SetMethodTimeout.
+	MethodTimeouts map[string]time.Duration `json:"methodTimeouts,omitempty"`
 }
 
 // HandlerRuntimeState TODO
@@ -139,6 +184,7 @@ const (
 // SubPeerScore show the runtime state of a peer with score.
 type SubPeerScore struct {
 	HostPort string `json:"hostPort"`
+	Identity string `json:"identity,omitempty"`
 	Score    uint64 `json:"score"`
 }
 
@@ -155,6 +201,14 @@ type ConnectionRuntimeState struct {
 	Relayer          RelayerRuntimeState     `json:"relayer"`
 	HealthChecks     []bool                  `json:"healthChecks,omitempty"`
 	LastActivity     int64                   `json:"lastActivity"`
+	Compression      CompressionType         `json:"compression,omitempty"`
+	ArgCompression   string                  `json:"argCompression,omitempty"`
+	ChecksumType     ChecksumType            `json:"checksumType"`
+	ProtocolStats    ConnectionProtocolStats `json:"protocolStats"`
+	Encrypted        bool                    `json:"encrypted,omitempty"`
+	RemoteDraining   bool                    `json:"remoteDraining,omitempty"`
+	SendBufferUsed   int                     `json:"sendBufferUsed"`
+	LastError        string                  `json:"lastError,omitempty"`
 }
 
 // RelayerRuntimeState is the runtime state for a single relayer.
@@ -196,10 +250,13 @@ type RelayItemState struct {
 // PeerRuntimeState is the runtime state for a single peer.
 type PeerRuntimeState struct {
 	HostPort            string                   `json:"hostPort"`
+	Identity            string                   `json:"identity,omitempty"`
 	OutboundConnections []ConnectionRuntimeState `json:"outboundConnections"`
 	InboundConnections  []ConnectionRuntimeState `json:"inboundConnections"`
 	ChosenCount         uint64                   `json:"chosenCount"`
 	SCCount             uint32                   `json:"scCount"`
+	Reconnections       uint64                   `json:"reconnections"`
+	Stats               PeerStats                `json:"stats"`
 }
 
 // IntrospectState returns the RuntimeState for this channel.
@@ -225,18 +282,50 @@ func (ch *Channel) IntrospectState(opts *IntrospectionOptions) *RuntimeState {
 
 	ch.State()
 	return &RuntimeState{
-		ID:                  ch.chID,
-		ChannelState:        state.String(),
-		CreatedStack:        ch.createdStack,
-		LocalPeer:           ch.PeerInfo(),
-		SubChannels:         ch.subChannels.IntrospectState(opts),
-		RootPeers:           ch.RootPeers().IntrospectState(opts),
-		Peers:               ch.Peers().IntrospectList(opts),
-		NumConnections:      numConns,
-		Connections:         connIDs,
-		InactiveConnections: getConnectionRuntimeState(inactiveConns, opts),
-		OtherChannels:       ch.IntrospectOthers(opts),
-		RuntimeVersion:      introspectRuntimeVersion(),
+		ID:                   ch.chID,
+		ChannelState:         state.String(),
+		CreatedStack:         ch.createdStack,
+		LocalPeer:            ch.PeerInfo(),
+		SubChannels:          ch.subChannels.IntrospectState(opts),
+		RootPeers:            ch.RootPeers().IntrospectState(opts),
+		Peers:                ch.Peers().IntrospectList(opts),
+		NumConnections:       numConns,
+		Connections:          connIDs,
+		InactiveConnections:  getConnectionRuntimeState(inactiveConns, opts),
+		OtherChannels:        ch.IntrospectOthers(opts),
+		RuntimeVersion:       introspectRuntimeVersion(),
+		Retry:                ch.retryRuntimeState(ch.defaultRetryOptions),
+		Timeout:              ch.defaultTimeout,
+		ConnectionOptions:    ch.connectionOptions.introspectState(),
+		RelayCircuitBreakers: ch.relayCircuitBreaker.introspectState(),
+	}
+}
+
+func (co ConnectionOptions) introspectState() ConnectionOptionsRuntimeState {
+	return ConnectionOptionsRuntimeState{
+		SendBufferSize:      co.SendBufferSize,
+		ChecksumType:        co.ChecksumType,
+		HealthChecks:        co.HealthChecks,
+		KeepAlive:           co.KeepAlive,
+		MaxCloseTime:        co.MaxCloseTime,
+		Compression:         co.Compression,
+		ArgCompression:      co.ArgCompression,
+		MaxFragmentsPerCall: co.MaxFragmentsPerCall,
+		ReportProtocolStats: co.ReportProtocolStats,
+		MaxConnectionAge:    co.MaxConnectionAge,
+	}
+}
+
+// retryRuntimeState returns the effective retry configuration for the given
+// default RetryOptions.
+func (ch *Channel) retryRuntimeState(opts *RetryOptions) RetryRuntimeState {
+	resolved := resolveRetryOptions(opts)
+	return RetryRuntimeState{
+		MaxAttempts:           resolved.MaxAttempts,
+		RetryOn:               resolved.RetryOn.String(),
+		TimeoutPerAttempt:     resolved.TimeoutPerAttempt,
+		HasPeerSelector:       resolved.PeerSelector != nil,
+		UnhealthyPeerCooldown: ch.RootPeers().unhealthyCooldown,
 	}
 }
 
@@ -301,7 +390,10 @@ func (subChMap *subChannelMap) IntrospectState(opts *IntrospectionOptions) map[s
 		state := SubChannelRuntimeState{
 			Service:  k,
 			Isolated: sc.Isolated(),
+			Retry:    sc.topChannel.retryRuntimeState(sc.retryOptions()),
+			Timeout:  sc.timeout(),
 		}
+		state.MethodTimeouts = sc.methodTimeoutsCopy()
 		if state.Isolated {
 			state.IsolatedPeers = sc.Peers().IntrospectList(opts)
 		}
@@ -334,15 +426,21 @@ func getConnectionRuntimeState(conns []*Connection, opts *IntrospectionOptions)
 
 // IntrospectState returns the runtime state for this peer.
 func (p *Peer) IntrospectState(opts *IntrospectionOptions) PeerRuntimeState {
+	// Stats locks the peer, so get them before locking.
+	stats := p.Stats()
+
 	p.RLock()
 	defer p.RUnlock()
 
 	return PeerRuntimeState{
 		HostPort:            p.hostPort,
+		Identity:            p.Identity(),
 		InboundConnections:  getConnectionRuntimeState(p.inboundConnections, opts),
 		OutboundConnections: getConnectionRuntimeState(p.outboundConnections, opts),
 		ChosenCount:         p.chosenCount.Load(),
 		SCCount:             p.scCount,
+		Reconnections:       p.reconnections.Load(),
+		Stats:               stats,
 	}
 }
 
@@ -363,6 +461,18 @@ func (c *Connection) IntrospectState(opts *IntrospectionOptions) ConnectionRunti
 		OutboundExchange: c.outbound.IntrospectState(opts),
 		HealthChecks:     c.healthCheckHistory.asBools(),
 		LastActivity:     c.lastActivity.Load(),
+		Compression:      c.compression,
+		ChecksumType:     c.opts.ChecksumType,
+		ProtocolStats:    c.protocolStats.snapshot(),
+		Encrypted:        c.isTLS(),
+		RemoteDraining:   c.remoteDraining.Load(),
+		SendBufferUsed:   len(c.sendCh),
+	}
+	if lastErr, ok := c.lastError.Load().(connLastError); ok {
+		state.LastError = lastErr.message
+	}
+	if c.argCompression != nil {
+		state.ArgCompression = c.argCompression.name
 	}
 	if c.relay != nil {
 		state.Relayer = c.relay.IntrospectState(opts)
@@ -458,6 +568,7 @@ func (l *PeerList) IntrospectList(opts *IntrospectionOptions) []SubPeerScore {
 	for _, ps := range l.peerHeap.peerScores {
 		peers = append(peers, SubPeerScore{
 			HostPort: ps.Peer.hostPort,
+			Identity: ps.Peer.Identity(),
 			Score:    ps.score,
 		})
 	}
@@ -0,0 +1,71 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// IntrospectionHandler returns an http.Handler that serves the channel's
+// RuntimeState as JSON, so it can be mounted on a debug port. The
+// IntrospectionOptions are read from boolean query parameters named after
+// their JSON fields, e.g. ?includeExchanges=true. Per-exchange detail is
+// excluded unless includeExchanges is set, since it can be large.
+func (ch *Channel) IntrospectionHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts, err := introspectionOptionsFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(ch.IntrospectState(opts)); err != nil {
+			ch.log.WithFields(ErrField(err)).Warn("Failed to write introspection state.")
+		}
+	})
+}
+
+func introspectionOptionsFromQuery(query url.Values) (*IntrospectionOptions, error) {
+	opts := &IntrospectionOptions{}
+	params := map[string]*bool{
+		"includeExchanges":     &opts.IncludeExchanges,
+		"includeEmptyPeers":    &opts.IncludeEmptyPeers,
+		"includeTombstones":    &opts.IncludeTombstones,
+		"includeOtherChannels": &opts.IncludeOtherChannels,
+	}
+	for name, field := range params {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %v: %q", name, v)
+		}
+		*field = b
+	}
+	return opts, nil
+}
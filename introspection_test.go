@@ -21,6 +21,9 @@
 package tchannel_test
 
 import (
+	json_encoding "encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -116,3 +119,135 @@ func TestIntrospectClosedConn(t *testing.T) {
 		}
 	})
 }
+
+func TestIntrospectRetryOptions(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.DefaultRetryOptions = &RetryOptions{
+		MaxAttempts:       3,
+		RetryOn:           RetryIdempotent,
+		TimeoutPerAttempt: 100 * time.Millisecond,
+	}
+	opts.UnhealthyPeerCooldown = time.Minute
+	ch := testutils.NewClient(t, opts)
+	defer ch.Close()
+
+	ch.GetSubChannel("default")
+	ch.GetSubChannel("custom", DefaultRetryOptions(&RetryOptions{
+		RetryOn: RetryNever,
+		PeerSelector: func(*RequestState, []*Peer) *Peer {
+			return nil
+		},
+	}))
+
+	channelRetry := RetryRuntimeState{
+		MaxAttempts:           3,
+		RetryOn:               "RetryIdempotent",
+		TimeoutPerAttempt:     100 * time.Millisecond,
+		UnhealthyPeerCooldown: time.Minute,
+	}
+	state := ch.IntrospectState(nil)
+	assert.Equal(t, channelRetry, state.Retry, "Unexpected channel retry state")
+	assert.Equal(t, channelRetry, state.SubChannels["default"].Retry,
+		"Subchannel without DefaultRetryOptions should report the channel's")
+	assert.Equal(t, RetryRuntimeState{
+		MaxAttempts:           5,
+		RetryOn:               "RetryNever",
+		HasPeerSelector:       true,
+		UnhealthyPeerCooldown: time.Minute,
+	}, state.SubChannels["custom"].Retry, "Unexpected subchannel retry state")
+
+	// The retry state is included in the serialized introspection output.
+	serialized, err := json_encoding.Marshal(state.SubChannels["custom"])
+	require.NoError(t, err, "Failed to marshal subchannel state")
+	assert.Contains(t, string(serialized), `"retry":{"maxAttempts":5,"retryOn":"RetryNever"`,
+		"Missing retry state in serialized output")
+}
+
+func TestIntrospectDefaultTimeout(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.DefaultTimeout = time.Second
+	ch := testutils.NewClient(t, opts)
+	defer ch.Close()
+
+	ch.GetSubChannel("default")
+	ch.GetSubChannel("custom", DefaultTimeout(100*time.Millisecond)).SetMethodTimeout("slow", time.Minute)
+
+	state := ch.IntrospectState(nil)
+	assert.Equal(t, time.Second, state.Timeout, "Unexpected channel timeout")
+	assert.Equal(t, time.Second, state.SubChannels["default"].Timeout,
+		"Subchannel without DefaultTimeout should report the channel's")
+	assert.Equal(t, 100*time.Millisecond, state.SubChannels["custom"].Timeout,
+		"Unexpected subchannel timeout")
+	assert.Equal(t, map[string]time.Duration{"slow": time.Minute}, state.SubChannels["custom"].MethodTimeouts,
+		"Unexpected method timeouts")
+	assert.Empty(t, state.SubChannels["default"].MethodTimeouts, "Unexpected method timeouts")
+}
+
+func TestIntrospectDefaultRetryOptions(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	assert.Equal(t, RetryRuntimeState{
+		MaxAttempts: 5,
+		RetryOn:     "RetryConnectionError",
+	}, ch.IntrospectState(nil).Retry, "Unexpected default retry state")
+}
+
+func TestIntrospectionHandler(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		blockEcho := make(chan struct{})
+		gotEcho := make(chan struct{})
+		testutils.RegisterEcho(ts.Server(), func() {
+			close(gotEcho)
+			<-blockEcho
+		})
+
+		client := ts.NewClient(nil)
+		callDone := make(chan struct{})
+		go func() {
+			assert.NoError(t, testutils.CallEcho(client, ts.HostPort(), ts.ServiceName(), nil))
+			close(callDone)
+		}()
+		<-gotEcho
+
+		handler := ts.Server().IntrospectionHandler()
+		getState := func(query string) RuntimeState {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/"+query, nil))
+			require.Equal(t, http.StatusOK, rec.Code, "Unexpected status: %s", rec.Body)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"), "Unexpected content type")
+
+			var state RuntimeState
+			require.NoError(t, json_encoding.Unmarshal(rec.Body.Bytes(), &state), "Failed to decode state")
+			return state
+		}
+		inboundExchanges := func(state RuntimeState) ExchangeSetRuntimeState {
+			require.Len(t, state.RootPeers, 1, "Expected a single peer")
+			for _, peer := range state.RootPeers {
+				require.Len(t, peer.InboundConnections, 1, "Expected a single inbound connection")
+				return peer.InboundConnections[0].InboundExchange
+			}
+			return ExchangeSetRuntimeState{}
+		}
+
+		state := getState("")
+		assert.Equal(t, ts.Server().PeerInfo(), state.LocalPeer, "Unexpected local peer")
+		assert.Equal(t, 1, state.NumConnections, "Unexpected number of connections")
+		assert.Contains(t, state.SubChannels, ts.ServiceName(), "Missing registered subchannel")
+		assert.Equal(t, ChecksumTypeCrc32, state.ConnectionOptions.ChecksumType, "Unexpected connection options")
+		exchanges := inboundExchanges(state)
+		assert.Equal(t, 1, exchanges.Count, "Expected the in-flight call's exchange")
+		assert.Empty(t, exchanges.Exchanges, "Exchanges should be excluded by default")
+
+		exchanges = inboundExchanges(getState("?includeExchanges=true"))
+		assert.Len(t, exchanges.Exchanges, 1, "Exchanges should be included")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/?includeExchanges=maybe", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "Invalid options should be rejected")
+
+		close(blockEcho)
+		<-callDone
+	})
+}
@@ -40,11 +40,15 @@ type Client struct {
 	ch            *tchannel.Channel
 	targetService string
 	hostPort      string
+	codec         Codec
 }
 
 // ClientOptions are options used when creating a client.
 type ClientOptions struct {
 	HostPort string
+
+	// Codec is used to encode and decode arguments. If nil, DefaultCodec is used.
+	Codec Codec
 }
 
 // NewClient returns a json.Client used to make outbound JSON calls.
@@ -52,38 +56,42 @@ func NewClient(ch *tchannel.Channel, targetService string, opts *ClientOptions)
 	client := &Client{
 		ch:            ch,
 		targetService: targetService,
+		codec:         DefaultCodec,
 	}
 	if opts != nil && opts.HostPort != "" {
 		client.hostPort = opts.HostPort
 	}
+	if opts != nil && opts.Codec != nil {
+		client.codec = opts.Codec
+	}
 	return client
 }
 
-func makeCall(call *tchannel.OutboundCall, headers, arg3In, respHeaders, arg3Out, errorOut interface{}) (bool, string, error) {
+func makeCall(codec Codec, call *tchannel.OutboundCall, headers, arg3In, respHeaders, arg3Out, errorOut interface{}) (bool, string, error) {
 	if mapHeaders, ok := headers.(map[string]string); ok {
 		headers = tchannel.InjectOutboundSpan(call.Response(), mapHeaders)
 	}
-	if err := tchannel.NewArgWriter(call.Arg2Writer()).WriteJSON(headers); err != nil {
+	if err := writeArg(codec, call.Arg2Writer, headers); err != nil {
 		return false, "arg2 write failed", err
 	}
-	if err := tchannel.NewArgWriter(call.Arg3Writer()).WriteJSON(arg3In); err != nil {
+	if err := writeArg(codec, call.Arg3Writer, arg3In); err != nil {
 		return false, "arg3 write failed", err
 	}
 
 	// Call Arg2Reader before checking application error.
-	if err := tchannel.NewArgReader(call.Response().Arg2Reader()).ReadJSON(respHeaders); err != nil {
+	if err := readArg(codec, call.Response().Arg2Reader, respHeaders); err != nil {
 		return false, "arg2 read failed", err
 	}
 
 	// If this is an error response, read the response into a map and return a jsonCallErr.
 	if call.Response().ApplicationError() {
-		if err := tchannel.NewArgReader(call.Response().Arg3Reader()).ReadJSON(errorOut); err != nil {
+		if err := readArg(codec, call.Response().Arg3Reader, errorOut); err != nil {
 			return false, "arg3 read error failed", err
 		}
 		return false, "", nil
 	}
 
-	if err := tchannel.NewArgReader(call.Response().Arg3Reader()).ReadJSON(arg3Out); err != nil {
+	if err := readArg(codec, call.Response().Arg3Reader, arg3Out); err != nil {
 		return false, "arg3 read failed", err
 	}
 
@@ -109,7 +117,7 @@ func (c *Client) Call(ctx Context, method string, arg, resp interface{}) error {
 		isOK        bool
 	)
 
-	err := c.ch.RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
+	err := c.ch.GetSubChannel(c.targetService).RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
 		respHeaders, respErr, isOK = nil, nil, false
 		errAt = "connect"
 
@@ -121,7 +129,7 @@ func (c *Client) Call(ctx Context, method string, arg, resp interface{}) error {
 			return err
 		}
 
-		isOK, errAt, err = makeCall(call, headers, arg, &respHeaders, resp, &respErr)
+		isOK, errAt, err = makeCall(c.codec, call, headers, arg, &respHeaders, resp, &respErr)
 		return err
 	})
 	if err != nil {
@@ -136,10 +144,10 @@ func (c *Client) Call(ctx Context, method string, arg, resp interface{}) error {
 }
 
 // TODO(prashantv): Clean up json.Call* interfaces.
-func wrapCall(ctx Context, call *tchannel.OutboundCall, method string, arg, resp interface{}) error {
+func wrapCall(ctx Context, call *tchannel.OutboundCall, method string, arg, resp interface{}, opts []Option) error {
 	var respHeaders map[string]string
 	var respErr ErrApplication
-	isOK, errAt, err := makeCall(call, ctx.Headers(), arg, &respHeaders, resp, &respErr)
+	isOK, errAt, err := makeCall(newOptions(opts).codec, call, ctx.Headers(), arg, &respHeaders, resp, &respErr)
 	if err != nil {
 		return fmt.Errorf("%s: %v", errAt, err)
 	}
@@ -152,21 +160,21 @@ func wrapCall(ctx Context, call *tchannel.OutboundCall, method string, arg, resp
 }
 
 // CallPeer makes a JSON call using the given peer.
-func CallPeer(ctx Context, peer *tchannel.Peer, serviceName, method string, arg, resp interface{}) error {
+func CallPeer(ctx Context, peer *tchannel.Peer, serviceName, method string, arg, resp interface{}, opts ...Option) error {
 	call, err := peer.BeginCall(ctx, serviceName, method, &tchannel.CallOptions{Format: tchannel.JSON})
 	if err != nil {
 		return err
 	}
 
-	return wrapCall(ctx, call, method, arg, resp)
+	return wrapCall(ctx, call, method, arg, resp, opts)
 }
 
 // CallSC makes a JSON call using the given subchannel.
-func CallSC(ctx Context, sc *tchannel.SubChannel, method string, arg, resp interface{}) error {
+func CallSC(ctx Context, sc *tchannel.SubChannel, method string, arg, resp interface{}, opts ...Option) error {
 	call, err := sc.BeginCall(ctx, method, &tchannel.CallOptions{Format: tchannel.JSON})
 	if err != nil {
 		return err
 	}
 
-	return wrapCall(ctx, call, method, arg, resp)
+	return wrapCall(ctx, call, method, arg, resp, opts)
 }
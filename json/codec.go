@@ -0,0 +1,136 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package json
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/internal/argreader"
+)
+
+// Codec encodes and decodes the JSON arguments of calls. Arguments are encoded
+// directly to the call's arg writer, and decoded directly from the arg
+// reader, so codecs should stream rather than buffer the whole argument.
+type Codec interface {
+	// Encode writes the JSON encoding of v to w.
+	Encode(w io.Writer, v interface{}) error
+
+	// Decode reads the JSON encoding of a value from r into v.
+	Decode(r io.Reader, v interface{}) error
+}
+
+var (
+	// DefaultCodec is the Codec used when no codec is specified. It uses
+	// encoding/json.
+	DefaultCodec Codec = stdCodec{}
+
+	// StrictCodec is like DefaultCodec, but fails to decode objects that
+	// contain fields which aren't in the destination struct.
+	StrictCodec Codec = stdCodec{strict: true}
+)
+
+type stdCodec struct {
+	strict bool
+}
+
+func (stdCodec) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (c stdCodec) Decode(r io.Reader, v interface{}) error {
+	d := json.NewDecoder(r)
+	if c.strict {
+		d.DisallowUnknownFields()
+	}
+	return d.Decode(v)
+}
+
+// Option configures JSON handlers registered using Register, or JSON calls.
+type Option interface {
+	apply(*options)
+}
+
+type options struct {
+	codec Codec
+}
+
+func newOptions(opts []Option) options {
+	o := options{codec: DefaultCodec}
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+	return o
+}
+
+type optCodec struct {
+	codec Codec
+}
+
+func (o optCodec) apply(opts *options) {
+	if o.codec != nil {
+		opts.codec = o.codec
+	}
+}
+
+// OptCodec sets the Codec used to encode and decode arguments.
+func OptCodec(codec Codec) Option {
+	return optCodec{codec}
+}
+
+// readArg decodes the argument returned by getReader into data using codec.
+func readArg(codec Codec, getReader func() (tchannel.ArgReader, error), data interface{}) error {
+	reader, err := getReader()
+	if err != nil {
+		return err
+	}
+
+	// TChannel allows for 0 length values (not valid JSON), so we use a
+	// bufio.Reader to check whether data is of 0 length.
+	// If the data is 0 length, then we don't try to read anything.
+	br := bufio.NewReader(reader)
+	if _, err := br.Peek(1); err == nil {
+		if err := codec.Decode(br, data); err != nil {
+			return err
+		}
+	} else if err != io.EOF {
+		return err
+	}
+
+	if err := argreader.EnsureEmpty(reader, "read arg"); err != nil {
+		return err
+	}
+	return reader.Close()
+}
+
+// writeArg encodes data to the argument returned by getWriter using codec.
+func writeArg(codec Codec, getWriter func() (tchannel.ArgWriter, error), data interface{}) error {
+	writer, err := getWriter()
+	if err != nil {
+		return err
+	}
+	if err := codec.Encode(writer, data); err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -79,6 +79,7 @@ type handler struct {
 	argType  reflect.Type
 	isArgMap bool
 	tracer   func() opentracing.Tracer
+	codec    Codec
 }
 
 func toHandler(f interface{}) (*handler, error) {
@@ -93,7 +94,10 @@ func toHandler(f interface{}) (*handler, error) {
 // Register registers the specified methods specified as a map from method name to the
 // JSON handler function. The handler functions should have the following signature:
 // func(context.Context, *ArgType)(*ResType, error)
-func Register(registrar tchannel.Registrar, funcs Handlers, onError func(context.Context, error)) error {
+// Arguments and results are encoded using DefaultCodec, unless another codec
+// is specified using OptCodec.
+func Register(registrar tchannel.Registrar, funcs Handlers, onError func(context.Context, error), opts ...Option) error {
+	options := newOptions(opts)
 	handlers := make(map[string]*handler)
 
 	handler := tchannel.HandlerFunc(func(ctx context.Context, call *tchannel.InboundCall) {
@@ -116,6 +120,7 @@ func Register(registrar tchannel.Registrar, funcs Handlers, onError func(context
 		h.tracer = func() opentracing.Tracer {
 			return tchannel.TracerFromRegistrar(registrar)
 		}
+		h.codec = options.codec
 		handlers[m] = h
 		registrar.Register(handler, m)
 	}
@@ -126,7 +131,7 @@ func Register(registrar tchannel.Registrar, funcs Handlers, onError func(context
 // Handle deserializes the JSON arguments and calls the underlying handler.
 func (h *handler) Handle(tctx context.Context, call *tchannel.InboundCall) error {
 	var headers map[string]string
-	if err := tchannel.NewArgReader(call.Arg2Reader()).ReadJSON(&headers); err != nil {
+	if err := readArg(h.codec, call.Arg2Reader, &headers); err != nil {
 		return fmt.Errorf("arg2 read failed: %v", err)
 	}
 	tctx = tchannel.ExtractInboundSpan(tctx, call, headers, h.tracer())
@@ -142,7 +147,7 @@ func (h *handler) Handle(tctx context.Context, call *tchannel.InboundCall) error
 		arg3 = reflect.New(h.argType.Elem())
 		callArg = arg3
 	}
-	if err := tchannel.NewArgReader(call.Arg3Reader()).ReadJSON(arg3.Interface()); err != nil {
+	if err := readArg(h.codec, call.Arg3Reader, arg3.Interface()); err != nil {
 		return fmt.Errorf("arg3 read failed: %v", err)
 	}
 
@@ -169,9 +174,9 @@ func (h *handler) Handle(tctx context.Context, call *tchannel.InboundCall) error
 		}
 	}
 
-	if err := tchannel.NewArgWriter(call.Response().Arg2Writer()).WriteJSON(ctx.ResponseHeaders()); err != nil {
+	if err := writeArg(h.codec, call.Response().Arg2Writer, ctx.ResponseHeaders()); err != nil {
 		return err
 	}
 
-	return tchannel.NewArgWriter(call.Response().Arg3Writer()).WriteJSON(res)
+	return writeArg(h.codec, call.Response().Arg3Writer, res)
 }
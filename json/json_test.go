@@ -22,6 +22,7 @@ package json
 
 import (
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
@@ -208,6 +209,27 @@ func TestHeadersForwarded(t *testing.T) {
 	}
 }
 
+func TestArgScheme(t *testing.T) {
+	ch, err := tchannel.NewChannel("svc", nil)
+	require.NoError(t, err)
+	defer ch.Close()
+
+	require.NoError(t, Register(ch, Handlers{
+		"scheme": func(ctx Context, _ *struct{}) (*Res, error) {
+			return &Res{tchannel.CurrentCall(ctx).ArgScheme()}, nil
+		},
+	}, nil))
+	require.NoError(t, ch.ListenAndServe("127.0.0.1:0"))
+
+	ctx, cancel := NewContext(time.Second)
+	defer cancel()
+
+	resp := &Res{}
+	peer := ch.Peers().Add(ch.PeerInfo().HostPort)
+	require.NoError(t, CallPeer(ctx, peer, "svc", "scheme", nil, resp))
+	assert.Equal(t, "json", resp.Result, "Unexpected arg scheme")
+}
+
 func TestEmptyRequestHeader(t *testing.T) {
 	ctx, cancel := NewContext(time.Second)
 	defer cancel()
@@ -274,3 +296,89 @@ func TestMapInputOutput(t *testing.T) {
 	require.NoError(t, tchannel.NewArgReader(resp.Arg3Reader()).ReadJSON(&data))
 	assert.Equal(t, arg, data.(map[string]interface{}), "result does not match arg")
 }
+
+// countingCodec is a Codec that counts the values encoded and decoded.
+type countingCodec struct {
+	encoded, decoded int
+}
+
+func (c *countingCodec) Encode(w io.Writer, v interface{}) error {
+	c.encoded++
+	return DefaultCodec.Encode(w, v)
+}
+
+func (c *countingCodec) Decode(r io.Reader, v interface{}) error {
+	c.decoded++
+	return DefaultCodec.Decode(r, v)
+}
+
+func TestCustomCodec(t *testing.T) {
+	ctx, cancel := NewContext(time.Second)
+	defer cancel()
+
+	ch, err := tchannel.NewChannel("server", nil)
+	require.NoError(t, err)
+	defer ch.Close()
+	require.NoError(t, ch.ListenAndServe("127.0.0.1:0"))
+
+	handler := func(ctx Context, args *ForwardArgs) (*Res, error) {
+		return &Res{Result: args.Method}, nil
+	}
+	onError := func(ctx context.Context, err error) {
+		t.Errorf("onError: %v", err)
+	}
+	serverCodec := &countingCodec{}
+	require.NoError(t, Register(ch, Handlers{"handle": handler}, onError, OptCodec(serverCodec)))
+
+	clientCodec := &countingCodec{}
+	resp := &Res{}
+	peer := ch.Peers().Add(ch.PeerInfo().HostPort)
+	require.NoError(t, CallPeer(ctx, peer, "server", "handle", &ForwardArgs{Method: "m"}, resp, OptCodec(clientCodec)))
+	assert.Equal(t, "m", resp.Result, "Unexpected result")
+
+	// Headers and the body are each encoded and decoded once on both sides.
+	assert.Equal(t, &countingCodec{encoded: 2, decoded: 2}, clientCodec, "Unexpected client codec usage")
+	assert.Equal(t, 2, serverCodec.encoded, "Unexpected server codec encodes")
+	assert.Equal(t, 2, serverCodec.decoded, "Unexpected server codec decodes")
+
+	client := NewClient(ch, "server", &ClientOptions{HostPort: ch.PeerInfo().HostPort, Codec: clientCodec})
+	require.NoError(t, client.Call(ctx, "handle", &ForwardArgs{Method: "m2"}, resp))
+	assert.Equal(t, "m2", resp.Result, "Unexpected result")
+	assert.Equal(t, 4, clientCodec.encoded, "Client should use the codec from ClientOptions")
+}
+
+func TestStrictCodec(t *testing.T) {
+	ctx, cancel := NewContext(time.Second)
+	defer cancel()
+
+	ch, err := tchannel.NewChannel("server", nil)
+	require.NoError(t, err)
+	defer ch.Close()
+	require.NoError(t, ch.ListenAndServe("127.0.0.1:0"))
+
+	handler := func(ctx Context, args *Res) (*Res, error) {
+		return args, nil
+	}
+	errs := make(chan error, 1)
+	onError := func(ctx context.Context, err error) {
+		errs <- err
+	}
+	require.NoError(t, Register(ch, Handlers{"handle": handler}, onError, OptCodec(StrictCodec)))
+
+	peer := ch.Peers().Add(ch.PeerInfo().HostPort)
+	require.NoError(t, CallPeer(ctx, peer, "server", "handle", &Res{Result: "ok"}, &Res{}),
+		"Call with known fields failed")
+
+	sctx, scancel := NewContext(100 * time.Millisecond)
+	defer scancel()
+	unknown := map[string]interface{}{"Result": "ok", "Unknown": true}
+	assert.Error(t, CallPeer(sctx, peer, "server", "handle", unknown, &Res{}),
+		"Call with unknown fields should fail")
+
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "unknown field", "Unexpected error")
+	case <-time.After(time.Second):
+		t.Fatal("Expected onError to be called")
+	}
+}
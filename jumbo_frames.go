@@ -0,0 +1,152 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"encoding/binary"
+	"strconv"
+	"sync"
+)
+
+// jumboFrameSize returns the jumbo frame size configured in opts, or 0 if
+// jumbo frames are not enabled.
+func jumboFrameSize(opts ConnectionOptions) int {
+	switch size := opts.JumboFrameSize; {
+	case size <= MaxFrameSize:
+		return 0
+	case size > MaxJumboFrameSize:
+		return MaxJumboFrameSize
+	default:
+		return size
+	}
+}
+
+// negotiateJumboFrameSize returns the largest frame size both peers support
+// if both advertised FeatureLargeFrames, or 0 if jumbo frames can't be used.
+func negotiateJumboFrameSize(local int, features connectionFeatures, remote initParams) int {
+	if local == 0 || !features.negotiated.Has(FeatureLargeFrames) {
+		return 0
+	}
+	remoteSize, err := strconv.Atoi(remote[InitParamMaxFrameSize])
+	if err != nil || remoteSize <= MaxFrameSize {
+		return 0
+	}
+	if remoteSize < local {
+		return remoteSize
+	}
+	return local
+}
+
+// jumboFrames is a pool of frames with room for the channel's jumbo frame
+// size, shared by the channel's connections that use jumbo frames.
+type jumboFrames struct {
+	size int
+	pool sync.Pool
+}
+
+// newJumboFrames returns the jumbo frames for a channel, or nil if jumbo
+// frames are not enabled. Relays don't use jumbo frames, since frames are
+// forwarded to connections that may not support them.
+func newJumboFrames(opts ConnectionOptions) *jumboFrames {
+	size := jumboFrameSize(opts)
+	if size == 0 {
+		return nil
+	}
+	return &jumboFrames{size: size, pool: sync.Pool{
+		New: func() interface{} { return NewFrame(size - FrameHeaderSize) },
+	}}
+}
+
+// frameSize returns the jumbo frame size, or 0 if jumbo frames are not enabled.
+func (j *jumboFrames) frameSize() int {
+	if j == nil {
+		return 0
+	}
+	return j.size
+}
+
+// jumboFramePool is the FramePool for a connection that uses jumbo frames.
+// Standard frames come from the channel's FramePool, and jumbo frames from
+// jumboFrames, and each frame is released to the pool it came from.
+type jumboFramePool struct {
+	FramePool
+
+	jumbo *jumboFrames
+}
+
+func (p jumboFramePool) getJumbo() *Frame {
+	return p.jumbo.pool.Get().(*Frame)
+}
+
+func (p jumboFramePool) Release(f *Frame) {
+	if len(f.Payload) > MaxFramePayloadSize {
+		f.Header = FrameHeader{}
+		p.jumbo.pool.Put(f)
+		return
+	}
+	p.FramePool.Release(f)
+}
+
+// getFrame returns a frame to read a frame with the given header into.
+func (c *Connection) getFrame(header []byte) *Frame {
+	// The reserved byte follows the size:2 and type:1 fields.
+	if pool, ok := c.opts.FramePool.(jumboFramePool); ok && header[3]&jumboFrameFlag != 0 {
+		return pool.getJumbo()
+	}
+	return c.opts.FramePool.Get()
+}
+
+// getCallFrame returns a frame for a fragment of a call, and the payload that
+// the fragment can use. On connections that use jumbo frames, call frames are
+// always jumbo frames.
+func (c *Connection) getCallFrame() (*Frame, []byte) {
+	pool, ok := c.opts.FramePool.(jumboFramePool)
+	if !ok {
+		frame := c.opts.FramePool.Get()
+		return frame, frame.Payload
+	}
+
+	frame := pool.getJumbo()
+	frame.Header.reserved1 = jumboFrameFlag
+	return frame, frame.Payload[:c.maxFrameSize-FrameHeaderSize]
+}
+
+// MaxFrameSize returns the maximum size of frames on the connection, which is
+// larger than the standard MaxFrameSize if both peers use jumbo frames.
+func (c *Connection) MaxFrameSize() int {
+	return c.maxFrameSize
+}
+
+// chunkSizeLen returns the size of the arg chunk sizes in a frame's payload.
+func chunkSizeLen(jumbo bool) int {
+	if jumbo {
+		return 4
+	}
+	return 2
+}
+
+// readChunkSize returns the arg chunk size at the start of b.
+func readChunkSize(b []byte, jumbo bool) int {
+	if jumbo {
+		return int(binary.BigEndian.Uint32(b))
+	}
+	return int(binary.BigEndian.Uint16(b))
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJumboFrames(t *testing.T) {
+	const mb = 1024 * 1024
+
+	tests := []struct {
+		msg          string
+		clientSize   int
+		serverSize   int
+		wantSize     int
+		wantFeatured bool
+	}{
+		{
+			msg:      "disabled",
+			wantSize: MaxFrameSize,
+		},
+		{
+			msg:        "only client",
+			clientSize: mb,
+			wantSize:   MaxFrameSize,
+		},
+		{
+			msg:        "only server",
+			serverSize: mb,
+			wantSize:   MaxFrameSize,
+		},
+		{
+			msg:          "both",
+			clientSize:   mb,
+			serverSize:   mb,
+			wantSize:     mb,
+			wantFeatured: true,
+		},
+		{
+			msg:          "smaller server size",
+			clientSize:   2 * mb,
+			serverSize:   mb,
+			wantSize:     mb,
+			wantFeatured: true,
+		},
+		{
+			msg:          "capped size",
+			clientSize:   100 * mb,
+			serverSize:   100 * mb,
+			wantSize:     MaxJumboFrameSize,
+			wantFeatured: true,
+		},
+		{
+			msg:        "size no larger than the standard size",
+			clientSize: MaxFrameSize,
+			serverSize: MaxFrameSize,
+			wantSize:   MaxFrameSize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			serverOpts := testutils.NewOpts()
+			serverOpts.DefaultConnectionOptions.JumboFrameSize = tt.serverSize
+			server := testutils.NewServer(t, serverOpts)
+			defer server.Close()
+			testutils.RegisterEcho(server, nil)
+
+			clientOpts := testutils.NewOpts()
+			clientOpts.DefaultConnectionOptions.JumboFrameSize = tt.clientSize
+			client := testutils.NewClient(t, clientOpts)
+			defer client.Close()
+
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+			conn, err := client.Connect(ctx, server.PeerInfo().HostPort)
+			require.NoError(t, err, "Connect failed")
+
+			assert.Equal(t, tt.wantSize, conn.MaxFrameSize(), "Unexpected max frame size")
+			assert.Equal(t, tt.wantFeatured, conn.SupportsFeature(FeatureLargeFrames), "Unexpected large frames support")
+
+			arg2 := testutils.RandBytes(100 * 1024)
+			arg3 := testutils.RandBytes(3 * mb)
+			gotArg2, gotArg3, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, server.ServiceName(), "echo", arg2, arg3)
+			require.NoError(t, err, "Call failed")
+			assert.True(t, bytes.Equal(arg2, gotArg2), "Unexpected arg2")
+			assert.True(t, bytes.Equal(arg3, gotArg3), "Unexpected arg3")
+		})
+	}
+}
+
+func TestJumboFramesRelay(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.DefaultConnectionOptions.JumboFrameSize = 1024 * 1024
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		clientOpts := testutils.NewOpts()
+		clientOpts.DefaultConnectionOptions.JumboFrameSize = 1024 * 1024
+		client := ts.NewClient(clientOpts)
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		conn, err := client.Connect(ctx, ts.HostPort())
+		require.NoError(t, err, "Connect failed")
+
+		// Relays don't use jumbo frames, so calls through a relay fall back
+		// to standard frames.
+		wantSize := 1024 * 1024
+		if ts.HasRelay() {
+			wantSize = MaxFrameSize
+		}
+		assert.Equal(t, wantSize, conn.MaxFrameSize(), "Unexpected max frame size")
+
+		arg3 := testutils.RandBytes(2 * 1024 * 1024)
+		_, gotArg3, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, arg3)
+		require.NoError(t, err, "Call failed")
+		assert.True(t, bytes.Equal(arg3, gotArg3), "Unexpected arg3")
+	})
+}
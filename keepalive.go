@@ -0,0 +1,188 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	_defaultKeepAliveTimeout            = time.Second
+	_defaultKeepAliveMaxUnansweredPings = 3
+)
+
+// errPeerDead is the error that in-flight calls fail with when keepalive pings
+// detect that the connection's peer is dead.
+var errPeerDead = errors.New("keepalive pings were not answered, peer is dead")
+
+// KeepAliveOptions configures keepalive pings on idle connections. Unlike
+// health checks, which ping on every interval, keepalive pings are only sent
+// when nothing has been received on the connection for the interval, so busy
+// connections don't pay for the extra pings.
+type KeepAliveOptions struct {
+	// Interval is how long a connection can go without receiving a call frame
+	// before a keepalive ping is sent. If this is zero, keepalives are disabled.
+	Interval time.Duration
+
+	// Timeout is how long to wait for a ping response.
+	// If no value is specified, it defaults to time.Second.
+	Timeout time.Duration
+
+	// MaxUnansweredPings is the number of consecutive keepalive pings that
+	// can go unanswered before the peer is considered dead. The connection is
+	// then closed, failing any in-flight calls with a network error rather
+	// than waiting for them to time out, and the channel's OnDeadPeer
+	// callback is called.
+	// If no value is specified, it defaults to 3.
+	MaxUnansweredPings int
+}
+
+func (ko KeepAliveOptions) enabled() bool {
+	return ko.Interval > 0
+}
+
+func (ko KeepAliveOptions) withDefaults() KeepAliveOptions {
+	if ko.Timeout == 0 {
+		ko.Timeout = _defaultKeepAliveTimeout
+	}
+	if ko.MaxUnansweredPings == 0 {
+		ko.MaxUnansweredPings = _defaultKeepAliveMaxUnansweredPings
+	}
+	return ko
+}
+
+// updateLastReceived marks when the last call frame was received. Pings are
+// ignored, so an idle connection is still pinged.
+func (c *Connection) updateLastReceived(frame *Frame) {
+	switch frame.Header.messageType {
+	case messageTypeCallReq, messageTypeCallReqContinue, messageTypeCallRes, messageTypeCallResContinue, messageTypeError:
+		c.lastReceived.Store(c.timeNow().UnixNano())
+	}
+}
+
+// keepAlive pings the connection whenever it has been idle for the keepalive
+// interval, and closes it once too many pings in a row go unanswered.
+// We accept connID on the stack so can more easily debug panics or leaked goroutines.
+func (c *Connection) keepAlive(connID uint32) {
+	defer close(c.keepAliveDone)
+
+	opts := c.opts.KeepAlive
+
+	ticker := c.timeTicker(opts.Interval)
+	defer ticker.Stop()
+
+	unanswered := 0
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.keepAliveCtx.Done():
+			return
+		}
+
+		lastReceived := c.lastReceived.Load()
+		if c.timeNow().Sub(time.Unix(0, lastReceived)) < opts.Interval {
+			unanswered = 0
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(c.keepAliveCtx, opts.Timeout)
+		err := c.ping(ctx)
+		cancel()
+		if err == nil {
+			unanswered = 0
+			continue
+		}
+
+		// If the ping failed because the connection closed or keepalives
+		// were stopped, we don't need to log or close the connection.
+		if GetSystemErrorCode(err) == ErrCodeCancelled || err == ErrInvalidConnectionState {
+			c.log.WithFields(ErrField(err)).Debug("Keepalive stopped.")
+			return
+		}
+
+		// A slow in-flight call may still be making progress, so a missed
+		// pong only counts if nothing else was received while we waited.
+		if c.lastReceived.Load() != lastReceived {
+			unanswered = 0
+			continue
+		}
+
+		unanswered++
+		c.log.WithFields(LogFields{
+			{"unansweredPings", unanswered},
+			ErrField(err),
+			{"maxUnansweredPings", opts.MaxUnansweredPings},
+		}...).Warn("Keepalive ping was not answered.")
+
+		if unanswered >= opts.MaxUnansweredPings {
+			c.statsReporter.IncCounter("connections.keepalive-closed", c.commonStatsTags, 1)
+			if c.events.OnPeerDead != nil {
+				c.events.OnPeerDead(c)
+			}
+
+			// The connection may be half-open, so in-flight calls would only
+			// fail once they time out. Fail them now so they can be retried.
+			// Keepalives are stopped first since connectionError waits for
+			// them to stop.
+			c.keepAliveQuit()
+			c.connectionError("keepalive", errPeerDead)
+			return
+		}
+	}
+}
+
+// peerDead is called when keepalive pings detect that a connection's peer is
+// dead. Outbound connections notify the peer for the host:port they were
+// created with, as well as the remote's host:port.
+func (ch *Channel) peerDead(c *Connection) {
+	if ch.onDeadPeer == nil {
+		return
+	}
+
+	notified := make(map[string]struct{}, 2)
+	for _, hostPort := range []string{c.outboundHP, c.remotePeerInfo.HostPort} {
+		if _, ok := notified[hostPort]; ok {
+			continue
+		}
+		if peer, ok := ch.RootPeers().Get(hostPort); ok {
+			notified[hostPort] = struct{}{}
+			ch.onDeadPeer(peer)
+		}
+	}
+}
+
+func (c *Connection) stopKeepAlive() {
+	// Keepalives are not enabled.
+	if c.keepAliveDone == nil {
+		return
+	}
+
+	// Best effort check to see if keepalives were stopped.
+	if c.keepAliveCtx.Err() != nil {
+		return
+	}
+	c.log.Debug("Stopping keepalives.")
+	c.keepAliveQuit()
+	<-c.keepAliveDone
+}
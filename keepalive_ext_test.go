@@ -0,0 +1,129 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"golang.org/x/net/context"
+)
+
+func TestKeepAliveClosesUnansweredConnection(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		var pingCount atomic.Int32
+		frameRelay, cancel := testutils.FrameRelay(t, ts.HostPort(), func(outgoing bool, f *Frame) *Frame {
+			if strings.Contains(f.Header.String(), "PingRes") {
+				// Drop ping responses so keepalive pings go unanswered.
+				pingCount.Inc()
+				return nil
+			}
+			return f
+		})
+		defer cancel()
+
+		started := make(chan struct{})
+		unblock := make(chan struct{})
+		defer close(unblock)
+		ts.RegisterFunc("block", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			close(started)
+			<-unblock
+			return &raw.Res{}, nil
+		})
+
+		deadPeers := make(chan string, 2)
+		clock := testutils.NewStubClock(time.Now())
+		ft := testutils.NewFakeTicker()
+		stats := newRecordingStatsReporter()
+		opts := testutils.NewOpts().
+			SetTimeNow(clock.Now).
+			SetTimeTicker(ft.New).
+			SetStatsReporter(stats).
+			SetKeepAlive(KeepAliveOptions{
+				Interval:           time.Second,
+				Timeout:            testutils.Timeout(50 * time.Millisecond),
+				MaxUnansweredPings: 2,
+			}).
+			AddLogFilter("Keepalive ping was not answered.", 2)
+		opts.OnDeadPeer = func(p *Peer) { deadPeers <- p.HostPort() }
+		client := ts.NewClient(opts)
+
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		conn, err := client.RootPeers().GetOrAdd(frameRelay).GetConnection(ctx)
+		require.NoError(t, err, "Failed to get connection")
+
+		// The connection was just created, so it isn't idle yet.
+		for i := 0; i < 3; i++ {
+			ft.Tick()
+		}
+		assert.Equal(t, int32(0), pingCount.Load(), "No pings should be sent on a connection that isn't idle")
+
+		// Start a call that's in-flight when the peer is found to be dead.
+		callErr := make(chan error, 1)
+		go func() {
+			ctx, cancel := NewContext(testutils.Timeout(5 * time.Second))
+			defer cancel()
+			_, _, _, err := raw.Call(ctx, client, frameRelay, ts.ServiceName(), "block", nil, nil)
+			callErr <- err
+		}()
+		<-started
+
+		clock.Elapse(2 * time.Second)
+		ft.Tick()
+		require.True(t, testutils.WaitFor(time.Second, func() bool {
+			return pingCount.Load() == 1
+		}), "Expected a keepalive ping on an idle connection")
+		assert.True(t, conn.IsActive(), "Connection should stay active after a single unanswered ping")
+
+		ft.Tick()
+		require.True(t, testutils.WaitFor(time.Second, func() bool {
+			return !conn.IsActive()
+		}), "Connection should be closed after too many unanswered pings")
+		assert.Equal(t, int64(1), stats.getCount("connections.keepalive-closed", client.StatsTags()),
+			"Expected keepalive close to be reported")
+
+		select {
+		case err := <-callErr:
+			assert.Equal(t, ErrCodeNetwork, GetSystemErrorCode(err), "In-flight call should fail with a network error")
+		case <-time.After(testutils.Timeout(time.Second)):
+			t.Fatal("In-flight call was not failed when the peer was found to be dead")
+		}
+
+		select {
+		case hostPort := <-deadPeers:
+			assert.Equal(t, frameRelay, hostPort, "Unexpected dead peer")
+		case <-time.After(testutils.Timeout(time.Second)):
+			t.Fatal("OnDeadPeer was not called")
+		}
+	})
+}
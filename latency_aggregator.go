@@ -0,0 +1,174 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// _maxLatencySamples is the maximum number of samples retained per
+// (service, method) by the outbound latency aggregator. Once it's reached,
+// the oldest samples are dropped even if they are still within the window.
+const _maxLatencySamples = 10000
+
+// LatencySnapshot contains percentiles of the latencies observed within the
+// latency window.
+type LatencySnapshot struct {
+	// Count is the number of calls within the window.
+	Count int
+
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+type latencyKey struct {
+	service string
+	method  string
+}
+
+type latencySample struct {
+	at      time.Time
+	latency time.Duration
+}
+
+// latencySamples are the samples for a single (service, method), oldest first.
+type latencySamples struct {
+	samples []latencySample
+	// start is the index of the oldest sample that has not been dropped.
+	start int
+}
+
+func (s *latencySamples) add(sample latencySample) {
+	s.samples = append(s.samples, sample)
+	if len(s.samples)-s.start > _maxLatencySamples {
+		s.start++
+	}
+}
+
+// prune drops samples that were recorded at or before cutoff.
+func (s *latencySamples) prune(cutoff time.Time) {
+	for s.start < len(s.samples) && !s.samples[s.start].at.After(cutoff) {
+		s.start++
+	}
+
+	// Compact once half of the slice has been dropped, so pruning is amortized.
+	if s.start > 0 && s.start >= len(s.samples)/2 {
+		n := copy(s.samples, s.samples[s.start:])
+		s.samples = s.samples[:n]
+		s.start = 0
+	}
+}
+
+func (s *latencySamples) len() int {
+	return len(s.samples) - s.start
+}
+
+// latencyAggregator maintains the latencies of outbound calls within a sliding
+// window, per (service, method).
+type latencyAggregator struct {
+	sync.Mutex
+
+	window  time.Duration
+	timeNow func() time.Time
+	byKey   map[latencyKey]*latencySamples
+}
+
+func newLatencyAggregator(timeNow func() time.Time, window time.Duration) *latencyAggregator {
+	if window <= 0 {
+		return nil
+	}
+	return &latencyAggregator{
+		window:  window,
+		timeNow: timeNow,
+		byKey:   make(map[latencyKey]*latencySamples),
+	}
+}
+
+func (a *latencyAggregator) record(key latencyKey, latency time.Duration) {
+	now := a.timeNow()
+
+	a.Lock()
+	samples, ok := a.byKey[key]
+	if !ok {
+		samples = &latencySamples{}
+		a.byKey[key] = samples
+	}
+	samples.prune(now.Add(-a.window))
+	samples.add(latencySample{at: now, latency: latency})
+	a.Unlock()
+}
+
+func (a *latencyAggregator) snapshot(key latencyKey) LatencySnapshot {
+	cutoff := a.timeNow().Add(-a.window)
+
+	a.Lock()
+	samples, ok := a.byKey[key]
+	if !ok {
+		a.Unlock()
+		return LatencySnapshot{}
+	}
+	samples.prune(cutoff)
+	if samples.len() == 0 {
+		delete(a.byKey, key)
+		a.Unlock()
+		return LatencySnapshot{}
+	}
+
+	latencies := make([]time.Duration, 0, samples.len())
+	for _, s := range samples.samples[samples.start:] {
+		latencies = append(latencies, s.latency)
+	}
+	a.Unlock()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return LatencySnapshot{
+		Count: len(latencies),
+		P50:   percentile(latencies, 0.50),
+		P90:   percentile(latencies, 0.90),
+		P99:   percentile(latencies, 0.99),
+		Max:   latencies[len(latencies)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile q of the sorted latencies.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	rank := int(math.Ceil(q * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// OutboundLatency returns percentiles of the latency of outbound calls to the
+// given service and method that completed within the OutboundLatencyWindow.
+// The latency includes any retries made by RunWithRetry. An empty snapshot is
+// returned if there were no calls, or if OutboundLatencyWindow is not set.
+func (ch *Channel) OutboundLatency(service, method string) LatencySnapshot {
+	if ch.latencies == nil {
+		return LatencySnapshot{}
+	}
+	return ch.latencies.snapshot(latencyKey{service, method})
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLatencyAggregator(window time.Duration) (*latencyAggregator, *time.Time) {
+	now := time.Unix(1000, 0)
+	return newLatencyAggregator(func() time.Time { return now }, window), &now
+}
+
+func TestLatencyAggregatorDisabled(t *testing.T) {
+	assert.Nil(t, newLatencyAggregator(time.Now, 0), "Expected no aggregator without a window")
+}
+
+func TestLatencyAggregatorPercentiles(t *testing.T) {
+	agg, _ := newTestLatencyAggregator(time.Minute)
+	k := latencyKey{"svc", "method"}
+
+	// Record 1ms to 1000ms in a random order.
+	for _, i := range rand.Perm(1000) {
+		agg.record(k, time.Duration(i+1)*time.Millisecond)
+	}
+
+	assert.Equal(t, LatencySnapshot{
+		Count: 1000,
+		P50:   500 * time.Millisecond,
+		P90:   900 * time.Millisecond,
+		P99:   990 * time.Millisecond,
+		Max:   1000 * time.Millisecond,
+	}, agg.snapshot(k), "Unexpected percentiles")
+	assert.Equal(t, LatencySnapshot{}, agg.snapshot(latencyKey{"svc", "other"}), "Expected no latencies for other methods")
+}
+
+func TestLatencyAggregatorWindow(t *testing.T) {
+	agg, now := newTestLatencyAggregator(time.Minute)
+	k := latencyKey{"svc", "method"}
+
+	agg.record(k, time.Second)
+	*now = now.Add(30 * time.Second)
+	agg.record(k, time.Millisecond)
+	assert.Equal(t, 2, agg.snapshot(k).Count, "Expected both samples within the window")
+	assert.Equal(t, time.Second, agg.snapshot(k).Max, "Unexpected max")
+
+	*now = now.Add(30 * time.Second)
+	snapshot := agg.snapshot(k)
+	assert.Equal(t, 1, snapshot.Count, "Expected oldest sample to leave the window")
+	assert.Equal(t, time.Millisecond, snapshot.Max, "Unexpected max")
+
+	*now = now.Add(30 * time.Second)
+	assert.Equal(t, LatencySnapshot{}, agg.snapshot(k), "Expected all samples to leave the window")
+}
+
+func TestLatencyAggregatorMaxSamples(t *testing.T) {
+	agg, _ := newTestLatencyAggregator(time.Minute)
+	k := latencyKey{"svc", "method"}
+
+	agg.record(k, time.Hour)
+	for i := 0; i < _maxLatencySamples; i++ {
+		agg.record(k, time.Millisecond)
+	}
+
+	snapshot := agg.snapshot(k)
+	assert.Equal(t, _maxLatencySamples, snapshot.Count, "Expected samples to be capped")
+	assert.Equal(t, time.Millisecond, snapshot.Max, "Expected oldest sample to be dropped")
+}
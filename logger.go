@@ -24,6 +24,9 @@ import (
 	"fmt"
 	"io"
 	"time"
+
+	"github.com/uber-go/atomic"
+	"golang.org/x/net/context"
 )
 
 import (
@@ -81,6 +84,24 @@ func ErrField(err error) LogField {
 // LogFields is a list of LogFields used to pass additional information to the logger.
 type LogFields []LogField
 
+// CallLogFields returns fields describing the inbound call that ctx was
+// created for: the service, method, caller, remote peer and call ID. Handlers
+// can add these to their logger using WithFields. It returns nil if ctx is not
+// for an inbound call.
+func CallLogFields(ctx context.Context) LogFields {
+	call, ok := CurrentCall(ctx).(*InboundCall)
+	if !ok {
+		return nil
+	}
+	return LogFields{
+		{"service", call.ServiceName()},
+		{"method", call.MethodString()},
+		{"caller", call.CallerName()},
+		{"remotePeer", call.RemotePeer().HostPort},
+		{"callID", call.mex.msgID},
+	}
+}
+
 // NullLogger is a logger that emits nowhere
 var NullLogger Logger = nullLogger{}
 
@@ -161,58 +182,88 @@ const (
 	LogLevelFatal
 )
 
+// LogLevelVar is a LogLevel that can be changed at runtime, such as from an
+// admin endpoint. It's safe for concurrent use.
+type LogLevelVar struct {
+	level atomic.Int32
+}
+
+// NewLogLevelVar returns a LogLevelVar set to level.
+func NewLogLevelVar(level LogLevel) *LogLevelVar {
+	v := &LogLevelVar{}
+	v.Set(level)
+	return v
+}
+
+// Level returns the current level.
+func (v *LogLevelVar) Level() LogLevel {
+	return LogLevel(v.level.Load())
+}
+
+// Set changes the level.
+func (v *LogLevelVar) Set(level LogLevel) {
+	v.level.Store(int32(level))
+}
+
 type levelLogger struct {
 	logger Logger
-	level  LogLevel
+	level  *LogLevelVar
 }
 
 // NewLevelLogger returns a logger that only logs messages with a minimum of level.
 func NewLevelLogger(logger Logger, level LogLevel) Logger {
-	return levelLogger{logger, level}
+	return levelLogger{logger, NewLogLevelVar(level)}
+}
+
+// NewLevelVarLogger returns a logger that only logs messages with a minimum
+// of the level in v. Changes to v apply to the logger, and to any loggers
+// created from it using WithFields.
+func NewLevelVarLogger(logger Logger, v *LogLevelVar) Logger {
+	return levelLogger{logger, v}
 }
 
 func (l levelLogger) Enabled(level LogLevel) bool {
-	return l.level <= level
+	return l.level.Level() <= level
 }
 
 func (l levelLogger) Fatal(msg string) {
-	if l.level <= LogLevelFatal {
+	if l.Enabled(LogLevelFatal) {
 		l.logger.Fatal(msg)
 	}
 }
 
 func (l levelLogger) Error(msg string) {
-	if l.level <= LogLevelError {
+	if l.Enabled(LogLevelError) {
 		l.logger.Error(msg)
 	}
 }
 
 func (l levelLogger) Warn(msg string) {
-	if l.level <= LogLevelWarn {
+	if l.Enabled(LogLevelWarn) {
 		l.logger.Warn(msg)
 	}
 }
 
 func (l levelLogger) Infof(msg string, args ...interface{}) {
-	if l.level <= LogLevelInfo {
+	if l.Enabled(LogLevelInfo) {
 		l.logger.Infof(msg, args...)
 	}
 }
 
 func (l levelLogger) Info(msg string) {
-	if l.level <= LogLevelInfo {
+	if l.Enabled(LogLevelInfo) {
 		l.logger.Info(msg)
 	}
 }
 
 func (l levelLogger) Debugf(msg string, args ...interface{}) {
-	if l.level <= LogLevelDebug {
+	if l.Enabled(LogLevelDebug) {
 		l.logger.Debugf(msg, args...)
 	}
 }
 
 func (l levelLogger) Debug(msg string) {
-	if l.level <= LogLevelDebug {
+	if l.Enabled(LogLevelDebug) {
 		l.logger.Debug(msg)
 	}
 }
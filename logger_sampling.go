@@ -0,0 +1,171 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	_defaultLogSamplingInterval = time.Second
+	_defaultLogSamplingFirst    = 10
+)
+
+// LogSamplingOptions configures sampling of repetitive log messages, such as
+// errors logged for every connection during a storm of connection resets.
+// Messages are sampled separately for each level and message, and fatal
+// messages are never sampled.
+type LogSamplingOptions struct {
+	// Interval is the period that messages are counted over.
+	// If no value is specified, it defaults to time.Second.
+	Interval time.Duration
+
+	// First is the number of times each message is logged per interval.
+	// If no value is specified, it defaults to 10.
+	First int
+
+	// Thereafter is the rate at which messages are logged once First have
+	// been logged in the interval, e.g. 100 logs every 100th message. If this
+	// is zero, no more messages are logged until the next interval.
+	Thereafter int
+
+	// TimeNow is used to get the current time. It defaults to time.Now.
+	TimeNow func() time.Time
+}
+
+func (o LogSamplingOptions) withDefaults() LogSamplingOptions {
+	if o.Interval == 0 {
+		o.Interval = _defaultLogSamplingInterval
+	}
+	if o.First == 0 {
+		o.First = _defaultLogSamplingFirst
+	}
+	if o.TimeNow == nil {
+		o.TimeNow = time.Now
+	}
+	return o
+}
+
+type logSampleKey struct {
+	level LogLevel
+	msg   string
+}
+
+// logSampler counts messages to decide which are logged. It's shared by all
+// loggers created from a sampled logger.
+type logSampler struct {
+	opts LogSamplingOptions
+
+	mu      sync.Mutex
+	resetAt time.Time
+	counts  map[logSampleKey]int
+}
+
+// allow returns whether a message should be logged.
+func (s *logSampler) allow(level LogLevel, msg string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Counts are reset every interval, which also bounds the memory used by
+	// messages that are no longer logged.
+	if now := s.opts.TimeNow(); !now.Before(s.resetAt) {
+		s.counts = make(map[logSampleKey]int)
+		s.resetAt = now.Add(s.opts.Interval)
+	}
+
+	key := logSampleKey{level, msg}
+	n := s.counts[key] + 1
+	s.counts[key] = n
+	if n <= s.opts.First {
+		return true
+	}
+	return s.opts.Thereafter > 0 && (n-s.opts.First)%s.opts.Thereafter == 0
+}
+
+type sampledLogger struct {
+	logger  Logger
+	sampler *logSampler
+}
+
+// NewSampledLogger returns a logger that samples repetitive messages, logging
+// only the first few of each message in every interval.
+func NewSampledLogger(logger Logger, opts LogSamplingOptions) Logger {
+	return sampledLogger{
+		logger:  logger,
+		sampler: &logSampler{opts: opts.withDefaults()},
+	}
+}
+
+func (l sampledLogger) Enabled(level LogLevel) bool {
+	return l.logger.Enabled(level)
+}
+
+func (l sampledLogger) Fatal(msg string) {
+	l.logger.Fatal(msg)
+}
+
+func (l sampledLogger) Error(msg string) {
+	if l.sampler.allow(LogLevelError, msg) {
+		l.logger.Error(msg)
+	}
+}
+
+func (l sampledLogger) Warn(msg string) {
+	if l.sampler.allow(LogLevelWarn, msg) {
+		l.logger.Warn(msg)
+	}
+}
+
+func (l sampledLogger) Infof(msg string, args ...interface{}) {
+	if l.sampler.allow(LogLevelInfo, msg) {
+		l.logger.Infof(msg, args...)
+	}
+}
+
+func (l sampledLogger) Info(msg string) {
+	if l.sampler.allow(LogLevelInfo, msg) {
+		l.logger.Info(msg)
+	}
+}
+
+func (l sampledLogger) Debugf(msg string, args ...interface{}) {
+	if l.sampler.allow(LogLevelDebug, msg) {
+		l.logger.Debugf(msg, args...)
+	}
+}
+
+func (l sampledLogger) Debug(msg string) {
+	if l.sampler.allow(LogLevelDebug, msg) {
+		l.logger.Debug(msg)
+	}
+}
+
+func (l sampledLogger) Fields() LogFields {
+	return l.logger.Fields()
+}
+
+func (l sampledLogger) WithFields(fields ...LogField) Logger {
+	return sampledLogger{
+		logger:  l.logger.WithFields(fields...),
+		sampler: l.sampler,
+	}
+}
@@ -24,10 +24,16 @@ import (
 	"bytes"
 	"errors"
 	"testing"
+	"time"
 
 	. "github.com/uber/tchannel-go"
 
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 func field(k string, v interface{}) LogField {
@@ -156,3 +162,82 @@ func TestLevelLogger(t *testing.T) {
 		assert.Equal(t, expectedLines[level], bytes.Count(buf.Bytes(), []byte{'\n'}))
 	}
 }
+
+func TestLevelVarLogger(t *testing.T) {
+	var buf bytes.Buffer
+	levelVar := NewLogLevelVar(LogLevelWarn)
+	logger := NewLevelVarLogger(NewLogger(&buf), levelVar)
+	fieldsLogger := logger.WithFields(field("key", "value"))
+
+	logger.Info("info")
+	fieldsLogger.Info("info")
+	assert.Equal(t, 0, buf.Len(), "Info should not be logged at warn level")
+
+	levelVar.Set(LogLevelInfo)
+	assert.Equal(t, LogLevelInfo, levelVar.Level())
+	assert.True(t, fieldsLogger.Enabled(LogLevelInfo), "Level change should apply to derived loggers")
+	logger.Info("info")
+	fieldsLogger.Info("info")
+	assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte{'\n'}))
+}
+
+func TestSampledLogger(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Unix(1000, 0)
+	logger := NewSampledLogger(NewLogger(&buf), LogSamplingOptions{
+		First:      2,
+		Thereafter: 3,
+		TimeNow:    func() time.Time { return now },
+	})
+	fieldsLogger := logger.WithFields(field("key", "value"))
+
+	lines := func() int {
+		n := bytes.Count(buf.Bytes(), []byte{'\n'})
+		buf.Reset()
+		return n
+	}
+
+	for i := 0; i < 9; i++ {
+		logger.Warn("warn")
+	}
+	// The first 2, then every 3rd after that (5th and 8th).
+	assert.Equal(t, 4, lines(), "Unexpected number of sampled messages")
+
+	// Messages are sampled separately, but derived loggers share the counts.
+	fieldsLogger.Warn("warn")
+	logger.Error("warn")
+	logger.Warn("other")
+	assert.Equal(t, 2, lines(), "Unexpected number of sampled messages")
+
+	now = now.Add(time.Second)
+	fieldsLogger.Warn("warn")
+	assert.Equal(t, 1, lines(), "Counts should reset after the interval")
+}
+
+func TestCallLogFields(t *testing.T) {
+	assert.Nil(t, CallLogFields(context.Background()), "Expected no fields outside of a call")
+
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		fieldsC := make(chan LogFields, 1)
+		ts.RegisterFunc("fields", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			fieldsC <- CallLogFields(ctx)
+			return &raw.Res{}, nil
+		})
+
+		client := ts.NewClient(nil)
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "fields", nil, nil)
+		require.NoError(t, err, "Call failed")
+
+		fields := make(map[string]interface{})
+		for _, f := range <-fieldsC {
+			fields[f.Key] = f.Value
+		}
+		assert.Equal(t, ts.ServiceName(), fields["service"])
+		assert.Equal(t, "fields", fields["method"])
+		assert.Equal(t, client.ServiceName(), fields["caller"])
+		assert.NotEmpty(t, fields["remotePeer"])
+		assert.Contains(t, fields, "callID")
+	})
+}
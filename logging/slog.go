@@ -0,0 +1,126 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+//go:build go1.21
+// +build go1.21
+
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/uber/tchannel-go"
+)
+
+// slogLevelFatal is the level that fatal messages are logged at, since slog
+// doesn't have a fatal level.
+const slogLevelFatal = slog.LevelError + 4
+
+type slogLogger struct {
+	logger *slog.Logger
+	fields tchannel.LogFields
+}
+
+// NewSlogLogger returns a tchannel.Logger that logs to the given slog logger.
+// Fields added using WithFields are logged as slog attributes. Fatal messages
+// are logged at slog.LevelError+4, after which the process exits.
+func NewSlogLogger(logger *slog.Logger) tchannel.Logger {
+	return slogLogger{logger: logger}
+}
+
+func (l slogLogger) Enabled(level tchannel.LogLevel) bool {
+	return l.logger.Enabled(context.Background(), slogLevel(level))
+}
+
+func (l slogLogger) Fatal(msg string) {
+	l.log(slogLevelFatal, msg)
+	os.Exit(1)
+}
+
+func (l slogLogger) Error(msg string) { l.log(slog.LevelError, msg) }
+func (l slogLogger) Warn(msg string)  { l.log(slog.LevelWarn, msg) }
+func (l slogLogger) Info(msg string)  { l.log(slog.LevelInfo, msg) }
+func (l slogLogger) Debug(msg string) { l.log(slog.LevelDebug, msg) }
+
+func (l slogLogger) Infof(msg string, args ...interface{}) {
+	if l.logger.Enabled(context.Background(), slog.LevelInfo) {
+		l.log(slog.LevelInfo, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l slogLogger) Debugf(msg string, args ...interface{}) {
+	if l.logger.Enabled(context.Background(), slog.LevelDebug) {
+		l.log(slog.LevelDebug, fmt.Sprintf(msg, args...))
+	}
+}
+
+// log logs a record with the source set to the adapter's caller.
+func (l slogLogger) log(level slog.Level, msg string) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	// Skip runtime.Callers, log, and the Logger method.
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	_ = l.logger.Handler().Handle(ctx, r)
+}
+
+func (l slogLogger) Fields() tchannel.LogFields {
+	return l.fields
+}
+
+func (l slogLogger) WithFields(fields ...tchannel.LogField) tchannel.Logger {
+	args := make([]interface{}, len(fields))
+	for i, f := range fields {
+		args[i] = slog.Any(f.Key, f.Value)
+	}
+
+	// Copy the fields so loggers created from the same parent don't share them.
+	newFields := make(tchannel.LogFields, 0, len(l.fields)+len(fields))
+	newFields = append(newFields, l.fields...)
+	newFields = append(newFields, fields...)
+	return slogLogger{
+		logger: l.logger.With(args...),
+		fields: newFields,
+	}
+}
+
+func slogLevel(level tchannel.LogLevel) slog.Level {
+	switch level {
+	case tchannel.LogLevelAll, tchannel.LogLevelDebug:
+		return slog.LevelDebug
+	case tchannel.LogLevelInfo:
+		return slog.LevelInfo
+	case tchannel.LogLevelWarn:
+		return slog.LevelWarn
+	case tchannel.LogLevelError:
+		return slog.LevelError
+	default:
+		return slogLevelFatal
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+//go:build go1.21
+// +build go1.21
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/uber/tchannel-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true, Level: slog.LevelInfo})
+	logger := NewSlogLogger(slog.New(handler))
+
+	assert.False(t, logger.Enabled(tchannel.LogLevelDebug), "Debug should be disabled")
+	assert.True(t, logger.Enabled(tchannel.LogLevelInfo), "Info should be enabled")
+	assert.True(t, logger.Enabled(tchannel.LogLevelFatal), "Fatal should be enabled")
+
+	fieldsLogger := logger.WithFields(tchannel.LogField{Key: "key", Value: "value"})
+	fieldsLogger.Debug("debug")
+	fieldsLogger.Infof("inf%v", "o")
+	logger.Warn("warn")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2, "Unexpected number of lines")
+
+	var entry struct {
+		Level  string `json:"level"`
+		Msg    string `json:"msg"`
+		Key    string `json:"key"`
+		Source struct {
+			File string `json:"file"`
+		} `json:"source"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry), "Failed to parse entry")
+	assert.Equal(t, "INFO", entry.Level)
+	assert.Equal(t, "info", entry.Msg)
+	assert.Equal(t, "value", entry.Key)
+	assert.True(t, strings.HasSuffix(entry.Source.File, "slog_test.go"), "Source should be the caller, got %v", entry.Source.File)
+
+	assert.Contains(t, lines[1], `"level":"WARN"`)
+	assert.NotContains(t, lines[1], `"key"`, "Parent logger should not have fields")
+
+	assert.Equal(t, tchannel.LogFields{{Key: "key", Value: "value"}}, fieldsLogger.Fields())
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package logging
+
+import (
+	"testing"
+
+	"github.com/uber/tchannel-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := NewZapLogger(zap.New(core))
+
+	assert.False(t, logger.Enabled(tchannel.LogLevelDebug), "Debug should be disabled")
+	assert.True(t, logger.Enabled(tchannel.LogLevelInfo), "Info should be enabled")
+	assert.True(t, logger.Enabled(tchannel.LogLevelError), "Error should be enabled")
+
+	fieldsLogger := logger.WithFields(tchannel.LogField{Key: "key", Value: "value"})
+	fieldsLogger.Debug("debug")
+	fieldsLogger.Infof("inf%v", "o")
+	fieldsLogger.Warn("warn")
+	logger.Error("error")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 3, "Unexpected number of entries")
+	assert.Equal(t, "info", entries[0].Message)
+	assert.Equal(t, zapcore.WarnLevel, entries[1].Level)
+	assert.Equal(t, map[string]interface{}{"key": "value"}, entries[1].ContextMap())
+	assert.Empty(t, entries[2].Context, "Parent logger should not have fields")
+
+	assert.Equal(t, tchannel.LogFields{{Key: "key", Value: "value"}}, fieldsLogger.Fields())
+	assert.Empty(t, logger.Fields())
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/uber-go/atomic"
+)
+
+const defaultHeapCheckInterval = time.Second
+
+// memoryPressure periodically samples the heap size, and tracks whether the
+// heap is over the configured threshold, in which case inbound calls are shed.
+type memoryPressure struct {
+	log           Logger
+	maxHeapSize   uint64
+	checkInterval time.Duration
+	heapSize      func() uint64
+	timeTicker    func(time.Duration) *time.Ticker
+
+	overThreshold atomic.Bool
+	stopped       atomic.Bool
+	stopCh        chan struct{}
+}
+
+// startMemoryPressure starts sampling the heap size if MaxHeapSize is set.
+// It returns nil if memory pressure shedding is disabled.
+func startMemoryPressure(log Logger, timeTicker func(time.Duration) *time.Ticker, opts *ChannelOptions) *memoryPressure {
+	if opts.MaxHeapSize == 0 {
+		return nil
+	}
+
+	mp := &memoryPressure{
+		log:           log,
+		maxHeapSize:   opts.MaxHeapSize,
+		checkInterval: opts.HeapCheckInterval,
+		heapSize:      opts.HeapSize,
+		timeTicker:    timeTicker,
+		stopCh:        make(chan struct{}),
+	}
+	if mp.checkInterval <= 0 {
+		mp.checkInterval = defaultHeapCheckInterval
+	}
+	if mp.heapSize == nil {
+		mp.heapSize = readHeapSize
+	}
+
+	log.WithFields(
+		LogField{"maxHeapSize", mp.maxHeapSize},
+		LogField{"heapCheckInterval", mp.checkInterval},
+	).Info("Starting heap size poller.")
+
+	mp.check()
+	go mp.pollerLoop()
+	return mp
+}
+
+func readHeapSize() uint64 {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return memStats.HeapAlloc
+}
+
+// shouldShed returns whether inbound calls should be rejected due to memory pressure.
+func (mp *memoryPressure) shouldShed() bool {
+	return mp != nil && mp.overThreshold.Load()
+}
+
+// Stop stops sampling the heap size.
+func (mp *memoryPressure) Stop() {
+	if mp == nil || !mp.stopped.CAS(false, true) {
+		return
+	}
+	close(mp.stopCh)
+}
+
+func (mp *memoryPressure) pollerLoop() {
+	ticker := mp.timeTicker(mp.checkInterval)
+
+	for {
+		select {
+		case <-ticker.C:
+			mp.check()
+		case <-mp.stopCh:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func (mp *memoryPressure) check() {
+	heapSize := mp.heapSize()
+	over := heapSize > mp.maxHeapSize
+	if mp.overThreshold.Swap(over) == over {
+		return
+	}
+
+	log := mp.log.WithFields(
+		LogField{"heapSize", heapSize},
+		LogField{"maxHeapSize", mp.maxHeapSize},
+	)
+	if over {
+		log.Warn("Heap size is over the threshold, shedding inbound calls.")
+	} else {
+		log.Info("Heap size is under the threshold, no longer shedding inbound calls.")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/atomic"
+)
+
+func TestMemoryPressureShedsInboundCalls(t *testing.T) {
+	const maxHeapSize = 1024 * 1024
+
+	heapSize := atomic.NewUint64(0)
+	ft := testutils.NewFakeTicker()
+	opts := testutils.NewOpts().
+		NoRelay().
+		SetTimeTicker(ft.New).
+		AddLogFilter("Heap size is over the threshold, shedding inbound calls.", 1)
+	opts.MaxHeapSize = maxHeapSize
+	opts.HeapSize = heapSize.Load
+
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		ts.Register(raw.Wrap(newTestHandler(t)), "echo")
+		client := ts.NewClient(nil)
+
+		call := func() error {
+			ctx, cancel := NewContext(time.Second)
+			defer cancel()
+
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+			return err
+		}
+
+		require.NoError(t, call(), "Call should succeed under the heap threshold")
+
+		heapSize.Store(2 * maxHeapSize)
+		ft.Tick()
+		var err error
+		assert.True(t, testutils.WaitFor(time.Second, func() bool {
+			err = call()
+			return err != nil
+		}), "Calls should be shed over the heap threshold")
+		assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Shed calls should fail with busy")
+
+		heapSize.Store(maxHeapSize / 2)
+		ft.Tick()
+		assert.True(t, testutils.WaitFor(time.Second, func() bool {
+			return call() == nil
+		}), "Calls should succeed once the heap is under the threshold")
+	})
+}
+
+func TestMemoryPressureDisabledByDefault(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	opts.HeapSize = func() uint64 { return 1 << 62 }
+
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		ts.Register(raw.Wrap(newTestHandler(t)), "echo")
+
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, ts.NewClient(nil), ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		assert.NoError(t, err, "Calls should not be shed when MaxHeapSize is not set")
+	})
+}
@@ -78,6 +78,24 @@ const (
 	InitParamTChannelLanguageVersion = "tchannel_language_version"
 	// InitParamTChannelVersion contains the library version.
 	InitParamTChannelVersion = "tchannel_version"
+	// InitParamCompression contains the connection-level compression the peer
+	// supports, or the compression agreed on in an init response.
+	InitParamCompression = "tchannel_compression"
+	// InitParamAuth contains the credentials used to authenticate the connection,
+	// sent by the connecting peer if it has an AuthProvider.
+	InitParamAuth = "tchannel_auth"
+	// InitParamChecksumTypes contains the comma-separated codes of the checksum
+	// types the peer supports that not all peers support, such as crc32c.
+	InitParamChecksumTypes = "tchannel_checksum_types"
+	// InitParamArgCompression contains the comma-separated names of the argument
+	// compression codecs the peer supports, or the codec agreed on in an init response.
+	InitParamArgCompression = "tchannel_arg_compression"
+	// InitParamFeatures contains the comma-separated optional protocol features
+	// the peer supports. See ProtocolFeature.
+	InitParamFeatures = "tchannel_features"
+	// InitParamMaxFrameSize contains the maximum frame size the peer can send
+	// and receive, if it supports jumbo frames.
+	InitParamMaxFrameSize = "tchannel_max_frame_size"
 )
 
 // initMessage is the base for messages in the initialization handshake
@@ -175,6 +193,35 @@ const (
 	// requested service. A relay may use the routing key over the service if
 	// it knows about traffic groups.
 	RoutingKey TransportHeaderName = "rk"
+
+	// BestEffort header marks a call as best-effort, meaning it is not on the
+	// critical path and may be shed before other calls when under load.
+	BestEffort TransportHeaderName = "be"
+
+	// Priority header sets the priority of a call, which determines how the
+	// call's frames are scheduled on a connection, including by relays.
+	Priority TransportHeaderName = "pri"
+
+	// IdempotencyKey header identifies retries of the same logical call, so
+	// servers with deduplication enabled can return the earlier result
+	// instead of running the handler again.
+	IdempotencyKey TransportHeaderName = "idempotency-key"
+
+	// NoRetry header may be set on an error response to tell the caller that
+	// the call must not be retried, regardless of the error code.
+	NoRetry TransportHeaderName = "no-retry"
+
+	// TraceParent header carries the W3C trace context of the caller's
+	// OpenTelemetry span, so relays and other intermediaries can read it
+	// without parsing the application headers.
+	TraceParent TransportHeaderName = "traceparent"
+
+	// TraceState header carries the W3C trace state accompanying TraceParent.
+	TraceState TransportHeaderName = "tracestate"
+
+	// AuthToken header carries a signed token, such as a JWT, that servers
+	// can use to authenticate the caller (see CallAuthorizer).
+	AuthToken TransportHeaderName = "auth"
 )
 
 // transportHeaders are passed as part of a CallReq/CallRes
@@ -285,6 +332,10 @@ type errorMessage struct {
 	errCode SystemErrCode
 	tracing Span
 	message string
+
+	// headers are optional transport headers written after the message.
+	// Peers that don't know about them ignore the trailing bytes.
+	headers transportHeaders
 }
 
 func (m *errorMessage) ID() uint32               { return m.id }
@@ -293,6 +344,10 @@ func (m *errorMessage) read(r *typed.ReadBuffer) error {
 	m.errCode = SystemErrCode(r.ReadSingleByte())
 	m.tracing.read(r)
 	m.message = r.ReadLen16String()
+	if r.BytesRemaining() > 0 {
+		m.headers = make(transportHeaders)
+		m.headers.read(r)
+	}
 	return r.Err()
 }
 
@@ -300,12 +355,19 @@ func (m *errorMessage) write(w *typed.WriteBuffer) error {
 	w.WriteSingleByte(byte(m.errCode))
 	m.tracing.write(w)
 	w.WriteLen16String(m.message)
+	if len(m.headers) > 0 {
+		m.headers.write(w)
+	}
 	return w.Err()
 }
 
 func (m errorMessage) AsSystemError() error {
 	// TODO(mmihic): Might be nice to return one of the well defined error types
-	return NewSystemError(m.errCode, m.message)
+	return SystemError{
+		code:    m.errCode,
+		msg:     m.message,
+		noRetry: m.headers[NoRetry] == "1",
+	}
 }
 
 // Error returns the error message from the converted
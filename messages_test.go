@@ -139,6 +139,17 @@ func TestErrorMessage(t *testing.T) {
 	assertRoundTrip(t, &m, &errorMessage{})
 }
 
+func TestErrorMessageHeaders(t *testing.T) {
+	m := errorMessage{
+		errCode: ErrCodeBusy,
+		message: "go away",
+		headers: transportHeaders{NoRetry: "1"},
+	}
+
+	assertRoundTrip(t, &m, &errorMessage{})
+	assert.True(t, m.AsSystemError().(SystemError).NoRetry(), "Expected no-retry hint on SystemError")
+}
+
 func assertRoundTrip(t *testing.T, expected message, actual message) {
 	w := typed.NewWriteBufferWithSize(1024)
 	require.Nil(t, expected.write(w), fmt.Sprintf("error writing message %v", expected.messageType()))
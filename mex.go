@@ -24,6 +24,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/uber/tchannel-go/typed"
 
@@ -37,6 +38,7 @@ var (
 	errMexSetShutdown      = errors.New("mexset has been shutdown")
 	errMexChannelFull      = NewSystemError(ErrCodeBusy, "cannot send frame to message exchange channel")
 	errUnexpectedFrameType = errors.New("unexpected frame received")
+	errTooManyFragments    = errors.New("call exceeded the maximum number of fragments")
 )
 
 const (
@@ -99,6 +101,22 @@ type messageExchange struct {
 
 	shutdownAtomic atomic.Bool
 	errChNotified  atomic.Bool
+
+	// continuations is the number of continuation fragments received.
+	continuations atomic.Int32
+
+	// lastActivity is the time (in unix nanos) that a frame was last sent or
+	// received for this exchange, used to detect stalled calls.
+	lastActivity atomic.Int64
+
+	// bandwidth limits the bytes sent and received by the call, and is nil
+	// if calls are not limited.
+	bandwidth *callBandwidth
+}
+
+// markActivity records that the exchange has made progress.
+func (mex *messageExchange) markActivity() {
+	mex.lastActivity.Store(mex.mexset.timeNow().UnixNano())
 }
 
 // checkError is called before waiting on the mex channels.
@@ -126,6 +144,7 @@ func (mex *messageExchange) forwardPeerFrame(frame *Frame) error {
 
 	select {
 	case mex.recvCh <- frame:
+		mex.markActivity()
 		return nil
 	case <-mex.ctx.Done():
 		// Note: One slow reader processing a large request could stall the connection.
@@ -172,6 +191,7 @@ func (mex *messageExchange) recvPeerFrame() (*Frame, error) {
 		if err := mex.checkFrame(frame); err != nil {
 			return nil, err
 		}
+		mex.markActivity()
 		return frame, nil
 	case <-mex.ctx.Done():
 		return nil, GetContextError(mex.ctx.Err())
@@ -269,8 +289,13 @@ type messageExchangeSet struct {
 	name       string
 	onRemoved  func()
 	onAdded    func()
+	timeNow    func() time.Time
 	sendChRefs sync.WaitGroup
 
+	// maxFragments is the maximum number of fragments an exchange can receive,
+	// or zero if the number of fragments is not limited.
+	maxFragments int
+
 	// maps are mutable, and are protected by the mutex.
 	exchanges        map[uint32]*messageExchange
 	expiredExchanges map[uint32]struct{}
@@ -284,6 +309,7 @@ func newMessageExchangeSet(log Logger, name string) *messageExchangeSet {
 		log:              log.WithFields(LogField{"exchange", name}),
 		exchanges:        make(map[uint32]*messageExchange),
 		expiredExchanges: make(map[uint32]struct{}),
+		timeNow:          time.Now,
 	}
 }
 
@@ -318,6 +344,7 @@ func (mexset *messageExchangeSet) newExchange(ctx context.Context, framePool Fra
 		mexset:    mexset,
 		framePool: framePool,
 	}
+	mex.markActivity()
 
 	mexset.Lock()
 	addErr := mexset.addExchange(mex)
@@ -444,10 +471,14 @@ func (mexset *messageExchangeSet) forwardPeerFrame(frame *Frame) error {
 		return nil
 	}
 
+	if mexset.exceedsMaxFragments(mex, frame) {
+		return errTooManyFragments
+	}
+
 	if err := mex.forwardPeerFrame(frame); err != nil {
 		mexset.log.WithFields(
 			LogField{"frameHeader", frame.Header.String()},
-			LogField{"frameSize", frame.Header.FrameSize()},
+			LogField{"frameSize", frame.Header.frameSize()},
 			LogField{"exchange", mexset.name},
 			ErrField(err),
 		).Info("Failed to forward frame.")
@@ -457,6 +488,22 @@ func (mexset *messageExchangeSet) forwardPeerFrame(frame *Frame) error {
 	return nil
 }
 
+// stalledExchanges returns the active exchanges that have not sent or received
+// a frame since the given time.
+func (mexset *messageExchangeSet) stalledExchanges(before time.Time) []*messageExchange {
+	beforeNanos := before.UnixNano()
+
+	var stalled []*messageExchange
+	mexset.RLock()
+	for _, mex := range mexset.exchanges {
+		if mex.lastActivity.Load() < beforeNanos {
+			stalled = append(stalled, mex)
+		}
+	}
+	mexset.RUnlock()
+	return stalled
+}
+
 // copyExchanges returns a copy of the exchanges if the exchange is active.
 // The caller must lock the mexset.
 func (mexset *messageExchangeSet) copyExchanges() (shutdown bool, exchanges map[uint32]*messageExchange) {
@@ -472,6 +519,23 @@ func (mexset *messageExchangeSet) copyExchanges() (shutdown bool, exchanges map[
 	return false, exchangesCopy
 }
 
+// exceedsMaxFragments records a continuation fragment for the exchange, and
+// returns whether the exchange has received more fragments than allowed.
+func (mexset *messageExchangeSet) exceedsMaxFragments(mex *messageExchange, frame *Frame) bool {
+	if mexset.maxFragments <= 0 {
+		return false
+	}
+
+	switch frame.Header.messageType {
+	case messageTypeCallReqContinue, messageTypeCallResContinue:
+	default:
+		return false
+	}
+
+	// The initial fragment is not forwarded, so it's not included in continuations.
+	return int(mex.continuations.Inc())+1 > mexset.maxFragments
+}
+
 // stopExchanges stops all message exchanges to unblock all waiters on the mex.
 // This should only be called on connection failures.
 func (mexset *messageExchangeSet) stopExchanges(err error) {
@@ -0,0 +1,83 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sync"
+
+	"github.com/uber-go/atomic"
+	"golang.org/x/net/context"
+)
+
+// Middleware wraps a Handler to run logic around every inbound call, such as
+// authentication, panic recovery, request logging or metrics. It applies to
+// all handlers on the channel, regardless of the arg scheme (raw, JSON,
+// Thrift) they were registered with.
+type Middleware func(Handler) Handler
+
+// middlewareChain is the handler used by connections to dispatch inbound
+// calls. It wraps the channel's root handler with the registered middleware.
+type middlewareChain struct {
+	sync.Mutex
+
+	root        Handler
+	middlewares []Middleware
+	composed    atomic.Value // Handler
+}
+
+func newMiddlewareChain(root Handler) *middlewareChain {
+	c := &middlewareChain{root: root}
+	c.composed.Store(handlerHolder{root})
+	return c
+}
+
+// handlerHolder allows Handlers of different concrete types to be stored in
+// an atomic.Value.
+type handlerHolder struct {
+	h Handler
+}
+
+func (c *middlewareChain) add(ms ...Middleware) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.middlewares = append(c.middlewares, ms...)
+	h := c.root
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		h = c.middlewares[i](h)
+	}
+	c.composed.Store(handlerHolder{h})
+}
+
+// Handle dispatches the call through the middleware to the root handler.
+func (c *middlewareChain) Handle(ctx context.Context, call *InboundCall) {
+	c.composed.Load().(handlerHolder).h.Handle(ctx, call)
+}
+
+// RegisterMiddleware adds middleware that wraps every inbound call handled by
+// the channel, including calls to alternate root handlers and to handlers
+// registered on any SubChannel. Middleware registered first is outermost, so
+// it runs before, and returns after, middleware registered later.
+// Middleware may be registered at any time; calls that are already being
+// handled are not affected.
+func (ch *Channel) RegisterMiddleware(ms ...Middleware) {
+	ch.middleware.add(ms...)
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/json"
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestMiddlewareOrderAcrossFormats(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	record := func(name string) Middleware {
+		return func(h Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, call *InboundCall) {
+				mu.Lock()
+				calls = append(calls, fmt.Sprintf("%v:%v:%v", name, call.Format(), call.MethodString()))
+				mu.Unlock()
+				h.Handle(ctx, call)
+			})
+		}
+	}
+
+	testutils.WithTestServer(t, testutils.NewOpts().NoRelay(), func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+		require.NoError(t, json.Register(ts.Server(), json.Handlers{
+			"json": func(ctx json.Context, arg map[string]string) (map[string]string, error) {
+				return arg, nil
+			},
+		}, nil))
+		ts.Server().RegisterMiddleware(record("first"), record("second"))
+
+		require.NoError(t, testutils.CallEcho(ts.NewClient(nil), ts.HostPort(), ts.ServiceName(), nil))
+
+		client := ts.NewClient(nil)
+		ctx, cancel := json.NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		var res map[string]string
+		peer := client.Peers().GetOrAdd(ts.HostPort())
+		require.NoError(t, json.CallPeer(ctx, peer, ts.ServiceName(), "json", map[string]string{"k": "v"}, &res))
+		assert.Equal(t, map[string]string{"k": "v"}, res)
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []string{
+			"first:raw:echo",
+			"second:raw:echo",
+			"first:json:json",
+			"second:json:json",
+		}, calls)
+	})
+}
+
+func TestMiddlewareRecoversPanics(t *testing.T) {
+	recoverPanics := func(h Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, call *InboundCall) {
+			defer func() {
+				if r := recover(); r != nil {
+					call.Response().SendSystemError(NewSystemError(ErrCodeUnexpected, "panic: %v", r))
+				}
+			}()
+			h.Handle(ctx, call)
+		})
+	}
+
+	testutils.WithTestServer(t, testutils.NewOpts().NoRelay(), func(ts *testutils.TestServer) {
+		ts.Server().RegisterMiddleware(recoverPanics)
+		testutils.RegisterFunc(ts.Server(), "panic", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			panic("boom")
+		})
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		_, _, _, err := raw.Call(ctx, ts.NewClient(nil), ts.HostPort(), ts.ServiceName(), "panic", nil, nil)
+		require.Error(t, err)
+		assert.Equal(t, ErrCodeUnexpected, GetSystemErrorCode(err))
+		assert.Contains(t, err.Error(), "panic: boom")
+	})
+}
@@ -28,6 +28,7 @@ import (
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
+	"github.com/uber-go/atomic"
 	"golang.org/x/net/context"
 )
 
@@ -54,10 +55,11 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 		return nil, ErrTimeoutRequired
 	}
 
-	// If the timeToLive is less than a millisecond, it will be encoded as 0 on
-	// the wire, hence we return a timeout immediately.
+	// The TTL sent is the time remaining until the deadline. If it's less than
+	// the minimum (which is at least a millisecond, as smaller TTLs are encoded
+	// as 0 on the wire), we return a timeout immediately.
 	timeToLive := deadline.Sub(now)
-	if timeToLive < time.Millisecond {
+	if timeToLive < c.opts.MinTimeToLive {
 		return nil, ErrTimeout
 	}
 
@@ -65,6 +67,10 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 		return nil, GetContextError(err)
 	}
 
+	if callOptions.BestEffort && c.sendBufferBackedUp() {
+		return nil, ErrSendBufferFull
+	}
+
 	if !c.pendingExchangeMethodAdd() {
 		// Connection is closed, no need to do anything.
 		return nil, ErrInvalidConnectionState
@@ -82,15 +88,26 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 		mex.shutdown()
 		return nil, ErrConnectionClosed
 	}
+	mex.bandwidth = c.bandwidth.newCall()
 
-	// Note: We don't verify number of transport headers as the library doesn't
-	// allow adding arbitrary headers. Ensure we never add >= 256 headers here.
+	// Note: The only arbitrary headers are baggage, which is limited by
+	// addBaggageHeaders. Ensure we never add >= 256 headers here.
 	headers := transportHeaders{
 		CallerName: c.localPeerInfo.ServiceName,
 	}
 	callOptions.setHeaders(headers)
+	disableCompression := callOptions.DisableCompression
 	if opts := currentCallOptions(ctx); opts != nil {
 		opts.overrideHeaders(headers)
+		disableCompression = disableCompression || opts.DisableCompression
+	}
+	if !c.SupportsFeature(FeaturePriority) {
+		// The peer doesn't schedule frames by priority, so don't send it.
+		delete(headers, Priority)
+	}
+	if err := addBaggageHeaders(ctx, headers); err != nil {
+		mex.shutdown()
+		return nil, err
 	}
 
 	call := new(OutboundCall)
@@ -102,8 +119,9 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 		Service:    serviceName,
 		TimeToLive: timeToLive,
 	}
+	endpoint := c.subChannels.endpointName(serviceName, methodName)
 	call.statsReporter = c.statsReporter
-	call.createStatsTags(c.commonStatsTags, callOptions, methodName)
+	call.createStatsTags(c.commonStatsTags, callOptions, endpoint)
 	call.log = c.log.WithFields(LogField{"Out-Call", requestID})
 
 	// TODO(mmihic): It'd be nice to do this without an fptr
@@ -116,6 +134,9 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 	}
 
 	call.contents = newFragmentingWriter(call.log, call, c.opts.ChecksumType.New())
+	call.compression = c.argCompression
+	call.uncompressed = disableCompression
+	call.priority = c.callPriority(headers)
 
 	response := new(OutboundCallResponse)
 	response.startedAt = now
@@ -123,7 +144,8 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 	response.requestState = callOptions.RequestState
 	response.mex = mex
 	response.log = c.log.WithFields(LogField{"Out-Response", requestID})
-	response.span = c.startOutboundSpan(ctx, serviceName, methodName, call, now)
+	response.span = c.startOutboundSpan(ctx, serviceName, endpoint, call, now)
+	response.traceSpan = c.startOutboundCallTrace(ctx, serviceName, endpoint, call, now)
 	response.messageForFragment = func(initial bool) message {
 		if initial {
 			return &response.callRes
@@ -132,14 +154,23 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 		return new(callResContinue)
 	}
 	response.contents = newFragmentingReader(response.log, response)
+	response.compression = c.argCompression
 	response.statsReporter = call.statsReporter
 	response.commonStatsTags = call.commonStatsTags
+	if c.latencies != nil {
+		response.latencies = c.latencies
+		response.latencyKey = latencyKey{serviceName, endpoint}
+	}
 
 	call.response = response
+	call.onFailed = response.callFailed
+	response.onFailed = response.callFailed
 
 	if err := call.writeMethod([]byte(methodName)); err != nil {
 		return nil, err
 	}
+	response.eventBus = c.eventBus
+	response.completedEvent = c.publishCallStarted(false /* inbound */, serviceName, methodName, now)
 	return call, nil
 }
 
@@ -156,6 +187,9 @@ func (c *Connection) handleCallRes(frame *Frame) bool {
 // forwarding the frame to the response channel waiting for it
 func (c *Connection) handleCallResContinue(frame *Frame) bool {
 	if err := c.outbound.forwardPeerFrame(frame); err != nil {
+		if err == errTooManyFragments {
+			c.tooManyFragments(frame)
+		}
 		return true
 	}
 	return false
@@ -234,8 +268,39 @@ type OutboundCallResponse struct {
 	startedAt       time.Time
 	timeNow         func() time.Time
 	span            opentracing.Span
+	traceSpan       CallTraceSpan
 	statsReporter   StatsReporter
 	commonStatsTags map[string]string
+
+	// latencies, if set, records the latency of the call under latencyKey.
+	latencies  *latencyAggregator
+	latencyKey latencyKey
+
+	// cancelCtx, if set, cancels the context created for the call's default
+	// timeout once the call completes or fails.
+	cancelCtx context.CancelFunc
+
+	// peer, if set, is the peer the call was made to, which records the
+	// latency of the call.
+	peer *Peer
+
+	// circuit, if set, is the peer's circuit for the service and method,
+	// which records the outcome of the call.
+	circuit    *circuit
+	circuitKey circuitKey
+
+	// appHeaders are application headers set by outbound interceptors, and
+	// onDone are called by interceptors once the call completes.
+	appHeaders map[string]string
+	onDone     []func(err error)
+
+	// ended is set once the outcome of the call has been recorded by callEnded.
+	ended atomic.Bool
+
+	// completedEvent, if set, is published to eventBus once the call
+	// completes. It's only set if there were subscribers when the call started.
+	eventBus       *eventBus
+	completedEvent *Event
 }
 
 // ApplicationError returns true if the call resulted in an application level error
@@ -289,6 +354,18 @@ func (c *Connection) handleError(frame *Frame) bool {
 		return true
 	}
 
+	if errMsg.id == invalidMessageID && errMsg.errCode == ErrCodeDeclined {
+		// The drain signal isn't an error, so it's not recorded as one.
+		c.log.WithFields(
+			LogField{"remotePeer", c.remotePeerInfo},
+			LogField{"errorMessage", errMsg.message},
+		).Info("Peer is draining, not starting new calls on the connection.")
+		c.remoteDraining.Store(true)
+		return true
+	}
+
+	c.recordError(errMsg.AsSystemError())
+
 	if errMsg.errCode == ErrCodeProtocol {
 		c.log.WithFields(
 			LogField{"remotePeer", c.remotePeerInfo},
@@ -339,12 +416,19 @@ func (response *OutboundCallResponse) doneReading(unexpected error) {
 		}
 		span.FinishWithOptions(opentracing.FinishOptions{FinishTime: now})
 	}
+	if span := response.traceSpan; span != nil {
+		span.End(now, unexpected, !isSuccess && lastAttempt)
+	}
 
 	latency := now.Sub(response.startedAt)
-	response.statsReporter.RecordTimer("outbound.calls.per-attempt.latency", response.commonStatsTags, latency)
+	response.callEnded(now, unexpected)
+	recordCallTimer(response.statsReporter, "outbound.calls.per-attempt.latency", response.commonStatsTags, latency, response.span)
 	if lastAttempt {
 		requestLatency := response.requestState.SinceStart(now, latency)
-		response.statsReporter.RecordTimer("outbound.calls.latency", response.commonStatsTags, requestLatency)
+		recordCallTimer(response.statsReporter, "outbound.calls.latency", response.commonStatsTags, requestLatency, response.span)
+		if response.latencies != nil {
+			response.latencies.record(response.latencyKey, requestLatency)
+		}
 	}
 	if retryCount := response.requestState.RetryCount(); retryCount > 0 {
 		retryTags := cloneTags(response.commonStatsTags)
@@ -366,6 +450,82 @@ func (response *OutboundCallResponse) doneReading(unexpected error) {
 	}
 
 	response.mex.shutdown()
+	response.stopDefaultTimeout()
+}
+
+// callFailed is called when writing the request or reading the response
+// fails, e.g. due to a timeout.
+func (response *OutboundCallResponse) callFailed(err error) {
+	response.callEnded(response.timeNow(), err)
+	response.stopDefaultTimeout()
+}
+
+// stopDefaultTimeout cancels the context created for the call's default
+// timeout, if any, so its timer doesn't outlive the call.
+func (response *OutboundCallResponse) stopDefaultTimeout() {
+	if response.cancelCtx != nil {
+		response.cancelCtx()
+	}
+}
+
+// callEnded records the outcome of the call once it ends, either when the
+// response has been read, or when the call fails. Only the first outcome is
+// recorded.
+func (response *OutboundCallResponse) callEnded(now time.Time, unexpected error) {
+	if response.ended.Swap(true) {
+		return
+	}
+
+	latency := now.Sub(response.startedAt)
+	if response.peer != nil {
+		response.peer.recordCall(now, latency, unexpected)
+		if strategy := response.peer.selectionStrategy; strategy != nil {
+			strategy.CallEnded(response.peer, latency, unexpected)
+		}
+	}
+	if response.circuit != nil {
+		outcome := circuitOutcomeOf(unexpected)
+		if unexpected == nil && response.ApplicationError() {
+			outcome = circuitIgnored
+		}
+		response.peer.circuitBreaker.record(response.circuitKey, response.circuit, outcome)
+	}
+	if e := response.completedEvent; e != nil {
+		e.Time = now
+		e.Latency = latency
+		e.ApplicationError = unexpected == nil && response.ApplicationError()
+		e.Err = unexpected
+		response.eventBus.publish(*e)
+	}
+	for _, f := range response.onDone {
+		f(unexpected)
+	}
+}
+
+// withDefaultTimeout returns a context with the given timeout if ctx has no
+// deadline, and the function to cancel it, which is nil if ctx is unchanged.
+func withDefaultTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, nil
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, nil
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// cancelWithCall ties cancel to the call: it's called once the call's response
+// has been read or the call fails, or immediately if the call could not be started.
+func cancelWithCall(call *OutboundCall, err error, cancel context.CancelFunc) (*OutboundCall, error) {
+	if cancel == nil {
+		return call, err
+	}
+	if err != nil {
+		cancel()
+		return call, err
+	}
+	call.response.cancelCtx = cancel
+	return call, nil
 }
 
 func validateCall(ctx context.Context, serviceName, methodName string, callOpts *CallOptions) error {
@@ -0,0 +1,94 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import "golang.org/x/net/context"
+
+// BeginCallFunc begins an outbound call to methodName, as SubChannel.BeginCall.
+type BeginCallFunc func(ctx context.Context, methodName string, callOptions *CallOptions) (*OutboundCall, error)
+
+// OutboundInterceptor wraps the BeginCall of a SubChannel to run logic around
+// outbound calls. An interceptor may change the context or call options
+// before calling next, fail the call by returning an error, add application
+// headers using OutboundCall.SetApplicationHeader, and be notified when the
+// call completes using OutboundCall.OnDone.
+type OutboundInterceptor func(next BeginCallFunc) BeginCallFunc
+
+// OutboundInterceptors returns a SubChannelOption that runs the given
+// interceptors around every call begun using the subchannel, replacing any
+// previously set. The first interceptor is outermost. Calls begun using
+// Channel.BeginCall or Peer.BeginCall are not intercepted.
+func OutboundInterceptors(interceptors ...OutboundInterceptor) SubChannelOption {
+	return func(s *SubChannel) {
+		s.Lock()
+		s.interceptors = append([]OutboundInterceptor(nil), interceptors...)
+		s.Unlock()
+	}
+}
+
+// intercepted returns f wrapped with the subchannel's interceptors.
+func (c *SubChannel) intercepted(f BeginCallFunc) BeginCallFunc {
+	c.RLock()
+	interceptors := c.interceptors
+	c.RUnlock()
+
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		f = interceptors[i](f)
+	}
+	return f
+}
+
+// SetApplicationHeader sets an application header to send with the call,
+// replacing any header with the same key set by the caller. It must be called
+// before the call's arguments are written, and is only supported by arg
+// schemes with application headers (JSON, Thrift, Protobuf).
+func (call *OutboundCall) SetApplicationHeader(key, value string) {
+	response := call.response
+	if response.appHeaders == nil {
+		response.appHeaders = make(map[string]string)
+	}
+	response.appHeaders[key] = value
+}
+
+// OnDone registers f to be called when the call completes, after the response
+// has been read, or once the call fails, e.g. due to a timeout. f is passed
+// the system error the call failed with, or nil; application errors are
+// reported by OutboundCallResponse.ApplicationError.
+// It must be called before the call's arguments are written.
+func (call *OutboundCall) OnDone(f func(err error)) {
+	call.response.onDone = append(call.response.onDone, f)
+}
+
+// addAppHeaders returns headers with the application headers set using
+// SetApplicationHeader added.
+func (response *OutboundCallResponse) addAppHeaders(headers map[string]string) map[string]string {
+	if len(response.appHeaders) == 0 {
+		return headers
+	}
+	newHeaders := make(map[string]string, len(headers)+len(response.appHeaders))
+	for k, v := range headers {
+		newHeaders[k] = v
+	}
+	for k, v := range response.appHeaders {
+		newHeaders[k] = v
+	}
+	return newHeaders
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/json"
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestOutboundInterceptors(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	record := func(event string) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}
+
+	authInterceptor := func(next BeginCallFunc) BeginCallFunc {
+		return func(ctx context.Context, methodName string, callOptions *CallOptions) (*OutboundCall, error) {
+			record("auth:" + methodName)
+			call, err := next(ctx, methodName, callOptions)
+			if err != nil {
+				return nil, err
+			}
+			call.SetApplicationHeader("auth", "token")
+			return call, nil
+		}
+	}
+	doneInterceptor := func(next BeginCallFunc) BeginCallFunc {
+		return func(ctx context.Context, methodName string, callOptions *CallOptions) (*OutboundCall, error) {
+			record("done:" + methodName)
+			call, err := next(ctx, methodName, callOptions)
+			if err != nil {
+				return nil, err
+			}
+			call.OnDone(func(err error) {
+				record("ended:" + methodName)
+			})
+			return call, nil
+		}
+	}
+
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		mu.Lock()
+		events = nil
+		mu.Unlock()
+
+		require.NoError(t, json.Register(ts.Server(), json.Handlers{
+			"headers": func(ctx json.Context, arg map[string]string) (map[string]string, error) {
+				return ctx.Headers(), nil
+			},
+		}, nil))
+
+		client := ts.NewClient(nil)
+		client.Peers().Add(ts.HostPort())
+		sc := client.GetSubChannel(ts.ServiceName(), OutboundInterceptors(authInterceptor, doneInterceptor))
+
+		ctx, cancel := json.NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		ctx = json.WithHeaders(ctx, map[string]string{"caller": "c", "auth": "overridden"})
+
+		var res map[string]string
+		require.NoError(t, json.CallSC(ctx, sc, "headers", nil, &res))
+		assert.Equal(t, map[string]string{"caller": "c", "auth": "token"}, res)
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []string{"auth:headers", "done:headers", "ended:headers"}, events)
+	})
+}
+
+func TestOutboundInterceptorOnDoneTimeout(t *testing.T) {
+	opts := testutils.NewOpts().DisableLogVerification()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		release := make(chan struct{})
+		testutils.RegisterFunc(ts.Server(), "block", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			<-release
+			return &raw.Res{}, nil
+		})
+
+		var (
+			mu      sync.Mutex
+			doneErr []error
+		)
+		onDone := func(next BeginCallFunc) BeginCallFunc {
+			return func(ctx context.Context, methodName string, callOptions *CallOptions) (*OutboundCall, error) {
+				call, err := next(ctx, methodName, callOptions)
+				if err != nil {
+					return nil, err
+				}
+				call.OnDone(func(err error) {
+					mu.Lock()
+					doneErr = append(doneErr, err)
+					mu.Unlock()
+				})
+				return call, nil
+			}
+		}
+
+		client := ts.NewClient(nil)
+		client.Peers().Add(ts.HostPort())
+		sc := client.GetSubChannel(ts.ServiceName(), OutboundInterceptors(onDone))
+
+		ctx, cancel := NewContext(20 * time.Millisecond)
+		defer cancel()
+		_, _, _, err := raw.CallSC(ctx, sc, "block", nil, nil)
+		close(release)
+		require.Equal(t, ErrTimeout, err, "Expected call to time out")
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []error{ErrTimeout}, doneErr, "OnDone should be called once with the timeout")
+	})
+}
+
+func TestOutboundInterceptorRejects(t *testing.T) {
+	errRejected := errors.New("rejected")
+	reject := func(next BeginCallFunc) BeginCallFunc {
+		return func(ctx context.Context, methodName string, callOptions *CallOptions) (*OutboundCall, error) {
+			return nil, errRejected
+		}
+	}
+
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		client := ts.NewClient(nil)
+		client.Peers().Add(ts.HostPort())
+		sc := client.GetSubChannel(ts.ServiceName(), OutboundInterceptors(reject))
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		_, err := sc.BeginCall(ctx, "echo", nil)
+		assert.Equal(t, errRejected, err)
+
+		// Calls to a specific host:port are not intercepted.
+		assert.NoError(t, testutils.CallEcho(client, ts.HostPort(), ts.ServiceName(), nil))
+	})
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTimeoutStoppedOnFailure(t *testing.T) {
+	server, err := NewChannel("server", nil)
+	require.NoError(t, err, "NewChannel failed")
+	defer server.Close()
+	require.NoError(t, server.ListenAndServe("127.0.0.1:0"), "ListenAndServe failed")
+
+	client, err := NewChannel("client", &ChannelOptions{DefaultTimeout: time.Minute})
+	require.NoError(t, err, "NewChannel failed")
+	defer client.Close()
+
+	call, err := client.BeginCall(context.Background(), server.PeerInfo().HostPort, "server", "method", nil)
+	require.NoError(t, err, "BeginCall failed")
+	ctx := call.mex.ctx
+	require.NoError(t, ctx.Err(), "Call context should be active")
+
+	call.failed(errors.New("write failed"))
+	assert.Equal(t, context.Canceled, ctx.Err(), "Default timeout should be cancelled once the call fails")
+}
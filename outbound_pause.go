@@ -0,0 +1,77 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// outboundPause blocks new outbound calls while outbound traffic is paused.
+type outboundPause struct {
+	sync.RWMutex
+
+	// resumed is non-nil while paused, and is closed on resume.
+	resumed chan struct{}
+}
+
+func (p *outboundPause) pause() {
+	p.Lock()
+	if p.resumed == nil {
+		p.resumed = make(chan struct{})
+	}
+	p.Unlock()
+}
+
+func (p *outboundPause) resume() {
+	p.Lock()
+	if p.resumed != nil {
+		close(p.resumed)
+		p.resumed = nil
+	}
+	p.Unlock()
+}
+
+func (p *outboundPause) paused() bool {
+	p.RLock()
+	paused := p.resumed != nil
+	p.RUnlock()
+	return paused
+}
+
+// wait blocks until outbound traffic is resumed, or the context is done.
+func (p *outboundPause) wait(ctx context.Context) error {
+	p.RLock()
+	resumed := p.resumed
+	p.RUnlock()
+
+	if resumed == nil {
+		return nil
+	}
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return GetContextError(ctx.Err())
+	}
+}
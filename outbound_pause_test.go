@@ -0,0 +1,100 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestPauseOutbound(t *testing.T) {
+	tests := []struct {
+		msg  string
+		call func(ctx context.Context, ts *testutils.TestServer, client *Channel) error
+	}{
+		{
+			msg: "call via peer",
+			call: func(ctx context.Context, ts *testutils.TestServer, client *Channel) error {
+				_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+				return err
+			},
+		},
+		{
+			msg: "call via subchannel",
+			call: func(ctx context.Context, ts *testutils.TestServer, client *Channel) error {
+				sc := client.GetSubChannel(ts.ServiceName())
+				sc.Peers().Add(ts.HostPort())
+				_, _, _, err := raw.CallSC(ctx, sc, "echo", nil, nil)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+				testutils.RegisterEcho(ts.Server(), nil)
+				client := ts.NewClient(nil)
+
+				client.PauseOutbound()
+				assert.True(t, client.OutboundPaused(), "Expected outbound to be paused")
+
+				// A call whose deadline passes while paused fails with a timeout.
+				ctx, cancel := NewContext(testutils.Timeout(20 * time.Millisecond))
+				err := tt.call(ctx, ts, client)
+				cancel()
+				assert.Equal(t, ErrTimeout, err, "Expected paused call to time out")
+
+				callDone := make(chan error, 1)
+				go func() {
+					ctx, cancel := NewContext(testutils.Timeout(time.Second))
+					defer cancel()
+					callDone <- tt.call(ctx, ts, client)
+				}()
+
+				select {
+				case err := <-callDone:
+					t.Fatalf("Call completed while outbound is paused: %v", err)
+				case <-time.After(testutils.Timeout(20 * time.Millisecond)):
+				}
+
+				client.ResumeOutbound()
+				assert.False(t, client.OutboundPaused(), "Expected outbound to be resumed")
+
+				select {
+				case err := <-callDone:
+					require.NoError(t, err, "Paused call should succeed after resume")
+				case <-time.After(testutils.Timeout(time.Second)):
+					t.Fatal("Paused call did not complete after resume")
+				}
+			})
+		})
+	}
+}
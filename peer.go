@@ -23,6 +23,7 @@ package tchannel
 import (
 	"container/heap"
 	"errors"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -48,6 +49,18 @@ var (
 	// ErrNoNewPeers indicates that no previously unselected peer is available.
 	ErrNoNewPeers = errors.New("no new peer available")
 
+	// ErrNoHealthyPeers indicates that all peers recently failed to connect,
+	// so the call fails without attempting to connect to them again.
+	// See ChannelOptions.UnhealthyPeerCooldown.
+	ErrNoHealthyPeers = errors.New("no healthy peers available")
+
+	// ErrPeerConnectionsSaturated indicates that every connection to the peer
+	// has ChannelOptions.MaxCallsPerConnection calls in progress, and no more
+	// connections can be created due to ChannelOptions.MaxConnectionsPerPeer.
+	ErrPeerConnectionsSaturated = NewSystemError(ErrCodeBusy, "all connections to the peer are saturated")
+
+	errImportBlankHostPort = errors.New("cannot import peer with blank host:port")
+
 	peerRng = trand.NewSeeded()
 )
 
@@ -65,17 +78,27 @@ type PeerList struct {
 
 	parent          *RootPeerList
 	peersByHostPort map[string]*peerScore
-	peerHeap        *peerHeap
-	scoreCalculator ScoreCalculator
-	lastSelected    uint64
+	// hostPortByIdentity maps peer identities to the host:port of the peer
+	// currently added for that identity.
+	hostPortByIdentity map[string]string
+	peerHeap           *peerHeap
+	scoreCalculator    ScoreCalculator
+	selection          PeerSelection
+	lastSelected       uint64
+
+	// strategyPeers is reused to pass the peers that can be selected to the
+	// PeerSelectionStrategy.
+	strategyPeers []*Peer
 }
 
 func newPeerList(root *RootPeerList) *PeerList {
 	return &PeerList{
-		parent:          root,
-		peersByHostPort: make(map[string]*peerScore),
-		scoreCalculator: newPreferIncomingCalculator(),
-		peerHeap:        newPeerHeap(),
+		parent:             root,
+		peersByHostPort:    make(map[string]*peerScore),
+		hostPortByIdentity: make(map[string]string),
+		scoreCalculator:    newPreferIncomingCalculator(),
+		selection:          root.peerSelection,
+		peerHeap:           newPeerHeap(),
 	}
 }
 
@@ -91,6 +114,14 @@ func (l *PeerList) SetStrategy(sc ScoreCalculator) {
 	}
 }
 
+// SetSelection sets the algorithm used to select peers from the list.
+func (l *PeerList) SetSelection(selection PeerSelection) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.selection = selection
+}
+
 // Siblings don't share peer lists (though they take care not to double-connect
 // to the same hosts).
 func (l *PeerList) newSibling() *PeerList {
@@ -106,8 +137,14 @@ func (l *PeerList) Add(hostPort string) *Peer {
 	l.Lock()
 	defer l.Unlock()
 
-	if p, ok := l.peersByHostPort[hostPort]; ok {
-		return p.Peer
+	return l.addLocked(hostPort).Peer
+}
+
+// addLocked adds a peer to the list if it does not exist, and returns the
+// peer's score. The list must be write-locked.
+func (l *PeerList) addLocked(hostPort string) *peerScore {
+	if ps, ok := l.peersByHostPort[hostPort]; ok {
+		return ps
 	}
 
 	p := l.parent.Add(hostPort)
@@ -116,7 +153,45 @@ func (l *PeerList) Add(hostPort string) *Peer {
 
 	l.peersByHostPort[hostPort] = ps
 	l.peerHeap.addPeer(ps)
-	return p
+	return ps
+}
+
+// AddWithIdentity adds a peer with a stable identity that's independent of its
+// host:port, such as a logical instance name. If the list already has a peer
+// with the same identity at a different host:port, that peer is replaced by
+// the peer at the new host:port, so an address change does not result in a
+// duplicate peer. Connections to the previous host:port are not affected.
+func (l *PeerList) AddWithIdentity(hostPort, identity string) *Peer {
+	if identity == "" {
+		return l.Add(hostPort)
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if prevHostPort, ok := l.hostPortByIdentity[identity]; ok && prevHostPort != hostPort {
+		l.removeLocked(prevHostPort)
+	}
+
+	ps := l.addLocked(hostPort)
+	if prevIdentity := ps.Peer.Identity(); prevIdentity != "" && prevIdentity != identity {
+		delete(l.hostPortByIdentity, prevIdentity)
+	}
+	l.hostPortByIdentity[identity] = hostPort
+	ps.Peer.setIdentity(identity)
+	return ps.Peer
+}
+
+// GetByIdentity returns the peer added with the given identity, if any.
+func (l *PeerList) GetByIdentity(identity string) (*Peer, bool) {
+	l.RLock()
+	defer l.RUnlock()
+
+	hostPort, ok := l.hostPortByIdentity[identity]
+	if !ok {
+		return nil, false
+	}
+	return l.peersByHostPort[hostPort].Peer, true
 }
 
 // GetNew returns a new, previously unselected peer from the peer list, or nil,
@@ -157,22 +232,240 @@ func (l *PeerList) Get(prevSelected map[string]struct{}) (*Peer, error) {
 	return peer, nil
 }
 
+// GetN returns up to n distinct peers from the peer list ordered by their
+// score, for callers that spread requests across peers themselves. Unhealthy
+// peers are excluded. The returned peers are counted as selected, so repeated
+// calls rotate between peers that have the same score.
+func (l *PeerList) GetN(n int) ([]*Peer, error) {
+	cooldown := l.parent.unhealthyCooldown
+	now := l.parent.timeNow()
+
+	l.Lock()
+	defer l.Unlock()
+	if l.peerHeap.Len() == 0 {
+		return nil, ErrNoPeers
+	}
+
+	var chosen, skipped []*peerScore
+	for l.peerHeap.Len() > 0 && len(chosen) < n {
+		ps := l.peerHeap.popPeer()
+		if ps.Peer.isHealthy(now, cooldown) {
+			chosen = append(chosen, ps)
+		} else {
+			skipped = append(skipped, ps)
+		}
+	}
+
+	for _, ps := range skipped {
+		heap.Push(l.peerHeap, ps)
+	}
+
+	peers := make([]*Peer, 0, len(chosen))
+	for _, ps := range chosen {
+		l.peerHeap.pushPeer(ps)
+		ps.chosenCount.Inc()
+		peers = append(peers, ps.Peer)
+	}
+
+	if len(peers) == 0 && len(skipped) > 0 {
+		return nil, ErrNoHealthyPeers
+	}
+	return peers, nil
+}
+
+// topPeers returns up to n healthy peers in the order they would be selected,
+// without counting them as selected. If n is zero or negative, all healthy
+// peers are returned.
+func (l *PeerList) topPeers(n int) []*Peer {
+	cooldown := l.parent.unhealthyCooldown
+	now := l.parent.timeNow()
+
+	var peers []*Peer
+	for _, peer := range l.unselectedPeers(nil) {
+		if n > 0 && len(peers) >= n {
+			break
+		}
+		if peer.isHealthy(now, cooldown) {
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
+
+// getForRequest returns a peer for the given request state. Previous
+// selected peers are avoided, and if all peers have been selected, the
+// peer selected by the last attempt is avoided if possible.
+func (l *PeerList) getForRequest(rs *RequestState) (*Peer, error) {
+	if rs == nil {
+		peer, err := l.Get(nil)
+		if err != nil {
+			return nil, err
+		}
+		return l.healthyPeer(peer, nil)
+	}
+
+	if rs.Attempt > 1 && rs.retryOpts != nil && rs.retryOpts.PeerSelector != nil {
+		if peer := rs.retryOpts.PeerSelector(rs, l.unselectedPeers(rs.SelectedPeers)); peer != nil {
+			return peer, nil
+		}
+	}
+
+	peer, err := l.GetNew(rs.SelectedPeers)
+	if err == ErrNoNewPeers {
+		l.Lock()
+		if rs.lastSelected != "" {
+			peer = l.choosePeer(map[string]struct{}{rs.lastSelected: {}}, false /* avoidHost */)
+		}
+		if peer == nil {
+			peer = l.choosePeer(nil, false /* avoidHost */)
+		}
+		l.Unlock()
+
+		if peer == nil {
+			return nil, ErrNoPeers
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return l.healthyPeer(peer, rs)
+}
+
+// healthyPeer returns the given peer if it's healthy, or otherwise the
+// healthy peer with the best score. Like the initial selection, peers that
+// the request already selected are avoided, falling back to avoiding only
+// the last selected peer. If no peers are healthy, it returns
+// ErrNoHealthyPeers.
+func (l *PeerList) healthyPeer(peer *Peer, rs *RequestState) (*Peer, error) {
+	cooldown := l.parent.unhealthyCooldown
+	now := l.parent.timeNow()
+	if peer.isHealthy(now, cooldown) {
+		return peer, nil
+	}
+
+	var prevSelected map[string]struct{}
+	var lastSelected string
+	if rs != nil {
+		prevSelected = rs.SelectedPeers
+		lastSelected = rs.lastSelected
+	}
+
+	l.RLock()
+	defer l.RUnlock()
+
+	best := l.bestHealthyPeer(now, cooldown, func(hostPort string) bool {
+		_, ok := prevSelected[hostPort]
+		return !ok
+	})
+	if best == nil && lastSelected != "" {
+		best = l.bestHealthyPeer(now, cooldown, func(hostPort string) bool {
+			return hostPort != lastSelected
+		})
+	}
+	if best == nil {
+		best = l.bestHealthyPeer(now, cooldown, func(string) bool { return true })
+	}
+	if best == nil {
+		return nil, ErrNoHealthyPeers
+	}
+	best.chosenCount.Inc()
+	return best.Peer, nil
+}
+
+// bestHealthyPeer returns the healthy peer with the best score that can be
+// chosen, or nil if there is none. The list must be read-locked.
+func (l *PeerList) bestHealthyPeer(now time.Time, cooldown time.Duration, canChoosePeer func(string) bool) *peerScore {
+	var best *peerScore
+	for hostPort, ps := range l.peersByHostPort {
+		if best != nil && ps.score >= best.score {
+			continue
+		}
+		if canChoosePeer(hostPort) && ps.Peer.isHealthy(now, cooldown) {
+			best = ps
+		}
+	}
+	return best
+}
+
+// unselectedPeers returns all peers that are not in prevSelected, ordered
+// by their score.
+func (l *PeerList) unselectedPeers(prevSelected map[string]struct{}) []*Peer {
+	l.RLock()
+	defer l.RUnlock()
+
+	// Copy the heap's backing slice rather than popping peers off the heap,
+	// so selection only needs a read lock and leaves the heap untouched.
+	candidates := make([]*peerScore, 0, l.peerHeap.Len())
+	for _, ps := range l.peerHeap.peerScores {
+		if _, ok := prevSelected[ps.HostPort()]; !ok {
+			candidates = append(candidates, ps)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score == candidates[j].score {
+			return candidates[i].order < candidates[j].order
+		}
+		return candidates[i].score < candidates[j].score
+	})
+
+	peers := make([]*Peer, len(candidates))
+	for i, ps := range candidates {
+		peers[i] = ps.Peer
+	}
+	return peers
+}
+
 // Remove removes a peer from the peer list. It returns an error if the peer cannot be found.
 // Remove does not affect connections to the peer in any way.
 func (l *PeerList) Remove(hostPort string) error {
 	l.Lock()
 	defer l.Unlock()
 
+	if !l.removeLocked(hostPort) {
+		return ErrPeerNotFound
+	}
+	return nil
+}
+
+// Update changes the peers in the list to hostPorts, adding any new peers
+// and removing any peers that are not in hostPorts. The list is updated
+// atomically, so concurrent calls never see a partially updated list.
+// Like Remove, Update does not affect connections to removed peers.
+func (l *PeerList) Update(hostPorts []string) {
+	keep := make(map[string]struct{}, len(hostPorts))
+	for _, hostPort := range hostPorts {
+		keep[hostPort] = struct{}{}
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	for hostPort := range l.peersByHostPort {
+		if _, ok := keep[hostPort]; !ok {
+			l.removeLocked(hostPort)
+		}
+	}
+	for _, hostPort := range hostPorts {
+		l.addLocked(hostPort)
+	}
+}
+
+// removeLocked removes a peer from the peer list, and returns whether the peer
+// was found. The list must be write-locked.
+func (l *PeerList) removeLocked(hostPort string) bool {
 	p, ok := l.peersByHostPort[hostPort]
 	if !ok {
-		return ErrPeerNotFound
+		return false
+	}
+
+	if identity := p.Identity(); identity != "" && l.hostPortByIdentity[identity] == hostPort {
+		delete(l.hostPortByIdentity, identity)
 	}
 
 	p.delSC()
 	delete(l.peersByHostPort, hostPort)
 	l.peerHeap.removePeer(p)
-
-	return nil
+	return true
 }
 func (l *PeerList) choosePeer(prevSelected map[string]struct{}, avoidHost bool) *Peer {
 	var psPopList []*peerScore
@@ -190,6 +483,18 @@ func (l *PeerList) choosePeer(prevSelected map[string]struct{}, avoidHost bool)
 		return true
 	}
 
+	if strategy := l.parent.selectionStrategy; strategy != nil {
+		if ps := l.choosePeerStrategy(strategy, canChoosePeer); ps != nil {
+			ps.chosenCount.Inc()
+			return ps.Peer
+		}
+	} else if l.selection == PeerSelectionP2C || l.selection == PeerSelectionLatency || l.selection == PeerSelectionAdaptive {
+		if ps := l.choosePeerP2C(canChoosePeer); ps != nil {
+			ps.chosenCount.Inc()
+			return ps.Peer
+		}
+	}
+
 	size := l.peerHeap.Len()
 	for i := 0; i < size; i++ {
 		popped := l.peerHeap.popPeer()
@@ -234,6 +539,44 @@ func (l *PeerList) Copy() map[string]*Peer {
 	return listCopy
 }
 
+// PeerState is the serializable state of a peer in a PeerList, used to
+// persist and restore the peer list with Export and Import.
+type PeerState struct {
+	HostPort string `json:"hostPort"`
+	Identity string `json:"identity,omitempty"`
+}
+
+// Export returns the state of all peers in the PeerList, sorted by host:port.
+func (l *PeerList) Export() []PeerState {
+	l.RLock()
+	peers := make([]PeerState, 0, len(l.peersByHostPort))
+	for hostPort, ps := range l.peersByHostPort {
+		peers = append(peers, PeerState{HostPort: hostPort, Identity: ps.Peer.Identity()})
+	}
+	l.RUnlock()
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].HostPort < peers[j].HostPort
+	})
+	return peers
+}
+
+// Import adds the peers from a previous Export to the PeerList. Peers that
+// already exist are left unchanged. Import does not connect to any peers.
+// If any peer is invalid, no peers are added.
+func (l *PeerList) Import(peers []PeerState) error {
+	for _, ps := range peers {
+		if ps.HostPort == "" {
+			return errImportBlankHostPort
+		}
+	}
+
+	for _, ps := range peers {
+		l.AddWithIdentity(ps.HostPort, ps.Identity)
+	}
+	return nil
+}
+
 // Len returns the length of the PeerList.
 func (l *PeerList) Len() int {
 	l.RLock()
@@ -320,8 +663,12 @@ type Peer struct {
 
 	channel             Connectable
 	hostPort            string
+	identity            atomic.String
+	addresses           atomic.Value // []string
 	onStatusChanged     func(*Peer)
 	onClosedConnRemoved func(*Peer)
+	timeNow             func() time.Time
+	outboundPause       *outboundPause
 
 	// scCount is the number of subchannels that this peer is added to.
 	scCount uint32
@@ -332,22 +679,71 @@ type Peer struct {
 	outboundConnections []*Connection
 	chosenCount         atomic.Uint64
 
+	// outboundConnects is the number of outbound connections added, and
+	// reconnections is the number of those added after the first.
+	outboundConnects atomic.Uint64
+	reconnections    atomic.Uint64
+
+	// connectFailedAt is the time (in Unix nanoseconds) of the last failed
+	// connection attempt, or 0 if the last attempt succeeded.
+	connectFailedAt atomic.Int64
+
+	// connFailedAt is the time (in Unix nanoseconds) that a connection to
+	// the peer last failed after it was established, or 0 if none has.
+	connFailedAt atomic.Int64
+
+	// ejectedUntil is the time (in Unix nanoseconds) until which the peer is
+	// ejected after failing active health checks, or 0 if it's not ejected.
+	ejectedUntil atomic.Int64
+
+	// latency is the moving average of outbound call latencies to this peer,
+	// in nanoseconds, and errorRate is the moving average of the fraction of
+	// outbound calls to this peer that failed.
+	latency   movingAverage
+	errorRate movingAverage
+
+	// selectionStrategy is the channel's PeerSelectionStrategy, if any, which
+	// is told the outcome of outbound calls to this peer.
+	selectionStrategy PeerSelectionStrategy
+
+	// circuitBreaker is the channel's circuit breaker, if any, which tracks
+	// outbound calls to this peer.
+	circuitBreaker *circuitBreaker
+
+	// connLimits limits the outbound connections to this peer, and
+	// capacityCh is closed when a call on any connection completes, waking
+	// calls waiting for capacity.
+	connLimits  peerConnLimits
+	capacityMut sync.Mutex
+	capacityCh  chan struct{}
+
+	// bandwidth limits the bytes sent to and received from this peer.
+	bandwidth *peerBandwidth
+
 	// onUpdate is a test-only hook.
 	onUpdate func(*Peer)
 }
 
-func newPeer(channel Connectable, hostPort string, onStatusChanged func(*Peer), onClosedConnRemoved func(*Peer)) *Peer {
+func newPeer(channel Connectable, hostPort string, onStatusChanged func(*Peer), onClosedConnRemoved func(*Peer), timeNow func() time.Time, pause *outboundPause) *Peer {
 	if hostPort == "" {
 		panic("Cannot create peer with blank hostPort")
 	}
 	if onStatusChanged == nil {
 		onStatusChanged = noopOnStatusChanged
 	}
+	if timeNow == nil {
+		timeNow = time.Now
+	}
+	if pause == nil {
+		pause = &outboundPause{}
+	}
 	return &Peer{
 		channel:             channel,
 		hostPort:            hostPort,
 		onStatusChanged:     onStatusChanged,
 		onClosedConnRemoved: onClosedConnRemoved,
+		timeNow:             timeNow,
+		outboundPause:       pause,
 	}
 }
 
@@ -356,6 +752,16 @@ func (p *Peer) HostPort() string {
 	return p.hostPort
 }
 
+// Identity returns the stable identity of this peer set by
+// PeerList.AddWithIdentity, or an empty string if it has none.
+func (p *Peer) Identity() string {
+	return p.identity.Load()
+}
+
+func (p *Peer) setIdentity(identity string) {
+	p.identity.Store(identity)
+}
+
 // getConn treats inbound and outbound connections as a single virtual list
 // that can be indexed. The peer must be read-locked.
 func (p *Peer) getConn(i int) *Connection {
@@ -378,7 +784,7 @@ func (p *Peer) getActiveConnLocked() (*Connection, bool) {
 	startOffset := peerRng.Intn(allConns)
 	for i := 0; i < allConns; i++ {
 		connIndex := (i + startOffset) % allConns
-		if conn := p.getConn(connIndex); conn.IsActive() {
+		if conn := p.getConn(connIndex); conn.canStartCalls() {
 			return conn, true
 		}
 	}
@@ -400,6 +806,10 @@ func (p *Peer) getActiveConn() (*Connection, bool) {
 // GetConnection returns an active connection to this peer. If no active connections
 // are found, it will create a new outbound connection and return it.
 func (p *Peer) GetConnection(ctx context.Context) (*Connection, error) {
+	if p.connLimits.enabled() {
+		return p.getConnectionLimited(ctx)
+	}
+
 	if activeConn, ok := p.getActiveConn(); ok {
 		return activeConn, nil
 	}
@@ -418,8 +828,13 @@ func (p *Peer) GetConnection(ctx context.Context) (*Connection, error) {
 }
 
 // getConnectionRelay gets a connection, and uses the given timeout to lazily
-// create a context if a new connection is required.
-func (p *Peer) getConnectionRelay(timeout time.Duration) (*Connection, error) {
+// create a context if a new connection is required. If poolSize is more than
+// 1, calls are spread across up to poolSize connections.
+func (p *Peer) getConnectionRelay(timeout time.Duration, poolSize int) (*Connection, error) {
+	if poolSize > 1 {
+		return p.getPooledConnectionRelay(timeout, poolSize)
+	}
+
 	if conn, ok := p.getActiveConn(); ok {
 		return conn, nil
 	}
@@ -433,6 +848,72 @@ func (p *Peer) getConnectionRelay(timeout time.Duration) (*Connection, error) {
 		return activeConn, nil
 	}
 
+	return p.connectRelay(timeout)
+}
+
+// getPooledConnectionRelay returns the active connection with the fewest
+// relayed calls in progress, creating a new connection if the peer has fewer
+// than poolSize active connections. If the new connection fails, existing
+// connections continue to be used.
+func (p *Peer) getPooledConnectionRelay(timeout time.Duration, poolSize int) (*Connection, error) {
+	if conn, active := p.getLeastPendingRelayConn(); active >= poolSize {
+		return conn, nil
+	}
+
+	p.newConnLock.Lock()
+	defer p.newConnLock.Unlock()
+
+	conn, active := p.getLeastPendingRelayConn()
+	if active >= poolSize {
+		return conn, nil
+	}
+
+	// Don't retry a failed connection for every call while there are other
+	// connections that can be used.
+	if conn != nil {
+		if failedAt := p.connectFailedAt.Load(); failedAt != 0 && p.timeNow().Sub(time.Unix(0, failedAt)) < _relayPoolConnectBackoff {
+			return conn, nil
+		}
+	}
+
+	newConn, err := p.connectRelay(timeout)
+	if err != nil && conn != nil {
+		return conn, nil
+	}
+	return newConn, err
+}
+
+// getLeastPendingRelayConn returns the active connection with the fewest
+// relayed calls in progress, and the number of active connections.
+func (p *Peer) getLeastPendingRelayConn() (*Connection, int) {
+	p.RLock()
+	defer p.RUnlock()
+
+	var (
+		best        *Connection
+		bestPending uint32
+		active      int
+	)
+	allConns := len(p.inboundConnections) + len(p.outboundConnections)
+	for i := 0; i < allConns; i++ {
+		conn := p.getConn(i)
+		if !conn.canStartCalls() {
+			continue
+		}
+		active++
+
+		var pending uint32
+		if conn.relay != nil {
+			pending = conn.relay.pending.Load()
+		}
+		if best == nil || pending < bestPending {
+			best, bestPending = conn, pending
+		}
+	}
+	return best, active
+}
+
+func (p *Peer) connectRelay(timeout time.Duration) (*Connection, error) {
 	// When the relay creates outbound connections, we don't want those services
 	// to ever connect back to us and send us traffic. We hide the host:port
 	// so that service instances on remote machines don't try to connect back
@@ -478,6 +959,14 @@ func (p *Peer) addConnection(c *Connection, direction connectionDirection) error
 	*conns = append(*conns, c)
 	p.Unlock()
 
+	if p.bandwidth != nil {
+		c.peerBandwidth.Store(p.bandwidth)
+	}
+
+	if direction == outbound && p.outboundConnects.Inc() > 1 {
+		p.reconnections.Inc()
+	}
+
 	// Inform third parties that a peer gained a connection.
 	p.onStatusChanged(p)
 
@@ -524,6 +1013,9 @@ func (p *Peer) connectionCloseStateChange(changed *Connection) {
 	p.Unlock()
 
 	if found {
+		if changed.failed.Load() {
+			p.connFailedAt.Store(p.timeNow().UnixNano())
+		}
 		p.onClosedConnRemoved(p)
 		// Inform third parties that a peer lost a connection.
 		p.onStatusChanged(p)
@@ -532,12 +1024,62 @@ func (p *Peer) connectionCloseStateChange(changed *Connection) {
 
 // Connect adds a new outbound connection to the peer.
 func (p *Peer) Connect(ctx context.Context) (*Connection, error) {
-	return p.channel.Connect(ctx, p.hostPort)
+	conn, err := p.channel.Connect(ctx, p.hostPort)
+	if err == nil {
+		p.connectFailedAt.Store(0)
+	} else if err != ErrRequestCancelled {
+		p.connectFailedAt.Store(p.timeNow().UnixNano())
+	}
+	return conn, err
+}
+
+// isHealthy returns whether the peer can be used for new calls. Peers that
+// were ejected by failed health checks are unhealthy until the ejection
+// expires. Peers that failed to connect within the cooldown are unhealthy,
+// unless they have an active connection.
+func (p *Peer) isHealthy(now time.Time, cooldown time.Duration) bool {
+	if ejectedUntil := p.ejectedUntil.Load(); ejectedUntil != 0 && now.UnixNano() < ejectedUntil {
+		return false
+	}
+
+	if cooldown <= 0 {
+		return true
+	}
+
+	failedAt := p.connectFailedAt.Load()
+	if failedAt == 0 || now.Sub(time.Unix(0, failedAt)) >= cooldown {
+		return true
+	}
+
+	_, ok := p.getActiveConn()
+	return ok
+}
+
+// failedRecently returns whether a connection to the peer failed, or the peer
+// failed to connect, within the cooldown, and the peer has no active
+// connection to use instead.
+func (p *Peer) failedRecently(now time.Time, cooldown time.Duration) bool {
+	failedAt := p.connectFailedAt.Load()
+	if connFailedAt := p.connFailedAt.Load(); connFailedAt > failedAt {
+		failedAt = connFailedAt
+	}
+	if failedAt == 0 || now.Sub(time.Unix(0, failedAt)) >= cooldown {
+		return false
+	}
+
+	_, ok := p.getActiveConn()
+	return !ok
 }
 
 // BeginCall starts a new call to this specific peer, returning an OutboundCall that can
 // be used to write the arguments of the call.
 func (p *Peer) BeginCall(ctx context.Context, serviceName, methodName string, callOptions *CallOptions) (*OutboundCall, error) {
+	return p.beginCall(ctx, p.timeNow(), serviceName, methodName, callOptions)
+}
+
+// beginCall starts a new call to this peer, where start is the time at which
+// the caller started the call, before any peer selection.
+func (p *Peer) beginCall(ctx context.Context, start time.Time, serviceName, methodName string, callOptions *CallOptions) (*OutboundCall, error) {
 	if callOptions == nil {
 		callOptions = defaultCallOptions
 	}
@@ -547,19 +1089,47 @@ func (p *Peer) BeginCall(ctx context.Context, serviceName, methodName string, ca
 		return nil, err
 	}
 
-	conn, err := p.GetConnection(ctx)
-	if err != nil {
-		return nil, err
+	var circuit *circuit
+	circuitKey := circuitKey{p.hostPort, serviceName, methodName}
+	if p.circuitBreaker != nil {
+		var ok bool
+		if circuit, ok = p.circuitBreaker.allow(circuitKey); !ok {
+			return nil, ErrCircuitOpen
+		}
 	}
 
-	call, err := conn.beginCall(ctx, serviceName, methodName, callOptions)
+	call, connected, err := p.beginCallOnConn(ctx, serviceName, methodName, callOptions)
 	if err != nil {
+		if circuit != nil {
+			p.circuitBreaker.record(circuitKey, circuit, circuitOutcomeOf(err))
+		}
 		return nil, err
 	}
 
+	call.statsReporter.RecordTimer("outbound.calls.peer-wait", call.commonStatsTags, connected.Sub(start))
+	call.response.peer = p
+	call.response.circuit = circuit
+	call.response.circuitKey = circuitKey
 	return call, err
 }
 
+// beginCallOnConn gets a connection to this peer and starts a call on it,
+// returning the call and the time at which the connection was ready.
+func (p *Peer) beginCallOnConn(ctx context.Context, serviceName, methodName string, callOptions *CallOptions) (*OutboundCall, time.Time, error) {
+	if err := p.outboundPause.wait(ctx); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	conn, err := p.GetConnection(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	connected := p.timeNow()
+
+	call, err := conn.beginCall(ctx, serviceName, methodName, callOptions)
+	return call, connected, err
+}
+
 // NumConnections returns the number of inbound and outbound connections for this peer.
 func (p *Peer) NumConnections() (inbound int, outbound int) {
 	p.RLock()
@@ -569,6 +1139,29 @@ func (p *Peer) NumConnections() (inbound int, outbound int) {
 	return inbound, outbound
 }
 
+// HasActiveConnection returns whether the peer has a connection in the
+// active state that can be used for calls. It does not make any calls.
+func (p *Peer) HasActiveConnection() bool {
+	_, ok := p.getActiveConn()
+	return ok
+}
+
+// ConnectionCount returns the number of inbound and outbound connections to
+// this peer that are in the active state, and how many of those are idle,
+// i.e. have no inbound or outbound calls in progress.
+func (p *Peer) ConnectionCount() (active int, idle int) {
+	p.runWithConnections(func(c *Connection) {
+		if !c.IsActive() {
+			return
+		}
+		active++
+		if c.inbound.count()+c.outbound.count() == 0 {
+			idle++
+		}
+	})
+	return active, idle
+}
+
 // NumPendingOutbound returns the number of pending outbound calls.
 func (p *Peer) NumPendingOutbound() int {
 	count := 0
@@ -584,6 +1177,42 @@ func (p *Peer) NumPendingOutbound() int {
 	return count
 }
 
+// PeerStats are the stats about outbound calls to a peer that are used by
+// PeerSelectionLatency and PeerSelectionAdaptive to select peers.
+type PeerStats struct {
+	// PendingOutbound is the number of pending outbound calls.
+	PendingOutbound int `json:"pendingOutbound"`
+
+	// Latency is the moving average of call latency, or 0 if no calls have
+	// been made to the peer.
+	Latency time.Duration `json:"latency"`
+
+	// ErrorRate is the moving average of the fraction of calls that failed,
+	// between 0 and 1.
+	ErrorRate float64 `json:"errorRate"`
+}
+
+// Stats returns the current stats about outbound calls to the peer.
+func (p *Peer) Stats() PeerStats {
+	now := p.timeNow()
+	return PeerStats{
+		PendingOutbound: p.NumPendingOutbound(),
+		Latency:         time.Duration(p.latency.at(now)),
+		ErrorRate:       p.errorRate.at(now),
+	}
+}
+
+// recordCall adds the outcome of an outbound call attempt to the peer's stats.
+func (p *Peer) recordCall(now time.Time, latency time.Duration, err error) {
+	p.latency.record(now, float64(latency))
+	switch circuitOutcomeOf(err) {
+	case circuitSucceeded:
+		p.errorRate.record(now, 0)
+	case circuitFailed:
+		p.errorRate.record(now, 1)
+	}
+}
+
 func (p *Peer) runWithConnections(f func(*Connection)) {
 	p.RLock()
 	for _, c := range p.inboundConnections {
@@ -0,0 +1,127 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"net"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const _defaultDialFallbackDelay = 300 * time.Millisecond
+
+// SetAddresses sets alternate addresses for the peer, such as its IPv6 and
+// IPv4 addresses, or other host:ports for the same process. New connections
+// to the peer dial HostPort first, and then each alternate address in order
+// if the previous dial hasn't succeeded within the channel's
+// DialFallbackDelay, using the first connection that succeeds.
+func (p *Peer) SetAddresses(addrs ...string) {
+	p.addresses.Store(append([]string(nil), addrs...))
+}
+
+// Addresses returns the addresses dialed for new connections to the peer,
+// starting with HostPort.
+func (p *Peer) Addresses() []string {
+	addrs, _ := p.addresses.Load().([]string)
+	return append([]string{p.hostPort}, addrs...)
+}
+
+type dialResult struct {
+	addr string
+	conn net.Conn
+	err  error
+}
+
+// dialPeer creates an outbound network connection for hostPort, racing the
+// addresses of its peer if it has alternate addresses.
+func (ch *Channel) dialPeer(ctx context.Context, hostPort string) (net.Conn, error) {
+	if p, ok := ch.RootPeers().Get(hostPort); ok {
+		if addrs := p.Addresses(); len(addrs) > 1 {
+			return ch.dialAddresses(ctx, addrs)
+		}
+	}
+	return ch.dial(ctx, hostPort)
+}
+
+// dialAddresses dials addrs in order, starting the next dial when the previous
+// one fails or after the fallback delay, and returns the first connection that
+// succeeds. If every dial fails, the first error is returned.
+func (ch *Channel) dialAddresses(ctx context.Context, addrs []string) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, len(addrs))
+	var started, pending int
+	var fallback <-chan time.Time
+	startNext := func() {
+		addr := addrs[started]
+		started++
+		pending++
+		go func() {
+			conn, err := ch.dial(ctx, addr)
+			results <- dialResult{addr, conn, err}
+		}()
+
+		fallback = nil
+		if started < len(addrs) {
+			fallback = time.After(ch.dialFallbackDelay)
+		}
+	}
+
+	startNext()
+
+	var firstErr error
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				go closeDialed(results, pending)
+				return r.conn, nil
+			}
+
+			ch.log.WithFields(
+				ErrField(r.err),
+				LogField{"address", r.addr},
+			).Debug("Outbound dial to peer address failed.")
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if started < len(addrs) {
+				startNext()
+			}
+		case <-fallback:
+			startNext()
+		}
+	}
+	return nil, firstErr
+}
+
+// closeDialed closes the connections of any dials still pending after another
+// address connected first.
+func closeDialed(results <-chan dialResult, pending int) {
+	for ; pending > 0; pending-- {
+		if r := <-results; r.conn != nil {
+			r.conn.Close()
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+const (
+	blackholeAddr = "192.0.2.1:1"
+	refusedAddr   = "192.0.2.2:1"
+)
+
+var errDialRefused = errors.New("connection refused")
+
+// addressDialer dials the test server for any address other than the
+// blackhole and refused addresses, and records the result of blackhole dials.
+func addressDialer(blackholeDone chan<- error) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		switch hostPort {
+		case blackholeAddr:
+			<-ctx.Done()
+			blackholeDone <- ctx.Err()
+			return nil, ctx.Err()
+		case refusedAddr:
+			return nil, errDialRefused
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, network, hostPort)
+	}
+}
+
+func TestPeerAddresses(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	peer := ch.Peers().Add("127.0.0.1:1")
+	assert.Equal(t, []string{"127.0.0.1:1"}, peer.Addresses(), "Peer without alternate addresses")
+
+	addrs := []string{"[::1]:1", "127.0.0.2:1"}
+	peer.SetAddresses(addrs...)
+	addrs[0] = "modified"
+	assert.Equal(t, []string{"127.0.0.1:1", "[::1]:1", "127.0.0.2:1"}, peer.Addresses(), "Peer with alternate addresses")
+}
+
+func TestPeerAddressesDial(t *testing.T) {
+	tests := []struct {
+		msg           string
+		addrs         []string
+		fallbackDelay time.Duration
+		wantErr       error
+	}{
+		{
+			msg:           "first address hangs, fallback after delay",
+			addrs:         []string{blackholeAddr, ""},
+			fallbackDelay: testutils.Timeout(10 * time.Millisecond),
+		},
+		{
+			msg:           "first address fails, fallback without waiting for delay",
+			addrs:         []string{refusedAddr, ""},
+			fallbackDelay: time.Hour,
+		},
+		{
+			msg:           "first address fails, second hangs",
+			addrs:         []string{refusedAddr, blackholeAddr, ""},
+			fallbackDelay: testutils.Timeout(10 * time.Millisecond),
+		},
+		{
+			msg:           "all addresses fail",
+			addrs:         []string{refusedAddr, refusedAddr},
+			fallbackDelay: time.Hour,
+			wantErr:       errDialRefused,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			opts := testutils.NewOpts().NoRelay()
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				testutils.RegisterEcho(ts.Server(), nil)
+
+				blackholeDone := make(chan error, len(tt.addrs))
+				clientOpts := testutils.NewOpts().SetDialer(addressDialer(blackholeDone))
+				clientOpts.DialFallbackDelay = tt.fallbackDelay
+				if tt.wantErr != nil {
+					clientOpts.AddLogFilter("Outbound net.Dial failed.", 1)
+				}
+				client := ts.NewClient(clientOpts)
+
+				addrs := append([]string(nil), tt.addrs...)
+				for i, addr := range addrs {
+					if addr == "" {
+						addrs[i] = ts.HostPort()
+					}
+				}
+				peer := client.Peers().Add(addrs[0])
+				peer.SetAddresses(addrs[1:]...)
+
+				ctx, cancel := NewContext(testutils.Timeout(time.Second))
+				defer cancel()
+
+				conn, err := peer.GetConnection(ctx)
+				if tt.wantErr != nil {
+					assert.Equal(t, tt.wantErr, err, "Unexpected dial error")
+					return
+				}
+				require.NoError(t, err, "Failed to connect using alternate address")
+				assert.Equal(t, ts.HostPort(), conn.RemotePeerInfo().HostPort, "Connected to unexpected peer")
+
+				for _, addr := range tt.addrs {
+					if addr != blackholeAddr {
+						continue
+					}
+					select {
+					case err := <-blackholeDone:
+						assert.Equal(t, context.Canceled, err, "Pending dial should be cancelled")
+					case <-ctx.Done():
+						t.Errorf("Pending dial was not cancelled")
+					}
+				}
+
+				require.NoError(t, testutils.CallEcho(client, addrs[0], ts.ServiceName(), nil),
+					"Call to peer with alternate addresses failed")
+			})
+		})
+	}
+}
@@ -21,14 +21,18 @@
 package tchannel_test
 
 import (
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
 	. "github.com/uber/tchannel-go"
 
+	"github.com/uber/tchannel-go/raw"
 	"github.com/uber/tchannel-go/testutils"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 func benchmarkGetConnection(b *testing.B, numIncoming, numOutgoing int) {
@@ -59,3 +63,86 @@ func benchmarkGetConnection(b *testing.B, numIncoming, numOutgoing int) {
 func BenchmarkGetConnection0In1Out(b *testing.B) { benchmarkGetConnection(b, 0, 1) }
 func BenchmarkGetConnection1In0Out(b *testing.B) { benchmarkGetConnection(b, 1, 0) }
 func BenchmarkGetConnection5In5Out(b *testing.B) { benchmarkGetConnection(b, 5, 5) }
+
+// benchmarkPeerSelectionSkewed makes concurrent calls to a set of servers
+// where one server is much slower than the others, and logs the call latency
+// percentiles for the given peer selection.
+func benchmarkPeerSelectionSkewed(b *testing.B, selection PeerSelection) {
+	const (
+		numServers  = 4
+		concurrency = 16
+		fastLatency = time.Millisecond
+		slowLatency = 20 * time.Millisecond
+	)
+
+	opts := testutils.NewOpts()
+	opts.PeerSelection = selection
+	client := testutils.NewClient(b, opts)
+	defer client.Close()
+
+	sc := client.GetSubChannel("svc", Isolated)
+	for i := 0; i < numServers; i++ {
+		latency := fastLatency
+		if i == 0 {
+			latency = slowLatency
+		}
+
+		server := testutils.NewServer(b, testutils.NewOpts().SetServiceName("svc"))
+		defer server.Close()
+		testutils.RegisterFunc(server, "sleep", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			time.Sleep(latency)
+			return &raw.Res{}, nil
+		})
+		sc.Peers().Add(server.PeerInfo().HostPort)
+	}
+
+	var (
+		mu        sync.Mutex
+		latencies = make([]time.Duration, 0, b.N)
+		wg        sync.WaitGroup
+		calls     = make(chan struct{})
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range calls {
+				ctx, cancel := NewContext(time.Second)
+				started := time.Now()
+				_, _, _, err := raw.CallSC(ctx, sc, "sleep", nil, nil)
+				latency := time.Since(started)
+				cancel()
+				if err != nil {
+					b.Errorf("Call failed: %v", err)
+					continue
+				}
+
+				mu.Lock()
+				latencies = append(latencies, latency)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		calls <- struct{}{}
+	}
+	close(calls)
+	wg.Wait()
+	b.StopTimer()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) time.Duration {
+		return latencies[int(float64(len(latencies)-1)*p)]
+	}
+	b.Logf("%v calls: p50 = %v, p99 = %v, max = %v", len(latencies), percentile(0.5), percentile(0.99), percentile(1))
+}
+
+func BenchmarkPeerSelectionSkewedScore(b *testing.B) {
+	benchmarkPeerSelectionSkewed(b, PeerSelectionScore)
+}
+
+func BenchmarkPeerSelectionSkewedP2C(b *testing.B) {
+	benchmarkPeerSelectionSkewed(b, PeerSelectionP2C)
+}
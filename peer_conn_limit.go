@@ -0,0 +1,182 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// peerConnLimits limit the outbound connections created to a peer, and the
+// concurrent calls made over each connection.
+type peerConnLimits struct {
+	maxCallsPerConn int
+	maxConns        int
+	queueTimeout    time.Duration
+}
+
+func newPeerConnLimits(opts *ChannelOptions) peerConnLimits {
+	return peerConnLimits{
+		maxCallsPerConn: opts.MaxCallsPerConnection,
+		maxConns:        opts.MaxConnectionsPerPeer,
+		queueTimeout:    opts.ConnectionQueueTimeout,
+	}
+}
+
+func (l peerConnLimits) enabled() bool {
+	return l.maxCallsPerConn > 0
+}
+
+// getConnectionLimited returns the active connection with the fewest calls in
+// progress, as long as it's not saturated. If all connections are saturated,
+// it creates a new outbound connection unless the peer has the maximum
+// number of connections, in which case it waits for a connection to have
+// capacity up to the queue timeout.
+func (p *Peer) getConnectionLimited(ctx context.Context) (*Connection, error) {
+	var queueTimer *time.Timer
+	for {
+		// Get the wait channel before checking for capacity, so we don't miss
+		// a call that completes after the check.
+		capacity := p.capacityWait()
+
+		if conn, ok := p.getUnsaturatedConn(); ok {
+			return conn, nil
+		}
+		if conn, ok, err := p.connectUnderLimit(ctx); ok {
+			return conn, err
+		}
+
+		if p.connLimits.queueTimeout <= 0 {
+			return nil, ErrPeerConnectionsSaturated
+		}
+		if queueTimer == nil {
+			queueTimer = time.NewTimer(p.connLimits.queueTimeout)
+			defer queueTimer.Stop()
+		}
+
+		select {
+		case <-capacity:
+		case <-queueTimer.C:
+			return nil, ErrPeerConnectionsSaturated
+		case <-ctx.Done():
+			return nil, GetContextError(ctx.Err())
+		}
+	}
+}
+
+// getUnsaturatedConn returns the active connection with the fewest outbound
+// calls in progress, if it has fewer than the maximum calls per connection.
+// Spreading calls across connections keeps them all in use, so they aren't
+// closed by the idle sweep and redialed while the peer has traffic.
+func (p *Peer) getUnsaturatedConn() (*Connection, bool) {
+	p.RLock()
+	defer p.RUnlock()
+
+	allConns := len(p.inboundConnections) + len(p.outboundConnections)
+	if allConns == 0 {
+		return nil, false
+	}
+
+	// Start at a random point so connections with the same number of calls
+	// are chosen evenly.
+	var (
+		best      *Connection
+		bestCalls int
+	)
+	startOffset := peerRng.Intn(allConns)
+	for i := 0; i < allConns; i++ {
+		conn := p.getConn((i + startOffset) % allConns)
+		if !conn.canStartCalls() {
+			continue
+		}
+		if calls := conn.outbound.count(); best == nil || calls < bestCalls {
+			best, bestCalls = conn, calls
+		}
+	}
+
+	if best == nil || bestCalls >= p.connLimits.maxCallsPerConn {
+		return nil, false
+	}
+	return best, true
+}
+
+// connectUnderLimit creates a new outbound connection if all connections are
+// saturated, and the peer has fewer than the maximum number of outbound
+// connections. It returns whether it attempted to connect, or found a
+// connection that is no longer saturated.
+func (p *Peer) connectUnderLimit(ctx context.Context) (_ *Connection, ok bool, _ error) {
+	// Lock here to restrict new connection creation attempts to one goroutine
+	p.newConnLock.Lock()
+	defer p.newConnLock.Unlock()
+
+	// Check again in case someone else connected, or a call completed.
+	if conn, ok := p.getUnsaturatedConn(); ok {
+		return conn, true, nil
+	}
+
+	if max := p.connLimits.maxConns; max > 0 && p.numActiveOutbound() >= max {
+		return nil, false, nil
+	}
+
+	conn, err := p.Connect(ctx)
+	return conn, true, err
+}
+
+func (p *Peer) numActiveOutbound() int {
+	p.RLock()
+	defer p.RUnlock()
+
+	var active int
+	for _, conn := range p.outboundConnections {
+		if conn.IsActive() {
+			active++
+		}
+	}
+	return active
+}
+
+// capacityWait returns a channel that is closed when a call to the peer
+// completes.
+func (p *Peer) capacityWait() <-chan struct{} {
+	p.capacityMut.Lock()
+	defer p.capacityMut.Unlock()
+
+	if p.capacityCh == nil {
+		p.capacityCh = make(chan struct{})
+	}
+	return p.capacityCh
+}
+
+// notifyCapacity wakes calls waiting for a connection to the peer to have
+// capacity. It's called whenever the calls on the peer's connections change.
+func (p *Peer) notifyCapacity() {
+	if p.connLimits.queueTimeout <= 0 {
+		return
+	}
+
+	p.capacityMut.Lock()
+	if p.capacityCh != nil {
+		close(p.capacityCh)
+		p.capacityCh = nil
+	}
+	p.capacityMut.Unlock()
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// registerBlockingHandler registers a handler that signals started when it's
+// called, and blocks until release is closed.
+func registerBlockingHandler(ts *testutils.TestServer) (started chan struct{}, release chan struct{}) {
+	started = make(chan struct{}, 10)
+	release = make(chan struct{})
+	testutils.RegisterFunc(ts.Server(), "block", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		started <- struct{}{}
+		<-release
+		return &raw.Res{}, nil
+	})
+	return started, release
+}
+
+func TestMaxConnectionsPerPeer(t *testing.T) {
+	testutils.WithTestServer(t, testutils.NewOpts().NoRelay(), func(ts *testutils.TestServer) {
+		started, release := registerBlockingHandler(ts)
+
+		clientOpts := testutils.NewOpts()
+		clientOpts.MaxCallsPerConnection = 1
+		clientOpts.MaxConnectionsPerPeer = 2
+		client := ts.NewClient(clientOpts)
+
+		call := func() error {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "block", nil, nil)
+			return err
+		}
+
+		errs := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() { errs <- call() }()
+			<-started
+		}
+
+		// Each connection has a call in progress, and no more connections can be created.
+		peer := client.Peers().GetOrAdd(ts.HostPort())
+		assertNumConnections(t, peer, 0, 2)
+		assert.Equal(t, ErrPeerConnectionsSaturated, call(), "Expected call to fail when saturated")
+
+		close(release)
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, <-errs, "Blocked call failed")
+		}
+
+		// Calls reuse the existing connections rather than dialing new ones.
+		for i := 0; i < 5; i++ {
+			require.NoError(t, call(), "Call failed")
+		}
+		assertNumConnections(t, peer, 0, 2)
+	})
+}
+
+func TestMaxConnectionsPerPeerQueue(t *testing.T) {
+	testutils.WithTestServer(t, testutils.NewOpts().NoRelay(), func(ts *testutils.TestServer) {
+		started, release := registerBlockingHandler(ts)
+
+		clientOpts := testutils.NewOpts()
+		clientOpts.MaxCallsPerConnection = 1
+		clientOpts.MaxConnectionsPerPeer = 1
+		clientOpts.ConnectionQueueTimeout = testutils.Timeout(time.Second)
+		client := ts.NewClient(clientOpts)
+
+		call := func() error {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "block", nil, nil)
+			return err
+		}
+
+		errs := make(chan error, 2)
+		go func() { errs <- call() }()
+		<-started
+
+		// The second call waits for the first call to complete.
+		go func() { errs <- call() }()
+		select {
+		case <-started:
+			t.Fatalf("Queued call should not be sent while the connection is saturated")
+		case <-time.After(testutils.Timeout(20 * time.Millisecond)):
+		}
+
+		close(release)
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, <-errs, "Call failed")
+		}
+		assertNumConnections(t, client.Peers().GetOrAdd(ts.HostPort()), 0, 1)
+	})
+}
@@ -22,8 +22,10 @@ package tchannel
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsEphemeralHostPort(t *testing.T) {
@@ -44,3 +46,143 @@ func TestIsEphemeralHostPort(t *testing.T) {
 		assert.Equal(t, tt.want, got, "Unexpected result for %q", tt.hostPort)
 	}
 }
+
+func TestErrorPenalty(t *testing.T) {
+	assert.Equal(t, 1.0, errorPenalty(0), "Peer without errors should not be penalized")
+	assert.Equal(t, 2.0, errorPenalty(0.5), "Peer failing half its calls should cost double")
+	assert.InDelta(t, 100.0, errorPenalty(1), 0.001, "Penalty should be capped")
+}
+
+func TestPeerRecordCall(t *testing.T) {
+	now := time.Unix(1000, 0)
+	p := newPeer(nil, "1.1.1.1:1", nil, nil, func() time.Time { return now }, nil)
+	p.latency.halfLife = time.Second
+	p.errorRate.halfLife = time.Second
+
+	p.recordCall(now, 10*time.Millisecond, nil)
+	assert.Equal(t, PeerStats{Latency: 10 * time.Millisecond}, p.Stats(), "Unexpected stats after success")
+
+	// Cancelled calls don't count towards the error rate.
+	now = now.Add(time.Second)
+	p.recordCall(now, 30*time.Millisecond, ErrRequestCancelled)
+	assert.Zero(t, p.Stats().ErrorRate, "Cancelled call should not be an error")
+
+	p.recordCall(now, 30*time.Millisecond, ErrTimeout)
+	stats := p.Stats()
+	assert.Equal(t, 0.5, stats.ErrorRate, "Unexpected error rate after a failure")
+	assert.Equal(t, 20*time.Millisecond, stats.Latency, "Unexpected latency")
+}
+
+func TestMovingAverage(t *testing.T) {
+	var l movingAverage
+	l.halfLife = time.Second
+	start := time.Unix(1000, 0)
+
+	assert.Zero(t, l.at(start), "Peer without samples should have no latency")
+
+	l.record(start, float64(100*time.Millisecond))
+	assert.Equal(t, float64(100*time.Millisecond), l.at(start), "First sample should set the average")
+
+	// After one half-life, the old average and the new sample have equal weight.
+	l.record(start.Add(time.Second), float64(300*time.Millisecond))
+	now := start.Add(time.Second)
+	assert.InDelta(t, float64(200*time.Millisecond), l.at(now), 1, "Unexpected average after second sample")
+
+	// Without samples, the average decays towards zero.
+	assert.InDelta(t, float64(100*time.Millisecond), l.at(now.Add(time.Second)), 1, "Average should halve after a half-life")
+	assert.InDelta(t, float64(50*time.Millisecond), l.at(now.Add(2*time.Second)), 1, "Average should quarter after two half-lives")
+}
+
+func TestUnselectedPeers(t *testing.T) {
+	ch, err := NewChannel("svc", nil)
+	require.NoError(t, err, "NewChannel failed")
+	defer ch.Close()
+
+	scores := map[string]uint64{
+		"1.1.1.1:1": 3,
+		"1.1.1.1:2": 1,
+		"1.1.1.1:3": 2,
+	}
+	peers := ch.GetSubChannel("svc").Peers()
+	peers.SetStrategy(ScoreCalculatorFunc(func(p *Peer) uint64 {
+		return scores[p.HostPort()]
+	}))
+	for hostPort := range scores {
+		peers.Add(hostPort)
+	}
+
+	hostPorts := func(peers []*Peer) []string {
+		var hostPorts []string
+		for _, p := range peers {
+			hostPorts = append(hostPorts, p.HostPort())
+		}
+		return hostPorts
+	}
+
+	heapBefore := append([]*peerScore(nil), peers.peerHeap.peerScores...)
+	assert.Equal(t, []string{"1.1.1.1:2", "1.1.1.1:3", "1.1.1.1:1"}, hostPorts(peers.unselectedPeers(nil)),
+		"Peers should be ordered by score")
+	assert.Equal(t, []string{"1.1.1.1:2", "1.1.1.1:1"}, hostPorts(peers.unselectedPeers(map[string]struct{}{"1.1.1.1:3": {}})),
+		"Previously selected peers should be excluded")
+	assert.Equal(t, heapBefore, peers.peerHeap.peerScores, "Listing peers should not modify the heap")
+}
+
+func TestHealthyPeerFallbackExclusions(t *testing.T) {
+	ch, err := NewChannel("svc", &ChannelOptions{UnhealthyPeerCooldown: time.Minute})
+	require.NoError(t, err, "NewChannel failed")
+	defer ch.Close()
+
+	scores := map[string]uint64{
+		"1.1.1.1:1": 1,
+		"1.1.1.1:2": 2,
+		"1.1.1.1:3": 3,
+	}
+	peers := ch.GetSubChannel("svc").Peers()
+	peers.SetStrategy(ScoreCalculatorFunc(func(p *Peer) uint64 {
+		return scores[p.HostPort()]
+	}))
+	for hostPort := range scores {
+		peers.Add(hostPort)
+	}
+
+	unhealthy := peers.Copy()["1.1.1.1:1"]
+	unhealthy.connectFailedAt.Store(time.Now().UnixNano())
+
+	tests := []struct {
+		msg          string
+		rs           *RequestState
+		wantHostPort string
+	}{
+		{
+			msg:          "no request state",
+			wantHostPort: "1.1.1.1:2",
+		},
+		{
+			msg: "avoid previously selected peers",
+			rs: &RequestState{
+				SelectedPeers: map[string]struct{}{"1.1.1.1:2": {}},
+				lastSelected:  "1.1.1.1:2",
+			},
+			wantHostPort: "1.1.1.1:3",
+		},
+		{
+			msg: "all peers selected, avoid the last selected peer",
+			rs: &RequestState{
+				SelectedPeers: map[string]struct{}{"1.1.1.1:2": {}, "1.1.1.1:3": {}},
+				lastSelected:  "1.1.1.1:3",
+			},
+			wantHostPort: "1.1.1.1:2",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := peers.healthyPeer(unhealthy, tt.rs)
+		require.NoError(t, err, "%v: healthyPeer failed", tt.msg)
+		assert.Equal(t, tt.wantHostPort, got.HostPort(), "%v: unexpected peer", tt.msg)
+	}
+
+	peers.RLock()
+	chosen := peers.peersByHostPort["1.1.1.1:2"].chosenCount.Load()
+	peers.RUnlock()
+	assert.EqualValues(t, 2, chosen, "Fallback peer selections should be counted")
+}
@@ -20,7 +20,82 @@
 
 package tchannel
 
-import "math"
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// PeerSelection is the algorithm used by a PeerList to select a peer.
+type PeerSelection int
+
+const (
+	// PeerSelectionScore selects the peer with the best score from the peer
+	// list's ScoreCalculator. This is the default.
+	PeerSelectionScore PeerSelection = iota
+
+	// PeerSelectionP2C uses the power of two random choices: it picks two
+	// random peers, and selects the one with fewer pending outbound calls.
+	// This avoids herding calls onto whichever peer currently has the best
+	// score. Peers that are unhealthy (see UnhealthyPeerCooldown) are not
+	// picked, and the ScoreCalculator is not used.
+	PeerSelectionP2C
+
+	// PeerSelectionLatency is like PeerSelectionP2C, but selects the peer with
+	// the lower moving average of call latency, weighted by pending outbound
+	// calls. This biases calls towards faster peers. The average decays over
+	// time (see ChannelOptions.PeerLatencyHalfLife), so peers that haven't been
+	// called for a while are probed again. Peers without any latency samples
+	// are compared using only their pending outbound calls.
+	PeerSelectionLatency
+
+	// PeerSelectionAdaptive is like PeerSelectionLatency, but also penalizes
+	// peers by the moving average of their call error rate, so peers that are
+	// slow or failing calls receive less traffic. Calls that fail with
+	// application errors, or that are cancelled by the caller, don't count as
+	// errors. Like latency, the error rate decays over time, so a peer that
+	// recovers gets traffic again. The stats used are exposed by Peer.Stats.
+	PeerSelectionAdaptive
+)
+
+const (
+	// p2cMaxSamples is the number of random peers PeerSelectionP2C samples to
+	// find two peers that can be selected, before falling back to the score.
+	p2cMaxSamples = 8
+
+	// defaultPeerLatencyHalfLife is the default half-life of the peer latency
+	// and error rate moving averages.
+	defaultPeerLatencyHalfLife = 10 * time.Second
+
+	// maxPeerErrorRate caps the error rate used by PeerSelectionAdaptive, so
+	// a peer failing every call is penalized heavily, but can still be picked
+	// if it's the only choice.
+	maxPeerErrorRate = 0.99
+)
+
+// PeerSelectionStrategy is a custom algorithm used to select peers, for load
+// balancing that isn't covered by PeerSelection, such as round-robin. It's set
+// using ChannelOptions.PeerSelectionStrategy, and is used by all of the
+// channel's peer lists.
+//
+// SelectPeer is called while the peer list is locked, so implementations must
+// be fast, and must not call back into the peer list. Both methods may be
+// called concurrently.
+// This is an unstable API - breaking changes are likely.
+type PeerSelectionStrategy interface {
+	// SelectPeer returns the peer to use for a call. peers only contains the
+	// peers that can be selected for the call: peers that were already tried
+	// by the call, and unhealthy peers (see UnhealthyPeerCooldown) are
+	// excluded. If it returns nil, or a peer that's not in peers, the peer
+	// with the best score is selected instead. The peers slice is reused, so
+	// it must not be retained after SelectPeer returns.
+	SelectPeer(peers []*Peer) *Peer
+
+	// CallEnded is called when an outbound call attempt to a peer ends, with
+	// the attempt's latency, and the error if the attempt failed. Calls that
+	// return application errors are successful attempts, so err is nil.
+	CallEnded(peer *Peer, latency time.Duration, err error)
+}
 
 // ScoreCalculator defines the interface to calculate the score.
 type ScoreCalculator interface {
@@ -87,3 +162,166 @@ func (preferIncomingCalculator) GetScore(p *Peer) uint64 {
 func newPreferIncomingCalculator() preferIncomingCalculator {
 	return preferIncomingCalculator{}
 }
+
+// choosePeerP2C samples random peers until it finds two that can be chosen,
+// and returns the one with fewer pending outbound calls. It returns nil if no
+// peer that can be chosen was found within p2cMaxSamples samples.
+// Note that a Write lock must be held to call this function.
+func (l *PeerList) choosePeerP2C(canChoosePeer func(hostPort string) bool) *peerScore {
+	size := l.peerHeap.Len()
+	if size == 0 {
+		return nil
+	}
+
+	cooldown := l.parent.unhealthyCooldown
+	now := l.parent.timeNow()
+
+	eligible := func(ps *peerScore) bool {
+		return canChoosePeer(ps.HostPort()) && ps.Peer.isHealthy(now, cooldown)
+	}
+
+	var first, second *peerScore
+	firstIndex := -1
+	for i := 0; i < p2cMaxSamples; i++ {
+		var index int
+		if first == nil {
+			index = l.peerHeap.rng.Intn(size)
+		} else {
+			// Sample from the other peers so the two choices are distinct.
+			if size == 1 {
+				break
+			}
+			index = l.peerHeap.rng.Intn(size - 1)
+			if index >= firstIndex {
+				index++
+			}
+		}
+
+		ps := l.peerHeap.peerScores[index]
+		if !eligible(ps) {
+			continue
+		}
+		if first == nil {
+			first, firstIndex = ps, index
+			continue
+		}
+		second = ps
+		break
+	}
+
+	if second != nil && l.p2cPrefers(second.Peer, first.Peer, now) {
+		return second
+	}
+	return first
+}
+
+// choosePeerStrategy returns the peer selected by strategy from the healthy
+// peers that can be chosen, or nil if the strategy did not select one of them.
+// Note that a Write lock must be held to call this function.
+func (l *PeerList) choosePeerStrategy(strategy PeerSelectionStrategy, canChoosePeer func(hostPort string) bool) *peerScore {
+	cooldown := l.parent.unhealthyCooldown
+	now := l.parent.timeNow()
+
+	// Reuse the slice from previous selections, to avoid allocating while
+	// the list is locked.
+	peers := l.strategyPeers[:0]
+	for _, ps := range l.peerHeap.peerScores {
+		if canChoosePeer(ps.HostPort()) && ps.Peer.isHealthy(now, cooldown) {
+			peers = append(peers, ps.Peer)
+		}
+	}
+	l.strategyPeers = peers
+	if len(peers) == 0 {
+		return nil
+	}
+
+	selected := strategy.SelectPeer(peers)
+	for _, p := range peers {
+		if p == selected {
+			return l.peersByHostPort[p.HostPort()]
+		}
+	}
+	return nil
+}
+
+// p2cPrefers returns whether the peer list's selection prefers a over b.
+func (l *PeerList) p2cPrefers(a, b *Peer, now time.Time) bool {
+	aPending, bPending := a.NumPendingOutbound(), b.NumPendingOutbound()
+	if l.selection != PeerSelectionLatency && l.selection != PeerSelectionAdaptive {
+		return aPending < bPending
+	}
+
+	aCost, bCost := float64(aPending+1), float64(bPending+1)
+	if l.selection == PeerSelectionAdaptive {
+		aCost *= errorPenalty(a.errorRate.at(now))
+		bCost *= errorPenalty(b.errorRate.at(now))
+	}
+
+	aLatency, bLatency := a.latency.at(now), b.latency.at(now)
+	if aLatency == 0 || bLatency == 0 {
+		// Without samples for both peers, latency isn't compared so that cold
+		// peers are tried without being flooded with calls.
+		return aCost < bCost
+	}
+	return aLatency*aCost < bLatency*bCost
+}
+
+// errorPenalty returns the factor that a peer's cost is multiplied by for its
+// error rate. It's the expected number of attempts for a call to succeed.
+func errorPenalty(errorRate float64) float64 {
+	if errorRate > maxPeerErrorRate {
+		errorRate = maxPeerErrorRate
+	}
+	return 1 / (1 - errorRate)
+}
+
+// movingAverage is an exponentially-weighted moving average of samples, such
+// as the latency of calls to a peer. The weight of the average halves every
+// halfLife, both when a new sample is added, and when the average is read, so
+// the average for a peer that isn't called decays back towards zero.
+type movingAverage struct {
+	sync.Mutex
+
+	halfLife   time.Duration
+	average    float64
+	lastSample time.Time
+}
+
+// decay returns the weight of the average at now.
+// Note that the lock must be held to call this function.
+func (a *movingAverage) decay(now time.Time) float64 {
+	halfLife := a.halfLife
+	if halfLife <= 0 {
+		halfLife = defaultPeerLatencyHalfLife
+	}
+	elapsed := now.Sub(a.lastSample)
+	if elapsed <= 0 {
+		return 1
+	}
+	return math.Exp2(-float64(elapsed) / float64(halfLife))
+}
+
+// record adds a sample to the average.
+func (a *movingAverage) record(now time.Time, v float64) {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.lastSample.IsZero() {
+		a.average = v
+	} else {
+		w := a.decay(now)
+		a.average = a.average*w + v*(1-w)
+	}
+	a.lastSample = now
+}
+
+// at returns the average at now, or 0 if there are no samples.
+func (a *movingAverage) at(now time.Time) float64 {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.lastSample.IsZero() {
+		return 0
+	}
+	return a.average * a.decay(now)
+}
@@ -21,7 +21,10 @@
 package tchannel_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"sort"
 	"sync"
 	"testing"
@@ -36,6 +39,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/uber-go/atomic"
+	"golang.org/x/net/context"
 )
 
 func fakePeer(t *testing.T, ch *Channel, hostPort string) *Peer {
@@ -66,6 +70,147 @@ func TestGetPeerNoPeer(t *testing.T) {
 	assert.Nil(t, peer, "should not return peer")
 }
 
+func TestPeerListExportImport(t *testing.T) {
+	ch1 := testutils.NewClient(t, nil)
+	defer ch1.Close()
+
+	hostPorts := []string{"1.1.1.1:3", "1.1.1.1:1", "1.1.1.1:2"}
+	for _, hostPort := range hostPorts {
+		ch1.Peers().Add(hostPort)
+	}
+
+	exported := ch1.Peers().Export()
+	assert.Equal(t, []PeerState{
+		{HostPort: "1.1.1.1:1"},
+		{HostPort: "1.1.1.1:2"},
+		{HostPort: "1.1.1.1:3"},
+	}, exported, "Unexpected exported peers")
+
+	// Verify the exported state round-trips through JSON.
+	serialized, err := json.Marshal(exported)
+	require.NoError(t, err, "Failed to marshal peers")
+	var deserialized []PeerState
+	require.NoError(t, json.Unmarshal(serialized, &deserialized), "Failed to unmarshal peers")
+
+	ch2 := testutils.NewClient(t, nil)
+	defer ch2.Close()
+
+	require.NoError(t, ch2.Peers().Import(deserialized), "Import failed")
+	assert.Equal(t, exported, ch2.Peers().Export(), "Imported peers should match exported peers")
+
+	// Importing does not connect to any of the peers.
+	for hostPort, peer := range ch2.Peers().Copy() {
+		in, out := peer.NumConnections()
+		assert.Equal(t, 0, in+out, "Unexpected connections to %v", hostPort)
+	}
+
+	// Importing again should not add duplicate peers.
+	require.NoError(t, ch2.Peers().Import(deserialized), "Import failed")
+	assert.Equal(t, 3, ch2.Peers().Len(), "Unexpected number of peers after re-import")
+}
+
+func TestPeerIdentity(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	peers := ch.Peers()
+	p1 := peers.AddWithIdentity("1.1.1.1:1", "instance-a")
+	assert.Equal(t, "instance-a", p1.Identity(), "Unexpected identity")
+	assert.True(t, p1 == peers.AddWithIdentity("1.1.1.1:1", "instance-a"), "Re-adding should return the same peer")
+	peers.AddWithIdentity("1.1.1.1:2", "instance-b")
+	assert.Equal(t, 2, peers.Len(), "Unexpected number of peers")
+
+	// The logical peer moves to a new address, which replaces the old peer.
+	p3 := peers.AddWithIdentity("1.1.1.1:3", "instance-a")
+	assert.Equal(t, "1.1.1.1:3", p3.HostPort(), "Unexpected host:port")
+	assert.Equal(t, 2, peers.Len(), "Address change should not add a duplicate peer")
+	_, ok := peers.Copy()["1.1.1.1:1"]
+	assert.False(t, ok, "Previous address should be removed")
+
+	got, ok := peers.GetByIdentity("instance-a")
+	require.True(t, ok, "GetByIdentity failed")
+	assert.True(t, p3 == got, "GetByIdentity should return the peer at the new address")
+	_, ok = peers.GetByIdentity("instance-c")
+	assert.False(t, ok, "GetByIdentity should fail for unknown identities")
+
+	// Introspection and exported state include the identity.
+	assert.Equal(t, []PeerState{
+		{HostPort: "1.1.1.1:2", Identity: "instance-b"},
+		{HostPort: "1.1.1.1:3", Identity: "instance-a"},
+	}, peers.Export(), "Unexpected exported peers")
+	state := ch.IntrospectState(&IntrospectionOptions{IncludeEmptyPeers: true})
+	assert.Equal(t, "instance-a", state.RootPeers["1.1.1.1:3"].Identity, "Unexpected identity in introspection")
+	assert.Equal(t, "1.1.1.1:3", state.RootPeers["1.1.1.1:3"].HostPort, "Unexpected host:port in introspection")
+
+	require.NoError(t, peers.Remove("1.1.1.1:3"), "Remove failed")
+	_, ok = peers.GetByIdentity("instance-a")
+	assert.False(t, ok, "Removed peer should not be found by identity")
+}
+
+func TestPeerIdentityAddressChange(t *testing.T) {
+	s1 := testutils.NewServer(t, nil)
+	defer s1.Close()
+	s2 := testutils.NewServer(t, nil)
+	defer s2.Close()
+	for _, s := range []*Channel{s1, s2} {
+		s := s
+		testutils.RegisterFunc(s, "whoami", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return &raw.Res{Arg3: []byte(s.PeerInfo().HostPort)}, nil
+		})
+	}
+
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	call := func() string {
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		_, arg3, _, err := raw.CallSC(ctx, client.GetSubChannel(testutils.DefaultServerName), "whoami", nil, nil)
+		require.NoError(t, err, "Call failed")
+		return string(arg3)
+	}
+
+	client.Peers().AddWithIdentity(s1.PeerInfo().HostPort, "server")
+	assert.Equal(t, s1.PeerInfo().HostPort, call(), "Call should go to the first address")
+
+	client.Peers().AddWithIdentity(s2.PeerInfo().HostPort, "server")
+	assert.Equal(t, 1, client.Peers().Len(), "Identity should dedup the peer")
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, s2.PeerInfo().HostPort, call(), "Calls should go to the new address")
+	}
+}
+
+func TestPeerListImportInvalid(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	err := ch.Peers().Import([]PeerState{{HostPort: "1.1.1.1:1"}, {}})
+	assert.Error(t, err, "Import with a blank host:port should fail")
+	assert.Equal(t, 0, ch.Peers().Len(), "No peers should be added on failure")
+}
+
+func TestPeerListUpdate(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	peers := ch.Peers()
+	peers.Add("1.1.1.1:1")
+	peers.AddWithIdentity("1.1.1.1:2", "instance-2")
+	p3 := peers.Add("1.1.1.1:3")
+
+	peers.Update([]string{"1.1.1.1:3", "1.1.1.1:4", "1.1.1.1:4"})
+	assert.Equal(t, []PeerState{{HostPort: "1.1.1.1:3"}, {HostPort: "1.1.1.1:4"}}, peers.Export(),
+		"Unexpected peers after update")
+	assert.Equal(t, p3, peers.GetOrAdd("1.1.1.1:3"), "Existing peers should be kept")
+
+	_, ok := peers.GetByIdentity("instance-2")
+	assert.False(t, ok, "Identity of removed peer should be removed")
+
+	peers.Update(nil)
+	assert.Equal(t, 0, peers.Len(), "Update with no peers should remove all peers")
+}
+
 func TestGetPeerSinglePeer(t *testing.T) {
 	ch := testutils.NewClient(t, nil)
 	defer ch.Close()
@@ -216,6 +361,63 @@ func TestPeerRemoveClosedConnection(t *testing.T) {
 	})
 }
 
+func TestPeerConnectionCount(t *testing.T) {
+	ctx, cancel := NewContext(time.Second)
+	defer cancel()
+
+	WithVerifiedServer(t, nil, func(ch *Channel, hostPort string) {
+		unblock := make(chan struct{})
+		testutils.RegisterFunc(ch, "block", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			<-unblock
+			return &raw.Res{}, nil
+		})
+
+		client := testutils.NewClient(t, nil)
+		defer client.Close()
+
+		p := client.Peers().Add(hostPort)
+		assert.False(t, p.HasActiveConnection(), "New peer should have no active connections")
+		active, idle := p.ConnectionCount()
+		assert.Equal(t, 0, active, "Unexpected active connections")
+		assert.Equal(t, 0, idle, "Unexpected idle connections")
+
+		c1, err := p.Connect(ctx)
+		require.NoError(t, err, "Failed to connect")
+		c2, err := p.Connect(ctx)
+		require.NoError(t, err, "Failed to connect")
+
+		assert.True(t, p.HasActiveConnection(), "Peer should have an active connection")
+		active, idle = p.ConnectionCount()
+		assert.Equal(t, 2, active, "Unexpected active connections")
+		assert.Equal(t, 2, idle, "Unexpected idle connections")
+
+		callDone := make(chan struct{})
+		go func() {
+			defer close(callDone)
+			_, _, _, err := raw.Call(ctx, client, hostPort, ch.ServiceName(), "block", nil, nil)
+			assert.NoError(t, err, "Call failed")
+		}()
+
+		assert.True(t, testutils.WaitFor(time.Second, func() bool {
+			_, idle := p.ConnectionCount()
+			return idle == 1
+		}), "Expected one connection to be busy with the call")
+		close(unblock)
+		<-callDone
+
+		require.NoError(t, c1.Close(), "Failed to close first connection")
+		active, idle = p.ConnectionCount()
+		assert.Equal(t, 1, active, "Unexpected active connections after close")
+		assert.Equal(t, 1, idle, "Unexpected idle connections after close")
+
+		require.NoError(t, c2.Close(), "Failed to close second connection")
+		assert.False(t, p.HasActiveConnection(), "Peer should have no active connections after close")
+		active, idle = p.ConnectionCount()
+		assert.Equal(t, 0, active, "Unexpected active connections after close")
+		assert.Equal(t, 0, idle, "Unexpected idle connections after close")
+	})
+}
+
 func TestPeerConnectCancelled(t *testing.T) {
 	WithVerifiedServer(t, nil, func(ch *Channel, hostPort string) {
 		ctx, cancel := NewContext(100 * time.Millisecond)
@@ -1199,3 +1401,468 @@ func createConstScoreStrategy(score uint64) (calc ScoreCalculator) {
 		return score
 	})
 }
+
+func TestUnhealthyPeersFailFast(t *testing.T) {
+	const cooldown = time.Minute
+
+	// blockingDialer simulates peers that are down and never respond, so
+	// dials block until the context's deadline.
+	var dials atomic.Int32
+	blockingDialer := func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		dials.Inc()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	clock := testutils.NewStubClock(time.Now())
+	opts := testutils.NewOpts().
+		SetDialer(blockingDialer).
+		SetTimeNow(clock.Now)
+	opts.UnhealthyPeerCooldown = cooldown
+	ch := testutils.NewClient(t, opts)
+	defer ch.Close()
+
+	sc := ch.GetSubChannel("svc", Isolated)
+	sc.Peers().Add("1.1.1.1:1")
+	sc.Peers().Add("1.1.1.1:2")
+
+	call := func(timeout time.Duration) (time.Duration, error) {
+		ctx, cancel := NewContext(timeout)
+		defer cancel()
+
+		started := time.Now()
+		_, err := sc.BeginCall(ctx, "method", nil)
+		return time.Since(started), err
+	}
+
+	// Each peer is tried once, and fails when the call's deadline passes.
+	for i := 0; i < 2; i++ {
+		_, err := call(testutils.Timeout(20 * time.Millisecond))
+		require.Error(t, err, "Call to a down peer should fail")
+		assert.NotEqual(t, ErrNoHealthyPeers, err, "Peer should be tried before it's marked unhealthy")
+	}
+	assert.EqualValues(t, 2, dials.Load(), "Expected each peer to be dialed once")
+
+	// All peers are now unhealthy, so calls fail fast without dialing.
+	elapsed, err := call(testutils.Timeout(time.Second))
+	assert.Equal(t, ErrNoHealthyPeers, err, "Expected calls to fail fast with no healthy peers")
+	assert.True(t, elapsed < testutils.Timeout(100*time.Millisecond), "Call took %v, expected it to fail fast", elapsed)
+	assert.EqualValues(t, 2, dials.Load(), "Unhealthy peers should not be dialed")
+
+	// Once the cooldown passes, peers are tried again.
+	clock.Elapse(cooldown)
+	_, err = call(testutils.Timeout(20 * time.Millisecond))
+	require.Error(t, err, "Call to a down peer should fail")
+	assert.NotEqual(t, ErrNoHealthyPeers, err, "Peers should be retried after the cooldown")
+	assert.EqualValues(t, 3, dials.Load(), "Expected a peer to be dialed after the cooldown")
+}
+
+func TestUnhealthyPeersDisabledByDefault(t *testing.T) {
+	opts := testutils.NewOpts().SetDialer(func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		return nil, errors.New("dial failed")
+	})
+	ch := testutils.NewClient(t, opts)
+	defer ch.Close()
+
+	sc := ch.GetSubChannel("svc", Isolated)
+	sc.Peers().Add("1.1.1.1:1")
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := NewContext(time.Second)
+		_, err := sc.BeginCall(ctx, "method", nil)
+		cancel()
+		assert.EqualError(t, err, "dial failed", "Expected dial error without UnhealthyPeerCooldown")
+	}
+}
+
+func TestPeerListGetN(t *testing.T) {
+	scores := map[string]uint64{
+		"1.1.1.1:1": 40,
+		"1.1.1.1:2": 10,
+		"1.1.1.1:3": 30,
+		"1.1.1.1:4": 20,
+	}
+
+	failingDialer := func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}
+	opts := testutils.NewOpts().SetDialer(failingDialer)
+	opts.UnhealthyPeerCooldown = time.Minute
+	ch := testutils.NewClient(t, opts)
+	defer ch.Close()
+
+	peers := ch.GetSubChannel("svc", Isolated).Peers()
+	_, err := peers.GetN(2)
+	assert.Equal(t, ErrNoPeers, err, "GetN on an empty list should fail")
+
+	peers.SetStrategy(ScoreCalculatorFunc(func(p *Peer) uint64 {
+		return scores[p.HostPort()]
+	}))
+	for hostPort := range scores {
+		peers.Add(hostPort)
+	}
+
+	hostPorts := func(peers []*Peer) []string {
+		var hps []string
+		for _, p := range peers {
+			hps = append(hps, p.HostPort())
+		}
+		return hps
+	}
+
+	got, err := peers.GetN(3)
+	require.NoError(t, err, "GetN failed")
+	assert.Equal(t, []string{"1.1.1.1:2", "1.1.1.1:4", "1.1.1.1:3"}, hostPorts(got),
+		"Peers should be distinct and ordered by score")
+
+	got, err = peers.GetN(10)
+	require.NoError(t, err, "GetN failed")
+	assert.Equal(t, []string{"1.1.1.1:2", "1.1.1.1:4", "1.1.1.1:3", "1.1.1.1:1"}, hostPorts(got),
+		"GetN should return all peers when n is larger than the list")
+
+	// Peers that recently failed to connect are excluded.
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+	p, ok := peers.Copy()["1.1.1.1:4"]
+	require.True(t, ok, "Peer not found")
+	_, err = p.Connect(ctx)
+	require.Error(t, err, "Connect should fail")
+
+	got, err = peers.GetN(3)
+	require.NoError(t, err, "GetN failed")
+	assert.Equal(t, []string{"1.1.1.1:2", "1.1.1.1:3", "1.1.1.1:1"}, hostPorts(got),
+		"Unhealthy peers should be excluded")
+	assert.Equal(t, 4, peers.Len(), "GetN should not remove peers")
+
+	for hostPort := range scores {
+		if p := peers.Copy()[hostPort]; p != nil {
+			p.Connect(ctx)
+		}
+	}
+	_, err = peers.GetN(2)
+	assert.Equal(t, ErrNoHealthyPeers, err, "GetN should fail if all peers are unhealthy")
+}
+
+func TestPeerListGetNRotatesEqualScores(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	peers := ch.Peers()
+	peers.SetStrategy(createConstScoreStrategy(0))
+	for i := 0; i < 4; i++ {
+		peers.Add(fmt.Sprintf("1.1.1.1:%v", i))
+	}
+
+	seen := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		got, err := peers.GetN(2)
+		require.NoError(t, err, "GetN failed")
+		require.Len(t, got, 2, "Unexpected number of peers")
+		assert.NotEqual(t, got[0].HostPort(), got[1].HostPort(), "Peers should be distinct")
+		for _, p := range got {
+			seen[p.HostPort()]++
+		}
+	}
+	assert.Len(t, seen, 4, "Repeated calls should spread across peers with equal scores")
+}
+
+func TestPeerSelectionP2C(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		busy := ts.Server()
+		idle := ts.NewServer(nil)
+
+		unblock := make(chan struct{})
+		testutils.RegisterFunc(busy, "block", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			<-unblock
+			return &raw.Res{}, nil
+		})
+
+		clientOpts := testutils.NewOpts()
+		clientOpts.PeerSelection = PeerSelectionP2C
+		client := ts.NewClient(clientOpts)
+
+		// Start calls to one of the peers that stay pending.
+		const pendingCalls = 3
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		var wg sync.WaitGroup
+		for i := 0; i < pendingCalls; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, _, err := raw.Call(ctx, client, busy.PeerInfo().HostPort, busy.ServiceName(), "block", nil, nil)
+				assert.NoError(t, err, "Call failed")
+			}()
+		}
+		busyPeer := client.Peers().GetOrAdd(busy.PeerInfo().HostPort)
+		require.True(t, testutils.WaitFor(time.Second, func() bool {
+			return busyPeer.NumPendingOutbound() == pendingCalls
+		}), "Calls did not start")
+
+		peers := client.GetSubChannel("svc", Isolated).Peers()
+		peers.Add(busy.PeerInfo().HostPort)
+		peers.Add(idle.PeerInfo().HostPort)
+		for i := 0; i < 20; i++ {
+			peer, err := peers.Get(nil)
+			require.NoError(t, err, "Get failed")
+			assert.Equal(t, idle.PeerInfo().HostPort, peer.HostPort(), "Expected the peer with fewer pending calls")
+		}
+
+		close(unblock)
+		wg.Wait()
+	})
+}
+
+func TestPeerSelectionLatency(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		slow := ts.Server()
+		fast := ts.NewServer(nil)
+		testutils.RegisterFunc(slow, "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			time.Sleep(20 * time.Millisecond)
+			return &raw.Res{}, nil
+		})
+		testutils.RegisterFunc(fast, "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return &raw.Res{}, nil
+		})
+
+		clientOpts := testutils.NewOpts()
+		clientOpts.PeerSelection = PeerSelectionLatency
+		clientOpts.PeerLatencyHalfLife = time.Hour
+		client := ts.NewClient(clientOpts)
+
+		peers := client.GetSubChannel("svc", Isolated).Peers()
+		peers.Add(slow.PeerInfo().HostPort)
+		peers.Add(fast.PeerInfo().HostPort)
+
+		// Peers without latency samples are selected by pending calls.
+		selected := make(map[string]int)
+		for i := 0; i < 20; i++ {
+			peer, err := peers.Get(nil)
+			require.NoError(t, err, "Get failed")
+			selected[peer.HostPort()]++
+		}
+		assert.Len(t, selected, 2, "Expected cold peers to be selected")
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		for _, server := range []*Channel{slow, fast} {
+			call, err := peers.GetOrAdd(server.PeerInfo().HostPort).BeginCall(ctx, server.ServiceName(), "echo", nil)
+			require.NoError(t, err, "BeginCall failed")
+			_, _, _, err = raw.WriteArgs(call, nil, nil)
+			require.NoError(t, err, "Call failed")
+		}
+
+		for i := 0; i < 20; i++ {
+			peer, err := peers.Get(nil)
+			require.NoError(t, err, "Get failed")
+			assert.Equal(t, fast.PeerInfo().HostPort, peer.HostPort(), "Expected the peer with lower latency")
+		}
+	})
+}
+
+func TestPeerStatsRecordsTimeouts(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay().DisableLogVerification()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		release := make(chan struct{})
+		testutils.RegisterFunc(ts.Server(), "block", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			<-release
+			return &raw.Res{}, nil
+		})
+
+		clientOpts := testutils.NewOpts()
+		clientOpts.PeerLatencyHalfLife = time.Hour
+		client := ts.NewClient(clientOpts)
+		peer := client.Peers().GetOrAdd(ts.HostPort())
+
+		ctx, cancel := NewContext(20 * time.Millisecond)
+		defer cancel()
+		_, _, _, err := raw.CallSC(ctx, client.GetSubChannel(ts.ServiceName()), "block", nil, nil)
+		close(release)
+		require.Equal(t, ErrTimeout, err, "Expected call to time out")
+
+		stats := peer.Stats()
+		assert.InDelta(t, 1.0, stats.ErrorRate, 0.001, "Timeouts should count towards the error rate")
+		assert.NotZero(t, stats.Latency, "Expected latency for the timed out call")
+	})
+}
+
+func TestPeerSelectionAdaptive(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		failing := ts.Server()
+		healthy := ts.NewServer(nil)
+		testutils.RegisterFunc(failing, "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return nil, ErrServerBusy
+		})
+		testutils.RegisterFunc(healthy, "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return &raw.Res{}, nil
+		})
+
+		clientOpts := testutils.NewOpts()
+		clientOpts.PeerSelection = PeerSelectionAdaptive
+		clientOpts.PeerLatencyHalfLife = time.Hour
+		client := ts.NewClient(clientOpts)
+
+		peers := client.GetSubChannel("svc", Isolated).Peers()
+		peers.Add(failing.PeerInfo().HostPort)
+		peers.Add(healthy.PeerInfo().HostPort)
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		for _, server := range []*Channel{failing, healthy} {
+			call, err := peers.GetOrAdd(server.PeerInfo().HostPort).BeginCall(ctx, server.ServiceName(), "echo", nil)
+			require.NoError(t, err, "BeginCall failed")
+			_, _, _, err = raw.WriteArgs(call, nil, nil)
+			if server == failing {
+				require.Error(t, err, "Call to failing peer should fail")
+			} else {
+				require.NoError(t, err, "Call failed")
+			}
+		}
+
+		failingStats := peers.GetOrAdd(failing.PeerInfo().HostPort).Stats()
+		assert.InDelta(t, 1.0, failingStats.ErrorRate, 0.001, "Unexpected error rate for failing peer")
+		assert.NotZero(t, failingStats.Latency, "Expected latency for failing peer")
+		assert.Zero(t, peers.GetOrAdd(healthy.PeerInfo().HostPort).Stats().ErrorRate,
+			"Unexpected error rate for healthy peer")
+
+		state := client.IntrospectState(&IntrospectionOptions{IncludeEmptyPeers: true})
+		assert.InDelta(t, 1.0, state.RootPeers[failing.PeerInfo().HostPort].Stats.ErrorRate, 0.001,
+			"Expected peer stats in introspection")
+
+		for i := 0; i < 20; i++ {
+			peer, err := peers.Get(nil)
+			require.NoError(t, err, "Get failed")
+			assert.Equal(t, healthy.PeerInfo().HostPort, peer.HostPort(), "Expected the peer without errors")
+		}
+	})
+}
+
+func TestPeerSelectionP2CSkipsUnhealthyPeers(t *testing.T) {
+	const downHostPort = "1.1.1.1:1"
+
+	failingDialer := func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}
+	opts := testutils.NewOpts().SetDialer(failingDialer)
+	opts.UnhealthyPeerCooldown = time.Minute
+	opts.PeerSelection = PeerSelectionP2C
+	ch := testutils.NewClient(t, opts)
+	defer ch.Close()
+
+	peers := ch.GetSubChannel("svc", Isolated).Peers()
+	for i := 1; i <= 4; i++ {
+		peers.Add(fmt.Sprintf("1.1.1.1:%v", i))
+	}
+
+	ctx, cancel := NewContext(time.Second)
+	defer cancel()
+	_, err := peers.GetOrAdd(downHostPort).Connect(ctx)
+	require.Error(t, err, "Connect to the down peer should fail")
+
+	selected := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		peer, err := peers.Get(nil)
+		require.NoError(t, err, "Get failed")
+		selected[peer.HostPort()]++
+	}
+	assert.Equal(t, 0, selected[downHostPort], "Unhealthy peer should not be selected")
+	assert.Len(t, selected, 3, "Expected all healthy peers to be selected")
+}
+
+// roundRobinStrategy is a PeerSelectionStrategy that selects peers in order of
+// their host:port, and records the calls that ended.
+type roundRobinStrategy struct {
+	sync.Mutex
+	next  int
+	ended []string
+}
+
+func (s *roundRobinStrategy) SelectPeer(peers []*Peer) *Peer {
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].HostPort() < peers[j].HostPort()
+	})
+
+	s.Lock()
+	defer s.Unlock()
+	peer := peers[s.next%len(peers)]
+	s.next++
+	return peer
+}
+
+func (s *roundRobinStrategy) CallEnded(peer *Peer, latency time.Duration, err error) {
+	s.Lock()
+	defer s.Unlock()
+	s.ended = append(s.ended, fmt.Sprintf("%v err=%v", peer.HostPort(), err))
+}
+
+func (s *roundRobinStrategy) Ended() []string {
+	s.Lock()
+	defer s.Unlock()
+	return append([]string(nil), s.ended...)
+}
+
+func TestPeerSelectionStrategy(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		s1 := ts.Server()
+		s2 := ts.NewServer(nil)
+		testutils.RegisterEcho(s1, nil)
+		testutils.RegisterEcho(s2, nil)
+
+		hostPorts := []string{s1.PeerInfo().HostPort, s2.PeerInfo().HostPort}
+		sort.Strings(hostPorts)
+
+		strategy := &roundRobinStrategy{}
+		clientOpts := testutils.NewOpts()
+		clientOpts.PeerSelection = PeerSelectionP2C
+		clientOpts.PeerSelectionStrategy = strategy
+		client := ts.NewClient(clientOpts)
+
+		sc := client.GetSubChannel(ts.ServiceName())
+		for _, hostPort := range hostPorts {
+			sc.Peers().Add(hostPort)
+		}
+
+		var want []string
+		for i := 0; i < 4; i++ {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			_, _, _, err := raw.CallSC(ctx, sc, "echo", nil, nil)
+			cancel()
+			require.NoError(t, err, "Call %v failed", i)
+			want = append(want, hostPorts[i%2]+" err=<nil>")
+		}
+
+		assert.Equal(t, want, strategy.Ended(), "Expected calls to alternate between peers")
+	})
+}
+
+func TestPeerSelectionStrategyCallTimeout(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay().DisableLogVerification()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		release := make(chan struct{})
+		testutils.RegisterFunc(ts.Server(), "block", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			<-release
+			return &raw.Res{}, nil
+		})
+
+		strategy := &roundRobinStrategy{}
+		clientOpts := testutils.NewOpts()
+		clientOpts.PeerSelectionStrategy = strategy
+		client := ts.NewClient(clientOpts)
+
+		sc := client.GetSubChannel(ts.ServiceName())
+		sc.Peers().Add(ts.HostPort())
+
+		ctx, cancel := NewContext(20 * time.Millisecond)
+		defer cancel()
+		_, _, _, err := raw.CallSC(ctx, sc, "block", nil, nil)
+		close(release)
+		require.Equal(t, ErrTimeout, err, "Expected call to time out")
+
+		want := []string{fmt.Sprintf("%v err=%v", ts.HostPort(), ErrTimeout)}
+		assert.Equal(t, want, strategy.Ended(), "Strategy should be told about the timeout")
+	})
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// _defaultReadyConnectionsInterval is how often MinReadyConnections checks
+// for peers without connections by default.
+const _defaultReadyConnectionsInterval = time.Second
+
+// WarmUpResult is the result of warming up a single peer.
+type WarmUpResult struct {
+	// HostPort is the host:port of the peer.
+	HostPort string
+
+	// Err is the error connecting to the peer, or nil if the peer has an
+	// active connection.
+	Err error
+
+	// AlreadyConnected is whether the peer had an active connection, so it
+	// was not dialed.
+	AlreadyConnected bool
+}
+
+// WarmUp connects to all peers in the list that do not have an active
+// connection, dialing at most concurrency peers at a time. If concurrency is
+// zero or negative, all peers are dialed at once. Dials use the given context,
+// and peers that have not been dialed by the time the context is done fail
+// with the context's error. It returns the result for each peer, sorted by
+// host:port.
+//
+// Peers that fail to connect are not marked as unhealthy (see
+// ChannelOptions.UnhealthyPeerCooldown), so calls will still lazily dial them.
+func (l *PeerList) WarmUp(ctx context.Context, concurrency int) []WarmUpResult {
+	peers := l.Copy()
+	sorted := make([]*Peer, 0, len(peers))
+	for _, peer := range peers {
+		sorted = append(sorted, peer)
+	}
+	return warmUpPeers(ctx, sorted, concurrency)
+}
+
+// WarmUp connects to the n peers that calls through the subchannel would be
+// sent to first, so that the first calls don't wait for a connection to be
+// established. Unhealthy peers are skipped. If n is zero or negative, or more
+// than the number of peers, all healthy peers are connected to. Peers are
+// dialed concurrently using the given context, and the result for each peer
+// is returned sorted by host:port.
+//
+// Like PeerList.WarmUp, peers that fail to connect are not marked unhealthy.
+func (c *SubChannel) WarmUp(ctx context.Context, n int) []WarmUpResult {
+	return warmUpPeers(ctx, c.Peers().topPeers(n), 0 /* concurrency */)
+}
+
+// MinReadyConnections is a SubChannelOption that keeps connections to the n
+// peers that calls through the subchannel would be sent to first, so calls
+// don't wait for connections to be established after peers are added or
+// connections are closed. Peers are checked every checkInterval, which
+// defaults to a second, until the channel is closed.
+func MinReadyConnections(n int, checkInterval time.Duration) SubChannelOption {
+	return func(s *SubChannel) {
+		if checkInterval <= 0 {
+			checkInterval = _defaultReadyConnectionsInterval
+		}
+		go s.keepConnectionsReady(n, checkInterval)
+	}
+}
+
+// keepConnectionsReady periodically warms up the subchannel's top n peers
+// until the channel is closed.
+func (c *SubChannel) keepConnectionsReady(n int, checkInterval time.Duration) {
+	ch := c.topChannel
+	ticker := ch.timeTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if ch.State() != ChannelClient && ch.State() != ChannelListening {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), checkInterval)
+			c.WarmUp(ctx, n)
+			cancel()
+		case <-ch.ClosedChan():
+			return
+		}
+	}
+}
+
+// warmUpPeers connects to the given peers that do not have an active
+// connection, dialing at most concurrency peers at a time, and returns the
+// result for each peer sorted by host:port.
+func warmUpPeers(ctx context.Context, peers []*Peer, concurrency int) []WarmUpResult {
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].HostPort() < peers[j].HostPort()
+	})
+
+	if concurrency <= 0 || concurrency > len(peers) {
+		concurrency = len(peers)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	results := make([]WarmUpResult, len(peers))
+	for i, peer := range peers {
+		results[i].HostPort = peer.HostPort()
+
+		if peer.HasActiveConnection() {
+			results[i].AlreadyConnected = true
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			results[i].Err = GetContextError(err)
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = GetContextError(ctx.Err())
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *WarmUpResult, peer *Peer) {
+			defer wg.Done()
+			result.Err = peer.warmUp(ctx)
+			<-sem
+		}(&results[i], peer)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// warmUp creates an outbound connection to the peer if it doesn't have an
+// active connection. Unlike Connect, failures don't mark the peer unhealthy.
+func (p *Peer) warmUp(ctx context.Context) error {
+	if _, ok := p.getActiveConn(); ok {
+		return nil
+	}
+
+	// Lock here so we don't race with a call lazily creating a connection.
+	p.newConnLock.Lock()
+	defer p.newConnLock.Unlock()
+
+	if _, ok := p.getActiveConn(); ok {
+		return nil
+	}
+
+	if _, err := p.channel.Connect(ctx, p.hostPort); err != nil {
+		return err
+	}
+	p.connectFailedAt.Store(0)
+	return nil
+}
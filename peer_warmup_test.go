@@ -0,0 +1,236 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/atomic"
+	"golang.org/x/net/context"
+)
+
+func TestPeerListWarmUp(t *testing.T) {
+	const (
+		numPeers    = 20
+		concurrency = 3
+	)
+
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+
+	// The dialer tracks the number of concurrent dials, and connects all peers
+	// to the same server except for peers with a port that's a multiple of 5,
+	// which fail to connect.
+	var active, maxActive, dials atomic.Int32
+	dialer := func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		dials.Inc()
+		n := active.Inc()
+		defer active.Dec()
+		for {
+			max := maxActive.Load()
+			if n <= max || maxActive.CAS(max, n) {
+				break
+			}
+		}
+
+		time.Sleep(testutils.Timeout(5 * time.Millisecond))
+		if strings.HasSuffix(hostPort, "0") || strings.HasSuffix(hostPort, "5") {
+			return nil, errors.New("connection refused")
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, server.PeerInfo().HostPort)
+	}
+
+	client := testutils.NewClient(t, testutils.NewOpts().SetDialer(dialer))
+	defer client.Close()
+	for i := 1; i <= numPeers; i++ {
+		client.Peers().Add(fmt.Sprintf("1.1.1.1:%v", i))
+	}
+
+	ctx, cancel := NewContext(testutils.Timeout(5 * time.Second))
+	defer cancel()
+
+	results := client.Peers().WarmUp(ctx, concurrency)
+	require.Len(t, results, numPeers, "Expected a result for every peer")
+	assert.True(t, maxActive.Load() <= concurrency, "Dialed %v peers concurrently, limit is %v", maxActive.Load(), concurrency)
+	assert.True(t, maxActive.Load() > 1, "Expected peers to be dialed concurrently")
+
+	var failed []string
+	for i, r := range results {
+		if i > 0 {
+			assert.True(t, results[i-1].HostPort < r.HostPort, "Results should be sorted by host:port")
+		}
+		if r.Err != nil {
+			failed = append(failed, r.HostPort)
+		}
+	}
+	assert.Equal(t, []string{"1.1.1.1:10", "1.1.1.1:15", "1.1.1.1:20", "1.1.1.1:5"}, failed, "Unexpected failed peers")
+
+	// Peers with active connections are not dialed again.
+	dials.Store(0)
+	results = client.Peers().WarmUp(ctx, concurrency)
+	assert.Equal(t, int32(len(failed)), dials.Load(), "Only peers without connections should be dialed")
+	for _, r := range results {
+		if r.Err == nil {
+			assert.True(t, r.AlreadyConnected, "Peer %v should be reported as already connected", r.HostPort)
+			assert.True(t, client.Peers().GetOrAdd(r.HostPort).HasActiveConnection(), "Peer %v should be connected", r.HostPort)
+		} else {
+			assert.False(t, r.AlreadyConnected, "Failed peer %v should not be reported as connected", r.HostPort)
+		}
+	}
+}
+
+func TestPeerListWarmUpFailureKeepsPeerHealthy(t *testing.T) {
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+	testutils.RegisterEcho(server, nil)
+
+	// The first dial fails, and later dials connect to the server.
+	var dials atomic.Int32
+	dialer := func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		if dials.Inc() == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, server.PeerInfo().HostPort)
+	}
+
+	opts := testutils.NewOpts().SetDialer(dialer)
+	opts.UnhealthyPeerCooldown = time.Hour
+	client := testutils.NewClient(t, opts)
+	defer client.Close()
+	sc := client.GetSubChannel(server.ServiceName())
+	sc.Peers().Add("1.1.1.1:1")
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	results := sc.Peers().WarmUp(ctx, 1)
+	require.Len(t, results, 1, "Expected a result for the peer")
+	require.Error(t, results[0].Err, "Expected warm up to fail")
+
+	// The warm up failure should not stop calls from lazily dialing the peer.
+	_, _, _, err := raw.CallSC(ctx, sc, "echo", nil, nil)
+	require.NoError(t, err, "Call should lazily connect to the peer")
+	assert.EqualValues(t, 2, dials.Load(), "Expected the call to dial the peer")
+}
+
+func TestPeerListWarmUpDeadline(t *testing.T) {
+	// Dials block until the context's deadline.
+	blockingDialer := func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	client := testutils.NewClient(t, testutils.NewOpts().SetDialer(blockingDialer))
+	defer client.Close()
+	for i := 0; i < 10; i++ {
+		client.Peers().Add(fmt.Sprintf("1.1.1.1:%v", i))
+	}
+
+	ctx, cancel := NewContext(testutils.Timeout(50 * time.Millisecond))
+	defer cancel()
+
+	started := time.Now()
+	results := client.Peers().WarmUp(ctx, 2)
+	assert.True(t, time.Since(started) < testutils.Timeout(time.Second), "WarmUp should stop at the context deadline")
+	require.Len(t, results, 10, "Expected a result for every peer")
+	for _, r := range results {
+		assert.Error(t, r.Err, "Peer %v should fail to connect", r.HostPort)
+	}
+}
+
+func TestSubChannelWarmUp(t *testing.T) {
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+
+	var dials atomic.Int32
+	dialer := func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		dials.Inc()
+		return (&net.Dialer{}).DialContext(ctx, network, server.PeerInfo().HostPort)
+	}
+
+	client := testutils.NewClient(t, testutils.NewOpts().SetDialer(dialer))
+	defer client.Close()
+	sc := client.GetSubChannel(server.ServiceName(), Isolated)
+	for i := 1; i <= 5; i++ {
+		sc.Peers().Add(fmt.Sprintf("1.1.1.1:%v", i))
+	}
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	results := sc.WarmUp(ctx, 2)
+	require.Len(t, results, 2, "Expected a result for the top 2 peers")
+	assert.EqualValues(t, 2, dials.Load(), "Expected only the top 2 peers to be dialed")
+
+	assert.Equal(t, 2, numConnectedPeers(sc), "Expected 2 peers to be connected")
+	for _, r := range results {
+		assert.NoError(t, r.Err, "Peer %v should connect", r.HostPort)
+		assert.True(t, sc.Peers().GetOrAdd(r.HostPort).HasActiveConnection(), "Peer %v should be connected", r.HostPort)
+	}
+
+	// Warming up all peers only dials peers that aren't connected.
+	results = sc.WarmUp(ctx, 0)
+	require.Len(t, results, 5, "Expected a result for every peer")
+	assert.EqualValues(t, 5, dials.Load(), "Expected the remaining peers to be dialed")
+}
+
+func TestMinReadyConnections(t *testing.T) {
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+
+	dialer := func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.PeerInfo().HostPort)
+	}
+
+	client := testutils.NewClient(t, testutils.NewOpts().SetDialer(dialer))
+	sc := client.GetSubChannel(server.ServiceName(), Isolated, MinReadyConnections(2, 10*time.Millisecond))
+	for i := 1; i <= 5; i++ {
+		sc.Peers().Add(fmt.Sprintf("1.1.1.1:%v", i))
+	}
+
+	require.True(t, testutils.WaitFor(testutils.Timeout(time.Second), func() bool {
+		return numConnectedPeers(sc) == 2
+	}), "Expected connections to the top peers to be established")
+
+	client.Close()
+}
+
+func numConnectedPeers(sc *SubChannel) int {
+	var connected int
+	for _, p := range sc.Peers().Copy() {
+		if p.HasActiveConnection() {
+			connected++
+		}
+	}
+	return connected
+}
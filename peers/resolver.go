@@ -0,0 +1,339 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/tchannel-go"
+)
+
+const defaultPollInterval = 30 * time.Second
+
+// ErrWatcherClosed is returned from Watcher.Next once the watcher is closed.
+var ErrWatcherClosed = errors.New("peers: watcher closed")
+
+// Resolver discovers the host:ports of a service from an external discovery
+// system, such as DNS or a service registry.
+type Resolver interface {
+	// Watch starts watching for changes to the service's host:ports.
+	Watch() (Watcher, error)
+}
+
+// Watcher returns updates to a service's host:ports from a Resolver.
+type Watcher interface {
+	// Next blocks until the host:ports change and returns the complete set of
+	// host:ports. The first call returns the current host:ports. Once the
+	// watcher is closed, Next returns ErrWatcherClosed.
+	Next() ([]string, error)
+
+	// Close stops the watcher, unblocking any calls to Next.
+	Close()
+}
+
+// ResolverOptions are options for the built-in resolvers that poll for changes.
+type ResolverOptions struct {
+	// Interval is how often to poll for changes. Defaults to 30 seconds.
+	Interval time.Duration
+}
+
+// pollWatcher is a Watcher that polls for the host:ports every interval.
+type pollWatcher struct {
+	interval time.Duration
+	poll     func() ([]string, error)
+
+	quit      chan struct{}
+	closeOnce sync.Once
+
+	// These are only used from Next, which is not called concurrently.
+	polled  bool
+	hasLast bool
+	last    []string
+}
+
+func newPollWatcher(opts *ResolverOptions, poll func() ([]string, error)) *pollWatcher {
+	w := &pollWatcher{
+		interval: defaultPollInterval,
+		poll:     poll,
+		quit:     make(chan struct{}),
+	}
+	if opts != nil && opts.Interval > 0 {
+		w.interval = opts.Interval
+	}
+	return w
+}
+
+func (w *pollWatcher) Next() ([]string, error) {
+	for {
+		if w.polled {
+			select {
+			case <-time.After(w.interval):
+			case <-w.quit:
+				return nil, ErrWatcherClosed
+			}
+		}
+		w.polled = true
+
+		hostPorts, err := w.poll()
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(hostPorts)
+		if !w.hasLast || !equalHostPorts(w.last, hostPorts) {
+			w.hasLast = true
+			w.last = hostPorts
+			return hostPorts, nil
+		}
+	}
+}
+
+func (w *pollWatcher) Close() {
+	w.closeOnce.Do(func() { close(w.quit) })
+}
+
+func equalHostPorts(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type dnsSRVResolver struct {
+	service, proto, name string
+	opts                 *ResolverOptions
+	lookupSRV            func(service, proto, name string) (string, []*net.SRV, error)
+}
+
+// NewDNSSRVResolver returns a Resolver that polls the DNS SRV records for
+// _service._proto.name, using the target and port of each record as a
+// host:port. If service and proto are empty, name is looked up directly.
+func NewDNSSRVResolver(service, proto, name string, opts *ResolverOptions) Resolver {
+	return &dnsSRVResolver{
+		service:   service,
+		proto:     proto,
+		name:      name,
+		opts:      opts,
+		lookupSRV: net.LookupSRV,
+	}
+}
+
+func (r *dnsSRVResolver) Watch() (Watcher, error) {
+	return newPollWatcher(r.opts, r.resolve), nil
+}
+
+func (r *dnsSRVResolver) resolve() ([]string, error) {
+	_, records, err := r.lookupSRV(r.service, r.proto, r.name)
+	if err != nil {
+		return nil, err
+	}
+
+	hostPorts := make([]string, 0, len(records))
+	for _, srv := range records {
+		host := strings.TrimSuffix(srv.Target, ".")
+		hostPorts = append(hostPorts, net.JoinHostPort(host, strconv.Itoa(int(srv.Port))))
+	}
+	return hostPorts, nil
+}
+
+type fileResolver struct {
+	path string
+	opts *ResolverOptions
+}
+
+// NewFileResolver returns a Resolver that polls a file containing a JSON
+// array of host:ports, such as a file that's kept up to date by a
+// configuration management agent.
+func NewFileResolver(path string, opts *ResolverOptions) Resolver {
+	return &fileResolver{path, opts}
+}
+
+func (r *fileResolver) Watch() (Watcher, error) {
+	w := newPollWatcher(r.opts, r.resolve)
+	// Fail early if the file can't be read, rather than on the first update.
+	if _, err := r.resolve(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (r *fileResolver) resolve() ([]string, error) {
+	contents, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var hostPorts []string
+	if err := json.Unmarshal(contents, &hostPorts); err != nil {
+		return nil, err
+	}
+	return hostPorts, nil
+}
+
+// PushResolver is a Resolver that's updated by calling Update, which can be
+// used to integrate discovery systems that push changes, such as Consul or
+// etcd watches.
+type PushResolver struct {
+	sync.Mutex
+
+	hostPorts []string
+	version   int
+	changed   chan struct{}
+}
+
+// NewPushResolver returns a PushResolver with the given initial host:ports.
+func NewPushResolver(hostPorts ...string) *PushResolver {
+	return &PushResolver{
+		hostPorts: hostPorts,
+		changed:   make(chan struct{}),
+	}
+}
+
+// Update changes the host:ports, notifying all watchers.
+func (r *PushResolver) Update(hostPorts []string) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.hostPorts = append([]string(nil), hostPorts...)
+	r.version++
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+func (r *PushResolver) current() ([]string, int, <-chan struct{}) {
+	r.Lock()
+	defer r.Unlock()
+	return r.hostPorts, r.version, r.changed
+}
+
+// Watch returns a Watcher for updates to the resolver.
+func (r *PushResolver) Watch() (Watcher, error) {
+	return &pushWatcher{
+		resolver: r,
+		version:  -1,
+		quit:     make(chan struct{}),
+	}, nil
+}
+
+type pushWatcher struct {
+	resolver  *PushResolver
+	version   int
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+func (w *pushWatcher) Next() ([]string, error) {
+	for {
+		hostPorts, version, changed := w.resolver.current()
+		if version != w.version {
+			w.version = version
+			return append([]string(nil), hostPorts...), nil
+		}
+
+		select {
+		case <-changed:
+		case <-w.quit:
+			return nil, ErrWatcherClosed
+		}
+	}
+}
+
+func (w *pushWatcher) Close() {
+	w.closeOnce.Do(func() { close(w.quit) })
+}
+
+// SyncOptions are options for syncing a peer list with a Resolver.
+type SyncOptions struct {
+	// OnError is called with any errors from the resolver, after which the
+	// peer list is left unchanged until the next successful update.
+	OnError func(error)
+
+	// AllowEmpty allows updates with no host:ports to remove all peers. By
+	// default, these updates are ignored, since they're usually caused by a
+	// problem with discovery, and the existing peers are more useful.
+	AllowEmpty bool
+}
+
+// Syncer keeps a peer list in sync with a Resolver.
+type Syncer struct {
+	watcher Watcher
+	done    chan struct{}
+}
+
+// Sync starts a Syncer that updates the peer list with the host:ports from
+// the resolver, adding and removing peers as they change. It returns an error
+// if the resolver fails to start watching.
+func Sync(pl *tchannel.PeerList, r Resolver, opts *SyncOptions) (*Syncer, error) {
+	var syncOpts SyncOptions
+	if opts != nil {
+		syncOpts = *opts
+	}
+
+	w, err := r.Watch()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Syncer{watcher: w, done: make(chan struct{})}
+	go s.run(pl, syncOpts)
+	return s, nil
+}
+
+func (s *Syncer) run(pl *tchannel.PeerList, opts SyncOptions) {
+	defer close(s.done)
+
+	for {
+		hostPorts, err := s.watcher.Next()
+		if err == ErrWatcherClosed {
+			return
+		}
+		if err != nil {
+			if opts.OnError != nil {
+				opts.OnError(err)
+			}
+			continue
+		}
+
+		if len(hostPorts) == 0 && !opts.AllowEmpty {
+			continue
+		}
+		pl.Update(hostPorts)
+	}
+}
+
+// Close stops syncing the peer list. The peers in the list are not changed.
+func (s *Syncer) Close() {
+	s.watcher.Close()
+	<-s.done
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peers
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testResolverOpts = &ResolverOptions{Interval: 10 * time.Millisecond}
+
+func peerHostPorts(pl *tchannel.PeerList) []string {
+	var hostPorts []string
+	for hostPort := range pl.Copy() {
+		hostPorts = append(hostPorts, hostPort)
+	}
+	sort.Strings(hostPorts)
+	return hostPorts
+}
+
+func waitForPeers(t *testing.T, pl *tchannel.PeerList, want ...string) {
+	ok := testutils.WaitFor(time.Second, func() bool {
+		return assert.ObjectsAreEqual(want, peerHostPorts(pl))
+	})
+	assert.True(t, ok, "Expected peers %v, got %v", want, peerHostPorts(pl))
+}
+
+func TestDNSSRVResolver(t *testing.T) {
+	var (
+		records   []*net.SRV
+		lookupErr error
+		gotName   string
+	)
+	r := NewDNSSRVResolver("tchannel", "tcp", "svc.example.com", testResolverOpts).(*dnsSRVResolver)
+	r.lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		gotName = "_" + service + "._" + proto + "." + name
+		return "", records, lookupErr
+	}
+
+	records = []*net.SRV{
+		{Target: "host2.example.com.", Port: 2000},
+		{Target: "host1.example.com.", Port: 1000},
+	}
+	w, err := r.Watch()
+	require.NoError(t, err, "Watch failed")
+	defer w.Close()
+
+	hostPorts, err := w.Next()
+	require.NoError(t, err, "Next failed")
+	assert.Equal(t, "_tchannel._tcp.svc.example.com", gotName, "Unexpected SRV name")
+	assert.Equal(t, []string{"host1.example.com:1000", "host2.example.com:2000"}, hostPorts)
+
+	lookupErr = errors.New("lookup failed")
+	_, err = w.Next()
+	assert.Equal(t, lookupErr, err, "Next should return lookup errors")
+
+	lookupErr = nil
+	records = append(records, &net.SRV{Target: "host3.example.com.", Port: 3000})
+	hostPorts, err = w.Next()
+	require.NoError(t, err, "Next failed")
+	assert.Len(t, hostPorts, 3, "Expected new record")
+}
+
+func TestFileResolver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peers")
+	require.NoError(t, err, "Failed to create temp dir")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "peers.json")
+	_, err = NewFileResolver(path, testResolverOpts).Watch()
+	assert.Error(t, err, "Watch should fail if the file does not exist")
+
+	writeFile := func(contents string) {
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644), "Failed to write file")
+	}
+	writeFile(`["1.1.1.1:1", "1.1.1.1:2"]`)
+
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	syncer, err := Sync(client.Peers(), NewFileResolver(path, testResolverOpts), nil)
+	require.NoError(t, err, "Sync failed")
+	defer syncer.Close()
+	waitForPeers(t, client.Peers(), "1.1.1.1:1", "1.1.1.1:2")
+
+	writeFile(`["1.1.1.1:2", "1.1.1.1:3"]`)
+	waitForPeers(t, client.Peers(), "1.1.1.1:2", "1.1.1.1:3")
+}
+
+func TestSyncPushResolver(t *testing.T) {
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	r := NewPushResolver("1.1.1.1:1")
+	syncer, err := Sync(client.Peers(), r, nil)
+	require.NoError(t, err, "Sync failed")
+	waitForPeers(t, client.Peers(), "1.1.1.1:1")
+
+	r.Update([]string{"1.1.1.1:2", "1.1.1.1:3"})
+	waitForPeers(t, client.Peers(), "1.1.1.1:2", "1.1.1.1:3")
+
+	// Empty updates are ignored by default.
+	r.Update(nil)
+	r.Update([]string{"1.1.1.1:4"})
+	waitForPeers(t, client.Peers(), "1.1.1.1:4")
+
+	syncer.Close()
+	r.Update([]string{"1.1.1.1:5"})
+	time.Sleep(testutils.Timeout(10 * time.Millisecond))
+	assert.Equal(t, []string{"1.1.1.1:4"}, peerHostPorts(client.Peers()), "Peers should not change after Close")
+}
+
+func TestSyncAllowEmpty(t *testing.T) {
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	r := NewPushResolver("1.1.1.1:1")
+	syncer, err := Sync(client.Peers(), r, &SyncOptions{AllowEmpty: true})
+	require.NoError(t, err, "Sync failed")
+	defer syncer.Close()
+	waitForPeers(t, client.Peers(), "1.1.1.1:1")
+
+	r.Update(nil)
+	waitForPeers(t, client.Peers())
+}
+
+func TestSyncErrors(t *testing.T) {
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	lookupErr := errors.New("lookup failed")
+	r := NewDNSSRVResolver("", "", "svc", testResolverOpts).(*dnsSRVResolver)
+	r.lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		return "", nil, lookupErr
+	}
+
+	errC := make(chan error, 1)
+	syncer, err := Sync(client.Peers(), r, &SyncOptions{
+		OnError: func(err error) {
+			select {
+			case errC <- err:
+			default:
+			}
+		},
+	})
+	require.NoError(t, err, "Sync failed")
+	defer syncer.Close()
+
+	select {
+	case err := <-errC:
+		assert.Equal(t, lookupErr, err, "Unexpected error")
+	case <-time.After(time.Second):
+		t.Fatal("OnError was not called")
+	}
+}
@@ -38,7 +38,31 @@ func (ch *Channel) outboundHandshake(ctx context.Context, c net.Conn, outboundHP
 		err = ch.initError(c, outbound, 1, err)
 	}()
 
+	jumboSize := ch.jumboFrames.frameSize()
+	local := localFeatures(ch.connectionOptions, jumboSize)
 	msg := &initReq{initMessage: ch.getInitMessage(ctx, 1)}
+	if compression := ch.connectionOptions.Compression; compression != CompressionNone && local.Has(FeatureCompression) {
+		msg.initParams[InitParamCompression] = string(compression)
+	}
+	if provider := ch.connectionOptions.AuthProvider; provider != nil {
+		credentials, err := provider.Credentials(ctx, outboundHP)
+		if err != nil {
+			return nil, err
+		}
+		msg.initParams[InitParamAuth] = credentials
+	}
+	if checksumTypes := ch.advertisedChecksumTypes(); checksumTypes != "" {
+		msg.initParams[InitParamChecksumTypes] = checksumTypes
+	}
+	if argCompression := ch.advertisedArgCompression(); argCompression != "" {
+		msg.initParams[InitParamArgCompression] = argCompression
+	}
+	if len(local) > 0 {
+		msg.initParams[InitParamFeatures] = local.String()
+	}
+	if local.Has(FeatureLargeFrames) {
+		msg.initParams[InitParamMaxFrameSize] = strconv.Itoa(jumboSize)
+	}
 	if err := ch.writeMessage(c, msg); err != nil {
 		return nil, err
 	}
@@ -62,7 +86,15 @@ func (ch *Channel) outboundHandshake(ctx context.Context, c net.Conn, outboundHP
 		return nil, NewWrappedSystemError(ErrCodeProtocol, err)
 	}
 
-	return ch.newConnection(c, 1 /* initialID */, outboundHP, remotePeer, remotePeerAddress, events), nil
+	compression := CompressionNone
+	if local.Has(FeatureCompression) {
+		compression = negotiateCompression(ch.connectionOptions.Compression, res.initParams)
+	}
+	argCompression := ch.negotiateArgCompression(res.initParams)
+	checksumType := negotiateChecksumType(ch.connectionOptions.ChecksumType, res.initParams)
+	features := negotiateFeatures(local, res.initParams)
+	features.jumboFrameSize = negotiateJumboFrameSize(jumboSize, features, res.initParams)
+	return ch.newConnection(c, 1 /* initialID */, outboundHP, remotePeer, remotePeerAddress, compression, argCompression, checksumType, features, events), nil
 }
 
 func (ch *Channel) inboundHandshake(ctx context.Context, c net.Conn, events connectionEvents) (_ *Connection, err error) {
@@ -83,17 +115,51 @@ func (ch *Channel) inboundHandshake(ctx context.Context, c net.Conn, events conn
 		return nil, unsupportedProtocolVersion(req.Version)
 	}
 
+	// Reject new connections while draining so the peer sends calls elsewhere.
+	if ch.Draining() {
+		return nil, errChannelDraining
+	}
+
 	remotePeer, remotePeerAddress, err := parseRemotePeer(req.initParams, c.RemoteAddr())
 	if err != nil {
 		return nil, NewWrappedSystemError(ErrCodeProtocol, err)
 	}
 
+	if err := validateAuth(ctx, ch.connectionOptions.AuthValidator, remotePeer, req.initParams); err != nil {
+		return nil, err
+	}
+
+	jumboSize := ch.jumboFrames.frameSize()
+	local := localFeatures(ch.connectionOptions, jumboSize)
 	res := &initRes{initMessage: ch.getInitMessage(ctx, id)}
+	compression := CompressionNone
+	if local.Has(FeatureCompression) {
+		compression = negotiateCompression(ch.connectionOptions.Compression, req.initParams)
+	}
+	if compression != CompressionNone {
+		res.initParams[InitParamCompression] = string(compression)
+	}
+	if checksumTypes := ch.advertisedChecksumTypes(); checksumTypes != "" {
+		res.initParams[InitParamChecksumTypes] = checksumTypes
+	}
+	argCompression := ch.negotiateArgCompression(req.initParams)
+	if argCompression != nil {
+		res.initParams[InitParamArgCompression] = argCompression.name
+	}
+	if len(local) > 0 {
+		res.initParams[InitParamFeatures] = local.String()
+	}
+	if local.Has(FeatureLargeFrames) {
+		res.initParams[InitParamMaxFrameSize] = strconv.Itoa(jumboSize)
+	}
 	if err := ch.writeMessage(c, res); err != nil {
 		return nil, err
 	}
 
-	return ch.newConnection(c, 0 /* initialID */, "" /* outboundHP */, remotePeer, remotePeerAddress, events), nil
+	checksumType := negotiateChecksumType(ch.connectionOptions.ChecksumType, req.initParams)
+	features := negotiateFeatures(local, req.initParams)
+	features.jumboFrameSize = negotiateJumboFrameSize(jumboSize, features, req.initParams)
+	return ch.newConnection(c, 0 /* initialID */, "" /* outboundHP */, remotePeer, remotePeerAddress, compression, argCompression, checksumType, features, events), nil
 }
 
 func (ch *Channel) getInitParams() initParams {
@@ -125,12 +191,17 @@ func (ch *Channel) initError(c net.Conn, connDir connectionDirection, id uint32,
 		return nil
 	}
 
-	ch.log.WithFields(LogFields{
+	logger := ch.log.WithFields(LogFields{
 		{"connectionDirection", connDir},
 		{"localAddr", c.LocalAddr().String()},
 		{"remoteAddr", c.RemoteAddr().String()},
 		ErrField(err),
-	}...).Error("Failed during connection handshake.")
+	}...)
+	if err == errChannelDraining {
+		logger.Info("Rejected connection handshake while draining.")
+	} else {
+		logger.Error("Failed during connection handshake.")
+	}
 
 	if ne, ok := err.(net.Error); ok && ne.Timeout() {
 		err = ErrTimeout
@@ -0,0 +1,79 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+// CallPriority is the priority used to schedule a call's frames on a
+// connection. Frames for high priority calls are written before frames for
+// normal priority calls, so latency-sensitive traffic isn't queued behind
+// bulk data on a saturated connection.
+type CallPriority int
+
+const (
+	// PriorityNormal is the default priority for calls.
+	PriorityNormal CallPriority = iota
+
+	// PriorityHigh is for control-plane and health traffic, which should be
+	// sent before any normal priority frames that are waiting.
+	PriorityHigh
+)
+
+func (p CallPriority) String() string {
+	switch p {
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// parsePriority returns the priority for a Priority transport header value.
+// Missing or unknown values use the normal priority.
+func parsePriority(v string) CallPriority {
+	if v == PriorityHigh.String() {
+		return PriorityHigh
+	}
+	return PriorityNormal
+}
+
+// callPriority returns the priority for a call on the connection with the
+// given transport headers. Priorities are only used once both peers support
+// FeaturePriority, and calls use the normal priority otherwise.
+func (c *Connection) callPriority(headers transportHeaders) CallPriority {
+	if !c.SupportsFeature(FeaturePriority) {
+		return PriorityNormal
+	}
+	return parsePriority(headers[Priority])
+}
+
+// sendChFor returns the send channel for frames of the given priority.
+func (c *Connection) sendChFor(p CallPriority) chan *Frame {
+	if p == PriorityHigh {
+		return c.prioritySendCh
+	}
+	return c.sendCh
+}
+
+// pendingFrames returns the number of frames waiting to be written.
+func (c *Connection) pendingFrames() int {
+	return len(c.sendCh) + len(c.prioritySendCh)
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		value string
+		want  CallPriority
+	}{
+		{"", PriorityNormal},
+		{"normal", PriorityNormal},
+		{"high", PriorityHigh},
+		{"unknown", PriorityNormal},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, parsePriority(tt.value), "parsePriority(%q)", tt.value)
+	}
+}
+
+func TestNextFramePrefersPriority(t *testing.T) {
+	c := &Connection{
+		sendCh:         make(chan *Frame, 3),
+		prioritySendCh: make(chan *Frame, 3),
+		stopCh:         make(chan struct{}),
+	}
+
+	normal := []*Frame{NewFrame(0), NewFrame(0)}
+	high := []*Frame{NewFrame(0), NewFrame(0)}
+	for _, f := range normal {
+		c.sendCh <- f
+	}
+	for _, f := range high {
+		c.sendChFor(PriorityHigh) <- f
+	}
+	assert.Equal(t, 4, c.pendingFrames(), "Unexpected pending frames")
+
+	// High priority frames are returned first, even though they were queued
+	// after the normal priority frames.
+	assert.True(t, high[0] == c.nextFrame(), "Expected first high priority frame")
+	assert.True(t, high[1] == c.nextFrame(), "Expected second high priority frame")
+
+	// Frames are drained after the connection is stopped.
+	close(c.stopCh)
+	assert.True(t, normal[0] == c.nextFrame(), "Expected first normal priority frame")
+	assert.True(t, normal[1] == c.nextFrame(), "Expected second normal priority frame")
+	assert.Nil(t, c.nextFrame(), "Expected no frames after draining")
+}
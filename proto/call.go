@@ -0,0 +1,178 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package proto
+
+import (
+	"fmt"
+
+	"github.com/uber/tchannel-go"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+// Client is used to make proto calls to other services.
+type Client struct {
+	ch            *tchannel.Channel
+	targetService string
+	hostPort      string
+}
+
+// ClientOptions are options used when creating a client.
+type ClientOptions struct {
+	HostPort string
+}
+
+// NewClient returns a proto.Client used to make outbound proto calls.
+func NewClient(ch *tchannel.Channel, targetService string, opts *ClientOptions) *Client {
+	client := &Client{
+		ch:            ch,
+		targetService: targetService,
+	}
+	if opts != nil && opts.HostPort != "" {
+		client.hostPort = opts.HostPort
+	}
+	return client
+}
+
+func makeCall(call *tchannel.OutboundCall, headers map[string]string, arg, resp proto.Message) (map[string]string, *ErrApplication, string, error) {
+	arg2, err := encodeHeaders(tchannel.InjectOutboundSpan(call.Response(), headers))
+	if err != nil {
+		return nil, nil, "arg2 encode failed", err
+	}
+	if err := tchannel.NewArgWriter(call.Arg2Writer()).Write(arg2); err != nil {
+		return nil, nil, "arg2 write failed", err
+	}
+	arg3, err := marshal(arg)
+	if err != nil {
+		return nil, nil, "arg3 marshal failed", err
+	}
+	if err := tchannel.NewArgWriter(call.Arg3Writer()).Write(arg3); err != nil {
+		return nil, nil, "arg3 write failed", err
+	}
+
+	// Call Arg2Reader before checking application error.
+	var respArg2 []byte
+	if err := tchannel.NewArgReader(call.Response().Arg2Reader()).Read(&respArg2); err != nil {
+		return nil, nil, "arg2 read failed", err
+	}
+	respHeaders, err := decodeHeaders(respArg2)
+	if err != nil {
+		return nil, nil, "arg2 decode failed", err
+	}
+
+	var respArg3 []byte
+	if err := tchannel.NewArgReader(call.Response().Arg3Reader()).Read(&respArg3); err != nil {
+		return nil, nil, "arg3 read failed", err
+	}
+
+	// If this is an error response, unmarshal the structured error instead.
+	if call.Response().ApplicationError() {
+		appErr := &ErrApplication{}
+		if err := proto.Unmarshal(respArg3, appErr); err != nil {
+			return nil, nil, "arg3 unmarshal error failed", err
+		}
+		return respHeaders, appErr, "", nil
+	}
+
+	if err := proto.Unmarshal(respArg3, resp); err != nil {
+		return nil, nil, "arg3 unmarshal failed", err
+	}
+	return respHeaders, nil, "", nil
+}
+
+func (c *Client) startCall(ctx context.Context, method string, callOptions *tchannel.CallOptions) (*tchannel.OutboundCall, error) {
+	if c.hostPort != "" {
+		return c.ch.BeginCall(ctx, c.hostPort, c.targetService, method, callOptions)
+	}
+
+	return c.ch.GetSubChannel(c.targetService).BeginCall(ctx, method, callOptions)
+}
+
+// Call makes a proto call, with retries. If the handler returns an error,
+// Call returns an *ErrApplication.
+func (c *Client) Call(ctx Context, method string, arg, resp proto.Message) error {
+	var (
+		headers = ctx.Headers()
+
+		respHeaders map[string]string
+		respErr     *ErrApplication
+		errAt       string
+	)
+
+	err := c.ch.GetSubChannel(c.targetService).RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
+		respHeaders, respErr = nil, nil
+		errAt = "connect"
+
+		call, err := c.startCall(ctx, method, &tchannel.CallOptions{
+			Format:       tchannel.Proto,
+			RequestState: rs,
+		})
+		if err != nil {
+			return err
+		}
+
+		respHeaders, respErr, errAt, err = makeCall(call, headers, arg, resp)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %v", errAt, err)
+	}
+	if respErr != nil {
+		return respErr
+	}
+
+	ctx.SetResponseHeaders(respHeaders)
+	return nil
+}
+
+func wrapCall(ctx Context, call *tchannel.OutboundCall, arg, resp proto.Message) error {
+	respHeaders, respErr, errAt, err := makeCall(call, ctx.Headers(), arg, resp)
+	if err != nil {
+		return fmt.Errorf("%s: %v", errAt, err)
+	}
+	if respErr != nil {
+		return respErr
+	}
+
+	ctx.SetResponseHeaders(respHeaders)
+	return nil
+}
+
+// CallPeer makes a proto call using the given peer.
+func CallPeer(ctx Context, peer *tchannel.Peer, serviceName, method string, arg, resp proto.Message) error {
+	call, err := peer.BeginCall(ctx, serviceName, method, &tchannel.CallOptions{Format: tchannel.Proto})
+	if err != nil {
+		return err
+	}
+
+	return wrapCall(ctx, call, arg, resp)
+}
+
+// CallSC makes a proto call using the given subchannel.
+func CallSC(ctx Context, sc *tchannel.SubChannel, method string, arg, resp proto.Message) error {
+	call, err := sc.BeginCall(ctx, method, &tchannel.CallOptions{Format: tchannel.Proto})
+	if err != nil {
+		return err
+	}
+
+	return wrapCall(ctx, call, arg, resp)
+}
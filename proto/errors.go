@@ -0,0 +1,58 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package proto
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// ErrApplication is the structured error sent in arg3 of an application
+// error response. Handlers can return an *ErrApplication to set the error
+// type, any other error is sent with the type "error" and its message.
+// Clients receive application errors as an *ErrApplication.
+type ErrApplication struct {
+	Type    string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+// Reset implements proto.Message.
+func (e *ErrApplication) Reset() { *e = ErrApplication{} }
+
+// String implements proto.Message.
+func (e *ErrApplication) String() string { return proto.CompactTextString(e) }
+
+// ProtoMessage implements proto.Message.
+func (*ErrApplication) ProtoMessage() {}
+
+func (e *ErrApplication) Error() string {
+	return fmt.Sprintf("proto call failed: %v: %v", e.Type, e.Message)
+}
+
+// toErrApplication converts an error returned by a handler into the
+// structured error sent to the caller.
+func toErrApplication(err error) *ErrApplication {
+	if appErr, ok := err.(*ErrApplication); ok {
+		return appErr
+	}
+	return &ErrApplication{Type: "error", Message: err.Error()}
+}
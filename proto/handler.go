@@ -0,0 +1,201 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package proto
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/uber/tchannel-go"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/opentracing/opentracing-go"
+	"golang.org/x/net/context"
+)
+
+var (
+	typeOfError   = reflect.TypeOf((*error)(nil)).Elem()
+	typeOfContext = reflect.TypeOf((*Context)(nil)).Elem()
+	typeOfMessage = reflect.TypeOf((*proto.Message)(nil)).Elem()
+)
+
+// Handlers is the map from method names to handlers.
+type Handlers map[string]interface{}
+
+// verifyHandler ensures that the given t is a function with the following signature:
+// func(proto.Context, *ArgType)(*ResType, error)
+// where *ArgType and *ResType are proto messages.
+func verifyHandler(t reflect.Type) error {
+	if t.Kind() != reflect.Func || t.NumIn() != 2 || t.NumOut() != 2 {
+		return fmt.Errorf("handler should be of format func(proto.Context, *ArgType) (*ResType, error)")
+	}
+
+	validateArgRes := func(t reflect.Type, name string) error {
+		if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct || !t.Implements(typeOfMessage) {
+			return fmt.Errorf("%v should be a pointer to a proto message", name)
+		}
+		return nil
+	}
+
+	if t.In(0) != typeOfContext {
+		return fmt.Errorf("arg0 should be of type proto.Context")
+	}
+	if err := validateArgRes(t.In(1), "second argument"); err != nil {
+		return err
+	}
+	if err := validateArgRes(t.Out(0), "first return value"); err != nil {
+		return err
+	}
+	if !t.Out(1).AssignableTo(typeOfError) {
+		return fmt.Errorf("second return value should be an error")
+	}
+
+	return nil
+}
+
+type handler struct {
+	handler reflect.Value
+	argType reflect.Type
+	tracer  func() opentracing.Tracer
+}
+
+func toHandler(f interface{}) (*handler, error) {
+	hV := reflect.ValueOf(f)
+	if err := verifyHandler(hV.Type()); err != nil {
+		return nil, err
+	}
+	return &handler{handler: hV, argType: hV.Type().In(1)}, nil
+}
+
+// Register registers the specified methods specified as a map from method name to the
+// proto handler function. The handler functions should have the following signature:
+// func(proto.Context, *ArgType)(*ResType, error)
+func Register(registrar tchannel.Registrar, funcs Handlers, onError func(context.Context, error)) error {
+	handlers := make(map[string]*handler)
+
+	handler := tchannel.HandlerFunc(func(ctx context.Context, call *tchannel.InboundCall) {
+		h, ok := handlers[string(call.Method())]
+		if !ok {
+			onError(ctx, fmt.Errorf("call for unregistered method: %s", call.Method()))
+			return
+		}
+
+		if err := h.Handle(ctx, call); err != nil {
+			onError(ctx, err)
+		}
+	})
+
+	for m, f := range funcs {
+		h, err := toHandler(f)
+		if err != nil {
+			return fmt.Errorf("%v cannot be used as a handler: %v", m, err)
+		}
+		h.tracer = func() opentracing.Tracer {
+			return tchannel.TracerFromRegistrar(registrar)
+		}
+		handlers[m] = h
+		registrar.Register(handler, m)
+	}
+
+	return nil
+}
+
+// RegisterService registers every exported method of service that has the
+// handler signature func(proto.Context, *ArgType) (*ResType, error) as the
+// method "serviceName::MethodName". This allows registering an implementation
+// of a service interface generated from a proto IDL. Other methods are ignored.
+func RegisterService(registrar tchannel.Registrar, serviceName string, service interface{}, onError func(context.Context, error)) error {
+	sV := reflect.ValueOf(service)
+	funcs := make(Handlers)
+	for i := 0; i < sV.NumMethod(); i++ {
+		m := sV.Method(i)
+		if verifyHandler(m.Type()) != nil {
+			continue
+		}
+		funcs[serviceName+"::"+sV.Type().Method(i).Name] = m.Interface()
+	}
+	if len(funcs) == 0 {
+		return fmt.Errorf("%T has no methods that can be used as handlers", service)
+	}
+
+	return Register(registrar, funcs, onError)
+}
+
+// Handle deserializes the proto arguments and calls the underlying handler.
+func (h *handler) Handle(tctx context.Context, call *tchannel.InboundCall) error {
+	var arg2 []byte
+	if err := tchannel.NewArgReader(call.Arg2Reader()).Read(&arg2); err != nil {
+		return fmt.Errorf("arg2 read failed: %v", err)
+	}
+	headers, err := decodeHeaders(arg2)
+	if err != nil {
+		return fmt.Errorf("arg2 decode failed: %v", err)
+	}
+	tctx = tchannel.ExtractInboundSpan(tctx, call, headers, h.tracer())
+	ctx := WithHeaders(tctx, headers)
+
+	var arg3 []byte
+	if err := tchannel.NewArgReader(call.Arg3Reader()).Read(&arg3); err != nil {
+		return fmt.Errorf("arg3 read failed: %v", err)
+	}
+	arg := reflect.New(h.argType.Elem())
+	if err := proto.Unmarshal(arg3, arg.Interface().(proto.Message)); err != nil {
+		return fmt.Errorf("arg3 unmarshal failed: %v", err)
+	}
+
+	results := h.handler.Call([]reflect.Value{reflect.ValueOf(ctx), arg})
+
+	var res proto.Message
+	if !results[0].IsNil() {
+		res = results[0].Interface().(proto.Message)
+	}
+	// If an error was returned, we create an error arg3 to respond with.
+	if err := results[1].Interface(); err != nil {
+		if serr, ok := err.(tchannel.SystemError); ok {
+			return call.Response().SendSystemError(serr)
+		}
+
+		call.Response().SetApplicationError()
+		res = toErrApplication(err.(error))
+	}
+
+	respHeaders, err := encodeHeaders(ctx.ResponseHeaders())
+	if err != nil {
+		return fmt.Errorf("arg2 encode failed: %v", err)
+	}
+	if err := tchannel.NewArgWriter(call.Response().Arg2Writer()).Write(respHeaders); err != nil {
+		return err
+	}
+
+	resBytes, err := marshal(res)
+	if err != nil {
+		return fmt.Errorf("arg3 marshal failed: %v", err)
+	}
+	return tchannel.NewArgWriter(call.Response().Arg3Writer()).Write(resBytes)
+}
+
+// marshal serializes the given message, treating a nil message as empty.
+func marshal(m proto.Message) ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return proto.Marshal(m)
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package proto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/proto/gen-go/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func echo(ctx Context, req *test.EchoRequest) (*test.EchoResponse, error) {
+	ctx.SetResponseHeaders(map[string]string{"hdr": ctx.Headers()["hdr"] + "-resp"})
+	switch req.Message {
+	case "app-error":
+		return nil, &ErrApplication{Type: "badRequest", Message: "bad message"}
+	case "error":
+		return nil, errors.New("failed")
+	case "system-error":
+		return nil, tchannel.NewSystemError(tchannel.ErrCodeBusy, "busy")
+	}
+
+	res := &test.EchoResponse{}
+	for i := 0; i < int(req.Count); i++ {
+		res.Messages = append(res.Messages, req.Message)
+	}
+	return res, nil
+}
+
+func scheme(ctx Context, _ *test.EchoRequest) (*test.EchoResponse, error) {
+	return &test.EchoResponse{Messages: []string{tchannel.CurrentCall(ctx).ArgScheme()}}, nil
+}
+
+func withServer(t *testing.T, f func(ch *tchannel.Channel, client *Client)) {
+	ch, err := tchannel.NewChannel("svc", nil)
+	require.NoError(t, err)
+	defer ch.Close()
+
+	onError := func(ctx context.Context, err error) {
+		t.Errorf("onError(%v)", err)
+	}
+	require.NoError(t, Register(ch, Handlers{
+		"echo":   echo,
+		"scheme": scheme,
+	}, onError))
+	require.NoError(t, ch.ListenAndServe("127.0.0.1:0"))
+
+	f(ch, NewClient(ch, "svc", &ClientOptions{HostPort: ch.PeerInfo().HostPort}))
+}
+
+func TestRoundTrip(t *testing.T) {
+	withServer(t, func(ch *tchannel.Channel, client *Client) {
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+		ctx = WithHeaders(ctx, map[string]string{"hdr": "val"})
+
+		res := &test.EchoResponse{}
+		require.NoError(t, client.Call(ctx, "echo", &test.EchoRequest{Message: "hello", Count: 3}, res))
+		assert.Equal(t, []string{"hello", "hello", "hello"}, res.Messages, "Unexpected response")
+		assert.Equal(t, map[string]string{"hdr": "val-resp"}, ctx.ResponseHeaders(), "Unexpected response headers")
+	})
+}
+
+func TestCallPeerAndSubChannel(t *testing.T) {
+	withServer(t, func(ch *tchannel.Channel, _ *Client) {
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		res := &test.EchoResponse{}
+		peer := ch.Peers().Add(ch.PeerInfo().HostPort)
+		require.NoError(t, CallPeer(ctx, peer, "svc", "echo", &test.EchoRequest{Message: "peer", Count: 1}, res))
+		assert.Equal(t, []string{"peer"}, res.Messages, "Unexpected response from CallPeer")
+
+		res = &test.EchoResponse{}
+		require.NoError(t, CallSC(ctx, ch.GetSubChannel("svc"), "echo", &test.EchoRequest{Message: "sc", Count: 2}, res))
+		assert.Equal(t, []string{"sc", "sc"}, res.Messages, "Unexpected response from CallSC")
+	})
+}
+
+func TestArgScheme(t *testing.T) {
+	withServer(t, func(ch *tchannel.Channel, client *Client) {
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		res := &test.EchoResponse{}
+		require.NoError(t, client.Call(ctx, "scheme", &test.EchoRequest{}, res))
+		assert.Equal(t, []string{"proto"}, res.Messages, "Unexpected arg scheme")
+	})
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		msg     string
+		wantErr error
+	}{
+		{
+			msg:     "app-error",
+			wantErr: &ErrApplication{Type: "badRequest", Message: "bad message"},
+		},
+		{
+			msg:     "error",
+			wantErr: &ErrApplication{Type: "error", Message: "failed"},
+		},
+	}
+
+	withServer(t, func(ch *tchannel.Channel, client *Client) {
+		for _, tt := range tests {
+			ctx, cancel := NewContext(time.Second)
+			err := client.Call(ctx, "echo", &test.EchoRequest{Message: tt.msg}, &test.EchoResponse{})
+			cancel()
+			assert.Equal(t, tt.wantErr, err, "Unexpected error for %v", tt.msg)
+		}
+
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+		err := client.Call(ctx, "echo", &test.EchoRequest{Message: "system-error"}, &test.EchoResponse{})
+		require.Error(t, err, "Expected system error")
+		_, isAppErr := err.(*ErrApplication)
+		assert.False(t, isAppErr, "System errors should not be application errors")
+		assert.Contains(t, err.Error(), "busy", "Unexpected error")
+	})
+}
+
+func TestRegisterInvalidHandler(t *testing.T) {
+	tests := []struct {
+		handler interface{}
+		wantErr string
+	}{
+		{
+			handler: func(ctx Context) error { return nil },
+			wantErr: "handler should be of format",
+		},
+		{
+			handler: func(ctx context.Context, _ *test.EchoRequest) (*test.EchoResponse, error) { return nil, nil },
+			wantErr: "arg0 should be of type proto.Context",
+		},
+		{
+			handler: func(ctx Context, _ *struct{}) (*test.EchoResponse, error) { return nil, nil },
+			wantErr: "second argument should be a pointer to a proto message",
+		},
+		{
+			handler: func(ctx Context, _ *test.EchoRequest) (string, error) { return "", nil },
+			wantErr: "first return value should be a pointer to a proto message",
+		},
+		{
+			handler: func(ctx Context, _ *test.EchoRequest) (*test.EchoResponse, string) { return nil, "" },
+			wantErr: "second return value should be an error",
+		},
+	}
+
+	ch, err := tchannel.NewChannel("svc", nil)
+	require.NoError(t, err)
+	defer ch.Close()
+
+	for _, tt := range tests {
+		err := Register(ch, Handlers{"method": tt.handler}, nil)
+		if assert.Error(t, err, "Expected invalid handler to fail") {
+			assert.True(t, strings.Contains(err.Error(), tt.wantErr), "Unexpected error: %v", err)
+		}
+	}
+}
+
+type echoService struct{}
+
+func (echoService) Echo(ctx Context, req *test.EchoRequest) (*test.EchoResponse, error) {
+	return echo(ctx, req)
+}
+
+func (echoService) String() string { return "echoService" }
+
+func TestRegisterService(t *testing.T) {
+	ch, err := tchannel.NewChannel("svc", nil)
+	require.NoError(t, err)
+	defer ch.Close()
+
+	require.NoError(t, RegisterService(ch, "Echo", echoService{}, func(ctx context.Context, err error) {
+		t.Errorf("onError(%v)", err)
+	}))
+	require.NoError(t, ch.ListenAndServe("127.0.0.1:0"))
+
+	ctx, cancel := NewContext(time.Second)
+	defer cancel()
+
+	client := NewClient(ch, "svc", &ClientOptions{HostPort: ch.PeerInfo().HostPort})
+	var res test.EchoResponse
+	require.NoError(t, client.Call(ctx, "Echo::Echo", &test.EchoRequest{Message: "hi", Count: 1}, &res))
+	assert.Equal(t, []string{"hi"}, res.Messages, "Unexpected response")
+
+	err = RegisterService(ch, "Empty", struct{}{}, nil)
+	assert.Error(t, err, "Expected service without handler methods to fail")
+}
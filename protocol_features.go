@@ -0,0 +1,158 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sort"
+	"strings"
+)
+
+// ProtocolFeature is an optional protocol feature that peers advertise in the
+// init handshake, so that features can be rolled out without bumping the
+// protocol version: a feature is only used on a connection once both peers
+// support it, and peers that don't advertise any features are assumed to
+// support none of them.
+type ProtocolFeature string
+
+// Optional protocol features.
+const (
+	// FeatureCompression is connection-level compression of frames.
+	FeatureCompression ProtocolFeature = "compression"
+
+	// FeaturePriority is scheduling of frames by the "pri" transport header.
+	FeaturePriority ProtocolFeature = "priority"
+
+	// FeatureDrainSignal is the connection-level error frame that a draining
+	// peer sends so that no new calls are started on the connection.
+	FeatureDrainSignal ProtocolFeature = "drain_signal"
+
+	// FeatureLargeFrames is jumbo frames larger than 64KB. It's only advertised
+	// when ConnectionOptions.JumboFrameSize is set.
+	FeatureLargeFrames ProtocolFeature = "large_frames"
+)
+
+// supportedFeatures are the features this version advertises by default.
+var supportedFeatures = []ProtocolFeature{
+	FeatureCompression,
+	FeaturePriority,
+	FeatureDrainSignal,
+}
+
+// ProtocolFeatures is a set of protocol features.
+type ProtocolFeatures map[ProtocolFeature]struct{}
+
+// parseProtocolFeatures parses the comma-separated features in an init param.
+// Unknown features are kept, so they can be reported for the remote peer.
+func parseProtocolFeatures(s string) ProtocolFeatures {
+	features := make(ProtocolFeatures)
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			features[ProtocolFeature(f)] = struct{}{}
+		}
+	}
+	return features
+}
+
+// Has returns whether the set contains the feature.
+func (fs ProtocolFeatures) Has(f ProtocolFeature) bool {
+	_, ok := fs[f]
+	return ok
+}
+
+// List returns the features in the set, sorted by name.
+func (fs ProtocolFeatures) List() []ProtocolFeature {
+	list := make([]ProtocolFeature, 0, len(fs))
+	for f := range fs {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// String returns the comma-separated features in the set, as advertised in
+// the init handshake.
+func (fs ProtocolFeatures) String() string {
+	list := fs.List()
+	names := make([]string, len(list))
+	for i, f := range list {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ",")
+}
+
+// intersect returns the features that are in both sets.
+func (fs ProtocolFeatures) intersect(other ProtocolFeatures) ProtocolFeatures {
+	both := make(ProtocolFeatures)
+	for f := range fs {
+		if other.Has(f) {
+			both[f] = struct{}{}
+		}
+	}
+	return both
+}
+
+// localFeatures returns the features advertised by connections using opts,
+// which are the supported features that haven't been disabled, and large
+// frames if jumboFrameSize is set.
+func localFeatures(opts ConnectionOptions, jumboFrameSize int) ProtocolFeatures {
+	features := make(ProtocolFeatures, len(supportedFeatures)+1)
+	for _, f := range supportedFeatures {
+		features[f] = struct{}{}
+	}
+	if jumboFrameSize > 0 {
+		features[FeatureLargeFrames] = struct{}{}
+	}
+	for _, f := range opts.DisabledFeatures {
+		delete(features, f)
+	}
+	return features
+}
+
+// connectionFeatures are the features advertised by the remote peer, and the
+// features supported by both peers.
+type connectionFeatures struct {
+	remote     ProtocolFeatures
+	negotiated ProtocolFeatures
+
+	// jumboFrameSize is the negotiated maximum frame size if both peers
+	// support large frames, or 0 otherwise.
+	jumboFrameSize int
+}
+
+func negotiateFeatures(local ProtocolFeatures, remote initParams) connectionFeatures {
+	remoteFeatures := parseProtocolFeatures(remote[InitParamFeatures])
+	return connectionFeatures{
+		remote:     remoteFeatures,
+		negotiated: local.intersect(remoteFeatures),
+	}
+}
+
+// RemoteFeatures returns the protocol features advertised by the remote peer
+// in the init handshake, including features this version doesn't know about.
+func (c *Connection) RemoteFeatures() ProtocolFeatures {
+	return c.features.remote
+}
+
+// SupportsFeature returns whether both peers of the connection support the
+// given protocol feature.
+func (c *Connection) SupportsFeature(f ProtocolFeature) bool {
+	return c.features.negotiated.Has(f)
+}
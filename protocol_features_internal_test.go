@@ -0,0 +1,39 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateFeatures(t *testing.T) {
+	local := localFeatures(ConnectionOptions{}, 0 /* jumboFrameSize */)
+
+	legacy := negotiateFeatures(local, initParams{InitParamCompression: "flate"})
+	assert.Empty(t, legacy.remote, "Peers that don't advertise features support none")
+	assert.Empty(t, legacy.negotiated, "No features should be negotiated with legacy peers")
+
+	newer := negotiateFeatures(local, initParams{InitParamFeatures: "priority, future_feature"})
+	assert.Equal(t, ProtocolFeatures{FeaturePriority: {}, "future_feature": {}}, newer.remote, "Unknown features should be kept")
+	assert.Equal(t, ProtocolFeatures{FeaturePriority: {}}, newer.negotiated, "Only features supported by both peers are negotiated")
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocolFeatures(t *testing.T) {
+	tests := []struct {
+		msg            string
+		clientDisabled []ProtocolFeature
+		serverDisabled []ProtocolFeature
+		wantRemote     []ProtocolFeature
+		wantSupported  []ProtocolFeature
+	}{
+		{
+			msg:           "all features",
+			wantRemote:    []ProtocolFeature{FeatureCompression, FeatureDrainSignal, FeaturePriority},
+			wantSupported: []ProtocolFeature{FeatureCompression, FeatureDrainSignal, FeaturePriority},
+		},
+		{
+			msg:            "disabled on client",
+			clientDisabled: []ProtocolFeature{FeatureCompression},
+			wantRemote:     []ProtocolFeature{FeatureCompression, FeatureDrainSignal, FeaturePriority},
+			wantSupported:  []ProtocolFeature{FeatureDrainSignal, FeaturePriority},
+		},
+		{
+			msg:            "disabled on server",
+			serverDisabled: []ProtocolFeature{FeaturePriority, FeatureCompression, FeatureDrainSignal},
+			wantRemote:     []ProtocolFeature{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			serverOpts := testutils.NewOpts().SetCompression(CompressionFlate)
+			serverOpts.DefaultConnectionOptions.DisabledFeatures = tt.serverDisabled
+			server := testutils.NewServer(t, serverOpts)
+			defer server.Close()
+			testutils.RegisterEcho(server, nil)
+
+			clientOpts := testutils.NewOpts().SetCompression(CompressionFlate)
+			clientOpts.DefaultConnectionOptions.DisabledFeatures = tt.clientDisabled
+			client := testutils.NewClient(t, clientOpts)
+			defer client.Close()
+
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+			conn, err := client.Connect(ctx, server.PeerInfo().HostPort)
+			require.NoError(t, err, "Connect failed")
+
+			assert.Equal(t, tt.wantRemote, conn.RemoteFeatures().List(), "Unexpected remote features")
+			for _, f := range []ProtocolFeature{FeatureCompression, FeaturePriority, FeatureDrainSignal, FeatureLargeFrames} {
+				want := false
+				for _, supported := range tt.wantSupported {
+					want = want || f == supported
+				}
+				assert.Equal(t, want, conn.SupportsFeature(f), "Unexpected support for %v", f)
+			}
+
+			// Calls work whether or not compression is used.
+			testutils.AssertEcho(t, client, server.PeerInfo().HostPort, server.ServiceName())
+		})
+	}
+}
+
+func TestProtocolFeaturesString(t *testing.T) {
+	features := ProtocolFeatures{FeaturePriority: {}, FeatureCompression: {}}
+	assert.Equal(t, "compression,priority", features.String(), "Unexpected String")
+	assert.True(t, features.Has(FeaturePriority), "Expected priority")
+	assert.False(t, features.Has(FeatureLargeFrames), "Unexpected large frames")
+	assert.Equal(t, "", ProtocolFeatures{}.String(), "Unexpected String for no features")
+}
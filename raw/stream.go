@@ -0,0 +1,127 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package raw
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/uber/tchannel-go"
+)
+
+// StreamHandler is the interface for a raw handler that streams arg3 instead
+// of buffering it in memory.
+type StreamHandler interface {
+	// HandleStream is called on incoming calls once arg2 has been read.
+	// The handler reads arg3 from args.Arg3 and writes the response using
+	// StartStreamResponse. If an error is returned before the response has
+	// been started, it is sent as a system error.
+	HandleStream(ctx context.Context, args *StreamArgs, response *tchannel.InboundCallResponse) error
+	OnError(ctx context.Context, err error)
+}
+
+// StreamArgs are the arguments for an incoming streamed call req.
+type StreamArgs struct {
+	Caller string
+	Format tchannel.Format
+	Method string
+	Arg2   []byte
+	Arg3   tchannel.ArgReader
+}
+
+// ReadStreamArgs reads arg2 from the given call, and returns a reader for arg3.
+func ReadStreamArgs(call *tchannel.InboundCall) (*StreamArgs, error) {
+	args := &StreamArgs{
+		Caller: call.CallerName(),
+		Format: call.Format(),
+		Method: string(call.Method()),
+	}
+	if err := tchannel.NewArgReader(call.Arg2Reader()).Read(&args.Arg2); err != nil {
+		return nil, err
+	}
+
+	arg3, err := call.Arg3Reader()
+	if err != nil {
+		return nil, err
+	}
+	args.Arg3 = arg3
+	return args, nil
+}
+
+// StartStreamResponse writes arg2 to the response, and returns the writer for arg3.
+// Data written to the arg3 writer is sent once a frame is filled, or when the
+// writer is flushed. The writer must be closed to complete the response.
+func StartStreamResponse(response *tchannel.InboundCallResponse, arg2 []byte) (tchannel.ArgWriter, error) {
+	if err := tchannel.NewArgWriter(response.Arg2Writer()).Write(arg2); err != nil {
+		return nil, err
+	}
+	return response.Arg3Writer()
+}
+
+// WrapStream wraps a StreamHandler as a tchannel.Handler that can be passed to tchannel.Register.
+func WrapStream(handler StreamHandler) tchannel.Handler {
+	return tchannel.HandlerFunc(func(ctx context.Context, call *tchannel.InboundCall) {
+		args, err := ReadStreamArgs(call)
+		if err != nil {
+			handler.OnError(ctx, err)
+			return
+		}
+
+		response := call.Response()
+		if err := handler.HandleStream(ctx, args, response); err != nil {
+			if err := response.SendSystemError(err); err != nil {
+				handler.OnError(ctx, err)
+			}
+		}
+	})
+}
+
+// StartStream writes arg2 to the call, and returns the writer for arg3.
+// The arg3 writer is flushed so that the call is sent before any arg3 is written.
+// The writer must be closed before the response can be read with ReadStreamResponse.
+func StartStream(call *tchannel.OutboundCall, arg2 []byte) (tchannel.ArgWriter, error) {
+	if err := tchannel.NewArgWriter(call.Arg2Writer()).Write(arg2); err != nil {
+		return nil, err
+	}
+
+	writer, err := call.Arg3Writer()
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// ReadStreamResponse reads arg2 from the response, and returns a reader for arg3.
+// The arg3 reader must be read until io.EOF and closed.
+func ReadStreamResponse(resp *tchannel.OutboundCallResponse) ([]byte, tchannel.ArgReader, error) {
+	var arg2 []byte
+	if err := tchannel.NewArgReader(resp.Arg2Reader()).Read(&arg2); err != nil {
+		return nil, nil, err
+	}
+
+	arg3, err := resp.Arg3Reader()
+	if err != nil {
+		return nil, nil, err
+	}
+	return arg2, arg3, nil
+}
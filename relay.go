@@ -40,13 +40,21 @@ const (
 	_relayTombTTL = 3 * time.Second
 	// _defaultRelayMaxTimeout is the default max TTL for relayed calls.
 	_defaultRelayMaxTimeout = 2 * time.Minute
+	// _relayPoolConnectBackoff is how long the relay waits before retrying a
+	// failed connection to grow a destination's pool of connections.
+	_relayPoolConnectBackoff = time.Second
 )
 
 var (
 	errRelayMethodFragmented = NewSystemError(ErrCodeBadRequest, "relay handler cannot receive fragmented calls")
 	errFrameNotSent          = NewSystemError(ErrCodeNetwork, "frame was not sent to remote side")
 	errBadRelayHost          = NewSystemError(ErrCodeDeclined, "bad relay host implementation")
+	errRelayRateLimited      = NewSystemError(ErrCodeBusy, "relay rate limit exceeded")
+	errRelayRequestTooLarge  = NewSystemError(ErrCodeBadRequest, "request exceeds relay max request size")
+	errRelayResponseTooLarge = NewSystemError(ErrCodeUnexpected, "response exceeds relay max response size")
+	errRelayCircuitOpen      = NewSystemError(ErrCodeDeclined, "relay circuit breaker is open")
 	errUnknownID             = errors.New("non-callReq for inactive ID")
+	errRelayPeerFailed       = errors.New("destination connection failed recently")
 )
 
 type relayItem struct {
@@ -54,9 +62,56 @@ type relayItem struct {
 	tomb        bool
 	local       bool
 	call        RelayCall
+	start       time.Time
 	destination *Relayer
 	span        Span
 	timeout     *relayTimer
+
+	// logFrames is set if an interceptor tagged the call for frame logging.
+	logFrames bool
+
+	// priority is the call's priority, which determines the send channel
+	// used for frames relayed in this item's direction.
+	priority CallPriority
+
+	// frames counts the frames relayed for the call in both directions. It's
+	// shared by the items for both directions, and is only set if the call
+	// implements RelayFrameCountingCall or RelayFrameStatsCall.
+	frames *relayFrameCounter
+
+	// argsSize is the total size of the args forwarded for this call in the
+	// direction this item handles. It's only set if that direction has a size
+	// limit.
+	argsSize *atomic.Int64
+
+	// shadow is set if the call is mirrored to a shadow destination. It's
+	// shared by the originator's item, and the shadow destination's item,
+	// which has no destination since its responses are discarded.
+	shadow *relayShadowCall
+}
+
+// relayFrameCounter counts the frames relayed for a call in each direction.
+type relayFrameCounter struct {
+	reqFrames atomic.Int64
+	reqBytes  atomic.Int64
+	resFrames atomic.Int64
+	resBytes  atomic.Int64
+}
+
+func (c *relayFrameCounter) add(f *Frame, fType frameType) {
+	if fType == requestFrame {
+		c.reqFrames.Inc()
+		c.reqBytes.Add(int64(f.Header.frameSize()))
+		return
+	}
+	c.resFrames.Inc()
+	c.resBytes.Add(int64(f.Header.frameSize()))
+}
+
+func (c *relayFrameCounter) load() (request, response RelayFrameStats) {
+	request = RelayFrameStats{Frames: int(c.reqFrames.Load()), Bytes: c.reqBytes.Load()}
+	response = RelayFrameStats{Frames: int(c.resFrames.Load()), Bytes: c.resBytes.Load()}
+	return request, response
 }
 
 type relayItems struct {
@@ -161,15 +216,41 @@ const (
 	responseFrame frameType = 1
 )
 
-// A Relayer forwards frames.
+// A Relayer forwards frames. Frames are read into pooled buffers that hold
+// the encoded header and payload, and are forwarded to the destination
+// connection in place with only the message ID remapped, so payloads are not
+// copied. Transport headers that the relay rewrites are also changed in place.
+// The relay doesn't verify or recompute checksums, since they're
+// carried in the payload and checked by the endpoints.
 type Relayer struct {
-	relayHost  RelayHost
-	maxTimeout time.Duration
+	relayHost    RelayHost
+	maxTimeout   time.Duration
+	adjustTTL    func(relay.CallFrame, time.Duration) time.Duration
+	observer     RelayCallObserver
+	stats        relay.Stats
+	interceptors []RelayInterceptor
+	limiter      relay.RateLimiter
+	breaker      *relayCircuitBreaker
+	maxRetries   int
+
+	// failover is how long destinations are failed over after their
+	// connection fails, and connsPerPeer is the number of connections used
+	// for each destination.
+	failover     time.Duration
+	connsPerPeer int
+
+	// maxRequestSize and maxResponseSize limit the total args size of
+	// relayed calls. Zero means no limit.
+	maxRequestSize  int
+	maxResponseSize int
 
 	// localHandlers is the set of service names that are handled by the local
 	// channel.
 	localHandler map[string]struct{}
 
+	// shadow mirrors a sample of calls to shadow destinations, if enabled.
+	shadow *relayShadow
+
 	// outbound is the remapping for requests that originated on this
 	// connection, and are outbound towards some other connection.
 	// It stores remappings for all request frames read on this connection.
@@ -194,13 +275,25 @@ type Relayer struct {
 // NewRelayer constructs a Relayer.
 func NewRelayer(ch *Channel, conn *Connection) *Relayer {
 	r := &Relayer{
-		relayHost:    ch.RelayHost(),
-		maxTimeout:   ch.relayMaxTimeout,
-		localHandler: ch.relayLocal,
-		outbound:     newRelayItems(conn.log.WithFields(LogField{"relayItems", "outbound"})),
-		inbound:      newRelayItems(conn.log.WithFields(LogField{"relayItems", "inbound"})),
-		peers:        ch.RootPeers(),
-		conn:         conn,
+		relayHost:       ch.RelayHost(),
+		maxTimeout:      ch.relayMaxTimeout,
+		adjustTTL:       ch.relayAdjustTTL,
+		observer:        ch.relayCallObserver,
+		stats:           ch.relayStats,
+		interceptors:    ch.relayInterceptors,
+		limiter:         ch.relayRateLimiter,
+		breaker:         ch.relayCircuitBreaker,
+		maxRetries:      ch.relayMaxPeerRetries,
+		failover:        ch.relayFailover,
+		connsPerPeer:    ch.relayConnsPerPeer,
+		maxRequestSize:  ch.relayMaxRequestSize,
+		maxResponseSize: ch.relayMaxResponseSize,
+		localHandler:    ch.relayLocal,
+		shadow:          ch.relayShadow,
+		outbound:        newRelayItems(conn.log.WithFields(LogField{"relayItems", "outbound"})),
+		inbound:         newRelayItems(conn.log.WithFields(LogField{"relayItems", "inbound"})),
+		peers:           ch.RootPeers(),
+		conn:            conn,
 		relayConn: &relay.Conn{
 			RemoteAddr:        conn.conn.RemoteAddr().String(),
 			RemoteProcessName: conn.RemotePeerInfo().ProcessName,
@@ -263,13 +356,15 @@ func (r *Relayer) Receive(f *Frame, fType frameType) (sent bool, failureReason s
 		// If we've gotten a response frame, we're the originating relayer and
 		// should handle stats.
 		if succeeded, failMsg := determinesCallSuccess(f); succeeded {
+			r.responseReceived(item.call)
 			item.call.Succeeded()
 		} else if len(failMsg) > 0 {
+			r.responseReceived(item.call)
 			item.call.Failed(failMsg)
 		}
 	}
 	select {
-	case r.conn.sendCh <- f:
+	case r.conn.sendChFor(item.priority) <- f:
 	default:
 		// Buffer is full, so drop this frame and cancel the call.
 		r.logger.WithFields(
@@ -277,7 +372,7 @@ func (r *Relayer) Receive(f *Frame, fType frameType) (sent bool, failureReason s
 		).Warn("Dropping call due to slow connection to destination.")
 
 		items := r.receiverItems(fType)
-		r.failRelayItem(items, id, "relay-dest-conn-slow")
+		r.failRelayItem(items, id, "relay-dest-conn-slow", errFrameNotSent)
 		return false, "relay-dest-conn-slow"
 	}
 
@@ -305,7 +400,7 @@ func (r *Relayer) canHandleNewCall() (bool, connectionState) {
 	return canHandle, curState
 }
 
-func (r *Relayer) getDestination(f lazyCallReq, call RelayCall) (*Connection, bool, error) {
+func (r *Relayer) getDestination(f lazyCallReq, call RelayCall, start time.Time) (*Connection, bool, error) {
 	if _, ok := r.outbound.Get(f.Header.ID); ok {
 		r.logger.WithFields(
 			LogField{"id", f.Header.ID},
@@ -327,7 +422,17 @@ func (r *Relayer) getDestination(f lazyCallReq, call RelayCall) (*Connection, bo
 	}
 
 	// TODO: Should connections use the call timeout? Or a separate timeout?
-	remoteConn, err := peer.getConnectionRelay(f.TTL())
+	remoteConn, err := r.getDestinationConn(peer, f.TTL())
+	if err != nil {
+		remoteConn, peer, err = r.retryDestination(f, call, peer, start, err)
+	}
+	if err == errRelayPeerFailed {
+		// There's no other peer to fail over to, so try the failed peer
+		// rather than failing the call without trying.
+		if remaining := f.TTL() - r.conn.timeNow().Sub(start); remaining > 0 {
+			remoteConn, err = peer.getConnectionRelay(remaining, r.connsPerPeer)
+		}
+	}
 	if err != nil {
 		r.logger.WithFields(
 			ErrField(err),
@@ -341,32 +446,113 @@ func (r *Relayer) getDestination(f lazyCallReq, call RelayCall) (*Connection, bo
 		return nil, false, nil
 	}
 
+	// Only forward the time the caller has left, since the relay may have
+	// spent some of the call's TTL, e.g. connecting to the destination.
+	remaining := f.TTL() - r.conn.timeNow().Sub(start)
+	if remaining < r.conn.opts.MinTimeToLive {
+		call.Failed(ErrCodeTimeout.relayMetricsKey())
+		r.conn.SendSystemError(f.Header.ID, f.Span(), ErrTimeout)
+		return nil, false, nil
+	}
+	f.SetTTL(remaining)
+
 	return remoteConn, true, nil
 }
 
+// getDestinationConn returns a connection to the given destination, unless the
+// destination is being failed over after its connection failed.
+func (r *Relayer) getDestinationConn(peer *Peer, timeout time.Duration) (*Connection, error) {
+	if r.failover > 0 && peer.failedRecently(r.conn.timeNow(), r.failover) {
+		return nil, errRelayPeerFailed
+	}
+	return peer.getConnectionRelay(timeout, r.connsPerPeer)
+}
+
+// retryDestination retries the call on other peers after the relay failed to
+// connect to failed. Nothing has been forwarded for the call yet, so it's safe
+// to send it to a different peer. Retries stop once the call's TTL has passed.
+func (r *Relayer) retryDestination(f lazyCallReq, call RelayCall, failed *Peer, start time.Time, err error) (*Connection, *Peer, error) {
+	retryable, ok := call.(RelayRetryableCall)
+	if !ok {
+		return nil, failed, err
+	}
+
+	for i := 0; i < r.maxRetries; i++ {
+		remaining := f.TTL() - r.conn.timeNow().Sub(start)
+		if remaining <= 0 {
+			break
+		}
+
+		peer, ok := retryable.RetryDestination(failed)
+		if !ok {
+			break
+		}
+		retryable.RetriedTo(peer)
+
+		remoteConn, connErr := r.getDestinationConn(peer, remaining)
+		if connErr == nil {
+			return remoteConn, peer, nil
+		}
+		failed, err = peer, connErr
+	}
+	return nil, failed, err
+}
+
+// rateLimiter returns the rate limiter for a new call, which is provided by
+// the RelayHost if it implements RelayRateLimitingHost.
+func (r *Relayer) rateLimiter() relay.RateLimiter {
+	if host, ok := r.relayHost.(RelayRateLimitingHost); ok {
+		return host.RateLimiter()
+	}
+	return r.limiter
+}
+
 func (r *Relayer) handleCallReq(f lazyCallReq) error {
 	if handled := r.handleLocalCallReq(f); handled {
 		return nil
 	}
 
-	call, err := r.relayHost.Start(f, r.relayConn)
+	if r.conn.draining.Load() && string(f.Method()) != healthMethod {
+		r.conn.SendSystemError(f.Header.ID, f.Span(), errChannelDraining)
+		return nil
+	}
+
+	if r.conn.headerLimits != nil {
+		drop, err := r.conn.checkHeaderLimits(f.Frame)
+		if err == nil && len(drop) > 0 {
+			changes := make([]relayHeaderChange, len(drop))
+			for i, k := range drop {
+				changes[i] = relayHeaderChange{key: k, delete: true}
+			}
+			if err = rewriteCallReqHeaders(f.Frame, changes); err == nil {
+				f = newLazyCallReq(f.Frame)
+			}
+		}
+		if err != nil {
+			r.conn.SendSystemError(f.Header.ID, f.Span(), err)
+			return nil
+		}
+	}
+
+	start := r.conn.timeNow()
+	call, err := r.startCall(f)
 	if err != nil {
 		// If we have a RateLimitDropError we record the statistic, but
 		// we *don't* send an error frame back to the client.
 		if _, silentlyDrop := err.(relay.RateLimitDropError); silentlyDrop {
 			if call != nil {
-				call.Failed("relay-dropped")
-				call.End()
+				call.Failed(relayStartFailure(err))
+				r.endCall(call, start)
 			}
 			return nil
 		}
+		if call != nil {
+			call.Failed(relayStartFailure(err))
+			r.endCall(call, start)
+		}
 		if _, ok := err.(SystemError); !ok {
 			err = NewSystemError(ErrCodeDeclined, err.Error())
 		}
-		if call != nil {
-			call.Failed(GetSystemErrorCode(err).relayMetricsKey())
-			call.End()
-		}
 		r.conn.SendSystemError(f.Header.ID, f.Span(), err)
 
 		// If the RelayHost returns a protocol error, close the connection.
@@ -376,17 +562,83 @@ func (r *Relayer) handleCallReq(f lazyCallReq) error {
 		return nil
 	}
 
+	// Don't forward calls that the caller will have given up on by the time
+	// the destination could respond.
+	if r.conn.opts.inboundExpired(f.TTL()) {
+		call.Failed(ErrCodeTimeout.relayMetricsKey())
+		r.endCall(call, start)
+		r.conn.SendSystemError(f.Header.ID, f.Span(), ErrTimeout)
+		return nil
+	}
+
+	var interceptOpts RelayInterceptOptions
+	if len(r.interceptors) > 0 {
+		call, f, interceptOpts, err = r.interceptCall(f, call)
+		if err != nil {
+			call.Failed(_interceptedFailure)
+			r.endCall(call, start)
+			r.conn.SendSystemError(f.Header.ID, f.Span(), err)
+			return nil
+		}
+	}
+	if interceptOpts.LogFrames {
+		r.logFrame(f.Frame, requestFrame)
+	}
+
+	if limiter := r.rateLimiter(); limiter != nil && !limiter.Allow(f) {
+		call.Failed("rate-limited")
+		r.endCall(call, start)
+		r.conn.SendSystemError(f.Header.ID, f.Span(), errRelayRateLimited)
+		return nil
+	}
+
+	if r.breaker != nil {
+		circuit, ok := r.breaker.allow(f)
+		if !ok {
+			call.Failed(_circuitOpenFailure)
+			r.endCall(call, start)
+			r.conn.SendSystemError(f.Header.ID, f.Span(), errRelayCircuitOpen)
+			return nil
+		}
+		call = &circuitRelayCall{RelayCallWrapper: RelayCallWrapper{call}, breaker: r.breaker, circuit: circuit}
+	}
+
+	argsSize, err := frameArgsSize(f.Frame)
+	if err != nil {
+		call.Failed(ErrCodeBadRequest.relayMetricsKey())
+		r.endCall(call, start)
+		r.conn.SendSystemError(f.Header.ID, f.Span(), err)
+		return nil
+	}
+	if r.maxRequestSize > 0 && argsSize > r.maxRequestSize {
+		call.Failed("frame-too-large")
+		r.endCall(call, start)
+		r.conn.SendSystemError(f.Header.ID, f.Span(), errRelayRequestTooLarge)
+		return nil
+	}
+
+	if r.adjustTTL != nil {
+		ttl := r.adjustTTL(f, f.TTL())
+		if ttl <= 0 {
+			call.Failed(ErrCodeTimeout.relayMetricsKey())
+			r.endCall(call, start)
+			r.conn.SendSystemError(f.Header.ID, f.Span(), ErrTimeout)
+			return nil
+		}
+		f.SetTTL(ttl)
+	}
+
 	// Check that the current connection is in a valid state to handle a new call.
 	if canHandle, state := r.canHandleNewCall(); !canHandle {
 		call.Failed("relay-client-conn-inactive")
-		call.End()
+		r.endCall(call, start)
 		err := errConnNotActive{"incoming", state}
 		r.conn.SendSystemError(f.Header.ID, f.Span(), NewWrappedSystemError(ErrCodeDeclined, err))
 		return err
 	}
 
 	// Get a remote connection and check whether it can handle this call.
-	remoteConn, ok, err := r.getDestination(f, call)
+	remoteConn, ok, err := r.getDestination(f, call, start)
 	if err == nil && ok {
 		if canHandle, state := remoteConn.relay.canHandleNewCall(); !canHandle {
 			err = NewWrappedSystemError(ErrCodeNetwork, errConnNotActive{"selected remote", state})
@@ -399,7 +651,7 @@ func (r *Relayer) handleCallReq(f lazyCallReq) error {
 		// state to handle this call. Since we already incremented pending on
 		// the current relay, we need to decrement it.
 		r.decrementPending()
-		call.End()
+		r.endCall(call, start)
 		return err
 	}
 
@@ -411,20 +663,116 @@ func (r *Relayer) handleCallReq(f lazyCallReq) error {
 		f.SetTTL(r.maxTimeout)
 	}
 	span := f.Span()
+	var frames *relayFrameCounter
+	if countsFrames(call) {
+		frames = &relayFrameCounter{}
+		frames.add(f.Frame, requestFrame)
+	}
 	// The remote side of the relay doesn't need to track stats.
-	remoteConn.relay.addRelayItem(false /* isOriginator */, destinationID, f.Header.ID, r, ttl, span, nil)
-	relayToDest := r.addRelayItem(true /* isOriginator */, f.Header.ID, destinationID, remoteConn.relay, ttl, span, call)
+	// The shadow call copies the frame, so it must start before the frame is
+	// sent to the destination, which releases it.
+	shadow := r.startShadowCall(f, ttl)
+	// The caller's priority is only honored if it negotiated priorities.
+	priority := PriorityNormal
+	if r.conn.SupportsFeature(FeaturePriority) {
+		priority = f.Priority()
+	}
+	remoteConn.relay.addRelayItem(false /* isOriginator */, destinationID, f.Header.ID, r, ttl, span, nil, time.Time{}, interceptOpts.LogFrames, priority, frames, nil)
+	relayToDest := r.addRelayItem(true /* isOriginator */, f.Header.ID, destinationID, remoteConn.relay, ttl, span, call, start, interceptOpts.LogFrames, priority, frames, shadow)
+	if relayToDest.argsSize != nil {
+		relayToDest.argsSize.Store(int64(argsSize))
+	}
 
 	f.Header.ID = destinationID
 	sent, failure := relayToDest.destination.Receive(f.Frame, requestFrame)
 	if !sent {
-		r.failRelayItem(r.outbound, origID, failure)
+		r.failRelayItem(r.outbound, origID, failure, errFrameNotSent)
 		return nil
 	}
 
 	return nil
 }
 
+// startCall starts a RelayCall using the RelayHost, wrapping it to notify the
+// observer if one is configured.
+func (r *Relayer) startCall(f lazyCallReq) (RelayCall, error) {
+	if r.stats == nil {
+		return r.startObservedCall(f)
+	}
+
+	stats := r.stats.Begin(f)
+	call, err := r.startObservedCall(f)
+	if call == nil {
+		// The relay only reports the outcome of calls that were started, so
+		// report the failure to start the call here.
+		if err != nil {
+			stats.Failed(relayStartFailure(err))
+		}
+		stats.End()
+		return nil, err
+	}
+	return &statsRelayCall{RelayCallWrapper: RelayCallWrapper{call}, stats: stats}, err
+}
+
+// relayStartFailure returns the failure reason for a call that the RelayHost
+// failed to start.
+func relayStartFailure(err error) string {
+	if _, ok := err.(relay.RateLimitDropError); ok {
+		return "relay-dropped"
+	}
+	if _, ok := err.(SystemError); !ok {
+		return ErrCodeDeclined.relayMetricsKey()
+	}
+	return GetSystemErrorCode(err).relayMetricsKey()
+}
+
+// startObservedCall starts a call using the RelayHost, and notifies the
+// observer if one is configured.
+func (r *Relayer) startObservedCall(f lazyCallReq) (RelayCall, error) {
+	if r.observer == nil {
+		return r.relayHost.Start(f, r.relayConn)
+	}
+
+	frame := copyCallFrame(f)
+	r.observer.CallBegin(frame)
+	call, err := r.relayHost.Start(f, r.relayConn)
+	return newObservedRelayCall(call, r.observer, frame), err
+}
+
+// countsFrames returns whether the RelayCall started by the RelayHost, which
+// may be wrapped by the relay, implements RelayFrameCountingCall or
+// RelayFrameStatsCall.
+func countsFrames(call RelayCall) bool {
+	switch c := call.(type) {
+	case *observedRelayCall:
+		return countsFrames(c.call)
+	case *circuitRelayCall:
+		return countsFrames(c.RelayCall)
+	case *interceptedRelayCall:
+		return countsFrames(c.RelayCall)
+	case *statsRelayCall:
+		return countsFrames(c.RelayCall)
+	}
+	if _, ok := call.(RelayFrameCountingCall); ok {
+		return true
+	}
+	_, ok := call.(RelayFrameStatsCall)
+	return ok
+}
+
+func (r *Relayer) responseReceived(call RelayCall) {
+	switch c := call.(type) {
+	case *observedRelayCall:
+		c.responseReceived()
+	case *circuitRelayCall:
+		r.responseReceived(c.RelayCall)
+	case *interceptedRelayCall:
+		r.responseReceived(c.RelayCall)
+	case *statsRelayCall:
+		r.responseReceived(c.RelayCall)
+	}
+}
+
 // Handle all frames except messageTypeCallReq.
 func (r *Relayer) handleNonCallReq(f *Frame) error {
 	frameType := frameTypeFor(f)
@@ -446,17 +794,41 @@ func (r *Relayer) handleNonCallReq(f *Frame) error {
 		// TODO: metrics for late-arriving frames.
 		return nil
 	}
+	if item.shadow != nil && frameType == responseFrame {
+		r.discardShadowResponse(items, item, f)
+		return nil
+	}
+	if item.argsSize != nil {
+		exceeded, err := r.exceedsMaxSize(item, f, frameType)
+		if err != nil {
+			r.failMalformedCall(items, f.Header.ID, item, frameType)
+			return nil
+		}
+		if exceeded {
+			r.failOversizedCall(items, f.Header.ID, item, frameType)
+			return nil
+		}
+	}
 	if finished && !item.timeout.Stop() {
 		// Timeout is firing, so no point proxying this frame
 		return nil
 	}
+	if item.logFrames {
+		r.logFrame(f, frameType)
+	}
+	if item.frames != nil {
+		item.frames.add(f, frameType)
+	}
+	if item.shadow != nil && !item.shadow.failed.Load() {
+		item.shadow.send(r.copyFrame(f))
+	}
 
 	originalID := f.Header.ID
 	f.Header.ID = item.remapID
 
 	sent, failure := item.destination.Receive(f, frameType)
 	if !sent {
-		r.failRelayItem(items, originalID, failure)
+		r.failRelayItem(items, originalID, failure, errFrameNotSent)
 		return nil
 	}
 
@@ -467,17 +839,32 @@ func (r *Relayer) handleNonCallReq(f *Frame) error {
 }
 
 // addRelayItem adds a relay item to either outbound or inbound.
-func (r *Relayer) addRelayItem(isOriginator bool, id, remapID uint32, destination *Relayer, ttl time.Duration, span Span, call RelayCall) relayItem {
+func (r *Relayer) addRelayItem(isOriginator bool, id, remapID uint32, destination *Relayer, ttl time.Duration, span Span, call RelayCall, start time.Time, logFrames bool, priority CallPriority, frames *relayFrameCounter, shadow *relayShadowCall) relayItem {
+	// The item's frames are sent on this relayer's connection, so they're only
+	// prioritized if that connection supports priorities.
+	if !r.conn.SupportsFeature(FeaturePriority) {
+		priority = PriorityNormal
+	}
 	item := relayItem{
 		call:        call,
+		start:       start,
 		remapID:     remapID,
 		destination: destination,
 		span:        span,
+		logFrames:   logFrames,
+		priority:    priority,
+		frames:      frames,
+		shadow:      shadow,
 	}
 
 	items := r.inbound
+	maxSize := r.maxResponseSize
 	if isOriginator {
 		items = r.outbound
+		maxSize = r.maxRequestSize
+	}
+	if maxSize > 0 {
+		item.argsSize = atomic.NewInt64(0)
 	}
 	item.timeout = r.timeouts.Get()
 	items.Add(id, item)
@@ -493,16 +880,66 @@ func (r *Relayer) timeoutRelayItem(items *relayItems, id uint32, isOriginator bo
 	if isOriginator {
 		r.conn.SendSystemError(id, item.span, ErrTimeout)
 		item.call.Failed("timeout")
-		item.call.End()
+		r.endRelayItem(item)
+	} else if item.shadow != nil {
+		item.shadow.finished("timeout")
 	}
 
 	r.decrementPending()
 }
 
+// exceedsMaxSize adds the args in f to the item's args size, and returns
+// whether the total exceeds the limit for the frame's direction. It returns an
+// error if the frame is malformed.
+func (r *Relayer) exceedsMaxSize(item relayItem, f *Frame, fType frameType) (bool, error) {
+	maxSize := r.maxRequestSize
+	if fType == responseFrame {
+		maxSize = r.maxResponseSize
+	}
+	size, err := frameArgsSize(f)
+	if err != nil {
+		return false, err
+	}
+	return item.argsSize.Add(int64(size)) > int64(maxSize), nil
+}
+
+// failOversizedCall stops relaying a call whose args exceeded the relay's max
+// request or response size, and fails the call on both sides of the relay.
+// Oversized requests are failed with a BadRequest error to the caller, while
+// oversized responses are logged, and the caller is sent an Unexpected error
+// in place of the remaining response.
+func (r *Relayer) failOversizedCall(items *relayItems, id uint32, item relayItem, fType frameType) {
+	if fType == requestFrame {
+		r.failRelayItem(items, id, "frame-too-large", errRelayRequestTooLarge)
+		item.destination.failRelayItem(item.destination.inbound, item.remapID, "frame-too-large", errRelayRequestTooLarge)
+		return
+	}
+
+	r.logger.WithFields(
+		LogField{"id", id},
+		LogField{"maxResponseSize", r.maxResponseSize},
+	).Error("Relayed response exceeded the max response size.")
+	r.failRelayItem(items, id, "frame-too-large", errRelayResponseTooLarge)
+	item.destination.failRelayItem(item.destination.outbound, item.remapID, "frame-too-large", errRelayResponseTooLarge)
+}
+
+// failMalformedCall stops relaying a call after a frame that's shorter than
+// the lengths it contains, and fails the call on both sides of the relay.
+func (r *Relayer) failMalformedCall(items *relayItems, id uint32, item relayItem, fType frameType) {
+	destItems := item.destination.inbound
+	if fType == responseFrame {
+		destItems = item.destination.outbound
+	}
+	r.logger.WithFields(LogField{"id", id}).Warn("Relayed frame is malformed.")
+	r.failRelayItem(items, id, "malformed-frame", errMalformedCallFrame)
+	item.destination.failRelayItem(destItems, item.remapID, "malformed-frame", errMalformedCallFrame)
+}
+
 // failRelayItem tombs the relay item so that future frames for this call are not
 // forwarded. We keep the relay item tombed, rather than delete it to ensure that
-// future frames do not cause error logs.
-func (r *Relayer) failRelayItem(items *relayItems, id uint32, failure string) {
+// future frames do not cause error logs. If the item is for the originator, err
+// is sent to the caller.
+func (r *Relayer) failRelayItem(items *relayItems, id uint32, failure string, err error) {
 	item, ok := items.Get(id)
 	if !ok {
 		items.logger.WithFields(LogField{"id", id}).Warn("Attempted to fail non-existent relay item.")
@@ -522,9 +959,9 @@ func (r *Relayer) failRelayItem(items *relayItems, id uint32, failure string) {
 		return
 	}
 	if item.call != nil {
-		r.conn.SendSystemError(id, item.span, errFrameNotSent)
+		r.conn.SendSystemError(id, item.span, err)
 		item.call.Failed(failure)
-		item.call.End()
+		r.endRelayItem(item)
 	}
 
 	r.decrementPending()
@@ -536,11 +973,38 @@ func (r *Relayer) finishRelayItem(items *relayItems, id uint32) {
 		return
 	}
 	if item.call != nil {
-		item.call.End()
+		r.endRelayItem(item)
 	}
 	r.decrementPending()
 }
 
+// endRelayItem ends the call of an originator's relay item, reporting the
+// frames relayed for the call if they were counted.
+func (r *Relayer) endRelayItem(item relayItem) {
+	if item.frames != nil {
+		setFrameStats(item.call, item.frames)
+	}
+	r.endCall(item.call, item.start)
+}
+
+// setFrameStats reports the frames counted for a call, using whichever of
+// RelayFrameCountingCall and RelayFrameStatsCall the call implements.
+func setFrameStats(call RelayCall, frames *relayFrameCounter) {
+	request, response := frames.load()
+	if counting, ok := call.(RelayFrameCountingCall); ok {
+		counting.SetFrameCount(request.Frames+response.Frames, request.Bytes+response.Bytes)
+	}
+	if stats, ok := call.(RelayFrameStatsCall); ok {
+		stats.SetFrameStats(request, response)
+	}
+}
+
+// endCall sets the duration of the call since start, and ends it.
+func (r *Relayer) endCall(call RelayCall, start time.Time) {
+	call.SetDuration(r.conn.timeNow().Sub(start))
+	call.End()
+}
+
 func (r *Relayer) decrementPending() {
 	r.pending.Dec()
 	r.conn.checkExchanges()
@@ -0,0 +1,314 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package relay
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/tchannel-go/internal/ratelimit"
+)
+
+// Wildcard matches any caller, service or method in an EdgeRateLimiter key.
+const Wildcard = "*"
+
+// RateLimiter is consulted by the relay for each new call. Calls that are not
+// allowed are rejected with a busy error, without being forwarded.
+type RateLimiter interface {
+	// Allow returns whether the call should be forwarded.
+	Allow(f CallFrame) bool
+}
+
+// edge is a caller->service::method triple, any of which may be a Wildcard.
+type edge struct {
+	caller, service, method string
+}
+
+// maxEdgeBuckets bounds the number of edges that are limited separately, so
+// that rotating caller, service or method names can't grow the rate limiter
+// without bound.
+const maxEdgeBuckets = 10000
+
+// EdgeRateLimiter is a RateLimiter that limits calls along each
+// caller->service::method edge using a separate token bucket per edge.
+type EdgeRateLimiter struct {
+	timeNow    func() time.Time
+	maxBuckets int
+
+	sync.RWMutex
+	limits edgeLimits
+	// buckets is keyed by caller, then service, then method, so that lookups
+	// using the call frame's bytes don't allocate. Only edges that match a
+	// limit have a bucket.
+	buckets    map[string]map[string]map[string]*ratelimit.Bucket
+	numBuckets int
+	lastSweep  time.Time
+	// overflow has a bucket per limit key that's shared by all edges matching
+	// the key once buckets is full, so edges that aren't tracked are still
+	// limited.
+	overflow map[edge]*ratelimit.Bucket
+}
+
+// NewEdgeRateLimiter returns an EdgeRateLimiter using the given limits, which
+// are keyed by "caller->service::method" and are in calls per second. Each of
+// the caller, service and method can be a Wildcard, e.g. "*->svc::*" limits
+// calls from every caller to every method of svc. Every edge that matches a
+// key is limited separately, using the most specific matching key, where a
+// matching service is more specific than a matching method, which is more
+// specific than a matching caller. Each edge can burst up to a second's worth
+// of calls (and at least one call). Edges that don't match any key are not limited.
+func NewEdgeRateLimiter(limits map[string]float64) (*EdgeRateLimiter, error) {
+	parsed, err := parseLimits(limits)
+	if err != nil {
+		return nil, err
+	}
+	return &EdgeRateLimiter{
+		limits:     parsed,
+		timeNow:    time.Now,
+		maxBuckets: maxEdgeBuckets,
+		buckets:    make(map[string]map[string]map[string]*ratelimit.Bucket),
+		overflow:   make(map[edge]*ratelimit.Bucket),
+	}, nil
+}
+
+// SetLimits replaces the limits used by the rate limiter, so that limits can
+// be reloaded at runtime. The limits are keyed the same way as for
+// NewEdgeRateLimiter. Every edge starts with a full bucket under the new
+// limits. If the limits are invalid, an error is returned and the existing
+// limits are kept.
+func (l *EdgeRateLimiter) SetLimits(limits map[string]float64) error {
+	parsed, err := parseLimits(limits)
+	if err != nil {
+		return err
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.limits = parsed
+	l.buckets = make(map[string]map[string]map[string]*ratelimit.Bucket)
+	l.numBuckets = 0
+	l.overflow = make(map[edge]*ratelimit.Bucket)
+	return nil
+}
+
+// edgeLimits is keyed by service, then method, then caller, so that limits can
+// be matched using the call frame's bytes without allocating.
+type edgeLimits map[string]map[string]map[string]edgeLimit
+
+// edgeLimit is a limit along with the key it was configured with.
+type edgeLimit struct {
+	key   edge
+	limit float64
+}
+
+func parseLimits(limits map[string]float64) (edgeLimits, error) {
+	parsed := make(edgeLimits)
+	for key, limit := range limits {
+		e, err := parseEdge(key)
+		if err != nil {
+			return nil, err
+		}
+		if limit <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %v for %q, must be positive", limit, key)
+		}
+
+		byMethod, ok := parsed[e.service]
+		if !ok {
+			byMethod = make(map[string]map[string]edgeLimit)
+			parsed[e.service] = byMethod
+		}
+		byCaller, ok := byMethod[e.method]
+		if !ok {
+			byCaller = make(map[string]edgeLimit)
+			byMethod[e.method] = byCaller
+		}
+		byCaller[e.caller] = edgeLimit{key: e, limit: limit}
+	}
+	return parsed, nil
+}
+
+func parseEdge(key string) (edge, error) {
+	callerEnd := strings.Index(key, "->")
+	if callerEnd < 0 {
+		return edge{}, fmt.Errorf("invalid rate limit key %q, expected caller->service::method", key)
+	}
+	rest := key[callerEnd+len("->"):]
+	serviceEnd := strings.Index(rest, "::")
+	if serviceEnd < 0 {
+		return edge{}, fmt.Errorf("invalid rate limit key %q, expected caller->service::method", key)
+	}
+
+	e := edge{
+		caller:  key[:callerEnd],
+		service: rest[:serviceEnd],
+		method:  rest[serviceEnd+len("::"):],
+	}
+	if e.caller == "" || e.service == "" || e.method == "" {
+		return edge{}, fmt.Errorf("invalid rate limit key %q, caller, service and method must be set", key)
+	}
+	return e, nil
+}
+
+// Allow returns whether the call is within the rate limit for its edge.
+func (l *EdgeRateLimiter) Allow(f CallFrame) bool {
+	now := l.timeNow()
+	bucket, ok := l.getBucket(f)
+	if !ok {
+		bucket = l.addBucket(f, now)
+	}
+	if bucket == nil {
+		return true
+	}
+	return bucket.Take(now)
+}
+
+func (l *EdgeRateLimiter) getBucket(f CallFrame) (*ratelimit.Bucket, bool) {
+	l.RLock()
+	defer l.RUnlock()
+
+	bucket, ok := l.buckets[string(f.Caller())][string(f.Service())][string(f.Method())]
+	return bucket, ok
+}
+
+// addBucket returns the bucket for an edge that isn't tracked yet, or nil if
+// the edge doesn't match any limit. Edges without a limit aren't tracked, so
+// that calls on them can't grow the rate limiter.
+func (l *EdgeRateLimiter) addBucket(f CallFrame, now time.Time) *ratelimit.Bucket {
+	l.RLock()
+	_, ok := l.matchLimit(f)
+	l.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	caller, service, method := string(f.Caller()), string(f.Service()), string(f.Method())
+
+	l.Lock()
+	defer l.Unlock()
+
+	if bucket, ok := l.buckets[caller][service][method]; ok {
+		// Another call on this edge added the bucket first.
+		return bucket
+	}
+	// The limits may have been replaced since the edge was matched.
+	matched, ok := l.matchLimit(f)
+	if !ok {
+		return nil
+	}
+
+	if l.numBuckets >= l.maxBuckets {
+		l.sweep(now)
+		if l.numBuckets >= l.maxBuckets {
+			bucket, ok := l.overflow[matched.key]
+			if !ok {
+				bucket = newTokenBucket(matched.limit, now)
+				l.overflow[matched.key] = bucket
+			}
+			return bucket
+		}
+	}
+
+	byService, ok := l.buckets[caller]
+	if !ok {
+		byService = make(map[string]map[string]*ratelimit.Bucket)
+		l.buckets[caller] = byService
+	}
+	byMethod, ok := byService[service]
+	if !ok {
+		byMethod = make(map[string]*ratelimit.Bucket)
+		byService[service] = byMethod
+	}
+	bucket := newTokenBucket(matched.limit, now)
+	byMethod[method] = bucket
+	l.numBuckets++
+	return bucket
+}
+
+// sweep removes buckets that have refilled, since they are the same as a new
+// bucket. To avoid scanning every bucket for each call while the rate limiter
+// is full, sweep runs at most once a second. It must be called with the lock
+// held.
+func (l *EdgeRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Second {
+		return
+	}
+	l.lastSweep = now
+
+	for caller, byService := range l.buckets {
+		for service, byMethod := range byService {
+			for method, bucket := range byMethod {
+				if bucket.Full(now) {
+					delete(byMethod, method)
+					l.numBuckets--
+				}
+			}
+			if len(byMethod) == 0 {
+				delete(byService, service)
+			}
+		}
+		if len(byService) == 0 {
+			delete(l.buckets, caller)
+		}
+	}
+}
+
+// matchLimit returns the limit for the most specific key that matches the edge.
+func (l *EdgeRateLimiter) matchLimit(f CallFrame) (edgeLimit, bool) {
+	// Try the most specific keys first: service is weighted highest, then
+	// method, then caller. Each lookup converts the frame's bytes directly in
+	// the index expression so that it doesn't allocate.
+	for specificity := 7; specificity >= 0; specificity-- {
+		var (
+			byMethod map[string]map[string]edgeLimit
+			byCaller map[string]edgeLimit
+			limit    edgeLimit
+			ok       bool
+		)
+		if specificity&4 != 0 {
+			byMethod = l.limits[string(f.Service())]
+		} else {
+			byMethod = l.limits[Wildcard]
+		}
+		if specificity&2 != 0 {
+			byCaller = byMethod[string(f.Method())]
+		} else {
+			byCaller = byMethod[Wildcard]
+		}
+		if specificity&1 != 0 {
+			limit, ok = byCaller[string(f.Caller())]
+		} else {
+			limit, ok = byCaller[Wildcard]
+		}
+		if ok {
+			return limit, true
+		}
+	}
+	return edgeLimit{}, false
+}
+
+// newTokenBucket returns a bucket that allows calls at rate per second, with
+// bursts of up to a second's worth of calls.
+func newTokenBucket(rate float64, now time.Time) *ratelimit.Bucket {
+	return ratelimit.NewBucket(rate, rate, now)
+}
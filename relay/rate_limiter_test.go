@@ -0,0 +1,207 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package relay
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCallFrame struct {
+	caller, service, method []byte
+}
+
+func newFakeCallFrame(caller, service, method string) *fakeCallFrame {
+	return &fakeCallFrame{[]byte(caller), []byte(service), []byte(method)}
+}
+
+func (f fakeCallFrame) Caller() []byte          { return f.caller }
+func (f fakeCallFrame) Service() []byte         { return f.service }
+func (f fakeCallFrame) Method() []byte          { return f.method }
+func (f fakeCallFrame) RoutingDelegate() []byte { return nil }
+func (f fakeCallFrame) RoutingKey() []byte      { return nil }
+func (f fakeCallFrame) ShardKey() []byte        { return nil }
+
+func newTestEdgeRateLimiter(t *testing.T, limits map[string]float64) (*EdgeRateLimiter, *time.Time) {
+	l, err := NewEdgeRateLimiter(limits)
+	require.NoError(t, err, "NewEdgeRateLimiter failed")
+
+	now := time.Unix(1000, 0)
+	l.timeNow = func() time.Time { return now }
+	return l, &now
+}
+
+func countAllowed(l *EdgeRateLimiter, f CallFrame, n int) int {
+	allowed := 0
+	for i := 0; i < n; i++ {
+		if l.Allow(f) {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestEdgeRateLimiter(t *testing.T) {
+	l, now := newTestEdgeRateLimiter(t, map[string]float64{
+		"c1->svc::m1": 10,
+	})
+
+	limited := newFakeCallFrame("c1", "svc", "m1")
+	assert.Equal(t, 10, countAllowed(l, limited, 20), "Expected a burst of a second's worth of calls")
+
+	*now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, 5, countAllowed(l, limited, 20), "Expected calls to be allowed at the configured rate")
+
+	*now = now.Add(time.Hour)
+	assert.Equal(t, 10, countAllowed(l, limited, 20), "Expected the burst to be capped")
+
+	for _, f := range []*fakeCallFrame{
+		newFakeCallFrame("c2", "svc", "m1"),
+		newFakeCallFrame("c1", "svc", "m2"),
+		newFakeCallFrame("c1", "other", "m1"),
+	} {
+		assert.Equal(t, 20, countAllowed(l, f, 20), "Edge %s->%s::%s should not be limited", f.caller, f.service, f.method)
+	}
+}
+
+func TestEdgeRateLimiterWildcards(t *testing.T) {
+	l, _ := newTestEdgeRateLimiter(t, map[string]float64{
+		"*->svc::*":    2,
+		"*->svc::m1":   3,
+		"c1->svc::*":   4,
+		"c1->*::*":     5,
+		"c2->svc::m2":  6,
+		"*->other::m3": 0.5,
+	})
+
+	tests := []struct {
+		caller, service, method string
+		want                    int
+	}{
+		{"c2", "svc", "m2", 6},   // exact match
+		{"c1", "svc", "m1", 3},   // service and method before service and caller
+		{"c3", "svc", "m1", 3},   // service and method
+		{"c1", "svc", "m2", 4},   // service and caller
+		{"c3", "svc", "m2", 2},   // service
+		{"c1", "foo", "m1", 5},   // caller
+		{"c3", "other", "m3", 1}, // fractional limits allow a single call
+		{"c3", "foo", "m1", 20},  // no match
+	}
+
+	for _, tt := range tests {
+		f := newFakeCallFrame(tt.caller, tt.service, tt.method)
+		assert.Equal(t, tt.want, countAllowed(l, f, 20), "Unexpected calls allowed for %v->%v::%v", tt.caller, tt.service, tt.method)
+	}
+
+	// Edges that match the same wildcard have separate limits.
+	assert.Equal(t, 2, countAllowed(l, newFakeCallFrame("c4", "svc", "m2"), 20),
+		"Expected a separate limit for a new edge matching a wildcard")
+}
+
+func TestEdgeRateLimiterSetLimits(t *testing.T) {
+	l, _ := newTestEdgeRateLimiter(t, map[string]float64{
+		"c1->svc::m1": 2,
+	})
+
+	f := newFakeCallFrame("c1", "svc", "m1")
+	other := newFakeCallFrame("c2", "svc", "m1")
+	assert.Equal(t, 2, countAllowed(l, f, 10), "Unexpected calls allowed before reload")
+	assert.Equal(t, 10, countAllowed(l, other, 10), "Edge without a limit should not be limited")
+
+	require.NoError(t, l.SetLimits(map[string]float64{
+		"*->svc::m1": 5,
+	}), "SetLimits failed")
+	assert.Equal(t, 5, countAllowed(l, f, 10), "Reloaded limit should apply with a full bucket")
+	assert.Equal(t, 5, countAllowed(l, other, 10), "Reloaded limit should apply to new edges")
+
+	assert.Error(t, l.SetLimits(map[string]float64{"invalid": 1}), "Expected invalid limits to fail")
+	assert.Equal(t, 0, countAllowed(l, f, 10), "Invalid limits should not replace the existing limits")
+}
+
+func TestEdgeRateLimiterInvalid(t *testing.T) {
+	tests := []struct {
+		key   string
+		limit float64
+	}{
+		{"svc::method", 1},
+		{"caller->svc", 1},
+		{"->svc::method", 1},
+		{"caller->::method", 1},
+		{"caller->svc::", 1},
+		{"caller->svc::method", 0},
+		{"caller->svc::method", -1},
+	}
+
+	for _, tt := range tests {
+		_, err := NewEdgeRateLimiter(map[string]float64{tt.key: tt.limit})
+		assert.Error(t, err, "Expected error for %q with limit %v", tt.key, tt.limit)
+	}
+}
+
+func TestEdgeRateLimiterNoAllocs(t *testing.T) {
+	l, now := newTestEdgeRateLimiter(t, map[string]float64{"*->svc::*": 1e9})
+	limited := newFakeCallFrame("c1", "svc", "m1")
+	unlimited := newFakeCallFrame("c1", "other", "m1")
+
+	// The first call on each edge creates its bucket.
+	l.Allow(limited)
+	l.Allow(unlimited)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		*now = now.Add(time.Second)
+		l.Allow(limited)
+		l.Allow(unlimited)
+	})
+	assert.Zero(t, allocs, "Allow should not allocate for known edges")
+}
+
+func TestEdgeRateLimiterBoundsEdges(t *testing.T) {
+	l, now := newTestEdgeRateLimiter(t, map[string]float64{
+		"*->svc::*": 1,
+	})
+	l.maxBuckets = 2
+
+	for i := 0; i < 10; i++ {
+		f := newFakeCallFrame("c1", "other", fmt.Sprint("m", i))
+		assert.True(t, l.Allow(f), "Edges without a limit should be allowed")
+	}
+	assert.Empty(t, l.buckets, "Edges without a limit should not be tracked")
+
+	for _, caller := range []string{"c1", "c2"} {
+		assert.Equal(t, 1, countAllowed(l, newFakeCallFrame(caller, "svc", "m"), 5), "Unexpected calls allowed for %v", caller)
+	}
+
+	// Once the rate limiter is full, new edges share a bucket for the limit
+	// they match, so rotating names can't escape the limit.
+	assert.True(t, l.Allow(newFakeCallFrame("c3", "svc", "m")), "First untracked edge should use the overflow bucket")
+	assert.False(t, l.Allow(newFakeCallFrame("c4", "svc", "m")), "Untracked edges should share the overflow bucket")
+	assert.Equal(t, 2, l.numBuckets, "Buckets should be bounded")
+
+	// Buckets that have refilled are dropped to make room for new edges.
+	*now = now.Add(2 * time.Second)
+	assert.Equal(t, 1, countAllowed(l, newFakeCallFrame("c5", "svc", "m"), 5), "New edge should have its own bucket")
+	assert.Equal(t, 1, l.numBuckets, "Refilled buckets should be dropped")
+	assert.Len(t, l.buckets, 1, "Empty maps for dropped buckets should be removed")
+}
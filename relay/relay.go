@@ -37,6 +37,28 @@ type CallFrame interface {
 	// RoutingKey may refer to an alternate traffic group instead of the
 	// traffic group identified by the service name.
 	RoutingKey() []byte
+	// ShardKey identifies the shard the call belongs to, and can be used to
+	// route calls for the same shard to the same instance.
+	ShardKey() []byte
+}
+
+// Stats creates the stats for relayed calls.
+type Stats interface {
+	// Begin is called when the relay receives a call, and returns the stats
+	// for the call. The frame is only valid until Begin returns.
+	Begin(f CallFrame) CallStats
+}
+
+// CallStats reports the outcome of a relayed call.
+type CallStats interface {
+	// Succeeded is called if the call succeeded.
+	Succeeded()
+
+	// Failed is called with the failure reason if the call failed.
+	Failed(reason string)
+
+	// End is called exactly once when the call ends.
+	End()
 }
 
 // Conn contains information about the underlying connection.
@@ -14,7 +14,9 @@ type hostFunc struct {
 type hostFuncPeer struct {
 	*MockCallStats
 
-	peer *tchannel.Peer
+	peers *tchannel.PeerList
+	peer  *tchannel.Peer
+	tried triedPeers
 }
 
 // HostFunc wraps a given function to implement tchannel.RelayHost.
@@ -30,13 +32,16 @@ func (hf *hostFunc) SetChannel(ch *tchannel.Channel) {
 func (hf *hostFunc) Start(cf relay.CallFrame, conn *relay.Conn) (tchannel.RelayCall, error) {
 	var peer *tchannel.Peer
 
+	peers := hf.ch.GetSubChannel(string(cf.Service())).Peers()
 	peerHP, err := hf.fn(cf, conn)
 	if peerHP != "" {
-		peer = hf.ch.GetSubChannel(string(cf.Service())).Peers().GetOrAdd(peerHP)
+		peer = peers.GetOrAdd(peerHP)
 	}
 
 	// We still track stats if we failed to get a peer, so return the peer.
-	return &hostFuncPeer{hf.stats.Begin(cf), peer}, err
+	stats := hf.stats.Begin(cf)
+	stats.setPeer(peer)
+	return &hostFuncPeer{MockCallStats: stats, peers: peers, peer: peer}, err
 }
 
 func (hf *hostFunc) Stats() *MockStats {
@@ -46,3 +51,9 @@ func (hf *hostFunc) Stats() *MockStats {
 func (p *hostFuncPeer) Destination() (*tchannel.Peer, bool) {
 	return p.peer, p.peer != nil
 }
+
+// RetryDestination selects a peer from the subchannel that hasn't failed for
+// the call.
+func (p *hostFuncPeer) RetryDestination(failed *tchannel.Peer) (*tchannel.Peer, bool) {
+	return p.tried.retry(p.peers, failed)
+}
@@ -25,9 +25,11 @@ import (
 	"sort"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/uber/tchannel-go"
 	"github.com/uber/tchannel-go/relay"
 )
 
@@ -39,7 +41,32 @@ type MockCallStats struct {
 	succeeded  int
 	failedMsgs []string
 	ended      int
+	duration   time.Duration
+	retries    []string
 	wg         *sync.WaitGroup
+
+	// peer is the host:port of the peer the RPC was sent to. On expected
+	// calls, peers is the set of host:ports the actual peer must be in.
+	peer  string
+	peers []string
+
+	// minDuration and maxDuration are only set on expected calls, and
+	// are the range that the actual call's duration must be within.
+	minDuration time.Duration
+	maxDuration time.Duration
+
+	// frames and frameBytes are the frames relayed for the RPC. On expected
+	// calls, frameBytes is only checked to be within [minFrameBytes,
+	// maxFrameBytes].
+	frames        int
+	frameBytes    int64
+	minFrameBytes int64
+	maxFrameBytes int64
+
+	// reqFrames and resFrames are the frames relayed for the RPC in each
+	// direction. On expected calls, they're only checked if resFrames is set.
+	reqFrames int
+	resFrames int
 }
 
 // Succeeded marks the RPC as succeeded.
@@ -52,6 +79,38 @@ func (m *MockCallStats) Failed(reason string) {
 	m.failedMsgs = append(m.failedMsgs, reason)
 }
 
+// SetDuration records the duration of the RPC.
+func (m *MockCallStats) SetDuration(d time.Duration) {
+	m.duration = d
+}
+
+// RetriedTo records that the RPC was retried on the given peer.
+func (m *MockCallStats) RetriedTo(peer *tchannel.Peer) {
+	m.retries = append(m.retries, peer.HostPort())
+	m.peer = peer.HostPort()
+}
+
+// setPeer records the peer selected for the RPC by the RelayHost.
+func (m *MockCallStats) setPeer(peer *tchannel.Peer) {
+	if peer != nil {
+		m.peer = peer.HostPort()
+	}
+}
+
+// SetFrameCount records the number and total size of the frames relayed for
+// the RPC.
+func (m *MockCallStats) SetFrameCount(n int, bytes int64) {
+	m.frames = n
+	m.frameBytes = bytes
+}
+
+// SetFrameStats records the number of frames relayed for the RPC in each
+// direction.
+func (m *MockCallStats) SetFrameStats(request, response tchannel.RelayFrameStats) {
+	m.reqFrames = request.Frames
+	m.resFrames = response.Frames
+}
+
 // End halts timer and metric collection for the RPC.
 func (m *MockCallStats) End() {
 	m.ended++
@@ -75,6 +134,56 @@ func (f *FluentMockCallStats) Failed(reason string) *FluentMockCallStats {
 	return f
 }
 
+// RetriedTo expects the RPC to be retried on the peer with the given host:port.
+func (f *FluentMockCallStats) RetriedTo(hostPort string) *FluentMockCallStats {
+	f.MockCallStats.retries = append(f.MockCallStats.retries, hostPort)
+	return f
+}
+
+// Peer expects the RPC to be sent to the peer with the given host:port, after
+// any retries. If neither Peer nor PeerIn is used, the peer is not checked.
+func (f *FluentMockCallStats) Peer(hostPort string) *FluentMockCallStats {
+	return f.PeerIn(hostPort)
+}
+
+// PeerIn expects the RPC to be sent to any of the peers with the given
+// host:ports, for tests where peer selection is nondeterministic.
+func (f *FluentMockCallStats) PeerIn(hostPorts ...string) *FluentMockCallStats {
+	f.MockCallStats.peers = hostPorts
+	return f
+}
+
+// DurationBetween expects the duration of the RPC to be within [min, max].
+// If it's not used, the duration of the RPC is not checked.
+func (f *FluentMockCallStats) DurationBetween(min, max time.Duration) *FluentMockCallStats {
+	f.MockCallStats.minDuration = min
+	f.MockCallStats.maxDuration = max
+	return f
+}
+
+// FrameCount expects n frames to be relayed for the RPC. If it's not used, the
+// number of frames is not checked.
+func (f *FluentMockCallStats) FrameCount(n int) *FluentMockCallStats {
+	f.MockCallStats.frames = n
+	return f
+}
+
+// FrameBytesBetween expects the total size of the frames relayed for the RPC
+// to be within [min, max]. If it's not used, the size is not checked.
+func (f *FluentMockCallStats) FrameBytesBetween(min, max int64) *FluentMockCallStats {
+	f.MockCallStats.minFrameBytes = min
+	f.MockCallStats.maxFrameBytes = max
+	return f
+}
+
+// RequestResponseFrames expects the given number of frames to be relayed for
+// the RPC in each direction. If it's not used, they're not checked.
+func (f *FluentMockCallStats) RequestResponseFrames(request, response int) *FluentMockCallStats {
+	f.MockCallStats.reqFrames = request
+	f.MockCallStats.resFrames = response
+	return f
+}
+
 // MockStats is a testing spy for the Stats interface.
 type MockStats struct {
 	mu    sync.Mutex
@@ -146,6 +255,25 @@ func (m *MockStats) assertCallEqual(t testing.TB, expected *MockCallStats, actua
 	assert.Equal(t, expected.succeeded, actual.succeeded, "Unexpected number of successes.")
 	assert.Equal(t, expected.failedMsgs, actual.failedMsgs, "Unexpected reasons for RPC failure.")
 	assert.Equal(t, expected.ended, actual.ended, "Unexpected number of calls to End.")
+	assert.Equal(t, expected.retries, actual.retries, "Unexpected peers the RPC was retried on.")
+	if len(expected.peers) > 0 {
+		assert.Contains(t, expected.peers, actual.peer, "Unexpected peer the RPC was sent to.")
+	}
+	if expected.maxDuration > 0 {
+		assert.True(t, actual.duration >= expected.minDuration && actual.duration <= expected.maxDuration,
+			"Unexpected duration %v, expected between %v and %v.", actual.duration, expected.minDuration, expected.maxDuration)
+	}
+	if expected.frames > 0 {
+		assert.Equal(t, expected.frames, actual.frames, "Unexpected number of frames relayed.")
+	}
+	if expected.resFrames > 0 {
+		assert.Equal(t, expected.reqFrames, actual.reqFrames, "Unexpected number of request frames relayed.")
+		assert.Equal(t, expected.resFrames, actual.resFrames, "Unexpected number of response frames relayed.")
+	}
+	if expected.maxFrameBytes > 0 {
+		assert.True(t, actual.frameBytes >= expected.minFrameBytes && actual.frameBytes <= expected.maxFrameBytes,
+			"Unexpected frame bytes %v, expected between %v and %v.", actual.frameBytes, expected.minFrameBytes, expected.maxFrameBytes)
+	}
 
 	if t.Failed() {
 		// The default testify output is often insufficient.
@@ -153,6 +281,33 @@ func (m *MockStats) assertCallEqual(t testing.TB, expected *MockCallStats, actua
 	}
 }
 
+// AssertPeersUsed asserts that every call along the edge was sent to one of
+// the peers with the given host:ports, and that each of them received at
+// least one call.
+func (m *MockStats) AssertPeersUsed(t testing.TB, caller, callee, procedure string, hostPorts ...string) {
+	// Wait for any outstanding CallStats to end.
+	m.wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	edge := m.tripleToKey(caller, callee, procedure)
+	calls, ok := m.stats[edge]
+	if !assert.True(t, ok, "No calls along %s edge.", edge) {
+		return
+	}
+
+	callsByPeer := make(map[string]int, len(hostPorts))
+	for _, call := range calls {
+		if assert.Contains(t, hostPorts, call.peer, "Call along %s edge sent to unexpected peer.", edge) {
+			callsByPeer[call.peer]++
+		}
+	}
+	for _, hostPort := range hostPorts {
+		assert.NotZero(t, callsByPeer[hostPort], "No calls along %s edge were sent to %v, calls by peer: %v", edge, hostPort, callsByPeer)
+	}
+}
+
 func (m *MockStats) tripleToKey(caller, callee, procedure string) string {
 	return fmt.Sprintf("%s->%s::%s", caller, callee, procedure)
 }
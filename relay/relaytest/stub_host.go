@@ -42,7 +42,9 @@ type StubRelayHost struct {
 type stubCall struct {
 	*MockCallStats
 
-	peer *tchannel.Peer
+	peers *tchannel.PeerList
+	peer  *tchannel.Peer
+	tried triedPeers
 }
 
 // NewStubRelayHost creates a new stub RelayHost for tests.
@@ -59,8 +61,11 @@ func (rh *StubRelayHost) SetChannel(ch *tchannel.Channel) {
 // Start starts a new RelayCall for the given call on a specific connection.
 func (rh *StubRelayHost) Start(cf relay.CallFrame, _ *relay.Conn) (tchannel.RelayCall, error) {
 	// Get a peer from the subchannel.
-	peer, err := rh.ch.GetSubChannel(string(cf.Service())).Peers().Get(nil)
-	return &stubCall{rh.stats.Begin(cf), peer}, err
+	peers := rh.ch.GetSubChannel(string(cf.Service())).Peers()
+	peer, err := peers.Get(nil)
+	stats := rh.stats.Begin(cf)
+	stats.setPeer(peer)
+	return &stubCall{MockCallStats: stats, peers: peers, peer: peer}, err
 }
 
 // Add adds a service instance with the specified host:port.
@@ -77,3 +82,33 @@ func (rh *StubRelayHost) Stats() *MockStats {
 func (c *stubCall) Destination() (*tchannel.Peer, bool) {
 	return c.peer, c.peer != nil
 }
+
+// RetryDestination selects a peer from the subchannel that hasn't failed for
+// the call.
+func (c *stubCall) RetryDestination(failed *tchannel.Peer) (*tchannel.Peer, bool) {
+	return c.tried.retry(c.peers, failed)
+}
+
+// triedPeers tracks the peers that failed for a call, so that retries only
+// select peers that haven't been tried.
+type triedPeers struct {
+	hostPorts map[string]struct{}
+}
+
+// retry records that failed was tried, and selects a peer from peers that
+// hasn't been tried.
+func (t *triedPeers) retry(peers *tchannel.PeerList, failed *tchannel.Peer) (*tchannel.Peer, bool) {
+	if t.hostPorts == nil {
+		t.hostPorts = make(map[string]struct{})
+	}
+	t.hostPorts[failed.HostPort()] = struct{}{}
+
+	peer, err := peers.Get(t.hostPorts)
+	if err != nil {
+		return nil, false
+	}
+	if _, ok := t.hostPorts[peer.HostPort()]; ok {
+		return nil, false
+	}
+	return peer, true
+}
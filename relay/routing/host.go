@@ -0,0 +1,296 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package routing
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/relay"
+)
+
+// Ensure that the Host implements tchannel.RelayHost.
+var _ tchannel.RelayHost = (*Host)(nil)
+
+// HostOptions are used to create a Host.
+type HostOptions struct {
+	// Source is used to load the routing table.
+	Source Source
+
+	// ReloadInterval is how often the routing table is reloaded from the
+	// Source once the Host is used by a channel. If it's zero, the table is
+	// only reloaded when Reload is called.
+	ReloadInterval time.Duration
+
+	// ConsistentHashing routes calls with a shard key ("sk" transport header)
+	// to the host:port the shard key hashes to on a consistent hash ring of
+	// the service's host:ports, so calls for a shard stick to one instance.
+	// Calls without a shard key use the subchannel's peer selection.
+	ConsistentHashing bool
+
+	// HashReplicas is the number of points each host:port has on the hash
+	// ring when ConsistentHashing is enabled. Defaults to 100.
+	HashReplicas int
+}
+
+// Host is a tchannel.RelayHost that relays calls for each service to one of
+// the host:ports assigned to the service in the routing table, using the
+// peer selection of an isolated subchannel for the service. Calls for
+// services that aren't in the table are declined.
+//
+// When the table is reloaded, host:ports that are no longer assigned to a
+// service stop receiving new calls, but calls that are already being relayed
+// to them are not affected. With ConsistentHashing, only the shards that hash
+// to added or removed host:ports move to a different host:port.
+type Host struct {
+	opts HostOptions
+	ch   *tchannel.Channel
+
+	mut     sync.RWMutex
+	table   Table
+	data    []byte
+	rings   map[string]*hashRing
+	applied map[string][]string
+}
+
+type call struct {
+	peers *tchannel.PeerList
+	peer  *tchannel.Peer
+
+	// ring and shardKey are set for calls routed by consistent hashing.
+	ring     *hashRing
+	shardKey []byte
+	tried    map[string]struct{}
+}
+
+// NewHost returns a Host after loading the routing table from the Source.
+func NewHost(opts HostOptions) (*Host, error) {
+	if opts.Source == nil {
+		return nil, fmt.Errorf("routing table Source is required")
+	}
+
+	h := &Host{opts: opts}
+	if err := h.Reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// SetChannel is called by the channel after creation. It starts reloading
+// the routing table periodically if a ReloadInterval is set, until the
+// channel is closed.
+func (h *Host) SetChannel(ch *tchannel.Channel) {
+	h.mut.Lock()
+	h.ch = ch
+	h.applyLocked()
+	h.mut.Unlock()
+
+	if h.opts.ReloadInterval > 0 {
+		go h.reloadLoop()
+	}
+}
+
+func (h *Host) reloadLoop() {
+	ticker := time.NewTicker(h.opts.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := h.Reload(); err != nil {
+				h.ch.Logger().WithFields(tchannel.ErrField(err)).Warn("Failed to reload routing table.")
+			}
+		case <-h.ch.ClosedChan():
+			return
+		}
+	}
+}
+
+// Reload loads the routing table from the Source, and starts using it if it
+// has changed. If the table can't be loaded, the previous table is kept.
+func (h *Host) Reload() error {
+	data, err := h.opts.Source()
+	if err != nil {
+		return fmt.Errorf("failed to load routing table: %v", err)
+	}
+
+	h.mut.RLock()
+	unchanged := h.data != nil && bytes.Equal(data, h.data)
+	h.mut.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	table, err := ParseTable(data)
+	if err != nil {
+		return err
+	}
+
+	var rings map[string]*hashRing
+	if h.opts.ConsistentHashing {
+		rings = make(map[string]*hashRing, len(table))
+		for service, hostPorts := range table {
+			rings[service] = newHashRing(hostPorts, h.opts.HashReplicas)
+		}
+	}
+
+	h.mut.Lock()
+	h.table = table
+	h.data = data
+	h.rings = rings
+	h.applyLocked()
+	h.mut.Unlock()
+	return nil
+}
+
+// Table returns the routing table that's currently used.
+func (h *Host) Table() Table {
+	h.mut.RLock()
+	defer h.mut.RUnlock()
+	return h.table
+}
+
+// applyLocked updates the peers of each service's subchannel to match the
+// routing table. The Host must be write-locked.
+func (h *Host) applyLocked() {
+	if h.ch == nil {
+		return
+	}
+
+	for service, hostPorts := range h.applied {
+		if _, ok := h.table[service]; !ok {
+			removePeers(h.peers(service), hostPorts)
+		}
+	}
+
+	for service, hostPorts := range h.table {
+		peers := h.peers(service)
+		assigned := make(map[string]struct{}, len(hostPorts))
+		for _, hostPort := range hostPorts {
+			assigned[hostPort] = struct{}{}
+			peers.GetOrAdd(hostPort)
+		}
+
+		var removed []string
+		for _, hostPort := range h.applied[service] {
+			if _, ok := assigned[hostPort]; !ok {
+				removed = append(removed, hostPort)
+			}
+		}
+		removePeers(peers, removed)
+	}
+
+	h.applied = h.table
+}
+
+func (h *Host) peers(service string) *tchannel.PeerList {
+	return h.ch.GetSubChannel(service, tchannel.Isolated).Peers()
+}
+
+func removePeers(peers *tchannel.PeerList, hostPorts []string) {
+	for _, hostPort := range hostPorts {
+		// The peer may have been removed already if it was listed twice.
+		peers.Remove(hostPort)
+	}
+}
+
+// Start selects a peer for the call from the host:ports assigned to the
+// call's service. With ConsistentHashing, calls with a shard key are sent to
+// the host:port the shard key hashes to.
+func (h *Host) Start(cf relay.CallFrame, _ *relay.Conn) (tchannel.RelayCall, error) {
+	service := string(cf.Service())
+
+	h.mut.RLock()
+	_, ok := h.table[service]
+	ring := h.rings[service]
+	h.mut.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no routes for service %q", service)
+	}
+
+	peers := h.peers(service)
+	if shardKey := cf.ShardKey(); ring != nil && len(shardKey) > 0 {
+		hostPort, ok := ring.lookup(shardKey, nil)
+		if !ok {
+			return nil, fmt.Errorf("no routes for shard key %q of service %q", shardKey, service)
+		}
+		return &call{
+			peers:    peers,
+			peer:     peers.GetOrAdd(hostPort),
+			ring:     ring,
+			shardKey: append([]byte(nil), shardKey...),
+			tried:    map[string]struct{}{hostPort: {}},
+		}, nil
+	}
+
+	peer, err := peers.Get(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &call{peers: peers, peer: peer}, nil
+}
+
+// Destination returns the selected peer for this call.
+func (c *call) Destination() (*tchannel.Peer, bool) {
+	return c.peer, c.peer != nil
+}
+
+// RetryDestination selects a peer for the call's service that hasn't failed
+// for the call. Calls routed by consistent hashing are retried on the next
+// host:port on the ring that hasn't been tried.
+func (c *call) RetryDestination(failed *tchannel.Peer) (*tchannel.Peer, bool) {
+	if c.tried == nil {
+		c.tried = make(map[string]struct{})
+	}
+	c.tried[failed.HostPort()] = struct{}{}
+
+	if c.ring != nil {
+		hostPort, ok := c.ring.lookup(c.shardKey, c.tried)
+		if !ok {
+			return nil, false
+		}
+		c.tried[hostPort] = struct{}{}
+		c.peer = c.peers.GetOrAdd(hostPort)
+		return c.peer, true
+	}
+
+	peer, err := c.peers.Get(c.tried)
+	if err != nil {
+		return nil, false
+	}
+	if _, ok := c.tried[peer.HostPort()]; ok {
+		// All of the peers have failed.
+		return nil, false
+	}
+	c.peer = peer
+	return peer, true
+}
+
+// RetriedTo is called when the call is retried on another peer.
+func (c *call) RetriedTo(*tchannel.Peer) {}
+
+func (c *call) Succeeded()                {}
+func (c *call) Failed(string)             {}
+func (c *call) SetDuration(time.Duration) {}
+func (c *call) End()                      {}
@@ -0,0 +1,282 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package routing_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/raw"
+	. "github.com/uber/tchannel-go/relay/routing"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// newNamedServer returns a server for svc whose "name" method returns name.
+func newNamedServer(t *testing.T, name string, f func()) *tchannel.Channel {
+	ch := testutils.NewServer(t, testutils.NewOpts().SetServiceName("svc"))
+	testutils.RegisterFunc(ch, "name", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		if f != nil {
+			f()
+		}
+		return &raw.Res{Arg3: []byte(name)}, nil
+	})
+	return ch
+}
+
+func callName(t *testing.T, client *tchannel.Channel, relayHostPort string) (string, error) {
+	ctx, cancel := tchannel.NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	_, arg3, _, err := raw.Call(ctx, client, relayHostPort, "svc", "name", nil, nil)
+	return string(arg3), err
+}
+
+func writeTable(t *testing.T, path string, table string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(table), 0644), "Failed to write routing table")
+}
+
+func TestParseTable(t *testing.T) {
+	table, err := ParseTable([]byte(`{"svc": ["127.0.0.1:1", "127.0.0.1:2"]}`))
+	require.NoError(t, err, "ParseTable failed")
+	assert.Equal(t, Table{"svc": {"127.0.0.1:1", "127.0.0.1:2"}}, table, "Unexpected table")
+
+	_, err = ParseTable([]byte(`{"svc": "127.0.0.1:1"}`))
+	assert.Error(t, err, "Expected invalid JSON to fail")
+
+	_, err = ParseTable([]byte(`{"svc": ["127.0.0.1"]}`))
+	assert.Error(t, err, "Expected missing port to fail")
+}
+
+func TestHostReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "routing")
+	require.NoError(t, err, "TempDir failed")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "routes.json")
+
+	unblock := make(chan struct{})
+	gotCall := make(chan struct{})
+	s1 := newNamedServer(t, "s1", func() {
+		close(gotCall)
+		<-unblock
+	})
+	defer s1.Close()
+	s2 := newNamedServer(t, "s2", nil)
+	defer s2.Close()
+
+	writeTable(t, path, fmt.Sprintf(`{"svc": [%q]}`, s1.PeerInfo().HostPort))
+	host, err := NewHost(HostOptions{Source: FileSource(path)})
+	require.NoError(t, err, "NewHost failed")
+
+	relay := testutils.NewServer(t, testutils.NewOpts().SetServiceName("relay").SetRelayHost(host))
+	defer relay.Close()
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	// Start a call on s1 that's in-flight while the table is reloaded.
+	type result struct {
+		name string
+		err  error
+	}
+	inflight := make(chan result, 1)
+	go func() {
+		name, err := callName(t, client, relay.PeerInfo().HostPort)
+		inflight <- result{name, err}
+	}()
+	<-gotCall
+
+	writeTable(t, path, fmt.Sprintf(`{"svc": [%q]}`, s2.PeerInfo().HostPort))
+	require.NoError(t, host.Reload(), "Reload failed")
+	assert.Equal(t, Table{"svc": {s2.PeerInfo().HostPort}}, host.Table(), "Unexpected table after reload")
+
+	for i := 0; i < 3; i++ {
+		name, err := callName(t, client, relay.PeerInfo().HostPort)
+		require.NoError(t, err, "Call after reload failed")
+		assert.Equal(t, "s2", name, "Calls should be routed to the new host:port")
+	}
+
+	close(unblock)
+	res := <-inflight
+	require.NoError(t, res.err, "In-flight call should not be affected by the reload")
+	assert.Equal(t, "s1", res.name, "In-flight call should complete on the old host:port")
+
+	// An invalid table is not used.
+	writeTable(t, path, `{"svc": ["invalid"]}`)
+	assert.Error(t, host.Reload(), "Expected invalid table to fail")
+	assert.Equal(t, Table{"svc": {s2.PeerInfo().HostPort}}, host.Table(), "Table should not change")
+
+	// Calls for services that are no longer routed are declined.
+	writeTable(t, path, `{"other": ["127.0.0.1:1"]}`)
+	require.NoError(t, host.Reload(), "Reload failed")
+	_, err = callName(t, client, relay.PeerInfo().HostPort)
+	assert.Equal(t, tchannel.ErrCodeDeclined, tchannel.GetSystemErrorCode(err), "Expected call to be declined")
+}
+
+func TestHostReloadInterval(t *testing.T) {
+	s1 := newNamedServer(t, "s1", nil)
+	defer s1.Close()
+	s2 := newNamedServer(t, "s2", nil)
+	defer s2.Close()
+
+	routes := make(chan string, 1)
+	routes <- s1.PeerInfo().HostPort
+	current := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case current = <-routes:
+		default:
+		}
+		fmt.Fprintf(w, `{"svc": [%q]}`, current)
+	}))
+	defer server.Close()
+
+	host, err := NewHost(HostOptions{
+		Source:         HTTPSource(server.URL, nil),
+		ReloadInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err, "NewHost failed")
+
+	relay := testutils.NewServer(t, testutils.NewOpts().SetServiceName("relay").SetRelayHost(host))
+	defer relay.Close()
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	name, err := callName(t, client, relay.PeerInfo().HostPort)
+	require.NoError(t, err, "Call failed")
+	assert.Equal(t, "s1", name, "Unexpected server")
+
+	routes <- s2.PeerInfo().HostPort
+	assert.True(t, testutils.WaitFor(time.Second, func() bool {
+		name, err := callName(t, client, relay.PeerInfo().HostPort)
+		return err == nil && name == "s2"
+	}), "Routing table was not reloaded")
+}
+
+func TestNewHostErrors(t *testing.T) {
+	_, err := NewHost(HostOptions{})
+	assert.Error(t, err, "Expected missing Source to fail")
+
+	_, err = NewHost(HostOptions{Source: FileSource("/does/not/exist")})
+	assert.Error(t, err, "Expected missing file to fail")
+}
+
+func callShard(t *testing.T, client *tchannel.Channel, relayHostPort, shardKey string) string {
+	ctx, cancel := tchannel.NewContextBuilder(testutils.Timeout(time.Second)).
+		SetShardKey(shardKey).Build()
+	defer cancel()
+
+	_, arg3, _, err := raw.Call(ctx, client, relayHostPort, "svc", "name", nil, nil)
+	require.NoError(t, err, "Call for shard %v failed", shardKey)
+	return string(arg3)
+}
+
+func TestHostConsistentHashing(t *testing.T) {
+	names := []string{"s1", "s2", "s3"}
+	var hostPorts []string
+	for _, name := range names {
+		ch := newNamedServer(t, name, nil)
+		defer ch.Close()
+		hostPorts = append(hostPorts, ch.PeerInfo().HostPort)
+	}
+
+	table := fmt.Sprintf(`{"svc": [%q, %q, %q]}`, hostPorts[0], hostPorts[1], hostPorts[2])
+	host, err := NewHost(HostOptions{
+		Source:            func() ([]byte, error) { return []byte(table), nil },
+		ConsistentHashing: true,
+	})
+	require.NoError(t, err, "NewHost failed")
+
+	relay := testutils.NewServer(t, testutils.NewOpts().SetServiceName("relay").SetRelayHost(host))
+	defer relay.Close()
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	shards := make(map[string]string)
+	used := make(map[string]struct{})
+	for i := 0; i < 30; i++ {
+		shardKey := fmt.Sprintf("shard-%v", i)
+		shards[shardKey] = callShard(t, client, relay.PeerInfo().HostPort, shardKey)
+		used[shards[shardKey]] = struct{}{}
+
+		for j := 0; j < 3; j++ {
+			assert.Equal(t, shards[shardKey], callShard(t, client, relay.PeerInfo().HostPort, shardKey),
+				"Calls for shard %v should go to the same server", shardKey)
+		}
+	}
+	assert.Len(t, used, len(names), "Shards should be spread across all servers")
+
+	// Only the shards on the removed host:port move.
+	table = fmt.Sprintf(`{"svc": [%q, %q]}`, hostPorts[0], hostPorts[1])
+	require.NoError(t, host.Reload(), "Reload failed")
+	for shardKey, name := range shards {
+		got := callShard(t, client, relay.PeerInfo().HostPort, shardKey)
+		if name == "s3" {
+			assert.NotEqual(t, "s3", got, "Shard %v should move off the removed server", shardKey)
+		} else {
+			assert.Equal(t, name, got, "Shard %v should not move", shardKey)
+		}
+	}
+}
+
+func TestHostRetryDestinationExcludesFailedPeers(t *testing.T) {
+	hostPorts := []string{"127.0.0.1:1", "127.0.0.1:2", "127.0.0.1:3"}
+	for _, consistentHashing := range []bool{false, true} {
+		t.Run(fmt.Sprintf("consistent hashing %v", consistentHashing), func(t *testing.T) {
+			table := fmt.Sprintf(`{"svc": [%q, %q, %q]}`, hostPorts[0], hostPorts[1], hostPorts[2])
+			host, err := NewHost(HostOptions{
+				Source:            func() ([]byte, error) { return []byte(table), nil },
+				ConsistentHashing: consistentHashing,
+			})
+			require.NoError(t, err, "NewHost failed")
+
+			relay := testutils.NewServer(t, testutils.NewOpts().SetServiceName("relay").SetRelayHost(host))
+			defer relay.Close()
+
+			call, err := host.Start(testutils.FakeCallFrame{ServiceF: "svc", ShardKeyF: "shard"}, nil)
+			require.NoError(t, err, "Start failed")
+			retryable, ok := call.(tchannel.RelayRetryableCall)
+			require.True(t, ok, "Call should be retryable")
+
+			peer, ok := call.Destination()
+			require.True(t, ok, "Expected a destination")
+			tried := map[string]struct{}{peer.HostPort(): {}}
+			for i := 1; i < len(hostPorts); i++ {
+				peer, ok = retryable.RetryDestination(peer)
+				require.True(t, ok, "Retry %v should select a peer", i)
+				require.NotContains(t, tried, peer.HostPort(), "Retry %v should not select a failed peer", i)
+				tried[peer.HostPort()] = struct{}{}
+			}
+
+			_, ok = retryable.RetryDestination(peer)
+			assert.False(t, ok, "There should be no peer to retry on once all peers failed")
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package routing
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// defaultReplicas is the number of points each host:port has on the ring.
+const defaultReplicas = 100
+
+// hashRing is a consistent hash ring of host:ports. Adding or removing a
+// host:port only changes the host:port for the keys that hash next to its
+// points on the ring.
+type hashRing struct {
+	hashes    []uint64
+	hostPorts map[uint64]string
+}
+
+func newHashRing(hostPorts []string, replicas int) *hashRing {
+	if replicas <= 0 {
+		replicas = defaultReplicas
+	}
+
+	r := &hashRing{hostPorts: make(map[uint64]string, len(hostPorts)*replicas)}
+	seen := make(map[string]struct{}, len(hostPorts))
+	for _, hostPort := range hostPorts {
+		if _, ok := seen[hostPort]; ok {
+			continue
+		}
+		seen[hostPort] = struct{}{}
+
+		for i := 0; i < replicas; i++ {
+			h := hashKey([]byte(hostPort + "#" + strconv.Itoa(i)))
+			if _, ok := r.hostPorts[h]; ok {
+				continue
+			}
+			r.hostPorts[h] = hostPort
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+// hashKey hashes the key using FNV-1a, mixing the result so that similar keys
+// such as the points of a host:port are spread around the ring.
+func hashKey(key []byte) uint64 {
+	f := fnv.New64a()
+	f.Write(key)
+	h := f.Sum64()
+
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// lookup returns the first host:port at or after the key's position on the
+// ring that isn't in exclude. It returns false if every host:port is
+// excluded.
+func (r *hashRing) lookup(key []byte, exclude map[string]struct{}) (string, bool) {
+	if len(r.hashes) == 0 {
+		return "", false
+	}
+
+	h := hashKey(key)
+	start := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	for i := 0; i < len(r.hashes); i++ {
+		hostPort := r.hostPorts[r.hashes[(start+i)%len(r.hashes)]]
+		if _, ok := exclude[hostPort]; !ok {
+			return hostPort, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package routing
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashRingLookup(t *testing.T) {
+	r := newHashRing([]string{"h1:1", "h2:2", "h3:3", "h1:1"}, 0)
+	assert.Len(t, r.hashes, 3*defaultReplicas, "Duplicate host:ports should be ignored")
+
+	counts := make(map[string]int)
+	for i := 0; i < 3000; i++ {
+		hostPort, ok := r.lookup([]byte(fmt.Sprint(i)), nil)
+		assert.True(t, ok, "lookup failed")
+		counts[hostPort]++
+	}
+	for hostPort, count := range counts {
+		assert.InDelta(t, 1000, count, 300, "Keys are not spread evenly to %v", hostPort)
+	}
+
+	hostPort, _ := r.lookup([]byte("key"), nil)
+	exclude := map[string]struct{}{hostPort: {}}
+	next, ok := r.lookup([]byte("key"), exclude)
+	assert.True(t, ok, "lookup with exclude failed")
+	assert.NotEqual(t, hostPort, next, "Excluded host:port should be skipped")
+
+	exclude[next] = struct{}{}
+	last, ok := r.lookup([]byte("key"), exclude)
+	assert.True(t, ok, "lookup with exclude failed")
+	exclude[last] = struct{}{}
+	_, ok = r.lookup([]byte("key"), exclude)
+	assert.False(t, ok, "lookup should fail when all host:ports are excluded")
+
+	_, ok = newHashRing(nil, 0).lookup([]byte("key"), nil)
+	assert.False(t, ok, "lookup on an empty ring should fail")
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package routing contains a RelayHost that routes relayed calls using a
+// table of service to host:port assignments. The table is loaded from a
+// Source, such as a file or an HTTP endpoint, and can be reloaded at runtime
+// without affecting calls that are already being relayed.
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Table maps service names to the host:ports of the service's instances.
+type Table map[string][]string
+
+// ParseTable parses a routing table serialized as a JSON object of the form
+// {"service": ["host:port", ...]}.
+func ParseTable(data []byte) (Table, error) {
+	var table Table
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse routing table: %v", err)
+	}
+	for service, hostPorts := range table {
+		for _, hostPort := range hostPorts {
+			if _, _, err := net.SplitHostPort(hostPort); err != nil {
+				return nil, fmt.Errorf("invalid host:port %q for service %q: %v", hostPort, service, err)
+			}
+		}
+	}
+	return table, nil
+}
+
+// Source returns the serialized routing table.
+type Source func() ([]byte, error)
+
+// FileSource returns a Source that reads the routing table from a file.
+func FileSource(path string) Source {
+	return func() ([]byte, error) {
+		return ioutil.ReadFile(path)
+	}
+}
+
+// httpSourceTimeout is the timeout for fetching the routing table in
+// HTTPSource if the client doesn't set one, so a server that doesn't respond
+// can't block reloads.
+var httpSourceTimeout = 10 * time.Second
+
+// HTTPSource returns a Source that fetches the routing table from a URL
+// using the given client. If client is nil, http.DefaultClient is used. If
+// the client has no Timeout, each fetch times out after 10 seconds.
+func HTTPSource(url string, client *http.Client) Source {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if client.Timeout == 0 {
+		withTimeout := *client
+		withTimeout.Timeout = httpSourceTimeout
+		client = &withTimeout
+	}
+	return func() ([]byte, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status fetching routing table: %v", resp.Status)
+		}
+		return ioutil.ReadAll(resp.Body)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPSourceTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { httpSourceTimeout = timeout }(httpSourceTimeout)
+	httpSourceTimeout = testutils.Timeout(20 * time.Millisecond)
+
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	for _, client := range []*http.Client{nil, {}} {
+		errC := make(chan error, 1)
+		go func() {
+			_, err := HTTPSource(server.URL, client)()
+			errC <- err
+		}()
+
+		select {
+		case err := <-errC:
+			assert.Error(t, err, "Expected fetch from an unresponsive server to fail")
+		case <-time.After(testutils.Timeout(time.Second)):
+			t.Fatal("Fetch from an unresponsive server did not time out")
+		}
+	}
+	assert.Zero(t, http.DefaultClient.Timeout, "http.DefaultClient should not be modified")
+}
@@ -20,7 +20,11 @@
 
 package tchannel
 
-import "github.com/uber/tchannel-go/relay"
+import (
+	"time"
+
+	"github.com/uber/tchannel-go/relay"
+)
 
 // RelayHost is the interface used to create RelayCalls when the relay
 // receives an incoming call.
@@ -49,6 +53,113 @@ type RelayCall interface {
 	// The call failed.
 	Failed(reason string)
 
+	// SetDuration sets the time from when the relay received the call until
+	// the call finished. It's called once, right before End.
+	SetDuration(d time.Duration)
+
 	// End stats collection for this RPC. Will be called exactly once.
 	End()
 }
+
+// RelayCallWrapper can be embedded by types that wrap a RelayCall, to forward
+// the methods of the optional RelayRetryableCall, RelayFrameCountingCall and
+// RelayFrameStatsCall interfaces to the wrapped call when it implements them.
+// Wrappers only need to implement the methods they observe, and should call
+// the RelayCallWrapper's method from them.
+type RelayCallWrapper struct {
+	RelayCall
+}
+
+// RetryDestination implements RelayRetryableCall.
+func (w RelayCallWrapper) RetryDestination(failed *Peer) (*Peer, bool) {
+	retryable, ok := w.RelayCall.(RelayRetryableCall)
+	if !ok {
+		return nil, false
+	}
+	return retryable.RetryDestination(failed)
+}
+
+// RetriedTo implements RelayRetryableCall.
+func (w RelayCallWrapper) RetriedTo(peer *Peer) {
+	if retryable, ok := w.RelayCall.(RelayRetryableCall); ok {
+		retryable.RetriedTo(peer)
+	}
+}
+
+// SetFrameCount implements RelayFrameCountingCall.
+func (w RelayCallWrapper) SetFrameCount(n int, bytes int64) {
+	if counting, ok := w.RelayCall.(RelayFrameCountingCall); ok {
+		counting.SetFrameCount(n, bytes)
+	}
+}
+
+// SetFrameStats implements RelayFrameStatsCall.
+func (w RelayCallWrapper) SetFrameStats(request, response RelayFrameStats) {
+	if stats, ok := w.RelayCall.(RelayFrameStatsCall); ok {
+		stats.SetFrameStats(request, response)
+	}
+}
+
+// RelayRateLimitingHost is an optional interface for a RelayHost that provides
+// the rate limiter consulted for each relayed call. It's called for every new
+// call, so limits can be changed at runtime by returning a different
+// RateLimiter, or by updating it, e.g. with relay.EdgeRateLimiter.SetLimits.
+// If implemented, it's used instead of ChannelOptions.RelayRateLimiter, and
+// calls are not limited while it returns nil.
+type RelayRateLimitingHost interface {
+	RelayHost
+
+	// RateLimiter returns the rate limiter for new calls.
+	RateLimiter() relay.RateLimiter
+}
+
+// RelayRetryableCall is an optional interface for a RelayCall that can select
+// another destination when the relay fails to connect to the selected peer.
+// Retries only happen before any frames have been forwarded for the call.
+// See ChannelOptions.RelayMaxPeerRetries.
+type RelayRetryableCall interface {
+	RelayCall
+
+	// RetryDestination returns another peer to retry the call on, after the
+	// relay failed to connect to failed.
+	RetryDestination(failed *Peer) (peer *Peer, ok bool)
+
+	// RetriedTo is called for each retry with the peer the call is retried on.
+	RetriedTo(peer *Peer)
+}
+
+// RelayFrameCountingCall is an optional interface for a RelayCall that's told
+// how many frames were relayed for the call. Frames are only counted for calls
+// that implement it.
+type RelayFrameCountingCall interface {
+	RelayCall
+
+	// SetFrameCount is called right before End with the number of frames
+	// relayed for the call and their total size in bytes. Frames are counted
+	// the same way in both directions: the call req or res, any continuation
+	// frames, and any error frame. It's not called for calls that failed
+	// before they were forwarded.
+	SetFrameCount(n int, bytes int64)
+}
+
+// RelayFrameStats is the number and total size in bytes of the frames relayed
+// for a call in one direction.
+type RelayFrameStats struct {
+	Frames int
+	Bytes  int64
+}
+
+// RelayFrameStatsCall is an optional interface for a RelayCall that's told how
+// many frames were relayed for the call in each direction, which can be used
+// for per-edge request and response size metrics. Frames are only counted for
+// calls that implement it or RelayFrameCountingCall.
+type RelayFrameStatsCall interface {
+	RelayCall
+
+	// SetFrameStats is called right before End with the frames relayed from
+	// the caller to the destination (the call req and its continuations), and
+	// from the destination to the caller (the call res, its continuations,
+	// and any error frame). It's not called for calls that failed before they
+	// were forwarded.
+	SetFrameStats(request, response RelayFrameStats)
+}
@@ -0,0 +1,391 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/tchannel-go/relay"
+)
+
+// _maxCircuits bounds the number of circuits a circuit breaker tracks, so that
+// calls for many distinct keys can't grow it without bound.
+const _maxCircuits = 10000
+
+// _circuitOpenFailure is the failure reason for calls rejected by an open
+// circuit breaker.
+const _circuitOpenFailure = "circuit-open"
+
+// circuitIgnoredFailures are the RelayCall failure reasons that are caused by
+// the caller or by the relay itself rather than by the destination, so they
+// are not counted as failures by the circuit breaker.
+var circuitIgnoredFailures = map[string]struct{}{
+	ErrCodeCancelled.MetricsKey():       {},
+	ErrCodeCancelled.relayMetricsKey():  {},
+	ErrCodeBadRequest.MetricsKey():      {},
+	ErrCodeBadRequest.relayMetricsKey(): {},
+	ErrCodeTimeout.relayMetricsKey():    {},
+	"application-error":                 {},
+	"rate-limited":                      {},
+	"relay-dropped":                     {},
+	"relay-client-conn-inactive":        {},
+	"frame-too-large":                   {},
+	_circuitOpenFailure:                 {},
+}
+
+// RelayCircuitBreakerOptions configure a circuit breaker that stops the relay
+// from forwarding calls for a service and method that is failing consistently.
+type RelayCircuitBreakerOptions struct {
+	// FailureRatio is the ratio of failed calls to calls within the Window at
+	// which the circuit opens. While the circuit is open, calls are rejected
+	// with a declined error without being forwarded. If this is zero (the
+	// default), the circuit breaker is disabled.
+	//
+	// Failures caused by the caller, such as cancellations and bad requests,
+	// and application errors are not counted.
+	FailureRatio float64
+
+	// Window is the rolling window over which calls and failures are counted.
+	// Passing zero uses the default of 10s.
+	Window time.Duration
+
+	// MinRequests is the number of calls required within the Window before
+	// the circuit can open. Passing zero uses the default of 10.
+	MinRequests int
+
+	// Cooldown is how long the circuit stays open before it half-opens, and
+	// forwards a single call to probe whether the destination has recovered.
+	// If the probe succeeds, the circuit closes, otherwise it opens again.
+	// Passing zero uses the default of 5s.
+	Cooldown time.Duration
+}
+
+func (o RelayCircuitBreakerOptions) enabled() bool {
+	return o.FailureRatio > 0
+}
+
+func (o RelayCircuitBreakerOptions) withDefaults() RelayCircuitBreakerOptions {
+	if o.Window <= 0 {
+		o.Window = 10 * time.Second
+	}
+	if o.MinRequests <= 0 {
+		o.MinRequests = 10
+	}
+	if o.Cooldown <= 0 {
+		o.Cooldown = 5 * time.Second
+	}
+	return o
+}
+
+// CircuitState is the state of a relay circuit breaker.
+type CircuitState int
+
+//go:generate stringer -type=CircuitState
+
+const (
+	// CircuitClosed forwards calls, and counts their failures.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen rejects calls until the cooldown has passed.
+	CircuitOpen
+
+	// CircuitHalfOpen forwards a single probe call to decide whether to close
+	// or reopen the circuit.
+	CircuitHalfOpen
+)
+
+// CircuitBreakerRuntimeState is the state of the relay circuit breaker for a
+// service and method.
+type CircuitBreakerRuntimeState struct {
+	State    string    `json:"state"`
+	Requests int       `json:"requests"`
+	Failures int       `json:"failures"`
+	OpenedAt time.Time `json:"openedAt,omitempty"`
+}
+
+type circuitOutcome int
+
+const (
+	circuitSucceeded circuitOutcome = iota
+	circuitFailed
+	circuitIgnored
+)
+
+// circuit is the circuit breaker for a single service and method.
+type circuit struct {
+	sync.Mutex
+
+	state    CircuitState
+	requests rollingCounter
+	failures rollingCounter
+	openedAt time.Time
+	probing  bool
+}
+
+// newCircuit returns a closed circuit that counts calls over window.
+func newCircuit(window time.Duration) *circuit {
+	return &circuit{
+		requests: newRollingCounter(window),
+		failures: newRollingCounter(window),
+	}
+}
+
+// idle returns whether the circuit is closed and has no calls within the
+// window, in which case it's the same as a new circuit and can be dropped.
+func (c *circuit) idle(now time.Time) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.state == CircuitClosed && c.requests.count(now) == 0
+}
+
+// allow returns whether a call should be made, and whether the circuit
+// half-opened to allow it as a probe.
+func (c *circuit) allow(now time.Time, cooldown time.Duration) (ok bool, halfOpened bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	switch c.state {
+	case CircuitOpen:
+		if now.Sub(c.openedAt) < cooldown {
+			return false, false
+		}
+		c.state = CircuitHalfOpen
+		c.probing = true
+		return true, true
+	case CircuitHalfOpen:
+		if c.probing {
+			return false, false
+		}
+		c.probing = true
+		return true, false
+	}
+	return true, false
+}
+
+// record records the outcome of a call that was allowed by the circuit, and
+// returns the circuit's new state and whether it changed.
+func (c *circuit) record(now time.Time, outcome circuitOutcome, opts RelayCircuitBreakerOptions) (CircuitState, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	prev := c.state
+	switch c.state {
+	case CircuitHalfOpen:
+		c.probing = false
+		switch outcome {
+		case circuitSucceeded:
+			c.state = CircuitClosed
+			c.requests.reset()
+			c.failures.reset()
+		case circuitFailed:
+			c.state = CircuitOpen
+			c.openedAt = now
+		}
+	case CircuitClosed:
+		if outcome == circuitIgnored {
+			break
+		}
+		c.requests.inc(now)
+		if outcome == circuitFailed {
+			c.failures.inc(now)
+		}
+
+		requests, failures := c.requests.count(now), c.failures.count(now)
+		if requests >= opts.MinRequests && float64(failures) >= opts.FailureRatio*float64(requests) {
+			c.state = CircuitOpen
+			c.openedAt = now
+		}
+	}
+	// Calls that were made before the circuit opened are ignored.
+	return c.state, c.state != prev
+}
+
+// relayCircuitBreaker tracks the outcome of relayed calls per service and
+// method, and rejects calls for a circuit that is open. It's shared by all
+// of a channel's relayers.
+type relayCircuitBreaker struct {
+	opts        RelayCircuitBreakerOptions
+	timeNow     func() time.Time
+	maxCircuits int
+
+	sync.RWMutex
+	// circuits is keyed by service, then method, so that lookups using the
+	// call frame's bytes don't allocate.
+	circuits    map[string]map[string]*circuit
+	numCircuits int
+	lastSweep   time.Time
+}
+
+func newRelayCircuitBreaker(timeNow func() time.Time, opts RelayCircuitBreakerOptions) *relayCircuitBreaker {
+	if !opts.enabled() {
+		return nil
+	}
+	return &relayCircuitBreaker{
+		opts:        opts.withDefaults(),
+		timeNow:     timeNow,
+		maxCircuits: _maxCircuits,
+		circuits:    make(map[string]map[string]*circuit),
+	}
+}
+
+func (b *relayCircuitBreaker) getCircuit(f relay.CallFrame) *circuit {
+	b.RLock()
+	c, ok := b.circuits[string(f.Service())][string(f.Method())]
+	b.RUnlock()
+	if ok {
+		return c
+	}
+
+	service, method := string(f.Service()), string(f.Method())
+
+	b.Lock()
+	defer b.Unlock()
+
+	if c, ok := b.circuits[service][method]; ok {
+		// Another call for this method added the circuit first.
+		return c
+	}
+
+	c = newCircuit(b.opts.Window)
+	if b.numCircuits >= b.maxCircuits {
+		b.sweep(b.timeNow())
+		if b.numCircuits >= b.maxCircuits {
+			// The call is allowed by an untracked circuit, so calls to
+			// methods beyond the limit aren't broken until circuits expire.
+			return c
+		}
+	}
+
+	byMethod, ok := b.circuits[service]
+	if !ok {
+		byMethod = make(map[string]*circuit)
+		b.circuits[service] = byMethod
+	}
+	byMethod[method] = c
+	b.numCircuits++
+	return c
+}
+
+// sweep removes idle circuits. To avoid scanning every circuit for each call
+// while the circuit breaker is full, sweep runs at most once a second. It must
+// be called with the lock held.
+func (b *relayCircuitBreaker) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < time.Second {
+		return
+	}
+	b.lastSweep = now
+
+	for service, byMethod := range b.circuits {
+		for method, c := range byMethod {
+			if c.idle(now) {
+				delete(byMethod, method)
+				b.numCircuits--
+			}
+		}
+		if len(byMethod) == 0 {
+			delete(b.circuits, service)
+		}
+	}
+}
+
+// allow returns whether a call should be forwarded. Allowed calls must report
+// their outcome using the returned circuit.
+func (b *relayCircuitBreaker) allow(f relay.CallFrame) (*circuit, bool) {
+	c := b.getCircuit(f)
+	if ok, _ := c.allow(b.timeNow(), b.opts.Cooldown); !ok {
+		return nil, false
+	}
+	return c, true
+}
+
+// record records the outcome of a call that was allowed by the circuit.
+func (b *relayCircuitBreaker) record(c *circuit, outcome circuitOutcome) {
+	c.record(b.timeNow(), outcome, b.opts)
+}
+
+// introspectState returns the state of each circuit, keyed by "service::method".
+func (b *relayCircuitBreaker) introspectState() map[string]CircuitBreakerRuntimeState {
+	if b == nil {
+		return nil
+	}
+
+	now := b.timeNow()
+	states := make(map[string]CircuitBreakerRuntimeState)
+
+	b.RLock()
+	defer b.RUnlock()
+
+	for service, byMethod := range b.circuits {
+		for method, c := range byMethod {
+			c.Lock()
+			states[service+"::"+method] = CircuitBreakerRuntimeState{
+				State:    c.state.String(),
+				Requests: c.requests.count(now),
+				Failures: c.failures.count(now),
+				OpenedAt: c.openedAt,
+			}
+			c.Unlock()
+		}
+	}
+	return states
+}
+
+// circuitRelayCall wraps a RelayCall to report its outcome to the circuit
+// breaker when it ends.
+type circuitRelayCall struct {
+	RelayCallWrapper
+
+	breaker *relayCircuitBreaker
+	circuit *circuit
+
+	sync.Mutex
+	failed  bool
+	ignored bool
+}
+
+func (c *circuitRelayCall) Failed(reason string) {
+	c.Lock()
+	if _, ok := circuitIgnoredFailures[reason]; ok {
+		c.ignored = true
+	} else {
+		c.failed = true
+	}
+	c.Unlock()
+
+	c.RelayCall.Failed(reason)
+}
+
+func (c *circuitRelayCall) End() {
+	c.RelayCall.End()
+
+	c.Lock()
+	outcome := circuitSucceeded
+	if c.failed {
+		outcome = circuitFailed
+	} else if c.ignored {
+		outcome = circuitIgnored
+	}
+	c.Unlock()
+
+	c.breaker.record(c.circuit, outcome)
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import "github.com/uber/tchannel-go/relay"
+
+const _interceptedFailure = "relay-intercepted"
+
+// RelayInterceptor inspects calls received by the relay before they are
+// forwarded. Interceptors are run in the order they're registered in
+// ChannelOptions.RelayInterceptors, synchronously on the relay's forwarding
+// path, so implementations must be fast and must not block.
+//
+// This is an unstable API - breaking changes are likely.
+type RelayInterceptor interface {
+	// Intercept is called for each new call once the RelayHost has started
+	// it. The frame is only valid until Intercept returns, and reflects any
+	// header changes made by earlier interceptors. opts is shared by all
+	// interceptors for the call, and may be modified.
+	//
+	// If Intercept returns an error, the call is rejected: the error is sent
+	// to the caller (as a declined error if it isn't a SystemError), and
+	// later interceptors are not run. Otherwise, the returned
+	// RelayInterceptedCall, if any, is notified of the call's outcome.
+	Intercept(f relay.CallFrame, opts *RelayInterceptOptions) (RelayInterceptedCall, error)
+}
+
+// RelayInterceptOptions are the per-call settings that a RelayInterceptor
+// may change.
+type RelayInterceptOptions struct {
+	// LogFrames logs every frame forwarded by the relay for the call, in both
+	// directions. This allows debugging a single edge without enabling
+	// verbose logging for all calls.
+	LogFrames bool
+
+	// headerChanges are the changes made using SetHeader and DeleteHeader by
+	// the current interceptor, which are applied once it returns.
+	headerChanges []relayHeaderChange
+}
+
+// SetHeader sets a transport header on the call req frame before it's
+// forwarded, e.g. to inject a routing key for the next hop. The RelayHost has
+// already selected the destination, so it's not affected by the change.
+func (o *RelayInterceptOptions) SetHeader(key, value string) {
+	o.headerChanges = append(o.headerChanges, relayHeaderChange{key: key, value: value})
+}
+
+// DeleteHeader removes a transport header from the call req frame before it's
+// forwarded, if it's set.
+func (o *RelayInterceptOptions) DeleteHeader(key string) {
+	o.headerChanges = append(o.headerChanges, relayHeaderChange{key: key, delete: true})
+}
+
+// RelayInterceptedCall is notified of the outcome of a call accepted by a
+// RelayInterceptor, mirroring RelayCall.Succeeded and RelayCall.Failed.
+type RelayInterceptedCall interface {
+	// Succeeded is called if the call succeeded.
+	Succeeded()
+
+	// Failed is called with the failure reason if the call failed.
+	Failed(reason string)
+}
+
+// interceptCall runs the interceptors for a new call, applying any header
+// changes they make to the frame. It returns the call, wrapped to notify
+// interceptors of its outcome, along with the frame, the options set by the
+// interceptors, and the error if an interceptor rejected the call.
+func (r *Relayer) interceptCall(f lazyCallReq, call RelayCall) (RelayCall, lazyCallReq, RelayInterceptOptions, error) {
+	var (
+		opts        RelayInterceptOptions
+		intercepted []RelayInterceptedCall
+		err         error
+	)
+	for _, interceptor := range r.interceptors {
+		var ic RelayInterceptedCall
+		if ic, err = interceptor.Intercept(f, &opts); err != nil {
+			break
+		}
+		if ic != nil {
+			intercepted = append(intercepted, ic)
+		}
+		if len(opts.headerChanges) > 0 {
+			if err = rewriteCallReqHeaders(f.Frame, opts.headerChanges); err != nil {
+				err = NewWrappedSystemError(ErrCodeUnexpected, err)
+				break
+			}
+			opts.headerChanges = nil
+			f = newLazyCallReq(f.Frame)
+		}
+	}
+
+	if len(intercepted) > 0 {
+		call = &interceptedRelayCall{RelayCallWrapper: RelayCallWrapper{call}, intercepted: intercepted}
+	}
+	if err != nil {
+		if _, ok := err.(SystemError); !ok {
+			err = NewSystemError(ErrCodeDeclined, err.Error())
+		}
+	}
+	return call, f, opts, err
+}
+
+// logFrame logs a frame forwarded for a call that an interceptor tagged with
+// RelayInterceptOptions.LogFrames.
+func (r *Relayer) logFrame(f *Frame, fType frameType) {
+	direction := "request"
+	if fType == responseFrame {
+		direction = "response"
+	}
+	r.logger.WithFields(
+		LogField{"header", f.Header},
+		LogField{"direction", direction},
+		LogField{"payloadSize", f.Header.payloadSize()},
+	).Info("Relaying frame.")
+}
+
+// interceptedRelayCall wraps a RelayCall to notify interceptors of its
+// outcome.
+type interceptedRelayCall struct {
+	RelayCallWrapper
+
+	intercepted []RelayInterceptedCall
+}
+
+func (c *interceptedRelayCall) Succeeded() {
+	c.RelayCall.Succeeded()
+	for _, ic := range c.intercepted {
+		ic.Succeeded()
+	}
+}
+
+func (c *interceptedRelayCall) Failed(reason string) {
+	c.RelayCall.Failed(reason)
+	for _, ic := range c.intercepted {
+		ic.Failed(reason)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"time"
 )
 
@@ -31,6 +32,8 @@ var (
 	_callerNameKeyBytes      = []byte(CallerName)
 	_routingDelegateKeyBytes = []byte(RoutingDelegate)
 	_routingKeyKeyBytes      = []byte(RoutingKey)
+	_shardKeyKeyBytes        = []byte(ShardKey)
+	_priorityKeyBytes        = []byte(Priority)
 )
 
 const (
@@ -87,7 +90,7 @@ func (cr lazyCallRes) OK() bool {
 type lazyCallReq struct {
 	*Frame
 
-	caller, method, delegate, key []byte
+	caller, method, delegate, key, shardKey, priority []byte
 }
 
 // TODO: Consider pooling lazyCallReq and using pointers to the struct.
@@ -121,6 +124,10 @@ func newLazyCallReq(f *Frame) lazyCallReq {
 			cr.delegate = val
 		} else if bytes.Equal(key, _routingKeyKeyBytes) {
 			cr.key = val
+		} else if bytes.Equal(key, _shardKeyKeyBytes) {
+			cr.shardKey = val
+		} else if bytes.Equal(key, _priorityKeyBytes) {
+			cr.priority = val
 		}
 	}
 
@@ -161,6 +168,16 @@ func (f lazyCallReq) RoutingKey() []byte {
 	return f.key
 }
 
+// ShardKey returns the shard key for this call req, if any.
+func (f lazyCallReq) ShardKey() []byte {
+	return f.shardKey
+}
+
+// Priority returns the priority of this callReq from the Priority header.
+func (f lazyCallReq) Priority() CallPriority {
+	return parsePriority(string(f.priority))
+}
+
 // TTL returns the time to live for this callReq.
 func (f lazyCallReq) TTL() time.Duration {
 	ttl := binary.BigEndian.Uint32(f.Payload[_ttlIndex : _ttlIndex+_ttlLen])
@@ -183,6 +200,177 @@ func (f lazyCallReq) HasMoreFragments() bool {
 	return f.Payload[_flagsIndex]&hasMoreFragmentsFlag != 0
 }
 
+// errMalformedCallFrame is returned when a call frame is shorter than the
+// lengths it contains require.
+var errMalformedCallFrame = NewSystemError(ErrCodeBadRequest, "malformed call frame")
+
+// frameArgsSize returns the size of the args in a call frame, excluding the
+// call's headers and checksum. It returns 0 for frames that don't carry args.
+func frameArgsSize(f *Frame) (int, error) {
+	payload := f.SizedPayload()
+
+	// flags:1
+	cur := 1
+	var err error
+	switch f.messageType() {
+	case messageTypeCallReq:
+		// ttl:4 tracing:25 service~1
+		cur += _ttlLen + _spanLength
+		if cur >= len(payload) {
+			return 0, errMalformedCallFrame
+		}
+		cur += 1 + int(payload[cur])
+		cur, err = skipTransportHeaders(payload, cur)
+	case messageTypeCallRes:
+		// code:1 tracing:25
+		cur += 1 + _spanLength
+		cur, err = skipTransportHeaders(payload, cur)
+	case messageTypeCallReqContinue, messageTypeCallResContinue:
+	default:
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// csumtype:1 (csum:4){0,1}
+	if cur >= len(payload) {
+		return 0, errMalformedCallFrame
+	}
+	cur += 1 + ChecksumType(payload[cur]).ChecksumSize()
+	if cur > len(payload) {
+		return 0, errMalformedCallFrame
+	}
+	return len(payload) - cur, nil
+}
+
+// skipTransportHeaders returns the offset after the transport headers
+// nh:1 (hk~1 hv~1){nh} that start at cur.
+func skipTransportHeaders(payload []byte, cur int) (int, error) {
+	if cur >= len(payload) {
+		return 0, errMalformedCallFrame
+	}
+	numHeaders := int(payload[cur])
+	cur++
+	for i := 0; i < 2*numHeaders; i++ {
+		if cur >= len(payload) {
+			return 0, errMalformedCallFrame
+		}
+		cur += 1 + int(payload[cur])
+	}
+	if cur > len(payload) {
+		return 0, errMalformedCallFrame
+	}
+	return cur, nil
+}
+
+// callReqHeaderStart returns the offset of the transport headers in a call
+// req payload, which follow the service name.
+func callReqHeaderStart(payload []byte) (int, error) {
+	if _serviceLenIndex >= len(payload) {
+		return 0, errMalformedCallFrame
+	}
+	return _serviceNameIndex + int(payload[_serviceLenIndex]), nil
+}
+
+// readTransportHeaders returns the transport headers nh:1 (hk~1 hv~1){nh}
+// that start at cur, in the order they're in the payload.
+func readTransportHeaders(payload []byte, cur int) ([][2]string, error) {
+	end, err := skipTransportHeaders(payload, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	// skipTransportHeaders checked that the headers fit in the payload.
+	numHeaders := int(payload[cur])
+	cur++
+	headers := make([][2]string, 0, numHeaders)
+	for cur < end {
+		keyLen := int(payload[cur])
+		key := string(payload[cur+1 : cur+1+keyLen])
+		cur += 1 + keyLen
+		valLen := int(payload[cur])
+		val := string(payload[cur+1 : cur+1+valLen])
+		cur += 1 + valLen
+		headers = append(headers, [2]string{key, val})
+	}
+	return headers, nil
+}
+
+// relayHeaderChange is a change to a transport header of a call req frame.
+type relayHeaderChange struct {
+	key, value string
+	delete     bool
+}
+
+// rewriteCallReqHeaders applies the changes, in order, to the transport
+// headers of a call req frame, moving the rest of the payload to fit the new
+// headers. The frame is not modified if an error is returned.
+func rewriteCallReqHeaders(f *Frame, changes []relayHeaderChange) error {
+	payload := f.SizedPayload()
+	headerStart, err := callReqHeaderStart(payload)
+	if err != nil {
+		return err
+	}
+	headerEnd, err := skipTransportHeaders(payload, headerStart)
+	if err != nil {
+		return err
+	}
+
+	headers, err := readTransportHeaders(payload, headerStart)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		if len(c.key) > math.MaxUint8 || len(c.value) > math.MaxUint8 {
+			return fmt.Errorf("transport header %q is too long", c.key)
+		}
+
+		found := -1
+		for i, h := range headers {
+			if h[0] == c.key {
+				found = i
+				break
+			}
+		}
+		switch {
+		case c.delete && found >= 0:
+			headers = append(headers[:found], headers[found+1:]...)
+		case !c.delete && found >= 0:
+			headers[found][1] = c.value
+		case !c.delete:
+			headers = append(headers, [2]string{c.key, c.value})
+		}
+	}
+	if len(headers) > math.MaxUint8 {
+		return fmt.Errorf("too many transport headers: %v", len(headers))
+	}
+
+	newHeadersLen := 1
+	for _, h := range headers {
+		newHeadersLen += 2 + len(h[0]) + len(h[1])
+	}
+	newSize := len(payload) - (headerEnd - headerStart) + newHeadersLen
+	if newSize > len(f.Payload) {
+		return fmt.Errorf("call req frame size %v exceeds the maximum payload size", newSize)
+	}
+
+	// Move the rest of the payload in place before writing the headers, since
+	// the new headers may overlap it. headers doesn't refer to the payload.
+	copy(f.Payload[headerStart+newHeadersLen:], payload[headerEnd:])
+	cur := headerStart
+	f.Payload[cur] = byte(len(headers))
+	cur++
+	for _, h := range headers {
+		for _, v := range h {
+			f.Payload[cur] = byte(len(v))
+			cur += 1 + copy(f.Payload[cur+1:], v)
+		}
+	}
+	f.Header.setPayloadSize(newSize)
+	return nil
+}
+
 // finishesCall checks whether this frame is the last one we should expect for
 // this RPC req-res.
 func finishesCall(f *Frame) bool {
@@ -21,12 +21,14 @@
 package tchannel
 
 import (
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/uber/tchannel-go/typed"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type testCallReq int
@@ -37,6 +39,8 @@ const (
 	reqHasDelegate
 	reqHasRoutingKey
 	reqHasChecksum
+	reqHasPriority
+	reqHasShardKey
 	reqTotalCombinations
 	reqHasAll testCallReq = reqTotalCombinations - 1
 )
@@ -69,6 +73,12 @@ func (cr testCallReq) req() lazyCallReq {
 	if cr&reqHasRoutingKey != 0 {
 		headers["rk"] = "fake-routingkey"
 	}
+	if cr&reqHasPriority != 0 {
+		headers["pri"] = "high"
+	}
+	if cr&reqHasShardKey != 0 {
+		headers["sk"] = "fake-shardkey"
+	}
 	writeHeaders(payload, headers)
 
 	if cr&reqHasChecksum == 0 {
@@ -250,6 +260,28 @@ func TestLazyCallReqRoutingKey(t *testing.T) {
 	})
 }
 
+func TestLazyCallReqShardKey(t *testing.T) {
+	withLazyCallReqCombinations(func(crt testCallReq) {
+		cr := crt.req()
+		if crt&reqHasShardKey == 0 {
+			assert.Equal(t, []byte(nil), cr.ShardKey(), "Unexpected shard key.")
+		} else {
+			assert.Equal(t, "fake-shardkey", string(cr.ShardKey()), "Shard key mismatch.")
+		}
+	})
+}
+
+func TestLazyCallReqPriority(t *testing.T) {
+	withLazyCallReqCombinations(func(crt testCallReq) {
+		cr := crt.req()
+		if crt&reqHasPriority == 0 {
+			assert.Equal(t, PriorityNormal, cr.Priority(), "Unexpected priority.")
+		} else {
+			assert.Equal(t, PriorityHigh, cr.Priority(), "Priority mismatch.")
+		}
+	})
+}
+
 func TestLazyCallReqMethod(t *testing.T) {
 	withLazyCallReqCombinations(func(crt testCallReq) {
 		cr := crt.req()
@@ -272,6 +304,95 @@ func TestLazyCallReqSetTTL(t *testing.T) {
 	})
 }
 
+func TestRewriteCallReqHeaders(t *testing.T) {
+	withLazyCallReqCombinations(func(crt testCallReq) {
+		cr := crt.req()
+		// The test frames don't set the payload size, so use the end of arg1.
+		cr.Header.SetPayloadSize(uint16(cap(cr.Payload) - cap(cr.method) + len(cr.method)))
+
+		err := rewriteCallReqHeaders(cr.Frame, []relayHeaderChange{
+			{key: "rk", value: "new-routingkey"},
+			{key: "cn", delete: true},
+			{key: "new", value: "header"},
+		})
+		assert.NoError(t, err, "rewriteCallReqHeaders failed")
+
+		cr = newLazyCallReq(cr.Frame)
+		assert.Equal(t, "new-routingkey", string(cr.RoutingKey()), "Routing key mismatch")
+		assert.Equal(t, []byte(nil), cr.Caller(), "Caller should be deleted")
+		if crt&reqHasDelegate != 0 {
+			assert.Equal(t, "fake-delegate", string(cr.RoutingDelegate()), "Routing delegate should be unchanged")
+		}
+		assert.Equal(t, "bankmoji", string(cr.Service()), "Service mismatch")
+		assert.Equal(t, "moneys", string(cr.Method()), "Method mismatch")
+		assert.Equal(t, 42*time.Millisecond, cr.TTL(), "TTL mismatch")
+	})
+}
+
+func TestRewriteCallReqHeadersMovesArgs(t *testing.T) {
+	tests := []struct {
+		msg     string
+		changes []relayHeaderChange
+	}{
+		{
+			msg:     "headers shrink",
+			changes: []relayHeaderChange{{key: "cn", delete: true}, {key: "rk", delete: true}},
+		},
+		{
+			msg:     "headers grow",
+			changes: []relayHeaderChange{{key: "new", value: strings.Repeat("x", 16)}},
+		},
+	}
+
+	for _, tt := range tests {
+		cr := reqHasAll.req()
+		cr.Header.SetPayloadSize(uint16(cap(cr.Payload) - cap(cr.method) + len(cr.method)))
+		payload := &cr.Payload[0]
+
+		require.NoError(t, rewriteCallReqHeaders(cr.Frame, tt.changes), "%v: rewriteCallReqHeaders failed", tt.msg)
+		cr = newLazyCallReq(cr.Frame)
+		assert.True(t, payload == &cr.Payload[0], "%v: frame should be rewritten in place", tt.msg)
+		assert.Equal(t, "moneys", string(cr.Method()), "%v: method mismatch", tt.msg)
+		assert.Equal(t, "bankmoji", string(cr.Service()), "%v: service mismatch", tt.msg)
+	}
+}
+
+func TestRewriteCallReqHeadersErrors(t *testing.T) {
+	cr := reqHasAll.req()
+	size := uint16(cap(cr.Payload) - cap(cr.method) + len(cr.method))
+	cr.Header.SetPayloadSize(size)
+
+	err := rewriteCallReqHeaders(cr.Frame, []relayHeaderChange{{key: "k", value: string(make([]byte, 256))}})
+	assert.Error(t, err, "Expected header value that's too long to fail")
+
+	err = rewriteCallReqHeaders(cr.Frame, []relayHeaderChange{{key: "k", value: string(make([]byte, 200))}})
+	assert.Error(t, err, "Expected headers that don't fit in the frame to fail")
+	assert.Equal(t, size, cr.Header.PayloadSize(), "Frame should not be modified on error")
+}
+
+func TestFrameArgsSizeTruncated(t *testing.T) {
+	cr := reqHasAll.req()
+	size := cap(cr.Payload) - cap(cr.method) + len(cr.method)
+	cr.Header.SetPayloadSize(uint16(size))
+
+	argsSize, err := frameArgsSize(cr.Frame)
+	require.NoError(t, err, "frameArgsSize failed")
+	argsStart := size - argsSize
+
+	// Frames that end before the args start are too short for the lengths
+	// they contain, and must fail rather than read past the payload.
+	for n := 0; n < size; n++ {
+		cr.Header.SetPayloadSize(uint16(n))
+		argsSize, err := frameArgsSize(cr.Frame)
+		if n < argsStart {
+			assert.Equal(t, errMalformedCallFrame, err, "Expected truncated frame of %v bytes to fail", n)
+		} else {
+			assert.NoError(t, err, "Unexpected error for frame of %v bytes", n)
+			assert.Equal(t, n-argsStart, argsSize, "Unexpected args size for frame of %v bytes", n)
+		}
+	}
+}
+
 func TestLazyCallResRejectsOtherFrames(t *testing.T) {
 	assertWrappingPanics(
 		t,
@@ -0,0 +1,214 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/tchannel-go/relay"
+)
+
+// RelayCallObserver is notified of the lifecycle of calls forwarded by the
+// relay. Methods are invoked synchronously on the relay's forwarding path, so
+// implementations must be fast and must not block; any expensive processing
+// should be handed off to another goroutine.
+//
+// The CallFrame passed to the observer is a copy, and is safe to retain.
+// This is an unstable API - breaking changes are likely.
+type RelayCallObserver interface {
+	// CallBegin is called when the relay receives a new call, before the
+	// RelayHost is asked to start it.
+	CallBegin(f relay.CallFrame)
+
+	// PeerSelected is called once the RelayHost has selected a destination peer.
+	PeerSelected(f relay.CallFrame, peer *Peer)
+
+	// ResponseReceived is called when the first response frame (or an error
+	// frame) for the call is received from the destination.
+	ResponseReceived(f relay.CallFrame, peer *Peer)
+
+	// CallEnd is called exactly once for every call passed to CallBegin.
+	// peer is nil if no destination was selected, and failure is the reason
+	// passed to RelayCall.Failed, or empty if the call did not fail.
+	CallEnd(f relay.CallFrame, peer *Peer, failure string)
+}
+
+// copiedCallFrame is a relay.CallFrame that owns its fields, since the
+// underlying frame is reused once it has been forwarded.
+type copiedCallFrame struct {
+	caller          []byte
+	service         []byte
+	method          []byte
+	routingDelegate []byte
+	routingKey      []byte
+	shardKey        []byte
+}
+
+func copyBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
+
+func copyCallFrame(f relay.CallFrame) *copiedCallFrame {
+	return &copiedCallFrame{
+		caller:          copyBytes(f.Caller()),
+		service:         copyBytes(f.Service()),
+		method:          copyBytes(f.Method()),
+		routingDelegate: copyBytes(f.RoutingDelegate()),
+		routingKey:      copyBytes(f.RoutingKey()),
+		shardKey:        copyBytes(f.ShardKey()),
+	}
+}
+
+func (f *copiedCallFrame) Caller() []byte          { return f.caller }
+func (f *copiedCallFrame) Service() []byte         { return f.service }
+func (f *copiedCallFrame) Method() []byte          { return f.method }
+func (f *copiedCallFrame) RoutingDelegate() []byte { return f.routingDelegate }
+func (f *copiedCallFrame) RoutingKey() []byte      { return f.routingKey }
+func (f *copiedCallFrame) ShardKey() []byte        { return f.shardKey }
+
+// observedRelayCall wraps a RelayCall to notify a RelayCallObserver. The
+// wrapped call may be nil if the RelayHost did not return a call.
+type observedRelayCall struct {
+	call     RelayCall
+	observer RelayCallObserver
+	frame    *copiedCallFrame
+
+	sync.Mutex
+	peer         *Peer
+	failure      string
+	gotResponse  bool
+	peerNotified bool
+}
+
+func newObservedRelayCall(call RelayCall, observer RelayCallObserver, frame *copiedCallFrame) *observedRelayCall {
+	return &observedRelayCall{
+		call:     call,
+		observer: observer,
+		frame:    frame,
+	}
+}
+
+func (c *observedRelayCall) Destination() (*Peer, bool) {
+	if c.call == nil {
+		return nil, false
+	}
+
+	peer, ok := c.call.Destination()
+	if !ok {
+		return peer, ok
+	}
+
+	c.Lock()
+	notify := !c.peerNotified
+	c.peerNotified = true
+	c.peer = peer
+	c.Unlock()
+
+	if notify {
+		c.observer.PeerSelected(c.frame, peer)
+	}
+	return peer, ok
+}
+
+func (c *observedRelayCall) RetryDestination(failed *Peer) (*Peer, bool) {
+	retryable, ok := c.call.(RelayRetryableCall)
+	if !ok {
+		return nil, false
+	}
+
+	peer, ok := retryable.RetryDestination(failed)
+	if !ok {
+		return peer, ok
+	}
+
+	c.Lock()
+	c.peer = peer
+	c.Unlock()
+
+	c.observer.PeerSelected(c.frame, peer)
+	return peer, ok
+}
+
+func (c *observedRelayCall) RetriedTo(peer *Peer) {
+	if retryable, ok := c.call.(RelayRetryableCall); ok {
+		retryable.RetriedTo(peer)
+	}
+}
+
+func (c *observedRelayCall) SetFrameCount(n int, bytes int64) {
+	if counting, ok := c.call.(RelayFrameCountingCall); ok {
+		counting.SetFrameCount(n, bytes)
+	}
+}
+
+func (c *observedRelayCall) SetFrameStats(request, response RelayFrameStats) {
+	if stats, ok := c.call.(RelayFrameStatsCall); ok {
+		stats.SetFrameStats(request, response)
+	}
+}
+
+func (c *observedRelayCall) Succeeded() {
+	if c.call != nil {
+		c.call.Succeeded()
+	}
+}
+
+func (c *observedRelayCall) Failed(reason string) {
+	c.Lock()
+	c.failure = reason
+	c.Unlock()
+
+	if c.call != nil {
+		c.call.Failed(reason)
+	}
+}
+
+func (c *observedRelayCall) SetDuration(d time.Duration) {
+	if c.call != nil {
+		c.call.SetDuration(d)
+	}
+}
+
+func (c *observedRelayCall) End() {
+	if c.call != nil {
+		c.call.End()
+	}
+
+	c.Lock()
+	peer, failure := c.peer, c.failure
+	c.Unlock()
+	c.observer.CallEnd(c.frame, peer, failure)
+}
+
+// responseReceived is called when the relay sees the response frame that
+// determines the result of the call.
+func (c *observedRelayCall) responseReceived() {
+	c.Lock()
+	notify := !c.gotResponse
+	c.gotResponse = true
+	peer := c.peer
+	c.Unlock()
+
+	if notify {
+		c.observer.ResponseReceived(c.frame, peer)
+	}
+}
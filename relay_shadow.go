@@ -0,0 +1,232 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/uber/tchannel-go/relay"
+	"github.com/uber/tchannel-go/trand"
+
+	"github.com/uber-go/atomic"
+)
+
+// _relayShadowConnectTimeout is the timeout for connecting to a shadow
+// destination in the background.
+const _relayShadowConnectTimeout = time.Second
+
+// RelayShadowOptions configure the relay to mirror a sample of relayed calls
+// to a secondary destination, for dark-launch testing. Mirrored calls are
+// forwarded unmodified, including all of their fragments, and their responses
+// are discarded, so they never affect the caller or the RelayCall stats of the
+// relayed call. Mirrored calls are instead reported to the StatsReporter as
+// relay.shadow.calls, with the result of each in relay.shadow.responses.
+type RelayShadowOptions struct {
+	// Destination returns the host:port that a call should be mirrored to,
+	// or false if the call should not be mirrored. If this is nil (the
+	// default), no calls are mirrored.
+	Destination func(f relay.CallFrame) (hostPort string, ok bool)
+
+	// Percentage is the percentage, from 0 to 100, of calls with a shadow
+	// destination that are mirrored.
+	Percentage float64
+}
+
+// relayShadow samples relayed calls to mirror, and manages connections to
+// shadow destinations.
+type relayShadow struct {
+	destination func(f relay.CallFrame) (string, bool)
+	percentage  float64
+	rng         *rand.Rand
+
+	mut        sync.Mutex
+	connecting map[string]struct{}
+}
+
+func newRelayShadow(opts RelayShadowOptions) *relayShadow {
+	if opts.Destination == nil || opts.Percentage <= 0 {
+		return nil
+	}
+	return &relayShadow{
+		destination: opts.Destination,
+		percentage:  opts.Percentage,
+		rng:         trand.NewSeeded(),
+		connecting:  make(map[string]struct{}),
+	}
+}
+
+// sample returns the shadow destination for the call if it should be mirrored.
+func (s *relayShadow) sample(f relay.CallFrame) (string, bool) {
+	hostPort, ok := s.destination(f)
+	if !ok || hostPort == "" {
+		return "", false
+	}
+	if s.percentage < 100 && s.rng.Float64()*100 >= s.percentage {
+		return "", false
+	}
+	return hostPort, true
+}
+
+// getConn returns an active connection to the shadow destination. Mirrored
+// calls must not delay the relayed call, so if there's no active connection,
+// a connection is started in the background and the call is not mirrored.
+func (s *relayShadow) getConn(peers *RootPeerList, hostPort string) (*Connection, bool) {
+	peer := peers.GetOrAdd(hostPort)
+	if conn, ok := peer.getActiveConn(); ok {
+		return conn, true
+	}
+
+	s.mut.Lock()
+	_, connecting := s.connecting[hostPort]
+	s.connecting[hostPort] = struct{}{}
+	s.mut.Unlock()
+	if connecting {
+		return nil, false
+	}
+
+	go func() {
+		peer.getConnectionRelay(_relayShadowConnectTimeout, 1)
+		s.mut.Lock()
+		delete(s.connecting, hostPort)
+		s.mut.Unlock()
+	}()
+	return nil, false
+}
+
+// relayShadowCall is a call mirrored to a shadow destination. It's shared by
+// the originator's relay item, which copies request frames to the shadow
+// destination, and the shadow destination's relay item, which discards the
+// responses.
+type relayShadowCall struct {
+	relayer       *Relayer
+	id            uint32
+	failed        atomic.Bool
+	statsReporter StatsReporter
+	tags          map[string]string
+}
+
+// startShadowCall mirrors the call to a shadow destination if it's sampled,
+// and returns the mirrored call, or nil if the call isn't mirrored.
+func (r *Relayer) startShadowCall(f lazyCallReq, ttl time.Duration) *relayShadowCall {
+	if r.shadow == nil {
+		return nil
+	}
+	hostPort, ok := r.shadow.sample(f)
+	if !ok {
+		return nil
+	}
+
+	tags := map[string]string{
+		"source":      string(f.Caller()),
+		"dest":        string(f.Service()),
+		"method":      string(f.Method()),
+		"shadow-host": hostPort,
+	}
+	for k, v := range r.conn.commonStatsTags {
+		tags[k] = v
+	}
+	shadow := &relayShadowCall{
+		statsReporter: r.conn.statsReporter,
+		tags:          tags,
+	}
+
+	shadowConn, ok := r.shadow.getConn(r.peers, hostPort)
+	if !ok {
+		shadow.skipped("relay-shadow-not-connected")
+		return nil
+	}
+	if canHandle, _ := shadowConn.relay.canHandleNewCall(); !canHandle {
+		shadow.skipped("relay-shadow-conn-inactive")
+		return nil
+	}
+
+	shadow.relayer = shadowConn.relay
+	shadow.id = shadowConn.NextMessageID()
+	shadowConn.relay.addRelayItem(false /* isOriginator */, shadow.id, 0, nil, ttl, f.Span(), nil, time.Time{}, false, f.Priority(), nil, shadow)
+	shadow.statsReporter.IncCounter("relay.shadow.calls", tags, 1)
+	shadow.send(r.copyFrame(f.Frame))
+	return shadow
+}
+
+// copyFrame copies a frame into a frame from the pool, so it can be sent to
+// the shadow destination independently of the original.
+func (r *Relayer) copyFrame(f *Frame) *Frame {
+	frame := r.conn.opts.FramePool.Get()
+	frame.Header = f.Header
+	copy(frame.Payload, f.SizedPayload())
+	return frame
+}
+
+// send sends a copy of a request frame to the shadow destination. Once a frame
+// fails to send, the shadow destination can't complete the call, so the
+// remaining frames are dropped.
+func (s *relayShadowCall) send(f *Frame) {
+	if s.failed.Load() {
+		s.relayer.conn.opts.FramePool.Release(f)
+		return
+	}
+
+	f.Header.ID = s.id
+	if sent, failure := s.relayer.Receive(f, requestFrame); !sent {
+		s.failed.Store(true)
+		s.relayer.conn.opts.FramePool.Release(f)
+		s.finished(failure)
+	}
+}
+
+// skipped records a sampled call that could not be mirrored.
+func (s *relayShadowCall) skipped(reason string) {
+	s.statsReporter.IncCounter("relay.shadow.skipped", s.withTag("reason", reason), 1)
+}
+
+// finished records the result of a mirrored call.
+func (s *relayShadowCall) finished(result string) {
+	s.statsReporter.IncCounter("relay.shadow.responses", s.withTag("result", result), 1)
+}
+
+func (s *relayShadowCall) withTag(key, value string) map[string]string {
+	tags := make(map[string]string, len(s.tags)+1)
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	tags[key] = value
+	return tags
+}
+
+// discardShadowResponse handles a response frame from a shadow destination,
+// which is never forwarded. The result of the call is recorded from the frame
+// that determines it, and the relay item is finished on the last frame.
+func (r *Relayer) discardShadowResponse(items *relayItems, item relayItem, f *Frame) {
+	id := f.Header.ID
+	finished := finishesCall(f)
+	if succeeded, failMsg := determinesCallSuccess(f); succeeded {
+		item.shadow.finished("success")
+	} else if len(failMsg) > 0 {
+		item.shadow.finished(failMsg)
+	}
+	r.conn.opts.FramePool.Release(f)
+
+	if finished && item.timeout.Stop() {
+		r.finishRelayItem(items, id)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import "github.com/uber/tchannel-go/relay"
+
+// statsRelayCall wraps a RelayCall to report its outcome to relay.CallStats.
+type statsRelayCall struct {
+	RelayCallWrapper
+
+	stats relay.CallStats
+}
+
+func (c *statsRelayCall) Succeeded() {
+	c.RelayCall.Succeeded()
+	c.stats.Succeeded()
+}
+
+func (c *statsRelayCall) Failed(reason string) {
+	c.RelayCall.Failed(reason)
+	c.stats.Failed(reason)
+}
+
+func (c *statsRelayCall) End() {
+	c.RelayCall.End()
+	c.stats.End()
+}
@@ -23,6 +23,7 @@ package tchannel_test
 import (
 	"errors"
 	"io"
+	"net"
 	"runtime"
 	"strings"
 	"sync"
@@ -138,6 +139,76 @@ func TestRelayConnectionCloseDrainsRelayItems(t *testing.T) {
 	})
 }
 
+func TestRelayCallDuration(t *testing.T) {
+	const sleep = 20 * time.Millisecond
+
+	opts := serviceNameOpts("test").SetRelayOnly()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterFunc(ts.Server(), "sleep", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			time.Sleep(sleep)
+			return &raw.Res{}, nil
+		})
+
+		client := ts.NewClient(serviceNameOpts("client"))
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), "test", "sleep", nil, nil)
+		require.NoError(t, err, "Relayed call failed.")
+
+		calls := relaytest.NewMockStats()
+		calls.Add("client", "test", "sleep").Succeeded().DurationBetween(sleep, testutils.Timeout(time.Second)).End()
+		ts.AssertRelayStats(calls)
+	})
+}
+
+func TestRelayFrameCount(t *testing.T) {
+	tests := []struct {
+		msg        string
+		arg3Size   int
+		wantFrames int
+	}{
+		{
+			msg:        "single frame in each direction",
+			arg3Size:   128,
+			wantFrames: 2,
+		},
+		{
+			msg:        "fragmented in each direction",
+			arg3Size:   3 * MaxFramePayloadSize,
+			wantFrames: 8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			opts := serviceNameOpts("test").SetRelayOnly()
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				testutils.RegisterEcho(ts.Server(), nil)
+
+				client := ts.NewClient(serviceNameOpts("client"))
+				err := testutils.CallEcho(client, ts.HostPort(), "test", &raw.Args{
+					Arg3: testutils.RandBytes(tt.arg3Size),
+				})
+				require.NoError(t, err, "Relayed call failed.")
+
+				// The frames in both directions carry arg3, along with headers
+				// and other fields that are much smaller than a frame.
+				minBytes := int64(2 * tt.arg3Size)
+				maxBytes := minBytes + int64(tt.wantFrames*FrameHeaderSize) + 1024
+
+				calls := relaytest.NewMockStats()
+				calls.Add("client", "test", "echo").Succeeded().
+					FrameCount(tt.wantFrames).
+					RequestResponseFrames(tt.wantFrames/2, tt.wantFrames/2).
+					FrameBytesBetween(minBytes, maxBytes).
+					End()
+				ts.AssertRelayStats(calls)
+			})
+		})
+	}
+}
+
 func TestRelayIDClash(t *testing.T) {
 	opts := serviceNameOpts("s1").SetRelayOnly()
 	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
@@ -375,6 +446,737 @@ func TestLargeTimeoutsAreClamped(t *testing.T) {
 	})
 }
 
+func TestRelayAdjustTTL(t *testing.T) {
+	const (
+		callTTL = time.Second
+		hopCost = 300 * time.Millisecond
+	)
+
+	tests := []struct {
+		msg     string
+		adjust  func(relay.CallFrame, time.Duration) time.Duration
+		wantMax time.Duration
+		wantMin time.Duration
+		wantErr bool
+	}{
+		{
+			msg: "reduce by hop cost",
+			adjust: func(f relay.CallFrame, ttl time.Duration) time.Duration {
+				assert.Equal(t, "echo-service", string(f.Service()), "Unexpected service")
+				return ttl - hopCost
+			},
+			wantMax: callTTL - hopCost,
+			wantMin: callTTL - 2*hopCost,
+		},
+		{
+			msg: "no TTL remaining",
+			adjust: func(_ relay.CallFrame, ttl time.Duration) time.Duration {
+				return ttl - 2*callTTL
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			opts := serviceNameOpts("echo-service").
+				SetRelayOnly().
+				SetRelayAdjustTTL(tt.adjust)
+
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				var gotTTL time.Duration
+				testutils.RegisterFunc(ts.Server(), "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+					deadline, ok := ctx.Deadline()
+					assert.True(t, ok, "Expected deadline to be set in handler")
+					gotTTL = deadline.Sub(time.Now())
+					return &raw.Res{Arg2: args.Arg2, Arg3: args.Arg3}, nil
+				})
+
+				ctx, cancel := NewContext(callTTL)
+				defer cancel()
+
+				_, _, _, err := raw.Call(ctx, ts.NewClient(nil), ts.HostPort(), "echo-service", "echo", nil, nil)
+				if tt.wantErr {
+					require.Error(t, err, "Expected call to fail")
+					assert.Equal(t, ErrCodeTimeout, GetSystemErrorCode(err), "Unexpected error code")
+					return
+				}
+
+				require.NoError(t, err, "Call failed")
+				assert.True(t, gotTTL <= tt.wantMax, "Forwarded TTL %v should be at most %v", gotTTL, tt.wantMax)
+				assert.True(t, gotTTL > tt.wantMin, "Forwarded TTL %v should be more than %v", gotTTL, tt.wantMin)
+			})
+		})
+	}
+}
+
+func TestRelayTimeoutPolicy(t *testing.T) {
+	tests := []struct {
+		msg     string
+		callTTL time.Duration
+		policy  RelayTimeoutPolicy
+		wantMax time.Duration
+		wantMin time.Duration
+		wantErr bool
+	}{
+		{
+			msg:     "clamp to service max",
+			callTTL: 10 * time.Second,
+			policy: RelayTimeoutPolicy{
+				Default:  RelayTimeoutRange{Max: 5 * time.Second},
+				Services: map[string]RelayTimeoutRange{"echo-service": {Max: time.Second}},
+			},
+			wantMax: time.Second,
+			wantMin: 500 * time.Millisecond,
+		},
+		{
+			msg:     "clamp to default max",
+			callTTL: 10 * time.Second,
+			policy: RelayTimeoutPolicy{
+				Default:  RelayTimeoutRange{Max: time.Second},
+				Services: map[string]RelayTimeoutRange{"other-service": {Max: 5 * time.Second}},
+			},
+			wantMax: time.Second,
+			wantMin: 500 * time.Millisecond,
+		},
+		{
+			msg:     "raise to service min",
+			callTTL: 100 * time.Millisecond,
+			policy: RelayTimeoutPolicy{
+				Services: map[string]RelayTimeoutRange{"echo-service": {Min: time.Second}},
+			},
+			wantMax: time.Second,
+			wantMin: 500 * time.Millisecond,
+		},
+		{
+			msg:     "reduce by overhead",
+			callTTL: time.Second,
+			policy:  RelayTimeoutPolicy{Overhead: 300 * time.Millisecond},
+			wantMax: 700 * time.Millisecond,
+			wantMin: 400 * time.Millisecond,
+		},
+		{
+			msg:     "overhead exceeds TTL",
+			callTTL: time.Second,
+			policy: RelayTimeoutPolicy{
+				Overhead: 2 * time.Second,
+				Default:  RelayTimeoutRange{Min: time.Second},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			opts := serviceNameOpts("echo-service").
+				SetRelayOnly().
+				SetRelayTimeoutPolicy(&tt.policy)
+
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				var gotTTL time.Duration
+				testutils.RegisterFunc(ts.Server(), "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+					deadline, ok := ctx.Deadline()
+					assert.True(t, ok, "Expected deadline to be set in handler")
+					gotTTL = deadline.Sub(time.Now())
+					return &raw.Res{Arg2: args.Arg2, Arg3: args.Arg3}, nil
+				})
+
+				ctx, cancel := NewContext(tt.callTTL)
+				defer cancel()
+
+				_, _, _, err := raw.Call(ctx, ts.NewClient(nil), ts.HostPort(), "echo-service", "echo", nil, nil)
+				if tt.wantErr {
+					require.Error(t, err, "Expected call to fail")
+					assert.Equal(t, ErrCodeTimeout, GetSystemErrorCode(err), "Unexpected error code")
+					return
+				}
+
+				require.NoError(t, err, "Call failed")
+				assert.True(t, gotTTL <= tt.wantMax, "Forwarded TTL %v should be at most %v", gotTTL, tt.wantMax)
+				assert.True(t, gotTTL > tt.wantMin, "Forwarded TTL %v should be more than %v", gotTTL, tt.wantMin)
+			})
+		})
+	}
+}
+
+func TestRelayForwardsRemainingTTL(t *testing.T) {
+	const (
+		hopDelay      = 300 * time.Millisecond
+		minTimeToLive = 200 * time.Millisecond
+	)
+
+	tests := []struct {
+		msg     string
+		callTTL time.Duration
+		wantErr bool
+	}{
+		{
+			msg:     "TTL reduced by slow hop",
+			callTTL: time.Second,
+		},
+		{
+			msg:     "remaining TTL below minimum",
+			callTTL: hopDelay + minTimeToLive/2,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			var serverHostPort string
+			slowHost := func(_ relay.CallFrame, _ *relay.Conn) (string, error) {
+				time.Sleep(hopDelay)
+				return serverHostPort, nil
+			}
+
+			opts := testutils.NewOpts().
+				SetRelayOnly().
+				SetRelayHost(relaytest.HostFunc(slowHost))
+			opts.DefaultConnectionOptions.MinTimeToLive = minTimeToLive
+
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				server := ts.NewServer(serviceNameOpts("svc"))
+				serverHostPort = server.PeerInfo().HostPort
+
+				var gotTTL time.Duration
+				testutils.RegisterFunc(server, "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+					deadline, ok := ctx.Deadline()
+					assert.True(t, ok, "Expected deadline to be set in handler")
+					gotTTL = deadline.Sub(time.Now())
+					return &raw.Res{Arg2: args.Arg2, Arg3: args.Arg3}, nil
+				})
+
+				ctx, cancel := NewContext(tt.callTTL)
+				defer cancel()
+
+				client := ts.NewClient(nil)
+				_, _, _, err := raw.Call(ctx, client, ts.HostPort(), "svc", "echo", nil, nil)
+
+				calls := relaytest.NewMockStats()
+				if tt.wantErr {
+					require.Error(t, err, "Expected call to fail")
+					assert.Equal(t, ErrCodeTimeout, GetSystemErrorCode(err), "Unexpected error code")
+					assert.Zero(t, gotTTL, "Handler should not be called")
+					calls.Add(client.PeerInfo().ServiceName, "svc", "echo").Failed("relay-timeout").End()
+				} else {
+					require.NoError(t, err, "Call failed")
+					assert.True(t, gotTTL <= tt.callTTL-hopDelay, "Forwarded TTL %v should be at most %v", gotTTL, tt.callTTL-hopDelay)
+					assert.True(t, gotTTL > tt.callTTL-2*hopDelay, "Forwarded TTL %v should be more than %v", gotTTL, tt.callTTL-2*hopDelay)
+					calls.Add(client.PeerInfo().ServiceName, "svc", "echo").Succeeded().End()
+				}
+				ts.AssertRelayStats(calls)
+			})
+		})
+	}
+}
+
+type relayObserverEvent struct {
+	event    string
+	method   string
+	hostPort string
+	failure  string
+}
+
+type recordingRelayObserver struct {
+	sync.Mutex
+	events []relayObserverEvent
+}
+
+func (o *recordingRelayObserver) record(event string, f relay.CallFrame, peer *Peer, failure string) {
+	e := relayObserverEvent{event: event, method: string(f.Method()), failure: failure}
+	if peer != nil {
+		e.hostPort = peer.HostPort()
+	}
+	o.Lock()
+	o.events = append(o.events, e)
+	o.Unlock()
+}
+
+func (o *recordingRelayObserver) Events() []relayObserverEvent {
+	o.Lock()
+	defer o.Unlock()
+	return append([]relayObserverEvent(nil), o.events...)
+}
+
+func (o *recordingRelayObserver) Reset() {
+	o.Lock()
+	o.events = nil
+	o.Unlock()
+}
+
+func (o *recordingRelayObserver) CallBegin(f relay.CallFrame) {
+	o.record("begin", f, nil, "")
+}
+
+func (o *recordingRelayObserver) PeerSelected(f relay.CallFrame, peer *Peer) {
+	o.record("peer-selected", f, peer, "")
+}
+
+func (o *recordingRelayObserver) ResponseReceived(f relay.CallFrame, peer *Peer) {
+	o.record("response-received", f, peer, "")
+}
+
+func (o *recordingRelayObserver) CallEnd(f relay.CallFrame, peer *Peer, failure string) {
+	o.record("end", f, peer, failure)
+}
+
+func TestRelayCallObserver(t *testing.T) {
+	tests := []struct {
+		method      string
+		wantFailure string
+	}{
+		{method: "echo"},
+		{method: "app-error", wantFailure: "application-error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			observer := &recordingRelayObserver{}
+			opts := testutils.NewOpts().
+				SetRelayOnly().
+				SetRelayCallObserver(observer)
+
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				// The observer is shared by each relay variant of the test server.
+				observer.Reset()
+
+				testutils.RegisterEcho(ts.Server(), nil)
+				testutils.RegisterFunc(ts.Server(), "app-error", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+					return &raw.Res{IsErr: true}, nil
+				})
+
+				ctx, cancel := NewContext(testutils.Timeout(time.Second))
+				defer cancel()
+
+				_, _, _, err := raw.Call(ctx, ts.NewClient(nil), ts.HostPort(), ts.ServiceName(), tt.method, nil, nil)
+				require.NoError(t, err, "Call failed")
+
+				// The call may end after the client has received the response.
+				require.True(t, testutils.WaitFor(time.Second, func() bool {
+					return len(observer.Events()) == 4
+				}), "Expected all observer callbacks, got %v", observer.Events())
+
+				serverHostPort := ts.Server().PeerInfo().HostPort
+				want := []relayObserverEvent{
+					{event: "begin", method: tt.method},
+					{event: "peer-selected", method: tt.method, hostPort: serverHostPort},
+					{event: "response-received", method: tt.method, hostPort: serverHostPort},
+					{event: "end", method: tt.method, hostPort: serverHostPort, failure: tt.wantFailure},
+				}
+				assert.Equal(t, want, observer.Events(), "Unexpected sequence of observer callbacks")
+			})
+		})
+	}
+}
+
+type recordingRelayStats struct {
+	events recordingInterceptorEvents
+}
+
+func (s *recordingRelayStats) Begin(f relay.CallFrame) relay.CallStats {
+	s.events.record("begin " + string(f.Service()) + "::" + string(f.Method()))
+	return &recordingCallStats{s}
+}
+
+type recordingCallStats struct {
+	s *recordingRelayStats
+}
+
+func (c *recordingCallStats) Succeeded()           { c.s.events.record("succeeded") }
+func (c *recordingCallStats) Failed(reason string) { c.s.events.record("failed " + reason) }
+func (c *recordingCallStats) End()                 { c.s.events.record("end") }
+
+// forwardedFrameSniffer tracks whether the frames sent by the relay are the
+// same frames it received.
+type forwardedFrameSniffer struct {
+	sync.Mutex
+
+	received  map[*Frame]struct{}
+	forwarded int
+	copied    int
+}
+
+func (s *forwardedFrameSniffer) SniffFrame(conn *Connection, dir FrameDirection, f *Frame) {
+	// The server's only connection is to the relay, so other connections
+	// belong to the relay. Pings, which have no payload, are handled by the
+	// relay rather than forwarded.
+	if strings.HasPrefix(conn.RemotePeerInfo().ProcessName, "relay-") || f.Header.PayloadSize() == 0 {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	switch dir {
+	case FrameReceived:
+		s.received[f] = struct{}{}
+	case FrameSent:
+		if _, ok := s.received[f]; ok {
+			s.forwarded++
+			delete(s.received, f)
+		} else {
+			s.copied++
+		}
+	}
+}
+
+func TestRelayForwardsFramesInPlace(t *testing.T) {
+	sniffer := &forwardedFrameSniffer{received: make(map[*Frame]struct{})}
+	opts := serviceNameOpts("test").SetRelayOnly()
+	opts.DefaultConnectionOptions.FrameSniffer = sniffer
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+		client := ts.NewClient(serviceNameOpts("client"))
+
+		// The large argument is fragmented across multiple frames.
+		largeArg := testutils.RandBytes(100000)
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+		_, arg3, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, largeArg)
+		require.NoError(t, err, "Call failed")
+		assert.Equal(t, largeArg, arg3, "Unexpected response")
+	})
+
+	sniffer.Lock()
+	defer sniffer.Unlock()
+	assert.True(t, sniffer.forwarded > 2, "Expected the fragmented call to be forwarded, got %v frames", sniffer.forwarded)
+	assert.Zero(t, sniffer.copied, "Relay should forward the frames it receives")
+	assert.Empty(t, sniffer.received, "Relay should forward every frame it receives")
+}
+
+func TestRelayStats(t *testing.T) {
+	tests := []struct {
+		msg        string
+		service    string
+		method     string
+		wantEvents []string
+	}{
+		{
+			msg:        "success",
+			method:     "echo",
+			wantEvents: []string{"begin testService::echo", "succeeded", "end"},
+		},
+		{
+			msg:        "application error",
+			method:     "app-error",
+			wantEvents: []string{"begin testService::app-error", "failed application-error", "end"},
+		},
+		{
+			msg:        "no peers",
+			service:    "unknown-svc",
+			method:     "echo",
+			wantEvents: []string{"begin unknown-svc::echo", "failed relay-declined", "end"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			stats := &recordingRelayStats{}
+			opts := testutils.NewOpts().
+				SetRelayOnly().
+				SetRelayStats(stats).
+				DisableLogVerification()
+
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				// The stats are shared by each relay variant of the test server.
+				stats.events.Reset()
+
+				testutils.RegisterEcho(ts.Server(), nil)
+				testutils.RegisterFunc(ts.Server(), "app-error", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+					return &raw.Res{IsErr: true}, nil
+				})
+
+				ctx, cancel := NewContext(testutils.Timeout(time.Second))
+				defer cancel()
+
+				service := tt.service
+				if service == "" {
+					service = ts.ServiceName()
+				}
+				raw.Call(ctx, ts.NewClient(nil), ts.HostPort(), service, tt.method, nil, nil)
+
+				// The call may end after the client has received the response.
+				require.True(t, testutils.WaitFor(time.Second, func() bool {
+					return len(stats.events.Events()) == len(tt.wantEvents)
+				}), "Expected all stats callbacks, got %v", stats.events.Events())
+				assert.Equal(t, tt.wantEvents, stats.events.Events(), "Unexpected stats callbacks")
+			})
+		})
+	}
+}
+
+// failingRelayHost is a RelayHost that fails to start calls without
+// returning a RelayCall.
+type failingRelayHost struct {
+	err error
+}
+
+func (failingRelayHost) SetChannel(*Channel) {}
+
+func (h failingRelayHost) Start(relay.CallFrame, *relay.Conn) (RelayCall, error) {
+	return nil, h.err
+}
+
+func TestRelayStatsWithoutCall(t *testing.T) {
+	tests := []struct {
+		msg        string
+		err        error
+		wantEvents []string
+	}{
+		{
+			msg:        "error",
+			err:        errors.New("no peers"),
+			wantEvents: []string{"begin testService::echo", "failed relay-declined", "end"},
+		},
+		{
+			msg:        "system error",
+			err:        NewSystemError(ErrCodeBusy, "busy"),
+			wantEvents: []string{"begin testService::echo", "failed relay-busy", "end"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			stats := &recordingRelayStats{}
+			opts := testutils.NewOpts().
+				SetRelayOnly().
+				SetRelayHost(failingRelayHost{tt.err}).
+				SetRelayStats(stats).
+				DisableLogVerification()
+
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				stats.events.Reset()
+
+				ctx, cancel := NewContext(testutils.Timeout(time.Second))
+				defer cancel()
+
+				_, _, _, err := raw.Call(ctx, ts.NewClient(nil), ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+				require.Error(t, err, "Call should fail")
+				assert.Equal(t, tt.wantEvents, stats.events.Events(), "Unexpected stats callbacks")
+			})
+		})
+	}
+}
+
+type recordingRelayInterceptor struct {
+	name    string
+	events  *recordingInterceptorEvents
+	reject  string
+	logging string
+}
+
+type recordingInterceptorEvents struct {
+	sync.Mutex
+	events []string
+}
+
+func (e *recordingInterceptorEvents) record(event string) {
+	e.Lock()
+	e.events = append(e.events, event)
+	e.Unlock()
+}
+
+func (e *recordingInterceptorEvents) Events() []string {
+	e.Lock()
+	defer e.Unlock()
+	return append([]string(nil), e.events...)
+}
+
+func (e *recordingInterceptorEvents) Reset() {
+	e.Lock()
+	e.events = nil
+	e.Unlock()
+}
+
+func (i *recordingRelayInterceptor) Intercept(f relay.CallFrame, opts *RelayInterceptOptions) (RelayInterceptedCall, error) {
+	method := string(f.Method())
+	i.events.record(i.name + " intercept " + method)
+	if method == i.reject {
+		return nil, errors.New("rejected by " + i.name)
+	}
+	if method == i.logging {
+		opts.LogFrames = true
+	}
+	return &recordingInterceptedCall{i}, nil
+}
+
+type recordingInterceptedCall struct {
+	interceptor *recordingRelayInterceptor
+}
+
+func (c *recordingInterceptedCall) Succeeded() {
+	c.interceptor.events.record(c.interceptor.name + " succeeded")
+}
+
+func (c *recordingInterceptedCall) Failed(reason string) {
+	c.interceptor.events.record(c.interceptor.name + " failed " + reason)
+}
+
+func TestRelayInterceptors(t *testing.T) {
+	tests := []struct {
+		method     string
+		wantErr    string
+		wantEvents []string
+		wantLogged bool
+	}{
+		{
+			method: "echo",
+			wantEvents: []string{
+				"first intercept echo",
+				"second intercept echo",
+				"third intercept echo",
+				"first succeeded",
+				"second succeeded",
+				"third succeeded",
+			},
+		},
+		{
+			method: "app-error",
+			wantEvents: []string{
+				"first intercept app-error",
+				"second intercept app-error",
+				"third intercept app-error",
+				"first failed application-error",
+				"second failed application-error",
+				"third failed application-error",
+			},
+		},
+		{
+			method:  "rejected",
+			wantErr: "rejected by second",
+			wantEvents: []string{
+				"first intercept rejected",
+				"second intercept rejected",
+				"first failed relay-intercepted",
+			},
+		},
+		{
+			method: "logged",
+			wantEvents: []string{
+				"first intercept logged",
+				"second intercept logged",
+				"third intercept logged",
+				"first succeeded",
+				"second succeeded",
+				"third succeeded",
+			},
+			wantLogged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			events := &recordingInterceptorEvents{}
+			logOut := &lockedBuffer{}
+			opts := testutils.NewOpts().
+				SetRelayOnly().
+				AddRelayInterceptors(
+					&recordingRelayInterceptor{name: "first", events: events, logging: "logged"},
+					&recordingRelayInterceptor{name: "second", events: events, reject: "rejected"},
+					&recordingRelayInterceptor{name: "third", events: events, reject: "rejected"},
+				)
+			opts.Logger = NewLogger(logOut)
+
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				// The interceptors are shared by each relay variant of the test server.
+				events.Reset()
+
+				handler := func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+					return &raw.Res{Arg2: args.Arg2, Arg3: args.Arg3}, nil
+				}
+				testutils.RegisterFunc(ts.Server(), "echo", handler)
+				testutils.RegisterFunc(ts.Server(), "logged", handler)
+				testutils.RegisterFunc(ts.Server(), "rejected", handler)
+				testutils.RegisterFunc(ts.Server(), "app-error", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+					return &raw.Res{IsErr: true}, nil
+				})
+
+				ctx, cancel := NewContext(testutils.Timeout(time.Second))
+				defer cancel()
+
+				_, _, _, err := raw.Call(ctx, ts.NewClient(nil), ts.HostPort(), ts.ServiceName(), tt.method, nil, nil)
+				if tt.wantErr != "" {
+					require.Error(t, err, "Expected rejected call to fail")
+					assert.Equal(t, ErrCodeDeclined, GetSystemErrorCode(err), "Unexpected error code")
+					assert.Equal(t, tt.wantErr, GetSystemErrorMessage(err), "Unexpected error message")
+				} else {
+					require.NoError(t, err, "Call failed")
+				}
+
+				// The call may end after the client has received the response.
+				require.True(t, testutils.WaitFor(time.Second, func() bool {
+					return len(events.Events()) == len(tt.wantEvents)
+				}), "Expected all interceptor callbacks, got %v", events.Events())
+				assert.Equal(t, tt.wantEvents, events.Events(), "Unexpected sequence of interceptor callbacks")
+			})
+
+			logs := logOut.String()
+			if tt.wantLogged {
+				assert.Contains(t, logs, "Relaying frame.", "Expected frames to be logged")
+				assert.Contains(t, logs, "messageTypeCallReq[", "Expected the call request to be logged")
+				assert.Contains(t, logs, "messageTypeCallRes[", "Expected the call response to be logged")
+			} else {
+				assert.NotContains(t, logs, "Relaying frame.", "Frames should only be logged for tagged calls")
+			}
+		})
+	}
+}
+
+// headerRewritingInterceptor applies header changes to every call, and
+// records the routing key it sees.
+type headerRewritingInterceptor struct {
+	set    map[string]string
+	delete []string
+
+	mu         sync.Mutex
+	routingKey string
+}
+
+func (i *headerRewritingInterceptor) Intercept(f relay.CallFrame, opts *RelayInterceptOptions) (RelayInterceptedCall, error) {
+	i.mu.Lock()
+	i.routingKey = string(f.RoutingKey())
+	i.mu.Unlock()
+
+	for k, v := range i.set {
+		opts.SetHeader(k, v)
+	}
+	for _, k := range i.delete {
+		opts.DeleteHeader(k)
+	}
+	return nil, nil
+}
+
+func (i *headerRewritingInterceptor) RoutingKey() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.routingKey
+}
+
+func TestRelayInterceptorRewritesHeaders(t *testing.T) {
+	first := &headerRewritingInterceptor{set: map[string]string{"rk": "injected", "sk": "shard"}}
+	second := &headerRewritingInterceptor{delete: []string{"sk"}}
+	opts := testutils.NewOpts().
+		SetRelayOnly().
+		AddRelayInterceptors(first, second)
+
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterFunc(ts.Server(), "headers", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			call := CurrentCall(ctx)
+			return &raw.Res{Arg3: []byte(call.RoutingKey() + "," + call.ShardKey() + "," + call.CallerName())}, nil
+		})
+
+		ctx, cancel := NewContextBuilder(testutils.Timeout(time.Second)).SetShardKey("original").Build()
+		defer cancel()
+
+		client := ts.NewClient(serviceNameOpts("client"))
+		_, arg3, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "headers", nil, []byte("arg3"))
+		require.NoError(t, err, "Call failed")
+		assert.Equal(t, "injected,,client", string(arg3), "Unexpected headers received by the server")
+		assert.Equal(t, "", first.RoutingKey(), "First interceptor should see the original frame")
+		assert.Equal(t, "injected", second.RoutingKey(), "Second interceptor should see the first's changes")
+	})
+}
+
 // TestRelayConcurrentCalls makes many concurrent calls and ensures that
 // we don't try to reuse any frames once they've been released.
 func TestRelayConcurrentCalls(t *testing.T) {
@@ -703,6 +1505,449 @@ func TestRelayRateLimitDrop(t *testing.T) {
 	})
 }
 
+// resettableRateLimiter allows each test server to use a new rate limiter.
+type resettableRateLimiter struct {
+	relay.RateLimiter
+}
+
+func TestRelayRateLimiter(t *testing.T) {
+	limiter := &resettableRateLimiter{}
+	opts := serviceNameOpts("svc").SetRelayOnly().SetRelayRateLimiter(limiter)
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		var err error
+		limiter.RateLimiter, err = relay.NewEdgeRateLimiter(map[string]float64{
+			"*->svc::echo": 2,
+		})
+		require.NoError(t, err, "NewEdgeRateLimiter failed")
+
+		var serverCalls atomic.Int32
+		testutils.RegisterEcho(ts.Server(), func() { serverCalls.Inc() })
+
+		calls := relaytest.NewMockStats()
+		for _, caller := range []string{"c1", "c2"} {
+			client := ts.NewClient(serviceNameOpts(caller))
+			for i := 0; i < 3; i++ {
+				err := testutils.CallEcho(client, ts.HostPort(), "svc", nil)
+				if i < 2 {
+					require.NoError(t, err, "Call %v from %v should be allowed", i, caller)
+					calls.Add(caller, "svc", "echo").Succeeded().End()
+					continue
+				}
+
+				require.Error(t, err, "Call %v from %v should be rate limited", i, caller)
+				assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Unexpected error code")
+				calls.Add(caller, "svc", "echo").Failed("rate-limited").End()
+			}
+		}
+
+		assert.EqualValues(t, 4, serverCalls.Load(), "Rate limited calls should not reach the server")
+		ts.AssertRelayStats(calls)
+	})
+}
+
+// rateLimitingRelayHost is a StubRelayHost that provides the relay's rate limiter.
+type rateLimitingRelayHost struct {
+	*relaytest.StubRelayHost
+
+	limiter relay.RateLimiter
+}
+
+func (rh *rateLimitingRelayHost) RateLimiter() relay.RateLimiter {
+	return rh.limiter
+}
+
+func TestRelayRateLimitingHost(t *testing.T) {
+	// The relay is created separately so that only it uses the RelayHost.
+	testutils.WithTestServer(t, serviceNameOpts("svc").NoRelay(), func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		relayHost := &rateLimitingRelayHost{StubRelayHost: relaytest.NewStubRelayHost()}
+		relayCh := ts.NewServer(testutils.NewOpts().SetServiceName("relay").SetRelayHost(relayHost))
+		relayHost.Add("svc", ts.HostPort())
+		client := ts.NewClient(nil)
+
+		callN := func(n int) (allowed int) {
+			for i := 0; i < n; i++ {
+				err := testutils.CallEcho(client, relayCh.PeerInfo().HostPort, "svc", nil)
+				if err == nil {
+					allowed++
+					continue
+				}
+				require.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Unexpected error code")
+			}
+			return allowed
+		}
+
+		assert.Equal(t, 5, callN(5), "Calls should not be limited without a rate limiter")
+
+		limiter, err := relay.NewEdgeRateLimiter(map[string]float64{"*->svc::echo": 2})
+		require.NoError(t, err, "NewEdgeRateLimiter failed")
+		relayHost.limiter = limiter
+		assert.Equal(t, 2, callN(5), "Calls should be limited by the host's rate limiter")
+
+		// Reloading the limits applies to new calls.
+		require.NoError(t, limiter.SetLimits(map[string]float64{"*->svc::echo": 4}), "SetLimits failed")
+		assert.Equal(t, 4, callN(5), "Calls should be limited by the reloaded limits")
+	})
+}
+
+func TestRelayMaxRequestSize(t *testing.T) {
+	const maxSize = 100 * 1024
+
+	opts := serviceNameOpts("svc").SetRelayOnly().DisableLogVerification()
+	opts.RelayMaxRequestSize = maxSize
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+		client := ts.NewClient(nil)
+
+		// The first fragment is under the limit, so the limit is only crossed
+		// once the continuation frames are relayed.
+		ctx, cancel := NewContext(testutils.Timeout(300 * time.Millisecond))
+		defer cancel()
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), "svc", "echo", nil, testutils.RandBytes(maxSize+1))
+		require.Error(t, err, "Expected oversized request to fail")
+		assert.Equal(t, ErrCodeBadRequest, GetSystemErrorCode(err), "Unexpected error code")
+
+		// Calls within the limit are unaffected.
+		require.NoError(t, testutils.CallEcho(client, ts.HostPort(), "svc", &raw.Args{
+			Arg3: testutils.RandBytes(maxSize - 1024),
+		}), "Call under the limit should succeed")
+
+		calls := relaytest.NewMockStats()
+		calls.Add(client.PeerInfo().ServiceName, "svc", "echo").Failed("frame-too-large").End()
+		calls.Add(client.PeerInfo().ServiceName, "svc", "echo").Succeeded().End()
+		ts.AssertRelayStats(calls)
+	})
+}
+
+func TestRelayMaxResponseSize(t *testing.T) {
+	const maxSize = 100 * 1024
+
+	opts := serviceNameOpts("svc").SetRelayOnly().
+		AddLogFilter("Relayed response exceeded the max response size.", 1)
+	opts.RelayMaxResponseSize = maxSize
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterFunc(ts.Server(), "big", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return &raw.Res{Arg3: testutils.RandBytes(maxSize + 1)}, nil
+		})
+		client := ts.NewClient(nil)
+
+		ctx, cancel := NewContext(testutils.Timeout(300 * time.Millisecond))
+		defer cancel()
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), "svc", "big", nil, nil)
+		require.Error(t, err, "Expected oversized response to fail")
+		assert.Equal(t, ErrCodeUnexpected, GetSystemErrorCode(err), "Unexpected error code")
+
+		// The first response fragment marks the call as succeeded before the
+		// limit is crossed by a continuation frame.
+		calls := relaytest.NewMockStats()
+		calls.Add(client.PeerInfo().ServiceName, "svc", "big").Succeeded().Failed("frame-too-large").End()
+		ts.AssertRelayStats(calls)
+	})
+}
+
+func TestRelayCircuitBreaker(t *testing.T) {
+	clock := testutils.NewStubClock(time.Now())
+	opts := serviceNameOpts("svc").SetRelayOnly().SetTimeNow(clock.Now).DisableLogVerification()
+	opts.RelayCircuitBreaker = RelayCircuitBreakerOptions{
+		FailureRatio: 0.5,
+		MinRequests:  2,
+		Cooldown:     time.Minute,
+	}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterFunc(ts.Server(), "method", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			switch string(args.Arg3) {
+			case "busy":
+				return nil, ErrServerBusy
+			case "cancelled":
+				return nil, ErrRequestCancelled
+			}
+			return &raw.Res{}, nil
+		})
+		client := ts.NewClient(nil)
+
+		call := func(result string) error {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), "svc", "method", nil, []byte(result))
+			return err
+		}
+		circuitState := func() CircuitBreakerRuntimeState {
+			return ts.Relay().IntrospectState(nil).RelayCircuitBreakers["svc::method"]
+		}
+		waitForState := func(state CircuitState, msg string) {
+			require.True(t, testutils.WaitFor(time.Second, func() bool {
+				return circuitState().State == state.String()
+			}), "%v: expected circuit to be %v, got %+v", msg, state, circuitState())
+		}
+
+		// Caller-side cancellations should not trip the breaker.
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, ErrCodeCancelled, GetSystemErrorCode(call("cancelled")), "Unexpected error code")
+		}
+		require.NoError(t, call("ok"), "Call should succeed")
+		assert.Zero(t, circuitState().Failures, "Cancellations should not be counted as failures")
+
+		require.Error(t, call("busy"), "Expected busy error")
+		waitForState(CircuitOpen, "failure ratio exceeded")
+
+		err := call("ok")
+		assert.Equal(t, ErrCodeDeclined, GetSystemErrorCode(err), "Calls should be declined while open")
+
+		// After the cooldown, a failed probe reopens the circuit.
+		clock.Elapse(time.Minute)
+		require.Error(t, call("busy"), "Expected busy error")
+		waitForState(CircuitOpen, "failed probe")
+		assert.Equal(t, ErrCodeDeclined, GetSystemErrorCode(call("ok")), "Calls should be declined while open")
+
+		// A successful probe closes the circuit.
+		clock.Elapse(time.Minute)
+		require.NoError(t, call("ok"), "Probe should succeed")
+		waitForState(CircuitClosed, "successful probe")
+		require.NoError(t, call("ok"), "Call should succeed once closed")
+	})
+}
+
+func TestRelayStatsPeerIn(t *testing.T) {
+	const numCalls = 30
+
+	// P2C selects peers randomly, so the peer for each call is unpredictable.
+	opts := serviceNameOpts("svc").SetRelayOnly()
+	opts.PeerSelection = PeerSelectionP2C
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		server2 := ts.NewServer(serviceNameOpts("svc"))
+		servers := []*Channel{ts.Server(), server2}
+		hostPorts := make([]string, len(servers))
+		for i, server := range servers {
+			testutils.RegisterEcho(server, nil)
+			hostPorts[i] = server.PeerInfo().HostPort
+		}
+
+		client := ts.NewClient(nil)
+		calls := relaytest.NewMockStats()
+		for i := 0; i < numCalls; i++ {
+			require.NoError(t, testutils.CallEcho(client, ts.HostPort(), "svc", nil), "Call failed")
+			calls.Add(client.PeerInfo().ServiceName, "svc", "echo").Succeeded().PeerIn(hostPorts...).End()
+		}
+		ts.AssertRelayStats(calls)
+		ts.RelayHost().Stats().AssertPeersUsed(t, client.PeerInfo().ServiceName, "svc", "echo", hostPorts...)
+	})
+}
+
+func TestRelayRetriesPeerOnConnectionFailure(t *testing.T) {
+	tests := []struct {
+		msg        string
+		maxRetries int
+		goodPeer   bool
+		wantErr    bool
+		wantRetry  int
+	}{
+		{
+			msg:        "no retries",
+			maxRetries: 0,
+			goodPeer:   true,
+			wantErr:    true,
+		},
+		{
+			msg:        "retry succeeds on another peer",
+			maxRetries: 2,
+			goodPeer:   true,
+			wantRetry:  1,
+		},
+		{
+			msg:        "no other peer to retry on",
+			maxRetries: 2,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			badHostPort := testutils.GetClosedHostPort(t)
+			getHost := func(relay.CallFrame, *relay.Conn) (string, error) {
+				return badHostPort, nil
+			}
+
+			opts := testutils.NewOpts().
+				SetRelayOnly().
+				SetRelayHost(relaytest.HostFunc(getHost)).
+				SetRelayMaxPeerRetries(tt.maxRetries).
+				AddLogFilter("Failed to connect to relay host.", 1)
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				server := ts.NewServer(serviceNameOpts("svc"))
+				testutils.RegisterEcho(server, nil)
+				if tt.goodPeer {
+					ts.Relay().GetSubChannel("svc").Peers().Add(server.PeerInfo().HostPort)
+				}
+
+				client := ts.NewClient(nil)
+				err := testutils.CallEcho(client, ts.HostPort(), "svc", nil)
+
+				calls := relaytest.NewMockStats()
+				call := calls.Add(client.PeerInfo().ServiceName, "svc", "echo")
+				if tt.wantRetry > 0 {
+					call.RetriedTo(server.PeerInfo().HostPort)
+				}
+				if tt.wantErr {
+					require.Error(t, err, "Call should fail")
+					assert.Equal(t, ErrCodeNetwork, GetSystemErrorCode(err), "Unexpected error code")
+					call.Failed("relay-connection-failed")
+				} else {
+					require.NoError(t, err, "Call should succeed after retrying")
+					call.Succeeded()
+				}
+				call.End()
+				ts.AssertRelayStats(calls)
+			})
+		})
+	}
+}
+
+func TestRelayRetriesPeerRespectsTTL(t *testing.T) {
+	badHostPort := testutils.GetClosedHostPort(t)
+	getHost := func(relay.CallFrame, *relay.Conn) (string, error) {
+		return badHostPort, nil
+	}
+
+	// Leave no time in the call's TTL to retry on another peer.
+	adjustTTL := func(relay.CallFrame, time.Duration) time.Duration {
+		return time.Nanosecond
+	}
+
+	opts := testutils.NewOpts().
+		SetRelayOnly().
+		SetRelayHost(relaytest.HostFunc(getHost)).
+		SetRelayAdjustTTL(adjustTTL).
+		SetRelayMaxPeerRetries(2).
+		AddLogFilter("Failed to connect to relay host.", 1)
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		server := ts.NewServer(serviceNameOpts("svc"))
+		testutils.RegisterEcho(server, nil)
+		ts.Relay().GetSubChannel("svc").Peers().Add(server.PeerInfo().HostPort)
+
+		client := ts.NewClient(nil)
+		err := testutils.CallEcho(client, ts.HostPort(), "svc", nil)
+		require.Error(t, err, "Call should fail without retrying")
+
+		stats := relaytest.NewMockStats()
+		stats.Add(client.PeerInfo().ServiceName, "svc", "echo").Failed("relay-connection-failed").End()
+		ts.AssertRelayStats(stats)
+	})
+}
+
+func TestRelayFailover(t *testing.T) {
+	// The unresponsive peer accepts connections, but never completes the
+	// TChannel handshake, so connecting to it times out.
+	unresponsive, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Listen failed")
+	defer unresponsive.Close()
+
+	badHostPort := unresponsive.Addr().String()
+	getHost := func(relay.CallFrame, *relay.Conn) (string, error) {
+		return badHostPort, nil
+	}
+
+	opts := testutils.NewOpts().
+		SetRelayOnly().
+		SetRelayHost(relaytest.HostFunc(getHost)).
+		SetRelayMaxPeerRetries(1).
+		SetRelayFailoverCooldown(time.Minute).
+		DisableLogVerification() // the first call fails to connect.
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		server := ts.NewServer(serviceNameOpts("svc"))
+		testutils.RegisterEcho(server, nil)
+		ts.Relay().GetSubChannel("svc").Peers().Add(server.PeerInfo().HostPort)
+
+		client := ts.NewClient(nil)
+		ctx, cancel := NewContext(50 * time.Millisecond)
+		defer cancel()
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), "svc", "echo", nil, nil)
+		require.Error(t, err, "Call to unresponsive peer should fail")
+
+		// Later calls are failed over to the other peer without waiting to
+		// connect to the unresponsive peer.
+		for i := 0; i < 3; i++ {
+			require.NoError(t, testutils.CallEcho(client, ts.HostPort(), "svc", nil), "Call should fail over")
+		}
+	})
+}
+
+func TestRelayFailoverAfterConnectionError(t *testing.T) {
+	var proxyHostPort atomic.String
+	getHost := func(relay.CallFrame, *relay.Conn) (string, error) {
+		return proxyHostPort.Load(), nil
+	}
+
+	opts := testutils.NewOpts().
+		SetRelayOnly().
+		SetRelayHost(relaytest.HostFunc(getHost)).
+		SetRelayMaxPeerRetries(1).
+		SetRelayFailoverCooldown(time.Minute).
+		DisableLogVerification() // the in-progress call fails when the connection fails.
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		failing := ts.NewServer(serviceNameOpts("svc").DisableLogVerification())
+		testutils.RegisterEcho(failing, nil)
+		unblock := make(chan struct{})
+		received := make(chan struct{})
+		testutils.RegisterFunc(failing, "block", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			close(received)
+			<-unblock
+			return &raw.Res{}, nil
+		})
+		hostPort, closeProxy := testutils.FrameRelay(t, failing.PeerInfo().HostPort, func(_ bool, f *Frame) *Frame { return f })
+		proxyHostPort.Store(hostPort)
+
+		server := ts.NewServer(serviceNameOpts("svc"))
+		testutils.RegisterEcho(server, nil)
+		ts.Relay().GetSubChannel("svc").Peers().Add(server.PeerInfo().HostPort)
+
+		client := ts.NewClient(nil)
+		require.NoError(t, testutils.CallEcho(client, ts.HostPort(), "svc", nil), "Call via proxy failed")
+
+		// Fail the connection while a call is in progress.
+		callErr := make(chan error, 1)
+		go func() {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), "svc", "block", nil, nil)
+			callErr <- err
+		}()
+		<-received
+		closeProxy()
+		assert.Error(t, <-callErr, "Call in progress should fail")
+		close(unblock)
+
+		// Replace the proxy with a listener that never completes the TChannel
+		// handshake, so calls only succeed if they're failed over.
+		unresponsive, err := net.Listen("tcp", hostPort)
+		require.NoError(t, err, "Listen failed")
+		defer unresponsive.Close()
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, testutils.CallEcho(client, ts.HostPort(), "svc", nil), "Call should fail over")
+		}
+	})
+}
+
+func TestRelayConnectionsPerPeer(t *testing.T) {
+	opts := testutils.NewOpts().
+		SetRelayOnly().
+		SetRelayConnectionsPerPeer(3)
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		client := ts.NewClient(nil)
+		for i := 0; i < 10; i++ {
+			testutils.AssertEcho(t, client, ts.HostPort(), ts.ServiceName())
+		}
+
+		peer, ok := ts.Relay().RootPeers().Get(ts.Server().PeerInfo().HostPort)
+		require.True(t, ok, "Relay has no peer for the server")
+		_, outbound := peer.NumConnections()
+		assert.Equal(t, 3, outbound, "Relay should use a pool of connections to the server")
+	})
+}
+
 // Test that a stalled connection to a single server does not block all calls
 // from that server, and we have stats to capture that this is happening.
 func TestRelayStalledConnection(t *testing.T) {
@@ -894,3 +2139,90 @@ func TestRelayRaceTimerCausesStuckConnectionOnClose(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+func TestRelayShadow(t *testing.T) {
+	var shadowHostPort atomic.String
+	stats := newRecordingStatsReporter()
+
+	opts := serviceNameOpts("svc").SetRelayOnly().SetStatsReporter(stats)
+	opts.RelayShadow = RelayShadowOptions{
+		Percentage: 100,
+		Destination: func(f relay.CallFrame) (string, bool) {
+			if string(f.Method()) == "unmirrored" {
+				return "", false
+			}
+			return shadowHostPort.Load(), true
+		},
+	}
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		handler := func(name string, calls chan<- []byte) func(context.Context, *raw.Args) (*raw.Res, error) {
+			return func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+				if calls != nil {
+					calls <- args.Arg3
+				}
+				return &raw.Res{Arg3: []byte(name)}, nil
+			}
+		}
+
+		shadowCalls := make(chan []byte, 100)
+		// The shadow destination isn't known to the RelayHost, so it hosts
+		// "svc" using a SubChannel.
+		shadow := ts.NewServer(serviceNameOpts("svc-shadow"))
+		shadowHostPort.Store(shadow.PeerInfo().HostPort)
+		for _, method := range []string{"echo", "unmirrored"} {
+			testutils.RegisterFunc(ts.Server(), method, handler("primary", nil))
+			testutils.RegisterFunc(shadow.GetSubChannel("svc"), method, handler("shadow", shadowCalls))
+		}
+		client := ts.NewClient(nil)
+
+		call := func(method string, arg3 []byte) {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+			_, res, _, err := raw.Call(ctx, client, ts.HostPort(), "svc", method, nil, arg3)
+			require.NoError(t, err, "Call failed")
+			assert.Equal(t, "primary", string(res), "Caller should get the primary response")
+		}
+
+		// The relay connects to the shadow destination in the background, so
+		// calls are only mirrored once the connection is established.
+		require.True(t, testutils.WaitFor(time.Second, func() bool {
+			call("echo", nil)
+			select {
+			case <-shadowCalls:
+				return true
+			default:
+				return false
+			}
+		}), "Calls were not mirrored to the shadow destination")
+
+		// Fragmented calls are mirrored in full.
+		largeArg := testutils.RandBytes(100 * 1024)
+		call("echo", largeArg)
+		select {
+		case got := <-shadowCalls:
+			assert.Equal(t, largeArg, got, "Shadow destination got unexpected args")
+		case <-time.After(testutils.Timeout(time.Second)):
+			t.Fatal("Fragmented call was not mirrored")
+		}
+
+		call("unmirrored", nil)
+		select {
+		case <-shadowCalls:
+			t.Error("Call without a shadow destination was mirrored")
+		case <-time.After(testutils.Timeout(20 * time.Millisecond)):
+		}
+
+		tags := ts.Relay().StatsTags()
+		tags["source"] = client.PeerInfo().ServiceName
+		tags["dest"] = "svc"
+		tags["method"] = "echo"
+		tags["shadow-host"] = shadow.PeerInfo().HostPort
+		require.True(t, testutils.WaitFor(time.Second, func() bool {
+			tags["result"] = "success"
+			responses := stats.getCount("relay.shadow.responses", tags)
+			delete(tags, "result")
+			return responses == stats.getCount("relay.shadow.calls", tags)
+		}), "Shadow responses were not recorded")
+		assert.True(t, stats.getCount("relay.shadow.calls", tags) >= 2, "Expected mirrored calls to be recorded")
+	})
+}
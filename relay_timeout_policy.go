@@ -0,0 +1,102 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"time"
+
+	"github.com/uber/tchannel-go/relay"
+)
+
+// RelayTimeoutRange bounds the TTL of relayed calls. Zero values are not
+// enforced.
+type RelayTimeoutRange struct {
+	// Min is the minimum TTL forwarded to the destination. Shorter TTLs are
+	// raised to Min, for destinations that can't respond any faster, even
+	// though the caller may time out first.
+	Min time.Duration
+
+	// Max is the maximum TTL forwarded to the destination, so callers with
+	// long timeouts don't hold relay resources for too long.
+	Max time.Duration
+}
+
+// RelayTimeoutPolicy configures how a relay rewrites the TTL of the calls it
+// forwards. The caller's TTL is first reduced by Overhead, and then clamped to
+// the range for the destination service.
+type RelayTimeoutPolicy struct {
+	// Overhead is subtracted from the caller's TTL before the call is
+	// forwarded, so the relay has time to return a response or an error
+	// before the caller's deadline. Calls with a TTL that doesn't exceed the
+	// overhead are failed with a timeout error.
+	Overhead time.Duration
+
+	// Default is the TTL range for services that are not in Services.
+	Default RelayTimeoutRange
+
+	// Services is the TTL range for specific destination services.
+	Services map[string]RelayTimeoutRange
+}
+
+// adjustTTL returns the TTL to forward for the call.
+func (p *RelayTimeoutPolicy) adjustTTL(f relay.CallFrame, ttl time.Duration) time.Duration {
+	ttl -= p.Overhead
+	if ttl <= 0 {
+		return ttl
+	}
+
+	limits, ok := p.Services[string(f.Service())]
+	if !ok {
+		limits = p.Default
+	}
+	if limits.Max > 0 && ttl > limits.Max {
+		ttl = limits.Max
+	}
+	if ttl < limits.Min {
+		ttl = limits.Min
+	}
+	return ttl
+}
+
+// relayAdjustTTL combines the RelayAdjustTTL function with the
+// RelayTimeoutPolicy, if either is set. The function is applied first.
+func relayAdjustTTL(adjust func(relay.CallFrame, time.Duration) time.Duration, policy *RelayTimeoutPolicy) func(relay.CallFrame, time.Duration) time.Duration {
+	if policy == nil {
+		return adjust
+	}
+
+	// Copy the policy so later changes by the caller don't affect the relay.
+	p := *policy
+	p.Services = make(map[string]RelayTimeoutRange, len(policy.Services))
+	for svc, limits := range policy.Services {
+		p.Services[svc] = limits
+	}
+
+	if adjust == nil {
+		return p.adjustTTL
+	}
+	return func(f relay.CallFrame, ttl time.Duration) time.Duration {
+		if ttl = adjust(f, ttl); ttl <= 0 {
+			return ttl
+		}
+		return p.adjustTTL(f, ttl)
+	}
+}
@@ -73,6 +73,20 @@ type reqResWriter struct {
 	messageForFragment messageForFragment
 	log                Logger
 	err                error
+
+	// compression, if set, is used to compress arg2 and arg3.
+	compression *argCompression
+
+	// uncompressed is set for calls that disabled compression, so neither
+	// the args nor the frames are compressed.
+	uncompressed bool
+
+	// priority determines which of the connection's send channels is used
+	// for the fragments.
+	priority CallPriority
+
+	// onFailed, if set, is called with the error when the writer fails.
+	onFailed func(err error)
 }
 
 //go:generate stringer -type=reqResReaderState
@@ -100,11 +114,27 @@ func (w *reqResWriter) arg1Writer() (ArgWriter, error) {
 }
 
 func (w *reqResWriter) arg2Writer() (ArgWriter, error) {
-	return w.argWriter(false /* last */, reqResWriterPreArg2, reqResWriterPreArg3)
+	return w.compressArg(w.argWriter(false /* last */, reqResWriterPreArg2, reqResWriterPreArg3))
 }
 
 func (w *reqResWriter) arg3Writer() (ArgWriter, error) {
-	return w.argWriter(true /* last */, reqResWriterPreArg3, reqResWriterComplete)
+	return w.compressArg(w.argWriter(true /* last */, reqResWriterPreArg3, reqResWriterComplete))
+}
+
+// compressArg wraps argWriter to compress the argument if the connection
+// negotiated argument compression, unless the call disabled compression.
+func (w *reqResWriter) compressArg(argWriter ArgWriter, err error) (ArgWriter, error) {
+	if err != nil || w.compression == nil {
+		return argWriter, err
+	}
+
+	compressingWriter := newCompressingArgWriter(argWriter, w.compression)
+	if w.uncompressed {
+		if err := compressingWriter.decide(false /* compress */); err != nil {
+			return nil, w.failed(err)
+		}
+	}
+	return compressingWriter, nil
 }
 
 // newFragment creates a new fragment for marshaling into
@@ -116,14 +146,16 @@ func (w *reqResWriter) newFragment(initial bool, checksum Checksum) (*writableFr
 	message := w.messageForFragment(initial)
 
 	// Create the frame
-	frame := w.conn.opts.FramePool.Get()
+	frame, payload := w.conn.getCallFrame()
 	frame.Header.ID = w.mex.msgID
 	frame.Header.messageType = message.messageType()
+	frame.uncompressed = w.uncompressed
 
 	// Write the message into the fragment, reserving flags and checksum bytes
-	wbuf := typed.NewWriteBuffer(frame.Payload[:])
+	wbuf := typed.NewWriteBuffer(payload)
 	fragment := new(writableFragment)
 	fragment.frame = frame
+	fragment.jumbo = frame.Header.isJumbo()
 	fragment.flagsRef = wbuf.DeferByte()
 	if err := message.write(wbuf); err != nil {
 		return nil, err
@@ -142,17 +174,21 @@ func (w *reqResWriter) flushFragment(fragment *writableFragment) error {
 	}
 
 	frame := fragment.frame.(*Frame)
-	frame.Header.SetPayloadSize(uint16(fragment.contents.BytesWritten()))
+	frame.Header.setPayloadSize(fragment.contents.BytesWritten())
 
 	if err := w.mex.checkError(); err != nil {
 		return w.failed(err)
 	}
+	if err := w.mex.throttleSend(frame); err != nil {
+		return w.failed(err)
+	}
 	select {
 	case <-w.mex.ctx.Done():
 		return w.failed(GetContextError(w.mex.ctx.Err()))
 	case <-w.mex.errCh.c:
 		return w.failed(w.mex.errCh.err)
-	case w.conn.sendCh <- frame:
+	case w.conn.sendChFor(w.priority) <- frame:
+		w.mex.markActivity()
 		return nil
 	}
 }
@@ -166,6 +202,9 @@ func (w *reqResWriter) failed(err error) error {
 
 	w.mex.shutdown()
 	w.err = err
+	if w.onFailed != nil {
+		w.onFailed(err)
+	}
 	return w.err
 }
 
@@ -189,6 +228,12 @@ type reqResReader struct {
 	previousFragment   *readableFragment
 	log                Logger
 	err                error
+
+	// compression, if set, is used to decompress arg2 and arg3.
+	compression *argCompression
+
+	// onFailed, if set, is called with the error when the reader fails.
+	onFailed func(err error)
 }
 
 // arg1Reader returns an ArgReader to read arg1.
@@ -198,12 +243,21 @@ func (r *reqResReader) arg1Reader() (ArgReader, error) {
 
 // arg2Reader returns an ArgReader to read arg2.
 func (r *reqResReader) arg2Reader() (ArgReader, error) {
-	return r.argReader(false /* last */, reqResReaderPreArg2, reqResReaderPreArg3)
+	return r.decompressArg(r.argReader(false /* last */, reqResReaderPreArg2, reqResReaderPreArg3))
 }
 
 // arg3Reader returns an ArgReader to read arg3.
 func (r *reqResReader) arg3Reader() (ArgReader, error) {
-	return r.argReader(true /* last */, reqResReaderPreArg3, reqResReaderComplete)
+	return r.decompressArg(r.argReader(true /* last */, reqResReaderPreArg3, reqResReaderComplete))
+}
+
+// decompressArg wraps argReader to decompress the argument if the connection
+// negotiated argument compression.
+func (r *reqResReader) decompressArg(argReader ArgReader, err error) (ArgReader, error) {
+	if err != nil || r.compression == nil {
+		return argReader, err
+	}
+	return newDecompressingArgReader(argReader, r.compression), nil
 }
 
 // argReader returns an ArgReader that can be used to read an argument. The
@@ -244,6 +298,10 @@ func (r *reqResReader) recvNextFragment(initial bool) (*readableFragment, error)
 
 		return nil, r.failed(err)
 	}
+	if err := r.mex.throttleReceive(frame); err != nil {
+		r.mex.framePool.Release(frame)
+		return nil, r.failed(err)
+	}
 
 	// Parse the message and setup the fragment
 	fragment, err := parseInboundFragment(r.mex.framePool, frame, message)
@@ -274,6 +332,9 @@ func (r *reqResReader) failed(err error) error {
 
 	r.mex.shutdown()
 	r.err = err
+	if r.onFailed != nil {
+		r.onFailed(err)
+	}
 	return r.err
 }
 
@@ -287,8 +348,12 @@ func parseInboundFragment(framePool FramePool, frame *Frame, message message) (*
 	}
 
 	fragment.checksumType = ChecksumType(rbuf.ReadSingleByte())
+	if !checksumRegistered[fragment.checksumType] {
+		return nil, NewSystemError(ErrCodeProtocol, "unknown checksum type %v", fragment.checksumType)
+	}
 	fragment.checksum = rbuf.ReadBytes(fragment.checksumType.ChecksumSize())
 	fragment.contents = rbuf
+	fragment.jumbo = frame.Header.isJumbo()
 	fragment.onDone = func() {
 		framePool.Release(frame)
 	}
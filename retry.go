@@ -65,6 +65,13 @@ type RequestState struct {
 	// Attempt is 1 for the first attempt, and so on.
 	Attempt   int
 	retryOpts *RetryOptions
+
+	// lastSelected is the host:port of the peer selected in the last attempt.
+	lastSelected string
+
+	// hedgedPeers is set while the attempt is hedged, and records the peers
+	// selected by the attempt.
+	hedgedPeers *hedgedPeers
 }
 
 // RetriableFunc is the type of function that can be passed to RunWithRetry.
@@ -86,10 +93,15 @@ func getErrCode(err error) SystemErrCode {
 }
 
 // CanRetry returns whether an error can be retried for the given retry option.
+// Errors that the server marked as non-retryable are never retried.
 func (r RetryOn) CanRetry(err error) bool {
 	if r == RetryNever {
 		return false
 	}
+	// The server has told us that this call must not be retried.
+	if se, ok := findSystemError(err); ok && se.NoRetry() {
+		return false
+	}
 	if r == RetryDefault {
 		r = RetryConnectionError
 	}
@@ -128,8 +140,18 @@ type RetryOptions struct {
 	// TimeoutPerAttempt is the per-retry timeout to use.
 	// If this is zero, then the original timeout is used.
 	TimeoutPerAttempt time.Duration
+
+	// PeerSelector, if set, is used to select the peer for retry attempts
+	// made through a SubChannel.
+	PeerSelector RetryPeerSelector
 }
 
+// RetryPeerSelector customizes the peer selected for a retry attempt.
+// It is passed the peers that have not been selected by previous attempts,
+// ordered by the peer list's preference. If it returns nil, the default
+// peer selection is used.
+type RetryPeerSelector func(rs *RequestState, candidates []*Peer) *Peer
+
 var defaultRetryOptions = &RetryOptions{
 	MaxAttempts: 5,
 }
@@ -138,15 +160,21 @@ var requestStatePool = sync.Pool{
 	New: func() interface{} { return &RequestState{} },
 }
 
-func getRetryOptions(ctx context.Context) *RetryOptions {
+// getRetryOptions returns the RetryOptions set in the context, or the given
+// defaults if the context does not set any.
+func getRetryOptions(ctx context.Context, defaults *RetryOptions) *RetryOptions {
+	if defaults == nil {
+		defaults = defaultRetryOptions
+	}
+
 	params := getTChannelParams(ctx)
 	if params == nil {
-		return defaultRetryOptions
+		return defaults
 	}
 
 	opts := params.retryOptions
 	if opts == nil {
-		return defaultRetryOptions
+		return defaults
 	}
 
 	if opts.MaxAttempts == 0 {
@@ -155,6 +183,49 @@ func getRetryOptions(ctx context.Context) *RetryOptions {
 	return opts
 }
 
+// copyRetryOptions returns a copy of the given options with MaxAttempts
+// defaulted, so they can be used as the defaults for RunWithRetry.
+func copyRetryOptions(opts *RetryOptions) *RetryOptions {
+	if opts == nil {
+		return nil
+	}
+
+	copied := *opts
+	if copied.MaxAttempts == 0 {
+		copied.MaxAttempts = defaultRetryOptions.MaxAttempts
+	}
+	return &copied
+}
+
+// ResolveRetryOptions returns the effective RetryOptions for a call made with
+// the given CallOptions. Options from the call's RequestState (which is set
+// by RunWithRetry using the RetryOptions in the context) take precedence, and
+// unset fields are filled in with the defaults. It does not modify callOpts.
+func ResolveRetryOptions(callOpts *CallOptions) RetryOptions {
+	opts := defaultRetryOptions
+	if callOpts != nil && callOpts.RequestState != nil && callOpts.RequestState.retryOpts != nil {
+		opts = callOpts.RequestState.retryOpts
+	}
+	return resolveRetryOptions(opts)
+}
+
+// resolveRetryOptions returns a copy of opts with unset fields filled in with
+// the defaults.
+func resolveRetryOptions(opts *RetryOptions) RetryOptions {
+	resolved := *defaultRetryOptions
+	if opts != nil {
+		resolved = *opts
+	}
+
+	if resolved.MaxAttempts == 0 {
+		resolved.MaxAttempts = defaultRetryOptions.MaxAttempts
+	}
+	if resolved.RetryOn == RetryDefault {
+		resolved.RetryOn = RetryConnectionError
+	}
+	return resolved
+}
+
 // HasRetries will return true if there are more retries left.
 func (rs *RequestState) HasRetries(err error) bool {
 	if rs == nil {
@@ -187,6 +258,10 @@ func (rs *RequestState) AddSelectedPeer(hostPort string) {
 		return
 	}
 
+	rs.lastSelected = hostPort
+	if rs.hedgedPeers != nil {
+		rs.hedgedPeers.add(hostPort)
+	}
 	host := getHost(hostPort)
 	if rs.SelectedPeers == nil {
 		rs.SelectedPeers = map[string]struct{}{
@@ -208,22 +283,52 @@ func (rs *RequestState) RetryCount() int {
 }
 
 // RunWithRetry will take a function that makes the TChannel call, and will
-// rerun it as specifed in the RetryOptions in the Context.
+// rerun it as specifed in the RetryOptions in the Context, or the channel's
+// DefaultRetryOptions if the Context has none. If the Context's CallOptions
+// set MaxTotalDuration, attempts are also bounded by the time remaining, and
+// ErrTimeout is returned once it's exceeded. Retries are also limited by the
+// channel's RetryBudget.
 func (ch *Channel) RunWithRetry(runCtx context.Context, f RetriableFunc) error {
+	return ch.runWithRetry(runCtx, ch.defaultRetryOptions, ch.retryBudget, 0 /* hedgeDelay */, f)
+}
+
+func (ch *Channel) runWithRetry(runCtx context.Context, defaults *RetryOptions, budget *retryBudget, hedgeDelay time.Duration, f RetriableFunc) error {
 	var err error
 
-	opts := getRetryOptions(runCtx)
+	opts := getRetryOptions(runCtx, defaults)
 	rs := ch.getRequestState(opts)
 	defer requestStatePool.Put(rs)
 
+	var maxTotalDuration time.Duration
+	if callOpts := currentCallOptions(runCtx); callOpts != nil {
+		maxTotalDuration = callOpts.MaxTotalDuration
+	}
+
+	budget.recordRequest()
+
 	for i := 0; i < opts.MaxAttempts; i++ {
+		timeout := opts.TimeoutPerAttempt
+		if maxTotalDuration > 0 {
+			remaining := maxTotalDuration - rs.SinceStart(ch.timeNow(), 0)
+			if remaining <= 0 {
+				ch.log.WithFields(
+					LogField{"attempt", rs.Attempt},
+					LogField{"maxTotalDuration", maxTotalDuration},
+				).Info("Failed after exceeding the maximum total duration.")
+				return ErrTimeout
+			}
+			if timeout == 0 || remaining < timeout {
+				timeout = remaining
+			}
+		}
+
 		rs.Attempt++
 
-		if opts.TimeoutPerAttempt == 0 {
-			err = f(runCtx, rs)
+		if timeout == 0 {
+			err = ch.runAttempt(runCtx, rs, hedgeDelay, budget, f)
 		} else {
-			attemptCtx, cancel := context.WithTimeout(runCtx, opts.TimeoutPerAttempt)
-			err = f(attemptCtx, rs)
+			attemptCtx, cancel := context.WithTimeout(runCtx, timeout)
+			err = ch.runAttempt(attemptCtx, rs, hedgeDelay, budget, f)
 			cancel()
 		}
 
@@ -236,12 +341,29 @@ func (ch *Channel) RunWithRetry(runCtx context.Context, f RetriableFunc) error {
 			}
 			return err
 		}
+		if rs.Attempt < opts.MaxAttempts && !budget.tryRetry() {
+			ch.statsReporter.IncCounter("outbound.calls.retry-budget-exhausted", ch.commonStatsTags, 1)
+			if ch.log.Enabled(LogLevelInfo) {
+				ch.log.WithFields(ErrField(err)).Info("Failed after exhausting the retry budget.")
+			}
+			return err
+		}
 
 		ch.log.WithFields(
 			ErrField(err),
 			LogField{"attempt", rs.Attempt},
 			LogField{"maxAttempts", opts.MaxAttempts},
 		).Info("Retrying request after retryable error.")
+		if rs.Attempt < opts.MaxAttempts {
+			if t := ch.callTracer; t != nil {
+				t.RecordRetry(runCtx, rs.Attempt, err)
+			}
+			ch.eventBus.publish(Event{
+				Type:    EventRetry,
+				Err:     err,
+				Attempt: rs.Attempt,
+			})
+		}
 	}
 
 	// Too many retries, return the last error
@@ -0,0 +1,110 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sync"
+	"time"
+)
+
+// RetryBudgetOptions configure a budget that limits retries made by
+// RunWithRetry to a fraction of the original requests, so that retries do
+// not amplify load on a service that is already failing.
+type RetryBudgetOptions struct {
+	// MaxRetryRatio is the maximum ratio of retries to original requests
+	// within the Window. For example, 0.1 allows one retry for every 10
+	// requests. If this is zero (the default), retries are not limited.
+	MaxRetryRatio float64
+
+	// Window is the sliding window over which requests and retries are
+	// counted. Passing zero uses the default of 10s.
+	Window time.Duration
+
+	// MinRetries is the number of retries permitted within the Window
+	// regardless of MaxRetryRatio, so that retries are still possible when
+	// there are few requests.
+	MinRetries int
+}
+
+func (o RetryBudgetOptions) enabled() bool {
+	return o.MaxRetryRatio > 0
+}
+
+func (o RetryBudgetOptions) withDefaults() RetryBudgetOptions {
+	if o.Window <= 0 {
+		o.Window = 10 * time.Second
+	}
+	return o
+}
+
+// retryBudget tracks the requests and retries made within a sliding window,
+// and only permits a retry if it keeps retries within the configured ratio.
+type retryBudget struct {
+	opts    RetryBudgetOptions
+	timeNow func() time.Time
+
+	mut      sync.Mutex
+	requests rollingCounter
+	retries  rollingCounter
+}
+
+func newRetryBudget(timeNow func() time.Time, opts RetryBudgetOptions) *retryBudget {
+	if !opts.enabled() {
+		return nil
+	}
+	opts = opts.withDefaults()
+	return &retryBudget{
+		opts:     opts,
+		timeNow:  timeNow,
+		requests: newRollingCounter(opts.Window),
+		retries:  newRollingCounter(opts.Window),
+	}
+}
+
+// recordRequest records an original (non-retry) request.
+func (b *retryBudget) recordRequest() {
+	if b == nil {
+		return
+	}
+
+	b.mut.Lock()
+	b.requests.inc(b.timeNow())
+	b.mut.Unlock()
+}
+
+// tryRetry returns whether a retry is permitted by the budget, and if so,
+// records the retry.
+func (b *retryBudget) tryRetry() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	now := b.timeNow()
+	requests, retries := b.requests.count(now), b.retries.count(now)
+	if float64(retries+1) > b.opts.MaxRetryRatio*float64(requests)+float64(b.opts.MinRetries) {
+		return false
+	}
+	b.retries.inc(now)
+	return true
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/uber/tchannel-go/testutils"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
 )
 
@@ -81,3 +82,87 @@ func TestRequestStateRetry(t *testing.T) {
 		assert.Equal(t, 5, counter, "RunWithRetry should retry 5 times")
 	})
 }
+
+func TestRetryDistinctPeers(t *testing.T) {
+	tests := []struct {
+		msg         string
+		maxAttempts int
+		selector    func(t *testing.T, attempts *[]int) RetryPeerSelector
+	}{
+		{
+			msg:         "each retry uses a new peer",
+			maxAttempts: 3,
+		},
+		{
+			msg:         "last peer is not reselected after all peers are tried",
+			maxAttempts: 4,
+		},
+		{
+			msg:         "custom selector picks the least preferred candidate",
+			maxAttempts: 3,
+			selector: func(t *testing.T, candidateCounts *[]int) RetryPeerSelector {
+				return func(rs *RequestState, candidates []*Peer) *Peer {
+					*candidateCounts = append(*candidateCounts, len(candidates))
+					for _, p := range candidates {
+						_, ok := rs.SelectedPeers[p.HostPort()]
+						assert.False(t, ok, "Candidate %v was previously selected", p.HostPort())
+					}
+					return candidates[len(candidates)-1]
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		opts := testutils.NewOpts().NoRelay()
+		testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+			var attempted []string
+			client := ts.NewClient(nil)
+			sc := client.GetSubChannel(ts.ServiceName(), Isolated)
+
+			for i := 0; i < 3; i++ {
+				server := ts.NewServer(testutils.NewOpts().SetServiceName(ts.ServiceName()))
+				hostPort := server.PeerInfo().HostPort
+				testutils.RegisterFunc(server, "busy", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+					return &raw.Res{SystemErr: ErrServerBusy}, nil
+				})
+				sc.Peers().Add(hostPort)
+			}
+
+			var candidateCounts []int
+			retryOpts := &RetryOptions{MaxAttempts: tt.maxAttempts}
+			if tt.selector != nil {
+				retryOpts.PeerSelector = tt.selector(t, &candidateCounts)
+			}
+
+			ctx, cancel := NewContextBuilder(time.Second).SetRetryOptions(retryOpts).Build()
+			defer cancel()
+
+			err := client.RunWithRetry(ctx, func(ctx context.Context, rs *RequestState) error {
+				call, err := sc.BeginCall(ctx, "busy", &CallOptions{RequestState: rs})
+				if err != nil {
+					return err
+				}
+				attempted = append(attempted, call.RemotePeer().HostPort)
+				_, _, _, err = raw.WriteArgs(call, nil, nil)
+				return err
+			})
+			assert.Equal(t, ErrServerBusy, err, "%v: expected busy error", tt.msg)
+			require.Len(t, attempted, tt.maxAttempts, "%v: unexpected number of attempts", tt.msg)
+
+			seen := make(map[string]struct{})
+			for i, hostPort := range attempted[:3] {
+				_, ok := seen[hostPort]
+				assert.False(t, ok, "%v: attempt %v reselected peer %v", tt.msg, i+1, hostPort)
+				seen[hostPort] = struct{}{}
+			}
+			for i := 1; i < len(attempted); i++ {
+				assert.NotEqual(t, attempted[i-1], attempted[i], "%v: attempt %v reselected the last peer", tt.msg, i+1)
+			}
+
+			if tt.selector != nil {
+				assert.Equal(t, []int{2, 1}, candidateCounts, "%v: unexpected candidates passed to selector", tt.msg)
+			}
+		})
+	}
+}
@@ -21,16 +21,19 @@
 package tchannel_test
 
 import (
+	"errors"
 	"net"
 	"testing"
 	"time"
 
 	. "github.com/uber/tchannel-go"
 
+	"github.com/uber/tchannel-go/raw"
 	"github.com/uber/tchannel-go/testutils"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/uber-go/atomic"
 	"golang.org/x/net/context"
 )
 
@@ -164,6 +167,38 @@ func TestRetryTillMaxAttempts(t *testing.T) {
 	}
 }
 
+func TestRetryDefaultOptions(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.DefaultRetryOptions = &RetryOptions{MaxAttempts: 2}
+	ch := testutils.NewClient(t, opts)
+	defer ch.Close()
+
+	sc := ch.GetSubChannel("svc", DefaultRetryOptions(&RetryOptions{RetryOn: RetryNever}))
+	errs := []error{ErrServerBusy, ErrServerBusy, ErrServerBusy, nil}
+
+	ctx, cancel := NewContext(time.Second)
+	defer cancel()
+
+	f, counter := createFuncToRetry(t, errs...)
+	assert.Equal(t, ErrServerBusy, ch.RunWithRetry(ctx, f), "Unexpected error")
+	assert.Equal(t, 2, *counter, "Channel should use its DefaultRetryOptions")
+
+	f, counter = createFuncToRetry(t, errs...)
+	assert.Equal(t, ErrServerBusy, sc.RunWithRetry(ctx, f), "Unexpected error")
+	assert.Equal(t, 1, *counter, "Subchannel should use its DefaultRetryOptions")
+
+	f, counter = createFuncToRetry(t, errs...)
+	assert.Equal(t, ErrServerBusy, ch.GetSubChannel("other").RunWithRetry(ctx, f), "Unexpected error")
+	assert.Equal(t, 2, *counter, "Subchannel without DefaultRetryOptions should use the channel's")
+
+	// RetryOptions in the context take precedence over the defaults.
+	ctx, cancel = NewContextBuilder(time.Second).SetRetryOptions(&RetryOptions{MaxAttempts: 4}).Build()
+	defer cancel()
+	f, counter = createFuncToRetry(t, errs...)
+	assert.NoError(t, sc.RunWithRetry(ctx, f), "Call should succeed after retries")
+	assert.Equal(t, 4, *counter, "Context RetryOptions should be used")
+}
+
 func TestRetrySubContextNoTimeoutPerAttempt(t *testing.T) {
 	e := getTestErrors()
 	ctx, cancel := NewContext(time.Second)
@@ -241,6 +276,93 @@ func TestRetryNetConnect(t *testing.T) {
 	assert.Equal(t, 5, counter, "RunWithRetry should have run f 5 times")
 }
 
+func TestRetryNoRetryHint(t *testing.T) {
+	tests := []struct {
+		msg          string
+		handlerErr   error
+		wantAttempts int
+		wantNoRetry  bool
+	}{
+		{
+			msg:          "retryable error",
+			handlerErr:   ErrServerBusy,
+			wantAttempts: 5,
+		},
+		{
+			msg:          "retryable error with no-retry hint",
+			handlerErr:   NewNoRetryError(ErrServerBusy),
+			wantAttempts: 1,
+			wantNoRetry:  true,
+		},
+		{
+			msg:          "unexpected error with no-retry hint",
+			handlerErr:   NewNoRetryError(errors.New("validation failed")),
+			wantAttempts: 1,
+			wantNoRetry:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			opts := testutils.NewOpts().AddLogFilter("Unexpected handler error", 1)
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				var handlerCalls atomic.Int32
+				testutils.RegisterFunc(ts.Server(), "fail", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+					handlerCalls.Inc()
+					return nil, tt.handlerErr
+				})
+
+				client := ts.NewClient(nil)
+				retryOpts := &RetryOptions{RetryOn: RetryIdempotent}
+				ctx, cancel := NewContextBuilder(testutils.Timeout(time.Second)).SetRetryOptions(retryOpts).Build()
+				defer cancel()
+
+				err := client.RunWithRetry(ctx, func(ctx context.Context, rs *RequestState) error {
+					_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "fail", nil, nil)
+					return err
+				})
+				require.Error(t, err, "Call should fail")
+				assert.Equal(t, GetSystemErrorCode(tt.handlerErr), GetSystemErrorCode(err), "Unexpected error code")
+
+				se, ok := err.(SystemError)
+				require.True(t, ok, "Expected SystemError, got %T", err)
+				assert.Equal(t, tt.wantNoRetry, se.NoRetry(), "Unexpected no-retry hint")
+				assert.EqualValues(t, tt.wantAttempts, handlerCalls.Load(), "Unexpected number of attempts")
+			})
+		})
+	}
+}
+
+func TestRetryMaxTotalDuration(t *testing.T) {
+	const attemptDuration = 300 * time.Millisecond
+
+	clock := testutils.NewStubClock(time.Now())
+	ch := testutils.NewClient(t, testutils.NewOpts().SetTimeNow(clock.Now))
+	defer ch.Close()
+
+	retryOpts := &RetryOptions{MaxAttempts: 10, RetryOn: RetryIdempotent}
+	ctx, cancel := NewContextBuilder(testutils.Timeout(time.Second)).
+		SetRetryOptions(retryOpts).
+		SetMaxTotalDuration(time.Second).
+		Build()
+	defer cancel()
+
+	var attempts int
+	err := ch.RunWithRetry(ctx, func(ctx context.Context, rs *RequestState) error {
+		attempts++
+		remaining := time.Second - time.Duration(attempts-1)*attemptDuration
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok, "Expected attempt to have a deadline")
+		assert.True(t, deadline.Sub(time.Now()) <= remaining,
+			"Attempt %v deadline should be bounded by the remaining duration %v", attempts, remaining)
+
+		clock.Elapse(attemptDuration)
+		return ErrServerBusy
+	})
+	assert.Equal(t, ErrTimeout, err, "Expected timeout once the maximum total duration is exceeded")
+	assert.Equal(t, 4, attempts, "Unexpected number of attempts")
+}
+
 func TestRequestStateSince(t *testing.T) {
 	baseTime := time.Date(2015, 1, 2, 3, 4, 5, 6, time.UTC)
 	tests := []struct {
@@ -268,3 +390,194 @@ func TestRequestStateSince(t *testing.T) {
 			tt.requestState, tt.now, tt.fallback, tt.expected, got)
 	}
 }
+
+func TestResolveRetryOptions(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	tests := []struct {
+		msg       string
+		retryOpts *RetryOptions
+		want      RetryOptions
+	}{
+		{
+			msg:  "no retry options in context",
+			want: RetryOptions{MaxAttempts: 5, RetryOn: RetryConnectionError},
+		},
+		{
+			msg:       "empty retry options use defaults",
+			retryOpts: &RetryOptions{},
+			want:      RetryOptions{MaxAttempts: 5, RetryOn: RetryConnectionError},
+		},
+		{
+			msg:       "unset max attempts uses default",
+			retryOpts: &RetryOptions{RetryOn: RetryNever, TimeoutPerAttempt: time.Millisecond},
+			want:      RetryOptions{MaxAttempts: 5, RetryOn: RetryNever, TimeoutPerAttempt: time.Millisecond},
+		},
+		{
+			msg:       "context options override defaults",
+			retryOpts: &RetryOptions{MaxAttempts: 2, RetryOn: RetryIdempotent, TimeoutPerAttempt: time.Second},
+			want:      RetryOptions{MaxAttempts: 2, RetryOn: RetryIdempotent, TimeoutPerAttempt: time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			ctx, cancel := NewContextBuilder(time.Second).SetRetryOptions(tt.retryOpts).Build()
+			defer cancel()
+
+			var got RetryOptions
+			err := ch.RunWithRetry(ctx, func(_ context.Context, rs *RequestState) error {
+				got = ResolveRetryOptions(&CallOptions{RequestState: rs})
+				return nil
+			})
+			require.NoError(t, err, "RunWithRetry failed")
+			assert.Equal(t, tt.want, got, "Unexpected resolved retry options")
+		})
+	}
+}
+
+func TestResolveRetryOptionsWithoutRequestState(t *testing.T) {
+	want := RetryOptions{MaxAttempts: 5, RetryOn: RetryConnectionError}
+	assert.Equal(t, want, ResolveRetryOptions(nil), "Unexpected options for nil CallOptions")
+	assert.Equal(t, want, ResolveRetryOptions(&CallOptions{}), "Unexpected options without RequestState")
+}
+
+func TestRetryBudget(t *testing.T) {
+	clock := testutils.NewStubClock(time.Now())
+	stats := newRecordingStatsReporter()
+	opts := testutils.NewOpts().
+		SetTimeNow(clock.Now).
+		SetStatsReporter(stats)
+	opts.RetryBudget = RetryBudgetOptions{
+		MaxRetryRatio: 0.1,
+		Window:        10 * time.Second,
+	}
+	ch := testutils.NewClient(t, opts)
+	defer ch.Close()
+
+	ctx, cancel := NewContextBuilder(time.Second).SetRetryOptions(&RetryOptions{MaxAttempts: 2}).Build()
+	defer cancel()
+
+	runCall := func(errs ...error) (int, error) {
+		f, counter := createFuncToRetry(t, errs...)
+		err := ch.RunWithRetry(ctx, f)
+		return *counter, err
+	}
+
+	// With fewer than 10 requests, there's no budget for a retry.
+	for i := 0; i < 9; i++ {
+		attempts, err := runCall(nil)
+		require.NoError(t, err, "Call should succeed")
+		require.Equal(t, 1, attempts, "Unexpected attempts")
+	}
+	attempts, err := runCall(ErrServerBusy, nil)
+	assert.NoError(t, err, "10th request allows a single retry")
+	assert.Equal(t, 2, attempts, "Expected call to be retried")
+
+	attempts, err = runCall(ErrServerBusy, nil)
+	assert.Equal(t, ErrServerBusy, err, "Expected original error once the budget is exhausted")
+	assert.Equal(t, 1, attempts, "Call should not be retried once the budget is exhausted")
+	assert.EqualValues(t, 1, stats.getCount("outbound.calls.retry-budget-exhausted", ch.StatsTags()),
+		"Unexpected retries denied by budget")
+
+	// Once the window has passed, the previous requests and retries expire.
+	clock.Elapse(11 * time.Second)
+	for i := 0; i < 10; i++ {
+		runCall(nil)
+	}
+	attempts, err = runCall(ErrServerBusy, nil)
+	assert.NoError(t, err, "Budget should be available after the window")
+	assert.Equal(t, 2, attempts, "Expected call to be retried")
+}
+
+func TestRetryBudgetMinRetries(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.RetryBudget = RetryBudgetOptions{
+		MaxRetryRatio: 0.1,
+		MinRetries:    2,
+	}
+	ch := testutils.NewClient(t, opts)
+	defer ch.Close()
+
+	ctx, cancel := NewContextBuilder(time.Second).SetRetryOptions(&RetryOptions{MaxAttempts: 5}).Build()
+	defer cancel()
+
+	f, counter := createFuncToRetry(t, ErrServerBusy, ErrServerBusy, ErrServerBusy, nil)
+	assert.Equal(t, ErrServerBusy, ch.RunWithRetry(ctx, f), "Expected original error once the budget is exhausted")
+	assert.Equal(t, 3, *counter, "MinRetries should permit retries with few requests")
+}
+
+func TestSubChannelRetryBudget(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	sc := ch.GetSubChannel("svc", RetryBudget(RetryBudgetOptions{MaxRetryRatio: 0.1}))
+
+	ctx, cancel := NewContextBuilder(time.Second).SetRetryOptions(&RetryOptions{MaxAttempts: 2}).Build()
+	defer cancel()
+
+	f, counter := createFuncToRetry(t, ErrServerBusy, nil)
+	assert.Equal(t, ErrServerBusy, sc.RunWithRetry(ctx, f), "Expected subchannel budget to deny the retry")
+	assert.Equal(t, 1, *counter, "Call should not be retried")
+
+	// The channel has no budget, so its calls are still retried.
+	f, counter = createFuncToRetry(t, ErrServerBusy, nil)
+	assert.NoError(t, ch.RunWithRetry(ctx, f), "Channel call should be retried")
+	assert.Equal(t, 2, *counter, "Expected call to be retried")
+}
+
+func TestHedgedRequests(t *testing.T) {
+	stats := newRecordingStatsReporter()
+	ch := testutils.NewClient(t, testutils.NewOpts().SetStatsReporter(stats))
+	defer ch.Close()
+
+	sc := ch.GetSubChannel("svc", HedgeDelay(10*time.Millisecond))
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	var attempts atomic.Int32
+	primaryCancelled := make(chan struct{})
+	err := sc.RunWithRetry(ctx, func(ctx context.Context, rs *RequestState) error {
+		if attempts.Inc() == 1 {
+			rs.AddSelectedPeer("1.1.1.1:1")
+			<-ctx.Done()
+			close(primaryCancelled)
+			return ctx.Err()
+		}
+
+		assert.Contains(t, rs.PrevSelectedPeers(), "1.1.1.1:1", "Hedged attempt should avoid the primary's peer")
+		return nil
+	})
+	require.NoError(t, err, "Hedged call should succeed")
+	assert.EqualValues(t, 2, attempts.Load(), "Expected a hedged attempt")
+	select {
+	case <-primaryCancelled:
+	default:
+		t.Errorf("Primary attempt should be cancelled and complete before RunWithRetry returns")
+	}
+	assert.EqualValues(t, 1, stats.getCount("outbound.calls.hedged", ch.StatsTags()), "Unexpected hedged calls")
+}
+
+func TestHedgedRequestsBudget(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	sc := ch.GetSubChannel("svc",
+		HedgeDelay(time.Millisecond),
+		RetryBudget(RetryBudgetOptions{MaxRetryRatio: 0.1}),
+	)
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	var attempts atomic.Int32
+	err := sc.RunWithRetry(ctx, func(ctx context.Context, rs *RequestState) error {
+		attempts.Inc()
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	require.NoError(t, err, "Call should succeed")
+	assert.EqualValues(t, 1, attempts.Load(), "Budget should prevent hedging")
+}
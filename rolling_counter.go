@@ -0,0 +1,78 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import "time"
+
+// _rollingCounterBuckets is the number of buckets a rollingCounter's window
+// is split into. Counts expire one bucket at a time as the window slides.
+const _rollingCounterBuckets = 10
+
+type rollingCounterBucket struct {
+	start time.Time
+	count int
+}
+
+// rollingCounter counts events within a sliding window. It's not safe for
+// concurrent use.
+type rollingCounter struct {
+	window         time.Duration
+	bucketDuration time.Duration
+	buckets        [_rollingCounterBuckets]rollingCounterBucket
+}
+
+func newRollingCounter(window time.Duration) rollingCounter {
+	bucketDuration := window / _rollingCounterBuckets
+	if bucketDuration <= 0 {
+		bucketDuration = 1
+	}
+	return rollingCounter{
+		window:         window,
+		bucketDuration: bucketDuration,
+	}
+}
+
+// inc records an event at now.
+func (c *rollingCounter) inc(now time.Time) {
+	start := now.Truncate(c.bucketDuration)
+	bucket := &c.buckets[(start.UnixNano()/int64(c.bucketDuration))%_rollingCounterBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = rollingCounterBucket{start: start}
+	}
+	bucket.count++
+}
+
+// count returns the number of events within the window ending at now.
+func (c *rollingCounter) count(now time.Time) int {
+	cutoff := now.Add(-c.window)
+	var total int
+	for _, bucket := range c.buckets {
+		if bucket.start.After(cutoff) {
+			total += bucket.count
+		}
+	}
+	return total
+}
+
+// reset clears all recorded events.
+func (c *rollingCounter) reset() {
+	c.buckets = [_rollingCounterBuckets]rollingCounterBucket{}
+}
@@ -20,22 +20,40 @@
 
 package tchannel
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
+
+// rootPeerListOptions configure a RootPeerList, and the peers it creates.
+type rootPeerListOptions struct {
+	onPeerStatusChanged func(*Peer)
+	timeNow             func() time.Time
+	outboundPause       *outboundPause
+	unhealthyCooldown   time.Duration
+	peerSelection       PeerSelection
+	selectionStrategy   PeerSelectionStrategy
+	circuitBreaker      *circuitBreaker
+	latencyHalfLife     time.Duration
+	connLimits          peerConnLimits
+	bandwidth           *channelBandwidth
+	events              *eventBus
+}
 
 // RootPeerList is the root peer list which is only used to connect to
 // peers and share peers between subchannels.
 type RootPeerList struct {
 	sync.RWMutex
+	rootPeerListOptions
 
-	channel             Connectable
-	onPeerStatusChanged func(*Peer)
-	peersByHostPort     map[string]*Peer
+	channel         Connectable
+	peersByHostPort map[string]*Peer
 }
 
-func newRootPeerList(ch Connectable, onPeerStatusChanged func(*Peer)) *RootPeerList {
+func newRootPeerList(ch Connectable, opts rootPeerListOptions) *RootPeerList {
 	return &RootPeerList{
+		rootPeerListOptions: opts,
 		channel:             ch,
-		onPeerStatusChanged: onPeerStatusChanged,
 		peersByHostPort:     make(map[string]*Peer),
 	}
 }
@@ -58,17 +76,32 @@ func (l *RootPeerList) Add(hostPort string) *Peer {
 
 	l.RUnlock()
 	l.Lock()
-	defer l.Unlock()
 
 	if p, ok := l.peersByHostPort[hostPort]; ok {
+		l.Unlock()
 		return p
 	}
 
 	var p *Peer
 	// To avoid duplicate connections, only the root list should create new
 	// peers. All other lists should keep refs to the root list's peers.
-	p = newPeer(l.channel, hostPort, l.onPeerStatusChanged, l.onClosedConnRemoved)
+	p = newPeer(l.channel, hostPort, l.onPeerStatusChanged, l.onClosedConnRemoved, l.timeNow, l.outboundPause)
+	p.latency.halfLife = l.latencyHalfLife
+	p.errorRate.halfLife = l.latencyHalfLife
+	p.connLimits = l.connLimits
+	p.selectionStrategy = l.selectionStrategy
+	p.circuitBreaker = l.circuitBreaker
+	p.bandwidth = l.bandwidth.newPeer()
 	l.peersByHostPort[hostPort] = p
+	l.Unlock()
+
+	// Publish the event without holding the lock, since subscribers may use
+	// the peer list.
+	l.events.publish(Event{
+		Type:     EventPeerAdded,
+		HostPort: hostPort,
+		Peer:     p,
+	})
 	return p
 }
 
@@ -0,0 +1,137 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ChannelRuntimeStats are live counters for a channel's connections, grouped
+// by the remote peer, which are cheaper to collect than the full RuntimeState.
+type ChannelRuntimeStats struct {
+	// Peers is the stats for each remote peer, keyed by host:port.
+	Peers map[string]PeerRuntimeStats `json:"peers"`
+}
+
+// PeerRuntimeStats are the counters for all connections to a remote peer.
+type PeerRuntimeStats struct {
+	FramesSent     uint64 `json:"framesSent"`
+	FramesReceived uint64 `json:"framesReceived"`
+	BytesSent      uint64 `json:"bytesSent"`
+	BytesReceived  uint64 `json:"bytesReceived"`
+	PendingCalls   int    `json:"pendingCalls"`
+
+	Connections []ConnectionRuntimeStats `json:"connections"`
+}
+
+// ConnectionRuntimeStats are the counters for a single connection.
+type ConnectionRuntimeStats struct {
+	ID              uint32                  `json:"id"`
+	ConnectionState string                  `json:"connectionState"`
+	Direction       string                  `json:"direction"`
+	ProtocolStats   ConnectionProtocolStats `json:"protocolStats"`
+	PendingInbound  int                     `json:"pendingInbound"`
+	PendingOutbound int                     `json:"pendingOutbound"`
+
+	// SendBufferUsed is the number of frames waiting in the send buffer,
+	// which holds up to SendBufferSize frames.
+	SendBufferUsed int `json:"sendBufferUsed"`
+	SendBufferSize int `json:"sendBufferSize"`
+
+	// LastError is the last error seen on the connection, either a
+	// connection error or an error frame received from the peer.
+	LastError     string    `json:"lastError,omitempty"`
+	LastErrorTime time.Time `json:"lastErrorTime,omitempty"`
+}
+
+// connLastError is the last error seen on a connection.
+type connLastError struct {
+	message string
+	at      time.Time
+}
+
+// recordError records err as the last error seen on the connection.
+func (c *Connection) recordError(err error) {
+	c.lastError.Store(connLastError{message: err.Error(), at: c.timeNow()})
+}
+
+// RuntimeStats returns the counters for the connection.
+func (c *Connection) RuntimeStats() ConnectionRuntimeStats {
+	stats := ConnectionRuntimeStats{
+		ID:              c.connID,
+		ConnectionState: c.readState().String(),
+		Direction:       c.connDirection.String(),
+		ProtocolStats:   c.protocolStats.snapshot(),
+		PendingInbound:  c.inbound.count(),
+		PendingOutbound: c.outbound.count(),
+		SendBufferUsed:  len(c.sendCh),
+		SendBufferSize:  cap(c.sendCh),
+	}
+	if lastErr, ok := c.lastError.Load().(connLastError); ok {
+		stats.LastError = lastErr.message
+		stats.LastErrorTime = lastErr.at
+	}
+	return stats
+}
+
+// RuntimeStats returns live counters for each of the channel's connections,
+// grouped by the remote peer.
+func (ch *Channel) RuntimeStats() ChannelRuntimeStats {
+	peers := make(map[string]PeerRuntimeStats)
+	for _, c := range ch.connections() {
+		connStats := c.RuntimeStats()
+		hostPort := c.RemotePeerInfo().HostPort
+
+		peer := peers[hostPort]
+		for _, n := range connStats.ProtocolStats.FramesSent {
+			peer.FramesSent += n
+		}
+		for _, n := range connStats.ProtocolStats.FramesReceived {
+			peer.FramesReceived += n
+		}
+		peer.BytesSent += connStats.ProtocolStats.BytesSent
+		peer.BytesReceived += connStats.ProtocolStats.BytesReceived
+		peer.PendingCalls += connStats.PendingInbound + connStats.PendingOutbound
+		peer.Connections = append(peer.Connections, connStats)
+		peers[hostPort] = peer
+	}
+
+	for _, peer := range peers {
+		sort.Slice(peer.Connections, func(i, j int) bool {
+			return peer.Connections[i].ID < peer.Connections[j].ID
+		})
+	}
+	return ChannelRuntimeStats{Peers: peers}
+}
+
+// RuntimeStatsHandler returns an http.Handler that serves the channel's
+// RuntimeStats as JSON.
+func (ch *Channel) RuntimeStatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(ch.RuntimeStats()); err != nil {
+			ch.log.WithFields(ErrField(err)).Warn("Failed to write runtime stats.")
+		}
+	})
+}
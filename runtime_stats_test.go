@@ -0,0 +1,80 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	json_encoding "encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestRuntimeStats(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+		testutils.RegisterFunc(ts.Server(), "busy", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return nil, ErrServerBusy
+		})
+
+		client := ts.NewClient(nil)
+		require.NoError(t, testutils.CallEcho(client, ts.HostPort(), ts.ServiceName(), nil), "Echo failed")
+
+		stats := client.RuntimeStats()
+		require.Contains(t, stats.Peers, ts.HostPort(), "Missing server peer")
+		peer := stats.Peers[ts.HostPort()]
+		require.Len(t, peer.Connections, 1, "Expected a single connection")
+		conn := peer.Connections[0]
+		assert.Equal(t, "outbound", conn.Direction, "Unexpected direction")
+		assert.Equal(t, uint64(1), conn.ProtocolStats.FramesSent["call-req"], "Unexpected call-req frames sent")
+		assert.True(t, peer.FramesSent > 0 && peer.BytesSent > 0, "Expected frames sent to the peer")
+		assert.True(t, peer.FramesReceived > 0 && peer.BytesReceived > 0, "Expected frames received from the peer")
+		assert.Equal(t, 0, peer.PendingCalls, "Unexpected pending calls")
+		assert.True(t, conn.SendBufferSize > 0, "Expected send buffer size")
+		assert.Empty(t, conn.LastError, "Unexpected last error")
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "busy", nil, nil)
+		require.Error(t, err, "Expected busy call to fail")
+
+		rec := httptest.NewRecorder()
+		client.RuntimeStatsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		require.Equal(t, http.StatusOK, rec.Code, "Unexpected status: %s", rec.Body)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"), "Unexpected content type")
+
+		var served ChannelRuntimeStats
+		require.NoError(t, json_encoding.Unmarshal(rec.Body.Bytes(), &served), "Failed to decode stats")
+		conn = served.Peers[ts.HostPort()].Connections[0]
+		assert.Contains(t, conn.LastError, "busy", "Expected the error frame to be the last error")
+		assert.False(t, conn.LastErrorTime.IsZero(), "Missing last error time")
+	})
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sort"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// shutdownPollInterval is how often Shutdown checks for in-flight calls.
+const shutdownPollInterval = 10 * time.Millisecond
+
+// healthMethod is the method used for health checks, which are still served
+// while the channel is draining so that they can report it.
+const healthMethod = "Meta::health"
+
+// errChannelDraining is returned for new calls and connections while the
+// channel is shutting down. It uses ErrCodeDeclined so that callers retry the
+// call on another peer.
+var errChannelDraining = NewSystemError(ErrCodeDeclined, "channel is draining")
+
+// Shutdown gracefully shuts down the channel. It stops accepting new inbound
+// calls (other than health checks) and connections, and stops dialing new
+// outbound connections. Once all in-flight inbound, outbound and relayed calls
+// have completed, the channel is closed.
+//
+// If ctx ends before the channel has closed, Shutdown returns the context's
+// error, and the channel keeps draining. Use Close to close it regardless of
+// in-flight calls.
+func (ch *Channel) Shutdown(ctx context.Context) error {
+	ch.Logger().Info("Channel.Shutdown called.")
+	ch.draining.Store(true)
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for ch.hasPendingCalls() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	ch.Close()
+	select {
+	case <-ch.ClosedChan():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// AbandonedCall describes a call that was still in progress when Drain's
+// deadline expired and its connection was forcibly closed.
+type AbandonedCall struct {
+	// ConnectionID is the ID of the connection the call was on.
+	ConnectionID uint32
+
+	// RemoteHostPort is the host:port of the remote peer of the connection.
+	RemoteHostPort string
+
+	// MessageID is the message ID of the call on the connection.
+	MessageID uint32
+
+	// Direction is "inbound", "outbound" or "relayed".
+	Direction string
+}
+
+// Drain gracefully drains the channel with a deadline. Like Shutdown, it stops
+// accepting new inbound calls and connections, and it also signals the remote
+// side of every connection that supports FeatureDrainSignal to stop starting
+// new calls on it. It then waits for in-flight calls to complete.
+//
+// If ctx ends before all calls have completed, the remaining connections are
+// closed forcibly, which fails their calls, and Drain returns the calls that
+// were abandoned along with the context's error.
+func (ch *Channel) Drain(ctx context.Context) ([]AbandonedCall, error) {
+	ch.Logger().Info("Channel.Drain called.")
+	ch.draining.Store(true)
+
+	for _, c := range ch.connections() {
+		c.sendDrainSignal()
+	}
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for ch.hasPendingCalls() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			abandoned := ch.forceClose()
+			return abandoned, ctx.Err()
+		}
+	}
+
+	ch.Close()
+	select {
+	case <-ch.ClosedChan():
+		return nil, nil
+	case <-ctx.Done():
+		return ch.forceClose(), ctx.Err()
+	}
+}
+
+// forceClose closes the channel, failing any calls still in progress on its
+// connections, and returns the calls that were abandoned.
+func (ch *Channel) forceClose() []AbandonedCall {
+	var abandoned []AbandonedCall
+	conns := ch.connections()
+	for _, c := range conns {
+		abandoned = append(abandoned, c.pendingCalls()...)
+	}
+	for _, c := range conns {
+		c.forceClose()
+	}
+	ch.Close()
+
+	sort.Slice(abandoned, func(i, j int) bool {
+		if abandoned[i].ConnectionID != abandoned[j].ConnectionID {
+			return abandoned[i].ConnectionID < abandoned[j].ConnectionID
+		}
+		return abandoned[i].MessageID < abandoned[j].MessageID
+	})
+	return abandoned
+}
+
+// connections returns a snapshot of the channel's connections.
+func (ch *Channel) connections() []*Connection {
+	ch.mutable.RLock()
+	defer ch.mutable.RUnlock()
+
+	conns := make([]*Connection, 0, len(ch.mutable.conns))
+	for _, c := range ch.mutable.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// sendDrainSignal tells the remote peer that this side is draining, so it
+// stops starting new calls on the connection. The signal is a connection-level
+// error frame with ErrCodeDeclined, so it's only sent to peers that support
+// FeatureDrainSignal, as other peers would treat it as an error.
+func (c *Connection) sendDrainSignal() {
+	if !c.IsActive() || !c.SupportsFeature(FeatureDrainSignal) {
+		return
+	}
+	if err := c.SendSystemError(invalidMessageID, Span{}, errChannelDraining); err != nil {
+		c.log.WithFields(ErrField(err)).Info("Failed to send drain signal.")
+	}
+}
+
+// pendingCalls returns the calls that are in progress on the connection.
+func (c *Connection) pendingCalls() []AbandonedCall {
+	remoteHostPort := c.RemotePeerInfo().HostPort
+	var calls []AbandonedCall
+	add := func(direction string, ids []uint32) {
+		for _, id := range ids {
+			calls = append(calls, AbandonedCall{
+				ConnectionID:   c.connID,
+				RemoteHostPort: remoteHostPort,
+				MessageID:      id,
+				Direction:      direction,
+			})
+		}
+	}
+
+	add("inbound", c.inbound.exchangeIDs())
+	add("outbound", c.outbound.exchangeIDs())
+	if c.relay != nil {
+		add("relayed", c.relay.inbound.callIDs())
+	}
+	return calls
+}
+
+// forceClose closes the connection without waiting for in-flight calls, which
+// are failed with errChannelDraining.
+func (c *Connection) forceClose() {
+	c.stopHealthCheck()
+	c.stopKeepAlive()
+	c.stopMaxAge()
+	c.close(LogField{"reason", "drain deadline exceeded"})
+
+	if c.stoppedExchanges.CAS(false, true) {
+		c.outbound.stopExchanges(errChannelDraining)
+		c.inbound.stopExchanges(errChannelDraining)
+	}
+
+	// checkExchanges will close the connection due to stoppedExchanges.
+	c.checkExchanges()
+}
+
+// exchangeIDs returns the IDs of the exchanges in the set.
+func (mexset *messageExchangeSet) exchangeIDs() []uint32 {
+	mexset.RLock()
+	_, exchanges := mexset.copyExchanges()
+	mexset.RUnlock()
+
+	ids := make([]uint32, 0, len(exchanges))
+	for id := range exchanges {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// callIDs returns the IDs of the calls being relayed, excluding tombstones.
+func (r *relayItems) callIDs() []uint32 {
+	r.RLock()
+	defer r.RUnlock()
+
+	ids := make([]uint32, 0, len(r.items))
+	for id, item := range r.items {
+		if !item.tomb {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Draining returns whether the channel is shutting down using Shutdown or Drain.
+func (ch *Channel) Draining() bool {
+	return ch.draining.Load()
+}
+
+// Draining returns whether the subchannel's channel is shutting down.
+func (c *SubChannel) Draining() bool {
+	return c.topChannel.Draining()
+}
+
+// hasPendingCalls returns whether any of the channel's connections have calls
+// in progress.
+func (ch *Channel) hasPendingCalls() bool {
+	ch.mutable.RLock()
+	defer ch.mutable.RUnlock()
+
+	for _, c := range ch.mutable.conns {
+		if c.hasPendingCalls() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// startBlockedCall makes an echo call to the test server in the background
+// which blocks until unblock is closed. It returns once the handler is running,
+// and the returned channel receives the call's error.
+func startBlockedCall(t *testing.T, ts *testutils.TestServer, client *Channel, unblock <-chan struct{}) <-chan error {
+	gotCall := make(chan struct{})
+	testutils.RegisterEcho(ts.Server(), func() {
+		close(gotCall)
+		<-unblock
+	})
+
+	errC := make(chan error, 1)
+	go func() {
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", []byte("arg2"), []byte("arg3"))
+		errC <- err
+	}()
+
+	<-gotCall
+	return errC
+}
+
+func TestShutdownWaitsForInflightCalls(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		client := ts.NewClient(nil)
+		unblock := make(chan struct{})
+		callErrC := startBlockedCall(t, ts, client, unblock)
+
+		shutdownErrC := make(chan error, 1)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), testutils.Timeout(time.Second))
+			defer cancel()
+			shutdownErrC <- ts.Server().Shutdown(ctx)
+		}()
+
+		require.True(t, testutils.WaitFor(time.Second, ts.Server().Draining), "Server did not start draining")
+		assert.NotEqual(t, ChannelClosed, ts.Server().State(), "Server closed with a call in progress")
+
+		// New calls are declined while the existing call is in progress.
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		assert.Equal(t, ErrCodeDeclined, GetSystemErrorCode(err), "New call should be declined while draining")
+
+		close(unblock)
+		assert.NoError(t, <-callErrC, "In-flight call should complete")
+		assert.NoError(t, <-shutdownErrC, "Shutdown failed")
+		assert.Equal(t, ChannelClosed, ts.Server().State(), "Server should be closed after Shutdown")
+	})
+}
+
+func TestShutdownRejectsNewConnections(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay().AddLogFilter("Failed during connection handshake.", 1)
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		unblock := make(chan struct{})
+		defer close(unblock)
+		startBlockedCall(t, ts, ts.NewClient(nil), unblock)
+
+		ctx, cancel := context.WithTimeout(context.Background(), testutils.Timeout(time.Second))
+		defer cancel()
+		go ts.Server().Shutdown(ctx)
+		require.True(t, testutils.WaitFor(time.Second, ts.Server().Draining), "Server did not start draining")
+
+		client := ts.NewClient(opts)
+		err := client.Ping(ctx, ts.HostPort())
+		assert.Equal(t, ErrCodeDeclined, GetSystemErrorCode(err), "New connections should be declined while draining")
+
+		_, err = ts.Server().Connect(ctx, client.PeerInfo().HostPort)
+		assert.Equal(t, ErrCodeDeclined, GetSystemErrorCode(err), "Outbound connections should fail while draining")
+	})
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		unblock := make(chan struct{})
+		callErrC := startBlockedCall(t, ts, ts.NewClient(nil), unblock)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, context.DeadlineExceeded, ts.Server().Shutdown(ctx), "Shutdown should time out")
+		assert.NotEqual(t, ChannelClosed, ts.Server().State(), "Server should not close with a call in progress")
+
+		close(unblock)
+		assert.NoError(t, <-callErrC, "In-flight call should complete")
+	})
+}
+
+func TestShutdownHealthCheck(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		client := ts.NewClient(nil)
+		unblock := make(chan struct{})
+		defer close(unblock)
+		startBlockedCall(t, ts, client, unblock)
+
+		testutils.RegisterFunc(ts.Server(), "Meta::health", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return &raw.Res{Arg3: []byte("draining")}, nil
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), testutils.Timeout(time.Second))
+		defer cancel()
+		go ts.Server().Shutdown(ctx)
+		require.True(t, testutils.WaitFor(time.Second, ts.Server().Draining), "Server did not start draining")
+
+		_, arg3, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "Meta::health", nil, nil)
+		require.NoError(t, err, "Health checks should be served while draining")
+		assert.Equal(t, "draining", string(arg3), "Unexpected health response")
+	})
+}
+
+// connRemoteDraining returns whether any of the client's outbound connections
+// received a drain signal.
+func connRemoteDraining(client *Channel) bool {
+	for _, peer := range client.IntrospectState(nil).RootPeers {
+		for _, conn := range peer.OutboundConnections {
+			if conn.RemoteDraining {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestDrainSignalsPeers(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		client := ts.NewClient(nil)
+		unblock := make(chan struct{})
+		callErrC := startBlockedCall(t, ts, client, unblock)
+
+		drainErrC := make(chan error, 1)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), testutils.Timeout(time.Second))
+			defer cancel()
+			_, err := ts.Server().Drain(ctx)
+			drainErrC <- err
+		}()
+
+		assert.True(t, testutils.WaitFor(time.Second, func() bool {
+			return connRemoteDraining(client)
+		}), "Client did not receive the drain signal")
+
+		close(unblock)
+		assert.NoError(t, <-callErrC, "In-flight call should complete")
+		assert.NoError(t, <-drainErrC, "Drain failed")
+		assert.Equal(t, ChannelClosed, ts.Server().State(), "Server should be closed after Drain")
+	})
+}
+
+func TestDrainSignalRequiresPeerSupport(t *testing.T) {
+	tests := []struct {
+		msg        string
+		disabled   []ProtocolFeature
+		wantSignal bool
+	}{
+		{msg: "peer supports drain signal", wantSignal: true},
+		{msg: "peer doesn't support drain signal", disabled: []ProtocolFeature{FeatureDrainSignal}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			opts := testutils.NewOpts().NoRelay()
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				clientOpts := testutils.NewOpts()
+				clientOpts.DefaultConnectionOptions.DisabledFeatures = tt.disabled
+				client := ts.NewClient(clientOpts)
+				unblock := make(chan struct{})
+				callErrC := startBlockedCall(t, ts, client, unblock)
+
+				drainErrC := make(chan error, 1)
+				go func() {
+					ctx, cancel := context.WithTimeout(context.Background(), testutils.Timeout(time.Second))
+					defer cancel()
+					_, err := ts.Server().Drain(ctx)
+					drainErrC <- err
+				}()
+
+				// The drain signal is sent before Drain waits for calls, so
+				// the server is draining once the signal could have been sent.
+				require.True(t, testutils.WaitFor(time.Second, ts.Server().Draining), "Server is not draining")
+				clientConn := func() ConnectionRuntimeStats {
+					conns := client.RuntimeStats().Peers[ts.HostPort()].Connections
+					require.Len(t, conns, 1, "Expected a single connection to the server")
+					return conns[0]
+				}
+				if tt.wantSignal {
+					assert.True(t, testutils.WaitFor(time.Second, func() bool {
+						return connRemoteDraining(client)
+					}), "Client did not receive the drain signal")
+				} else {
+					// Give the server a chance to send the signal if it was going to.
+					time.Sleep(testutils.Timeout(20 * time.Millisecond))
+					assert.False(t, connRemoteDraining(client), "Drain signal should not be sent to peers without support")
+				}
+				assert.Empty(t, clientConn().LastError, "Drain signal should not be recorded as an error")
+
+				close(unblock)
+				assert.NoError(t, <-callErrC, "In-flight call should complete")
+				assert.NoError(t, <-drainErrC, "Drain failed")
+			})
+		})
+	}
+}
+
+func TestDrainDeadlineAbandonsCalls(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay().AddLogFilter("simpleHandler OnError.", 1)
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		unblock := make(chan struct{})
+		defer close(unblock)
+		client := ts.NewClient(nil)
+		callErrC := startBlockedCall(t, ts, client, unblock)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		abandoned, err := ts.Server().Drain(ctx)
+		assert.Equal(t, context.DeadlineExceeded, err, "Drain should time out")
+		require.Len(t, abandoned, 1, "Expected the blocked call to be abandoned")
+		assert.Equal(t, "inbound", abandoned[0].Direction, "Unexpected direction")
+		assert.NotEmpty(t, abandoned[0].RemoteHostPort, "Missing remote host:port")
+
+		assert.Error(t, <-callErrC, "Abandoned call should fail")
+		assert.True(t, testutils.WaitFor(time.Second, func() bool {
+			return ts.Server().State() == ChannelClosed
+		}), "Server should close after the drain deadline")
+	})
+}
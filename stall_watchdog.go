@@ -0,0 +1,134 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"time"
+
+	"github.com/uber-go/atomic"
+)
+
+// ErrCallStalled is a SystemError indicating that a call was failed by the stall
+// watchdog since none of its frames were sent or received within the stall timeout.
+var ErrCallStalled = NewSystemError(ErrCodeTimeout, "call stalled")
+
+// stallWatchdog periodically looks for calls that have not sent or received any
+// frames within the stall timeout, and fails them so that a stuck call cannot
+// hold up the connection until it times out.
+type stallWatchdog struct {
+	ch            *Channel
+	stallTimeout  time.Duration
+	checkInterval time.Duration
+
+	stopped atomic.Bool
+	stopCh  chan struct{}
+}
+
+// startStallWatchdog starts checking for stalled calls if StallTimeout is set.
+// It returns nil if the watchdog is disabled.
+func startStallWatchdog(ch *Channel, opts *ChannelOptions) *stallWatchdog {
+	if opts.StallTimeout <= 0 {
+		return nil
+	}
+
+	sw := &stallWatchdog{
+		ch:            ch,
+		stallTimeout:  opts.StallTimeout,
+		checkInterval: opts.StallCheckInterval,
+		stopCh:        make(chan struct{}),
+	}
+	if sw.checkInterval <= 0 {
+		sw.checkInterval = sw.stallTimeout / 2
+	}
+
+	ch.log.WithFields(
+		LogField{"stallTimeout", sw.stallTimeout},
+		LogField{"stallCheckInterval", sw.checkInterval},
+	).Info("Starting stalled calls watchdog.")
+
+	go sw.pollerLoop()
+	return sw
+}
+
+// Stop stops checking for stalled calls.
+func (sw *stallWatchdog) Stop() {
+	if sw == nil || !sw.stopped.CAS(false, true) {
+		return
+	}
+	close(sw.stopCh)
+}
+
+func (sw *stallWatchdog) pollerLoop() {
+	ticker := sw.ch.timeTicker(sw.checkInterval)
+
+	for {
+		select {
+		case <-ticker.C:
+			sw.checkStalledCalls()
+		case <-sw.stopCh:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func (sw *stallWatchdog) checkStalledCalls() {
+	stalledBefore := sw.ch.timeNow().Add(-sw.stallTimeout)
+
+	sw.ch.mutable.RLock()
+	conns := make([]*Connection, 0, len(sw.ch.mutable.conns))
+	for _, conn := range sw.ch.mutable.conns {
+		conns = append(conns, conn)
+	}
+	sw.ch.mutable.RUnlock()
+
+	for _, conn := range conns {
+		for _, mex := range conn.inbound.stalledExchanges(stalledBefore) {
+			sw.failStalledCall(conn, mex, "inbound.calls.stalled")
+		}
+		for _, mex := range conn.outbound.stalledExchanges(stalledBefore) {
+			sw.failStalledCall(conn, mex, "outbound.calls.stalled")
+		}
+	}
+}
+
+// failStalledCall fails a single call with ErrCallStalled. Any reader or writer
+// blocked on the call is unblocked, and for inbound calls, the handler's context
+// is cancelled and the caller is sent an error frame.
+func (sw *stallWatchdog) failStalledCall(conn *Connection, mex *messageExchange, stat string) {
+	// The call may have already failed or completed after we found it.
+	if !mex.errChNotified.CAS(false, true) {
+		return
+	}
+
+	conn.log.WithFields(
+		LogField{"remotePeer", conn.remotePeerInfo},
+		LogField{"exchange", mex.mexset.name},
+		LogField{"msgID", mex.msgID},
+		LogField{"lastActivity", time.Unix(0, mex.lastActivity.Load())},
+	).Warn("Failing stalled call.")
+	conn.statsReporter.IncCounter(stat, conn.commonStatsTags, 1)
+
+	mex.errCh.Notify(ErrCallStalled)
+	if mex.mexset.name == messageExchangeSetInbound {
+		conn.SendSystemError(mex.msgID, Span{}, ErrCallStalled)
+	}
+}
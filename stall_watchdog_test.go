@@ -0,0 +1,90 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestStallWatchdog(t *testing.T) {
+	opts := testutils.NewOpts().AddLogFilter("Failing stalled call.", 1)
+	opts.StallTimeout = testutils.Timeout(100 * time.Millisecond)
+	opts.StallCheckInterval = testutils.Timeout(10 * time.Millisecond)
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		handlerCancelled := make(chan struct{})
+		ts.Register(HandlerFunc(func(ctx context.Context, call *InboundCall) {
+			// Never read the request, so once the call's buffer is full,
+			// the connection cannot read any more frames.
+			<-ctx.Done()
+			close(handlerCancelled)
+			call.Response().SendSystemError(ctx.Err())
+		}), "stall")
+		ts.Register(raw.Wrap(newTestHandler(t)), "echo")
+
+		client := ts.NewClient(nil)
+		ctx, cancel := NewContext(testutils.Timeout(5 * time.Second))
+		defer cancel()
+
+		start := time.Now()
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "stall", nil, testutils.RandBytes(1024*1024))
+		require.Error(t, err, "Stalled call should fail")
+		assert.Equal(t, ErrCodeTimeout, GetSystemErrorCode(err), "Unexpected error code")
+		assert.Contains(t, err.Error(), ErrCallStalled.(SystemError).Message(), "Expected stall error")
+		assert.True(t, time.Since(start) < testutils.Timeout(time.Second),
+			"Stalled call should fail before its timeout, took %v", time.Since(start))
+
+		select {
+		case <-handlerCancelled:
+		case <-time.After(testutils.Timeout(time.Second)):
+			t.Fatal("Handler context was not cancelled for the stalled call")
+		}
+
+		// The connection can be used after the stalled call is failed.
+		testutils.AssertEcho(t, client, ts.HostPort(), ts.ServiceName())
+		assert.Equal(t, 1, len(client.IntrospectState(nil).RootPeers), "Expected a single peer")
+	})
+}
+
+func TestStallWatchdogDisabled(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		ts.RegisterFunc("slow", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			time.Sleep(testutils.Timeout(50 * time.Millisecond))
+			return &raw.Res{Arg3: args.Arg3}, nil
+		})
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		_, arg3, _, err := raw.Call(ctx, ts.NewClient(nil), ts.HostPort(), ts.ServiceName(), "slow", nil, []byte("arg3"))
+		require.NoError(t, err, "Slow call should not fail without a stall timeout")
+		assert.Equal(t, []byte("arg3"), arg3, "Unexpected response")
+	})
+}
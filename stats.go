@@ -23,6 +23,8 @@ package tchannel
 import (
 	"log"
 	"time"
+
+	"github.com/opentracing/opentracing-go"
 )
 
 // StatsReporter is the the interface used to report stats.
@@ -32,6 +34,32 @@ type StatsReporter interface {
 	RecordTimer(name string, tags map[string]string, d time.Duration)
 }
 
+// ExemplarStatsReporter is an optional interface for stats reporters that can
+// attach exemplars to timers, such as OpenMetrics histograms. If the channel's
+// StatsReporter implements it, call latency timers are recorded with the trace
+// ID of the call, so a metric can be linked to a trace. Trace IDs are only
+// available when the Tracer supports Zipkin-style span IDs.
+type ExemplarStatsReporter interface {
+	StatsReporter
+
+	// RecordTimerWithExemplar records a timer with the trace ID of the call
+	// as an exemplar. It is used instead of RecordTimer for call latencies.
+	RecordTimerWithExemplar(name string, tags map[string]string, d time.Duration, traceID uint64)
+}
+
+// recordCallTimer records a call latency timer. If the reporter supports
+// exemplars and the span has a trace ID, the trace ID is attached as an exemplar.
+func recordCallTimer(r StatsReporter, name string, tags map[string]string, d time.Duration, span opentracing.Span) {
+	if er, ok := r.(ExemplarStatsReporter); ok && span != nil {
+		var injectable injectableSpan
+		if err := injectable.initFromOpenTracing(span); err == nil && injectable.traceID != 0 {
+			er.RecordTimerWithExemplar(name, tags, d, injectable.traceID)
+			return
+		}
+	}
+	r.RecordTimer(name, tags, d)
+}
+
 // NullStatsReporter is a stats reporter that discards the statistics.
 var NullStatsReporter StatsReporter = nullStatsReporter{}
 
@@ -0,0 +1,182 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package prometheus
+
+import (
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/relay"
+)
+
+// RelayHost wraps a RelayHost so that the calls it starts report metrics to
+// the reporter, labelled by the caller, callee, method and the peer the call
+// was relayed to. Calls are counted by relay.calls.success, relay.calls.failed
+// (also labelled with the reason) and relay.calls.retries, their latency is
+// recorded by relay.calls.latency, and the bytes relayed by
+// relay.request.bytes and relay.response.bytes. The calls in progress are
+// tracked by the relay.calls.inflight gauge, which isn't labelled by peer.
+// To bound the number of series, methods can be limited using
+// Options.RelayMethods.
+//
+// This is the Prometheus implementation of relay metrics, so relays don't
+// need their own adapter.
+//
+// Calls are passed through to the wrapped host's calls, including the
+// optional RelayRateLimitingHost and RelayRetryableCall interfaces.
+func (r *Reporter) RelayHost(host tchannel.RelayHost) tchannel.RelayHost {
+	h := &relayHost{host, r}
+	if _, ok := host.(tchannel.RelayRateLimitingHost); ok {
+		return rateLimitingRelayHost{h}
+	}
+	return h
+}
+
+type relayHost struct {
+	tchannel.RelayHost
+
+	r *Reporter
+}
+
+func (h *relayHost) Start(f relay.CallFrame, conn *relay.Conn) (tchannel.RelayCall, error) {
+	call, err := h.RelayHost.Start(f, conn)
+	if call == nil {
+		return nil, err
+	}
+	c := &relayCall{
+		RelayCallWrapper: tchannel.RelayCallWrapper{RelayCall: call},
+		r:                h.r,
+		caller:           string(f.Caller()),
+		callee:           string(f.Service()),
+		method:           h.r.relayMethod(f.Method()),
+	}
+	c.inflight().value.Inc()
+	return c, err
+}
+
+// relayMethod returns the method used to label a relayed call.
+func (r *Reporter) relayMethod(method []byte) string {
+	if r.relayMethods == nil {
+		return string(method)
+	}
+	// The conversion in the map lookup doesn't allocate.
+	if _, ok := r.relayMethods[string(method)]; !ok {
+		return "other"
+	}
+	return string(method)
+}
+
+type rateLimitingRelayHost struct {
+	*relayHost
+}
+
+func (h rateLimitingRelayHost) RateLimiter() relay.RateLimiter {
+	return h.RelayHost.(tchannel.RelayRateLimitingHost).RateLimiter()
+}
+
+// relayCall records metrics for a relayed call when it ends.
+type relayCall struct {
+	tchannel.RelayCallWrapper
+
+	r                      *Reporter
+	caller, callee, method string
+
+	peer      string
+	succeeded bool
+	failure   string
+	duration  time.Duration
+	request   tchannel.RelayFrameStats
+	response  tchannel.RelayFrameStats
+}
+
+var _ tchannel.RelayRetryableCall = (*relayCall)(nil)
+var _ tchannel.RelayFrameStatsCall = (*relayCall)(nil)
+
+func (c *relayCall) Destination() (*tchannel.Peer, bool) {
+	peer, ok := c.RelayCall.Destination()
+	if ok && peer != nil {
+		c.peer = peer.HostPort()
+	}
+	return peer, ok
+}
+
+func (c *relayCall) RetriedTo(peer *tchannel.Peer) {
+	c.RelayCallWrapper.RetriedTo(peer)
+	c.peer = peer.HostPort()
+	c.r.getSeries(counterType, "relay.calls.retries", c.labels()).value.Inc()
+}
+
+func (c *relayCall) Succeeded() {
+	c.RelayCall.Succeeded()
+	c.succeeded = true
+}
+
+func (c *relayCall) Failed(reason string) {
+	c.RelayCall.Failed(reason)
+	c.failure = reason
+}
+
+func (c *relayCall) SetDuration(d time.Duration) {
+	c.RelayCall.SetDuration(d)
+	c.duration = d
+}
+
+func (c *relayCall) SetFrameStats(request, response tchannel.RelayFrameStats) {
+	c.RelayCallWrapper.SetFrameStats(request, response)
+	c.request = request
+	c.response = response
+}
+
+func (c *relayCall) End() {
+	labels := c.labels()
+	switch {
+	case c.failure != "":
+		failedLabels := append(labels, label{"reason", c.failure})
+		c.r.getSeries(counterType, "relay.calls.failed", failedLabels).value.Inc()
+	case c.succeeded:
+		c.r.getSeries(counterType, "relay.calls.success", labels).value.Inc()
+	}
+	c.r.getSeries(histogramType, "relay.calls.latency", labels).hist.observe(c.duration.Seconds())
+	c.r.getSeries(counterType, "relay.request.bytes", labels).value.Add(c.request.Bytes)
+	c.r.getSeries(counterType, "relay.response.bytes", labels).value.Add(c.response.Bytes)
+	c.inflight().value.Dec()
+
+	c.RelayCall.End()
+}
+
+// inflight returns the gauge of calls in progress for the call's edge.
+func (c *relayCall) inflight() *series {
+	return c.r.getSeries(gaugeType, "relay.calls.inflight", []label{
+		{"callee", c.callee},
+		{"caller", c.caller},
+		{"method", c.method},
+	})
+}
+
+// labels returns the labels for the call's metrics, sorted by name.
+func (c *relayCall) labels() []label {
+	return []label{
+		{"callee", c.callee},
+		{"caller", c.caller},
+		{"method", c.method},
+		{"peer", c.peer},
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package prometheus
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/relay"
+	"github.com/uber/tchannel-go/relay/relaytest"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type rateLimitingHost struct {
+	tchannel.RelayHost
+}
+
+func (rateLimitingHost) RateLimiter() relay.RateLimiter { return nil }
+
+func TestRelayHostInterfaces(t *testing.T) {
+	r := New(Options{})
+	host := relaytest.NewStubRelayHost()
+
+	_, ok := r.RelayHost(host).(tchannel.RelayRateLimitingHost)
+	assert.False(t, ok, "Wrapped host should not be a RelayRateLimitingHost")
+
+	_, ok = r.RelayHost(rateLimitingHost{host}).(tchannel.RelayRateLimitingHost)
+	assert.True(t, ok, "Wrapped host should be a RelayRateLimitingHost")
+}
+
+func scrape(t *testing.T, r *Reporter) string {
+	buf := &bytes.Buffer{}
+	_, err := r.WriteTo(buf)
+	require.NoError(t, err, "WriteTo failed")
+	return buf.String()
+}
+
+func TestRelayHost(t *testing.T) {
+	tests := []struct {
+		msg               string
+		relayMethods      []string
+		wantEchoMethod    string
+		wantUnknownMethod string
+	}{
+		{
+			msg:               "all methods",
+			wantEchoMethod:    "echo",
+			wantUnknownMethod: "unknown",
+		},
+		{
+			msg:               "relay methods",
+			relayMethods:      []string{"echo"},
+			wantEchoMethod:    "echo",
+			wantUnknownMethod: "other",
+		},
+		{
+			msg:               "other methods",
+			relayMethods:      []string{"other-method"},
+			wantEchoMethod:    "other",
+			wantUnknownMethod: "other",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			r := New(Options{RelayMethods: tt.relayMethods})
+
+			server := testutils.NewServer(t, nil)
+			defer server.Close()
+			testutils.RegisterEcho(server, nil)
+			serverHostPort := server.PeerInfo().HostPort
+
+			host := relaytest.HostFunc(func(f relay.CallFrame, _ *relay.Conn) (string, error) {
+				if string(f.Method()) == "unknown" {
+					return "", errors.New("no peers")
+				}
+				return serverHostPort, nil
+			})
+			relayCh := testutils.NewServer(t, testutils.NewOpts().
+				SetServiceName("relay").
+				SetRelayHost(r.RelayHost(host)).
+				DisableLogVerification())
+			defer relayCh.Close()
+			relayHostPort := relayCh.PeerInfo().HostPort
+
+			client := testutils.NewClient(t, nil)
+			defer client.Close()
+
+			for i := 0; i < 3; i++ {
+				err := testutils.CallEcho(client, relayHostPort, server.ServiceName(), nil)
+				require.NoError(t, err, "CallEcho failed")
+			}
+
+			ctx, cancel := tchannel.NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+			_, _, _, err := raw.Call(ctx, client, relayHostPort, server.ServiceName(), "unknown", nil, nil)
+			require.Error(t, err, "Expected call with no peers to fail")
+
+			// The relay ends calls after forwarding the response, so wait for
+			// the calls to end.
+			edge := `{callee="` + server.ServiceName() + `",caller="` + client.ServiceName() + `",method="` + tt.wantEchoMethod + `",peer="` + serverHostPort + `"}`
+			failedEdge := `{callee="` + server.ServiceName() + `",caller="` + client.ServiceName() + `",method="` + tt.wantUnknownMethod + `",peer="",reason="relay-`
+			inflight := regexp.MustCompile(`relay_calls_inflight\{[^}]*\} [1-9]`)
+			require.True(t, testutils.WaitFor(time.Second, func() bool {
+				metrics := scrape(t, r)
+				return strings.Contains(metrics, "relay_calls_latency_seconds_count"+edge+" 3\n") &&
+					strings.Contains(metrics, "relay_calls_failed_total"+failedEdge) &&
+					!inflight.MatchString(metrics)
+			}), "Calls did not end:\n%v", scrape(t, r))
+
+			metrics := scrape(t, r)
+			calls := `{callee="` + server.ServiceName() + `",caller="` + client.ServiceName() + `",method="` + tt.wantEchoMethod + `"}`
+			assert.Contains(t, metrics, "relay_calls_inflight"+calls+" 0\n", "Expected no calls in progress")
+			assert.Contains(t, metrics, "relay_calls_success_total"+edge+" 3\n", "Missing successful calls")
+			assert.Regexp(t, `relay_request_bytes_total`+regexp.QuoteMeta(edge)+` [1-9]\d*\n`, metrics, "Missing request bytes")
+			assert.Regexp(t, `relay_response_bytes_total`+regexp.QuoteMeta(edge)+` [1-9]\d*\n`, metrics, "Missing response bytes")
+			assert.Contains(t, metrics, "relay_calls_failed_total"+failedEdge, "Missing failed call")
+		})
+	}
+}
@@ -0,0 +1,407 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package prometheus provides a tchannel.StatsReporter that exports metrics
+// in the Prometheus text format, labelled by the edges of the call graph.
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber-go/atomic"
+	"github.com/uber/tchannel-go"
+)
+
+// DefaultBuckets are the default histogram buckets for timers, in seconds.
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// contentType is the content type of the Prometheus text format.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Options configures a Reporter.
+type Options struct {
+	// Namespace is prefixed to the names of all metrics, e.g. "tchannel".
+	Namespace string
+
+	// Buckets are the upper bounds of the histogram buckets for timers, in
+	// seconds. Defaults to DefaultBuckets.
+	Buckets []float64
+
+	// RelayMethods, if set, are the methods used to label calls relayed by
+	// RelayHost. Calls for any other method are labelled with the method
+	// "other", which bounds the number of series when callers can send
+	// arbitrary methods.
+	RelayMethods []string
+}
+
+// Reporter is a tchannel.StatsReporter that keeps metrics in memory, and
+// exports them in the Prometheus text format using Handler.
+//
+// Metric names have their "." and "-" replaced by "_": counters have a
+// "_total" suffix, and timers are histograms with a "_seconds" suffix. Tags
+// are converted to labels: calls are labelled with the caller, callee and
+// method, so metrics can be aggregated by the edges of the call graph, and
+// metrics for a remote peer are labelled with the peer. The "app" and "host"
+// tags are dropped, since they identify the process, which Prometheus labels
+// with the scraped instance.
+type Reporter struct {
+	namespace    string
+	buckets      []float64
+	relayMethods map[string]struct{}
+
+	sync.RWMutex
+	families map[string]*family
+}
+
+var _ tchannel.StatsReporter = (*Reporter)(nil)
+
+type metricType string
+
+const (
+	counterType   metricType = "counter"
+	gaugeType     metricType = "gauge"
+	histogramType metricType = "histogram"
+)
+
+// family is the series of a metric with different labels.
+type family struct {
+	name    string
+	typ     metricType
+	help    string
+	buckets []float64
+
+	sync.RWMutex
+	series map[string]*series
+}
+
+// series is a metric with a set of labels. Counters and gauges use value,
+// and histograms use hist.
+type series struct {
+	labels string
+	value  atomic.Int64
+	hist   *histogram
+}
+
+type histogram struct {
+	sync.Mutex
+
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+// New returns a Reporter.
+func New(opts Options) *Reporter {
+	buckets := opts.Buckets
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	var relayMethods map[string]struct{}
+	if len(opts.RelayMethods) > 0 {
+		relayMethods = make(map[string]struct{}, len(opts.RelayMethods))
+		for _, m := range opts.RelayMethods {
+			relayMethods[m] = struct{}{}
+		}
+	}
+
+	return &Reporter{
+		namespace:    opts.Namespace,
+		buckets:      buckets,
+		relayMethods: relayMethods,
+		families:     make(map[string]*family),
+	}
+}
+
+// IncCounter increments the counter with the given name and tags.
+func (r *Reporter) IncCounter(name string, tags map[string]string, value int64) {
+	r.getSeries(counterType, name, convertTags(tags)).value.Add(value)
+}
+
+// UpdateGauge sets the gauge with the given name and tags.
+func (r *Reporter) UpdateGauge(name string, tags map[string]string, value int64) {
+	r.getSeries(gaugeType, name, convertTags(tags)).value.Store(value)
+}
+
+// RecordTimer records the duration in the histogram with the given name and tags.
+func (r *Reporter) RecordTimer(name string, tags map[string]string, d time.Duration) {
+	r.getSeries(histogramType, name, convertTags(tags)).hist.observe(d.Seconds())
+}
+
+// Handler returns an http.Handler that serves the metrics in the Prometheus
+// text format, to be scraped by Prometheus.
+func (r *Reporter) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		r.WriteTo(w)
+	})
+}
+
+// WriteTo writes the metrics to w in the Prometheus text format.
+func (r *Reporter) WriteTo(w io.Writer) (int64, error) {
+	r.RLock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.RUnlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	buf := &bytes.Buffer{}
+	for _, f := range families {
+		f.write(buf)
+	}
+	return buf.WriteTo(w)
+}
+
+func (r *Reporter) getSeries(typ metricType, name string, labels []label) *series {
+	return r.getFamily(typ, name).getSeries(formatLabels(labels))
+}
+
+func (r *Reporter) getFamily(typ metricType, name string) *family {
+	key := metricName(r.namespace, name, typ)
+
+	r.RLock()
+	f, ok := r.families[key]
+	r.RUnlock()
+	if ok {
+		return f
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	// Always double-check under the write-lock.
+	if f, ok := r.families[key]; ok {
+		return f
+	}
+
+	f = &family{
+		name:    key,
+		typ:     typ,
+		help:    fmt.Sprintf("TChannel %v %v.", typ, name),
+		buckets: r.buckets,
+		series:  make(map[string]*series),
+	}
+	r.families[key] = f
+	return f
+}
+
+func (f *family) getSeries(labels string) *series {
+	f.RLock()
+	s, ok := f.series[labels]
+	f.RUnlock()
+	if ok {
+		return s
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	if s, ok := f.series[labels]; ok {
+		return s
+	}
+
+	s = &series{labels: labels}
+	if f.typ == histogramType {
+		s.hist = &histogram{
+			buckets: f.buckets,
+			counts:  make([]uint64, len(f.buckets)),
+		}
+	}
+	f.series[labels] = s
+	return s
+}
+
+func (f *family) write(buf *bytes.Buffer) {
+	f.RLock()
+	series := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		series = append(series, s)
+	}
+	f.RUnlock()
+	sort.Slice(series, func(i, j int) bool { return series[i].labels < series[j].labels })
+
+	fmt.Fprintf(buf, "# HELP %s %s\n", f.name, f.help)
+	fmt.Fprintf(buf, "# TYPE %s %s\n", f.name, f.typ)
+	for _, s := range series {
+		if s.hist == nil {
+			fmt.Fprintf(buf, "%s%s %d\n", f.name, s.labels, s.value.Load())
+			continue
+		}
+		s.hist.write(buf, f.name, s.labels)
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	h.Lock()
+	defer h.Unlock()
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) write(buf *bytes.Buffer, name, labels string) {
+	h.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	count, sum := h.count, h.sum
+	h.Unlock()
+
+	// Bucket counts are cumulative.
+	var cumulative uint64
+	for i, upper := range h.buckets {
+		cumulative += counts[i]
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", name, withLabel(labels, "le", formatFloat(upper)), cumulative)
+	}
+	fmt.Fprintf(buf, "%s_bucket%s %d\n", name, withLabel(labels, "le", "+Inf"), count)
+	fmt.Fprintf(buf, "%s_sum%s %s\n", name, labels, formatFloat(sum))
+	fmt.Fprintf(buf, "%s_count%s %d\n", name, labels, count)
+}
+
+// metricName returns the Prometheus name for a TChannel metric.
+func metricName(namespace, name string, typ metricType) string {
+	name = sanitize(name)
+	if namespace != "" {
+		name = sanitize(namespace) + "_" + name
+	}
+	switch typ {
+	case counterType:
+		return name + "_total"
+	case histogramType:
+		return name + "_seconds"
+	default:
+		return name
+	}
+}
+
+// sanitize replaces characters that aren't valid in Prometheus metric and
+// label names with "_".
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// convertTags converts TChannel's stats tags to labels, sorted by name.
+func convertTags(tags map[string]string) []label {
+	_, outbound := tags["target-service"]
+	_, inbound := tags["calling-service"]
+
+	labels := make([]label, 0, len(tags))
+	for k, v := range tags {
+		var name string
+		switch k {
+		case "app", "host":
+			continue
+		case "service":
+			// The channel's service is the caller of outbound calls, and the
+			// callee of inbound calls.
+			switch {
+			case outbound:
+				name = "caller"
+			case inbound:
+				name = "callee"
+			default:
+				name = "service"
+			}
+		case "calling-service":
+			name = "caller"
+		case "target-service":
+			name = "callee"
+		case "endpoint", "target-endpoint":
+			name = "method"
+		case "peer", "target-host":
+			name = "peer"
+		default:
+			name = sanitize(k)
+		}
+		labels = append(labels, label{name, v})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+	return labels
+}
+
+// formatLabels formats labels as they're written after a metric name, which
+// is also used as the key of the series.
+func formatLabels(labels []label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(l.name)
+		buf.WriteString(`="`)
+		buf.WriteString(escapeLabelValue(l.value))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// withLabel adds a label to formatted labels.
+func withLabel(labels, name, value string) string {
+	l := name + `="` + value + `"`
+	if labels == "" {
+		return "{" + l + "}"
+	}
+	return labels[:len(labels)-1] + "," + l + "}"
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
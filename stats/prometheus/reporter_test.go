@@ -0,0 +1,212 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertTags(t *testing.T) {
+	tests := []struct {
+		tags map[string]string
+		want []label
+	}{
+		{
+			tags: nil,
+			want: []label{},
+		},
+		{
+			// Outbound call
+			tags: map[string]string{
+				"app":             "foo-1",
+				"host":            "host1",
+				"target-service":  "tsvc",
+				"service":         "foo",
+				"target-endpoint": "te",
+				"retry-count":     "4",
+			},
+			want: []label{
+				{"callee", "tsvc"},
+				{"caller", "foo"},
+				{"method", "te"},
+				{"retry_count", "4"},
+			},
+		},
+		{
+			// Inbound call
+			tags: map[string]string{
+				"service":         "foo",
+				"calling-service": "bar",
+				"endpoint":        "ep",
+			},
+			want: []label{
+				{"callee", "foo"},
+				{"caller", "bar"},
+				{"method", "ep"},
+			},
+		},
+		{
+			// Per-peer connection stats
+			tags: map[string]string{
+				"service":    "foo",
+				"peer":       "1.1.1.1:1",
+				"frame-type": "call-req",
+			},
+			want: []label{
+				{"frame_type", "call-req"},
+				{"peer", "1.1.1.1:1"},
+				{"service", "foo"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.tags), func(t *testing.T) {
+			assert.Equal(t, tt.want, convertTags(tt.tags))
+		})
+	}
+}
+
+func TestMetricName(t *testing.T) {
+	tests := []struct {
+		namespace string
+		name      string
+		typ       metricType
+		want      string
+	}{
+		{"", "outbound.calls.send", counterType, "outbound_calls_send_total"},
+		{"", "outbound.calls.per-attempt.latency", histogramType, "outbound_calls_per_attempt_latency_seconds"},
+		{"", "connections.active", gaugeType, "connections_active"},
+		{"tchannel", "inbound.calls.recvd", counterType, "tchannel_inbound_calls_recvd_total"},
+		{"my-app", "inbound.calls.recvd", counterType, "my_app_inbound_calls_recvd_total"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, metricName(tt.namespace, tt.name, tt.typ), "Unexpected name for %v", tt.name)
+	}
+}
+
+func TestReporter(t *testing.T) {
+	r := New(Options{Namespace: "tchannel", Buckets: []float64{0.1, 0.01}})
+
+	outbound := map[string]string{
+		"service":         "foo",
+		"target-service":  "bar",
+		"target-endpoint": "baz",
+	}
+	r.IncCounter("outbound.calls.send", outbound, 2)
+	r.IncCounter("outbound.calls.send", outbound, 3)
+	r.IncCounter("outbound.calls.send", map[string]string{"target-service": "q\"\\\n"}, 1)
+	r.UpdateGauge("connections.active", map[string]string{"peer": "1.1.1.1:1"}, 5)
+	r.UpdateGauge("connections.active", map[string]string{"peer": "1.1.1.1:1"}, 4)
+	r.RecordTimer("outbound.calls.latency", outbound, 5*time.Millisecond)
+	r.RecordTimer("outbound.calls.latency", outbound, 50*time.Millisecond)
+	r.RecordTimer("outbound.calls.latency", outbound, time.Second)
+
+	want := `# HELP tchannel_connections_active TChannel gauge connections.active.
+# TYPE tchannel_connections_active gauge
+tchannel_connections_active{peer="1.1.1.1:1"} 4
+# HELP tchannel_outbound_calls_latency_seconds TChannel histogram outbound.calls.latency.
+# TYPE tchannel_outbound_calls_latency_seconds histogram
+tchannel_outbound_calls_latency_seconds_bucket{callee="bar",caller="foo",method="baz",le="0.01"} 1
+tchannel_outbound_calls_latency_seconds_bucket{callee="bar",caller="foo",method="baz",le="0.1"} 2
+tchannel_outbound_calls_latency_seconds_bucket{callee="bar",caller="foo",method="baz",le="+Inf"} 3
+tchannel_outbound_calls_latency_seconds_sum{callee="bar",caller="foo",method="baz"} 1.055
+tchannel_outbound_calls_latency_seconds_count{callee="bar",caller="foo",method="baz"} 3
+# HELP tchannel_outbound_calls_send_total TChannel counter outbound.calls.send.
+# TYPE tchannel_outbound_calls_send_total counter
+tchannel_outbound_calls_send_total{callee="bar",caller="foo",method="baz"} 5
+tchannel_outbound_calls_send_total{callee="q\"\\\n"} 1
+`
+	buf := &bytes.Buffer{}
+	n, err := r.WriteTo(buf)
+	require.NoError(t, err, "WriteTo failed")
+	assert.Equal(t, int64(buf.Len()), n, "Unexpected number of bytes written")
+	assert.Equal(t, want, buf.String(), "Unexpected metrics")
+}
+
+func TestReporterNoLabels(t *testing.T) {
+	r := New(Options{Buckets: []float64{1}})
+	r.IncCounter("inbound.calls.recvd", nil, 1)
+	r.RecordTimer("inbound.calls.latency", map[string]string{"app": "foo-1"}, time.Second)
+
+	want := `# HELP inbound_calls_latency_seconds TChannel histogram inbound.calls.latency.
+# TYPE inbound_calls_latency_seconds histogram
+inbound_calls_latency_seconds_bucket{le="1"} 1
+inbound_calls_latency_seconds_bucket{le="+Inf"} 1
+inbound_calls_latency_seconds_sum 1
+inbound_calls_latency_seconds_count 1
+# HELP inbound_calls_recvd_total TChannel counter inbound.calls.recvd.
+# TYPE inbound_calls_recvd_total counter
+inbound_calls_recvd_total 1
+`
+	buf := &bytes.Buffer{}
+	_, err := r.WriteTo(buf)
+	require.NoError(t, err, "WriteTo failed")
+	assert.Equal(t, want, buf.String(), "Unexpected metrics")
+}
+
+func TestReporterConcurrent(t *testing.T) {
+	r := New(Options{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tags := map[string]string{"target-service": fmt.Sprint(i % 2)}
+			for j := 0; j < 100; j++ {
+				r.IncCounter("outbound.calls.send", tags, 1)
+				r.RecordTimer("outbound.calls.latency", tags, time.Millisecond)
+				r.WriteTo(ioutil.Discard)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, callee := range []string{"0", "1"} {
+		labels := formatLabels([]label{{"callee", callee}})
+		assert.EqualValues(t, 500, r.getSeries(counterType, "outbound.calls.send", []label{{"callee", callee}}).value.Load(),
+			"Unexpected counter for %v", labels)
+		assert.EqualValues(t, 500, r.getSeries(histogramType, "outbound.calls.latency", []label{{"callee", callee}}).hist.count,
+			"Unexpected histogram count for %v", labels)
+	}
+}
+
+func TestReporterHandler(t *testing.T) {
+	r := New(Options{})
+	r.IncCounter("inbound.calls.recvd", map[string]string{"service": "foo"}, 1)
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, rec.Code, "Unexpected status code")
+	assert.Equal(t, contentType, rec.Header().Get("Content-Type"), "Unexpected content type")
+	assert.Contains(t, rec.Body.String(), `inbound_calls_recvd_total{service="foo"} 1`, "Unexpected metrics")
+}
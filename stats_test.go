@@ -22,7 +22,9 @@ package tchannel_test
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -31,8 +33,10 @@ import (
 	"github.com/uber/tchannel-go/raw"
 	"github.com/uber/tchannel-go/testutils"
 
+	"github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
 	"golang.org/x/net/context"
 )
 
@@ -102,7 +106,8 @@ func TestStatsCalls(t *testing.T) {
 		serverOpts := testutils.NewOpts().
 			SetStatsReporter(serverStats).
 			SetTimeNow(serverClock.Now)
-		WithVerifiedServer(t, serverOpts, func(serverCh *Channel, hostPort string) {
+		testutils.WithTestServer(t, serverOpts, func(ts *testutils.TestServer) {
+			serverCh, hostPort := ts.Server(), ts.HostPort()
 			handler := raw.Wrap(handler)
 			serverCh.Register(handler, "echo")
 			serverCh.Register(handler, "app-error")
@@ -123,8 +128,14 @@ func TestStatsCalls(t *testing.T) {
 			inboundTags := tagsForInboundCall(serverCh, ch, tt.method)
 
 			clientStats.Expected.IncCounter("outbound.calls.send", outboundTags, 1)
+			clientStats.Expected.RecordTimer("outbound.calls.peer-wait", outboundTags, 0)
 			clientStats.Expected.RecordTimer("outbound.calls.per-attempt.latency", outboundTags, 100*time.Millisecond)
 			clientStats.Expected.RecordTimer("outbound.calls.latency", outboundTags, 100*time.Millisecond)
+			serverStats.Expected.IncCounter("inbound.connections.accepted", serverCh.StatsTags(), 1)
+			if ts.HasRelay() {
+				// The relay shares the server's stats reporter.
+				serverStats.Expected.IncCounter("inbound.connections.accepted", ts.Relay().StatsTags(), 1)
+			}
 			serverStats.Expected.IncCounter("inbound.calls.recvd", inboundTags, 1)
 			serverStats.Expected.RecordTimer("inbound.calls.latency", inboundTags, 70*time.Millisecond)
 
@@ -234,6 +245,7 @@ func TestStatsWithRetries(t *testing.T) {
 			}
 			clientStats.Expected.IncCounter("outbound.calls.send", outboundTags, int64(tt.numAttempts))
 			for i, latency := range tt.perAttemptLatencies {
+				clientStats.Expected.RecordTimer("outbound.calls.peer-wait", outboundTags, 0)
 				clientStats.Expected.RecordTimer("outbound.calls.per-attempt.latency", outboundTags, latency)
 				if i > 0 {
 					tags := tagsForOutboundCall(serverCh, ch, "req")
@@ -246,3 +258,293 @@ func TestStatsWithRetries(t *testing.T) {
 		}
 	})
 }
+
+func TestStatsPeerWait(t *testing.T) {
+	defer testutils.SetTimeout(t, 2*time.Second)()
+
+	const dialDelay = 50 * time.Millisecond
+
+	initialTime := time.Date(2015, 2, 1, 10, 10, 0, 0, time.UTC)
+	clientClock := testutils.NewStubClock(initialTime)
+	clientStats := newRecordingStatsReporter()
+
+	opts := testutils.NewOpts().NoRelay()
+	WithVerifiedServer(t, opts, func(serverCh *Channel, hostPort string) {
+		testutils.RegisterEcho(serverCh, nil)
+
+		var dialer net.Dialer
+		ch := testutils.NewClient(t, testutils.NewOpts().
+			SetStatsReporter(clientStats).
+			SetTimeNow(clientClock.Now).
+			SetDialer(func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+				clientClock.Elapse(dialDelay)
+				return dialer.DialContext(ctx, network, hostPort)
+			}))
+		defer ch.Close()
+		ch.Peers().Add(hostPort)
+
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		// The first call has to dial a connection, while the second call reuses it.
+		sc := ch.GetSubChannel(serverCh.ServiceName())
+		for i := 0; i < 2; i++ {
+			_, err := raw.CallV2(ctx, sc, raw.CArgs{Method: "echo"})
+			require.NoError(t, err, "Call failed")
+		}
+
+		outboundTags := tagsForOutboundCall(serverCh, ch, "echo")
+		clientStats.Expected.IncCounter("outbound.calls.send", outboundTags, 2)
+		clientStats.Expected.IncCounter("outbound.calls.success", outboundTags, 2)
+		clientStats.Expected.RecordTimer("outbound.calls.peer-wait", outboundTags, dialDelay)
+		clientStats.Expected.RecordTimer("outbound.calls.peer-wait", outboundTags, 0)
+		clientStats.Expected.RecordTimer("outbound.calls.per-attempt.latency", outboundTags, 0)
+		clientStats.Expected.RecordTimer("outbound.calls.per-attempt.latency", outboundTags, 0)
+		clientStats.Expected.RecordTimer("outbound.calls.latency", outboundTags, 0)
+		clientStats.Expected.RecordTimer("outbound.calls.latency", outboundTags, 0)
+	})
+
+	clientStats.Validate(t)
+}
+
+// exemplarStatsReporter records the trace IDs attached to timers as exemplars.
+type exemplarStatsReporter struct {
+	StatsReporter
+
+	sync.Mutex
+	timers    map[string]int
+	exemplars map[string][]uint64
+}
+
+func newExemplarStatsReporter() *exemplarStatsReporter {
+	return &exemplarStatsReporter{
+		StatsReporter: NullStatsReporter,
+		timers:        make(map[string]int),
+		exemplars:     make(map[string][]uint64),
+	}
+}
+
+func (r *exemplarStatsReporter) RecordTimer(name string, tags map[string]string, d time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.timers[name]++
+}
+
+func (r *exemplarStatsReporter) RecordTimerWithExemplar(name string, tags map[string]string, d time.Duration, traceID uint64) {
+	r.Lock()
+	defer r.Unlock()
+	r.exemplars[name] = append(r.exemplars[name], traceID)
+}
+
+func (r *exemplarStatsReporter) getExemplars(name string) []uint64 {
+	r.Lock()
+	defer r.Unlock()
+	return r.exemplars[name]
+}
+
+func (r *exemplarStatsReporter) getTimers(name string) int {
+	r.Lock()
+	defer r.Unlock()
+	return r.timers[name]
+}
+
+func TestStatsExemplars(t *testing.T) {
+	serverTracer, serverCloser := jaeger.NewTracer(testutils.DefaultServerName, jaeger.NewConstSampler(true), jaeger.NewNullReporter())
+	defer serverCloser.Close()
+	clientTracer, clientCloser := jaeger.NewTracer("client", jaeger.NewConstSampler(true), jaeger.NewNullReporter())
+	defer clientCloser.Close()
+
+	serverStats := newExemplarStatsReporter()
+	serverOpts := testutils.NewOpts().
+		SetStatsReporter(serverStats).
+		NoRelay()
+	serverOpts.Tracer = serverTracer
+
+	testutils.WithTestServer(t, serverOpts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		clientStats := newExemplarStatsReporter()
+		clientOpts := testutils.NewOpts().SetStatsReporter(clientStats)
+		clientOpts.Tracer = clientTracer
+		client := ts.NewClient(clientOpts)
+
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		span := client.Tracer().StartSpan("client")
+		defer span.Finish()
+		traceID := span.Context().(jaeger.SpanContext).TraceID().Low
+
+		_, _, _, err := raw.Call(opentracing.ContextWithSpan(ctx, span), client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		require.NoError(t, err, "Call failed")
+
+		assert.Equal(t, []uint64{traceID}, clientStats.getExemplars("outbound.calls.per-attempt.latency"),
+			"outbound per-attempt latency should have the trace ID as an exemplar")
+		assert.Equal(t, []uint64{traceID}, clientStats.getExemplars("outbound.calls.latency"),
+			"outbound latency should have the trace ID as an exemplar")
+		assert.Equal(t, 0, clientStats.getTimers("outbound.calls.latency"),
+			"outbound latency should not be recorded without an exemplar")
+
+		// The server records the inbound latency after sending the response.
+		require.True(t, testutils.WaitFor(time.Second, func() bool {
+			return len(serverStats.getExemplars("inbound.calls.latency")) > 0
+		}), "inbound latency was not recorded")
+		assert.Equal(t, []uint64{traceID}, serverStats.getExemplars("inbound.calls.latency"),
+			"inbound latency should have the trace ID as an exemplar")
+	})
+}
+
+func TestStatsExemplarsWithoutTraceID(t *testing.T) {
+	// The default tracer does not support Zipkin-style span IDs, so timers are
+	// recorded without exemplars.
+	clientStats := newExemplarStatsReporter()
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		client := ts.NewClient(testutils.NewOpts().SetStatsReporter(clientStats))
+		ctx, cancel := NewContext(time.Second)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		require.NoError(t, err, "Call failed")
+	})
+
+	assert.Equal(t, 2, clientStats.getTimers("outbound.calls.latency"), "Expected latency for each test server")
+	assert.Empty(t, clientStats.getExemplars("outbound.calls.latency"), "Expected no exemplars")
+}
+
+func TestOutboundLatency(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		clock := testutils.NewStubClock(time.Now())
+
+		// The handler advances the client's clock by the latency in arg3.
+		testutils.RegisterFunc(ts.Server(), "sleep", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			d, err := time.ParseDuration(string(args.Arg3))
+			if err != nil {
+				return nil, err
+			}
+			clock.Elapse(d)
+			return &raw.Res{}, nil
+		})
+
+		opts := testutils.NewOpts().SetTimeNow(clock.Now)
+		opts.OutboundLatencyWindow = time.Minute
+		client := ts.NewClient(opts)
+
+		// Latencies from 1us to 100us. The latencies are kept small since the
+		// client's clock is also used to compute the TTL of each call.
+		for i := 1; i <= 100; i++ {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			latency := fmt.Sprintf("%vus", i)
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "sleep", nil, []byte(latency))
+			cancel()
+			require.NoError(t, err, "Call failed")
+		}
+
+		snapshot := client.OutboundLatency(ts.ServiceName(), "sleep")
+		assert.Equal(t, 100, snapshot.Count, "Unexpected number of calls")
+		assert.InDelta(t, 50*time.Microsecond, snapshot.P50, float64(time.Microsecond), "Unexpected P50")
+		assert.InDelta(t, 90*time.Microsecond, snapshot.P90, float64(time.Microsecond), "Unexpected P90")
+		assert.InDelta(t, 99*time.Microsecond, snapshot.P99, float64(time.Microsecond), "Unexpected P99")
+		assert.InDelta(t, 100*time.Microsecond, snapshot.Max, float64(time.Microsecond), "Unexpected max")
+
+		assert.Equal(t, LatencySnapshot{}, client.OutboundLatency(ts.ServiceName(), "echo"), "Expected no latencies for other methods")
+		assert.Equal(t, LatencySnapshot{}, ts.Server().OutboundLatency(ts.ServiceName(), "sleep"),
+			"Expected no latencies when OutboundLatencyWindow is not set")
+
+		// Earlier calls are dropped once they are outside the window.
+		clock.Elapse(time.Minute)
+		assert.Equal(t, LatencySnapshot{}, client.OutboundLatency(ts.ServiceName(), "sleep"), "Expected calls to leave the window")
+	})
+}
+
+func TestAcceptRateLimit(t *testing.T) {
+	const (
+		numConns = 15
+		rate     = 100
+		burst    = 5
+	)
+
+	serverStats := newRecordingStatsReporter()
+	opts := testutils.NewOpts().SetStatsReporter(serverStats).NoRelay()
+	opts.MaxConnectionAcceptRate = rate
+	opts.ConnectionAcceptBurst = burst
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		var clients []*Channel
+		for i := 0; i < numConns; i++ {
+			clients = append(clients, ts.NewClient(nil))
+		}
+
+		// Flood the server with new connections at once.
+		start := time.Now()
+		var wg sync.WaitGroup
+		for _, client := range clients {
+			wg.Add(1)
+			go func(client *Channel) {
+				defer wg.Done()
+				ctx, cancel := NewContext(testutils.Timeout(time.Second))
+				defer cancel()
+				assert.NoError(t, client.Ping(ctx, ts.HostPort()), "Ping failed")
+			}(client)
+		}
+		wg.Wait()
+
+		// Connections past the burst are accepted at the configured rate.
+		minDuration := time.Second * (numConns - burst) / rate
+		assert.True(t, time.Since(start) >= minDuration,
+			"Flood of connections finished faster than the accept rate, took %v", time.Since(start))
+
+		serverStats.Lock()
+		defer serverStats.Unlock()
+		tags := tagsToString(ts.Server().StatsTags())
+		assert.EqualValues(t, numConns, serverStats.Values["inbound.connections.accepted"][tags].count,
+			"Unexpected number of accepted connections")
+		limited := serverStats.Values["inbound.connections.rate-limited"][tags]
+		require.NotNil(t, limited, "Expected connections to be rate limited")
+		assert.True(t, limited.count >= numConns-burst,
+			"Expected at least %v connections to be rate-limited, got %v", numConns-burst, limited.count)
+		assert.Len(t, serverStats.Values["inbound.connections.accept-delay"][tags].timers, int(limited.count),
+			"Expected a delay to be recorded for each rate-limited connection")
+	})
+}
+
+func TestConnectionPoolUtilization(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		clientStats := newRecordingStatsReporter()
+		ticker := testutils.NewFakeTicker()
+		clientOpts := testutils.NewOpts().
+			SetStatsReporter(clientStats).
+			SetTimeTicker(ticker.New)
+		clientOpts.ConnectionPoolStatsInterval = time.Second
+		clientOpts.MaxCallsPerConnection = 1
+		clientOpts.MaxConnectionsPerPeer = 4
+		client := ts.NewClient(clientOpts)
+
+		peer := client.Peers().GetOrAdd(ts.HostPort())
+		connect := func() {
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+			_, err := peer.Connect(ctx)
+			require.NoError(t, err, "Connect failed")
+		}
+
+		tags := client.StatsTags()
+		tags["peer"] = ts.HostPort()
+		waitForGauge := func(want int64) bool {
+			ticker.Tick()
+			return testutils.WaitFor(time.Second, func() bool {
+				got, ok := clientStats.getGauge("connection.pool.utilization", tags)
+				return ok && got == want
+			})
+		}
+
+		// Use 2 of the peer's maximum of 4 connections.
+		connect()
+		connect()
+		assert.True(t, waitForGauge(50), "Expected half of the pool to be utilized")
+
+		connect()
+		assert.True(t, waitForGauge(75), "Expected three quarters of the pool to be utilized")
+	})
+}
@@ -41,6 +41,9 @@ type statsValue struct {
 
 	// timers is the list of timer values if this metrics is a timer.
 	timers []time.Duration
+
+	// gauge is the last reported value if this metric is a gauge.
+	gauge int64
 }
 
 type recordingStatsReporter struct {
@@ -105,7 +108,9 @@ func (r *recordingStatsReporter) getStat(name string, tags map[string]string) *s
 
 func (r *recordingStatsReporter) IncCounter(name string, tags map[string]string, value int64) {
 	statVal := r.getStat(name, tags)
+	r.Lock()
 	statVal.count += value
+	r.Unlock()
 }
 
 func (r *recordingStatsReporter) RecordTimer(name string, tags map[string]string, d time.Duration) {
@@ -145,4 +150,34 @@ func (r *recordingStatsReporter) Validate(t *testing.T) {
 	}
 }
 
-func (r *recordingStatsReporter) UpdateGauge(name string, tags map[string]string, value int64) {}
+func (r *recordingStatsReporter) UpdateGauge(name string, tags map[string]string, value int64) {
+	statVal := r.getStat(name, tags)
+	r.Lock()
+	statVal.gauge = value
+	r.Unlock()
+}
+
+// getGauge returns the last reported value for the given gauge, and whether
+// the gauge has been reported.
+func (r *recordingStatsReporter) getGauge(name string, tags map[string]string) (int64, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	statVal, ok := r.Values[name][tagsToString(tags)]
+	if !ok {
+		return 0, false
+	}
+	return statVal.gauge, true
+}
+
+// getCount returns the current value of the given counter.
+func (r *recordingStatsReporter) getCount(name string, tags map[string]string) int64 {
+	r.Lock()
+	defer r.Unlock()
+
+	statVal, ok := r.Values[name][tagsToString(tags)]
+	if !ok {
+		return 0
+	}
+	return statVal.count
+}
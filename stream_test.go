@@ -31,6 +31,7 @@ import (
 
 	. "github.com/uber/tchannel-go"
 
+	"github.com/uber/tchannel-go/raw"
 	"github.com/uber/tchannel-go/testutils"
 
 	"github.com/stretchr/testify/assert"
@@ -338,3 +339,61 @@ func TestResponseClosedBeforeRequest(t *testing.T) {
 		<-writerDone
 	})
 }
+
+type rawStreamEcho struct {
+	t testing.TB
+}
+
+func (h rawStreamEcho) HandleStream(ctx context.Context, args *raw.StreamArgs, response *InboundCallResponse) error {
+	writer, err := raw.StartStreamResponse(response, args.Arg2)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(writer, args.Arg3); err != nil {
+		return err
+	}
+	if err := args.Arg3.Close(); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func (h rawStreamEcho) OnError(ctx context.Context, err error) {
+	h.t.Errorf("unexpected OnError: %v", err)
+}
+
+func TestRawStreamLargePayload(t *testing.T) {
+	ctx, cancel := NewContext(time.Second)
+	defer cancel()
+
+	payload := testutils.RandBytes(10 * MaxFramePayloadSize)
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		ts.Register(raw.WrapStream(rawStreamEcho{t}), "echoStream")
+
+		call, err := ts.Server().BeginCall(ctx, ts.HostPort(), ts.ServiceName(), "echoStream", nil)
+		require.NoError(t, err, "BeginCall failed")
+
+		writer, err := raw.StartStream(call, []byte("headers"))
+		require.NoError(t, err, "StartStream failed")
+
+		// Write the payload in chunks, flushing after each chunk.
+		for chunk := payload; len(chunk) > 0; {
+			n := MaxFramePayloadSize / 3
+			if n > len(chunk) {
+				n = len(chunk)
+			}
+			require.NoError(t, writeFlushBytes(writer, chunk[:n]), "arg3 write failed")
+			chunk = chunk[n:]
+		}
+		require.NoError(t, writer.Close(), "arg3 close failed")
+
+		arg2, reader, err := raw.ReadStreamResponse(call.Response())
+		require.NoError(t, err, "ReadStreamResponse failed")
+		assert.Equal(t, []byte("headers"), arg2, "arg2 mismatch")
+
+		got, err := ioutil.ReadAll(reader)
+		require.NoError(t, err, "arg3 read failed")
+		require.NoError(t, reader.Close(), "arg3 reader close failed")
+		assert.Equal(t, payload, got, "arg3 mismatch")
+	})
+}
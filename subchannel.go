@@ -23,6 +23,7 @@ package tchannel
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/opentracing/opentracing-go"
 	"golang.org/x/net/context"
@@ -39,6 +40,64 @@ func Isolated(s *SubChannel) {
 	s.Unlock()
 }
 
+// FallbackService returns a SubChannelOption that makes calls to the given
+// service when calls through the subchannel cannot be started due to an
+// infrastructure failure: no (healthy) peers being available, or failing to
+// connect to the selected peer. Errors returned by the service do not trigger the fallback.
+func FallbackService(serviceName string) SubChannelOption {
+	return func(s *SubChannel) {
+		s.Lock()
+		s.fallbackService = serviceName
+		s.Unlock()
+	}
+}
+
+// DefaultRetryOptions returns a SubChannelOption that sets the RetryOptions
+// used by the subchannel's RunWithRetry when the context does not set any.
+func DefaultRetryOptions(opts *RetryOptions) SubChannelOption {
+	return func(s *SubChannel) {
+		s.Lock()
+		s.defaultRetryOptions = copyRetryOptions(opts)
+		s.Unlock()
+	}
+}
+
+// DefaultTimeout returns a SubChannelOption that sets the timeout for calls
+// through the subchannel when the context has no deadline, overriding the
+// channel's DefaultTimeout.
+func DefaultTimeout(timeout time.Duration) SubChannelOption {
+	return func(s *SubChannel) {
+		s.Lock()
+		s.defaultTimeout = timeout
+		s.Unlock()
+	}
+}
+
+// RetryBudget returns a SubChannelOption that limits the retries made using
+// the subchannel's RunWithRetry with its own budget, instead of the channel's
+// RetryBudget.
+func RetryBudget(opts RetryBudgetOptions) SubChannelOption {
+	return func(s *SubChannel) {
+		s.Lock()
+		s.retryBudget = newRetryBudget(s.topChannel.timeNow, opts)
+		s.Unlock()
+	}
+}
+
+// OpaqueEndpoint is the endpoint name reported in stats and tracing for calls
+// on a subchannel with OpaqueArg1, in place of arg1.
+const OpaqueEndpoint = "opaque-arg1"
+
+// OpaqueArg1 is a SubChannelOption that treats arg1 of calls to and from the
+// subchannel's service as opaque bytes rather than a method name. This allows
+// raw schemes to use arg1 for binary routing keys. Since arg1 may not be
+// printable, calls are reported in stats and tracing as OpaqueEndpoint.
+func OpaqueArg1(s *SubChannel) {
+	s.Lock()
+	s.opaqueArg1 = true
+	s.Unlock()
+}
+
 // SubChannel allows calling a specific service on a channel.
 // TODO(prashant): Allow creating a subchannel with default call options.
 // TODO(prashant): Allow registering handlers on a subchannel.
@@ -51,6 +110,31 @@ type SubChannel struct {
 	handler            Handler
 	logger             Logger
 	statsReporter      StatsReporter
+	fallbackService    string
+	opaqueArg1         bool
+
+	// defaultRetryOptions overrides the channel's DefaultRetryOptions for
+	// calls made through this subchannel, if set.
+	defaultRetryOptions *RetryOptions
+
+	// defaultTimeout overrides the channel's DefaultTimeout for calls made
+	// through this subchannel, if set.
+	defaultTimeout time.Duration
+
+	// methodTimeouts override the default timeout for calls to specific
+	// methods through this subchannel.
+	methodTimeouts map[string]time.Duration
+
+	// retryBudget overrides the channel's RetryBudget for calls made using
+	// the subchannel's RunWithRetry, if set.
+	retryBudget *retryBudget
+
+	// hedgeDelay is the delay after which attempts made using the
+	// subchannel's RunWithRetry are hedged. Attempts are not hedged if zero.
+	hedgeDelay time.Duration
+
+	// interceptors are run around calls begun using the subchannel.
+	interceptors []OutboundInterceptor
 }
 
 // Map of subchannel and the corresponding service
@@ -79,16 +163,101 @@ func (c *SubChannel) ServiceName() string {
 // BeginCall starts a new call to a remote peer, returning an OutboundCall that can
 // be used to write the arguments of the call.
 func (c *SubChannel) BeginCall(ctx context.Context, methodName string, callOptions *CallOptions) (*OutboundCall, error) {
+	ctx, cancel := withDefaultTimeout(ctx, c.methodTimeout(methodName))
+	call, err := c.intercepted(c.beginCallWithFallback)(ctx, methodName, callOptions)
+	return cancelWithCall(call, err, cancel)
+}
+
+func (c *SubChannel) beginCallWithFallback(ctx context.Context, methodName string, callOptions *CallOptions) (*OutboundCall, error) {
+	call, err := c.beginCall(ctx, methodName, callOptions)
+	if err == nil || !canFallback(err) {
+		return call, err
+	}
+
+	c.RLock()
+	fallbackService := c.fallbackService
+	c.RUnlock()
+	if fallbackService == "" {
+		return call, err
+	}
+
+	c.logger.WithFields(
+		ErrField(err),
+		LogField{"fallbackService", fallbackService},
+	).Info("Failed to begin call, calling fallback service.")
+	tags := map[string]string{
+		"target-service":   c.serviceName,
+		"target-endpoint":  c.endpointName(methodName),
+		"fallback-service": fallbackService,
+	}
+	for k, v := range c.topChannel.commonStatsTags {
+		tags[k] = v
+	}
+	c.statsReporter.IncCounter("outbound.calls.fallback", tags, 1)
+
+	// The fallback service's own fallback is not used, to avoid fallback loops.
+	return c.topChannel.GetSubChannel(fallbackService).beginCall(ctx, methodName, callOptions)
+}
+
+// canFallback returns whether a BeginCall error is an infrastructure failure
+// that should use the fallback service.
+func canFallback(err error) bool {
+	return err == ErrNoPeers || err == ErrNoHealthyPeers || getErrCode(err) == ErrCodeNetwork
+}
+
+func (c *SubChannel) beginCall(ctx context.Context, methodName string, callOptions *CallOptions) (*OutboundCall, error) {
 	if callOptions == nil {
 		callOptions = defaultCallOptions
 	}
 
-	peer, err := c.peers.Get(callOptions.RequestState.PrevSelectedPeers())
+	start := c.topChannel.timeNow()
+
+	// Wait for paused outbound traffic to resume before selecting a peer,
+	// so the selection reflects any peer changes made while paused.
+	if err := c.topChannel.outboundPause.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	selectState := callOptions.RequestState
+	if preferred := callOptions.PreferredPeer; preferred != "" && (selectState == nil || selectState.Attempt <= 1) {
+		call, err := c.beginPreferredCall(ctx, start, methodName, callOptions)
+		if call != nil || err != nil {
+			return call, err
+		}
+
+		// Avoid the preferred peer when falling back to normal peer selection.
+		if selectState == nil {
+			selectState = &RequestState{}
+		}
+		selectState.AddSelectedPeer(preferred)
+	}
+
+	peer, err := c.peers.getForRequest(selectState)
 	if err != nil {
 		return nil, err
 	}
 
-	return peer.BeginCall(ctx, c.ServiceName(), methodName, callOptions)
+	return peer.beginCall(ctx, start, c.ServiceName(), methodName, callOptions)
+}
+
+// beginPreferredCall tries to begin the call on the PreferredPeer. It returns
+// no call and no error if normal peer selection should be used instead.
+func (c *SubChannel) beginPreferredCall(ctx context.Context, start time.Time, methodName string, callOptions *CallOptions) (*OutboundCall, error) {
+	ps, ok := c.peers.exists(callOptions.PreferredPeer)
+	if !ok || !ps.Peer.isHealthy(c.topChannel.timeNow(), c.peers.parent.unhealthyCooldown) {
+		return nil, nil
+	}
+
+	call, err := ps.Peer.beginCall(ctx, start, c.ServiceName(), methodName, callOptions)
+	if err == nil || !canFallback(err) {
+		return call, err
+	}
+
+	c.logger.WithFields(
+		ErrField(err),
+		LogField{"preferredPeer", callOptions.PreferredPeer},
+	).Info("Failed to begin call on the preferred peer, using peer selection.")
+	return nil, nil
 }
 
 // Peers returns the PeerList for this subchannel.
@@ -103,6 +272,99 @@ func (c *SubChannel) Isolated() bool {
 	return c.topChannel.Peers() != c.peers
 }
 
+// RunWithRetry runs the given function like Channel.RunWithRetry, but when
+// the context does not set RetryOptions, it uses the subchannel's
+// DefaultRetryOptions, falling back to the channel's. Retries are limited by
+// the subchannel's RetryBudget if set, and attempts are hedged if the
+// subchannel has a HedgeDelay.
+func (c *SubChannel) RunWithRetry(ctx context.Context, f RetriableFunc) error {
+	c.RLock()
+	budget, hedgeDelay := c.retryBudget, c.hedgeDelay
+	c.RUnlock()
+	if budget == nil {
+		budget = c.topChannel.retryBudget
+	}
+	return c.topChannel.runWithRetry(ctx, c.retryOptions(), budget, hedgeDelay, f)
+}
+
+// retryOptions returns the default RetryOptions for calls through this subchannel.
+func (c *SubChannel) retryOptions() *RetryOptions {
+	c.RLock()
+	opts := c.defaultRetryOptions
+	c.RUnlock()
+	if opts == nil {
+		return c.topChannel.defaultRetryOptions
+	}
+	return opts
+}
+
+// timeout returns the default timeout for calls through this subchannel.
+func (c *SubChannel) timeout() time.Duration {
+	c.RLock()
+	timeout := c.defaultTimeout
+	c.RUnlock()
+	if timeout == 0 {
+		return c.topChannel.defaultTimeout
+	}
+	return timeout
+}
+
+// SetMethodTimeout sets the timeout used for calls to methodName through this
+// subchannel when the context has no deadline, overriding the subchannel's
+// DefaultTimeout. The method name is the name passed to BeginCall, which is
+// "Service::method" for Thrift. A zero timeout removes the override.
+func (c *SubChannel) SetMethodTimeout(methodName string, timeout time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	if timeout == 0 {
+		delete(c.methodTimeouts, methodName)
+		return
+	}
+	if c.methodTimeouts == nil {
+		c.methodTimeouts = make(map[string]time.Duration)
+	}
+	c.methodTimeouts[methodName] = timeout
+}
+
+// methodTimeout returns the default timeout for calls to methodName through
+// this subchannel.
+func (c *SubChannel) methodTimeout(methodName string) time.Duration {
+	c.RLock()
+	timeout, ok := c.methodTimeouts[methodName]
+	c.RUnlock()
+	if ok {
+		return timeout
+	}
+	return c.timeout()
+}
+
+// methodTimeoutsCopy returns a copy of the per-method timeouts.
+func (c *SubChannel) methodTimeoutsCopy() map[string]time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	if len(c.methodTimeouts) == 0 {
+		return nil
+	}
+	timeouts := make(map[string]time.Duration, len(c.methodTimeouts))
+	for k, v := range c.methodTimeouts {
+		timeouts[k] = v
+	}
+	return timeouts
+}
+
+// endpointName returns the name used for the given method in stats and tracing.
+func (c *SubChannel) endpointName(methodName string) string {
+	c.RLock()
+	opaque := c.opaqueArg1
+	c.RUnlock()
+	if opaque {
+		return OpaqueEndpoint
+	}
+	return methodName
+}
+
 // Register registers a handler on the subchannel for the given method.
 //
 // This function panics if the Handler for the SubChannel was overwritten with
@@ -140,6 +402,15 @@ func (c *SubChannel) GetHandlers() map[string]Handler {
 	return handlersMap
 }
 
+// hasHandlers returns whether this SubChannel can handle inbound calls, either
+// using registered methods, or a handler set using SetHandler.
+func (c *SubChannel) hasHandlers() bool {
+	if handlers, ok := c.handler.(*handlerMap); ok {
+		return !handlers.isEmpty()
+	}
+	return true
+}
+
 // SetHandler changes the SubChannel's underlying handler. This may be used to
 // set up a catch-all Handler for all requests received by this SubChannel.
 //
@@ -207,6 +478,15 @@ func (subChMap *subChannelMap) getOrAdd(serviceName string, ch *Channel) (_ *Sub
 	return subChMap.registerNewSubChannel(serviceName, ch)
 }
 
+// endpointName returns the name used for a method of the given service in
+// stats and tracing.
+func (subChMap *subChannelMap) endpointName(serviceName, methodName string) string {
+	if sc, ok := subChMap.get(serviceName); ok {
+		return sc.endpointName(methodName)
+	}
+	return methodName
+}
+
 func (subChMap *subChannelMap) updatePeer(p *Peer) {
 	subChMap.RLock()
 	for _, subCh := range subChMap.subchannels {
@@ -21,6 +21,7 @@
 package tchannel_test
 
 import (
+	"strconv"
 	"testing"
 	"time"
 
@@ -30,6 +31,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/uber-go/atomic"
 	"golang.org/x/net/context"
 )
 
@@ -268,3 +270,316 @@ func TestHandlerWithoutSubChannel(t *testing.T) {
 		})
 	})
 }
+
+func TestFallbackService(t *testing.T) {
+	tests := []struct {
+		msg          string
+		primaryPeer  func(t *testing.T, ts *testutils.TestServer) string
+		wantFallback bool
+		wantErrCode  SystemErrCode
+	}{
+		{
+			msg:          "primary has no peers",
+			wantFallback: true,
+		},
+		{
+			msg: "primary peer is unreachable",
+			primaryPeer: func(t *testing.T, _ *testutils.TestServer) string {
+				return testutils.GetClosedHostPort(t)
+			},
+			wantFallback: true,
+		},
+		{
+			msg: "primary returns an error",
+			primaryPeer: func(_ *testing.T, ts *testutils.TestServer) string {
+				return ts.HostPort()
+			},
+			wantErrCode: ErrCodeDeclined,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+				var fallbackCalls atomic.Int32
+				testutils.RegisterEcho(ts.Server(), func() { fallbackCalls.Inc() })
+
+				client := ts.NewClient(nil)
+				client.GetSubChannel(ts.ServiceName(), Isolated).Peers().Add(ts.HostPort())
+				primary := client.GetSubChannel("primary", Isolated, FallbackService(ts.ServiceName()))
+				if tt.primaryPeer != nil {
+					primary.Peers().Add(tt.primaryPeer(t, ts))
+				}
+
+				ctx, cancel := NewContext(time.Second)
+				defer cancel()
+
+				_, _, _, err := raw.CallSC(ctx, primary, "echo", nil, nil)
+				if !tt.wantFallback {
+					require.Error(t, err, "Call should fail")
+					assert.Equal(t, tt.wantErrCode, GetSystemErrorCode(err), "Unexpected error code")
+					assert.EqualValues(t, 0, fallbackCalls.Load(), "Fallback service should not be called")
+					return
+				}
+
+				require.NoError(t, err, "Call should succeed using the fallback service")
+				assert.EqualValues(t, 1, fallbackCalls.Load(), "Fallback service should be called")
+			})
+		})
+	}
+}
+
+func TestOpaqueArg1(t *testing.T) {
+	routingKey := []byte{0xff, 0x00, 0xfe, '.', '\n', 0x80}
+
+	serverStats := newRecordingStatsReporter()
+	opts := testutils.NewOpts().SetStatsReporter(serverStats).NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		const service = "routed"
+		ts.Server().GetSubChannel(service, OpaqueArg1).SetHandler(HandlerFunc(func(ctx context.Context, call *InboundCall) {
+			// Echo arg1 back in arg3 so the client can verify it was unchanged.
+			if _, err := raw.ReadArgs(call); err != nil {
+				call.Response().SendSystemError(err)
+				return
+			}
+			require.NoError(t, raw.WriteResponse(call.Response(), &raw.Res{Arg3: call.Method()}))
+		}))
+
+		clientStats := newRecordingStatsReporter()
+		client := ts.NewClient(testutils.NewOpts().SetStatsReporter(clientStats))
+		client.Peers().Add(ts.HostPort())
+		sc := client.GetSubChannel(service, OpaqueArg1)
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		_, arg3, _, err := raw.CallSC(ctx, sc, string(routingKey), nil, nil)
+		require.NoError(t, err, "Call with binary arg1 failed")
+		assert.Equal(t, routingKey, arg3, "arg1 should round-trip unchanged")
+
+		// The binary arg1 is not used as the endpoint in stats.
+		clientStats.Lock()
+		for tags := range clientStats.Values["outbound.calls.send"] {
+			assert.Contains(t, tags, "target-endpoint = "+OpaqueEndpoint, "Unexpected outbound stats tags")
+		}
+		assert.Len(t, clientStats.Values["outbound.calls.send"], 1, "Expected outbound stats for the call")
+		clientStats.Unlock()
+
+		serverStats.Lock()
+		for tags := range serverStats.Values["inbound.calls.recvd"] {
+			assert.Contains(t, tags, "endpoint = "+OpaqueEndpoint, "Unexpected inbound stats tags")
+		}
+		assert.Len(t, serverStats.Values["inbound.calls.recvd"], 1, "Expected inbound stats for the call")
+		serverStats.Unlock()
+	})
+}
+
+func TestPreferredPeer(t *testing.T) {
+	var servers []*Channel
+	for i := 0; i < 2; i++ {
+		s := testutils.NewServer(t, nil)
+		defer s.Close()
+		testutils.RegisterFunc(s, "whoami", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			return &raw.Res{Arg3: []byte(s.PeerInfo().HostPort)}, nil
+		})
+		servers = append(servers, s)
+	}
+	owner, other := servers[0].PeerInfo().HostPort, servers[1].PeerInfo().HostPort
+
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+	sc := client.GetSubChannel(testutils.DefaultServerName, Isolated)
+	sc.Peers().Add(owner)
+	sc.Peers().Add(other)
+
+	call := func(preferred string) string {
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		res, err := raw.CallV2(ctx, sc, raw.CArgs{
+			Method:      "whoami",
+			CallOptions: &CallOptions{PreferredPeer: preferred},
+		})
+		require.NoError(t, err, "Call failed")
+		return string(res.Arg3)
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, owner, call(owner), "Call should use the preferred peer")
+		assert.Equal(t, other, call(other), "Call should use the preferred peer")
+	}
+
+	// Peers that aren't in the peer list are ignored.
+	assert.Contains(t, []string{owner, other}, call("1.1.1.1:1"), "Call should use peer selection")
+
+	// Once the preferred peer is down, calls fall back to other peers.
+	servers[0].Close()
+	ownerPeer := sc.Peers().GetOrAdd(owner)
+	require.True(t, testutils.WaitFor(time.Second, func() bool {
+		return !ownerPeer.HasActiveConnection()
+	}), "Client did not see the preferred peer's connection close")
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, other, call(owner), "Call should fall back when the preferred peer is down")
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+	ttlHandler := func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		deadline, _ := ctx.Deadline()
+		ttl := deadline.Sub(time.Now())
+		return &raw.Res{Arg3: []byte(strconv.FormatInt(int64(ttl), 10))}, nil
+	}
+	for _, service := range []string{server.ServiceName(), "default-timeout", "custom-timeout"} {
+		testutils.RegisterFunc(server.GetSubChannel(service), "ttl", ttlHandler)
+		testutils.RegisterFunc(server.GetSubChannel(service), "ttl-method", ttlHandler)
+	}
+	serverName := server.PeerInfo().ServiceName
+	hostPort := server.PeerInfo().HostPort
+
+	opts := testutils.NewOpts()
+	opts.DefaultTimeout = 2 * time.Second
+	client := testutils.NewClient(t, opts)
+	defer client.Close()
+
+	client.Peers().Add(hostPort)
+	client.GetSubChannel("default-timeout")
+	client.GetSubChannel("custom-timeout", DefaultTimeout(500*time.Millisecond)).SetMethodTimeout("ttl-method", 200*time.Millisecond)
+	client.GetSubChannel("default-timeout").SetMethodTimeout("ttl-method", 300*time.Millisecond)
+
+	explicitCtx, cancel := NewContext(5 * time.Second)
+	defer cancel()
+
+	tests := []struct {
+		msg     string
+		ctx     context.Context
+		call    func(ctx context.Context) ([]byte, error)
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{
+			msg: "channel default",
+			ctx: context.Background(),
+			call: func(ctx context.Context) ([]byte, error) {
+				_, arg3, _, err := raw.Call(ctx, client, hostPort, serverName, "ttl", nil, nil)
+				return arg3, err
+			},
+			wantMin: time.Second,
+			wantMax: 2 * time.Second,
+		},
+		{
+			msg: "subchannel without default uses channel default",
+			ctx: context.Background(),
+			call: func(ctx context.Context) ([]byte, error) {
+				_, arg3, _, err := raw.CallSC(ctx, client.GetSubChannel("default-timeout"), "ttl", nil, nil)
+				return arg3, err
+			},
+			wantMin: time.Second,
+			wantMax: 2 * time.Second,
+		},
+		{
+			msg: "subchannel default",
+			ctx: context.Background(),
+			call: func(ctx context.Context) ([]byte, error) {
+				_, arg3, _, err := raw.CallSC(ctx, client.GetSubChannel("custom-timeout"), "ttl", nil, nil)
+				return arg3, err
+			},
+			wantMin: 250 * time.Millisecond,
+			wantMax: 500 * time.Millisecond,
+		},
+		{
+			msg: "method timeout overrides subchannel default",
+			ctx: context.Background(),
+			call: func(ctx context.Context) ([]byte, error) {
+				_, arg3, _, err := raw.CallSC(ctx, client.GetSubChannel("custom-timeout"), "ttl-method", nil, nil)
+				return arg3, err
+			},
+			wantMin: 100 * time.Millisecond,
+			wantMax: 200 * time.Millisecond,
+		},
+		{
+			msg: "method timeout overrides channel default",
+			ctx: context.Background(),
+			call: func(ctx context.Context) ([]byte, error) {
+				_, arg3, _, err := raw.CallSC(ctx, client.GetSubChannel("default-timeout"), "ttl-method", nil, nil)
+				return arg3, err
+			},
+			wantMin: 150 * time.Millisecond,
+			wantMax: 300 * time.Millisecond,
+		},
+		{
+			msg: "context deadline overrides method timeout",
+			ctx: explicitCtx,
+			call: func(ctx context.Context) ([]byte, error) {
+				_, arg3, _, err := raw.CallSC(ctx, client.GetSubChannel("custom-timeout"), "ttl-method", nil, nil)
+				return arg3, err
+			},
+			wantMin: 4 * time.Second,
+			wantMax: 5 * time.Second,
+		},
+		{
+			msg: "context deadline overrides subchannel default",
+			ctx: explicitCtx,
+			call: func(ctx context.Context) ([]byte, error) {
+				_, arg3, _, err := raw.CallSC(ctx, client.GetSubChannel("custom-timeout"), "ttl", nil, nil)
+				return arg3, err
+			},
+			wantMin: 4 * time.Second,
+			wantMax: 5 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		arg3, err := tt.call(tt.ctx)
+		require.NoError(t, err, "%v: call failed", tt.msg)
+
+		ttl, err := strconv.ParseInt(string(arg3), 10, 64)
+		require.NoError(t, err, "%v: failed to parse TTL", tt.msg)
+		assert.True(t, time.Duration(ttl) > tt.wantMin && time.Duration(ttl) <= tt.wantMax,
+			"%v: unexpected TTL %v", tt.msg, time.Duration(ttl))
+	}
+}
+
+func TestSetMethodTimeoutRemove(t *testing.T) {
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	sc := client.GetSubChannel("svc")
+	sc.SetMethodTimeout("method", time.Second)
+	sc.SetMethodTimeout("method", 0)
+
+	client.Peers().Add("1.1.1.1:1")
+	_, err := sc.BeginCall(context.Background(), "method", nil)
+	assert.Equal(t, ErrTimeoutRequired, err, "Removed method timeout should not be used")
+}
+
+func TestMaxHandlerDuration(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	opts.MaxHandlerDuration = 100 * time.Millisecond
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterFunc(ts.Server(), "ttl", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			deadline, _ := ctx.Deadline()
+			return &raw.Res{Arg3: []byte(strconv.FormatInt(int64(time.Until(deadline)), 10))}, nil
+		})
+
+		ctx, cancel := NewContext(5 * time.Second)
+		defer cancel()
+		_, arg3, _, err := raw.Call(ctx, ts.Server(), ts.HostPort(), ts.ServiceName(), "ttl", nil, nil)
+		require.NoError(t, err, "Call failed")
+
+		ttl, err := strconv.ParseInt(string(arg3), 10, 64)
+		require.NoError(t, err, "Failed to parse TTL")
+		assert.True(t, time.Duration(ttl) <= 100*time.Millisecond,
+			"Handler deadline should be capped, got %v", time.Duration(ttl))
+	})
+}
+
+func TestNoDefaultTimeout(t *testing.T) {
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	client.Peers().Add("1.1.1.1:1")
+	_, err := client.GetSubChannel("svc").BeginCall(context.Background(), "method", nil)
+	assert.Equal(t, ErrTimeoutRequired, err, "Calls without a deadline or default timeout should fail")
+}
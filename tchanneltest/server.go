@@ -0,0 +1,294 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package tchanneltest provides an in-process mock TChannel server for tests.
+//
+// Tests declare the calls they expect the server to receive, along with the
+// response to each call, and then assert that every expected call was made:
+//
+//	server := tchanneltest.NewServer(t, "svc", nil)
+//	defer server.Close()
+//
+//	server.Expect("caller", "svc", "echo").Returns(nil, []byte("hello"))
+//	// ... make calls to server.HostPort() ...
+//	server.AssertExpectations(t)
+package tchanneltest
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/raw"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// Ensure that the Server implements tchannel.Handler.
+var _ tchannel.Handler = (*Server)(nil)
+
+// HandlerFunc returns the response for a call, and can be used for responses
+// that depend on the call's arguments.
+type HandlerFunc func(ctx context.Context, args *raw.Args) (*raw.Res, error)
+
+// Expectation is an expected call to a Server. Its methods return the
+// Expectation, so they can be chained to declare the response to the call.
+type Expectation struct {
+	caller, service, method string
+
+	// arg2 and arg3 are only set if the call must have the given args.
+	matchArgs  bool
+	arg2, arg3 []byte
+
+	// times is the number of calls expected, or 0 for any number of calls.
+	times   int
+	delay   time.Duration
+	handler HandlerFunc
+
+	// calls are the args of the calls matching the expectation, and are
+	// protected by the server's mutex.
+	mu    *sync.Mutex
+	calls []*raw.Args
+}
+
+// WithArgs expects the call to have the given arg2 and arg3. Calls with other
+// args don't match the expectation.
+func (e *Expectation) WithArgs(arg2, arg3 []byte) *Expectation {
+	e.matchArgs = true
+	e.arg2 = arg2
+	e.arg3 = arg3
+	return e
+}
+
+// Times expects the call to be made n times. By default, a call is expected
+// once. Calls beyond n don't match the expectation.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// AnyTimes allows the call to be made any number of times, including none.
+func (e *Expectation) AnyTimes() *Expectation {
+	e.times = 0
+	return e
+}
+
+// Delay delays the response by d. If the call's context ends first, no
+// response is sent.
+func (e *Expectation) Delay(d time.Duration) *Expectation {
+	e.delay = d
+	return e
+}
+
+// Returns responds to the call with the given arg2 and arg3.
+func (e *Expectation) Returns(arg2, arg3 []byte) *Expectation {
+	return e.Do(func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		return &raw.Res{Arg2: arg2, Arg3: arg3}, nil
+	})
+}
+
+// ReturnsAppError responds to the call with an application error using the
+// given arg2 and arg3.
+func (e *Expectation) ReturnsAppError(arg2, arg3 []byte) *Expectation {
+	return e.Do(func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		return &raw.Res{IsErr: true, Arg2: arg2, Arg3: arg3}, nil
+	})
+}
+
+// ReturnsError responds to the call with an error frame for err. Errors that
+// are not a tchannel.SystemError are sent as ErrCodeUnexpected.
+func (e *Expectation) ReturnsError(err error) *Expectation {
+	return e.Do(func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		return nil, err
+	})
+}
+
+// Do uses f to respond to the call.
+func (e *Expectation) Do(f HandlerFunc) *Expectation {
+	e.handler = f
+	return e
+}
+
+// Calls returns the args of the calls that matched the expectation.
+func (e *Expectation) Calls() []*raw.Args {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]*raw.Args(nil), e.calls...)
+}
+
+func (e *Expectation) String() string {
+	return fmt.Sprintf("%s->%s::%s", e.caller, e.service, e.method)
+}
+
+// matches returns whether the call can be used for this expectation. The
+// caller must hold the server's lock.
+func (e *Expectation) matches(service string, args *raw.Args) bool {
+	if e.caller != "" && e.caller != args.Caller {
+		return false
+	}
+	if e.service != service || e.method != args.Method {
+		return false
+	}
+	if e.matchArgs && !(bytes.Equal(e.arg2, args.Arg2) && bytes.Equal(e.arg3, args.Arg3)) {
+		return false
+	}
+	return e.times == 0 || len(e.calls) < e.times
+}
+
+// Server is an in-process TChannel server that responds to calls using the
+// expectations declared with Expect. Calls that don't match any expectation
+// fail with ErrCodeBadRequest, and are reported by AssertExpectations.
+type Server struct {
+	ch *tchannel.Channel
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	unexpected   []string
+}
+
+// NewServer returns a Server for the given service that is listening on a
+// random local port. opts may be nil. The server handles calls for all
+// services, so any Handler in opts is ignored.
+func NewServer(t testing.TB, serviceName string, opts *tchannel.ChannelOptions) *Server {
+	s := &Server{}
+
+	chOpts := tchannel.ChannelOptions{}
+	if opts != nil {
+		chOpts = *opts
+	}
+	chOpts.Handler = s
+
+	ch, err := tchannel.NewChannel(serviceName, &chOpts)
+	require.NoError(t, err, "NewChannel failed")
+	require.NoError(t, ch.ListenAndServe("127.0.0.1:0"), "ListenAndServe failed")
+	s.ch = ch
+	return s
+}
+
+// Channel returns the server's channel.
+func (s *Server) Channel() *tchannel.Channel {
+	return s.ch
+}
+
+// HostPort returns the host:port the server is listening on.
+func (s *Server) HostPort() string {
+	return s.ch.PeerInfo().HostPort
+}
+
+// ServiceName returns the server's service name.
+func (s *Server) ServiceName() string {
+	return s.ch.ServiceName()
+}
+
+// Close closes the server's channel.
+func (s *Server) Close() {
+	s.ch.Close()
+}
+
+// Expect declares an expected call from caller to service::method, which is
+// expected once, and responds with empty args. An empty caller matches calls
+// from any caller. Expectations are matched in the order they're declared.
+func (s *Server) Expect(caller, service, method string) *Expectation {
+	e := &Expectation{
+		caller:  caller,
+		service: service,
+		method:  method,
+		times:   1,
+		mu:      &s.mu,
+	}
+	e.Returns(nil, nil)
+
+	s.mu.Lock()
+	s.expectations = append(s.expectations, e)
+	s.mu.Unlock()
+	return e
+}
+
+// AssertExpectations asserts that every expected call was made the expected
+// number of times, and that there were no unexpected calls.
+func (s *Server) AssertExpectations(t testing.TB) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ok := true
+	for _, e := range s.expectations {
+		if e.times > 0 && len(e.calls) != e.times {
+			ok = assert.Fail(t, "Unmet expectation",
+				"Expected %v call %v times, got %v calls.", e, e.times, len(e.calls))
+		}
+	}
+	if len(s.unexpected) > 0 {
+		ok = assert.Fail(t, "Unexpected calls", "Got unexpected calls: %v", s.unexpected)
+	}
+	return ok
+}
+
+// Handle handles calls to the server, and implements tchannel.Handler.
+func (s *Server) Handle(ctx context.Context, call *tchannel.InboundCall) {
+	args, err := raw.ReadArgs(call)
+	if err != nil {
+		call.Response().SendSystemError(err)
+		return
+	}
+
+	e := s.match(call.ServiceName(), args)
+	if e == nil {
+		call.Response().SendSystemError(tchannel.NewSystemError(tchannel.ErrCodeBadRequest,
+			"unexpected call %s->%s::%s", args.Caller, call.ServiceName(), args.Method))
+		return
+	}
+
+	if e.delay > 0 {
+		select {
+		case <-time.After(e.delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	resp, err := e.handler(ctx, args)
+	if err != nil {
+		resp = &raw.Res{SystemErr: err}
+	}
+	raw.WriteResponse(call.Response(), resp)
+}
+
+// match returns the first expectation matching the call, and records the
+// call. It returns nil and records an unexpected call if there's no match.
+func (s *Server) match(service string, args *raw.Args) *Expectation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.expectations {
+		if e.matches(service, args) {
+			e.calls = append(e.calls, args)
+			return e
+		}
+	}
+
+	s.unexpected = append(s.unexpected, fmt.Sprintf("%s->%s::%s", args.Caller, service, args.Method))
+	return nil
+}
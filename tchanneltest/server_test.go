@@ -0,0 +1,144 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchanneltest_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/tchanneltest"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// recordingT records failures instead of failing the test, so tests can
+// check that AssertExpectations fails.
+type recordingT struct {
+	testing.TB
+
+	failures []string
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Helper() {}
+
+func call(t *testing.T, server *tchanneltest.Server, method string, arg2, arg3 []byte) (*raw.CRes, error) {
+	client := testutils.NewClient(t, testutils.NewOpts().SetServiceName("caller"))
+	defer client.Close()
+
+	ctx, cancel := tchannel.NewContext(testutils.Timeout(100 * time.Millisecond))
+	defer cancel()
+
+	sc := client.GetSubChannel(server.ServiceName())
+	sc.Peers().Add(server.HostPort())
+	return raw.CallV2(ctx, sc, raw.CArgs{Method: method, Arg2: arg2, Arg3: arg3})
+}
+
+func TestServerReturns(t *testing.T) {
+	server := tchanneltest.NewServer(t, "svc", nil)
+	defer server.Close()
+
+	e := server.Expect("caller", "svc", "echo").WithArgs([]byte("a2"), []byte("a3")).Returns([]byte("r2"), []byte("r3"))
+	server.Expect("", "svc", "fail").ReturnsAppError(nil, []byte("failed"))
+
+	res, err := call(t, server, "echo", []byte("a2"), []byte("a3"))
+	require.NoError(t, err, "Call failed")
+	assert.Equal(t, []byte("r2"), res.Arg2, "Unexpected arg2")
+	assert.Equal(t, []byte("r3"), res.Arg3, "Unexpected arg3")
+
+	res, err = call(t, server, "fail", nil, nil)
+	require.NoError(t, err, "Call failed")
+	assert.True(t, res.AppError, "Expected application error")
+	assert.Equal(t, []byte("failed"), res.Arg3, "Unexpected arg3")
+
+	if calls := e.Calls(); assert.Len(t, calls, 1, "Unexpected calls") {
+		assert.Equal(t, "caller", calls[0].Caller, "Unexpected caller")
+	}
+	server.AssertExpectations(t)
+}
+
+func TestServerErrors(t *testing.T) {
+	server := tchanneltest.NewServer(t, "svc", nil)
+	defer server.Close()
+
+	server.Expect("caller", "svc", "busy").Times(2).ReturnsError(tchannel.ErrServerBusy)
+	server.Expect("caller", "svc", "slow").Delay(time.Second)
+
+	for i := 0; i < 2; i++ {
+		_, err := call(t, server, "busy", nil, nil)
+		assert.Equal(t, tchannel.ErrCodeBusy, tchannel.GetSystemErrorCode(err), "Unexpected error")
+	}
+
+	_, err := call(t, server, "slow", nil, nil)
+	assert.Equal(t, tchannel.ErrCodeTimeout, tchannel.GetSystemErrorCode(err), "Expected delayed call to time out")
+	server.AssertExpectations(t)
+}
+
+func TestServerDo(t *testing.T) {
+	server := tchanneltest.NewServer(t, "svc", nil)
+	defer server.Close()
+
+	server.Expect("caller", "svc", "echo").AnyTimes().Do(func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		return &raw.Res{Arg2: args.Arg3, Arg3: args.Arg2}, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		res, err := call(t, server, "echo", []byte("a2"), []byte("a3"))
+		require.NoError(t, err, "Call failed")
+		assert.Equal(t, []byte("a3"), res.Arg2, "Unexpected arg2")
+		assert.Equal(t, []byte("a2"), res.Arg3, "Unexpected arg3")
+	}
+	server.AssertExpectations(t)
+}
+
+func TestServerUnmetExpectations(t *testing.T) {
+	server := tchanneltest.NewServer(t, "svc", nil)
+	defer server.Close()
+
+	server.Expect("caller", "svc", "echo").Times(2)
+	server.Expect("caller", "svc", "args").WithArgs(nil, []byte("expected"))
+
+	_, err := call(t, server, "echo", nil, nil)
+	require.NoError(t, err, "Call failed")
+
+	// Calls that don't match any expectation fail.
+	_, err = call(t, server, "unknown", nil, nil)
+	assert.Equal(t, tchannel.ErrCodeBadRequest, tchannel.GetSystemErrorCode(err), "Unexpected error")
+	_, err = call(t, server, "args", nil, []byte("other"))
+	assert.Equal(t, tchannel.ErrCodeBadRequest, tchannel.GetSystemErrorCode(err), "Unexpected error")
+
+	rt := &recordingT{TB: t}
+	assert.False(t, server.AssertExpectations(rt), "Expected unmet expectations")
+	require.Len(t, rt.failures, 3, "Unexpected failures: %v", rt.failures)
+	assert.Contains(t, rt.failures[0], "caller->svc::echo call 2 times, got 1 calls")
+	assert.Contains(t, rt.failures[1], "caller->svc::args call 1 times, got 0 calls")
+	assert.Contains(t, rt.failures[2], "caller->svc::unknown")
+	assert.Contains(t, rt.failures[2], "caller->svc::args")
+}
@@ -45,6 +45,15 @@ type FakeIncomingCall struct {
 
 	// RoutingDelegateF is the routing delegate.
 	RoutingDelegateF string
+
+	// BestEffortF is whether the call is best-effort.
+	BestEffortF bool
+
+	// PriorityF is the priority of the call.
+	PriorityF tchannel.CallPriority
+
+	// ArgSchemeF is the format of the call's arguments.
+	ArgSchemeF string
 }
 
 // CallerName returns the caller name as specified in the fake call.
@@ -67,6 +76,21 @@ func (f *FakeIncomingCall) RoutingDelegate() string {
 	return f.RoutingDelegateF
 }
 
+// BestEffort returns whether the call is best-effort as specified in the fake call.
+func (f *FakeIncomingCall) BestEffort() bool {
+	return f.BestEffortF
+}
+
+// Priority returns the priority as specified in the fake call.
+func (f *FakeIncomingCall) Priority() tchannel.CallPriority {
+	return f.PriorityF
+}
+
+// ArgScheme returns the arg scheme as specified in the fake call.
+func (f *FakeIncomingCall) ArgScheme() string {
+	return f.ArgSchemeF
+}
+
 // LocalPeer returns the local peer information for this call.
 func (f *FakeIncomingCall) LocalPeer() tchannel.LocalPeerInfo {
 	return f.LocalPeerF
@@ -80,9 +104,12 @@ func (f *FakeIncomingCall) RemotePeer() tchannel.PeerInfo {
 // CallOptions returns the incoming call options suitable for proxying a request.
 func (f *FakeIncomingCall) CallOptions() *tchannel.CallOptions {
 	return &tchannel.CallOptions{
+		Format:          tchannel.Format(f.ArgScheme()),
 		ShardKey:        f.ShardKey(),
 		RoutingKey:      f.RoutingKey(),
 		RoutingDelegate: f.RoutingDelegate(),
+		BestEffort:      f.BestEffort(),
+		Priority:        f.Priority(),
 	}
 }
 
@@ -93,7 +120,7 @@ func NewIncomingCall(callerName string) tchannel.IncomingCall {
 
 // FakeCallFrame is a stub implementation of the CallFrame interface.
 type FakeCallFrame struct {
-	ServiceF, MethodF, CallerF, RoutingKeyF, RoutingDelegateF string
+	ServiceF, MethodF, CallerF, RoutingKeyF, RoutingDelegateF, ShardKeyF string
 }
 
 var _ relay.CallFrame = FakeCallFrame{}
@@ -122,3 +149,8 @@ func (f FakeCallFrame) RoutingKey() []byte {
 func (f FakeCallFrame) RoutingDelegate() []byte {
 	return []byte(f.RoutingDelegateF)
 }
+
+// ShardKey returns the shard key field.
+func (f FakeCallFrame) ShardKey() []byte {
+	return []byte(f.ShardKeyF)
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package testutils
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/raw"
+
+	"golang.org/x/net/context"
+)
+
+// CapturedCall is a call captured by a CallRecorder, which can be replayed
+// using ReplayCall. Captured calls are serialized as JSON, with one call per
+// line, using WriteCapturedCalls and ReadCapturedCalls. Args are serialized as
+// base64 strings.
+type CapturedCall struct {
+	Service         string          `json:"service"`
+	Method          string          `json:"method"`
+	Format          tchannel.Format `json:"format,omitempty"`
+	CallerName      string          `json:"callerName,omitempty"`
+	ShardKey        string          `json:"shardKey,omitempty"`
+	RoutingKey      string          `json:"routingKey,omitempty"`
+	RoutingDelegate string          `json:"routingDelegate,omitempty"`
+	BestEffort      bool            `json:"bestEffort,omitempty"`
+	Arg2            []byte          `json:"arg2"`
+	Arg3            []byte          `json:"arg3"`
+
+	// Response is the response sent by the handler, if it sent one.
+	Response *CapturedResponse `json:"response,omitempty"`
+}
+
+// CapturedResponse is the response to a CapturedCall.
+type CapturedResponse struct {
+	IsErr bool   `json:"isErr,omitempty"`
+	Arg2  []byte `json:"arg2"`
+	Arg3  []byte `json:"arg3"`
+}
+
+// CallRecorder is a tchannel.Handler that captures each call before
+// passing it to a raw.Handler.
+type CallRecorder struct {
+	sync.Mutex
+
+	handler raw.Handler
+	calls   []CapturedCall
+}
+
+// NewCallRecorder returns a CallRecorder that captures calls to the given handler.
+func NewCallRecorder(handler raw.Handler) *CallRecorder {
+	return &CallRecorder{handler: handler}
+}
+
+// Handle captures the call and its response, and implements tchannel.Handler.
+func (r *CallRecorder) Handle(ctx context.Context, call *tchannel.InboundCall) {
+	args, err := raw.ReadArgs(call)
+	if err != nil {
+		r.handler.OnError(ctx, err)
+		return
+	}
+
+	captured := CapturedCall{
+		Service:         call.ServiceName(),
+		Method:          args.Method,
+		Format:          args.Format,
+		CallerName:      args.Caller,
+		ShardKey:        call.ShardKey(),
+		RoutingKey:      call.RoutingKey(),
+		RoutingDelegate: call.RoutingDelegate(),
+		BestEffort:      call.BestEffort(),
+		Arg2:            args.Arg2,
+		Arg3:            args.Arg3,
+	}
+
+	resp, err := r.handler.Handle(ctx, args)
+	if err != nil {
+		resp = &raw.Res{SystemErr: err}
+	}
+	if resp.SystemErr == nil {
+		captured.Response = &CapturedResponse{
+			IsErr: resp.IsErr,
+			Arg2:  resp.Arg2,
+			Arg3:  resp.Arg3,
+		}
+	}
+
+	r.Lock()
+	r.calls = append(r.calls, captured)
+	r.Unlock()
+
+	if err := raw.WriteResponse(call.Response(), resp); err != nil {
+		r.handler.OnError(ctx, err)
+	}
+}
+
+// Calls returns the calls captured so far, in the order they were received.
+func (r *CallRecorder) Calls() []CapturedCall {
+	r.Lock()
+	defer r.Unlock()
+
+	return append([]CapturedCall(nil), r.calls...)
+}
+
+// WriteCapturedCalls serializes the given calls to w.
+func WriteCapturedCalls(w io.Writer, calls []CapturedCall) error {
+	enc := json.NewEncoder(w)
+	for _, call := range calls {
+		if err := enc.Encode(call); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadCapturedCalls reads calls serialized using WriteCapturedCalls from r.
+func ReadCapturedCalls(r io.Reader) ([]CapturedCall, error) {
+	var calls []CapturedCall
+	dec := json.NewDecoder(r)
+	for {
+		var call CapturedCall
+		if err := dec.Decode(&call); err == io.EOF {
+			return calls, nil
+		} else if err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+}
+
+// ReplayCall re-issues a captured call from ch to the given hostPort, using
+// the same service, method, transport headers and args, and returns the
+// response. The caller name is always ch's service name.
+func ReplayCall(ctx context.Context, ch *tchannel.Channel, hostPort string, call CapturedCall) (*CapturedResponse, error) {
+	outbound, err := ch.BeginCall(ctx, hostPort, call.Service, call.Method, &tchannel.CallOptions{
+		Format:          call.Format,
+		ShardKey:        call.ShardKey,
+		RoutingKey:      call.RoutingKey,
+		RoutingDelegate: call.RoutingDelegate,
+		BestEffort:      call.BestEffort,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	arg2, arg3, resp, err := raw.WriteArgs(outbound, call.Arg2, call.Arg3)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CapturedResponse{
+		IsErr: resp.ApplicationError(),
+		Arg2:  arg2,
+		Arg3:  arg3,
+	}, nil
+}
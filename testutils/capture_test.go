@@ -0,0 +1,99 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package testutils
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/raw"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestCaptureReplay(t *testing.T) {
+	handler := func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		call := tchannel.CurrentCall(ctx)
+		return &raw.Res{
+			IsErr: string(args.Arg2) == "fail",
+			Arg2:  []byte(call.ShardKey()),
+			Arg3:  append([]byte(args.Method+":"), args.Arg3...),
+		}, nil
+	}
+
+	var captured []CapturedCall
+	WithTestServer(t, nil, func(ts *TestServer) {
+		recorder := NewCallRecorder(rawFuncHandler{ts.Server(), handler})
+		ts.Server().Register(recorder, "capture")
+
+		client := ts.NewClient(nil)
+		for _, arg2 := range []string{"ok", "fail"} {
+			ctx, cancel := tchannel.NewContext(Timeout(time.Second))
+			call, err := client.BeginCall(ctx, ts.HostPort(), ts.ServiceName(), "capture", &tchannel.CallOptions{
+				Format:     tchannel.JSON,
+				ShardKey:   "shard",
+				RoutingKey: "rk",
+			})
+			require.NoError(t, err, "BeginCall failed")
+			_, _, _, err = raw.WriteArgs(call, []byte(arg2), RandBytes(1000))
+			require.NoError(t, err, "Call failed")
+			cancel()
+		}
+
+		captured = recorder.Calls()
+		require.Len(t, captured, 2, "Expected both calls to be captured")
+		for _, call := range captured {
+			assert.Equal(t, ts.ServiceName(), call.Service, "Unexpected service")
+			assert.Equal(t, "capture", call.Method, "Unexpected method")
+			assert.Equal(t, tchannel.JSON, call.Format, "Unexpected format")
+			assert.Equal(t, client.ServiceName(), call.CallerName, "Unexpected caller")
+			assert.Equal(t, "shard", call.ShardKey, "Unexpected shard key")
+			assert.Equal(t, "rk", call.RoutingKey, "Unexpected routing key")
+			require.NotNil(t, call.Response, "Expected response to be captured")
+		}
+		assert.False(t, captured[0].Response.IsErr, "First call should succeed")
+		assert.True(t, captured[1].Response.IsErr, "Second call should fail")
+	})
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteCapturedCalls(buf, captured), "WriteCapturedCalls failed")
+	read, err := ReadCapturedCalls(buf)
+	require.NoError(t, err, "ReadCapturedCalls failed")
+	assert.Equal(t, captured, read, "Captured calls changed after serialization")
+
+	// Replay the calls against a new server running the same handler.
+	WithTestServer(t, nil, func(ts *TestServer) {
+		RegisterFunc(ts.Server(), "capture", handler)
+
+		client := ts.NewClient(nil)
+		for _, call := range read {
+			ctx, cancel := tchannel.NewContext(Timeout(time.Second))
+			resp, err := ReplayCall(ctx, client, ts.HostPort(), call)
+			cancel()
+			require.NoError(t, err, "ReplayCall failed")
+			assert.Equal(t, call.Response, resp, "Replayed call got a different response")
+		}
+	})
+}
@@ -21,6 +21,7 @@
 package testutils
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 
@@ -32,6 +33,7 @@ import (
 )
 
 // NewServerChannel creates a TChannel that is listening and returns the channel.
+// If opts.TLSConfig is set, the channel accepts connections over TLS.
 // Passed in options may be mutated (for post-verification of state).
 func NewServerChannel(opts *ChannelOpts) (*tchannel.Channel, error) {
 	opts = opts.Copy()
@@ -44,6 +46,9 @@ func NewServerChannel(opts *ChannelOpts) (*tchannel.Channel, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not get listening port from %v: %v", l.Addr().String(), err)
 	}
+	if opts.TLSConfig != nil {
+		l = tls.NewListener(l, opts.TLSConfig)
+	}
 
 	serviceName := defaultString(opts.ServiceName, DefaultServerName)
 	opts.ProcessName = defaultString(opts.ProcessName, serviceName+"-"+port)
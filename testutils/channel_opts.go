@@ -21,14 +21,18 @@
 package testutils
 
 import (
+	"crypto/tls"
 	"flag"
+	"net"
 	"testing"
 	"time"
 
 	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/relay"
 	"github.com/uber/tchannel-go/tos"
 
 	"github.com/uber-go/atomic"
+	"golang.org/x/net/context"
 )
 
 var connectionLog = flag.Bool("connectionLog", false, "Enables connection logging in tests")
@@ -119,12 +123,44 @@ func (o *ChannelOpts) SetFramePool(framePool tchannel.FramePool) *ChannelOpts {
 	return o
 }
 
+// SetChecksumType sets ChecksumType in DefaultConnectionOptions.
+func (o *ChannelOpts) SetChecksumType(checksumType tchannel.ChecksumType) *ChannelOpts {
+	o.DefaultConnectionOptions.ChecksumType = checksumType
+	return o
+}
+
 // SetHealthChecks sets HealthChecks in DefaultConnectionOptions.
 func (o *ChannelOpts) SetHealthChecks(healthChecks tchannel.HealthCheckOptions) *ChannelOpts {
 	o.DefaultConnectionOptions.HealthChecks = healthChecks
 	return o
 }
 
+// SetKeepAlive sets KeepAlive in ChannelOptions.
+func (o *ChannelOpts) SetKeepAlive(keepAlive tchannel.KeepAliveOptions) *ChannelOpts {
+	o.KeepAlive = keepAlive
+	return o
+}
+
+// SetDispatchPool sets DispatchPool in ChannelOptions.
+func (o *ChannelOpts) SetDispatchPool(dispatchPool tchannel.DispatchPoolOptions) *ChannelOpts {
+	o.DispatchPool = dispatchPool
+	return o
+}
+
+// SetCompression sets Compression in DefaultConnectionOptions.
+func (o *ChannelOpts) SetCompression(compression tchannel.CompressionType) *ChannelOpts {
+	o.DefaultConnectionOptions.Compression = compression
+	return o
+}
+
+// SetArgCompression sets ArgCompression and ArgCompressionThreshold in
+// DefaultConnectionOptions.
+func (o *ChannelOpts) SetArgCompression(threshold int, codecs ...string) *ChannelOpts {
+	o.DefaultConnectionOptions.ArgCompression = codecs
+	o.DefaultConnectionOptions.ArgCompressionThreshold = threshold
+	return o
+}
+
 // SetSendBufferSize sets the SendBufferSize in DefaultConnectionOptions.
 func (o *ChannelOpts) SetSendBufferSize(bufSize int) *ChannelOpts {
 	o.DefaultConnectionOptions.SendBufferSize = bufSize
@@ -137,6 +173,18 @@ func (o *ChannelOpts) SetTosPriority(tosPriority tos.ToS) *ChannelOpts {
 	return o
 }
 
+// SetDialer sets the dialer used for outbound connections.
+func (o *ChannelOpts) SetDialer(f func(context.Context, string, string) (net.Conn, error)) *ChannelOpts {
+	o.ChannelOptions.Dialer = f
+	return o
+}
+
+// SetTLSConfig sets TLSConfig in ChannelOptions.
+func (o *ChannelOpts) SetTLSConfig(config *tls.Config) *ChannelOpts {
+	o.ChannelOptions.TLSConfig = config
+	return o
+}
+
 // SetTimeNow sets TimeNow in ChannelOptions.
 func (o *ChannelOpts) SetTimeNow(timeNow func() time.Time) *ChannelOpts {
 	o.TimeNow = timeNow
@@ -212,6 +260,69 @@ func (o *ChannelOpts) SetRelayMaxTimeout(d time.Duration) *ChannelOpts {
 	return o
 }
 
+// SetRelayAdjustTTL sets the function used to adjust the TTL of relayed calls.
+func (o *ChannelOpts) SetRelayAdjustTTL(f func(relay.CallFrame, time.Duration) time.Duration) *ChannelOpts {
+	o.ChannelOptions.RelayAdjustTTL = f
+	return o
+}
+
+// SetRelayTimeoutPolicy sets the policy used to rewrite the TTL of relayed calls.
+func (o *ChannelOpts) SetRelayTimeoutPolicy(policy *tchannel.RelayTimeoutPolicy) *ChannelOpts {
+	o.ChannelOptions.RelayTimeoutPolicy = policy
+	return o
+}
+
+// SetRelayCallObserver sets the observer notified of relayed call lifecycle events.
+func (o *ChannelOpts) SetRelayCallObserver(observer tchannel.RelayCallObserver) *ChannelOpts {
+	o.ChannelOptions.RelayCallObserver = observer
+	return o
+}
+
+// SetRelayStats sets the stats notified of the outcome of relayed calls.
+func (o *ChannelOpts) SetRelayStats(stats relay.Stats) *ChannelOpts {
+	o.ChannelOptions.RelayStats = stats
+	return o
+}
+
+// SetCallTracer sets the CallTracer used to create spans for calls.
+func (o *ChannelOpts) SetCallTracer(tracer tchannel.CallTracer) *ChannelOpts {
+	o.ChannelOptions.CallTracer = tracer
+	return o
+}
+
+// AddRelayInterceptors adds interceptors that are run for relayed calls.
+func (o *ChannelOpts) AddRelayInterceptors(interceptors ...tchannel.RelayInterceptor) *ChannelOpts {
+	o.ChannelOptions.RelayInterceptors = append(o.ChannelOptions.RelayInterceptors, interceptors...)
+	return o
+}
+
+// SetRelayRateLimiter sets the rate limiter consulted for relayed calls.
+func (o *ChannelOpts) SetRelayRateLimiter(limiter relay.RateLimiter) *ChannelOpts {
+	o.ChannelOptions.RelayRateLimiter = limiter
+	return o
+}
+
+// SetRelayMaxPeerRetries sets the number of times the relay retries a call on
+// a different peer when it fails to connect to the selected peer.
+func (o *ChannelOpts) SetRelayMaxPeerRetries(n int) *ChannelOpts {
+	o.ChannelOptions.RelayMaxPeerRetries = n
+	return o
+}
+
+// SetRelayFailoverCooldown sets how long the relay fails over calls away
+// from a destination after its connection fails.
+func (o *ChannelOpts) SetRelayFailoverCooldown(d time.Duration) *ChannelOpts {
+	o.ChannelOptions.RelayFailoverCooldown = d
+	return o
+}
+
+// SetRelayConnectionsPerPeer sets the number of connections the relay uses
+// for each destination.
+func (o *ChannelOpts) SetRelayConnectionsPerPeer(n int) *ChannelOpts {
+	o.ChannelOptions.RelayConnectionsPerPeer = n
+	return o
+}
+
 // SetOnPeerStatusChanged sets the callback for channel status change
 // noficiations.
 func (o *ChannelOpts) SetOnPeerStatusChanged(f func(*tchannel.Peer)) *ChannelOpts {
@@ -402,6 +402,9 @@ func comparableState(ch *tchannel.Channel, opts *tchannel.IntrospectionOptions)
 
 	// Tests start with ChannelClient or ChannelListening, but end with ChannelClosed.
 	s.ChannelState = ""
+
+	// Circuit breakers are created for each relayed method, and aren't leaks.
+	s.RelayCircuitBreakers = nil
 	return s
 }
 
@@ -21,6 +21,8 @@
 package thrift
 
 import (
+	"fmt"
+
 	"github.com/uber/tchannel-go"
 	"github.com/uber/tchannel-go/internal/argreader"
 
@@ -40,6 +42,10 @@ type client struct {
 type ClientOptions struct {
 	// HostPort specifies a specific server to hit.
 	HostPort string
+
+	// ErrorMapper converts errors returned by calls, such as system errors,
+	// into errors returned to the caller.
+	ErrorMapper ErrorMapper
 }
 
 // NewClient returns a Client that makes calls over the given tchannel to the given Hyperbahn service.
@@ -133,7 +139,7 @@ func (c *client) Call(ctx Context, thriftService, methodName string, req, resp t
 		isOK        bool
 	)
 
-	err := c.ch.RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
+	err := c.sc.RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
 		respHeaders, isOK = nil, false
 
 		call, err := c.startCall(ctx, thriftService+"::"+methodName, &tchannel.CallOptions{
@@ -152,9 +158,56 @@ func (c *client) Call(ctx Context, thriftService, methodName string, req, resp t
 		return err
 	})
 	if err != nil {
+		if c.opts.ErrorMapper != nil {
+			err = mapError(c.opts.ErrorMapper, methodName, err)
+		}
 		return false, err
 	}
 
 	ctx.SetResponseHeaders(respHeaders)
 	return isOK, nil
 }
+
+// CallOneway sends the request for a oneway method, and returns once the
+// request has been written. The server completes oneway calls with an empty
+// response after the handler runs, which is discarded in the background.
+func (c *client) CallOneway(ctx Context, thriftService, methodName string, req thrift.TStruct) error {
+	headers := ctx.Headers()
+
+	return c.sc.RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
+		call, err := c.startCall(ctx, thriftService+"::"+methodName, &tchannel.CallOptions{
+			Format:       tchannel.Thrift,
+			RequestState: rs,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := writeArgs(call, headers, req); err != nil {
+			return err
+		}
+
+		go discardResponse(call.Response())
+		return nil
+	})
+}
+
+// discardResponse reads the response to a call, which completes the call,
+// and discards it.
+func discardResponse(response *tchannel.OutboundCallResponse) {
+	var arg2, arg3 []byte
+	if err := tchannel.NewArgReader(response.Arg2Reader()).Read(&arg2); err != nil {
+		return
+	}
+	tchannel.NewArgReader(response.Arg3Reader()).Read(&arg3)
+}
+
+// CallOneway calls a oneway method using the given client, which must
+// implement TChanOnewayClient. It's used by the generated client code.
+func CallOneway(ctx Context, client TChanClient, thriftService, methodName string, req thrift.TStruct) error {
+	onewayClient, ok := client.(TChanOnewayClient)
+	if !ok {
+		return fmt.Errorf("client %T does not support oneway methods", client)
+	}
+	return onewayClient.CallOneway(ctx, thriftService, methodName, req)
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package thrift
+
+import (
+	"io/ioutil"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// DispatchFunc handles a call to any method of a Thrift service. It's passed
+// the method name (without the service prefix) and the serialized request
+// args struct, and returns the serialized result struct. success is false if
+// the result contains an exception declared in the IDL.
+//
+// This is used to build Thrift-aware proxies that don't use generated code.
+type DispatchFunc func(ctx Context, method string, args []byte) (success bool, result []byte, err error)
+
+// rawStruct is a thrift.TStruct holding an already serialized struct.
+type rawStruct []byte
+
+func (s *rawStruct) Read(p thrift.TProtocol) error {
+	b, err := ioutil.ReadAll(p.Transport())
+	*s = b
+	return err
+}
+
+func (s *rawStruct) Write(p thrift.TProtocol) error {
+	_, err := p.Transport().Write(*s)
+	return err
+}
+
+// dispatchServer is a TChanServer that passes every call to a DispatchFunc.
+type dispatchServer struct {
+	service  string
+	methods  []string
+	dispatch DispatchFunc
+}
+
+func (s *dispatchServer) Service() string   { return s.service }
+func (s *dispatchServer) Methods() []string { return s.methods }
+
+func (s *dispatchServer) Handle(ctx Context, methodName string, protocol thrift.TProtocol) (bool, thrift.TStruct, error) {
+	var args rawStruct
+	if err := args.Read(protocol); err != nil {
+		return false, nil, err
+	}
+
+	success, result, err := s.dispatch(ctx, methodName, args)
+	if err != nil {
+		return false, nil, err
+	}
+
+	resp := rawStruct(result)
+	return success, &resp, nil
+}
+
+// RegisterDispatcher registers f to handle calls to all of the given methods
+// of a Thrift service with a single registration, rather than registering
+// generated code for each method. Headers, tracing, and RegisterOptions are
+// handled the same way as for Register.
+func (s *Server) RegisterDispatcher(service string, methods []string, f DispatchFunc, opts ...RegisterOption) {
+	s.Register(&dispatchServer{
+		service:  service,
+		methods:  methods,
+		dispatch: f,
+	}, opts...)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package thrift_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	tchannel "github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/testutils"
+	. "github.com/uber/tchannel-go/thrift"
+	gen "github.com/uber/tchannel-go/thrift/gen-go/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterDispatcher(t *testing.T) {
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	var dispatched []string
+	dispatch := func(ctx Context, method string, args []byte) (bool, []byte, error) {
+		dispatched = append(dispatched, method)
+		assert.Equal(t, "value", ctx.Headers()["key"], "Unexpected request headers")
+		ctx.SetResponseHeaders(map[string]string{"method": method})
+
+		switch method {
+		case "Call":
+			var req gen.SimpleServiceCallArgs
+			require.NoError(t, ReadStruct(bytes.NewReader(args), &req), "Failed to read args")
+			res := &gen.Data{B1: !req.Arg.B1, S2: req.Arg.S2 + "-res", I3: req.Arg.I3 + 1}
+			return true, serializeStruct(t, &gen.SimpleServiceCallResult{Success: res}), nil
+		case "Simple":
+			res := &gen.SimpleServiceSimpleResult{SimpleErr: &gen.SimpleErr{Message: "simple failed"}}
+			return false, serializeStruct(t, res), nil
+		default:
+			return false, nil, tchannel.NewSystemError(tchannel.ErrCodeBadRequest, "unsupported method %v", method)
+		}
+	}
+
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+	NewServer(server).RegisterDispatcher("SimpleService", []string{"Call", "Simple", "SimpleFuture"}, dispatch)
+
+	clientCh := testutils.NewClient(t, nil)
+	defer clientCh.Close()
+	clientCh.Peers().Add(server.PeerInfo().HostPort)
+	client := gen.NewTChanSimpleServiceClient(NewClient(clientCh, server.ServiceName(), nil))
+
+	callCtx := WithHeaders(ctx, map[string]string{"key": "value"})
+	res, err := client.Call(callCtx, &gen.Data{B1: true, S2: "req", I3: 1})
+	require.NoError(t, err, "Call failed")
+	assert.Equal(t, &gen.Data{B1: false, S2: "req-res", I3: 2}, res, "Unexpected Call result")
+	assert.Equal(t, map[string]string{"method": "Call"}, callCtx.ResponseHeaders(), "Unexpected response headers")
+
+	callCtx = WithHeaders(ctx, map[string]string{"key": "value"})
+	err = client.Simple(callCtx)
+	assert.Equal(t, &gen.SimpleErr{Message: "simple failed"}, err, "Unexpected Simple error")
+
+	callCtx = WithHeaders(ctx, map[string]string{"key": "value"})
+	err = client.SimpleFuture(callCtx)
+	assert.Equal(t, tchannel.ErrCodeBadRequest, tchannel.GetSystemErrorCode(err), "Unexpected SimpleFuture error")
+
+	assert.Equal(t, []string{"Call", "Simple", "SimpleFuture"}, dispatched, "Unexpected dispatched methods")
+}
@@ -29,6 +29,10 @@ and register the service using:
   // Any number of services can be registered on the same Thrift server.
   server.Register(gen.NewTChan[SERVICE2]Server(handler)
 
+Proxies that don't use generated code can handle every method of a service
+with a single DispatchFunc, which is passed the serialized args struct:
+  server.RegisterDispatcher("[SERVICE]", methods, dispatch)
+
 To use a Thrift client use the generated TChan client:
   thriftClient := thrift.NewClient(ch, "hyperbahnService", nil)
   client := gen.NewTChan[SERVICE]Client(thriftClient)
@@ -0,0 +1,146 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dynamic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/uber/tchannel-go/thrift"
+
+	athrift "github.com/apache/thrift/lib/go/thrift"
+)
+
+// Args returns the arguments of a call to the method as a Thrift struct,
+// where args is a map from argument name to value, or a Go struct with a
+// field for each argument.
+func (m *Method) Args(args interface{}) (athrift.TStruct, error) {
+	body, err := normalize(args)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := body.(map[string]interface{}); !ok && body != nil {
+		return nil, fmt.Errorf("expected arguments as a map or struct, got %T", args)
+	}
+
+	return dynamicStruct{write: func(p athrift.TProtocol) error {
+		return m.idl.writeStruct(p, m.file, m.m.Arguments, body)
+	}}, nil
+}
+
+// NewResult returns a Thrift struct that the method's result can be read into.
+func (m *Method) NewResult() *Result {
+	return &Result{m: m}
+}
+
+// Result is the result of a call to a method, which is either the return
+// value, or one of the method's exceptions.
+type Result struct {
+	m      *Method
+	fields map[string]interface{}
+}
+
+// Read implements athrift.TStruct.
+func (r *Result) Read(p athrift.TProtocol) error {
+	fields, err := r.m.idl.readStruct(p, r.m.file, r.m.resultFields())
+	r.fields = fields
+	return err
+}
+
+// Write implements athrift.TStruct.
+func (r *Result) Write(p athrift.TProtocol) error {
+	return r.m.idl.writeStruct(p, r.m.file, r.m.resultFields(), r.fields)
+}
+
+// Success returns the return value, which is nil for void methods.
+func (r *Result) Success() interface{} {
+	return r.fields["success"]
+}
+
+// Exception returns the exception that the method failed with, if any.
+func (r *Result) Exception() *Exception {
+	for _, ex := range r.m.m.Exceptions {
+		if v, ok := r.fields[ex.Name]; ok {
+			return &Exception{Name: ex.Name, Value: v}
+		}
+	}
+	return nil
+}
+
+// Exception is an exception declared by a method that a call failed with.
+type Exception struct {
+	// Name is the name of the method's exception field.
+	Name string
+	// Value is the decoded exception struct.
+	Value interface{}
+}
+
+func (e *Exception) Error() string {
+	return fmt.Sprintf("thrift exception %v: %v", e.Name, e.Value)
+}
+
+// Client makes Thrift calls to a service using an IDL.
+type Client struct {
+	idl    *IDL
+	client thrift.TChanClient
+}
+
+// NewClient returns a Client that makes calls using client, which is usually
+// created using thrift.NewClient, with the methods defined in idl.
+func NewClient(client thrift.TChanClient, idl *IDL) *Client {
+	return &Client{idl: idl, client: client}
+}
+
+// Call calls a method, given as "Service::method", with the given arguments
+// (see Method.Args), and returns the decoded return value. If the method
+// fails with one of its exceptions, the error is an *Exception.
+func (c *Client) Call(ctx thrift.Context, method string, args interface{}) (interface{}, error) {
+	parts := strings.SplitN(method, "::", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("thrift method %q must be in the form Service::method", method)
+	}
+
+	m, err := c.idl.Method(parts[0], parts[1])
+	if err != nil {
+		return nil, err
+	}
+	req, err := m.Args(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Oneway() {
+		return nil, thrift.CallOneway(ctx, c.client, parts[0], parts[1], req)
+	}
+
+	res := m.NewResult()
+	success, err := c.client.Call(ctx, parts[0], parts[1], req, res)
+	if err != nil {
+		return nil, err
+	}
+	if !success {
+		if ex := res.Exception(); ex != nil {
+			return nil, ex
+		}
+		return nil, fmt.Errorf("thrift method %v failed with an unknown exception", method)
+	}
+	return res.Success(), nil
+}
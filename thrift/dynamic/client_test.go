@@ -0,0 +1,161 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dynamic_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go/testutils"
+	"github.com/uber/tchannel-go/thrift"
+	. "github.com/uber/tchannel-go/thrift/dynamic"
+	gen "github.com/uber/tchannel-go/thrift/gen-go/test"
+
+	"github.com/samuel/go-thrift/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func thriftType(name string) *parser.Type {
+	return &parser.Type{Name: name}
+}
+
+// testIDL returns the parsed form of SimpleService in thrift/test.thrift.
+func testIDL() *IDL {
+	data := &parser.Struct{Name: "Data", Fields: []*parser.Field{
+		{ID: 1, Name: "b1", Type: thriftType("bool")},
+		{ID: 2, Name: "s2", Type: thriftType("string")},
+		{ID: 3, Name: "i3", Type: thriftType("i32")},
+	}}
+	simpleErr := &parser.Struct{Name: "SimpleErr", Fields: []*parser.Field{
+		{ID: 1, Name: "message", Type: thriftType("string")},
+	}}
+	service := &parser.Service{Name: "SimpleService", Methods: map[string]*parser.Method{
+		"Call": {
+			Name:       "Call",
+			ReturnType: thriftType("Data"),
+			Arguments:  []*parser.Field{{ID: 1, Name: "arg", Type: thriftType("Data")}},
+		},
+		"Simple": {
+			Name:       "Simple",
+			Exceptions: []*parser.Field{{ID: 1, Name: "simpleErr", Type: thriftType("SimpleErr")}},
+		},
+	}}
+
+	return NewIDL(map[string]*parser.Thrift{
+		"test.thrift": {
+			Structs:    map[string]*parser.Struct{"Data": data},
+			Exceptions: map[string]*parser.Struct{"SimpleErr": simpleErr},
+			Services:   map[string]*parser.Service{"SimpleService": service},
+		},
+	}, "test.thrift")
+}
+
+type simpleHandler struct{}
+
+func (simpleHandler) Call(ctx thrift.Context, arg *gen.Data) (*gen.Data, error) {
+	return &gen.Data{B1: !arg.B1, S2: arg.S2 + "!", I3: arg.I3 * 2}, nil
+}
+
+func (simpleHandler) Simple(ctx thrift.Context) error {
+	return &gen.SimpleErr{Message: "simple failed"}
+}
+
+func (simpleHandler) SimpleFuture(ctx thrift.Context) error {
+	return errors.New("unimplemented")
+}
+
+func TestClientCall(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		thrift.NewServer(ts.Server()).Register(gen.NewTChanSimpleServiceServer(simpleHandler{}))
+
+		tchanClient := thrift.NewClient(ts.NewClient(nil), ts.ServiceName(), &thrift.ClientOptions{
+			HostPort: ts.HostPort(),
+		})
+		client := NewClient(tchanClient, testIDL())
+		wantData := map[string]interface{}{"b1": false, "s2": "foo!", "i3": int32(42)}
+
+		tests := []struct {
+			msg     string
+			method  string
+			args    interface{}
+			want    interface{}
+			wantErr error
+		}{
+			{
+				msg:    "map arguments",
+				method: "SimpleService::Call",
+				args:   map[string]interface{}{"arg": map[string]interface{}{"b1": true, "s2": "foo", "i3": 21}},
+				want:   wantData,
+			},
+			{
+				msg:    "struct arguments",
+				method: "SimpleService::Call",
+				args: struct {
+					Arg *gen.Data
+				}{&gen.Data{B1: true, S2: "foo", I3: 21}},
+				want: wantData,
+			},
+			{
+				msg:     "exception",
+				method:  "SimpleService::Simple",
+				wantErr: &Exception{Name: "simpleErr", Value: map[string]interface{}{"message": "simple failed"}},
+			},
+		}
+
+		for _, tt := range tests {
+			ctx, cancel := thrift.NewContext(testutils.Timeout(time.Second))
+			got, err := client.Call(ctx, tt.method, tt.args)
+			cancel()
+
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err, "%v: unexpected error", tt.msg)
+				continue
+			}
+			require.NoError(t, err, "%v: call failed", tt.msg)
+			assert.Equal(t, tt.want, got, "%v: unexpected result", tt.msg)
+		}
+	})
+}
+
+func TestClientCallInvalid(t *testing.T) {
+	client := NewClient(nil, testIDL())
+	ctx, cancel := thrift.NewContext(time.Second)
+	defer cancel()
+
+	tests := []struct {
+		method  string
+		args    interface{}
+		wantErr string
+	}{
+		{"Call", nil, "must be in the form Service::method"},
+		{"SimpleService::Unknown", nil, "method Unknown not found in service SimpleService"},
+		{"Unknown::Call", nil, "service Unknown not found"},
+		{"SimpleService::Call", "foo", "expected arguments as a map or struct"},
+	}
+
+	for _, tt := range tests {
+		_, err := client.Call(ctx, tt.method, tt.args)
+		require.Error(t, err, "Call(%v) should fail", tt.method)
+		assert.Contains(t, err.Error(), tt.wantErr, "Call(%v) unexpected error", tt.method)
+	}
+}
@@ -0,0 +1,452 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dynamic
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	athrift "github.com/apache/thrift/lib/go/thrift"
+	"github.com/samuel/go-thrift/parser"
+)
+
+var thriftTypes = map[string]athrift.TType{
+	"bool":   athrift.BOOL,
+	"byte":   athrift.BYTE,
+	"i8":     athrift.BYTE,
+	"i16":    athrift.I16,
+	"i32":    athrift.I32,
+	"i64":    athrift.I64,
+	"double": athrift.DOUBLE,
+	"string": athrift.STRING,
+	"binary": athrift.STRING,
+	"list":   athrift.LIST,
+	"set":    athrift.SET,
+	"map":    athrift.MAP,
+}
+
+// resolvedType is a Thrift type with typedefs resolved.
+type resolvedType struct {
+	// file is the file the type's own key and value types are relative to.
+	file string
+	t    *parser.Type
+
+	// enum or fields are set for enums and structs.
+	enum   *parser.Enum
+	fields []*parser.Field
+}
+
+func (rt resolvedType) ttype() athrift.TType {
+	switch {
+	case rt.enum != nil:
+		return athrift.I32
+	case rt.fields != nil:
+		return athrift.STRUCT
+	}
+	return thriftTypes[rt.t.Name]
+}
+
+func (idl *IDL) resolve(file string, t *parser.Type) (resolvedType, error) {
+	for {
+		if _, ok := thriftTypes[t.Name]; ok {
+			return resolvedType{file: file, t: t}, nil
+		}
+
+		defFile, name := idl.lookup(file, t.Name)
+		parsed := idl.files[defFile]
+		if td, ok := parsed.Typedefs[name]; ok {
+			file, t = defFile, td.Type
+			continue
+		}
+		if enum, ok := parsed.Enums[name]; ok {
+			return resolvedType{file: defFile, t: t, enum: enum}, nil
+		}
+		for _, structs := range []map[string]*parser.Struct{parsed.Structs, parsed.Unions, parsed.Exceptions} {
+			if s, ok := structs[name]; ok {
+				return resolvedType{file: defFile, t: t, fields: s.Fields}, nil
+			}
+		}
+		return resolvedType{}, fmt.Errorf("unknown type %v", t.Name)
+	}
+}
+
+func (idl *IDL) writeStruct(p athrift.TProtocol, file string, fields []*parser.Field, v interface{}) error {
+	obj, ok := v.(map[string]interface{})
+	if !ok && v != nil {
+		return fmt.Errorf("expected an object, got %v", v)
+	}
+
+	byName := make(map[string]*parser.Field, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	for name := range obj {
+		if _, ok := byName[name]; !ok {
+			return fmt.Errorf("unknown field %v", name)
+		}
+	}
+
+	if err := p.WriteStructBegin(""); err != nil {
+		return err
+	}
+	for _, f := range fields {
+		fv, ok := obj[f.Name]
+		if !ok || fv == nil {
+			continue
+		}
+
+		rt, err := idl.resolve(file, f.Type)
+		if err != nil {
+			return err
+		}
+		if err := p.WriteFieldBegin(f.Name, rt.ttype(), int16(f.ID)); err != nil {
+			return err
+		}
+		if err := idl.writeValue(p, rt, fv); err != nil {
+			return fmt.Errorf("field %v: %v", f.Name, err)
+		}
+		if err := p.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := p.WriteFieldStop(); err != nil {
+		return err
+	}
+	return p.WriteStructEnd()
+}
+
+func (idl *IDL) writeValue(p athrift.TProtocol, rt resolvedType, v interface{}) error {
+	switch {
+	case rt.fields != nil:
+		return idl.writeStruct(p, rt.file, rt.fields, v)
+	case rt.enum != nil:
+		n, err := enumValue(rt.enum, v)
+		if err != nil {
+			return err
+		}
+		return p.WriteI32(n)
+	}
+
+	switch rt.t.Name {
+	case "bool":
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("expected a bool, got %v", v)
+		}
+		return p.WriteBool(b)
+	case "byte", "i8", "i16", "i32", "i64":
+		n, err := intValue(v)
+		if err != nil {
+			return err
+		}
+		switch rt.t.Name {
+		case "byte", "i8":
+			return p.WriteByte(int8(n))
+		case "i16":
+			return p.WriteI16(int16(n))
+		case "i32":
+			return p.WriteI32(int32(n))
+		}
+		return p.WriteI64(n)
+	case "double":
+		num, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("expected a number, got %v", v)
+		}
+		f, err := num.Float64()
+		if err != nil {
+			return err
+		}
+		return p.WriteDouble(f)
+	case "string", "binary":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got %v", v)
+		}
+		return p.WriteString(s)
+	case "list", "set":
+		return idl.writeList(p, rt, v)
+	case "map":
+		return idl.writeMap(p, rt, v)
+	}
+	return fmt.Errorf("unsupported type %v", rt.t.Name)
+}
+
+func (idl *IDL) writeList(p athrift.TProtocol, rt resolvedType, v interface{}) error {
+	list, ok := v.([]interface{})
+	if !ok {
+		return fmt.Errorf("expected an array, got %v", v)
+	}
+	elem, err := idl.resolve(rt.file, rt.t.ValueType)
+	if err != nil {
+		return err
+	}
+
+	if rt.t.Name == "set" {
+		err = p.WriteSetBegin(elem.ttype(), len(list))
+	} else {
+		err = p.WriteListBegin(elem.ttype(), len(list))
+	}
+	if err != nil {
+		return err
+	}
+	for _, ev := range list {
+		if err := idl.writeValue(p, elem, ev); err != nil {
+			return err
+		}
+	}
+	if rt.t.Name == "set" {
+		return p.WriteSetEnd()
+	}
+	return p.WriteListEnd()
+}
+
+func (idl *IDL) writeMap(p athrift.TProtocol, rt resolvedType, v interface{}) error {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected an object, got %v", v)
+	}
+	key, err := idl.resolve(rt.file, rt.t.KeyType)
+	if err != nil {
+		return err
+	}
+	value, err := idl.resolve(rt.file, rt.t.ValueType)
+	if err != nil {
+		return err
+	}
+
+	// Write keys in a stable order.
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if err := p.WriteMapBegin(key.ttype(), value.ttype(), len(obj)); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		kv, err := mapKey(key, k)
+		if err != nil {
+			return err
+		}
+		if err := idl.writeValue(p, key, kv); err != nil {
+			return err
+		}
+		if err := idl.writeValue(p, value, obj[k]); err != nil {
+			return err
+		}
+	}
+	return p.WriteMapEnd()
+}
+
+// mapKey converts a JSON object key to the JSON value for the map's key type.
+func mapKey(key resolvedType, k string) (interface{}, error) {
+	if key.enum != nil {
+		return k, nil
+	}
+	switch key.t.Name {
+	case "string", "binary":
+		return k, nil
+	case "bool":
+		return strconv.ParseBool(k)
+	case "byte", "i8", "i16", "i32", "i64", "double":
+		return json.Number(k), nil
+	}
+	return nil, fmt.Errorf("unsupported map key type %v", key.t.Name)
+}
+
+func intValue(v interface{}) (int64, error) {
+	num, ok := v.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("expected a number, got %v", v)
+	}
+	return num.Int64()
+}
+
+// enumValue returns the value for an enum given as a name or a number.
+func enumValue(enum *parser.Enum, v interface{}) (int32, error) {
+	if name, ok := v.(string); ok {
+		ev, ok := enum.Values[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown value %v for enum %v", name, enum.Name)
+		}
+		return int32(ev.Value), nil
+	}
+	n, err := intValue(v)
+	return int32(n), err
+}
+
+// readStruct reads a struct into a map from field name to value. Fields that
+// are unknown or have an unexpected type are skipped.
+func (idl *IDL) readStruct(p athrift.TProtocol, file string, fields []*parser.Field) (map[string]interface{}, error) {
+	byID := make(map[int16]*parser.Field, len(fields))
+	for _, f := range fields {
+		byID[int16(f.ID)] = f
+	}
+
+	if _, err := p.ReadStructBegin(); err != nil {
+		return nil, err
+	}
+	obj := make(map[string]interface{})
+	for {
+		_, wireType, id, err := p.ReadFieldBegin()
+		if err != nil {
+			return nil, err
+		}
+		if wireType == athrift.STOP {
+			break
+		}
+
+		f, ok := byID[id]
+		if !ok {
+			if err := p.Skip(wireType); err != nil {
+				return nil, err
+			}
+		} else {
+			rt, err := idl.resolve(file, f.Type)
+			if err != nil {
+				return nil, err
+			}
+			v, err := idl.readValue(p, rt, wireType)
+			if err != nil {
+				return nil, fmt.Errorf("field %v: %v", f.Name, err)
+			}
+			obj[f.Name] = v
+		}
+		if err := p.ReadFieldEnd(); err != nil {
+			return nil, err
+		}
+	}
+	return obj, p.ReadStructEnd()
+}
+
+func (idl *IDL) readValue(p athrift.TProtocol, rt resolvedType, wireType athrift.TType) (interface{}, error) {
+	if rt.ttype() != wireType {
+		return nil, p.Skip(wireType)
+	}
+
+	switch {
+	case rt.fields != nil:
+		return idl.readStruct(p, rt.file, rt.fields)
+	case rt.enum != nil:
+		n, err := p.ReadI32()
+		for name, ev := range rt.enum.Values {
+			if int32(ev.Value) == n {
+				return name, err
+			}
+		}
+		return n, err
+	}
+
+	switch rt.t.Name {
+	case "bool":
+		return p.ReadBool()
+	case "byte", "i8":
+		return p.ReadByte()
+	case "i16":
+		return p.ReadI16()
+	case "i32":
+		return p.ReadI32()
+	case "i64":
+		return p.ReadI64()
+	case "double":
+		return p.ReadDouble()
+	case "string", "binary":
+		return p.ReadString()
+	case "list", "set":
+		return idl.readList(p, rt)
+	case "map":
+		return idl.readMap(p, rt)
+	}
+	return nil, fmt.Errorf("unsupported type %v", rt.t.Name)
+}
+
+func (idl *IDL) readList(p athrift.TProtocol, rt resolvedType) (interface{}, error) {
+	elem, err := idl.resolve(rt.file, rt.t.ValueType)
+	if err != nil {
+		return nil, err
+	}
+
+	var elemType athrift.TType
+	var size int
+	if rt.t.Name == "set" {
+		elemType, size, err = p.ReadSetBegin()
+	} else {
+		elemType, size, err = p.ReadListBegin()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]interface{}, 0, size)
+	for i := 0; i < size; i++ {
+		v, err := idl.readValue(p, elem, elemType)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	if rt.t.Name == "set" {
+		return list, p.ReadSetEnd()
+	}
+	return list, p.ReadListEnd()
+}
+
+func (idl *IDL) readMap(p athrift.TProtocol, rt resolvedType) (interface{}, error) {
+	key, err := idl.resolve(rt.file, rt.t.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	value, err := idl.resolve(rt.file, rt.t.ValueType)
+	if err != nil {
+		return nil, err
+	}
+
+	keyType, valueType, size, err := p.ReadMapBegin()
+	if err != nil {
+		return nil, err
+	}
+	obj := make(map[string]interface{}, size)
+	for i := 0; i < size; i++ {
+		k, err := idl.readValue(p, key, keyType)
+		if err != nil {
+			return nil, err
+		}
+		v, err := idl.readValue(p, value, valueType)
+		if err != nil {
+			return nil, err
+		}
+		obj[fmt.Sprint(k)] = v
+	}
+	return obj, p.ReadMapEnd()
+}
+
+// dynamicStruct adapts functions that read or write a struct to a TStruct.
+type dynamicStruct struct {
+	write func(p athrift.TProtocol) error
+	read  func(p athrift.TProtocol) error
+}
+
+func (s dynamicStruct) Write(p athrift.TProtocol) error { return s.write(p) }
+func (s dynamicStruct) Read(p athrift.TProtocol) error  { return s.read(p) }
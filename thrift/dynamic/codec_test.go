@@ -0,0 +1,151 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dynamic
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/uber/tchannel-go/thrift"
+
+	athrift "github.com/apache/thrift/lib/go/thrift"
+	"github.com/samuel/go-thrift/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func thriftType(name string) *parser.Type {
+	return &parser.Type{Name: name}
+}
+
+func TestRoundTrip(t *testing.T) {
+	idl := NewIDL(map[string]*parser.Thrift{
+		"main.thrift": {
+			Includes: map[string]string{"other": "other.thrift"},
+			Structs: map[string]*parser.Struct{
+				"Inner": {Name: "Inner", Fields: []*parser.Field{
+					{ID: 1, Name: "d", Type: thriftType("double")},
+				}},
+			},
+		},
+		"other.thrift": {
+			Typedefs: map[string]*parser.Typedef{
+				"Name": {Type: thriftType("string"), Alias: "Name"},
+			},
+			Enums: map[string]*parser.Enum{
+				"Color": {Name: "Color", Values: map[string]*parser.EnumValue{
+					"RED":  {Name: "RED", Value: 1},
+					"BLUE": {Name: "BLUE", Value: 2},
+				}},
+			},
+		},
+	}, "main.thrift")
+	fields := []*parser.Field{
+		{ID: 1, Name: "bytes", Type: thriftType("byte")},
+		{ID: 2, Name: "i16", Type: thriftType("i16")},
+		{ID: 3, Name: "i64s", Type: &parser.Type{Name: "list", ValueType: thriftType("i64")}},
+		{ID: 4, Name: "names", Type: &parser.Type{Name: "set", ValueType: thriftType("other.Name")}},
+		{ID: 5, Name: "colors", Type: &parser.Type{Name: "map", KeyType: thriftType("i32"), ValueType: thriftType("other.Color")}},
+		{ID: 6, Name: "inner", Type: thriftType("Inner")},
+		{ID: 7, Name: "data", Type: thriftType("binary")},
+		{ID: 8, Name: "unset", Type: thriftType("string"), Optional: true},
+	}
+
+	var args interface{}
+	decoder := json.NewDecoder(strings.NewReader(`{
+		"bytes": 7,
+		"i16": -3,
+		"i64s": [1, 9007199254740993],
+		"names": ["a", "b"],
+		"colors": {"1": "RED", "2": 2},
+		"inner": {"d": 1.5},
+		"data": "raw"
+	}`))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&args), "Failed to decode JSON")
+
+	var buf bytes.Buffer
+	writer := dynamicStruct{write: func(p athrift.TProtocol) error {
+		return idl.writeStruct(p, "main.thrift", fields, args)
+	}}
+	require.NoError(t, thrift.WriteStruct(&buf, writer), "Failed to write struct")
+
+	var got map[string]interface{}
+	reader := dynamicStruct{read: func(p athrift.TProtocol) error {
+		var err error
+		got, err = idl.readStruct(p, "main.thrift", fields)
+		return err
+	}}
+	require.NoError(t, thrift.ReadStruct(&buf, reader), "Failed to read struct")
+
+	assert.Equal(t, map[string]interface{}{
+		"bytes":  int8(7),
+		"i16":    int16(-3),
+		"i64s":   []interface{}{int64(1), int64(9007199254740993)},
+		"names":  []interface{}{"a", "b"},
+		"colors": map[string]interface{}{"1": "RED", "2": "BLUE"},
+		"inner":  map[string]interface{}{"d": 1.5},
+		"data":   "raw",
+	}, got, "Unexpected round-tripped struct")
+}
+
+func TestNormalize(t *testing.T) {
+	type inner struct {
+		D float64
+	}
+	type args struct {
+		Name     string `json:"name"`
+		Renamed  int16  `thrift:"other,2"`
+		Skipped  string `json:"-"`
+		Inner    *inner
+		NilInner *inner
+		IDs      []int64
+		Data     []byte
+		Counts   map[int32]uint8
+		private  string
+	}
+
+	got, err := normalize(args{
+		Name:    "foo",
+		Renamed: -1,
+		Skipped: "skipped",
+		Inner:   &inner{D: 1.5},
+		IDs:     []int64{1, 2},
+		Data:    []byte("raw"),
+		Counts:  map[int32]uint8{7: 8},
+		private: "private",
+	})
+	require.NoError(t, err, "normalize failed")
+	assert.Equal(t, map[string]interface{}{
+		"name":     "foo",
+		"other":    json.Number("-1"),
+		"inner":    map[string]interface{}{"d": json.Number("1.5")},
+		"nilInner": nil,
+		"iDs":      []interface{}{json.Number("1"), json.Number("2")},
+		"data":     "raw",
+		"counts":   map[string]interface{}{"7": json.Number("8")},
+	}, got, "Unexpected normalized value")
+
+	_, err = normalize(map[string]interface{}{"f": func() {}})
+	assert.Error(t, err, "normalize should fail for unsupported values")
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package dynamic makes Thrift calls using a Thrift IDL that's parsed at
+// runtime, rather than generated code. Arguments are built from maps,
+// slices and basic values, or from Go structs, and results are decoded into
+// generic values. This is useful for tools, such as gateways, CLIs and test
+// fixtures, that can't include generated code for every service they call.
+//
+// Structs are decoded as map[string]interface{} keyed by field name, lists
+// and sets as []interface{}, maps as map[string]interface{} with the keys
+// formatted as strings, enums as the name of the value, and other types as
+// the corresponding Go type (e.g. int32 for i32, and string for binary).
+package dynamic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samuel/go-thrift/parser"
+)
+
+// IDL is a set of parsed Thrift files.
+type IDL struct {
+	files map[string]*parser.Thrift
+	main  string
+}
+
+// Parse parses the Thrift file at filename, and any files that it includes.
+func Parse(filename string) (*IDL, error) {
+	p := &parser.Parser{}
+	files, main, err := p.ParseFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %v: %v", filename, err)
+	}
+	return NewIDL(files, main), nil
+}
+
+// NewIDL returns an IDL for files that have already been parsed, where main
+// is the name of the file that services are looked up in.
+func NewIDL(files map[string]*parser.Thrift, main string) *IDL {
+	return &IDL{files: files, main: main}
+}
+
+// Method returns a method of a service in the main file. The method may be
+// defined by a service that it extends.
+func (idl *IDL) Method(serviceName, methodName string) (*Method, error) {
+	file := idl.main
+	svc := serviceName
+	for {
+		s, ok := idl.files[file].Services[svc]
+		if !ok {
+			return nil, fmt.Errorf("service %v not found", svc)
+		}
+		if m, ok := s.Methods[methodName]; ok {
+			return &Method{idl: idl, file: file, service: serviceName, m: m}, nil
+		}
+		if s.Extends == "" {
+			return nil, fmt.Errorf("method %v not found in service %v", methodName, svc)
+		}
+		file, svc = idl.lookup(file, s.Extends)
+	}
+}
+
+// lookup returns the file and name that a name used in file refers to, which
+// may be in an included file.
+func (idl *IDL) lookup(file, name string) (string, string) {
+	if parts := strings.SplitN(name, ".", 2); len(parts) == 2 {
+		if included, ok := idl.files[file].Includes[parts[0]]; ok {
+			return included, parts[1]
+		}
+	}
+	return file, name
+}
+
+// Method is a method of a Thrift service.
+type Method struct {
+	idl     *IDL
+	file    string
+	service string
+	m       *parser.Method
+}
+
+// Service returns the name of the service the method was looked up in.
+func (m *Method) Service() string {
+	return m.service
+}
+
+// Name returns the name of the method.
+func (m *Method) Name() string {
+	return m.m.Name
+}
+
+// Oneway returns whether the method is a oneway method.
+func (m *Method) Oneway() bool {
+	return m.m.Oneway
+}
+
+// resultFields returns the fields of the method's result struct, where the
+// return value is field 0, followed by the exceptions.
+func (m *Method) resultFields() []*parser.Field {
+	fields := m.m.Exceptions
+	if m.m.ReturnType != nil {
+		success := &parser.Field{ID: 0, Name: "success", Type: m.m.ReturnType}
+		fields = append([]*parser.Field{success}, fields...)
+	}
+	return fields
+}
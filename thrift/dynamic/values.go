@@ -0,0 +1,149 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dynamic
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// normalize converts a value built by the caller into the generic values
+// that are encoded: map[string]interface{} for structs and maps,
+// []interface{} for lists and sets, json.Number for numbers, and string for
+// binary values. Go structs are converted to maps using the field name from
+// the field's json or thrift tag if it has one, and the Go field name with
+// the first letter lowercased otherwise.
+func normalize(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, bool, string, json.Number:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for k, fv := range v {
+			nv, err := normalize(fv)
+			if err != nil {
+				return nil, err
+			}
+			obj[k] = nv
+		}
+		return obj, nil
+	}
+	return normalizeValue(reflect.ValueOf(v))
+}
+
+func normalizeValue(rv reflect.Value) (interface{}, error) {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return normalize(rv.Elem().Interface())
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(rv.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return json.Number(strconv.FormatUint(rv.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		return json.Number(strconv.FormatFloat(rv.Float(), 'g', -1, 64)), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return string(rv.Bytes()), nil
+		}
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			v, err := normalize(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		obj := make(map[string]interface{}, rv.Len())
+		for _, k := range rv.MapKeys() {
+			v, err := normalize(rv.MapIndex(k).Interface())
+			if err != nil {
+				return nil, err
+			}
+			obj[fmt.Sprint(k.Interface())] = v
+		}
+		return obj, nil
+	case reflect.Struct:
+		return normalizeStruct(rv)
+	}
+	return nil, fmt.Errorf("unsupported value %v of type %v", rv.Interface(), rv.Type())
+}
+
+func normalizeStruct(rv reflect.Value) (interface{}, error) {
+	t := rv.Type()
+	obj := make(map[string]interface{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// Unexported field.
+			continue
+		}
+
+		name := structFieldName(f)
+		if name == "" {
+			continue
+		}
+		v, err := normalize(rv.Field(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %v", f.Name, err)
+		}
+		obj[name] = v
+	}
+	return obj, nil
+}
+
+// structFieldName returns the Thrift field name for a Go struct field, or ""
+// if the field should be skipped.
+func structFieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "thrift"} {
+		name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+
+	r, size := utf8.DecodeRuneInString(f.Name)
+	return string(unicode.ToLower(r)) + f.Name[size:]
+}
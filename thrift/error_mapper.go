@@ -0,0 +1,105 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package thrift
+
+import (
+	"fmt"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// ErrorMapper converts an error for a call to the given Thrift method.
+//
+// On the server, it converts errors returned by handlers that are not
+// exceptions declared by the method. Returning a *tchannel.SystemError sends
+// a system error with that code, and returning an *Exception sends the given
+// exception as if the handler had returned it. Any other error is sent as an
+// unexpected error.
+//
+// On the client, it converts errors returned by calls, such as system errors,
+// into errors for the caller. Declared exceptions are returned by the
+// generated client code and are not passed to the mapper.
+//
+// If the mapper returns nil, the original error is used.
+type ErrorMapper func(method string, err error) error
+
+func mapError(m ErrorMapper, method string, err error) error {
+	if mapped := m(method, err); mapped != nil {
+		return mapped
+	}
+	return err
+}
+
+// Exception is an error that is sent to the caller as the exception declared
+// with the given field ID in the method's "throws" clause, so the caller's
+// generated code returns it as a typed error.
+type Exception struct {
+	// FieldID is the field ID of the exception in the "throws" clause.
+	FieldID int16
+
+	// Value is the generated exception struct.
+	Value thrift.TStruct
+}
+
+func (e *Exception) Error() string {
+	if err, ok := e.Value.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprintf("thrift exception %v: %v", e.FieldID, e.Value)
+}
+
+// Read is not supported, as the result struct that contains the exception
+// is only known by the generated code.
+func (e *Exception) Read(p thrift.TProtocol) error {
+	return fmt.Errorf("cannot read exception %v without the method's result struct", e.FieldID)
+}
+
+// Write writes the method's result struct containing only the exception.
+func (e *Exception) Write(p thrift.TProtocol) error {
+	if err := p.WriteStructBegin("result"); err != nil {
+		return err
+	}
+	if err := p.WriteFieldBegin("exception", thrift.STRUCT, e.FieldID); err != nil {
+		return err
+	}
+	if err := e.Value.Write(p); err != nil {
+		return err
+	}
+	if err := p.WriteFieldEnd(); err != nil {
+		return err
+	}
+	if err := p.WriteFieldStop(); err != nil {
+		return err
+	}
+	return p.WriteStructEnd()
+}
+
+type optErrorMapper ErrorMapper
+
+// OptErrorMapper registers an ErrorMapper for errors returned by the
+// service's handlers.
+func OptErrorMapper(m ErrorMapper) RegisterOption {
+	return optErrorMapper(m)
+}
+
+func (o optErrorMapper) Apply(h *handler) {
+	h.errorMapper = ErrorMapper(o)
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package thrift_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/testutils"
+	. "github.com/uber/tchannel-go/thrift"
+	gen "github.com/uber/tchannel-go/thrift/gen-go/test"
+	"github.com/uber/tchannel-go/thrift/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	errNotFound     = errors.New("not found")
+	errInvalidInput = errors.New("invalid input")
+)
+
+// testServerErrorMapper maps errNotFound to the SimpleErr exception, and
+// errInvalidInput to a bad request.
+func testServerErrorMapper(method string, err error) error {
+	switch err {
+	case errNotFound:
+		return &Exception{FieldID: 1, Value: &gen.SimpleErr{Message: method + ": " + err.Error()}}
+	case errInvalidInput:
+		return tchannel.NewSystemError(tchannel.ErrCodeBadRequest, err.Error())
+	}
+	return nil
+}
+
+func TestServerErrorMapper(t *testing.T) {
+	tests := []struct {
+		msg        string
+		handlerErr error
+		wantErr    error
+		wantCode   tchannel.SystemErrCode
+	}{
+		{
+			msg:        "mapped to exception",
+			handlerErr: errNotFound,
+			wantErr:    &gen.SimpleErr{Message: "Simple: not found"},
+		},
+		{
+			msg:        "mapped to system error",
+			handlerErr: errInvalidInput,
+			wantCode:   tchannel.ErrCodeBadRequest,
+		},
+		{
+			msg:        "not mapped",
+			handlerErr: errors.New("unknown"),
+			wantCode:   tchannel.ErrCodeUnexpected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			h := new(mocks.TChanSimpleService)
+			h.On("Simple", ctxArg()).Return(tt.handlerErr)
+
+			serverCh := testutils.NewServer(t, nil)
+			defer serverCh.Close()
+			NewServer(serverCh).Register(gen.NewTChanSimpleServiceServer(h), OptErrorMapper(testServerErrorMapper))
+
+			clientCh := testutils.NewClient(t, nil)
+			defer clientCh.Close()
+			clientCh.Peers().Add(serverCh.PeerInfo().HostPort)
+			client := gen.NewTChanSimpleServiceClient(NewClient(clientCh, serverCh.ServiceName(), nil))
+
+			ctx, cancel := NewContext(time.Second)
+			defer cancel()
+
+			err := client.Simple(ctx)
+			require.Error(t, err, "Expected call to fail")
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err, "Unexpected error")
+			} else {
+				assert.Equal(t, tt.wantCode, tchannel.GetSystemErrorCode(err), "Unexpected error code for %v", err)
+			}
+			h.AssertExpectations(t)
+		})
+	}
+}
+
+func TestClientErrorMapper(t *testing.T) {
+	h := new(mocks.TChanSimpleService)
+	h.On("Simple", ctxArg()).Return(errInvalidInput)
+
+	serverCh := testutils.NewServer(t, nil)
+	defer serverCh.Close()
+	NewServer(serverCh).Register(gen.NewTChanSimpleServiceServer(h), OptErrorMapper(testServerErrorMapper))
+
+	clientCh := testutils.NewClient(t, nil)
+	defer clientCh.Close()
+	clientCh.Peers().Add(serverCh.PeerInfo().HostPort)
+
+	var mappedMethod string
+	client := gen.NewTChanSimpleServiceClient(NewClient(clientCh, serverCh.ServiceName(), &ClientOptions{
+		ErrorMapper: func(method string, err error) error {
+			mappedMethod = method
+			if tchannel.GetSystemErrorCode(err) == tchannel.ErrCodeBadRequest {
+				return errInvalidInput
+			}
+			return nil
+		},
+	}))
+
+	ctx, cancel := NewContext(time.Second)
+	defer cancel()
+
+	err := client.Simple(ctx)
+	assert.Equal(t, errInvalidInput, err, "Expected the client to map the system error")
+	assert.Equal(t, "Simple", mappedMethod, "Unexpected method passed to the mapper")
+
+	// Declared exceptions are returned by the generated code without mapping.
+	mappedMethod = ""
+	thriftErr := &gen.SimpleErr{Message: "declared"}
+	h.ExpectedCalls = nil
+	h.On("Simple", ctxArg()).Return(thriftErr)
+	err = client.Simple(ctx)
+	assert.Equal(t, thriftErr, err, "Expected the declared exception")
+	assert.Empty(t, mappedMethod, "Mapper should not be called for declared exceptions")
+}
@@ -251,14 +251,245 @@ func (p *HealthRequest) String() string {
 	return fmt.Sprintf("HealthRequest(%+v)", *p)
 }
 
+// Attributes:
+//  - Name
+//  - Ok
+//  - Message
+//  - NonFatal
+type HealthCheckStatus struct {
+	Name     string  `thrift:"name,1,required" db:"name" json:"name"`
+	Ok       bool    `thrift:"ok,2,required" db:"ok" json:"ok"`
+	Message  *string `thrift:"message,3" db:"message" json:"message,omitempty"`
+	NonFatal *bool   `thrift:"nonFatal,4" db:"nonFatal" json:"nonFatal,omitempty"`
+}
+
+func NewHealthCheckStatus() *HealthCheckStatus {
+	return &HealthCheckStatus{}
+}
+
+func (p *HealthCheckStatus) GetName() string {
+	return p.Name
+}
+
+func (p *HealthCheckStatus) GetOk() bool {
+	return p.Ok
+}
+
+var HealthCheckStatus_Message_DEFAULT string
+
+func (p *HealthCheckStatus) GetMessage() string {
+	if !p.IsSetMessage() {
+		return HealthCheckStatus_Message_DEFAULT
+	}
+	return *p.Message
+}
+
+var HealthCheckStatus_NonFatal_DEFAULT bool
+
+func (p *HealthCheckStatus) GetNonFatal() bool {
+	if !p.IsSetNonFatal() {
+		return HealthCheckStatus_NonFatal_DEFAULT
+	}
+	return *p.NonFatal
+}
+func (p *HealthCheckStatus) IsSetMessage() bool {
+	return p.Message != nil
+}
+
+func (p *HealthCheckStatus) IsSetNonFatal() bool {
+	return p.NonFatal != nil
+}
+
+func (p *HealthCheckStatus) Read(iprot thrift.TProtocol) error {
+	if _, err := iprot.ReadStructBegin(); err != nil {
+		return thrift.PrependError(fmt.Sprintf("%T read error: ", p), err)
+	}
+
+	var issetName bool = false
+	var issetOk bool = false
+
+	for {
+		_, fieldTypeId, fieldId, err := iprot.ReadFieldBegin()
+		if err != nil {
+			return thrift.PrependError(fmt.Sprintf("%T field %d read error: ", p, fieldId), err)
+		}
+		if fieldTypeId == thrift.STOP {
+			break
+		}
+		switch fieldId {
+		case 1:
+			if err := p.ReadField1(iprot); err != nil {
+				return err
+			}
+			issetName = true
+		case 2:
+			if err := p.ReadField2(iprot); err != nil {
+				return err
+			}
+			issetOk = true
+		case 3:
+			if err := p.ReadField3(iprot); err != nil {
+				return err
+			}
+		case 4:
+			if err := p.ReadField4(iprot); err != nil {
+				return err
+			}
+		default:
+			if err := iprot.Skip(fieldTypeId); err != nil {
+				return err
+			}
+		}
+		if err := iprot.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := iprot.ReadStructEnd(); err != nil {
+		return thrift.PrependError(fmt.Sprintf("%T read struct end error: ", p), err)
+	}
+	if !issetName {
+		return thrift.NewTProtocolExceptionWithType(thrift.INVALID_DATA, fmt.Errorf("Required field Name is not set"))
+	}
+	if !issetOk {
+		return thrift.NewTProtocolExceptionWithType(thrift.INVALID_DATA, fmt.Errorf("Required field Ok is not set"))
+	}
+	return nil
+}
+
+func (p *HealthCheckStatus) ReadField1(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadString(); err != nil {
+		return thrift.PrependError("error reading field 1: ", err)
+	} else {
+		p.Name = v
+	}
+	return nil
+}
+
+func (p *HealthCheckStatus) ReadField2(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadBool(); err != nil {
+		return thrift.PrependError("error reading field 2: ", err)
+	} else {
+		p.Ok = v
+	}
+	return nil
+}
+
+func (p *HealthCheckStatus) ReadField3(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadString(); err != nil {
+		return thrift.PrependError("error reading field 3: ", err)
+	} else {
+		p.Message = &v
+	}
+	return nil
+}
+
+func (p *HealthCheckStatus) ReadField4(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadBool(); err != nil {
+		return thrift.PrependError("error reading field 4: ", err)
+	} else {
+		p.NonFatal = &v
+	}
+	return nil
+}
+
+func (p *HealthCheckStatus) Write(oprot thrift.TProtocol) error {
+	if err := oprot.WriteStructBegin("HealthCheckStatus"); err != nil {
+		return thrift.PrependError(fmt.Sprintf("%T write struct begin error: ", p), err)
+	}
+	if err := p.writeField1(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField2(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField3(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField4(oprot); err != nil {
+		return err
+	}
+	if err := oprot.WriteFieldStop(); err != nil {
+		return thrift.PrependError("write field stop error: ", err)
+	}
+	if err := oprot.WriteStructEnd(); err != nil {
+		return thrift.PrependError("write struct stop error: ", err)
+	}
+	return nil
+}
+
+func (p *HealthCheckStatus) writeField1(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("name", thrift.STRING, 1); err != nil {
+		return thrift.PrependError(fmt.Sprintf("%T write field begin error 1:name: ", p), err)
+	}
+	if err := oprot.WriteString(string(p.Name)); err != nil {
+		return thrift.PrependError(fmt.Sprintf("%T.name (1) field write error: ", p), err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return thrift.PrependError(fmt.Sprintf("%T write field end error 1:name: ", p), err)
+	}
+	return err
+}
+
+func (p *HealthCheckStatus) writeField2(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("ok", thrift.BOOL, 2); err != nil {
+		return thrift.PrependError(fmt.Sprintf("%T write field begin error 2:ok: ", p), err)
+	}
+	if err := oprot.WriteBool(bool(p.Ok)); err != nil {
+		return thrift.PrependError(fmt.Sprintf("%T.ok (2) field write error: ", p), err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return thrift.PrependError(fmt.Sprintf("%T write field end error 2:ok: ", p), err)
+	}
+	return err
+}
+
+func (p *HealthCheckStatus) writeField3(oprot thrift.TProtocol) (err error) {
+	if p.IsSetMessage() {
+		if err := oprot.WriteFieldBegin("message", thrift.STRING, 3); err != nil {
+			return thrift.PrependError(fmt.Sprintf("%T write field begin error 3:message: ", p), err)
+		}
+		if err := oprot.WriteString(string(*p.Message)); err != nil {
+			return thrift.PrependError(fmt.Sprintf("%T.message (3) field write error: ", p), err)
+		}
+		if err := oprot.WriteFieldEnd(); err != nil {
+			return thrift.PrependError(fmt.Sprintf("%T write field end error 3:message: ", p), err)
+		}
+	}
+	return err
+}
+
+func (p *HealthCheckStatus) writeField4(oprot thrift.TProtocol) (err error) {
+	if p.IsSetNonFatal() {
+		if err := oprot.WriteFieldBegin("nonFatal", thrift.BOOL, 4); err != nil {
+			return thrift.PrependError(fmt.Sprintf("%T write field begin error 4:nonFatal: ", p), err)
+		}
+		if err := oprot.WriteBool(bool(*p.NonFatal)); err != nil {
+			return thrift.PrependError(fmt.Sprintf("%T.nonFatal (4) field write error: ", p), err)
+		}
+		if err := oprot.WriteFieldEnd(); err != nil {
+			return thrift.PrependError(fmt.Sprintf("%T write field end error 4:nonFatal: ", p), err)
+		}
+	}
+	return err
+}
+
+func (p *HealthCheckStatus) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("HealthCheckStatus(%+v)", *p)
+}
+
 // Attributes:
 //  - Ok
 //  - Message
 //  - State
+//  - Checks
 type HealthStatus struct {
-	Ok      bool         `thrift:"ok,1,required" db:"ok" json:"ok"`
-	Message *string      `thrift:"message,2" db:"message" json:"message,omitempty"`
-	State   *HealthState `thrift:"state,3" db:"state" json:"state,omitempty"`
+	Ok      bool                 `thrift:"ok,1,required" db:"ok" json:"ok"`
+	Message *string              `thrift:"message,2" db:"message" json:"message,omitempty"`
+	State   *HealthState         `thrift:"state,3" db:"state" json:"state,omitempty"`
+	Checks  []*HealthCheckStatus `thrift:"checks,4" db:"checks" json:"checks,omitempty"`
 }
 
 func NewHealthStatus() *HealthStatus {
@@ -286,6 +517,12 @@ func (p *HealthStatus) GetState() HealthState {
 	}
 	return *p.State
 }
+
+var HealthStatus_Checks_DEFAULT []*HealthCheckStatus
+
+func (p *HealthStatus) GetChecks() []*HealthCheckStatus {
+	return p.Checks
+}
 func (p *HealthStatus) IsSetMessage() bool {
 	return p.Message != nil
 }
@@ -294,6 +531,10 @@ func (p *HealthStatus) IsSetState() bool {
 	return p.State != nil
 }
 
+func (p *HealthStatus) IsSetChecks() bool {
+	return p.Checks != nil
+}
+
 func (p *HealthStatus) Read(iprot thrift.TProtocol) error {
 	if _, err := iprot.ReadStructBegin(); err != nil {
 		return thrift.PrependError(fmt.Sprintf("%T read error: ", p), err)
@@ -323,6 +564,10 @@ func (p *HealthStatus) Read(iprot thrift.TProtocol) error {
 			if err := p.ReadField3(iprot); err != nil {
 				return err
 			}
+		case 4:
+			if err := p.ReadField4(iprot); err != nil {
+				return err
+			}
 		default:
 			if err := iprot.Skip(fieldTypeId); err != nil {
 				return err
@@ -369,6 +614,26 @@ func (p *HealthStatus) ReadField3(iprot thrift.TProtocol) error {
 	return nil
 }
 
+func (p *HealthStatus) ReadField4(iprot thrift.TProtocol) error {
+	_, size, err := iprot.ReadListBegin()
+	if err != nil {
+		return thrift.PrependError("error reading list begin: ", err)
+	}
+	tSlice := make([]*HealthCheckStatus, 0, size)
+	p.Checks = tSlice
+	for i := 0; i < size; i++ {
+		_elem0 := &HealthCheckStatus{}
+		if err := _elem0.Read(iprot); err != nil {
+			return thrift.PrependError(fmt.Sprintf("%T error reading struct: ", _elem0), err)
+		}
+		p.Checks = append(p.Checks, _elem0)
+	}
+	if err := iprot.ReadListEnd(); err != nil {
+		return thrift.PrependError("error reading list end: ", err)
+	}
+	return nil
+}
+
 func (p *HealthStatus) Write(oprot thrift.TProtocol) error {
 	if err := oprot.WriteStructBegin("HealthStatus"); err != nil {
 		return thrift.PrependError(fmt.Sprintf("%T write struct begin error: ", p), err)
@@ -382,6 +647,9 @@ func (p *HealthStatus) Write(oprot thrift.TProtocol) error {
 	if err := p.writeField3(oprot); err != nil {
 		return err
 	}
+	if err := p.writeField4(oprot); err != nil {
+		return err
+	}
 	if err := oprot.WriteFieldStop(); err != nil {
 		return thrift.PrependError("write field stop error: ", err)
 	}
@@ -434,6 +702,29 @@ func (p *HealthStatus) writeField3(oprot thrift.TProtocol) (err error) {
 	return err
 }
 
+func (p *HealthStatus) writeField4(oprot thrift.TProtocol) (err error) {
+	if p.IsSetChecks() {
+		if err := oprot.WriteFieldBegin("checks", thrift.LIST, 4); err != nil {
+			return thrift.PrependError(fmt.Sprintf("%T write field begin error 4:checks: ", p), err)
+		}
+		if err := oprot.WriteListBegin(thrift.STRUCT, len(p.Checks)); err != nil {
+			return thrift.PrependError("error writing list begin: ", err)
+		}
+		for _, v := range p.Checks {
+			if err := v.Write(oprot); err != nil {
+				return thrift.PrependError(fmt.Sprintf("%T error writing struct: ", v), err)
+			}
+		}
+		if err := oprot.WriteListEnd(); err != nil {
+			return thrift.PrependError("error writing list end: ", err)
+		}
+		if err := oprot.WriteFieldEnd(); err != nil {
+			return thrift.PrependError(fmt.Sprintf("%T write field end error 4:checks: ", p), err)
+		}
+	}
+	return err
+}
+
 func (p *HealthStatus) String() string {
 	if p == nil {
 		return "<nil>"
@@ -44,3 +44,22 @@ type TChanServer interface {
 	// Methods returns the method names handled by this server.
 	Methods() []string
 }
+
+// TChanOnewayClient is implemented by TChanClients that can make calls to
+// Thrift oneway methods, and is used by the generated client code.
+type TChanOnewayClient interface {
+	// CallOneway sends the request for a oneway method, and returns once it
+	// has been sent without waiting for the method to run.
+	CallOneway(ctx Context, serviceName, methodName string, req athrift.TStruct) error
+}
+
+// TChanOnewayServer is implemented by generated servers for services with
+// oneway methods. The Server responds to oneway methods with an empty response
+// once the handler returns, and handler errors are only logged since there is
+// no caller waiting for them.
+type TChanOnewayServer interface {
+	TChanServer
+
+	// IsOneway returns whether the given method is a oneway method.
+	IsOneway(methodName string) bool
+}
@@ -27,6 +27,8 @@ import (
 
 	"github.com/uber/tchannel-go"
 	"github.com/uber/tchannel-go/thrift/gen-go/meta"
+
+	"golang.org/x/net/context"
 )
 
 // HealthFunc is the interface for custom health endpoints.
@@ -47,6 +49,15 @@ const (
 	Traffic
 )
 
+// checkType returns the type of channel health checks to run for this type of
+// health request.
+func (t HealthRequestType) checkType() tchannel.HealthCheckType {
+	if t == Traffic {
+		return tchannel.ReadinessHealth
+	}
+	return tchannel.LivenessHealth
+}
+
 // HealthRequest is optional parametres for a health request.
 type HealthRequest struct {
 	// Type is the type of health check being requested.
@@ -60,6 +71,12 @@ type HealthRequestFunc func(Context, HealthRequest) (ok bool, message string)
 // healthHandler implements the default health check enpoint.
 type metaHandler struct {
 	healthFn HealthRequestFunc
+
+	// draining, if set, returns whether the channel is shutting down.
+	draining func() bool
+
+	// health, if set, runs the health checks registered on the channel.
+	health func(context.Context, tchannel.HealthCheckType) tchannel.HealthStatus
 }
 
 // newMetaHandler return a new HealthHandler instance.
@@ -69,11 +86,31 @@ func newMetaHandler() *metaHandler {
 
 // Health returns true as default Health endpoint.
 func (h *metaHandler) Health(ctx Context, req *meta.HealthRequest) (*meta.HealthStatus, error) {
-	ok, message := h.healthFn(ctx, metaReqToReq(req))
-	if message == "" {
-		return &meta.HealthStatus{Ok: ok}, nil
+	if h.draining != nil && h.draining() {
+		message := "draining"
+		state := meta.HealthState_STOPPING
+		return &meta.HealthStatus{Ok: false, Message: &message, State: &state}, nil
 	}
-	return &meta.HealthStatus{Ok: ok, Message: &message}, nil
+
+	healthReq := metaReqToReq(req)
+	ok, message := h.healthFn(ctx, healthReq)
+	status := &meta.HealthStatus{Ok: ok}
+	if h.health != nil {
+		chStatus := h.health(ctx, healthReq.Type.checkType())
+		for _, r := range chStatus.Checks {
+			status.Checks = append(status.Checks, healthCheckResultToMeta(r))
+		}
+		if !chStatus.Ok {
+			status.Ok = false
+			if message == "" {
+				message = chStatus.Message
+			}
+		}
+	}
+	if message != "" {
+		status.Message = &message
+	}
+	return status, nil
 }
 
 func (h *metaHandler) ThriftIDL(ctx Context) (*meta.ThriftIDLs, error) {
@@ -97,6 +134,18 @@ func (h *metaHandler) setHandler(f HealthRequestFunc) {
 	h.healthFn = f
 }
 
+func healthCheckResultToMeta(r tchannel.HealthCheckResult) *meta.HealthCheckStatus {
+	status := &meta.HealthCheckStatus{Name: r.Name, Ok: r.Err == nil}
+	if r.Err != nil {
+		message := r.Err.Error()
+		status.Message = &message
+	}
+	if r.NonFatal {
+		status.NonFatal = &r.NonFatal
+	}
+	return status
+}
+
 func metaReqToReq(r *meta.HealthRequest) HealthRequest {
 	if r == nil {
 		return HealthRequest{}
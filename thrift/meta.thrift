@@ -25,10 +25,21 @@ struct HealthRequest {
     1: optional HealthRequestType type
 }
 
+// HealthCheckStatus is the result of a single health check registered
+// on the channel.
+struct HealthCheckStatus {
+    1: required string name
+    2: required bool ok
+    3: optional string message
+    // nonFatal checks are informational, and do not affect HealthStatus.ok.
+    4: optional bool nonFatal
+}
+
 struct HealthStatus {
     1: required bool ok
     2: optional string message
     3: optional HealthState state
+    4: optional list<HealthCheckStatus> checks
 }
 
 typedef string filename
@@ -21,17 +21,20 @@
 package thrift
 
 import (
+	"errors"
 	"runtime"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/raw"
 	"github.com/uber/tchannel-go/testutils"
 	"github.com/uber/tchannel-go/thrift/gen-go/meta"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 func TestThriftIDL(t *testing.T) {
@@ -126,6 +129,137 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+func TestHealthRegisteredChecks(t *testing.T) {
+	errCheck := errors.New("unavailable")
+	tests := []struct {
+		msg         string
+		register    func(ch *tchannel.Channel)
+		reqType     meta.HealthRequestType
+		wantOK      bool
+		wantMessage *string
+		wantChecks  []*meta.HealthCheckStatus
+	}{
+		{
+			msg:      "no checks",
+			register: func(ch *tchannel.Channel) {},
+			wantOK:   true,
+		},
+		{
+			msg: "passing check",
+			register: func(ch *tchannel.Channel) {
+				ch.RegisterHealthCheck("db", func(context.Context) error { return nil })
+			},
+			wantOK: true,
+			wantChecks: []*meta.HealthCheckStatus{
+				{Name: "db", Ok: true},
+			},
+		},
+		{
+			msg: "failing check",
+			register: func(ch *tchannel.Channel) {
+				ch.RegisterHealthCheck("db", func(context.Context) error { return nil })
+				ch.RegisterHealthCheck("cache", func(context.Context) error { return errCheck })
+			},
+			wantOK:      false,
+			wantMessage: stringPtr("health check cache failed: unavailable"),
+			wantChecks: []*meta.HealthCheckStatus{
+				{Name: "db", Ok: true},
+				{Name: "cache", Ok: false, Message: stringPtr("unavailable")},
+			},
+		},
+		{
+			msg: "failing non-fatal check",
+			register: func(ch *tchannel.Channel) {
+				ch.RegisterHealthCheck("cache", func(context.Context) error { return errCheck }, tchannel.NonFatalHealthCheck)
+			},
+			wantOK: true,
+			wantChecks: []*meta.HealthCheckStatus{
+				{Name: "cache", Ok: false, Message: stringPtr("unavailable"), NonFatal: boolPtr(true)},
+			},
+		},
+		{
+			msg: "readiness check skipped for process health",
+			register: func(ch *tchannel.Channel) {
+				ch.RegisterHealthCheck("db", func(context.Context) error { return nil })
+				ch.RegisterHealthCheck("warmup", func(context.Context) error { return errCheck }, tchannel.ReadinessHealthCheck)
+			},
+			reqType: meta.HealthRequestType_PROCESS,
+			wantOK:  true,
+			wantChecks: []*meta.HealthCheckStatus{
+				{Name: "db", Ok: true},
+			},
+		},
+		{
+			msg: "readiness check run for traffic health",
+			register: func(ch *tchannel.Channel) {
+				ch.RegisterHealthCheck("db", func(context.Context) error { return nil })
+				ch.RegisterHealthCheck("warmup", func(context.Context) error { return errCheck }, tchannel.ReadinessHealthCheck)
+			},
+			reqType:     meta.HealthRequestType_TRAFFIC,
+			wantOK:      false,
+			wantMessage: stringPtr("health check warmup failed: unavailable"),
+			wantChecks: []*meta.HealthCheckStatus{
+				{Name: "db", Ok: true},
+				{Name: "warmup", Ok: false, Message: stringPtr("unavailable")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			ctx, cancel := NewContext(time.Second * 10)
+			defer cancel()
+
+			tchan, _ := setupMetaServer(t)
+			defer tchan.Close()
+			tt.register(tchan)
+
+			c := getMetaClient(t, tchan.PeerInfo().HostPort)
+			ret, err := c.Health(ctx, &meta.HealthRequest{Type: &tt.reqType})
+			require.NoError(t, err, "Health endpoint failed")
+
+			assert.Equal(t, tt.wantOK, ret.Ok, "Health status mismatch")
+			assert.Equal(t, tt.wantMessage, ret.Message, "Health message mismatch")
+			assert.Equal(t, tt.wantChecks, ret.Checks, "Health checks mismatch")
+		})
+	}
+}
+
+func TestHealthDraining(t *testing.T) {
+	ctx, cancel := NewContext(time.Second * 10)
+	defer cancel()
+
+	tchan, _ := setupMetaServer(t)
+	defer tchan.Close()
+
+	gotCall := make(chan struct{})
+	unblock := make(chan struct{})
+	defer close(unblock)
+	testutils.RegisterEcho(tchan, func() {
+		close(gotCall)
+		<-unblock
+	})
+
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+	go raw.Call(ctx, client, tchan.PeerInfo().HostPort, "meta", "echo", nil, nil)
+	<-gotCall
+
+	shutdownCtx, shutdownCancel := context.WithCancel(ctx)
+	defer shutdownCancel()
+	go tchan.Shutdown(shutdownCtx)
+	require.True(t, testutils.WaitFor(time.Second, tchan.Draining), "Channel did not start draining")
+
+	// New connections are rejected while draining, so use the existing one.
+	client.Peers().Add(tchan.PeerInfo().HostPort)
+	c := newTChanMetaClient(NewClient(client, "meta", nil))
+	ret, err := c.Health(ctx, &meta.HealthRequest{})
+	require.NoError(t, err, "Health endpoint failed")
+	assert.False(t, ret.Ok, "Health should fail while draining")
+	assert.Equal(t, stringPtr("draining"), ret.Message, "Health message mismatch")
+	assert.Equal(t, meta.HealthStatePtr(meta.HealthState_STOPPING), ret.State, "Health state mismatch")
+}
+
 func TestMetaReqToReq(t *testing.T) {
 	tests := []struct {
 		msg  string
@@ -198,3 +332,7 @@ func getMetaClient(t *testing.T, dst string) tchanMeta {
 func stringPtr(s string) *string {
 	return &s
 }
+
+func boolPtr(b bool) *bool {
+	return &b
+}
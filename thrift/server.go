@@ -35,6 +35,7 @@ import (
 type handler struct {
 	server         TChanServer
 	postResponseCB PostResponseCB
+	errorMapper    ErrorMapper
 }
 
 // Server handles incoming TChannel calls and forwards them to the matching TChanServer.
@@ -50,6 +51,16 @@ type Server struct {
 // NewServer returns a server that can serve thrift services over TChannel.
 func NewServer(registrar tchannel.Registrar) *Server {
 	metaHandler := newMetaHandler()
+	if d, ok := registrar.(interface {
+		Draining() bool
+	}); ok {
+		metaHandler.draining = d.Draining
+	}
+	if c, ok := registrar.(interface {
+		Health(context.Context, tchannel.HealthCheckType) tchannel.HealthStatus
+	}); ok {
+		metaHandler.health = c.Health
+	}
 	server := &Server{
 		ch:          registrar,
 		log:         registrar.Logger(),
@@ -159,6 +170,10 @@ func (s *Server) handle(origCtx context.Context, handler handler, method string,
 	origCtx = tchannel.ExtractInboundSpan(origCtx, call, headers, tracer)
 	ctx := s.ctxFn(origCtx, method, headers)
 
+	if onewayServer, ok := handler.server.(TChanOnewayServer); ok && onewayServer.IsOneway(method) {
+		return s.handleOneway(ctx, handler, method, call, reader)
+	}
+
 	wp := getProtocolReader(reader)
 	success, resp, err := handler.server.Handle(ctx, method, wp.protocol)
 	thriftProtocolPool.Put(wp)
@@ -171,8 +186,18 @@ func (s *Server) handle(origCtx context.Context, handler handler, method string,
 		if _, ok := err.(thrift.TProtocolException); ok {
 			// We failed to parse the Thrift generated code, so convert the error to bad request.
 			err = tchannel.NewSystemError(tchannel.ErrCodeBadRequest, err.Error())
+		} else if handler.errorMapper != nil {
+			err = mapError(handler.errorMapper, method, err)
+		}
+
+		if exception, ok := err.(*Exception); ok {
+			// The error was mapped to a declared exception, which is sent
+			// like any other application error.
+			success, resp, err = false, exception, nil
 		}
+	}
 
+	if err != nil {
 		reader.Close()
 		call.Response().SendSystemError(err)
 		return nil
@@ -210,6 +235,42 @@ func (s *Server) handle(origCtx context.Context, handler handler, method string,
 	return err
 }
 
+// handleOneway runs the handler for a oneway method and then completes the
+// call with an empty response. Callers don't wait for the response, so handler
+// errors are returned to be logged rather than sent back.
+func (s *Server) handleOneway(ctx Context, handler handler, method string, call *tchannel.InboundCall, reader tchannel.ArgReader) error {
+	wp := getProtocolReader(reader)
+	_, _, handlerErr := handler.server.Handle(ctx, method, wp.protocol)
+	thriftProtocolPool.Put(wp)
+
+	if err := argreader.EnsureEmpty(reader, "reading request body"); err != nil {
+		return err
+	}
+	if err := reader.Close(); err != nil {
+		return err
+	}
+
+	writer, err := call.Response().Arg2Writer()
+	if err != nil {
+		return err
+	}
+	if err := WriteHeaders(writer, nil); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	writer, err = call.Response().Arg3Writer()
+	if err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return handlerErr
+}
+
 func getServiceMethod(method string) (string, string, bool) {
 	s := string(method)
 	sep := strings.Index(s, "::")
@@ -0,0 +1,161 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package thrift
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/uber/tchannel-go"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// errStreamedFieldInvalid is returned when the serialized request struct
+// cannot have a streamed field appended to it.
+var errStreamedFieldInvalid = errors.New("request struct must end with a field stop to append a streamed field")
+
+// StreamedField is a binary field in a request struct whose value is
+// streamed from a reader rather than held in memory.
+//
+// The streamed field is always written as the last field of the request
+// struct, after all the fields written by the request struct itself, so the
+// request struct must not also set the field.
+type StreamedField struct {
+	// ID is the Thrift field ID of the binary field.
+	ID int16
+
+	// Size is the exact number of bytes that will be read from Reader.
+	// Thrift encodes the length of binary fields before the value, so the
+	// size must be known upfront.
+	Size int32
+
+	// Reader is the source of the field value.
+	Reader io.Reader
+}
+
+// TChanStreamingClient is a TChanClient that can also stream a binary field
+// of the request without buffering it in memory.
+type TChanStreamingClient interface {
+	TChanClient
+
+	// CallStreamed makes a call where the streamed field is appended to the
+	// fields serialized from req (which may be nil). Streamed calls
+	// are never retried, since the streamed field cannot be replayed.
+	CallStreamed(ctx Context, serviceName, methodName string, req thrift.TStruct, streamed StreamedField, resp thrift.TStruct) (success bool, err error)
+}
+
+// NewStreamingClient returns a TChanStreamingClient that makes calls over the
+// given tchannel to the given Hyperbahn service.
+func NewStreamingClient(ch *tchannel.Channel, serviceName string, opts *ClientOptions) TChanStreamingClient {
+	return NewClient(ch, serviceName, opts).(*client)
+}
+
+func (c *client) CallStreamed(ctx Context, thriftService, methodName string, req thrift.TStruct, streamed StreamedField, resp thrift.TStruct) (bool, error) {
+	call, err := c.startCall(ctx, thriftService+"::"+methodName, &tchannel.CallOptions{
+		Format: tchannel.Thrift,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if err := writeStreamedArgs(call, ctx.Headers(), req, streamed); err != nil {
+		return false, err
+	}
+
+	respHeaders, isOK, err := readResponse(call.Response(), resp)
+	if err != nil {
+		return false, err
+	}
+
+	ctx.SetResponseHeaders(respHeaders)
+	return isOK, nil
+}
+
+func writeStreamedArgs(call *tchannel.OutboundCall, headers map[string]string, req thrift.TStruct, streamed StreamedField) error {
+	writer, err := call.Arg2Writer()
+	if err != nil {
+		return err
+	}
+	headers = tchannel.InjectOutboundSpan(call.Response(), headers)
+	if err := WriteHeaders(writer, headers); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	writer, err = call.Arg3Writer()
+	if err != nil {
+		return err
+	}
+
+	if err := writeStreamedStruct(writer, req, streamed); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// writeStreamedStruct writes the fields of req, followed by the streamed field
+// and the field stop that ends the struct.
+func writeStreamedStruct(writer io.Writer, req thrift.TStruct, streamed StreamedField) error {
+	if req != nil {
+		var buf bytes.Buffer
+		if err := WriteStruct(&buf, req); err != nil {
+			return err
+		}
+
+		// Drop the field stop that ends the struct so the streamed field can follow.
+		serialized := buf.Bytes()
+		if len(serialized) == 0 || serialized[len(serialized)-1] != thrift.STOP {
+			return errStreamedFieldInvalid
+		}
+		if _, err := writer.Write(serialized[:len(serialized)-1]); err != nil {
+			return err
+		}
+	}
+
+	wp := getProtocolWriter(writer)
+	defer thriftProtocolPool.Put(wp)
+
+	if err := wp.protocol.WriteFieldBegin("", thrift.STRING, streamed.ID); err != nil {
+		return err
+	}
+	if err := wp.protocol.WriteI32(streamed.Size); err != nil {
+		return err
+	}
+
+	n, err := io.CopyN(writer, streamed.Reader, int64(streamed.Size))
+	if err == io.EOF {
+		return fmt.Errorf("streamed field %v ended after %v bytes, expected %v bytes", streamed.ID, n, streamed.Size)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := wp.protocol.WriteFieldStop(); err != nil {
+		return err
+	}
+	return wp.protocol.Flush()
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package thrift_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/uber/tchannel-go/thrift"
+
+	"github.com/uber/tchannel-go/testutils"
+	gen "github.com/uber/tchannel-go/thrift/gen-go/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingReader struct {
+	io.Reader
+	reads int
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	r.reads++
+	return r.Reader.Read(b)
+}
+
+func TestStreamedRequestField(t *testing.T) {
+	blob := testutils.RandString(1024 * 1024)
+	withSetup(t, func(ctx Context, args testArgs) {
+		args.s2.On("Echo", ctxArg(), blob).Return("received", nil)
+
+		client := NewStreamingClient(args.clientCh, args.serverCh.ServiceName(), nil)
+		reader := &countingReader{Reader: strings.NewReader(blob)}
+
+		var res gen.SecondServiceEchoResult
+		success, err := client.CallStreamed(ctx, "SecondService", "Echo", nil /* req */, StreamedField{
+			ID:     1,
+			Size:   int32(len(blob)),
+			Reader: reader,
+		}, &res)
+		require.NoError(t, err, "CallStreamed failed")
+		assert.True(t, success, "CallStreamed should succeed")
+		require.NotNil(t, res.Success, "Missing result")
+		assert.Equal(t, "received", *res.Success, "Unexpected result")
+		assert.True(t, reader.reads > 1, "Streamed field should be read in chunks")
+	})
+}
+
+func TestStreamedRequestFieldShortRead(t *testing.T) {
+	withSetup(t, func(ctx Context, args testArgs) {
+		client := NewStreamingClient(args.clientCh, args.serverCh.ServiceName(), nil)
+
+		var res gen.SecondServiceEchoResult
+		_, err := client.CallStreamed(ctx, "SecondService", "Echo", nil /* req */, StreamedField{
+			ID:     1,
+			Size:   100,
+			Reader: strings.NewReader("too short"),
+		}, &res)
+		require.Error(t, err, "CallStreamed should fail when the reader is too short")
+		assert.Contains(t, err.Error(), "ended after 9 bytes", "Unexpected error")
+	})
+}
@@ -68,6 +68,16 @@ func {{ .ClientConstructor }}(client thrift.TChanClient) {{ .Interface }} {
 }
 
 {{ range .Methods }}
+	{{ if .Oneway }}
+	func (c *{{ $svc.ClientStruct }}) {{ .Name }}({{ .ArgList }}) {{ .RetType }} {
+		args := {{ .ArgsType }}{
+			{{ range .Arguments }}
+				{{ .ArgStructName }}: {{ .Name }},
+			{{ end }}
+		}
+		return thrift.CallOneway(ctx, c.client, c.thriftService, "{{ .ThriftName }}", &args)
+	}
+	{{ else }}
 	func (c *{{ $svc.ClientStruct }}) {{ .Name }}({{ .ArgList }}) {{ .RetType }} {
 		var resp {{ .ResultType }}
 		args := {{ .ArgsType }}{
@@ -93,6 +103,7 @@ func {{ .ClientConstructor }}(client thrift.TChanClient) {{ .Interface }} {
 			return err
 		{{ end }}
 	}
+	{{ end }}
 {{ end }}
 
 type {{ .ServerStruct }} struct {
@@ -129,6 +140,25 @@ func (s *{{ .ServerStruct }}) Methods() []string {
 	}
 }
 
+{{ if .HasOneway }}
+func (s *{{ .ServerStruct }}) IsOneway(methodName string) bool {
+	switch methodName {
+		{{ range .Methods }}
+			{{ if .Oneway }}
+			case "{{ .ThriftName }}":
+				return true
+			{{ end }}
+		{{ end }}
+	}
+	{{ if .HasExtends }}
+		if server, ok := s.TChanServer.(thrift.TChanOnewayServer); ok {
+			return server.IsOneway(methodName)
+		}
+	{{ end }}
+	return false
+}
+{{ end }}
+
 func (s *{{ .ServerStruct }}) Handle(ctx {{ contextType }}, methodName string, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	switch methodName {
 		{{ range .Methods }}
@@ -145,6 +175,18 @@ func (s *{{ .ServerStruct }}) Handle(ctx {{ contextType }}, methodName string, p
 }
 
 {{ range .Methods }}
+	{{ if .Oneway }}
+	func (s *{{ $svc.ServerStruct }}) {{ .HandleFunc }}(ctx {{ contextType }}, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
+		var req {{ .ArgsType }}
+
+		if err := req.Read(protocol); err != nil {
+			return false, nil, err
+		}
+
+		err := s.handler.{{ .Name }}({{ .CallList "req" }})
+		return err == nil, nil, err
+	}
+	{{ else }}
 	func (s *{{ $svc.ServerStruct }}) {{ .HandleFunc }}(ctx {{ contextType }}, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 		var req {{ .ArgsType }}
 		var res {{ .ResultType }}
@@ -184,6 +226,7 @@ func (s *{{ .ServerStruct }}) Handle(ctx {{ contextType }}, methodName string, p
 
 		return err == nil, &res, nil
 	}
+	{{ end }}
 
 {{ end }}
 
@@ -0,0 +1,13 @@
+service Notifier {
+  oneway void notify(1: string message)
+  string echo(1: string arg)
+}
+
+service ExtendedNotifier extends Notifier {
+  oneway void notifyAll(1: list<string> messages)
+  void ping()
+}
+
+service NotifierClient extends Notifier {
+  void pong()
+}
@@ -37,8 +37,8 @@ func Validate(svc *parser.Service) error {
 }
 
 func validateMethod(svc *parser.Service, m *parser.Method) error {
-	if m.Oneway {
-		return fmt.Errorf("oneway methods are not supported: %s.%v", svc.Name, m.Name)
+	if m.Oneway && (m.ReturnType != nil || len(m.Exceptions) > 0) {
+		return fmt.Errorf("oneway methods must be void and cannot throw exceptions: %s.%v", svc.Name, m.Name)
 	}
 	for _, arg := range m.Arguments {
 		if arg.Optional {
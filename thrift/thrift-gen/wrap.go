@@ -97,6 +97,19 @@ func (s *Service) ExtendsServicePrefix() string {
 	return ""
 }
 
+// HasOneway returns whether this service, or a service it extends, has
+// oneway methods.
+func (s *Service) HasOneway() bool {
+	for svc := s; svc != nil; svc = svc.ExtendsService {
+		for _, m := range svc.Service.Methods {
+			if m.Oneway {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 type byMethodName []*Method
 
 func (l byMethodName) Len() int           { return len(l) }
@@ -300,6 +300,16 @@ func TestHeaders(t *testing.T) {
 	})
 }
 
+func TestArgScheme(t *testing.T) {
+	withSetup(t, func(ctx Context, args testArgs) {
+		args.s1.On("Simple", ctxArg()).Return(nil).Run(func(args mock.Arguments) {
+			ctx := args.Get(0).(Context)
+			assert.Equal(t, "thrift", tchannel.CurrentCall(ctx).ArgScheme(), "Unexpected arg scheme")
+		})
+		require.NoError(t, args.c1.Simple(ctx))
+	})
+}
+
 func TestClientHostPort(t *testing.T) {
 	ctx, cancel := NewContext(time.Second)
 	defer cancel()
@@ -473,6 +483,37 @@ func TestThriftMetaHealthNoArgs(t *testing.T) {
 	})
 }
 
+func TestThriftOneway(t *testing.T) {
+	withSetup(t, func(ctx Context, args testArgs) {
+		args.server.Register(onewayServer{gen.NewTChanSecondServiceServer(args.s2)})
+
+		release := make(chan struct{})
+		handled := make(chan struct{})
+		args.s2.On("Echo", ctxArg(), "oneway").Return("", nil).Run(func(mock.Arguments) {
+			<-release
+			close(handled)
+		})
+
+		client := NewClient(args.clientCh, args.serverCh.ServiceName(), nil)
+		err := CallOneway(ctx, client, "SecondService", "Echo", &gen.SecondServiceEchoArgs{Arg: "oneway"})
+		require.NoError(t, err, "CallOneway failed")
+
+		// The call returns without waiting for the handler, which is still blocked.
+		close(release)
+		select {
+		case <-handled:
+		case <-time.After(time.Second):
+			t.Errorf("oneway handler did not run")
+		}
+	})
+}
+
+func TestThriftOnewayUnsupportedClient(t *testing.T) {
+	client := rewriteMethodClient{}
+	err := CallOneway(Wrap(context.Background()), client, "SecondService", "Echo", &gen.SecondServiceEchoArgs{})
+	assert.Error(t, err, "CallOneway should fail for clients without oneway support")
+}
+
 func withSetup(t *testing.T, f func(ctx Context, args testArgs)) {
 	args := testArgs{
 		s1: new(mocks.TChanSimpleService),
@@ -541,3 +582,11 @@ type rewriteMethodClient struct {
 func (c rewriteMethodClient) Call(ctx Context, serviceName, methodName string, req, resp thrift.TStruct) (success bool, err error) {
 	return c.client.Call(ctx, serviceName, c.rewriteTo, req, resp)
 }
+
+type onewayServer struct {
+	TChanServer
+}
+
+func (onewayServer) IsOneway(methodName string) bool {
+	return methodName == "Echo"
+}
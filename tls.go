@@ -0,0 +1,154 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// TLSDialerOptions configures the dialer returned by NewTLSDialer.
+type TLSDialerOptions struct {
+	// Config is the TLS configuration used for all peers. If ServerName is
+	// not set, the host of the peer's host:port is used.
+	Config *tls.Config
+
+	// ClientCertificate, if set, selects the client certificate to present
+	// when dialing the peer with the given host:port, so that peers which
+	// require different client certificates can be called from the same
+	// channel. A nil certificate means no client certificate is presented.
+	// If this is not set, the certificates in Config are used.
+	ClientCertificate func(hostPort string) (*tls.Certificate, error)
+}
+
+// NewTLSDialer returns a dialer that can be used as ChannelOptions.Dialer
+// to connect to peers over TLS.
+func NewTLSDialer(opts TLSDialerOptions) func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+	return func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		config, err := tlsConfigForPeer(opts, hostPort)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := dialContext(ctx, hostPort)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConn := tls.Client(conn, config)
+		if deadline, ok := ctx.Deadline(); ok {
+			tlsConn.SetDeadline(deadline)
+		}
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn.SetDeadline(time.Time{})
+		return tlsConn, nil
+	}
+}
+
+// tlsConfigForPeer returns the TLS configuration to use for the given peer.
+func tlsConfigForPeer(opts TLSDialerOptions, hostPort string) (*tls.Config, error) {
+	var config *tls.Config
+	if opts.Config != nil {
+		config = opts.Config.Clone()
+	} else {
+		config = &tls.Config{}
+	}
+
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(hostPort)
+		if err != nil {
+			return nil, err
+		}
+		config.ServerName = host
+	}
+
+	if opts.ClientCertificate != nil {
+		cert, err := opts.ClientCertificate(hostPort)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = nil
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert == nil {
+				// An empty certificate means no certificate is sent.
+				return &tls.Certificate{}, nil
+			}
+			return cert, nil
+		}
+	}
+
+	return config, nil
+}
+
+// PeerCertificates returns the certificate chain presented by the remote peer
+// of the incoming call in ctx, leaf first. It returns nil if ctx is not for
+// an incoming call, or if the call was not received over a TLS connection.
+// The certificates are only verified if the server's tls.Config requires it
+// (e.g. using tls.RequireAndVerifyClientCert).
+func PeerCertificates(ctx context.Context) []*x509.Certificate {
+	call, ok := CurrentCall(ctx).(*InboundCall)
+	if !ok {
+		return nil
+	}
+
+	state, ok := call.conn.tlsConnectionState()
+	if !ok {
+		return nil
+	}
+	return state.PeerCertificates
+}
+
+// tlsConn returns the underlying TLS connection, if the connection uses TLS.
+func (c *Connection) tlsConn() (*tls.Conn, bool) {
+	conn := c.conn
+	if cc, ok := conn.(*compressedConn); ok {
+		conn = cc.Conn
+	}
+	if cc, ok := conn.(*coalescingConn); ok {
+		conn = cc.Conn
+	}
+
+	tlsConn, ok := conn.(*tls.Conn)
+	return tlsConn, ok
+}
+
+// isTLS returns whether the underlying connection uses TLS.
+func (c *Connection) isTLS() bool {
+	_, ok := c.tlsConn()
+	return ok
+}
+
+// tlsConnectionState returns the TLS state of the underlying connection, if
+// the connection uses TLS.
+func (c *Connection) tlsConnectionState() (tls.ConnectionState, bool) {
+	tlsConn, ok := c.tlsConn()
+	if !ok {
+		return tls.ConnectionState{}, false
+	}
+	return tlsConn.ConnectionState(), true
+}
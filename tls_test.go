@@ -0,0 +1,450 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+var testCertSerial int64
+
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Failed to generate CA key")
+
+	testCertSerial++
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(testCertSerial),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err, "Failed to create CA certificate")
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err, "Failed to parse CA certificate")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue creates a certificate signed by the CA that is valid for 127.0.0.1.
+func (ca *testCA) issue(t *testing.T, name string) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Failed to generate key")
+
+	testCertSerial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(testCertSerial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err, "Failed to create certificate")
+
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// newTLSServer starts a server that requires client certificates signed by clientCA.
+func newTLSServer(t *testing.T, serverCert *tls.Certificate, clientCA *testCA) *Channel {
+	ch, err := NewChannel("tls-server", nil)
+	require.NoError(t, err, "NewChannel failed")
+	testutils.RegisterEcho(ch, nil)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Listen failed")
+	require.NoError(t, ch.Serve(tls.NewListener(l, &tls.Config{
+		Certificates: []tls.Certificate{*serverCert},
+		ClientCAs:    clientCA.pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})), "Serve failed")
+	return ch
+}
+
+func TestTLSDialerPerPeerClientCertificates(t *testing.T) {
+	serverCA := newTestCA(t, "server-ca")
+	caA := newTestCA(t, "client-ca-a")
+	caB := newTestCA(t, "client-ca-b")
+	certA := caA.issue(t, "client-a")
+	certB := caB.issue(t, "client-b")
+
+	serverA := newTLSServer(t, serverCA.issue(t, "server-a"), caA)
+	defer serverA.Close()
+	serverB := newTLSServer(t, serverCA.issue(t, "server-b"), caB)
+	defer serverB.Close()
+	hostPortA := serverA.PeerInfo().HostPort
+	hostPortB := serverB.PeerInfo().HostPort
+
+	tests := []struct {
+		msg        string
+		clientCert func(hostPort string) (*tls.Certificate, error)
+		wantErrA   bool
+		wantErrB   bool
+	}{
+		{
+			msg: "certificate per peer",
+			clientCert: func(hostPort string) (*tls.Certificate, error) {
+				if hostPort == hostPortA {
+					return certA, nil
+				}
+				return certB, nil
+			},
+		},
+		{
+			msg: "same certificate for all peers",
+			clientCert: func(hostPort string) (*tls.Certificate, error) {
+				return certA, nil
+			},
+			wantErrB: true,
+		},
+		{
+			msg: "no certificate",
+			clientCert: func(hostPort string) (*tls.Certificate, error) {
+				return nil, nil
+			},
+			wantErrA: true,
+			wantErrB: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			// With TLS 1.3, the server may reject the client certificate after
+			// the client has completed the TLS handshake, so the error is
+			// only seen during the TChannel handshake.
+			opts := testutils.NewOpts().
+				AddLogFilter("Failed during connection handshake.", 2).
+				SetDialer(NewTLSDialer(TLSDialerOptions{
+					Config:            &tls.Config{RootCAs: serverCA.pool},
+					ClientCertificate: tt.clientCert,
+				}))
+			client := testutils.NewClient(t, opts)
+			defer client.Close()
+
+			for _, peer := range []struct {
+				hostPort string
+				wantErr  bool
+			}{
+				{hostPortA, tt.wantErrA},
+				{hostPortB, tt.wantErrB},
+			} {
+				ctx, cancel := NewContext(testutils.Timeout(time.Second))
+				_, arg3, _, err := raw.Call(ctx, client, peer.hostPort, "tls-server", "echo", nil, []byte("hello"))
+				cancel()
+
+				if peer.wantErr {
+					assert.Error(t, err, "Call to %v should fail", peer.hostPort)
+					continue
+				}
+				if assert.NoError(t, err, "Call to %v failed", peer.hostPort) {
+					assert.Equal(t, "hello", string(arg3), "Unexpected response")
+				}
+			}
+		})
+	}
+}
+
+func TestPeerCertificates(t *testing.T) {
+	serverCA := newTestCA(t, "server-ca")
+	clientCA := newTestCA(t, "client-ca")
+
+	server := newTLSServer(t, serverCA.issue(t, "server"), clientCA)
+	defer server.Close()
+	testutils.RegisterFunc(server, "whoami", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		certs := PeerCertificates(ctx)
+		if len(certs) == 0 {
+			return nil, errors.New("no peer certificates")
+		}
+		return &raw.Res{Arg3: []byte(certs[0].Subject.CommonName)}, nil
+	})
+
+	client := testutils.NewClient(t, testutils.NewOpts().SetDialer(NewTLSDialer(TLSDialerOptions{
+		Config: &tls.Config{
+			RootCAs:      serverCA.pool,
+			Certificates: []tls.Certificate{*clientCA.issue(t, "authorized-client")},
+		},
+	})))
+	defer client.Close()
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	_, arg3, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, "tls-server", "whoami", nil, nil)
+	require.NoError(t, err, "Call failed")
+	assert.Equal(t, "authorized-client", string(arg3), "Unexpected client certificate subject")
+}
+
+func TestPeerCertificatesWithoutTLS(t *testing.T) {
+	assert.Nil(t, PeerCertificates(context.Background()), "Expected no certificates outside of a call")
+
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		testutils.RegisterFunc(ts.Server(), "whoami", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			assert.Nil(t, PeerCertificates(ctx), "Expected no certificates without TLS")
+			return &raw.Res{}, nil
+		})
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, ts.NewClient(nil), ts.HostPort(), ts.ServiceName(), "whoami", nil, nil)
+		require.NoError(t, err, "Call failed")
+	})
+}
+
+func TestChannelTLSConfig(t *testing.T) {
+	serverCA := newTestCA(t, "server-ca")
+	clientCA := newTestCA(t, "client-ca")
+
+	server, err := NewChannel("tls-server", &ChannelOptions{
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{*serverCA.issue(t, "server")},
+			ClientCAs:    clientCA.pool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+		},
+	})
+	require.NoError(t, err, "NewChannel failed")
+	defer server.Close()
+	require.NoError(t, server.ListenAndServe("127.0.0.1:0"), "ListenAndServe failed")
+	testutils.RegisterEcho(server, nil)
+
+	client := testutils.NewClient(t, testutils.NewOpts().SetTLSConfig(&tls.Config{
+		RootCAs:      serverCA.pool,
+		Certificates: []tls.Certificate{*clientCA.issue(t, "client")},
+	}))
+	defer client.Close()
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	_, arg3, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, "tls-server", "echo", nil, []byte("hello"))
+	require.NoError(t, err, "Call failed")
+	assert.Equal(t, "hello", string(arg3), "Unexpected response")
+
+	for _, ch := range []*Channel{client, server} {
+		for _, peer := range ch.IntrospectState(nil).RootPeers {
+			conns := append(peer.InboundConnections, peer.OutboundConnections...)
+			require.Len(t, conns, 1, "Expected a single connection")
+			assert.True(t, conns[0].Encrypted, "%v: connection should be encrypted", ch.ServiceName())
+		}
+	}
+}
+
+func TestChannelTLSConfigRejectsPlainConnections(t *testing.T) {
+	serverCA := newTestCA(t, "server-ca")
+	server, err := NewChannel("tls-server", &ChannelOptions{
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{*serverCA.issue(t, "server")},
+		},
+	})
+	require.NoError(t, err, "NewChannel failed")
+	defer server.Close()
+	require.NoError(t, server.ListenAndServe("127.0.0.1:0"), "ListenAndServe failed")
+	testutils.RegisterEcho(server, nil)
+
+	opts := testutils.NewOpts().AddLogFilter("Failed during connection handshake.", 1)
+	client := testutils.NewClient(t, opts)
+	defer client.Close()
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	_, _, _, err = raw.Call(ctx, client, server.PeerInfo().HostPort, "tls-server", "echo", nil, nil)
+	assert.Error(t, err, "Call without TLS should fail")
+}
+
+func TestConnectionNotEncryptedWithoutTLS(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+		client := ts.NewClient(nil)
+		testutils.AssertEcho(t, client, ts.HostPort(), ts.ServiceName())
+
+		for _, peer := range client.IntrospectState(nil).RootPeers {
+			for _, conn := range peer.OutboundConnections {
+				assert.False(t, conn.Encrypted, "Connection should not be encrypted")
+			}
+		}
+	})
+}
+
+// mutualTLSConfig returns a config that presents cert, and requires peers to
+// present certificates signed by ca, for both servers and clients.
+func mutualTLSConfig(ca *testCA, cert *tls.Certificate) *tls.Config {
+	config := &tls.Config{
+		RootCAs:    ca.pool,
+		ClientCAs:  ca.pool,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+	}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{*cert}
+	}
+	return config
+}
+
+func TestChannelTLSConfigClientCertificates(t *testing.T) {
+	ca := newTestCA(t, "ca")
+	otherCA := newTestCA(t, "other-ca")
+
+	server, err := NewChannel("tls-server", &ChannelOptions{
+		TLSConfig: mutualTLSConfig(ca, ca.issue(t, "server")),
+	})
+	require.NoError(t, err, "NewChannel failed")
+	defer server.Close()
+	require.NoError(t, server.ListenAndServe("127.0.0.1:0"), "ListenAndServe failed")
+	testutils.RegisterEcho(server, nil)
+
+	tests := []struct {
+		msg        string
+		clientCert *tls.Certificate
+		wantErr    bool
+	}{
+		{
+			msg:        "trusted certificate",
+			clientCert: ca.issue(t, "client"),
+		},
+		{
+			msg:     "no certificate",
+			wantErr: true,
+		},
+		{
+			msg:        "untrusted certificate",
+			clientCert: otherCA.issue(t, "client"),
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			// With TLS 1.3, the server may reject the client certificate after
+			// the client has completed the TLS handshake, so the error is
+			// only seen during the TChannel handshake.
+			opts := testutils.NewOpts().
+				AddLogFilter("Failed during connection handshake.", 1).
+				SetTLSConfig(mutualTLSConfig(ca, tt.clientCert))
+			client := testutils.NewClient(t, opts)
+			defer client.Close()
+
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+
+			_, arg3, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, "tls-server", "echo", nil, []byte("hello"))
+			if tt.wantErr {
+				assert.Error(t, err, "Call should be rejected")
+				return
+			}
+			require.NoError(t, err, "Call failed")
+			assert.Equal(t, "hello", string(arg3), "Unexpected response")
+		})
+	}
+}
+
+func TestChannelTLSConfigPeerInfo(t *testing.T) {
+	ca := newTestCA(t, "ca")
+
+	var channels []*Channel
+	for _, name := range []string{"tls-server", "tls-client"} {
+		ch, err := NewChannel(name, &ChannelOptions{
+			TLSConfig: mutualTLSConfig(ca, ca.issue(t, name)),
+		})
+		require.NoError(t, err, "NewChannel failed")
+		defer ch.Close()
+		require.NoError(t, ch.ListenAndServe("127.0.0.1:0"), "ListenAndServe failed")
+		channels = append(channels, ch)
+	}
+	server, client := channels[0], channels[1]
+
+	for _, ch := range channels {
+		host, port, err := net.SplitHostPort(ch.PeerInfo().HostPort)
+		require.NoError(t, err, "Failed to parse host:port")
+		assert.Equal(t, "127.0.0.1", host, "%v: unexpected host", ch.ServiceName())
+		assert.NotEqual(t, "0", port, "%v: unexpected port", ch.ServiceName())
+		assert.False(t, ch.PeerInfo().IsEphemeralHostPort(), "%v: host:port should not be ephemeral", ch.ServiceName())
+	}
+
+	testutils.RegisterFunc(server, "remote", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		return &raw.Res{Arg3: []byte(CurrentCall(ctx).RemotePeer().HostPort)}, nil
+	})
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	call, err := client.BeginCall(ctx, server.PeerInfo().HostPort, "tls-server", "remote", nil)
+	require.NoError(t, err, "BeginCall failed")
+	_, arg3, _, err := raw.WriteArgs(call, nil, nil)
+	require.NoError(t, err, "Call failed")
+	assert.Equal(t, server.PeerInfo().HostPort, call.RemotePeer().HostPort, "Unexpected server host:port seen by the client")
+	assert.Equal(t, client.PeerInfo().HostPort, string(arg3), "Unexpected client host:port seen by the server")
+}
+
+func TestRelayTLS(t *testing.T) {
+	ca := newTestCA(t, "ca")
+	opts := testutils.NewOpts().
+		SetRelayOnly().
+		SetTLSConfig(mutualTLSConfig(ca, ca.issue(t, "server")))
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		client := ts.NewClient(testutils.NewOpts().SetTLSConfig(mutualTLSConfig(ca, ca.issue(t, "client"))))
+		testutils.AssertEcho(t, client, ts.HostPort(), ts.ServiceName())
+
+		assert.Equal(t, ts.Relay().PeerInfo().HostPort, ts.HostPort(), "Calls should be made to the relay")
+		var numConns int
+		for hostPort, peer := range ts.Relay().IntrospectState(nil).RootPeers {
+			for _, conn := range append(peer.InboundConnections, peer.OutboundConnections...) {
+				assert.True(t, conn.Encrypted, "Relay connection to %v should be encrypted", hostPort)
+				numConns++
+			}
+		}
+		assert.True(t, numConns >= 2, "Expected relay connections to the client and server, got %v", numConns)
+	})
+}
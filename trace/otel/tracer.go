@@ -0,0 +1,144 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package otel provides a tchannel.CallTracer that creates OpenTelemetry spans
+// for inbound and outbound calls.
+package otel
+
+import (
+	"time"
+
+	"github.com/uber/tchannel-go"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/context"
+)
+
+// instrumentationName is the name of the OpenTelemetry tracer used for
+// TChannel spans.
+const instrumentationName = "github.com/uber/tchannel-go"
+
+// appPropagator propagates the span context and baggage in application
+// headers, using the same tracing key prefix as OpenTracing.
+var appPropagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+// transportPropagator propagates the span context in the TraceParent and
+// TraceState transport headers.
+var transportPropagator = propagation.TraceContext{}
+
+type callTracer struct {
+	tracer trace.Tracer
+}
+
+// NewCallTracer returns a tchannel.CallTracer that creates spans named
+// service::method using the given TracerProvider. The span context and baggage
+// are propagated in the application headers, and the span context in the
+// transport headers, using the W3C trace context and baggage formats.
+// If provider is nil, it returns nil, so no spans are created.
+func NewCallTracer(provider trace.TracerProvider) tchannel.CallTracer {
+	if provider == nil {
+		return nil
+	}
+	return &callTracer{tracer: provider.Tracer(instrumentationName)}
+}
+
+// spanName returns the name of the span for a call to service::method.
+func spanName(call tchannel.CallTraceInfo) string {
+	return call.Service + "::" + call.Method
+}
+
+func attributes(call tchannel.CallTraceInfo) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("rpc.system", "tchannel"),
+		attribute.String("rpc.service", call.Service),
+		attribute.String("rpc.method", call.Method),
+		attribute.String("as", string(call.Format)),
+		attribute.String("net.peer.name", call.RemoteHostPort),
+	}
+}
+
+// StartOutbound starts a client span as a child of any span in ctx, and
+// injects its span context into the transport headers.
+func (t *callTracer) StartOutbound(ctx context.Context, call tchannel.CallTraceInfo, transportHeaders tchannel.TraceCarrier) tchannel.CallTraceSpan {
+	ctx, span := t.tracer.Start(ctx, spanName(call),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(call.StartTime),
+		trace.WithAttributes(attributes(call)...),
+	)
+	transportPropagator.Inject(ctx, transportHeaders)
+	return &callSpan{ctx: ctx, span: span}
+}
+
+// StartInbound extracts the span context from the transport headers, and the
+// span context and baggage from the application headers, and starts a server
+// span. The application headers take precedence if both carry a span context.
+// The returned context contains the span and the restored baggage.
+func (t *callTracer) StartInbound(ctx context.Context, call tchannel.CallTraceInfo, transportHeaders, appHeaders tchannel.TraceCarrier) (context.Context, tchannel.CallTraceSpan) {
+	ctx = transportPropagator.Extract(ctx, transportHeaders)
+	ctx = appPropagator.Extract(ctx, appHeaders)
+	ctx, span := t.tracer.Start(ctx, spanName(call),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithTimestamp(call.StartTime),
+		trace.WithAttributes(attributes(call)...),
+		trace.WithAttributes(attribute.String("rpc.caller", call.Caller)),
+	)
+	return ctx, &callSpan{ctx: ctx, span: span}
+}
+
+// RecordRetry adds an event for a retried attempt to the span in ctx, if
+// there is one.
+func (t *callTracer) RecordRetry(ctx context.Context, attempt int, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.AddEvent("tchannel.retry", trace.WithAttributes(
+		attribute.Int("rpc.tchannel.attempt", attempt),
+		attribute.String("error", err.Error()),
+	))
+}
+
+// callSpan is the span of a single call, along with the context containing
+// it, which is used to inject the span context and baggage.
+type callSpan struct {
+	ctx  context.Context
+	span trace.Span
+}
+
+func (s *callSpan) Inject(appHeaders tchannel.TraceCarrier) {
+	appPropagator.Inject(s.ctx, appHeaders)
+}
+
+// End ends the span, marking it as failed if the call failed.
+func (s *callSpan) End(now time.Time, err error, failed bool) {
+	if err != nil {
+		s.span.RecordError(err, trace.WithTimestamp(now))
+	}
+	if failed {
+		s.span.SetStatus(codes.Error, "")
+	}
+	s.span.End(trace.WithTimestamp(now))
+}
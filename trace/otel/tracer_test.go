@@ -0,0 +1,227 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package otel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/json"
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/context"
+)
+
+type observedCall struct {
+	spanContext trace.SpanContext
+	baggage     string
+	headers     map[string]string
+}
+
+// withServer runs f against a channel with a CallTracer using tp, and a JSON
+// "call" handler that reports the span and baggage it sees.
+func withServer(t *testing.T, tp trace.TracerProvider, f func(ch *tchannel.Channel, observed chan observedCall)) {
+	opts := testutils.NewOpts().SetCallTracer(NewCallTracer(tp)).NoRelay()
+	testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+		ch := ts.Server()
+		observed := make(chan observedCall, 1)
+		handler := func(ctx json.Context, _ *struct{}) (*struct{}, error) {
+			observed <- observedCall{
+				spanContext: trace.SpanContextFromContext(ctx),
+				baggage:     baggage.FromContext(ctx).Member("tenant").Value(),
+				headers:     ctx.Headers(),
+			}
+			return &struct{}{}, nil
+		}
+		onError := func(ctx context.Context, err error) { t.Errorf("onError %v", err) }
+		require.NoError(t, json.Register(ch, json.Handlers{"call": handler}, onError))
+
+		f(ch, observed)
+	})
+}
+
+func makeCall(t *testing.T, ch *tchannel.Channel, ctx context.Context) {
+	ctx, cancel := tchannel.NewContextBuilder(time.Second).SetParentContext(ctx).Build()
+	defer cancel()
+
+	peer := ch.Peers().GetOrAdd(ch.PeerInfo().HostPort)
+	require.NoError(t, json.CallPeer(json.WithHeaders(ctx, map[string]string{"app": "header"}), peer,
+		ch.PeerInfo().ServiceName, "call", nil, &struct{}{}))
+}
+
+func waitForSpans(t *testing.T, recorder *tracetest.SpanRecorder, n int) []sdktrace.ReadOnlySpan {
+	// Spans are ended after the response is sent or read, which may happen
+	// after the call returns.
+	require.True(t, testutils.WaitFor(time.Second, func() bool {
+		return len(recorder.Ended()) >= n
+	}), "Expected %v spans", n)
+	return recorder.Ended()
+}
+
+func TestSpanPropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	withServer(t, tp, func(ch *tchannel.Channel, observed chan observedCall) {
+		ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+		member, err := baggage.NewMember("tenant", "acme")
+		require.NoError(t, err)
+		bag, err := baggage.New(member)
+		require.NoError(t, err)
+		ctx = baggage.ContextWithBaggage(ctx, bag)
+
+		makeCall(t, ch, ctx)
+		parent.End()
+
+		got := <-observed
+		assert.Equal(t, parent.SpanContext().TraceID(), got.spanContext.TraceID(), "Trace ID should be propagated")
+		assert.True(t, got.spanContext.IsSampled(), "Sampled flag should be propagated")
+		assert.Equal(t, "acme", got.baggage, "Baggage should be restored on the server")
+		assert.Equal(t, map[string]string{"app": "header"}, got.headers, "Tracing headers should be hidden from handlers")
+
+		spans := waitForSpans(t, recorder, 3)
+		byKind := make(map[trace.SpanKind]sdktrace.ReadOnlySpan)
+		for _, span := range spans {
+			byKind[span.SpanKind()] = span
+		}
+		client, server := byKind[trace.SpanKindClient], byKind[trace.SpanKindServer]
+		require.NotNil(t, client, "Missing client span")
+		require.NotNil(t, server, "Missing server span")
+
+		wantName := ch.PeerInfo().ServiceName + "::call"
+		assert.Equal(t, wantName, client.Name(), "Unexpected client span name")
+		assert.Equal(t, wantName, server.Name(), "Unexpected server span name")
+		assert.Equal(t, parent.SpanContext().SpanID(), client.Parent().SpanID(), "Client span should be a child of the parent")
+		assert.Equal(t, client.SpanContext().SpanID(), server.Parent().SpanID(), "Server span should be a child of the client span")
+		assert.True(t, server.Parent().IsRemote(), "Server span parent should be remote")
+	})
+}
+
+func TestHonorsParentSampling(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+		sdktrace.WithSpanProcessor(recorder),
+	)
+
+	withServer(t, tp, func(ch *tchannel.Channel, observed chan observedCall) {
+		parent := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{1},
+			SpanID:  trace.SpanID{1},
+		})
+		ctx := trace.ContextWithRemoteSpanContext(context.Background(), parent)
+
+		makeCall(t, ch, ctx)
+
+		got := <-observed
+		assert.Equal(t, parent.TraceID(), got.spanContext.TraceID(), "Trace ID should be propagated")
+		assert.False(t, got.spanContext.IsSampled(), "Unsampled parent should not be sampled")
+		assert.Empty(t, recorder.Ended(), "No spans should be recorded for an unsampled parent")
+	})
+}
+
+func TestDisabledWithoutProvider(t *testing.T) {
+	withServer(t, nil, func(ch *tchannel.Channel, observed chan observedCall) {
+		recorder := tracetest.NewSpanRecorder()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+		defer parent.End()
+
+		makeCall(t, ch, ctx)
+
+		got := <-observed
+		assert.False(t, got.spanContext.IsValid(), "No span should be started without a CallTracer")
+		assert.Equal(t, map[string]string{"app": "header"}, got.headers, "Unexpected headers")
+	})
+}
+
+func TestTransportHeaders(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	withServer(t, tp, func(ch *tchannel.Channel, _ chan observedCall) {
+		type rawObserved struct {
+			traceParent string
+			spanContext trace.SpanContext
+		}
+		observed := make(chan rawObserved, 1)
+		ch.Register(tchannel.HandlerFunc(func(ctx context.Context, call *tchannel.InboundCall) {
+			// Raw calls have no application headers, so the span context
+			// can only be extracted from the transport headers.
+			ctx = tchannel.ExtractInboundSpan(ctx, call, nil, ch.Tracer())
+			observed <- rawObserved{call.TraceParent(), trace.SpanContextFromContext(ctx)}
+			_, err := raw.ReadArgs(call)
+			assert.NoError(t, err, "Failed to read args")
+			assert.NoError(t, raw.WriteResponse(call.Response(), &raw.Res{}), "Failed to write response")
+		}), "raw")
+
+		ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+		defer parent.End()
+		ctx, cancel := tchannel.NewContextBuilder(time.Second).SetParentContext(ctx).Build()
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, ch, ch.PeerInfo().HostPort, ch.PeerInfo().ServiceName, "raw", nil, nil)
+		require.NoError(t, err, "Call failed")
+
+		got := <-observed
+		assert.Contains(t, got.traceParent, parent.SpanContext().TraceID().String(), "Trace ID should be in the transport header")
+		assert.Equal(t, parent.SpanContext().TraceID(), got.spanContext.TraceID(), "Trace ID should be propagated")
+		assert.NotEqual(t, parent.SpanContext().SpanID(), got.spanContext.SpanID(), "Server span should be a new span")
+	})
+}
+
+func TestRetryEvents(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	withServer(t, tp, func(ch *tchannel.Channel, _ chan observedCall) {
+		ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+		ctx, cancel := tchannel.NewContextBuilder(time.Second).SetParentContext(ctx).Build()
+		defer cancel()
+
+		var attempts int
+		require.NoError(t, ch.RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
+			attempts++
+			if attempts < 3 {
+				return tchannel.ErrServerBusy
+			}
+			return nil
+		}))
+		parent.End()
+
+		spans := waitForSpans(t, recorder, 1)
+		events := spans[0].Events()
+		require.Len(t, events, 2, "Expected an event per retried attempt")
+		for i, event := range events {
+			assert.Equal(t, "tchannel.retry", event.Name, "Unexpected event name")
+			assert.Contains(t, event.Attributes, attribute.Int("rpc.tchannel.attempt", i+1), "Missing attempt attribute")
+		}
+	})
+}
@@ -165,21 +165,30 @@ func (c *Connection) startOutboundSpan(ctx context.Context, serviceName, methodN
 // InjectOutboundSpan retrieves OpenTracing Span from `response`, where it is stored
 // when the outbound call is initiated. The tracing API is used to serialize the span
 // into the application `headers`, which will propagate tracing context to the server.
+// If the channel has a CallTracer, the span context and baggage of its span are
+// also injected into the headers, as are any application headers set by
+// outbound interceptors using OutboundCall.SetApplicationHeader.
 // Returns modified headers containing serialized tracing context.
 //
 // Sometimes caller pass a shared instance of the `headers` map, so instead of modifying
 // it we clone it into the new map (assuming that Tracer actually injects some tracing keys).
 func InjectOutboundSpan(response *OutboundCallResponse, headers map[string]string) map[string]string {
+	headers = response.addAppHeaders(headers)
 	span := response.span
-	if span == nil {
+	if span == nil && response.traceSpan == nil {
 		return headers
 	}
 	newHeaders := make(map[string]string)
 	carrier := tracingHeadersCarrier(newHeaders)
-	if err := span.Tracer().Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
-		// Something had to go seriously wrong for Inject to fail, usually a setup problem.
-		// A good Tracer implementation may also emit a metric.
-		response.log.WithFields(ErrField(err)).Error("Failed to inject tracing span.")
+	if span != nil {
+		if err := span.Tracer().Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
+			// Something had to go seriously wrong for Inject to fail, usually a setup problem.
+			// A good Tracer implementation may also emit a metric.
+			response.log.WithFields(ErrField(err)).Error("Failed to inject tracing span.")
+		}
+	}
+	if response.traceSpan != nil {
+		response.traceSpan.Inject(carrier)
 	}
 	if len(newHeaders) == 0 {
 		return headers // Tracer did not add any tracing headers, so return the original map
@@ -226,7 +235,12 @@ func (c *Connection) extractInboundSpan(callReq *callReq) opentracing.Span {
 // by all tracers is used to deserialize the tracing context from the
 // application headers and start a new server-side span.
 // Once the span is started, it is wrapped in a new Context, which is returned.
+// If the channel has a CallTracer, its server span is also started, and any
+// baggage it propagates in the headers is restored into the Context.
 func ExtractInboundSpan(ctx context.Context, call *InboundCall, headers map[string]string, tracer opentracing.Tracer) context.Context {
+	// The CallTracer reads the tracing keys before they are removed below.
+	ctx = call.startInboundCallTrace(ctx, headers)
+
 	var span = call.Response().span
 	if span != nil {
 		if headers != nil {
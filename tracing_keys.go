@@ -94,6 +94,22 @@ func (c tracingHeadersCarrier) ForeachKey(handler func(key, val string) error) e
 	return nil
 }
 
+// Get conforms to the TraceCarrier interface.
+func (c tracingHeadersCarrier) Get(key string) string {
+	return c[tracingKeyEncoding.mapAndCache(key)]
+}
+
+// Keys conforms to the TraceCarrier interface.
+func (c tracingHeadersCarrier) Keys() []string {
+	var keys []string
+	for k := range c {
+		if strings.HasPrefix(k, tracingKeyPrefix) {
+			keys = append(keys, tracingKeyDecoding.mapAndCache(k))
+		}
+	}
+	return keys
+}
+
 func (c tracingHeadersCarrier) RemoveTracingKeys() {
 	for key := range c {
 		if strings.HasPrefix(key, tracingKeyPrefix) {
@@ -0,0 +1,113 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tunnel
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ConnectDialerOptions configures the dialer returned by NewConnectDialer.
+type ConnectDialerOptions struct {
+	// ProxyHostPort is the host:port of the HTTP proxy.
+	ProxyHostPort string
+
+	// Header is sent with each CONNECT request, e.g. to set
+	// Proxy-Authorization.
+	Header http.Header
+
+	// Username and Password authenticate with the proxy using basic
+	// authentication. If Username is empty, the Proxy-Authorization header is
+	// only sent if it's set in Header.
+	Username string
+	Password string
+
+	// Dialer is used to connect to the proxy. If not set, a net.Dialer is used.
+	Dialer Dialer
+}
+
+// NewConnectDialer returns a dialer that can be used as ChannelOptions.Dialer
+// to connect to peers through an HTTP proxy using CONNECT requests.
+func NewConnectDialer(opts ConnectDialerOptions) Dialer {
+	dial := opts.Dialer
+	if dial == nil {
+		dial = defaultDialer
+	}
+
+	header := make(http.Header, len(opts.Header)+1)
+	for k, v := range opts.Header {
+		header[k] = v
+	}
+	if opts.Username != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(opts.Username + ":" + opts.Password))
+		header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+
+	return func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		conn, err := dial(ctx, network, opts.ProxyHostPort)
+		if err != nil {
+			return nil, err
+		}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		tunneled, err := connect(conn, hostPort, header)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn.SetDeadline(time.Time{})
+		return tunneled, nil
+	}
+}
+
+// connect sends a CONNECT request for hostPort over conn, and returns the
+// tunneled connection once the proxy accepts it.
+func connect(conn net.Conn, hostPort string, header http.Header) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: hostPort},
+		Host:   hostPort,
+		Header: header,
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy CONNECT to %v failed: %v", hostPort, resp.Status)
+	}
+	return withBuffered(conn, br), nil
+}
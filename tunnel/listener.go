@@ -0,0 +1,145 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tunnel
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+// ErrListenerClosed is returned by Accept once the Listener is closed.
+var ErrListenerClosed = errors.New("tunnel listener closed")
+
+// Listener is a net.Listener that accepts TChannel connections tunneled over
+// HTTP, using either CONNECT requests or WebSocket upgrades. It must be
+// served as an http.Handler, and passed to Channel.Serve.
+type Listener struct {
+	addr   net.Addr
+	conns  chan net.Conn
+	ws     websocket.Server
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewListener returns a Listener which reports addr as its address. This
+// should be the address of the HTTP server the Listener is served on, as it
+// is used as the channel's host:port.
+func NewListener(addr net.Addr) *Listener {
+	l := &Listener{
+		addr:   addr,
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+	l.ws.Handler = l.serveWebSocket
+	return l
+}
+
+// Accept waits for and returns the next tunneled connection.
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, ErrListenerClosed
+	}
+}
+
+// Close stops the Listener accepting connections. Connections that were
+// already accepted are not closed.
+func (l *Listener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+// Addr returns the address passed to NewListener.
+func (l *Listener) Addr() net.Addr {
+	return l.addr
+}
+
+// ServeHTTP accepts CONNECT requests and WebSocket upgrade requests as
+// tunneled connections.
+func (l *Listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		l.ws.ServeHTTP(w, r)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection cannot be tunneled", http.StatusInternalServerError)
+		return
+	}
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		conn.Close()
+		return
+	}
+	if !l.accept(withBuffered(conn, brw.Reader)) {
+		conn.Close()
+	}
+}
+
+func (l *Listener) serveWebSocket(ws *websocket.Conn) {
+	ws.PayloadType = websocket.BinaryFrame
+	conn := &serverWebSocketConn{
+		addrConn: addrConn{Conn: ws, local: l.addr, remote: hostPortAddr(ws.Request().RemoteAddr)},
+		done:     make(chan struct{}),
+	}
+	if !l.accept(conn) {
+		return
+	}
+
+	// The WebSocket is closed when this handler returns, so wait for the
+	// connection to be closed by the channel.
+	<-conn.done
+}
+
+// accept passes conn to Accept, returning false if the Listener is closed.
+func (l *Listener) accept(conn net.Conn) bool {
+	select {
+	case l.conns <- conn:
+		return true
+	case <-l.closed:
+		return false
+	}
+}
+
+// serverWebSocketConn signals the WebSocket handler once it's closed.
+type serverWebSocketConn struct {
+	addrConn
+
+	once sync.Once
+	done chan struct{}
+}
+
+func (c *serverWebSocketConn) Close() error {
+	err := c.addrConn.Close()
+	c.once.Do(func() { close(c.done) })
+	return err
+}
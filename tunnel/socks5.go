@@ -0,0 +1,218 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// SOCKS5 protocol constants, see RFC 1928 and RFC 1929.
+const (
+	socks5Version      = 5
+	socks5AuthVersion  = 1
+	socks5AuthNone     = 0
+	socks5AuthPassword = 2
+	socks5CmdConnect   = 1
+	socks5AddrIPv4     = 1
+	socks5AddrDomain   = 3
+	socks5AddrIPv6     = 4
+)
+
+var socks5Replies = map[byte]string{
+	1: "general SOCKS server failure",
+	2: "connection not allowed by ruleset",
+	3: "network unreachable",
+	4: "host unreachable",
+	5: "connection refused",
+	6: "TTL expired",
+	7: "command not supported",
+	8: "address type not supported",
+}
+
+// SOCKS5DialerOptions configures the dialer returned by NewSOCKS5Dialer.
+type SOCKS5DialerOptions struct {
+	// ProxyHostPort is the host:port of the SOCKS5 proxy.
+	ProxyHostPort string
+
+	// Username and Password authenticate with the proxy. If Username is
+	// empty, no authentication is used.
+	Username string
+	Password string
+
+	// Dialer is used to connect to the proxy. If not set, a net.Dialer is used.
+	Dialer Dialer
+}
+
+// NewSOCKS5Dialer returns a dialer that can be used as ChannelOptions.Dialer
+// to connect to peers through a SOCKS5 proxy.
+func NewSOCKS5Dialer(opts SOCKS5DialerOptions) Dialer {
+	dial := opts.Dialer
+	if dial == nil {
+		dial = defaultDialer
+	}
+
+	return func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		conn, err := dial(ctx, network, opts.ProxyHostPort)
+		if err != nil {
+			return nil, err
+		}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		if err := socks5Connect(conn, hostPort, opts.Username, opts.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn.SetDeadline(time.Time{})
+		return conn, nil
+	}
+}
+
+// socks5Connect negotiates authentication with the proxy over conn, and then
+// asks it to connect to hostPort.
+func socks5Connect(conn net.Conn, hostPort, username, password string) error {
+	method := byte(socks5AuthNone)
+	if username != "" {
+		method = socks5AuthPassword
+	}
+	if _, err := conn.Write([]byte{socks5Version, 1, method}); err != nil {
+		return err
+	}
+
+	var reply [2]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return err
+	}
+	if reply[0] != socks5Version {
+		return fmt.Errorf("unexpected SOCKS version %v", reply[0])
+	}
+	if reply[1] != method {
+		return errors.New("SOCKS5 proxy does not support the authentication method")
+	}
+	if method == socks5AuthPassword {
+		if err := socks5Authenticate(conn, username, password); err != nil {
+			return err
+		}
+	}
+
+	req, err := socks5ConnectRequest(hostPort)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	return socks5ReadReply(conn, hostPort)
+}
+
+func socks5Authenticate(conn net.Conn, username, password string) error {
+	if len(username) > 255 || len(password) > 255 {
+		return errors.New("SOCKS5 username and password must be at most 255 bytes")
+	}
+
+	req := []byte{socks5AuthVersion, byte(len(username))}
+	req = append(req, username...)
+	req = append(req, byte(len(password)))
+	req = append(req, password...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	var reply [2]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return err
+	}
+	if reply[1] != 0 {
+		return errors.New("SOCKS5 proxy authentication failed")
+	}
+	return nil
+}
+
+func socks5ConnectRequest(hostPort string) ([]byte, error) {
+	host, portStr, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in %q: %v", hostPort, err)
+	}
+
+	req := []byte{socks5Version, socks5CmdConnect, 0}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return nil, fmt.Errorf("host %q is too long for SOCKS5", host)
+		}
+		req = append(req, socks5AddrDomain, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, socks5AddrIPv4)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, socks5AddrIPv6)
+		req = append(req, ip.To16()...)
+	}
+	return append(req, byte(port>>8), byte(port)), nil
+}
+
+// socks5ReadReply reads the reply to a connect request, which includes the
+// address the proxy bound to connect to the target.
+func socks5ReadReply(conn net.Conn, hostPort string) error {
+	var header [4]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return err
+	}
+	if header[1] != 0 {
+		reason, ok := socks5Replies[header[1]]
+		if !ok {
+			reason = fmt.Sprintf("unknown error %v", header[1])
+		}
+		return fmt.Errorf("SOCKS5 connect to %v failed: %v", hostPort, reason)
+	}
+
+	var addrLen int
+	switch header[3] {
+	case socks5AddrIPv4:
+		addrLen = net.IPv4len
+	case socks5AddrIPv6:
+		addrLen = net.IPv6len
+	case socks5AddrDomain:
+		var domainLen [1]byte
+		if _, err := io.ReadFull(conn, domainLen[:]); err != nil {
+			return err
+		}
+		addrLen = int(domainLen[0])
+	default:
+		return fmt.Errorf("unexpected SOCKS5 address type %v", header[3])
+	}
+
+	// The bound address and port are not needed.
+	_, err := io.ReadFull(conn, make([]byte, addrLen+2))
+	return err
+}
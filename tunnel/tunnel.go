@@ -0,0 +1,104 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package tunnel carries TChannel connections through proxies, so that
+// clients behind L7 proxies which only allow HTTP traffic, or whose egress
+// must traverse a proxy, can reach TChannel services.
+//
+// Clients use NewConnectDialer to tunnel through an HTTP proxy using CONNECT,
+// NewSOCKS5Dialer to connect through a SOCKS5 proxy, or NewWebSocketDialer to
+// frame the connection as a WebSocket, as the ChannelOptions.Dialer. PerHost
+// selects a different dialer for specific peers. Servers serve a Listener as
+// an http.Handler, and pass it to Channel.Serve to accept the tunneled
+// connections.
+package tunnel
+
+import (
+	"bufio"
+	"net"
+
+	"golang.org/x/net/context"
+)
+
+// Dialer dials a connection to hostPort, matching ChannelOptions.Dialer.
+type Dialer func(ctx context.Context, network, hostPort string) (net.Conn, error)
+
+func defaultDialer(ctx context.Context, network, hostPort string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, network, hostPort)
+}
+
+// PerHost returns a dialer that dials each peer using the dialer for its
+// host:port in dialers, or else for its host, so proxies can be configured
+// per peer. Peers without a dialer use fallback, or a net.Dialer if fallback
+// is nil.
+func PerHost(dialers map[string]Dialer, fallback Dialer) Dialer {
+	if fallback == nil {
+		fallback = defaultDialer
+	}
+
+	return func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		if dial, ok := dialers[hostPort]; ok {
+			return dial(ctx, network, hostPort)
+		}
+		if host, _, err := net.SplitHostPort(hostPort); err == nil {
+			if dial, ok := dialers[host]; ok {
+				return dial(ctx, network, hostPort)
+			}
+		}
+		return fallback(ctx, network, hostPort)
+	}
+}
+
+// bufferedConn is a net.Conn which first reads any data that was buffered
+// while reading the HTTP response or request that set up the tunnel.
+type bufferedConn struct {
+	net.Conn
+
+	r *bufio.Reader
+}
+
+func withBuffered(conn net.Conn, r *bufio.Reader) net.Conn {
+	if r.Buffered() == 0 {
+		return conn
+	}
+	return &bufferedConn{Conn: conn, r: r}
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// addrConn overrides the addresses of a net.Conn, which are not the
+// underlying socket addresses for WebSocket connections.
+type addrConn struct {
+	net.Conn
+
+	local, remote net.Addr
+}
+
+func (c *addrConn) LocalAddr() net.Addr  { return c.local }
+func (c *addrConn) RemoteAddr() net.Addr { return c.remote }
+
+// hostPortAddr is a net.Addr for a TCP host:port.
+type hostPortAddr string
+
+func (a hostPortAddr) Network() string { return "tcp" }
+func (a hostPortAddr) String() string  { return string(a) }
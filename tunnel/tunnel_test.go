@@ -0,0 +1,329 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tunnel_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/testutils"
+	. "github.com/uber/tchannel-go/tunnel"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// newTunnelServer starts a channel serving a Listener over HTTP, and returns
+// its host:port and a function to stop it.
+func newTunnelServer(t *testing.T) (string, func()) {
+	ch, err := tchannel.NewChannel("tunnel-server", nil)
+	require.NoError(t, err, "NewChannel failed")
+	testutils.RegisterEcho(ch, nil)
+
+	httpL, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Listen failed")
+
+	l := NewListener(httpL.Addr())
+	server := &httptest.Server{Listener: httpL, Config: &http.Server{Handler: l}}
+	server.Start()
+
+	require.NoError(t, ch.Serve(l), "Serve failed")
+	return httpL.Addr().String(), func() {
+		ch.Close()
+		server.Close()
+	}
+}
+
+func callEcho(t *testing.T, dialer Dialer, hostPort string) {
+	client, err := tchannel.NewChannel("tunnel-client", &tchannel.ChannelOptions{Dialer: dialer})
+	require.NoError(t, err, "NewChannel failed")
+	defer client.Close()
+
+	require.NoError(t, testutils.CallEcho(client, hostPort, "tunnel-server", nil), "Call over tunnel failed")
+}
+
+func TestWebSocketTunnel(t *testing.T) {
+	hostPort, stop := newTunnelServer(t)
+	defer stop()
+
+	callEcho(t, NewWebSocketDialer(WebSocketDialerOptions{}), hostPort)
+}
+
+func TestConnectTunnelToListener(t *testing.T) {
+	hostPort, stop := newTunnelServer(t)
+	defer stop()
+
+	callEcho(t, NewConnectDialer(ConnectDialerOptions{ProxyHostPort: hostPort}), hostPort)
+}
+
+func TestConnectTunnelThroughProxy(t *testing.T) {
+	server := testutils.NewServer(t, testutils.NewOpts().SetServiceName("tunnel-server"))
+	defer server.Close()
+	testutils.RegisterEcho(server, nil)
+
+	var connected []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != "secret" {
+			http.Error(w, "unauthorized", http.StatusProxyAuthRequired)
+			return
+		}
+		connected = append(connected, r.Host)
+
+		target, err := net.Dial("tcp", r.Host)
+		if !assert.NoError(t, err, "proxy failed to dial target") {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err, "Hijack failed")
+		conn.Write([]byte("HTTP/1.1 200 OK\r\n\r\n"))
+		go func() {
+			io.Copy(target, conn)
+			target.Close()
+		}()
+		io.Copy(conn, target)
+		conn.Close()
+	}))
+	defer proxy.Close()
+
+	proxyHostPort := proxy.Listener.Addr().String()
+	callEcho(t, NewConnectDialer(ConnectDialerOptions{
+		ProxyHostPort: proxyHostPort,
+		Header:        http.Header{"Proxy-Authorization": {"secret"}},
+	}), server.PeerInfo().HostPort)
+	assert.Equal(t, []string{server.PeerInfo().HostPort}, connected)
+
+	client, err := tchannel.NewChannel("tunnel-client", &tchannel.ChannelOptions{
+		Dialer: NewConnectDialer(ConnectDialerOptions{ProxyHostPort: proxyHostPort}),
+	})
+	require.NoError(t, err, "NewChannel failed")
+	defer client.Close()
+	err = testutils.CallEcho(client, server.PeerInfo().HostPort, "tunnel-server", nil)
+	require.Error(t, err, "Call without proxy credentials should fail")
+	assert.Contains(t, err.Error(), "407")
+}
+
+func TestConnectTunnelBasicAuth(t *testing.T) {
+	server := testutils.NewServer(t, testutils.NewOpts().SetServiceName("tunnel-server"))
+	defer server.Close()
+	testutils.RegisterEcho(server, nil)
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+			http.Error(w, "unauthorized", http.StatusProxyAuthRequired)
+			return
+		}
+		target, err := net.Dial("tcp", r.Host)
+		require.NoError(t, err, "proxy failed to dial target")
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err, "Hijack failed")
+		conn.Write([]byte("HTTP/1.1 200 OK\r\n\r\n"))
+		pipe(conn, target)
+	}))
+	defer proxy.Close()
+
+	callEcho(t, NewConnectDialer(ConnectDialerOptions{
+		ProxyHostPort: proxy.Listener.Addr().String(),
+		Username:      "user",
+		Password:      "pass",
+	}), server.PeerInfo().HostPort)
+}
+
+// pipe copies data between the two connections until either is closed.
+func pipe(a, b net.Conn) {
+	go func() {
+		io.Copy(a, b)
+		a.Close()
+	}()
+	io.Copy(b, a)
+	b.Close()
+}
+
+// socks5Proxy is a minimal SOCKS5 proxy which only supports CONNECT.
+type socks5Proxy struct {
+	sync.Mutex
+
+	ln       net.Listener
+	password string
+	targets  []string
+}
+
+func newSOCKS5Proxy(t *testing.T, password string) *socks5Proxy {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Listen failed")
+
+	p := &socks5Proxy{ln: ln, password: password}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go p.handle(conn)
+		}
+	}()
+	return p
+}
+
+func (p *socks5Proxy) hostPort() string {
+	return p.ln.Addr().String()
+}
+
+func (p *socks5Proxy) connected() []string {
+	p.Lock()
+	defer p.Unlock()
+	return p.targets
+}
+
+func (p *socks5Proxy) handle(conn net.Conn) {
+	target, err := p.accept(conn)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	pipe(conn, target)
+}
+
+func (p *socks5Proxy) accept(conn net.Conn) (net.Conn, error) {
+	buf := make([]byte, 262)
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return nil, err
+	}
+	methods := buf[2 : 2+buf[1]]
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return nil, err
+	}
+	if p.password == "" {
+		_, err := conn.Write([]byte{5, 0})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if methods[0] != 2 {
+			conn.Write([]byte{5, 0xff})
+			return nil, errors.New("password authentication required")
+		}
+		conn.Write([]byte{5, 2})
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return nil, err
+		}
+		user := make([]byte, buf[1])
+		io.ReadFull(conn, user)
+		io.ReadFull(conn, buf[:1])
+		password := make([]byte, buf[0])
+		io.ReadFull(conn, password)
+		if string(password) != p.password {
+			conn.Write([]byte{1, 1})
+			return nil, errors.New("invalid password")
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	// Only IPv4 addresses are used in tests.
+	if _, err := io.ReadFull(conn, buf[:10]); err != nil {
+		return nil, err
+	}
+	hostPort := net.JoinHostPort(net.IP(buf[4:8]).String(), strconv.Itoa(int(buf[8])<<8|int(buf[9])))
+	target, err := net.Dial("tcp", hostPort)
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return nil, err
+	}
+	p.Lock()
+	p.targets = append(p.targets, hostPort)
+	p.Unlock()
+	conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+	return target, nil
+}
+
+func TestSOCKS5Dialer(t *testing.T) {
+	server := testutils.NewServer(t, testutils.NewOpts().SetServiceName("tunnel-server"))
+	defer server.Close()
+	testutils.RegisterEcho(server, nil)
+	serverHostPort := server.PeerInfo().HostPort
+
+	tests := []struct {
+		msg           string
+		proxyPassword string
+		password      string
+		wantErr       string
+	}{
+		{msg: "no auth"},
+		{msg: "password auth", proxyPassword: "pass", password: "pass"},
+		{msg: "wrong password", proxyPassword: "pass", password: "wrong", wantErr: "authentication failed"},
+		{msg: "missing password", proxyPassword: "pass", wantErr: "does not support the authentication method"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			proxy := newSOCKS5Proxy(t, tt.proxyPassword)
+			defer proxy.ln.Close()
+
+			opts := SOCKS5DialerOptions{ProxyHostPort: proxy.hostPort()}
+			if tt.password != "" {
+				opts.Username = "user"
+				opts.Password = tt.password
+			}
+			dialer := NewSOCKS5Dialer(opts)
+			if tt.wantErr == "" {
+				callEcho(t, dialer, serverHostPort)
+				assert.Equal(t, []string{serverHostPort}, proxy.connected(), "Unexpected proxy targets")
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), testutils.Timeout(time.Second))
+			defer cancel()
+			_, err := dialer(ctx, "tcp", serverHostPort)
+			require.Error(t, err, "Dial should fail")
+			assert.Contains(t, err.Error(), tt.wantErr, "Unexpected error")
+		})
+	}
+}
+
+func TestPerHost(t *testing.T) {
+	server := testutils.NewServer(t, testutils.NewOpts().SetServiceName("tunnel-server"))
+	defer server.Close()
+	testutils.RegisterEcho(server, nil)
+	serverHostPort := server.PeerInfo().HostPort
+
+	proxy := newSOCKS5Proxy(t, "")
+	defer proxy.ln.Close()
+	socks := NewSOCKS5Dialer(SOCKS5DialerOptions{ProxyHostPort: proxy.hostPort()})
+
+	// Peers without a dialer use the fallback.
+	callEcho(t, PerHost(map[string]Dialer{"10.0.0.1": socks}, nil), serverHostPort)
+	assert.Empty(t, proxy.connected(), "Peer without a dialer should not use the proxy")
+
+	callEcho(t, PerHost(map[string]Dialer{"127.0.0.1": socks}, nil), serverHostPort)
+	assert.Equal(t, []string{serverHostPort}, proxy.connected(), "Peer should use the dialer for its host")
+
+	callEcho(t, PerHost(map[string]Dialer{serverHostPort: socks}, nil), serverHostPort)
+	assert.Len(t, proxy.connected(), 2, "Peer should use the dialer for its host:port")
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tunnel
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocketDialerOptions configures the dialer returned by NewWebSocketDialer.
+type WebSocketDialerOptions struct {
+	// Path is the path the peer's Listener is served on. Defaults to "/".
+	Path string
+
+	// TLSConfig, if set, is used to connect to peers using wss rather than ws.
+	// If ServerName is not set, the host of the peer's host:port is used.
+	TLSConfig *tls.Config
+
+	// Header is sent with each WebSocket upgrade request.
+	Header http.Header
+
+	// Dialer is used to connect to the peer. If not set, a net.Dialer is used.
+	// Use a dialer returned by NewConnectDialer to connect through a proxy.
+	Dialer Dialer
+}
+
+// NewWebSocketDialer returns a dialer that can be used as
+// ChannelOptions.Dialer to connect to peers serving a Listener, with the
+// TChannel frames carried in binary WebSocket messages.
+func NewWebSocketDialer(opts WebSocketDialerOptions) Dialer {
+	dial := opts.Dialer
+	if dial == nil {
+		dial = defaultDialer
+	}
+	path := opts.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return func(ctx context.Context, network, hostPort string) (net.Conn, error) {
+		scheme := "ws"
+		if opts.TLSConfig != nil {
+			scheme = "wss"
+		}
+		config, err := websocket.NewConfig(scheme+"://"+hostPort+path, "http://"+hostPort)
+		if err != nil {
+			return nil, err
+		}
+		config.Header = opts.Header
+
+		conn, err := dial(ctx, network, hostPort)
+		if err != nil {
+			return nil, err
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+
+		ws, err := upgrade(conn, hostPort, config, opts.TLSConfig)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn.SetDeadline(time.Time{})
+		return ws, nil
+	}
+}
+
+func upgrade(conn net.Conn, hostPort string, config *websocket.Config, tlsConfig *tls.Config) (net.Conn, error) {
+	rwc := conn
+	if tlsConfig != nil {
+		tlsConfig = tlsConfig.Clone()
+		if tlsConfig.ServerName == "" {
+			host, _, err := net.SplitHostPort(hostPort)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.ServerName = host
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, err
+		}
+		rwc = tlsConn
+	}
+
+	ws, err := websocket.NewClient(config, rwc)
+	if err != nil {
+		return nil, err
+	}
+	ws.PayloadType = websocket.BinaryFrame
+	return &addrConn{Conn: ws, local: conn.LocalAddr(), remote: conn.RemoteAddr()}, nil
+}
@@ -43,7 +43,7 @@ func (p *Peer) SetOnUpdate(f func(*Peer)) {
 
 // GetConnectionRelay exports the getConnectionRelay for tests.
 func (p *Peer) GetConnectionRelay(timeout time.Duration) (*Connection, error) {
-	return p.getConnectionRelay(timeout)
+	return p.getConnectionRelay(timeout, 1)
 }
 
 // SetRandomSeed seeds all the random number generators in the channel so that
@@ -0,0 +1,139 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWriteCoalescingBufferSize = 32 * 1024
+
+	// coalescingCloseTimeout limits how long Close waits to write buffered
+	// frames, so closing a connection to a peer that isn't reading doesn't hang.
+	coalescingCloseTimeout = time.Second
+)
+
+// WriteCoalescingOptions configures coalescing of frames written to a
+// connection, so that many small frames are written using a single syscall.
+type WriteCoalescingOptions struct {
+	// FlushLatency is how long frames can be buffered once there are no more
+	// frames waiting to be written, before they're written to the network.
+	// This adds up to FlushLatency to the latency of calls, in exchange for
+	// fewer syscalls. If this is zero (the default), frames are written as
+	// soon as there are no more frames waiting to be written.
+	FlushLatency time.Duration
+
+	// BufferSize is the number of bytes that are buffered before they're
+	// written, regardless of FlushLatency. Defaults to 32KB.
+	BufferSize int
+}
+
+func (o WriteCoalescingOptions) enabled() bool {
+	return o.FlushLatency > 0
+}
+
+// coalescingConn is a net.Conn that buffers writes, and writes them to the
+// underlying connection once the buffer is full, or FlushLatency after Flush
+// is called.
+type coalescingConn struct {
+	net.Conn
+
+	flushLatency time.Duration
+
+	mu         sync.Mutex
+	w          *bufio.Writer
+	flushTimer *time.Timer
+	err        error
+}
+
+func newCoalescingConn(conn net.Conn, opts WriteCoalescingOptions) *coalescingConn {
+	size := opts.BufferSize
+	if size <= 0 {
+		size = defaultWriteCoalescingBufferSize
+	}
+	return &coalescingConn{
+		Conn:         conn,
+		flushLatency: opts.FlushLatency,
+		w:            bufio.NewWriterSize(conn, size),
+	}
+}
+
+func (c *coalescingConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(b)
+	if err != nil {
+		c.err = err
+	}
+	return n, err
+}
+
+// Flush schedules the buffered writes to be written after the flush latency,
+// unless a flush is already scheduled. It returns any error from a previous
+// write to the underlying connection.
+func (c *coalescingConn) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	if c.flushTimer == nil && c.w.Buffered() > 0 {
+		c.flushTimer = time.AfterFunc(c.flushLatency, c.flushNow)
+	}
+	return nil
+}
+
+// flushNow writes any buffered writes to the underlying connection.
+func (c *coalescingConn) flushNow() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked()
+}
+
+func (c *coalescingConn) flushLocked() {
+	if c.flushTimer != nil {
+		c.flushTimer.Stop()
+		c.flushTimer = nil
+	}
+	if c.err == nil {
+		c.err = c.w.Flush()
+	}
+}
+
+// Close writes any buffered writes before closing the underlying connection.
+func (c *coalescingConn) Close() error {
+	c.mu.Lock()
+	if c.err == nil && c.w.Buffered() > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(coalescingCloseTimeout))
+	}
+	c.flushLocked()
+	c.mu.Unlock()
+	return c.Conn.Close()
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/uber/tchannel-go"
+
+	"github.com/uber/tchannel-go/raw"
+	"github.com/uber/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionWriteTuning(t *testing.T) {
+	tests := []struct {
+		msg  string
+		opts ConnectionOptions
+	}{
+		{
+			msg: "coalescing",
+			opts: ConnectionOptions{
+				WriteCoalescing: WriteCoalescingOptions{FlushLatency: 200 * time.Microsecond},
+			},
+		},
+		{
+			msg: "coalescing with small buffer and compression",
+			opts: ConnectionOptions{
+				WriteCoalescing: WriteCoalescingOptions{FlushLatency: time.Millisecond, BufferSize: 100},
+				Compression:     CompressionFlate,
+			},
+		},
+		{
+			msg: "socket options",
+			opts: ConnectionOptions{
+				SocketReadBufferSize:  64 * 1024,
+				SocketWriteBufferSize: 64 * 1024,
+				EnableNagle:           true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			opts := testutils.NewOpts()
+			opts.DefaultConnectionOptions = tt.opts
+			testutils.WithTestServer(t, opts, func(ts *testutils.TestServer) {
+				testutils.RegisterEcho(ts.Server(), nil)
+				client := ts.NewClient(opts)
+				for _, arg3 := range [][]byte{[]byte("small"), testutils.RandBytes(100000)} {
+					ctx, cancel := NewContext(testutils.Timeout(time.Second))
+					_, gotArg3, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, arg3)
+					cancel()
+					require.NoError(t, err, "Call failed")
+					require.Equal(t, arg3, gotArg3, "Unexpected response")
+				}
+			})
+		})
+	}
+}
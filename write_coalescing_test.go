@@ -0,0 +1,98 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeRecordingConn is a net.Conn that records each write.
+type writeRecordingConn struct {
+	net.Conn
+
+	sync.Mutex
+	writes [][]byte
+	closed bool
+}
+
+func (c *writeRecordingConn) Write(b []byte) (int, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.writes = append(c.writes, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func (c *writeRecordingConn) SetWriteDeadline(time.Time) error { return nil }
+
+func (c *writeRecordingConn) Close() error {
+	c.Lock()
+	defer c.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *writeRecordingConn) getWrites() [][]byte {
+	c.Lock()
+	defer c.Unlock()
+	return c.writes
+}
+
+func TestCoalescingConnFlushLatency(t *testing.T) {
+	inner := &writeRecordingConn{}
+	conn := newCoalescingConn(inner, WriteCoalescingOptions{FlushLatency: 10 * time.Millisecond})
+
+	for _, s := range []string{"a", "b", "c"} {
+		_, err := conn.Write([]byte(s))
+		require.NoError(t, err, "Write failed")
+	}
+	require.NoError(t, conn.Flush(), "Flush failed")
+	assert.Empty(t, inner.getWrites(), "Writes should be buffered until the flush latency")
+
+	deadline := time.Now().Add(time.Second)
+	for len(inner.getWrites()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, [][]byte{[]byte("abc")}, inner.getWrites(), "Writes should be coalesced")
+}
+
+func TestCoalescingConnBufferFull(t *testing.T) {
+	inner := &writeRecordingConn{}
+	conn := newCoalescingConn(inner, WriteCoalescingOptions{FlushLatency: time.Hour, BufferSize: 4})
+
+	_, err := conn.Write([]byte("abc"))
+	require.NoError(t, err, "Write failed")
+	assert.Empty(t, inner.getWrites(), "Writes should be buffered")
+
+	_, err = conn.Write([]byte("def"))
+	require.NoError(t, err, "Write failed")
+	assert.Equal(t, []byte("abcd"), bytes.Join(inner.getWrites(), nil), "Full buffer should be written")
+
+	require.NoError(t, conn.Close(), "Close failed")
+	assert.Equal(t, []byte("abcdef"), bytes.Join(inner.getWrites(), nil), "Close should write buffered writes")
+	assert.True(t, inner.closed, "Underlying connection should be closed")
+}
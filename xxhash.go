@@ -0,0 +1,154 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+// xxHash64 primes, see https://github.com/Cyan4973/xxHash/blob/dev/doc/xxhash_spec.md
+// These are variables rather than constants so that arithmetic on them wraps.
+var (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// xxHash64 is a streaming implementation of the 64-bit xxHash algorithm
+// with a seed of 0.
+type xxHash64 struct {
+	v1, v2, v3, v4 uint64
+	total          uint64
+	mem            [32]byte
+	n              int
+}
+
+var _ hash.Hash64 = (*xxHash64)(nil)
+
+func newXXHash64() *xxHash64 {
+	h := &xxHash64{}
+	h.Reset()
+	return h
+}
+
+func (h *xxHash64) Reset() {
+	h.v1 = xxPrime1 + xxPrime2
+	h.v2 = xxPrime2
+	h.v3 = 0
+	h.v4 = -xxPrime1
+	h.total = 0
+	h.n = 0
+}
+
+func (h *xxHash64) Size() int { return 8 }
+
+func (h *xxHash64) BlockSize() int { return 32 }
+
+func (h *xxHash64) Write(b []byte) (int, error) {
+	n := len(b)
+	h.total += uint64(n)
+
+	if h.n+len(b) < 32 {
+		h.n += copy(h.mem[h.n:], b)
+		return n, nil
+	}
+
+	if h.n > 0 {
+		c := copy(h.mem[h.n:], b)
+		h.v1 = xxRound(h.v1, binary.LittleEndian.Uint64(h.mem[0:8]))
+		h.v2 = xxRound(h.v2, binary.LittleEndian.Uint64(h.mem[8:16]))
+		h.v3 = xxRound(h.v3, binary.LittleEndian.Uint64(h.mem[16:24]))
+		h.v4 = xxRound(h.v4, binary.LittleEndian.Uint64(h.mem[24:32]))
+		b = b[c:]
+		h.n = 0
+	}
+
+	for ; len(b) >= 32; b = b[32:] {
+		h.v1 = xxRound(h.v1, binary.LittleEndian.Uint64(b[0:8]))
+		h.v2 = xxRound(h.v2, binary.LittleEndian.Uint64(b[8:16]))
+		h.v3 = xxRound(h.v3, binary.LittleEndian.Uint64(b[16:24]))
+		h.v4 = xxRound(h.v4, binary.LittleEndian.Uint64(b[24:32]))
+	}
+
+	h.n = copy(h.mem[:], b)
+	return n, nil
+}
+
+func (h *xxHash64) Sum64() uint64 {
+	var v uint64
+	if h.total >= 32 {
+		v = rotl64(h.v1, 1) + rotl64(h.v2, 7) +
+			rotl64(h.v3, 12) + rotl64(h.v4, 18)
+		v = xxMergeRound(v, h.v1)
+		v = xxMergeRound(v, h.v2)
+		v = xxMergeRound(v, h.v3)
+		v = xxMergeRound(v, h.v4)
+	} else {
+		v = h.v3 + xxPrime5
+	}
+	v += h.total
+
+	b := h.mem[:h.n]
+	for ; len(b) >= 8; b = b[8:] {
+		v ^= xxRound(0, binary.LittleEndian.Uint64(b))
+		v = rotl64(v, 27)*xxPrime1 + xxPrime4
+	}
+	if len(b) >= 4 {
+		v ^= uint64(binary.LittleEndian.Uint32(b)) * xxPrime1
+		v = rotl64(v, 23)*xxPrime2 + xxPrime3
+		b = b[4:]
+	}
+	for _, c := range b {
+		v ^= uint64(c) * xxPrime5
+		v = rotl64(v, 11) * xxPrime1
+	}
+
+	v ^= v >> 33
+	v *= xxPrime2
+	v ^= v >> 29
+	v *= xxPrime3
+	v ^= v >> 32
+	return v
+}
+
+func (h *xxHash64) Sum(b []byte) []byte {
+	var sum [8]byte
+	binary.BigEndian.PutUint64(sum[:], h.Sum64())
+	return append(b, sum[:]...)
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = rotl64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMergeRound(acc, v uint64) uint64 {
+	acc ^= xxRound(0, v)
+	return acc*xxPrime1 + xxPrime4
+}
+
+func rotl64(x uint64, r uint) uint64 {
+	return (x << r) | (x >> (64 - r))
+}